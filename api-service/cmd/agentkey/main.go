@@ -0,0 +1,30 @@
+package main
+
+import (
+	"api-service/internal/config"
+	"api-service/internal/service"
+	"fmt"
+	"log"
+	"os"
+)
+
+// 输出 Agent 的任务密钥，写入该 Agent 配置的 security.task_key
+// 用法: agentkey <agent-id>...，与 api-service 共用同一配置文件
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: agentkey <agent-id>...")
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+	if cfg.Agent.TaskKey == "" {
+		log.Fatal("agent.task_key is empty")
+	}
+
+	for _, agentID := range os.Args[1:] {
+		fmt.Printf("%s\t%s\n", agentID, service.AgentTaskKey(cfg.Agent.TaskKey, agentID))
+	}
+}
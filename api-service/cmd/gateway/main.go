@@ -0,0 +1,75 @@
+package main
+
+import (
+	"api-service/internal/config"
+	"api-service/internal/database"
+	"api-service/internal/gateway"
+	"api-service/internal/repository"
+	"api-service/internal/service"
+	"api-service/pkg/utils"
+	"context"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// 内置反向代理网关，按 AppGatewayPublish 将域名转发到应用实例端口
+func main() {
+	// 加载配置，与 api-service 共用同一配置文件
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
+	// 初始化数据库
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+
+	// 初始化Redis，用于路由重新加载通知与 ACME HTTP-01 质询
+	rdb, err := database.InitRedis(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize Redis:", err)
+	}
+
+	// 初始化证书私钥解密器
+	encryptor, err := utils.NewEncryptor(cfg.Security.EncryptionKey)
+	if err != nil {
+		log.Fatal("Failed to initialize encryptor:", err)
+	}
+
+	// 初始化指标存储，用于写入按分钟汇总的流量统计
+	metricsStore, err := database.InitMetricsStore(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize metrics store:", err)
+	}
+	defer metricsStore.Close()
+
+	// 访问日志默认输出到标准输出
+	var accessLog io.Writer = os.Stdout
+	if cfg.Gateway.AccessLog != "" {
+		f, err := os.OpenFile(cfg.Gateway.AccessLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			log.Fatal("Failed to open access log:", err)
+		}
+		defer f.Close()
+		accessLog = f
+	}
+
+	gw := gateway.New(cfg.Gateway,
+		repository.NewGatewayRepository(db),
+		repository.NewCertificateRepository(db),
+		service.NewMonitorService(metricsStore, repository.NewServerRepository(db), repository.NewApplicationRepository(db)),
+		rdb, encryptor, accessLog)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := gw.Run(ctx); err != nil {
+		log.Fatal("Gateway stopped with error:", err)
+	}
+	log.Println("Gateway exited")
+}
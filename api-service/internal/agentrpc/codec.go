@@ -0,0 +1,28 @@
+package agentrpc
+
+import (
+	"api-service/internal/constants"
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// codec 以 JSON 编码 gRPC 消息，Agent 与服务端无需共享 protobuf 生成代码
+// 客户端通过 grpc.CallContentSubtype("json") 选择该编码
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return constants.AgentRPCCodec
+}
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
@@ -0,0 +1,169 @@
+// Package agentrpc 提供 Agent 连接的 gRPC 服务：心跳与指标上报
+package agentrpc
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/service"
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// Envelope 请求信封，Payload 为使用任务密钥加密的 JSON，与 Redis 任务通道的格式一致
+type Envelope struct {
+	AgentID string `json:"agent_id"`
+	Payload string `json:"payload"`
+}
+
+// Reply 响应，ServerID 为 Agent 注册所在的服务器
+type Reply struct {
+	ServerID uint `json:"server_id"`
+	Accepted int  `json:"accepted"`
+}
+
+// AgentServiceServer webox.agent.v1.AgentService 的服务端接口
+type AgentServiceServer interface {
+	Heartbeat(ctx context.Context, req *Envelope) (*Reply, error)
+	ReportMetrics(ctx context.Context, req *Envelope) (*Reply, error)
+}
+
+// Server Agent gRPC 服务
+type Server struct {
+	agentService   service.AgentService
+	monitorService service.MonitorService
+}
+
+func NewServer(agentService service.AgentService, monitorService service.MonitorService) *Server {
+	return &Server{
+		agentService:   agentService,
+		monitorService: monitorService,
+	}
+}
+
+// Serve 监听 addr 并提供服务，ctx 结束时优雅停止
+// certFile 与 keyFile 均为空时不启用 TLS，仅用于开发环境
+func (s *Server) Serve(ctx context.Context, addr, certFile, keyFile string) error {
+	var opts []grpc.ServerOption
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})))
+	} else {
+		log.Printf("Agent gRPC server is running without TLS, set grpc.tls_cert and grpc.tls_key in production")
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	gs := grpc.NewServer(opts...)
+	gs.RegisterService(&serviceDesc, s)
+
+	go func() {
+		<-ctx.Done()
+		gs.GracefulStop()
+	}()
+
+	log.Printf("Agent gRPC server listening on %s", addr)
+	return gs.Serve(lis)
+}
+
+// Heartbeat 更新 Agent 与服务器的心跳时间
+func (s *Server) Heartbeat(_ context.Context, req *Envelope) (*Reply, error) {
+	var heartbeat service.AgentHeartbeat
+	agent, err := s.authenticate(req, &heartbeat, &heartbeat)
+	if err != nil {
+		return nil, err
+	}
+	return &Reply{ServerID: agent.ServerID}, nil
+}
+
+// ReportMetrics 写入 Agent 批量上报的指标，同时更新心跳时间
+func (s *Server) ReportMetrics(_ context.Context, req *Envelope) (*Reply, error) {
+	var metrics service.AgentMetrics
+	agent, err := s.authenticate(req, &metrics, &metrics.AgentHeartbeat)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted, err := s.monitorService.IngestAgentMetrics(agent, metrics.Points)
+	if err != nil {
+		log.Printf("Failed to ingest metrics from agent %s: %v", agent.AgentID, err)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &Reply{ServerID: agent.ServerID, Accepted: accepted}, nil
+}
+
+// authenticate 使用信封中 Agent 的任务密钥解密载荷，并确认载荷中的 Agent ID 与信封一致，随后记录心跳
+// 任务密钥按 Agent ID 派生，只有持有该 Agent 密钥的一方能以该 Agent 的身份上报
+func (s *Server) authenticate(req *Envelope, payload interface{}, heartbeat *service.AgentHeartbeat) (*model.ServerAgent, error) {
+	if err := s.agentService.DecodeMessage(req.AgentID, req.Payload, payload); err != nil {
+		log.Printf("Rejected agent message claiming to be %q: %v", req.AgentID, err)
+		return nil, status.Error(codes.Unauthenticated, "invalid payload")
+	}
+	if heartbeat.AgentID == "" || heartbeat.AgentID != req.AgentID {
+		return nil, status.Error(codes.Unauthenticated, "agent id mismatch")
+	}
+
+	agent, err := s.agentService.Heartbeat(heartbeat)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "agent %s is not registered", req.AgentID)
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return agent, nil
+}
+
+func heartbeatHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Envelope)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + constants.AgentRPCService + "/Heartbeat"}
+	return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).Heartbeat(ctx, req.(*Envelope))
+	})
+}
+
+func reportMetricsHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Envelope)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + constants.AgentRPCService + "/ReportMetrics"}
+	return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportMetrics(ctx, req.(*Envelope))
+	})
+}
+
+// serviceDesc 手写的服务描述，消息经 JSON 编码
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: constants.AgentRPCService,
+	HandlerType: (*AgentServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Heartbeat", Handler: heartbeatHandler},
+		{MethodName: "ReportMetrics", Handler: reportMetricsHandler},
+	},
+	Streams: []grpc.StreamDesc{},
+}
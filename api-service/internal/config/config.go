@@ -2,17 +2,27 @@ package config
 
 import (
 	"api-service/internal/constants"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	InfluxDB InfluxDBConfig `mapstructure:"influxdb"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	GRPC     GRPCConfig     `mapstructure:"grpc"`
+	Server       ServerConfig       `mapstructure:"server"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	Redis        RedisConfig        `mapstructure:"redis"`
+	InfluxDB     InfluxDBConfig     `mapstructure:"influxdb"`
+	Metrics      MetricsConfig      `mapstructure:"metrics"`
+	JWT          JWTConfig          `mapstructure:"jwt"`
+	GRPC         GRPCConfig         `mapstructure:"grpc"`
+	Security     SecurityConfig     `mapstructure:"security"`
+	Agent        AgentConfig        `mapstructure:"agent"`
+	Certificate  CertificateConfig  `mapstructure:"certificate"`
+	ACME         ACMEConfig         `mapstructure:"acme"`
+	Gateway      GatewayConfig      `mapstructure:"gateway"`
+	Prometheus   PrometheusConfig   `mapstructure:"prometheus"`
+	Notification NotificationConfig `mapstructure:"notification"`
+	Storage      StorageConfig      `mapstructure:"storage"`
 }
 
 type ServerConfig struct {
@@ -38,13 +48,104 @@ type InfluxDBConfig struct {
 	Bucket string `mapstructure:"bucket"`
 }
 
+// MetricsConfig 指标存储配置，Store 为 influxdb 或 sqlite
+type MetricsConfig struct {
+	Store  string              `mapstructure:"store"`
+	SQLite MetricsSQLiteConfig `mapstructure:"sqlite"`
+}
+
+// MetricsSQLiteConfig 内置存储配置，原始数据降采样为 5 分钟与 1 小时两级，各级按保留时长清理
+type MetricsSQLiteConfig struct {
+	Path              string        `mapstructure:"path"`
+	RawRetention      time.Duration `mapstructure:"raw_retention"`
+	Rollup5mRetention time.Duration `mapstructure:"rollup_5m_retention"`
+	Rollup1hRetention time.Duration `mapstructure:"rollup_1h_retention"`
+}
+
 type JWTConfig struct {
 	Secret     string `mapstructure:"secret"`
 	ExpireTime int    `mapstructure:"expire_time"`
 }
 
+// GRPCConfig Agent gRPC 服务配置，TLSCert 与 TLSKey 为 PEM 文件路径，均为空时不启用 TLS
 type GRPCConfig struct {
-	Port string `mapstructure:"port"`
+	Port    string `mapstructure:"port"`
+	TLSCert string `mapstructure:"tls_cert"`
+	TLSKey  string `mapstructure:"tls_key"`
+}
+
+type SecurityConfig struct {
+	EncryptionKey           string `mapstructure:"encryption_key"`
+	SecretExpiryWarningDays int    `mapstructure:"secret_expiry_warning_days"`
+}
+
+type CertificateConfig struct {
+	ExpiryWarningDays int `mapstructure:"expiry_warning_days"`
+}
+
+type ACMEConfig struct {
+	DirectoryURL          string                       `mapstructure:"directory_url"`
+	Email                 string                       `mapstructure:"email"`
+	CARootsFile           string                       `mapstructure:"ca_roots_file"`
+	EABKeyID              string                       `mapstructure:"eab_key_id"`
+	EABHMACKey            string                       `mapstructure:"eab_hmac_key"`
+	RenewBeforeDays       int                          `mapstructure:"renew_before_days"`
+	DNSPropagationSeconds int                          `mapstructure:"dns_propagation_seconds"`
+	DNSProviders          map[string]map[string]string `mapstructure:"dns_providers"`
+}
+
+// GatewayConfig 内置反向代理网关配置，GatewayID 为 0 时加载全部网关的发布规则
+type GatewayConfig struct {
+	GatewayID     uint   `mapstructure:"gateway_id"`
+	HTTPAddr      string `mapstructure:"http_addr"`
+	HTTPSAddr     string `mapstructure:"https_addr"`
+	ReloadChannel string `mapstructure:"reload_channel"`
+	PollInterval  int    `mapstructure:"poll_interval"` // 秒
+	// RateLimitStore 限流计数存储: local 为进程内计数，redis 为多个网关实例共享计数
+	RateLimitStore string `mapstructure:"rate_limit_store"`
+	// AccessLog 访问日志文件路径，为空时输出到标准输出
+	AccessLog string `mapstructure:"access_log"`
+}
+
+// PrometheusConfig /metrics 端点配置，Token 非空时抓取须携带 Bearer 令牌
+type PrometheusConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Token   string `mapstructure:"token"`
+}
+
+// NotificationConfig 通知配置
+// Channels 的键为渠道名称，值为驱动配置，其中 driver 指定驱动(smtp、webhook、sms、slack、dingtalk、wecom、feishu)，其余为驱动的配置项
+// DeploymentChannels 为部署完成时除站内通知外使用的渠道
+// PushChannel 为多实例间转发站内通知推送的 Redis 频道
+type NotificationConfig struct {
+	ConsoleURL         string                       `mapstructure:"console_url"`
+	MaxAttempts        int                          `mapstructure:"max_attempts"`
+	PushChannel        string                       `mapstructure:"push_channel"`
+	DeploymentChannels []string                     `mapstructure:"deployment_channels"`
+	Channels           map[string]map[string]string `mapstructure:"channels"`
+}
+
+// StorageConfig 用户文件存储配置，Backend 为 local 或 s3
+type StorageConfig struct {
+	Backend     string          `mapstructure:"backend"`
+	LocalPath   string          `mapstructure:"local_path"`
+	MaxFileSize int64           `mapstructure:"max_file_size"` // 字节
+	S3          StorageS3Config `mapstructure:"s3"`
+}
+
+// StorageS3Config 兼容 S3 的对象存储，PathStyle 为 true 时使用 endpoint/bucket/key 形式的地址(MinIO 需开启)
+type StorageS3Config struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	PathStyle bool   `mapstructure:"path_style"`
+}
+
+type AgentConfig struct {
+	TaskKey        string `mapstructure:"task_key"`
+	ResultsChannel string `mapstructure:"results_channel"`
 }
 
 func Load() (*Config, error) {
@@ -77,7 +178,35 @@ func setDefaults() {
 	viper.SetDefault("redis.host", "localhost")
 	viper.SetDefault("redis.port", "6379")
 	viper.SetDefault("redis.db", 0)
+	viper.SetDefault("influxdb.org", constants.DefaultInfluxDBOrg)
+	viper.SetDefault("influxdb.bucket", constants.DefaultInfluxDBBucket)
+	viper.SetDefault("metrics.store", constants.MetricsStoreInfluxDB)
+	viper.SetDefault("metrics.sqlite.path", constants.DefaultMetricsSQLitePath)
+	viper.SetDefault("metrics.sqlite.raw_retention", constants.DefaultMetricsRawRetention)
+	viper.SetDefault("metrics.sqlite.rollup_5m_retention", constants.DefaultMetrics5mRetention)
+	viper.SetDefault("metrics.sqlite.rollup_1h_retention", constants.DefaultMetrics1hRetention)
 	viper.SetDefault("jwt.secret", "change-this-secret-key-in-production")
 	viper.SetDefault("jwt.expire_time", constants.DefaultJWTExpireTime)
 	viper.SetDefault("grpc.port", "9090")
+	viper.SetDefault("security.encryption_key", "change-this-encryption-key-in-production")
+	viper.SetDefault("security.secret_expiry_warning_days", constants.DefaultSecretExpiryWarningDays)
+	viper.SetDefault("agent.task_key", "change-this-task-key-in-production")
+	viper.SetDefault("agent.results_channel", constants.AgentResultsChannel)
+	viper.SetDefault("certificate.expiry_warning_days", constants.DefaultCertExpiryWarningDays)
+	viper.SetDefault("acme.directory_url", constants.DefaultACMEDirectoryURL)
+	viper.SetDefault("acme.renew_before_days", constants.DefaultACMERenewBeforeDays)
+	viper.SetDefault("acme.dns_propagation_seconds", constants.DefaultACMEDNSPropagationSec)
+	viper.SetDefault("gateway.http_addr", constants.DefaultGatewayHTTPAddr)
+	viper.SetDefault("gateway.https_addr", constants.DefaultGatewayHTTPSAddr)
+	viper.SetDefault("gateway.reload_channel", constants.GatewayReloadChannel)
+	viper.SetDefault("gateway.poll_interval", constants.DefaultGatewayPollSeconds)
+	viper.SetDefault("gateway.rate_limit_store", constants.GatewayRateLimitLocal)
+	viper.SetDefault("prometheus.enabled", true)
+	viper.SetDefault("notification.max_attempts", constants.DefaultNotificationMaxAttempts)
+	viper.SetDefault("notification.push_channel", constants.NotificationPushChannel)
+	viper.SetDefault("storage.backend", constants.BlobStoreLocal)
+	viper.SetDefault("storage.local_path", constants.DefaultStorageLocalPath)
+	viper.SetDefault("storage.max_file_size", constants.DefaultMaxFileSize)
+	viper.SetDefault("storage.s3.region", constants.DefaultS3Region)
+	viper.SetDefault("storage.s3.path_style", true)
 }
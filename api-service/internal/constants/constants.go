@@ -63,6 +63,519 @@ const (
 	DefaultGinMode       = "release"
 	DefaultLogLevel      = "info"
 	DefaultMaxHeaderSize = 1 << 20 // 1MB
+
+	DefaultSecretExpiryWarningDays = 30
+	DefaultCertExpiryWarningDays   = 30
+)
+
+// 密钥类型常量
+const (
+	SecretTypeAPIKey      = "API_KEY"
+	SecretTypeDatabase    = "DATABASE"
+	SecretTypeSSH         = "SSH"
+	SecretTypeCertificate = "CERTIFICATE"
+	SecretTypeCustom      = "CUSTOM"
+)
+
+// SSL证书相关常量
+const (
+	CertificateStatusPending = "PENDING"
+	CertificateStatusValid   = "VALID"
+	CertificateStatusExpired = "EXPIRED"
+	CertificateStatusRevoked = "REVOKED"
+	CertificateStatusFailed  = "FAILED"
+
+	CertificateTypeLetsEncrypt = "LETS_ENCRYPT"
+	CertificateTypeCommercial  = "COMMERCIAL"
+	CertificateTypeSelfSigned  = "SELF_SIGNED"
+	CertificateTypeInternal    = "INTERNAL"
+
+	CertificateCheckInterval = 24 * time.Hour
+)
+
+// 内部 CA 相关常量
+const (
+	DefaultCAKeyType        = "ECDSA_P256"
+	DefaultCAValidityDays   = 3650
+	DefaultLeafValidityDays = 365
+	MaxLeafValidityDays     = 3650
+	CABundleFilename        = "webox-ca-bundle.pem"
+)
+
+// ACME 相关常量
+const (
+	DefaultACMEDirectoryURL      = "https://acme-v02.api.letsencrypt.org/directory"
+	DefaultACMERenewBeforeDays   = 30
+	DefaultACMEDNSPropagationSec = 30
+
+	ACMEChallengeHTTP01 = "http-01"
+	ACMEChallengeDNS01  = "dns-01"
+
+	ACMEHTTPChallengeKeyPrefix = "acme:http01:"
+	ACMEHTTPChallengePath      = "/.well-known/acme-challenge/"
+	ACMEChallengeTTL           = time.Hour
+	ACMEOrderTimeout           = 10 * time.Minute
+	ACMERenewCheckInterval     = 12 * time.Hour
+
+	SystemConfigACMEAccountKey = "acme.account_key"
+)
+
+// 网关相关常量
+const (
+	GatewayReloadChannel      = "gateway:reload"
+	DefaultGatewayHTTPAddr    = ":80"
+	DefaultGatewayHTTPSAddr   = ":443"
+	DefaultGatewayPollSeconds = 30
+
+	GatewayStatusDefault = "DEFAULT"
+	GatewayStatusRunning = "RUNNING"
+	GatewayStatusStopped = "STOPPED"
+	GatewayStatusError   = "ERROR"
+
+	// 网关需要支持 WebSocket 等长连接，不设置读写超时
+	GatewayReadHeaderTimeout = 10 * time.Second
+	GatewayIdleTimeout       = 120 * time.Second
+	GatewayDialTimeout       = 10 * time.Second
+	GatewayShutdownTimeout   = 10 * time.Second
+
+	GatewayRateLimitLocal     = "local"
+	GatewayRateLimitRedis     = "redis"
+	GatewayRateLimitKeyPrefix = "gateway:ratelimit:"
+	GatewayHitFlushInterval   = 10 * time.Second
+
+	// 流量汇总按分钟写入指标库
+	GatewayTrafficRollupInterval = time.Minute
+	GatewayMaxPathsPerDomain     = 200
+	GatewayMaxPathLength         = 128
+	GatewayOtherPath             = "__other__"
+	DefaultGatewayTopPaths       = 10
+	DefaultGatewayTrafficRange   = time.Hour
+
+	MeasurementGatewayRequests = "gateway_requests"
+	MeasurementGatewayLatency  = "gateway_latency"
+)
+
+// 导出到外部反向代理的默认路径，证书文件名为 webox-cert-<ID>.crt/.key
+const (
+	ProxyExportNginxConfigDir   = "/etc/nginx/conf.d"
+	ProxyExportNginxCertDir     = "/etc/nginx/webox/certs"
+	ProxyExportTraefikConfigDir = "/etc/traefik/dynamic"
+	ProxyExportTraefikCertDir   = "/etc/traefik/webox/certs"
+	ProxyExportFilePrefix       = "webox-gateway-"
+	ProxyExportCertPrefix       = "webox-cert-"
+)
+
+// 服务器与应用监控指标，服务器按 server_id 标签、应用按 app_id 标签区分
+const (
+	MeasurementServerMetrics = "server_metrics"
+	MeasurementAppMetrics    = "app_metrics"
+
+	DefaultMetricsRange = time.Hour
+	MaxMetricsRange     = 90 * 24 * time.Hour
+	MaxMetricsPoints    = 300
+	MinMetricsWindow    = 10 * time.Second
+
+	MetricGroupCPU     = "cpu"
+	MetricGroupMemory  = "memory"
+	MetricGroupDisk    = "disk"
+	MetricGroupNetwork = "network"
+
+	MetricFieldCPUUsage  = "cpu_usage"      // 百分比
+	MetricFieldMemUsage  = "memory_usage"   // 百分比
+	MetricFieldDiskUsage = "disk_usage"     // 百分比
+	MetricFieldNetRxRate = "network_rx_bps" // 字节/秒
+	MetricFieldNetTxRate = "network_tx_bps" // 字节/秒
+	MetricFieldMemUsed   = "memory_used"    // 字节，仅容器
+	MetricFieldRunning   = "running"        // 1 为运行中，仅容器
+)
+
+// 指标存储后端，sqlite 为内置存储，按分辨率逐级降采样
+const (
+	MetricsStoreInfluxDB = "influxdb"
+	MetricsStoreSQLite   = "sqlite"
+
+	DefaultInfluxDBOrg    = "websoft9"
+	DefaultInfluxDBBucket = "metrics"
+
+	DefaultMetricsSQLitePath   = "./data/metrics.db"
+	DefaultMetricsRawRetention = 48 * time.Hour
+	DefaultMetrics5mRetention  = 30 * 24 * time.Hour
+	DefaultMetrics1hRetention  = 400 * 24 * time.Hour
+	MetricsRollup5mResolution  = 5 * time.Minute
+	MetricsRollup1hResolution  = time.Hour
+)
+
+// MetricGroupFields 查询参数 fields 中的指标分组对应的字段，顺序即返回顺序
+var MetricGroupFields = map[string][]string{
+	MetricGroupCPU:     {MetricFieldCPUUsage},
+	MetricGroupMemory:  {MetricFieldMemUsage},
+	MetricGroupDisk:    {MetricFieldDiskUsage},
+	MetricGroupNetwork: {MetricFieldNetRxRate, MetricFieldNetTxRate},
+}
+
+// MetricGroups 未指定 fields 时返回的分组
+var MetricGroups = []string{MetricGroupCPU, MetricGroupMemory, MetricGroupDisk, MetricGroupNetwork}
+
+// MetricsWindows 自动聚合窗口候选值，取使数据点不超过 MaxMetricsPoints 的最小窗口
+var MetricsWindows = []time.Duration{
+	10 * time.Second, 30 * time.Second, time.Minute, 5 * time.Minute, 15 * time.Minute,
+	30 * time.Minute, time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// GatewayLatencyBuckets 网关延迟直方图分桶边界(毫秒)
+var GatewayLatencyBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// 网关访问控制规则常量
+const (
+	AccessRuleIPWhitelist = "IP_WHITELIST"
+	AccessRuleIPBlacklist = "IP_BLACKLIST"
+	AccessRuleRateLimit   = "RATE_LIMIT"
+
+	AccessActionBlock    = "BLOCK"
+	AccessActionAllow    = "ALLOW"
+	AccessActionRedirect = "REDIRECT"
+)
+
+// 告警相关常量
+const (
+	AlertRuleTypeThreshold = "THRESHOLD"
+	AlertRuleTypeAnomaly   = "ANOMALY"
+	AlertRuleTypeCustom    = "CUSTOM"
+
+	AlertTargetCertificate = "CERTIFICATE"
+	AlertTargetServer      = "SERVER"
+	AlertTargetApplication = "APPLICATION"
+
+	AlertStatusFiring       = "FIRING"
+	AlertStatusResolved     = "RESOLVED"
+	AlertStatusAcknowledged = "ACKNOWLEDGED"
+
+	AlertMetricCertificateExpiry = "ssl_certificate_expiry"
+
+	// 阈值规则的评估间隔，min/max/last 基于 AlertEvaluationStep 窗口内的平均值计算
+	AlertEvaluationInterval = time.Minute
+	AlertEvaluationStep     = time.Minute
+	MaxAlertWindow          = 24 * time.Hour
+	MaxAlertFor             = 7 * 24 * time.Hour
+
+	// 异常检测规则：新建基线时回溯学习的最大步数，停机后追赶学习同样以此为上限
+	AnomalyTrainingSteps = 1440
+	// 回测预览的默认与最大天数
+	DefaultAnomalyPreviewDays = 7
+	MaxAnomalyPreviewDays     = 30
+)
+
+// 通知相关常量
+const (
+	NotificationTypeEmail   = "EMAIL"
+	NotificationTypeSMS     = "SMS"
+	NotificationTypeWebhook = "WEBHOOK"
+	NotificationTypePush    = "PUSH"
+
+	NotificationStatusPending = "PENDING"
+	NotificationStatusSent    = "SENT"
+	NotificationStatusFailed  = "FAILED"
+
+	NotificationTargetUser  = "USER"
+	NotificationTargetGroup = "GROUP"
+	NotificationTargetAll   = "ALL"
+
+	// Notification.Type
+	NotificationKindSystem = "SYSTEM"
+	NotificationKindUser   = "USER"
+	NotificationKindAlert  = "ALERT"
+
+	NotificationLevelInfo    = "INFO"
+	NotificationLevelWarning = "WARNING"
+	NotificationLevelError   = "ERROR"
+
+	// 内置模板
+	NotificationTemplateAlertFiring         = "alert.firing"
+	NotificationTemplateAlertResolved       = "alert.resolved"
+	NotificationTemplateDeploymentSucceeded = "deployment.succeeded"
+	NotificationTemplateDeploymentFailed    = "deployment.failed"
+
+	// NotificationRecord.ReferenceType
+	NotificationRefAlert      = "alert"
+	NotificationRefDeployment = "deployment"
+
+	// 投递失败后按 NotificationRetryBase 的指数退避重试，间隔不超过 NotificationRetryMax
+	DefaultNotificationMaxAttempts = 5
+	NotificationRetryBase          = 30 * time.Second
+	NotificationRetryMax           = time.Hour
+	NotificationPollInterval       = 10 * time.Second
+	NotificationSendTimeout        = 30 * time.Second
+	NotificationBatchSize          = 100
+
+	// 站内通知实时推送，各实例通过 NotificationPushChannel 转发给本实例的订阅者
+	NotificationPushChannel       = "notification:push"
+	NotificationStreamTicketKey   = "notification:stream-ticket:"
+	NotificationStreamTicketTTL   = 30 * time.Second
+	NotificationStreamHeartbeat   = 25 * time.Second
+	NotificationStreamBuffer      = 32
+	NotificationStreamReplayLimit = 100
+
+	// 站内通知推送事件
+	InboxEventNotification = "notification"
+	InboxEventUnreadCount  = "unread_count"
+)
+
+// 出站 Webhook 相关常量
+const (
+	// 事件目录，WebhookEventAll 订阅全部事件
+	WebhookEventAll                 = "*"
+	WebhookEventPing                = "ping"
+	WebhookEventAppDeployed         = "app.deployed"
+	WebhookEventAppFailed           = "app.failed"
+	WebhookEventAlertFiring         = "alert.firing"
+	WebhookEventAlertResolved       = "alert.resolved"
+	WebhookEventServerOnline        = "server.online"
+	WebhookEventServerOffline       = "server.offline"
+	WebhookEventCertificateExpiring = "certificate.expiring"
+
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliverySucceeded = "SUCCEEDED"
+	WebhookDeliveryFailed    = "FAILED"
+
+	WebhookHeaderEvent    = "X-Webox-Event"
+	WebhookHeaderDelivery = "X-Webox-Delivery"
+
+	DefaultWebhookTimeout    = 30 // 秒
+	MaxWebhookTimeout        = 60
+	DefaultWebhookRetryCount = 3
+	MaxWebhookRetryCount     = 10
+
+	// 投递失败后按 WebhookRetryBase 的指数退避重试，间隔不超过 WebhookRetryMax
+	WebhookRetryBase       = 30 * time.Second
+	WebhookRetryMax        = time.Hour
+	WebhookPollInterval    = 10 * time.Second
+	WebhookBatchSize       = 100
+	WebhookMaxResponseBody = 4096
+	WebhookDialTimeout     = 10 * time.Second
+)
+
+// WebhookEvents 可订阅的事件及说明
+var WebhookEvents = map[string]string{
+	WebhookEventAppDeployed:         "An application deployment succeeded",
+	WebhookEventAppFailed:           "An application deployment failed",
+	WebhookEventAlertFiring:         "An alert started firing",
+	WebhookEventAlertResolved:       "A firing alert was resolved",
+	WebhookEventServerOnline:        "A server agent started reporting heartbeats",
+	WebhookEventServerOffline:       "A server agent stopped reporting heartbeats",
+	WebhookEventCertificateExpiring: "An SSL certificate is about to expire",
+}
+
+// 工作流相关常量
+const (
+	WorkflowStatusDraft    = "DRAFT"
+	WorkflowStatusActive   = "ACTIVE"
+	WorkflowStatusInactive = "INACTIVE"
+	WorkflowStatusArchived = "ARCHIVED"
+
+	WorkflowScheduleManual   = "MANUAL"
+	WorkflowScheduleSchedule = "SCHEDULE"
+	WorkflowScheduleTrigger  = "TRIGGER"
+
+	// 错过触发时间(如 api-service 停机)的处理策略：SKIP 跳过，ONCE 补执行一次，ALL 逐次补执行
+	WorkflowCatchUpSkip = "SKIP"
+	WorkflowCatchUpOnce = "ONCE"
+	WorkflowCatchUpAll  = "ALL"
+	// ALL 策略最多补执行的次数，更早的触发时间被跳过
+	MaxWorkflowCatchUpRuns = 10
+	// 晚于触发时间该时长以内仍视为按时触发
+	WorkflowScheduleGrace = time.Minute
+
+	WorkflowTaskStatusDefault = "DEFAULT"
+	WorkflowTaskStatusOnline  = "ONLINE"
+	WorkflowTaskStatusOffline = "OFFLINE"
+
+	WorkflowTriggerManual   = "MANUAL"
+	WorkflowTriggerSchedule = "SCHEDULE"
+	WorkflowTriggerEvent    = "TRIGGER"
+
+	// TRIGGER 任务除 WebhookEvents 中的平台事件外，还可以订阅入站 Webhook
+	WorkflowEventWebhook = "webhook"
+	// 入站 Webhook 地址，后接任务的地址凭证
+	WorkflowWebhookPath = "/api/v1/workflows/hooks/"
+	// 入站 Webhook 请求体上限
+	MaxWorkflowWebhookBody = 1 << 20
+
+	// WorkflowExecution.Status
+	WorkflowExecutionPending = "PENDING"
+	WorkflowExecutionRunning = "RUNNING"
+	WorkflowExecutionSuccess = "SUCCESS"
+	WorkflowExecutionFailure = "FAILURE"
+	WorkflowExecutionStopped = "STOPPED"
+
+	// WorkflowStepRun.Status，SUCCESS、FAILURE、SKIPPED 与 workflow 包中的步骤状态一致
+	WorkflowStepPending = "PENDING"
+	WorkflowStepRunning = "RUNNING"
+	WorkflowStepSuccess = "SUCCESS"
+	WorkflowStepFailure = "FAILURE"
+	WorkflowStepSkipped = "SKIPPED"
+	WorkflowStepStopped = "STOPPED"
+
+	WorkflowPollInterval = 5 * time.Second
+	// 步骤未指定 timeout 时的超时时间(秒)
+	DefaultWorkflowStepTimeout = 600
+	// 超过步骤超时时间该时长后仍未收到结果的任务视为超时，留出结果回传的时间
+	WorkflowResultGrace = 30 * time.Second
+	// 步骤运行记录保存的命令输出上限(字节)
+	WorkflowMaxStepOutput = 64 << 10
+)
+
+// WorkflowStepTypes 工作流步骤可使用的 Agent 任务类型，
+// deploy_app、install_ca 与 proxy_config 的结果由各自的服务处理，不能用于工作流
+var WorkflowStepTypes = []string{AgentTaskSystemCommand, AgentTaskServiceManage, AgentTaskManageApp, AgentTaskFileTransfer}
+
+// RoleAdmin 管理员角色代码，可管理通知模板并向其他用户发送通知
+const RoleAdmin = "admin"
+
+// 告警静默可匹配的标签
+const (
+	AlertLabelRuleID     = "rule_id"
+	AlertLabelRuleName   = "rule_name"
+	AlertLabelRuleType   = "rule_type"
+	AlertLabelTargetType = "target_type"
+	AlertLabelTargetID   = "target_id"
+	AlertLabelMetricName = "metric_name"
+)
+
+var AlertSilenceLabels = []string{AlertLabelRuleID, AlertLabelRuleName, AlertLabelRuleType,
+	AlertLabelTargetType, AlertLabelTargetID, AlertLabelMetricName}
+
+// AlertMetricFields 阈值规则可使用的指标，表达式中的 cpu.usage 对应字段 cpu_usage
+var AlertMetricFields = map[string][]string{
+	AlertTargetServer: {MetricFieldCPUUsage, MetricFieldMemUsage, MetricFieldDiskUsage,
+		MetricFieldNetRxRate, MetricFieldNetTxRate},
+	AlertTargetApplication: {MetricFieldCPUUsage, MetricFieldMemUsage, MetricFieldMemUsed,
+		MetricFieldNetRxRate, MetricFieldNetTxRate, MetricFieldRunning},
+}
+
+// 审计日志相关常量
+const (
+	AuditModuleSecret = "SECRET"
+
+	AuditActionCreate   = "CREATE"
+	AuditActionUpdate   = "UPDATE"
+	AuditActionDelete   = "DELETE"
+	AuditActionReveal   = "REVEAL"
+	AuditActionRotate   = "ROTATE"
+	AuditActionRollback = "ROLLBACK"
+	AuditActionShare    = "SHARE"
+	AuditActionInject   = "INJECT"
+)
+
+// Agent 任务通道相关常量
+const (
+	AgentTaskChannelPrefix = "agent:"
+	AgentResultsChannel    = "agent:results"
+	// 派生 Agent 任务密钥时使用的标签前缀，后接 Agent ID
+	AgentTaskKeyLabel = "webox-agent-task:"
+
+	AgentMessageTask       = "task"
+	AgentMessageTaskResult = "task_result"
+	AgentMessageTaskLog    = "task_log"
+
+	AgentTaskDeployApp     = "deploy_app"
+	AgentTaskManageApp     = "manage_app"
+	AgentTaskInstallCA     = "install_ca"
+	AgentTaskSystemCommand = "system_command"
+	AgentTaskServiceManage = "service_manage"
+	AgentTaskFileTransfer  = "file_transfer"
+	// 写入 nginx/Traefik 配置并重新加载代理
+	AgentTaskProxyConfig = "proxy_config"
+
+	AgentStatusOnline = "ONLINE"
+
+	// Server.Status，由 Agent 心跳维护
+	ServerStatusRunning = "RUNNING"
+	ServerStatusOffline = "OFFLINE"
+)
+
+// 日志分段相关常量
+const (
+	LogTargetWorkflowExecution = "WORKFLOW_EXECUTION"
+	LogTargetDeployment        = "DEPLOYMENT"
+
+	// 分页查询的分段数
+	DefaultLogPageSize = 100
+	MaxLogPageSize     = 1000
+	// Agent 单批推送的行数上限，超出部分丢弃
+	MaxAgentTaskLogLines = 1000
+	// 实时日志轮询新分段的间隔与心跳间隔
+	LogStreamPollInterval = time.Second
+	LogStreamHeartbeat    = 25 * time.Second
+)
+
+// 用户文件相关常量
+const (
+	// UserFile.Type
+	UserFileTypeFile      = "FILE"
+	UserFileTypeDirectory = "DIRECTORY"
+
+	// 文件存储后端，s3 为兼容 S3 的对象存储
+	BlobStoreLocal = "local"
+	BlobStoreS3    = "s3"
+
+	DefaultStorageLocalPath = "./data/files"
+	DefaultS3Region         = "us-east-1"
+	// 单个文件上限，S3 单次写入最大 5GB
+	DefaultMaxFileSize = 5 << 30
+	MaxFileNameLength  = 255
+
+	// 分片上传
+	DefaultUploadChunkSize = 8 << 20
+	MinUploadChunkSize     = 1 << 20
+	MaxUploadChunkSize     = 64 << 20
+	// 上传会话在最后一次上传分片后的有效期，过期的会话与分片被清理
+	UploadSessionTTL      = 24 * time.Hour
+	UploadCleanupInterval = time.Hour
+	UploadCleanupBatch    = 100
+
+	// FileUpload.Status
+	FileUploadUploading  = "UPLOADING"
+	FileUploadCompleting = "COMPLETING"
+
+	// MIME 类型识别读取的文件头长度
+	MimeSniffLength = 512
+)
+
+// Agent gRPC 通道，消息以 JSON 编码，载荷与任务通道一样使用任务密钥加密
+const (
+	AgentRPCService = "webox.agent.v1.AgentService"
+	AgentRPCCodec   = "json"
+	// 载荷中的发送时间与服务端时间相差超过该值时拒绝，限制重放窗口
+	AgentMessageMaxSkew  = 5 * time.Minute
+	AgentMaxMetricPoints = 5000
+	// 最近一次心跳在该时长内的 Agent 视为在线，Agent 默认心跳间隔为 30 秒
+	AgentConnectedWindow = 90 * time.Second
+	// 检查服务器是否离线的间隔，超过 AgentConnectedWindow 没有心跳的运行中服务器标记为离线
+	ServerPresenceInterval = 30 * time.Second
+)
+
+// Prometheus 运行指标
+const (
+	TelemetryNamespace = "webox"
+	TelemetryPath      = "/metrics"
+	// 未匹配任何路由的请求使用的 route 标签，避免任意路径产生大量序列
+	TelemetryUnmatchedRoute = "unmatched"
+)
+
+// Agent 上报指标的标签，server_id、agent_id 与 app_id 由服务端根据 Agent 身份设置
+const (
+	MetricTagServerID       = "server_id"
+	MetricTagAgentID        = "agent_id"
+	MetricTagAppID          = "app_id"
+	MetricTagContainerName  = "container_name"
+	MetricTagComposeProject = "compose_project"
+)
+
+// 应用实例状态常量
+const (
+	AppInstanceStatusDeployment = "DEPLOYMENT"
+	AppInstanceStatusRunning    = "RUNNING"
+	AppInstanceStatusStopped    = "STOPPED"
 )
 
 // 测试数据常量
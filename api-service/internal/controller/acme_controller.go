@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ACMEController struct {
+	acmeService service.ACMEService
+}
+
+func NewACMEController(acmeService service.ACMEService) *ACMEController {
+	return &ACMEController{
+		acmeService: acmeService,
+	}
+}
+
+type ACMECertificateRequest struct {
+	Name          string   `json:"name"`
+	Domains       []string `json:"domains" binding:"required,min=1"`
+	ChallengeType string   `json:"challenge_type"`
+	DNSProvider   string   `json:"dns_provider"`
+	AutoRenew     *bool    `json:"auto_renew"`
+}
+
+// RequestCertificate 通过 ACME 申请证书，签发在后台进行，可通过证书状态查询结果
+func (c *ACMEController) RequestCertificate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req ACMECertificateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	// 默认开启自动续期
+	autoRenew := true
+	if req.AutoRenew != nil {
+		autoRenew = *req.AutoRenew
+	}
+
+	cert, err := c.acmeService.RequestCertificate(userID, &service.ACMERequest{
+		Name:          req.Name,
+		Domains:       req.Domains,
+		ChallengeType: req.ChallengeType,
+		DNSProvider:   req.DNSProvider,
+		AutoRenew:     autoRenew,
+	})
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to request certificate", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate order submitted", cert)
+}
+
+func (c *ACMEController) RenewCertificate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid certificate ID")
+	if !ok {
+		return
+	}
+
+	cert, err := c.acmeService.RenewCertificate(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to renew certificate", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate renewal submitted", cert)
+}
+
+// HTTPChallenge 响应 ACME HTTP-01 质询，返回纯文本 key authorization
+func (c *ACMEController) HTTPChallenge(ctx *gin.Context) {
+	keyAuth, err := c.acmeService.HTTPChallengeResponse(ctx.Request.Context(), ctx.Param("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			ctx.String(http.StatusNotFound, "not found")
+			return
+		}
+		ctx.String(http.StatusInternalServerError, "challenge lookup failed")
+		return
+	}
+
+	ctx.String(http.StatusOK, keyAuth)
+}
@@ -0,0 +1,494 @@
+package controller
+
+import (
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AlertController struct {
+	alertService service.AlertService
+}
+
+func NewAlertController(alertService service.AlertService) *AlertController {
+	return &AlertController{
+		alertService: alertService,
+	}
+}
+
+// AlertRuleRequest 阈值或异常检测规则参数，enabled 默认为 true
+type AlertRuleRequest struct {
+	Name                 string   `json:"name" binding:"required"`
+	RuleType             string   `json:"rule_type"`
+	TargetType           string   `json:"target_type" binding:"required"`
+	TargetID             uint     `json:"target_id" binding:"required"`
+	ConditionExpression  string   `json:"condition_expression" binding:"required"`
+	NotificationChannels []string `json:"notification_channels"`
+	Enabled              *bool    `json:"enabled"`
+}
+
+// AlertRulePreviewRequest 异常检测规则回测参数，days 默认为 7
+type AlertRulePreviewRequest struct {
+	TargetType          string `json:"target_type" binding:"required"`
+	TargetID            uint   `json:"target_id" binding:"required"`
+	ConditionExpression string `json:"condition_expression" binding:"required"`
+	Days                int    `json:"days"`
+}
+
+// AlertNoteRequest 确认或恢复告警时的备注
+type AlertNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// SilenceRequest 静默参数，starts_at 缺省时立即生效
+type SilenceRequest struct {
+	Matchers map[string]string `json:"matchers" binding:"required"`
+	Comment  string            `json:"comment"`
+	StartsAt *time.Time        `json:"starts_at"`
+	EndsAt   time.Time         `json:"ends_at" binding:"required"`
+}
+
+// MaintenanceWindowRequest 维护窗口参数，enabled 默认为 true
+type MaintenanceWindowRequest struct {
+	Name            string `json:"name" binding:"required"`
+	ServerID        *uint  `json:"server_id"`
+	ResourceGroupID *uint  `json:"resource_group_id"`
+	Weekdays        string `json:"weekdays"`
+	StartTime       string `json:"start_time" binding:"required"`
+	DurationMinutes int    `json:"duration_minutes" binding:"required"`
+	Timezone        string `json:"timezone"`
+	Enabled         *bool  `json:"enabled"`
+	Description     string `json:"description"`
+}
+
+func (r *MaintenanceWindowRequest) input() *service.MaintenanceWindowInput {
+	return &service.MaintenanceWindowInput{
+		Name:            r.Name,
+		ServerID:        r.ServerID,
+		ResourceGroupID: r.ResourceGroupID,
+		Weekdays:        r.Weekdays,
+		StartTime:       r.StartTime,
+		DurationMinutes: r.DurationMinutes,
+		Timezone:        r.Timezone,
+		Enabled:         r.Enabled == nil || *r.Enabled,
+		Description:     r.Description,
+	}
+}
+
+func (r *AlertRuleRequest) input() *service.AlertRuleInput {
+	return &service.AlertRuleInput{
+		Name:                 r.Name,
+		RuleType:             r.RuleType,
+		TargetType:           r.TargetType,
+		TargetID:             r.TargetID,
+		ConditionExpression:  r.ConditionExpression,
+		NotificationChannels: r.NotificationChannels,
+		Enabled:              r.Enabled == nil || *r.Enabled,
+	}
+}
+
+func (c *AlertController) CreateRule(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req AlertRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	rule, err := c.alertService.CreateRule(userID, req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create alert rule", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Alert rule created successfully", rule)
+}
+
+// PreviewRule 用最近若干天的指标回测异常检测规则，返回期间会产生的告警
+func (c *AlertController) PreviewRule(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req AlertRulePreviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	preview, err := c.alertService.PreviewRule(userID, &service.AlertRuleInput{
+		TargetType:          req.TargetType,
+		TargetID:            req.TargetID,
+		ConditionExpression: req.ConditionExpression,
+	}, req.Days)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to preview alert rule", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Alert rule preview generated successfully", preview)
+}
+
+func (c *AlertController) ListRules(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	rules, err := c.alertService.ListRules(userID)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get alert rules", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Alert rules retrieved successfully", gin.H{
+		"rules": rules,
+		"total": len(rules),
+	})
+}
+
+func (c *AlertController) GetRule(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid alert rule ID")
+	if !ok {
+		return
+	}
+
+	rule, err := c.alertService.GetRule(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get alert rule", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Alert rule retrieved successfully", rule)
+}
+
+func (c *AlertController) UpdateRule(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid alert rule ID")
+	if !ok {
+		return
+	}
+
+	var req AlertRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	rule, err := c.alertService.UpdateRule(userID, id, req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to update alert rule", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Alert rule updated successfully", rule)
+}
+
+func (c *AlertController) DeleteRule(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid alert rule ID")
+	if !ok {
+		return
+	}
+
+	if err := c.alertService.DeleteRule(userID, id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete alert rule", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Alert rule deleted successfully", nil)
+}
+
+// ListRecords 查询告警记录，支持 status、rule_id、suppressed 与 start/end(RFC3339，按触发时间)过滤
+func (c *AlertController) ListRecords(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	query := &service.AlertRecordQuery{Status: ctx.Query("status")}
+	if raw := ctx.Query("rule_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid rule_id", err.Error())
+			return
+		}
+		query.AlertRuleID = uint(id)
+	}
+	if raw := ctx.Query("suppressed"); raw != "" {
+		suppressed, err := strconv.ParseBool(raw)
+		if err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid suppressed", err.Error())
+			return
+		}
+		query.Suppressed = &suppressed
+	}
+	for name, dst := range map[string]*time.Time{"start": &query.Start, "end": &query.End} {
+		if raw := ctx.Query(name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				response.Error(ctx, http.StatusBadRequest, "Invalid "+name+" time", err.Error())
+				return
+			}
+			*dst = t
+		}
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+
+	records, total, err := c.alertService.ListRecords(userID, query, page, pageSize)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get alerts", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Alerts retrieved successfully", gin.H{
+		"records":   records,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+func (c *AlertController) GetRecord(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid alert ID")
+	if !ok {
+		return
+	}
+
+	record, err := c.alertService.GetRecord(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get alert", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Alert retrieved successfully", record)
+}
+
+func (c *AlertController) AcknowledgeRecord(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid alert ID")
+	if !ok {
+		return
+	}
+
+	// 请求体可省略
+	var req AlertNoteRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	record, err := c.alertService.AcknowledgeRecord(userID, id, req.Note)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to acknowledge alert", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Alert acknowledged successfully", record)
+}
+
+func (c *AlertController) ResolveRecord(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid alert ID")
+	if !ok {
+		return
+	}
+
+	// 请求体可省略
+	var req AlertNoteRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	record, err := c.alertService.ResolveRecord(userID, id, req.Note)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to resolve alert", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Alert resolved successfully", record)
+}
+
+func (c *AlertController) CreateSilence(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req SilenceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	input := &service.SilenceInput{
+		Matchers: req.Matchers,
+		Comment:  req.Comment,
+		EndsAt:   req.EndsAt,
+	}
+	if req.StartsAt != nil {
+		input.StartsAt = *req.StartsAt
+	}
+
+	silence, err := c.alertService.CreateSilence(userID, input)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create silence", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Silence created successfully", silence)
+}
+
+func (c *AlertController) ListSilences(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	silences, err := c.alertService.ListSilences(userID)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get silences", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Silences retrieved successfully", gin.H{
+		"silences": silences,
+		"total":    len(silences),
+	})
+}
+
+func (c *AlertController) DeleteSilence(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid silence ID")
+	if !ok {
+		return
+	}
+
+	if err := c.alertService.DeleteSilence(userID, id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete silence", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Silence deleted successfully", nil)
+}
+
+func (c *AlertController) CreateMaintenanceWindow(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req MaintenanceWindowRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	window, err := c.alertService.CreateMaintenanceWindow(userID, req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create maintenance window", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Maintenance window created successfully", window)
+}
+
+func (c *AlertController) ListMaintenanceWindows(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	windows, err := c.alertService.ListMaintenanceWindows(userID)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get maintenance windows", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Maintenance windows retrieved successfully", gin.H{
+		"maintenance_windows": windows,
+		"total":               len(windows),
+	})
+}
+
+func (c *AlertController) UpdateMaintenanceWindow(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid maintenance window ID")
+	if !ok {
+		return
+	}
+
+	var req MaintenanceWindowRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	window, err := c.alertService.UpdateMaintenanceWindow(userID, id, req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to update maintenance window", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Maintenance window updated successfully", window)
+}
+
+func (c *AlertController) DeleteMaintenanceWindow(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid maintenance window ID")
+	if !ok {
+		return
+	}
+
+	if err := c.alertService.DeleteMaintenanceWindow(userID, id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete maintenance window", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Maintenance window deleted successfully", nil)
+}
@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CAController struct {
+	caService service.CAService
+}
+
+func NewCAController(caService service.CAService) *CAController {
+	return &CAController{
+		caService: caService,
+	}
+}
+
+type CreateCARequest struct {
+	Name         string `json:"name" binding:"required"`
+	CommonName   string `json:"common_name"`
+	Organization string `json:"organization"`
+	KeyType      string `json:"key_type"`
+	ValidityDays int    `json:"validity_days"`
+	Description  string `json:"description"`
+}
+
+// IssueCertificateRequest 签发内部证书参数，未指定 ca_id 时生成自签名证书
+type IssueCertificateRequest struct {
+	Name         string   `json:"name"`
+	CAID         *uint    `json:"ca_id"`
+	CommonName   string   `json:"common_name"`
+	DNSNames     []string `json:"dns_names"`
+	IPAddresses  []string `json:"ip_addresses"`
+	KeyType      string   `json:"key_type"`
+	ValidityDays int      `json:"validity_days"`
+	Usages       []string `json:"usages"`
+}
+
+type InstallCARequest struct {
+	ServerIDs []uint `json:"server_ids" binding:"required"`
+}
+
+func (c *CAController) CreateCA(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req CreateCARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	ca, err := c.caService.CreateCA(userID, &service.CAInput{
+		Name:         req.Name,
+		CommonName:   req.CommonName,
+		Organization: req.Organization,
+		KeyType:      req.KeyType,
+		ValidityDays: req.ValidityDays,
+		Description:  req.Description,
+	})
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create certificate authority", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate authority created successfully", ca)
+}
+
+func (c *CAController) ListCAs(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	cas, err := c.caService.ListCAs(userID)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get certificate authorities", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate authorities retrieved successfully", gin.H{
+		"certificate_authorities": cas,
+		"total":                   len(cas),
+	})
+}
+
+func (c *CAController) GetCA(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid certificate authority ID")
+	if !ok {
+		return
+	}
+
+	ca, err := c.caService.GetCA(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get certificate authority", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate authority retrieved successfully", ca)
+}
+
+func (c *CAController) DeleteCA(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid certificate authority ID")
+	if !ok {
+		return
+	}
+
+	if err := c.caService.DeleteCA(userID, id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete certificate authority", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate authority deleted successfully", nil)
+}
+
+// ExportBundle 下载 CA 证书 PEM 包，可通过 ids 参数(逗号分隔)指定 CA
+func (c *CAController) ExportBundle(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var ids []uint
+	if raw := ctx.Query("ids"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+			if err != nil {
+				response.Error(ctx, http.StatusBadRequest, "Invalid certificate authority ID", err.Error())
+				return
+			}
+			ids = append(ids, uint(id))
+		}
+	}
+
+	c.sendBundle(ctx, userID, ids)
+}
+
+// ExportCABundle 下载单个 CA 的证书
+func (c *CAController) ExportCABundle(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid certificate authority ID")
+	if !ok {
+		return
+	}
+
+	c.sendBundle(ctx, userID, []uint{id})
+}
+
+func (c *CAController) InstallCA(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid certificate authority ID")
+	if !ok {
+		return
+	}
+
+	var req InstallCARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	taskIDs, err := c.caService.InstallCA(userID, id, req.ServerIDs)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to install certificate authority", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate authority install tasks dispatched", gin.H{
+		"task_ids": taskIDs,
+		"total":    len(taskIDs),
+	})
+}
+
+func (c *CAController) IssueCertificate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req IssueCertificateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	cert, err := c.caService.IssueCertificate(userID, &service.IssueCertificateInput{
+		Name:         req.Name,
+		CAID:         req.CAID,
+		CommonName:   req.CommonName,
+		DNSNames:     req.DNSNames,
+		IPAddresses:  req.IPAddresses,
+		KeyType:      req.KeyType,
+		ValidityDays: req.ValidityDays,
+		Usages:       req.Usages,
+	})
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to issue certificate", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate issued successfully", cert)
+}
+
+func (c *CAController) sendBundle(ctx *gin.Context, userID uint, ids []uint) {
+	bundle, err := c.caService.ExportBundle(userID, ids)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to export certificate authority bundle", err.Error())
+		return
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename="+constants.CABundleFilename)
+	ctx.Data(http.StatusOK, "application/x-pem-file", bundle)
+}
@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CertificateController struct {
+	certificateService service.CertificateService
+}
+
+func NewCertificateController(certificateService service.CertificateService) *CertificateController {
+	return &CertificateController{
+		certificateService: certificateService,
+	}
+}
+
+// CertificateRequest 证书上传参数，支持 JSON 或 multipart 表单(PEM 文件)
+type CertificateRequest struct {
+	Name            string `json:"name" form:"name"`
+	CertificateType string `json:"certificate_type" form:"certificate_type"`
+	Certificate     string `json:"certificate" form:"-"`
+	PrivateKey      string `json:"private_key" form:"-"`
+	Chain           string `json:"chain" form:"-"`
+	AutoRenew       bool   `json:"auto_renew" form:"auto_renew"`
+}
+
+func (c *CertificateController) UploadCertificate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	input, err := bindCertificateRequest(ctx)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	cert, err := c.certificateService.UploadCertificate(userID, input)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to upload certificate", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate uploaded successfully", cert)
+}
+
+func (c *CertificateController) ListCertificates(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	certs, err := c.certificateService.ListCertificates(userID, ctx.Query("status"))
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get certificates", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificates retrieved successfully", gin.H{
+		"certificates": certs,
+		"total":        len(certs),
+	})
+}
+
+func (c *CertificateController) ListExpiringCertificates(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	days, _ := strconv.Atoi(ctx.DefaultQuery("days", "0"))
+	certs, err := c.certificateService.ListExpiringCertificates(userID, days)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get expiring certificates", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Expiring certificates retrieved successfully", gin.H{
+		"certificates": certs,
+		"total":        len(certs),
+	})
+}
+
+func (c *CertificateController) GetCertificate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid certificate ID")
+	if !ok {
+		return
+	}
+
+	cert, err := c.certificateService.GetCertificate(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get certificate", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate retrieved successfully", cert)
+}
+
+func (c *CertificateController) UpdateCertificate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid certificate ID")
+	if !ok {
+		return
+	}
+
+	input, err := bindCertificateRequest(ctx)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	cert, err := c.certificateService.UpdateCertificate(userID, id, input)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to update certificate", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate updated successfully", cert)
+}
+
+func (c *CertificateController) DeleteCertificate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid certificate ID")
+	if !ok {
+		return
+	}
+
+	if err := c.certificateService.DeleteCertificate(userID, id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete certificate", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Certificate deleted successfully", nil)
+}
+
+// bindCertificateRequest 解析证书上传参数，multipart 表单中的 PEM 可以是文件或文本字段
+func bindCertificateRequest(ctx *gin.Context) (*service.CertificateInput, error) {
+	var req CertificateRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return nil, err
+	}
+
+	if ctx.ContentType() == gin.MIMEMultipartPOSTForm {
+		fields := map[string]*string{
+			"certificate": &req.Certificate,
+			"private_key": &req.PrivateKey,
+			"chain":       &req.Chain,
+		}
+		for field, target := range fields {
+			data, err := readFormFile(ctx, field)
+			if err != nil {
+				return nil, err
+			}
+			if data == "" {
+				data = ctx.PostForm(field)
+			}
+			*target = data
+		}
+	}
+
+	if req.Certificate == "" || req.PrivateKey == "" {
+		return nil, errors.New("certificate and private_key are required")
+	}
+
+	return &service.CertificateInput{
+		Name:            req.Name,
+		CertificateType: req.CertificateType,
+		Certificate:     req.Certificate,
+		PrivateKey:      req.PrivateKey,
+		Chain:           req.Chain,
+		AutoRenew:       req.AutoRenew,
+	}, nil
+}
+
+func readFormFile(ctx *gin.Context, field string) (string, error) {
+	header, err := ctx.FormFile(field)
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			return "", nil
+		}
+		return "", err
+	}
+	if header.Size > constants.DefaultMaxHeaderSize {
+		return "", errors.New(field + " file is too large")
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUserID 获取 JWT 中间件写入的用户ID，失败时直接返回 401
+func currentUserID(ctx *gin.Context) (uint, bool) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.Error(ctx, http.StatusUnauthorized, "Unauthorized", "User ID not found")
+		return 0, false
+	}
+	return userID.(uint), true
+}
+
+// parseIDParam 解析路径中的数字ID参数，失败时直接返回 400
+func parseIDParam(ctx *gin.Context, name, message string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(name), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, message, err.Error())
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseTimeRange 解析 start/end 查询参数(RFC3339)，缺省时取截至当前的 defaultRange，失败时直接返回 400
+func parseTimeRange(ctx *gin.Context, defaultRange time.Duration) (time.Time, time.Time, bool) {
+	end := time.Now()
+	if raw := ctx.Query("end"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid end time", err.Error())
+			return time.Time{}, time.Time{}, false
+		}
+		end = t
+	}
+
+	start := end.Add(-defaultRange)
+	if raw := ctx.Query("start"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid start time", err.Error())
+			return time.Time{}, time.Time{}, false
+		}
+		start = t
+	}
+	return start, end, true
+}
+
+// auditMeta 从请求中提取审计信息
+func auditMeta(ctx *gin.Context) *service.AuditMeta {
+	return &service.AuditMeta{
+		Username:      ctx.GetString("username"),
+		IPAddress:     ctx.ClientIP(),
+		UserAgent:     ctx.Request.UserAgent(),
+		RequestMethod: ctx.Request.Method,
+		RequestURL:    ctx.Request.URL.Path,
+	}
+}
+
+// serviceErrorStatus 将 service 层错误映射为 HTTP 状态码，未标记的错误(数据库、外部服务等)返回 500
+func serviceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidInput):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"api-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestServiceErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{service.ErrNotFound, http.StatusNotFound},
+		{fmt.Errorf("server 1: %w", service.ErrPermissionDenied), http.StatusForbidden},
+		{fmt.Errorf("step a: %w", service.ErrInvalidInput), http.StatusBadRequest},
+		{gorm.ErrInvalidDB, http.StatusInternalServerError},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := serviceErrorStatus(tt.err); got != tt.want {
+			t.Errorf("serviceErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
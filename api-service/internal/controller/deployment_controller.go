@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeploymentController struct {
+	deploymentService service.DeploymentService
+}
+
+func NewDeploymentController(deploymentService service.DeploymentService) *DeploymentController {
+	return &DeploymentController{
+		deploymentService: deploymentService,
+	}
+}
+
+type CreateDeploymentRequest struct {
+	ServerID      uint                   `json:"server_id" binding:"required"`
+	TemplateID    *uint                  `json:"template_id"`
+	AppInstanceID *uint                  `json:"app_instance_id"`
+	ProjectName   string                 `json:"project_name" binding:"required"`
+	Inputs        map[string]string      `json:"inputs"`
+	ConfigData    map[string]interface{} `json:"config_data"`
+}
+
+type RedeployRequest struct {
+	InstanceIDs []uint `json:"instance_ids"`
+}
+
+func (c *DeploymentController) CreateDeployment(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req CreateDeploymentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	deployment, err := c.deploymentService.CreateDeployment(userID, &service.DeploymentInput{
+		ServerID:      req.ServerID,
+		TemplateID:    req.TemplateID,
+		AppInstanceID: req.AppInstanceID,
+		ProjectName:   req.ProjectName,
+		Inputs:        req.Inputs,
+		ConfigData:    req.ConfigData,
+	}, auditMeta(ctx))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create deployment", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Deployment created successfully", deployment)
+}
+
+func (c *DeploymentController) GetDeployment(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid deployment ID")
+	if !ok {
+		return
+	}
+
+	deployment, err := c.deploymentService.GetDeployment(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get deployment", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Deployment retrieved successfully", deployment)
+}
+
+// ListLogs 分页返回部署日志
+func (c *DeploymentController) ListLogs(ctx *gin.Context) {
+	if fetch, ok := c.logFetcher(ctx); ok {
+		listLogs(ctx, fetch, "Failed to get deployment logs")
+	}
+}
+
+// StreamLogs 以 Server-Sent Events 实时推送部署日志
+func (c *DeploymentController) StreamLogs(ctx *gin.Context) {
+	if fetch, ok := c.logFetcher(ctx); ok {
+		streamLogs(ctx, fetch, "Failed to get deployment logs")
+	}
+}
+
+func (c *DeploymentController) logFetcher(ctx *gin.Context) (logFetcher, bool) {
+	id, ok := parseIDParam(ctx, "id", "Invalid deployment ID")
+	if !ok {
+		return nil, false
+	}
+	return func(userID, afterID uint, limit int) (*service.LogPage, error) {
+		return c.deploymentService.ListLogs(userID, id, afterID, limit)
+	}, true
+}
+
+func (c *DeploymentController) ListDeployments(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+
+	deployments, total, err := c.deploymentService.ListDeployments(userID, page, pageSize)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get deployments", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Deployments retrieved successfully", gin.H{
+		"deployments": deployments,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+	})
+}
+
+func (c *DeploymentController) ListSecretUsages(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid secret ID")
+	if !ok {
+		return
+	}
+
+	instances, err := c.deploymentService.ListSecretUsages(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get secret usages", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Secret usages retrieved successfully", gin.H{
+		"app_instances": instances,
+		"total":         len(instances),
+	})
+}
+
+func (c *DeploymentController) RedeployBySecret(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid secret ID")
+	if !ok {
+		return
+	}
+
+	var req RedeployRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	deployments, err := c.deploymentService.RedeployBySecret(userID, id, req.InstanceIDs, auditMeta(ctx))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to redeploy app instances", err.Error())
+		return
+	}
+
+	response.Success(ctx, "App instances redeployed successfully", gin.H{
+		"deployments": deployments,
+		"total":       len(deployments),
+	})
+}
@@ -0,0 +1,367 @@
+package controller
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead 表单上传时请求体中除文件内容外的部分
+const multipartOverhead = 1 << 20
+
+type FileController struct {
+	fileService service.FileService
+}
+
+func NewFileController(fileService service.FileService) *FileController {
+	return &FileController{
+		fileService: fileService,
+	}
+}
+
+// CreateDirectoryRequest 创建目录参数，parent_id 为空时创建在根目录
+type CreateDirectoryRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+type RenameFileRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// MoveFileRequest 移动参数，parent_id 为空时移动到根目录
+type MoveFileRequest struct {
+	ParentID *uint `json:"parent_id"`
+}
+
+// CreateUploadRequest 分片上传参数，chunk_size 为空时为 8MB，checksum 为可选的 SHA-256
+type CreateUploadRequest struct {
+	Name      string `json:"name" binding:"required"`
+	ParentID  *uint  `json:"parent_id"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk_size"`
+	Checksum  string `json:"checksum"`
+}
+
+func (c *FileController) ListFiles(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	parentID, ok := parseIDQuery(ctx, "parent_id")
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+
+	files, total, err := c.fileService.List(userID, parentID, page, pageSize)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get files", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Files retrieved successfully", gin.H{
+		"files":     files,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+func (c *FileController) GetFile(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid file ID")
+	if !ok {
+		return
+	}
+
+	file, err := c.fileService.Get(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get file", err.Error())
+		return
+	}
+
+	response.Success(ctx, "File retrieved successfully", file)
+}
+
+func (c *FileController) CreateDirectory(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req CreateDirectoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	dir, err := c.fileService.CreateDirectory(userID, req.ParentID, req.Name)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create directory", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Directory created successfully", dir)
+}
+
+// UploadFile 以 multipart/form-data 上传文件，表单字段 file 为文件内容，可选 parent_id、name(默认为文件名)与 checksum
+func (c *FileController) UploadFile(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.fileService.MaxFileSize()+multipartOverhead)
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		response.Error(ctx, status, "Invalid file", err.Error())
+		return
+	}
+	var parentID *uint
+	if raw := ctx.PostForm("parent_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid parent_id", err.Error())
+			return
+		}
+		v := uint(id)
+		parentID = &v
+	}
+	name := ctx.PostForm("name")
+	if name == "" {
+		name = header.Filename
+	}
+
+	content, err := header.Open()
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid file", err.Error())
+		return
+	}
+	defer content.Close()
+
+	file, err := c.fileService.Upload(userID, parentID, name, content, header.Size, strings.ToLower(ctx.PostForm("checksum")))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to upload file", err.Error())
+		return
+	}
+
+	response.Success(ctx, "File uploaded successfully", file)
+}
+
+func (c *FileController) RenameFile(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid file ID")
+	if !ok {
+		return
+	}
+
+	var req RenameFileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	file, err := c.fileService.Rename(userID, id, req.Name)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to rename file", err.Error())
+		return
+	}
+
+	response.Success(ctx, "File renamed successfully", file)
+}
+
+func (c *FileController) MoveFile(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid file ID")
+	if !ok {
+		return
+	}
+
+	var req MoveFileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	file, err := c.fileService.Move(userID, id, req.ParentID)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to move file", err.Error())
+		return
+	}
+
+	response.Success(ctx, "File moved successfully", file)
+}
+
+func (c *FileController) DeleteFile(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid file ID")
+	if !ok {
+		return
+	}
+
+	if err := c.fileService.Delete(userID, id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete file", err.Error())
+		return
+	}
+
+	response.Success(ctx, "File deleted successfully", nil)
+}
+
+// DownloadFile 下载文件，支持 Range 与 If-None-Match(ETag 为 SHA-256)；从头开始且未命中缓存的下载计入下载次数
+func (c *FileController) DownloadFile(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid file ID")
+	if !ok {
+		return
+	}
+
+	file, content, err := c.fileService.Open(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to download file", err.Error())
+		return
+	}
+	defer content.Close()
+
+	etag := `"` + file.Checksum + `"`
+	rangeHeader := ctx.GetHeader("Range")
+	if (rangeHeader == "" || strings.HasPrefix(rangeHeader, "bytes=0-")) && ctx.GetHeader("If-None-Match") != etag {
+		c.fileService.CountDownload(file)
+	}
+
+	ctx.Header("Content-Type", file.MimeType)
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("ETag", etag)
+	http.ServeContent(ctx.Writer, ctx.Request, file.Name, file.UpdatedAt, content)
+}
+
+func (c *FileController) CreateUpload(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req CreateUploadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	session, err := c.fileService.CreateUpload(userID, &service.UploadInput{
+		ParentID:  req.ParentID,
+		Name:      req.Name,
+		Size:      req.Size,
+		ChunkSize: req.ChunkSize,
+		Checksum:  strings.ToLower(req.Checksum),
+	})
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create upload", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Upload created successfully", session)
+}
+
+// GetUpload 查询上传会话与已上传的分片，用于断点续传
+func (c *FileController) GetUpload(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	session, err := c.fileService.GetUpload(userID, ctx.Param("upload_id"))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get upload", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Upload retrieved successfully", session)
+}
+
+// UploadChunk 上传分片，请求体为分片内容，须携带 Content-Length
+func (c *FileController) UploadChunk(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(ctx.Param("index"))
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid chunk index", err.Error())
+		return
+	}
+	if ctx.Request.ContentLength < 0 {
+		response.Error(ctx, http.StatusLengthRequired, "Content-Length is required", "")
+		return
+	}
+	if ctx.Request.ContentLength > constants.MaxUploadChunkSize {
+		response.Error(ctx, http.StatusRequestEntityTooLarge, "Chunk is too large", "")
+		return
+	}
+
+	err = c.fileService.UploadChunk(userID, ctx.Param("upload_id"), index, ctx.Request.Body, ctx.Request.ContentLength)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to upload chunk", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Chunk uploaded successfully", gin.H{"index": index})
+}
+
+func (c *FileController) CompleteUpload(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	file, err := c.fileService.CompleteUpload(userID, ctx.Param("upload_id"))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to complete upload", err.Error())
+		return
+	}
+
+	response.Success(ctx, "File uploaded successfully", file)
+}
+
+func (c *FileController) AbortUpload(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.fileService.AbortUpload(userID, ctx.Param("upload_id")); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to abort upload", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Upload aborted successfully", nil)
+}
@@ -0,0 +1,457 @@
+package controller
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/service"
+	"api-service/pkg/proxyconf"
+	"api-service/pkg/response"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GatewayController struct {
+	gatewayService service.GatewayService
+}
+
+func NewGatewayController(gatewayService service.GatewayService) *GatewayController {
+	return &GatewayController{
+		gatewayService: gatewayService,
+	}
+}
+
+type GatewayRequest struct {
+	Name        string `json:"name"`
+	ServerID    uint   `json:"server_id"`
+	Description string `json:"description"`
+}
+
+// PublishRequest 发布参数，audit_log_enabled 默认为 true
+type PublishRequest struct {
+	AppInstanceID   uint   `json:"app_instance_id" binding:"required"`
+	ServiceDomain   string `json:"service_domain" binding:"required"`
+	ServicePort     int    `json:"service_port" binding:"required"`
+	UpstreamHost    string `json:"upstream_host"`
+	CertificateID   *uint  `json:"certificate_id"`
+	AuditLogEnabled *bool  `json:"audit_log_enabled"`
+}
+
+// ProxyDeployRequest 代理配置下发参数，目录为空时使用默认目录
+type ProxyDeployRequest struct {
+	Format    string `json:"format" binding:"required"`
+	ConfigDir string `json:"config_dir"`
+	CertDir   string `json:"cert_dir"`
+}
+
+// AccessRuleRequest 访问控制规则参数，enabled 默认为 true
+type AccessRuleRequest struct {
+	RuleName    string `json:"rule_name" binding:"required"`
+	RuleType    string `json:"rule_type" binding:"required"`
+	LimitCount  int    `json:"limit_count"`
+	TimeWindow  int    `json:"time_window"`
+	TargetPath  string `json:"target_path"`
+	TargetIP    string `json:"target_ip"`
+	Action      string `json:"action"`
+	RedirectURL string `json:"redirect_url"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+func (c *GatewayController) CreateGateway(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req GatewayRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+	if req.Name == "" || req.ServerID == 0 {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", "name and server_id are required")
+		return
+	}
+
+	gateway, err := c.gatewayService.CreateGateway(userID, &service.GatewayInput{
+		Name:        req.Name,
+		ServerID:    req.ServerID,
+		Description: req.Description,
+	})
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create gateway", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Gateway created successfully", gateway)
+}
+
+func (c *GatewayController) ListGateways(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	gateways, err := c.gatewayService.ListGateways(userID)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get gateways", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Gateways retrieved successfully", gin.H{
+		"gateways": gateways,
+		"total":    len(gateways),
+	})
+}
+
+func (c *GatewayController) GetGateway(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+
+	gateway, err := c.gatewayService.GetGateway(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get gateway", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Gateway retrieved successfully", gateway)
+}
+
+func (c *GatewayController) UpdateGateway(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+
+	var req GatewayRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	gateway, err := c.gatewayService.UpdateGateway(userID, id, &service.GatewayInput{
+		Name:        req.Name,
+		ServerID:    req.ServerID,
+		Description: req.Description,
+	})
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to update gateway", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Gateway updated successfully", gateway)
+}
+
+func (c *GatewayController) DeleteGateway(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+
+	if err := c.gatewayService.DeleteGateway(userID, id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete gateway", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Gateway deleted successfully", nil)
+}
+
+func (c *GatewayController) CreatePublish(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+
+	var req PublishRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	publish, err := c.gatewayService.CreatePublish(userID, id, publishInput(&req))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to publish app instance", err.Error())
+		return
+	}
+
+	response.Success(ctx, "App instance published successfully", publish)
+}
+
+func (c *GatewayController) UpdatePublish(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+	publishID, ok := parseIDParam(ctx, "publish_id", "Invalid publish ID")
+	if !ok {
+		return
+	}
+
+	var req PublishRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	publish, err := c.gatewayService.UpdatePublish(userID, id, publishID, publishInput(&req))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to update publish", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Publish updated successfully", publish)
+}
+
+func (c *GatewayController) DeletePublish(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+	publishID, ok := parseIDParam(ctx, "publish_id", "Invalid publish ID")
+	if !ok {
+		return
+	}
+
+	if err := c.gatewayService.DeletePublish(userID, id, publishID); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete publish", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Publish deleted successfully", nil)
+}
+
+// ListAccessRules 获取网关的访问控制规则及命中计数
+func (c *GatewayController) ListAccessRules(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+
+	rules, err := c.gatewayService.ListAccessRules(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get access rules", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Access rules retrieved successfully", gin.H{
+		"access_rules": rules,
+		"total":        len(rules),
+	})
+}
+
+func (c *GatewayController) CreateAccessRule(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+
+	var req AccessRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	rule, err := c.gatewayService.CreateAccessRule(userID, id, accessRuleInput(&req))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create access rule", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Access rule created successfully", rule)
+}
+
+func (c *GatewayController) UpdateAccessRule(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+	ruleID, ok := parseIDParam(ctx, "rule_id", "Invalid access rule ID")
+	if !ok {
+		return
+	}
+
+	var req AccessRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	rule, err := c.gatewayService.UpdateAccessRule(userID, id, ruleID, accessRuleInput(&req))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to update access rule", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Access rule updated successfully", rule)
+}
+
+func (c *GatewayController) DeleteAccessRule(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+	ruleID, ok := parseIDParam(ctx, "rule_id", "Invalid access rule ID")
+	if !ok {
+		return
+	}
+
+	if err := c.gatewayService.DeleteAccessRule(userID, id, ruleID); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete access rule", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Access rule deleted successfully", nil)
+}
+
+// GetTraffic 获取网关发布域名的流量统计：热门路径、状态码分布与延迟分位数
+func (c *GatewayController) GetTraffic(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+	start, end, ok := parseTimeRange(ctx, constants.DefaultGatewayTrafficRange)
+	if !ok {
+		return
+	}
+	top, _ := strconv.Atoi(ctx.DefaultQuery("top", strconv.Itoa(constants.DefaultGatewayTopPaths)))
+
+	traffic, err := c.gatewayService.GetTraffic(userID, id, ctx.Query("domain"), start, end, top)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get gateway traffic", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Gateway traffic retrieved successfully", gin.H{
+		"domains": traffic,
+		"total":   len(traffic),
+	})
+}
+
+// ExportProxyConfig 将网关配置导出为 nginx 或 Traefik 配置文件内容
+func (c *GatewayController) ExportProxyConfig(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+
+	export, err := c.gatewayService.ExportConfig(userID, id, ctx.DefaultQuery("format", proxyconf.FormatNginx))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to export proxy config", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Proxy config exported successfully", export)
+}
+
+// DeployProxyConfig 将网关配置写入网关所在服务器的 nginx 或 Traefik 并重新加载
+func (c *GatewayController) DeployProxyConfig(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid gateway ID")
+	if !ok {
+		return
+	}
+
+	var req ProxyDeployRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	deployment, err := c.gatewayService.DeployConfig(userID, id, &service.ProxyDeployInput{
+		Format:    req.Format,
+		ConfigDir: req.ConfigDir,
+		CertDir:   req.CertDir,
+	})
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to deploy proxy config", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Proxy config deployment dispatched", deployment)
+}
+
+func accessRuleInput(req *AccessRuleRequest) *service.AccessRuleInput {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return &service.AccessRuleInput{
+		RuleName:    req.RuleName,
+		RuleType:    req.RuleType,
+		LimitCount:  req.LimitCount,
+		TimeWindow:  req.TimeWindow,
+		TargetPath:  req.TargetPath,
+		TargetIP:    req.TargetIP,
+		Action:      req.Action,
+		RedirectURL: req.RedirectURL,
+		Enabled:     enabled,
+	}
+}
+
+func publishInput(req *PublishRequest) *service.PublishInput {
+	auditLog := true
+	if req.AuditLogEnabled != nil {
+		auditLog = *req.AuditLogEnabled
+	}
+	return &service.PublishInput{
+		AppInstanceID:   req.AppInstanceID,
+		ServiceDomain:   req.ServiceDomain,
+		ServicePort:     req.ServicePort,
+		UpstreamHost:    req.UpstreamHost,
+		CertificateID:   req.CertificateID,
+		AuditLogEnabled: auditLog,
+	}
+}
@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InboxController struct {
+	inboxService service.InboxService
+}
+
+func NewInboxController(inboxService service.InboxService) *InboxController {
+	return &InboxController{
+		inboxService: inboxService,
+	}
+}
+
+// MarkReadRequest 标记已读的站内通知ID
+type MarkReadRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// List 分页查询当前用户的站内通知，可按未读、类型与级别过滤
+func (c *InboxController) List(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	query := &service.InboxQuery{Kind: ctx.Query("type"), Level: ctx.Query("level")}
+	if raw := ctx.Query("unread"); raw != "" {
+		unread, err := strconv.ParseBool(raw)
+		if err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid unread", err.Error())
+			return
+		}
+		query.Unread = unread
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+
+	items, total, err := c.inboxService.List(userID, query, page, pageSize)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get notifications", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Notifications retrieved successfully", gin.H{
+		"notifications": items,
+		"total":         total,
+		"page":          page,
+		"page_size":     pageSize,
+	})
+}
+
+func (c *InboxController) UnreadCount(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	count, err := c.inboxService.UnreadCount(userID)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to count unread notifications", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Unread count retrieved successfully", gin.H{"unread_count": count})
+}
+
+func (c *InboxController) MarkRead(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req MarkReadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	n, err := c.inboxService.MarkRead(userID, req.IDs)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to mark notifications as read", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Notifications marked as read", gin.H{"updated": n})
+}
+
+func (c *InboxController) MarkAllRead(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	n, err := c.inboxService.MarkAllRead(userID)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to mark notifications as read", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Notifications marked as read", gin.H{"updated": n})
+}
+
+func (c *InboxController) Delete(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid notification ID")
+	if !ok {
+		return
+	}
+
+	if err := c.inboxService.Delete(userID, id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete notification", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Notification deleted successfully", nil)
+}
+
+// StreamTicket 签发建立推送连接的一次性凭证
+func (c *InboxController) StreamTicket(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	ticket, err := c.inboxService.IssueStreamTicket(userID)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to issue stream ticket", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Stream ticket issued successfully", gin.H{
+		"ticket":     ticket,
+		"expires_in": int(constants.NotificationStreamTicketTTL.Seconds()),
+	})
+}
+
+// Stream 以 Server-Sent Events 推送站内通知，使用 ticket 查询参数认证
+// 连接建立后先推送未读数，重新连接时按 Last-Event-ID 头或 last_event_id 参数补发期间的通知
+func (c *InboxController) Stream(ctx *gin.Context) {
+	userID, err := c.inboxService.RedeemStreamTicket(ctx.Query("ticket"))
+	if err != nil {
+		response.Error(ctx, http.StatusUnauthorized, "Invalid stream ticket", err.Error())
+		return
+	}
+
+	// 凭证只能使用一次，客户端重新获取凭证后以新的 EventSource 重连，此时只能通过查询参数传递 last_event_id
+	var afterID uint
+	raw := ctx.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = ctx.Query("last_event_id")
+	}
+	if raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid Last-Event-ID", err.Error())
+			return
+		}
+		afterID = uint(id)
+	}
+
+	sub, err := c.inboxService.Subscribe(userID, afterID)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to subscribe to notifications", err.Error())
+		return
+	}
+	defer sub.Close()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(constants.NotificationStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := writeInboxEvent(ctx, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(ctx.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+func writeInboxEvent(ctx *gin.Context, event *service.InboxEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.ID != 0 {
+		if _, err := fmt.Fprintf(ctx.Writer, "id: %d\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(ctx.Writer, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logFetcher 查询 afterID 之后的一页日志分段
+type logFetcher func(userID, afterID uint, limit int) (*service.LogPage, error)
+
+// listLogs 分页返回日志分段，after 为上一页返回的 next_after，limit 为分段数
+func listLogs(ctx *gin.Context, fetch logFetcher, errMessage string) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	afterID, ok := parseLogCursor(ctx, ctx.Query("after"))
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(constants.DefaultLogPageSize)))
+
+	page, err := fetch(userID, afterID, limit)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), errMessage, err.Error())
+		return
+	}
+	response.Success(ctx, "Logs retrieved successfully", page)
+}
+
+// streamLogs 以 Server-Sent Events 推送日志分段，先补发历史分段再持续推送新分段，目标结束且日志推送完后发送 end 事件
+// 事件ID为分段ID，重新连接时按 Last-Event-ID 头或 after 参数续传
+func streamLogs(ctx *gin.Context, fetch logFetcher, errMessage string) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	raw := ctx.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = ctx.Query("after")
+	}
+	afterID, ok := parseLogCursor(ctx, raw)
+	if !ok {
+		return
+	}
+
+	// 首次查询在响应头之前，以便返回 404/403
+	page, err := fetch(userID, afterID, constants.MaxLogPageSize)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), errMessage, err.Error())
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	poll := time.NewTicker(constants.LogStreamPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(constants.LogStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	// 结束后的日志(如执行结束的系统日志)可能晚于状态写入，结束后再查询一轮才发送 end
+	draining := false
+	for {
+		for _, segment := range page.Segments {
+			if err := writeLogEvent(ctx, segment.ID, "log", segment); err != nil {
+				return
+			}
+		}
+		ctx.Writer.Flush()
+		afterID = page.NextAfter
+
+		switch {
+		case len(page.Segments) == constants.MaxLogPageSize:
+			// 历史分段未补发完，立即继续查询
+		case page.Finished && draining:
+			writeLogEvent(ctx, 0, "end", gin.H{"status": page.Status})
+			ctx.Writer.Flush()
+			return
+		default:
+			draining = page.Finished
+			if !waitLogPoll(ctx, poll, heartbeat) {
+				return
+			}
+		}
+
+		page, err = fetch(userID, afterID, constants.MaxLogPageSize)
+		if err != nil {
+			writeLogEvent(ctx, 0, "error", gin.H{"error": err.Error()})
+			ctx.Writer.Flush()
+			return
+		}
+	}
+}
+
+// waitLogPoll 等待下一次轮询，期间发送心跳，连接关闭时返回 false
+func waitLogPoll(ctx *gin.Context, poll, heartbeat *time.Ticker) bool {
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-poll.C:
+			return true
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(ctx.Writer, ": ping\n\n"); err != nil {
+				return false
+			}
+			ctx.Writer.Flush()
+		}
+	}
+}
+
+func writeLogEvent(ctx *gin.Context, id uint, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		if _, err := fmt.Fprintf(ctx.Writer, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(ctx.Writer, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// parseLogCursor 解析日志分段游标，为空时从头开始
+func parseLogCursor(ctx *gin.Context, raw string) (uint, bool) {
+	if raw == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid log cursor", err.Error())
+		return 0, false
+	}
+	return uint(id), true
+}
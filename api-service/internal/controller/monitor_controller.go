@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MonitorController struct {
+	monitorService service.MonitorService
+}
+
+func NewMonitorController(monitorService service.MonitorService) *MonitorController {
+	return &MonitorController{
+		monitorService: monitorService,
+	}
+}
+
+// GetServerMetrics 获取服务器监控指标时序
+func (c *MonitorController) GetServerMetrics(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid server ID")
+	if !ok {
+		return
+	}
+	query, ok := parseMetricsQuery(ctx)
+	if !ok {
+		return
+	}
+
+	series, err := c.monitorService.GetServerMetrics(userID, id, query)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get server metrics", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Server metrics retrieved successfully", series)
+}
+
+// GetApplicationMetrics 获取应用监控指标时序
+func (c *MonitorController) GetApplicationMetrics(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid application ID")
+	if !ok {
+		return
+	}
+	query, ok := parseMetricsQuery(ctx)
+	if !ok {
+		return
+	}
+
+	series, err := c.monitorService.GetApplicationMetrics(userID, id, query)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get application metrics", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Application metrics retrieved successfully", series)
+}
+
+// parseMetricsQuery 解析 start/end、fields(逗号分隔的指标分组)与 window 查询参数，失败时直接返回 400
+func parseMetricsQuery(ctx *gin.Context) (*service.MetricsQuery, bool) {
+	start, end, ok := parseTimeRange(ctx, constants.DefaultMetricsRange)
+	if !ok {
+		return nil, false
+	}
+	query := &service.MetricsQuery{Start: start, End: end}
+
+	for _, group := range strings.Split(ctx.Query("fields"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			query.Groups = append(query.Groups, group)
+		}
+	}
+
+	if raw := ctx.Query("window"); raw != "" {
+		window, err := time.ParseDuration(raw)
+		if err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid window", err.Error())
+			return nil, false
+		}
+		query.Window = window
+	}
+	return query, true
+}
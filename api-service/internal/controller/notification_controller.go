@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationController struct {
+	notificationService service.NotificationService
+}
+
+func NewNotificationController(notificationService service.NotificationService) *NotificationController {
+	return &NotificationController{
+		notificationService: notificationService,
+	}
+}
+
+// NotificationRequest 发送通知参数，template 与 title/content 二选一
+type NotificationRequest struct {
+	Level         string                 `json:"level"`
+	TargetType    string                 `json:"target_type" binding:"required"`
+	TargetIDs     []uint                 `json:"target_ids"`
+	Channels      []string               `json:"channels"`
+	Template      string                 `json:"template"`
+	Variables     map[string]interface{} `json:"variables"`
+	Title         string                 `json:"title"`
+	Content       string                 `json:"content"`
+	ReferenceType string                 `json:"reference_type"`
+	ReferenceID   string                 `json:"reference_id"`
+}
+
+// NotificationTemplateRequest 模板参数，variables 为变量名到类型的映射，enabled 默认为 true
+type NotificationTemplateRequest struct {
+	Name      string            `json:"name" binding:"required"`
+	Type      string            `json:"type" binding:"required"`
+	Subject   string            `json:"subject"`
+	Content   string            `json:"content" binding:"required"`
+	Variables map[string]string `json:"variables"`
+	Enabled   *bool             `json:"enabled"`
+}
+
+// TestChannelRequest 测试渠道参数，recipient 为邮箱或手机号，webhook 渠道可留空
+type TestChannelRequest struct {
+	Recipient string `json:"recipient"`
+}
+
+func (r *NotificationTemplateRequest) input() *service.NotificationTemplateInput {
+	return &service.NotificationTemplateInput{
+		Name:      r.Name,
+		Type:      r.Type,
+		Subject:   r.Subject,
+		Content:   r.Content,
+		Variables: r.Variables,
+		Enabled:   r.Enabled == nil || *r.Enabled,
+	}
+}
+
+// Send 向用户、用户组或全部用户发送通知
+func (c *NotificationController) Send(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req NotificationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	notification, err := c.notificationService.Send(&service.NotificationRequest{
+		Kind:          constants.NotificationKindUser,
+		Level:         req.Level,
+		SenderID:      &userID,
+		TargetType:    req.TargetType,
+		TargetIDs:     req.TargetIDs,
+		Channels:      req.Channels,
+		Template:      req.Template,
+		Variables:     req.Variables,
+		Title:         req.Title,
+		Content:       req.Content,
+		ReferenceType: req.ReferenceType,
+		ReferenceID:   req.ReferenceID,
+	})
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to send notification", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Notification queued successfully", notification)
+}
+
+func (c *NotificationController) ListChannels(ctx *gin.Context) {
+	response.Success(ctx, "Notification channels retrieved successfully", c.notificationService.Channels())
+}
+
+// TestChannel 立即通过渠道发送一条测试通知
+func (c *NotificationController) TestChannel(ctx *gin.Context) {
+	var req TestChannelRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	if err := c.notificationService.TestChannel(ctx.Param("name"), req.Recipient); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to send test notification", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Test notification sent successfully", nil)
+}
+
+func (c *NotificationController) ListTemplates(ctx *gin.Context) {
+	templates, err := c.notificationService.ListTemplates()
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get notification templates", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Notification templates retrieved successfully", templates)
+}
+
+func (c *NotificationController) GetTemplate(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "Invalid template ID")
+	if !ok {
+		return
+	}
+
+	template, err := c.notificationService.GetTemplate(id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get notification template", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Notification template retrieved successfully", template)
+}
+
+func (c *NotificationController) CreateTemplate(ctx *gin.Context) {
+	var req NotificationTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	template, err := c.notificationService.CreateTemplate(req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create notification template", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Notification template created successfully", template)
+}
+
+// UpdateTemplate 更新模板，内置模板只能修改主题、内容与启用状态
+func (c *NotificationController) UpdateTemplate(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "Invalid template ID")
+	if !ok {
+		return
+	}
+
+	var req NotificationTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	template, err := c.notificationService.UpdateTemplate(id, req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to update notification template", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Notification template updated successfully", template)
+}
+
+func (c *NotificationController) DeleteTemplate(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "Invalid template ID")
+	if !ok {
+		return
+	}
+
+	if err := c.notificationService.DeleteTemplate(id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete notification template", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Notification template deleted successfully", nil)
+}
+
+// ListRecords 分页查询投递记录，可按状态、渠道与通知过滤
+func (c *NotificationController) ListRecords(ctx *gin.Context) {
+	query := &service.NotificationRecordQuery{
+		Status:  ctx.Query("status"),
+		Channel: ctx.Query("channel"),
+	}
+	if raw := ctx.Query("notification_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid notification_id", err.Error())
+			return
+		}
+		query.NotificationID = uint(id)
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+
+	records, total, err := c.notificationService.ListRecords(query, page, pageSize)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get notification records", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Notification records retrieved successfully", gin.H{
+		"records":   records,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
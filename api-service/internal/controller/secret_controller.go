@@ -0,0 +1,300 @@
+package controller
+
+import (
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SecretController struct {
+	secretService service.SecretService
+}
+
+func NewSecretController(secretService service.SecretService) *SecretController {
+	return &SecretController{
+		secretService: secretService,
+	}
+}
+
+type CreateSecretRequest struct {
+	Name         string                 `json:"name" binding:"required"`
+	KeyType      string                 `json:"key_type" binding:"required"`
+	Value        string                 `json:"value" binding:"required"`
+	Description  string                 `json:"description"`
+	CustomFields map[string]interface{} `json:"custom_fields"`
+	ExpiresAt    *time.Time             `json:"expires_at"`
+}
+
+// UpdateSecretRequest 未给出的字段保持不变，clear_expires_at 为 true 时移除过期时间
+type UpdateSecretRequest struct {
+	Name           string                 `json:"name"`
+	KeyType        string                 `json:"key_type"`
+	Description    *string                `json:"description"`
+	CustomFields   map[string]interface{} `json:"custom_fields"`
+	ExpiresAt      *time.Time             `json:"expires_at"`
+	ClearExpiresAt bool                   `json:"clear_expires_at"`
+}
+
+type RotateSecretRequest struct {
+	Value string `json:"value" binding:"required"`
+	Note  string `json:"note"`
+}
+
+type ShareSecretRequest struct {
+	Users  []uint `json:"users"`
+	Groups []uint `json:"groups"`
+}
+
+func (c *SecretController) CreateSecret(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req CreateSecretRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	secret, err := c.secretService.CreateSecret(userID, &service.SecretInput{
+		Name:         req.Name,
+		KeyType:      req.KeyType,
+		Value:        req.Value,
+		Description:  req.Description,
+		CustomFields: req.CustomFields,
+		ExpiresAt:    req.ExpiresAt,
+	}, auditMeta(ctx))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create secret", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Secret created successfully", secret)
+}
+
+func (c *SecretController) ListSecrets(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	secrets, err := c.secretService.ListSecrets(userID, ctx.Query("key_type"))
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get secrets", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Secrets retrieved successfully", gin.H{
+		"secrets": secrets,
+		"total":   len(secrets),
+	})
+}
+
+func (c *SecretController) ListExpiringSecrets(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	days, _ := strconv.Atoi(ctx.DefaultQuery("days", "0"))
+	secrets, err := c.secretService.ListExpiringSecrets(userID, days)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get expiring secrets", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Expiring secrets retrieved successfully", gin.H{
+		"secrets": secrets,
+		"total":   len(secrets),
+	})
+}
+
+func (c *SecretController) GetSecret(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid secret ID")
+	if !ok {
+		return
+	}
+
+	secret, err := c.secretService.GetSecret(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get secret", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Secret retrieved successfully", secret)
+}
+
+func (c *SecretController) UpdateSecret(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid secret ID")
+	if !ok {
+		return
+	}
+
+	var req UpdateSecretRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	secret, err := c.secretService.UpdateSecret(userID, id, &service.SecretUpdateInput{
+		Name:           req.Name,
+		KeyType:        req.KeyType,
+		Description:    req.Description,
+		CustomFields:   req.CustomFields,
+		ExpiresAt:      req.ExpiresAt,
+		ClearExpiresAt: req.ClearExpiresAt,
+	}, auditMeta(ctx))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to update secret", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Secret updated successfully", secret)
+}
+
+func (c *SecretController) DeleteSecret(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid secret ID")
+	if !ok {
+		return
+	}
+
+	if err := c.secretService.DeleteSecret(userID, id, auditMeta(ctx)); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete secret", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Secret deleted successfully", nil)
+}
+
+func (c *SecretController) RevealSecret(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid secret ID")
+	if !ok {
+		return
+	}
+
+	value, err := c.secretService.RevealSecret(userID, id, auditMeta(ctx))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to reveal secret", err.Error())
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	response.Success(ctx, "Secret revealed successfully", gin.H{"value": value})
+}
+
+func (c *SecretController) RotateSecret(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid secret ID")
+	if !ok {
+		return
+	}
+
+	var req RotateSecretRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	secret, err := c.secretService.RotateSecret(userID, id, req.Value, req.Note, auditMeta(ctx))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to rotate secret", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Secret rotated successfully", secret)
+}
+
+func (c *SecretController) ListVersions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid secret ID")
+	if !ok {
+		return
+	}
+
+	versions, err := c.secretService.ListVersions(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get secret versions", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Secret versions retrieved successfully", gin.H{
+		"versions": versions,
+		"total":    len(versions),
+	})
+}
+
+func (c *SecretController) RollbackSecret(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid secret ID")
+	if !ok {
+		return
+	}
+	version, err := strconv.Atoi(ctx.Param("version"))
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid secret version", err.Error())
+		return
+	}
+
+	secret, err := c.secretService.RollbackSecret(userID, id, version, auditMeta(ctx))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to rollback secret", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Secret rolled back successfully", secret)
+}
+
+func (c *SecretController) ShareSecret(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid secret ID")
+	if !ok {
+		return
+	}
+
+	var req ShareSecretRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	secret, err := c.secretService.ShareSecret(userID, id, req.Users, req.Groups, auditMeta(ctx))
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to share secret", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Secret shared successfully", secret)
+}
@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookController(webhookService service.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// WebhookRequest Webhook 参数，is_active 默认为 true，timeout 与 retry_count 缺省时为 30 秒与 3 次
+type WebhookRequest struct {
+	Name       string            `json:"name" binding:"required"`
+	URL        string            `json:"url" binding:"required"`
+	Secret     string            `json:"secret"`
+	Events     []string          `json:"events" binding:"required"`
+	Headers    map[string]string `json:"headers"`
+	Timeout    int               `json:"timeout"`
+	RetryCount *int              `json:"retry_count"`
+	IsActive   *bool             `json:"is_active"`
+}
+
+func (r *WebhookRequest) input() *service.WebhookInput {
+	return &service.WebhookInput{
+		Name:       r.Name,
+		URL:        r.URL,
+		Secret:     r.Secret,
+		Events:     r.Events,
+		Headers:    r.Headers,
+		Timeout:    r.Timeout,
+		RetryCount: r.RetryCount,
+		Active:     r.IsActive == nil || *r.IsActive,
+	}
+}
+
+func (c *WebhookController) ListEvents(ctx *gin.Context) {
+	response.Success(ctx, "Webhook events retrieved successfully", c.webhookService.Events())
+}
+
+// CreateWebhook 创建 Webhook，响应中的 secret 只返回这一次
+func (c *WebhookController) CreateWebhook(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req WebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	webhook, secret, err := c.webhookService.CreateWebhook(userID, req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create webhook", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Webhook created successfully", gin.H{
+		"webhook": webhook,
+		"secret":  secret,
+	})
+}
+
+func (c *WebhookController) ListWebhooks(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+
+	webhooks, total, err := c.webhookService.ListWebhooks(userID, page, pageSize)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get webhooks", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Webhooks retrieved successfully", gin.H{
+		"webhooks":  webhooks,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+func (c *WebhookController) GetWebhook(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	webhook, err := c.webhookService.GetWebhook(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get webhook", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Webhook retrieved successfully", webhook)
+}
+
+// UpdateWebhook 更新 Webhook，secret 为空时保留原密钥
+func (c *WebhookController) UpdateWebhook(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	var req WebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	webhook, err := c.webhookService.UpdateWebhook(userID, id, req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to update webhook", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Webhook updated successfully", webhook)
+}
+
+func (c *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := c.webhookService.DeleteWebhook(userID, id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete webhook", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Webhook deleted successfully", nil)
+}
+
+// Ping 投递一次 ping 事件，结果可在投递记录中查看
+func (c *WebhookController) Ping(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := c.webhookService.Ping(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to ping webhook", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Webhook ping queued successfully", delivery)
+}
+
+// ListDeliveries 分页查询投递记录，可按状态与事件过滤
+func (c *WebhookController) ListDeliveries(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+
+	deliveries, total, err := c.webhookService.ListDeliveries(userID, id, &service.WebhookDeliveryQuery{
+		Status: ctx.Query("status"),
+		Event:  ctx.Query("event"),
+	}, page, pageSize)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get webhook deliveries", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Webhook deliveries retrieved successfully", gin.H{
+		"deliveries": deliveries,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+	})
+}
+
+func (c *WebhookController) GetDelivery(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseIDParam(ctx, "delivery_id", "Invalid delivery ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := c.webhookService.GetDelivery(userID, id, deliveryID)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get webhook delivery", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Webhook delivery retrieved successfully", delivery)
+}
+
+// Redeliver 重新投递已完成的投递记录，返回新的投递记录
+func (c *WebhookController) Redeliver(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseIDParam(ctx, "delivery_id", "Invalid delivery ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := c.webhookService.Redeliver(userID, id, deliveryID)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to redeliver webhook delivery", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Webhook redelivery queued successfully", delivery)
+}
@@ -0,0 +1,481 @@
+package controller
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/service"
+	"api-service/pkg/response"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WorkflowController struct {
+	workflowService service.WorkflowService
+}
+
+func NewWorkflowController(workflowService service.WorkflowService) *WorkflowController {
+	return &WorkflowController{
+		workflowService: workflowService,
+	}
+}
+
+// WorkflowRequest 工作流参数，definition 为工作流定义对象
+type WorkflowRequest struct {
+	Name        string          `json:"name" binding:"required"`
+	Code        string          `json:"code" binding:"required"`
+	Description string          `json:"description"`
+	Definition  json.RawMessage `json:"definition" binding:"required"`
+	Status      string          `json:"status"`
+}
+
+func (r *WorkflowRequest) input() *service.WorkflowInput {
+	return &service.WorkflowInput{
+		Name:        r.Name,
+		Code:        r.Code,
+		Description: r.Description,
+		Definition:  r.Definition,
+		Status:      r.Status,
+	}
+}
+
+// WorkflowTaskRequest 工作流任务参数，inputs 为每次执行的默认参数
+// schedule_type 为 SCHEDULE 时按 cron_expression 在 timezone 时区定时执行，
+// 为 TRIGGER 时在 trigger_event 事件通过 trigger_filter 时执行，trigger_inputs 以事件数据渲染执行参数
+type WorkflowTaskRequest struct {
+	Name           string                 `json:"name" binding:"required"`
+	WorkflowID     uint                   `json:"workflow_id" binding:"required"`
+	ScheduleType   string                 `json:"schedule_type"`
+	CronExpression string                 `json:"cron_expression"`
+	Timezone       string                 `json:"timezone"`
+	CatchUpPolicy  string                 `json:"catch_up_policy"`
+	TriggerEvent   string                 `json:"trigger_event"`
+	TriggerFilter  string                 `json:"trigger_filter"`
+	TriggerInputs  map[string]string      `json:"trigger_inputs"`
+	Inputs         map[string]interface{} `json:"inputs"`
+	Status         string                 `json:"status"`
+}
+
+func (r *WorkflowTaskRequest) input() *service.WorkflowTaskInput {
+	return &service.WorkflowTaskInput{
+		Name:           r.Name,
+		WorkflowID:     r.WorkflowID,
+		ScheduleType:   r.ScheduleType,
+		CronExpression: r.CronExpression,
+		Timezone:       r.Timezone,
+		CatchUpPolicy:  r.CatchUpPolicy,
+		TriggerEvent:   r.TriggerEvent,
+		TriggerFilter:  r.TriggerFilter,
+		TriggerInputs:  r.TriggerInputs,
+		Inputs:         r.Inputs,
+		Status:         r.Status,
+	}
+}
+
+// RunWorkflowTaskRequest 手动执行参数，覆盖任务中保存的同名参数
+type RunWorkflowTaskRequest struct {
+	Inputs map[string]interface{} `json:"inputs"`
+}
+
+func (c *WorkflowController) CreateWorkflow(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req WorkflowRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	workflow, err := c.workflowService.CreateWorkflow(userID, req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create workflow", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow created successfully", workflow)
+}
+
+func (c *WorkflowController) ListWorkflows(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+
+	workflows, total, err := c.workflowService.ListWorkflows(userID, page, pageSize)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get workflows", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflows retrieved successfully", gin.H{
+		"workflows": workflows,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+func (c *WorkflowController) GetWorkflow(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid workflow ID")
+	if !ok {
+		return
+	}
+
+	workflow, err := c.workflowService.GetWorkflow(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get workflow", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow retrieved successfully", workflow)
+}
+
+func (c *WorkflowController) UpdateWorkflow(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid workflow ID")
+	if !ok {
+		return
+	}
+
+	var req WorkflowRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	workflow, err := c.workflowService.UpdateWorkflow(userID, id, req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to update workflow", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow updated successfully", workflow)
+}
+
+func (c *WorkflowController) DeleteWorkflow(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid workflow ID")
+	if !ok {
+		return
+	}
+
+	if err := c.workflowService.DeleteWorkflow(userID, id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete workflow", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow deleted successfully", nil)
+}
+
+func (c *WorkflowController) CreateTask(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req WorkflowTaskRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	task, err := c.workflowService.CreateTask(userID, req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to create workflow task", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow task created successfully", task)
+}
+
+// ListTasks 分页查询工作流任务，可按 workflow_id 过滤
+func (c *WorkflowController) ListTasks(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	workflowID, ok := parseIDQuery(ctx, "workflow_id")
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+
+	tasks, total, err := c.workflowService.ListTasks(userID, workflowID, page, pageSize)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get workflow tasks", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow tasks retrieved successfully", gin.H{
+		"tasks":     tasks,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+func (c *WorkflowController) GetTask(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid workflow task ID")
+	if !ok {
+		return
+	}
+
+	task, err := c.workflowService.GetTask(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get workflow task", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow task retrieved successfully", task)
+}
+
+func (c *WorkflowController) UpdateTask(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid workflow task ID")
+	if !ok {
+		return
+	}
+
+	var req WorkflowTaskRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	task, err := c.workflowService.UpdateTask(userID, id, req.input())
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to update workflow task", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow task updated successfully", task)
+}
+
+func (c *WorkflowController) DeleteTask(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid workflow task ID")
+	if !ok {
+		return
+	}
+
+	if err := c.workflowService.DeleteTask(userID, id); err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to delete workflow task", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow task deleted successfully", nil)
+}
+
+// RunTask 手动执行任务，返回新建的执行，执行由后台引擎推进
+func (c *WorkflowController) RunTask(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid workflow task ID")
+	if !ok {
+		return
+	}
+
+	// 请求体可省略
+	var req RunWorkflowTaskRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.Error(ctx, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	execution, err := c.workflowService.RunTask(userID, id, req.Inputs)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to run workflow task", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow execution created successfully", execution)
+}
+
+// RotateTriggerToken 生成入站 Webhook 地址，响应中的 token 只返回这一次
+func (c *WorkflowController) RotateTriggerToken(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid workflow task ID")
+	if !ok {
+		return
+	}
+
+	token, err := c.workflowService.RotateTriggerToken(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to rotate trigger token", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Trigger token rotated successfully", gin.H{
+		"token": token,
+		"path":  constants.WorkflowWebhookPath + token,
+	})
+}
+
+// TriggerWebhook 入站 Webhook，以 JSON 请求体为事件数据触发任务，地址中的凭证即认证
+func (c *WorkflowController) TriggerWebhook(ctx *gin.Context) {
+	// 请求体可省略
+	data := make(map[string]interface{})
+	if ctx.Request.ContentLength != 0 {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, constants.MaxWorkflowWebhookBody)
+		if err := json.NewDecoder(ctx.Request.Body).Decode(&data); err != nil && !errors.Is(err, io.EOF) {
+			response.Error(ctx, http.StatusBadRequest, "Invalid request", "request body must be a JSON object: "+err.Error())
+			return
+		}
+	}
+
+	execution, err := c.workflowService.TriggerWebhook(ctx.Param("token"), data)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to trigger workflow task", err.Error())
+		return
+	}
+	if execution == nil {
+		response.Success(ctx, "Event did not match the trigger filter", nil)
+		return
+	}
+
+	response.Success(ctx, "Workflow execution created successfully", execution)
+}
+
+// ListExecutions 分页查询执行历史，可按 task_id 过滤，不包含定义快照、执行日志与事件数据
+func (c *WorkflowController) ListExecutions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	taskID, ok := parseIDQuery(ctx, "task_id")
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+
+	executions, total, err := c.workflowService.ListExecutions(userID, taskID, page, pageSize)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "Failed to get workflow executions", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow executions retrieved successfully", gin.H{
+		"executions": executions,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+	})
+}
+
+// GetExecution 查询执行详情，包含各步骤在各服务器上的运行记录
+func (c *WorkflowController) GetExecution(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid workflow execution ID")
+	if !ok {
+		return
+	}
+
+	execution, err := c.workflowService.GetExecution(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to get workflow execution", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow execution retrieved successfully", execution)
+}
+
+// ListExecutionLogs 分页返回执行日志
+func (c *WorkflowController) ListExecutionLogs(ctx *gin.Context) {
+	if fetch, ok := c.executionLogFetcher(ctx); ok {
+		listLogs(ctx, fetch, "Failed to get workflow execution logs")
+	}
+}
+
+// StreamExecutionLogs 以 Server-Sent Events 实时推送执行日志
+func (c *WorkflowController) StreamExecutionLogs(ctx *gin.Context) {
+	if fetch, ok := c.executionLogFetcher(ctx); ok {
+		streamLogs(ctx, fetch, "Failed to get workflow execution logs")
+	}
+}
+
+func (c *WorkflowController) executionLogFetcher(ctx *gin.Context) (logFetcher, bool) {
+	id, ok := parseIDParam(ctx, "id", "Invalid workflow execution ID")
+	if !ok {
+		return nil, false
+	}
+	return func(userID, afterID uint, limit int) (*service.LogPage, error) {
+		return c.workflowService.ListExecutionLogs(userID, id, afterID, limit)
+	}, true
+}
+
+func (c *WorkflowController) StopExecution(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(ctx, "id", "Invalid workflow execution ID")
+	if !ok {
+		return
+	}
+
+	execution, err := c.workflowService.StopExecution(userID, id)
+	if err != nil {
+		response.Error(ctx, serviceErrorStatus(err), "Failed to stop workflow execution", err.Error())
+		return
+	}
+
+	response.Success(ctx, "Workflow execution stopped successfully", execution)
+}
+
+// parseIDQuery 解析可选的ID查询参数，未提供时返回 nil
+func parseIDQuery(ctx *gin.Context, name string) (*uint, bool) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "Invalid "+name, err.Error())
+		return nil, false
+	}
+	v := uint(id)
+	return &v, true
+}
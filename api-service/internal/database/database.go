@@ -3,6 +3,8 @@ package database
 import (
 	"api-service/internal/config"
 	"api-service/internal/constants"
+	"api-service/pkg/blobstore"
+	"api-service/pkg/metricstore"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -42,3 +44,57 @@ func InitInfluxDB(cfg *config.Config) (influxdb2.Client, error) {
 	client := influxdb2.NewClient(cfg.InfluxDB.URL, cfg.InfluxDB.Token)
 	return client, nil
 }
+
+// InitMetricsStore 按 metrics.store 初始化指标存储，sqlite 使用独立的数据库文件
+func InitMetricsStore(cfg *config.Config) (metricstore.Store, error) {
+	switch cfg.Metrics.Store {
+	case constants.MetricsStoreInfluxDB:
+		client, err := InitInfluxDB(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return metricstore.NewInflux(client, cfg.InfluxDB.Org, cfg.InfluxDB.Bucket), nil
+	case constants.MetricsStoreSQLite:
+		sqliteCfg := cfg.Metrics.SQLite
+		if err := os.MkdirAll(filepath.Dir(sqliteCfg.Path), constants.DefaultDirPerm); err != nil {
+			return nil, fmt.Errorf("failed to create metrics directory: %v", err)
+		}
+		db, err := gorm.Open(sqlite.Open(sqliteCfg.Path), &gorm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open metrics database: %v", err)
+		}
+		// 避免后台降采样与写入并发时出现 database is locked
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+		return metricstore.NewSQLite(db, []metricstore.Tier{
+			{Resolution: 0, Retention: sqliteCfg.RawRetention},
+			{Resolution: constants.MetricsRollup5mResolution, Retention: sqliteCfg.Rollup5mRetention},
+			{Resolution: constants.MetricsRollup1hResolution, Retention: sqliteCfg.Rollup1hRetention},
+		})
+	default:
+		return nil, fmt.Errorf("unknown metrics store: %s", cfg.Metrics.Store)
+	}
+}
+
+// InitBlobStore 按 storage.backend 初始化用户文件存储
+func InitBlobStore(cfg *config.Config) (blobstore.Store, error) {
+	switch cfg.Storage.Backend {
+	case constants.BlobStoreLocal:
+		return blobstore.NewLocal(cfg.Storage.LocalPath)
+	case constants.BlobStoreS3:
+		s3Cfg := cfg.Storage.S3
+		return blobstore.NewS3(blobstore.S3Options{
+			Endpoint:  s3Cfg.Endpoint,
+			Region:    s3Cfg.Region,
+			Bucket:    s3Cfg.Bucket,
+			AccessKey: s3Cfg.AccessKey,
+			SecretKey: s3Cfg.SecretKey,
+			PathStyle: s3Cfg.PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Storage.Backend)
+	}
+}
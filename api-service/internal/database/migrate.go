@@ -1,14 +1,17 @@
 package database
 
 import (
+	"api-service/internal/constants"
 	"api-service/internal/model"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 // AutoMigrate 自动迁移数据库表结构
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		// 系统管理相关表
 		&model.UserGroup{},
 		&model.User{},
@@ -21,6 +24,9 @@ func AutoMigrate(db *gorm.DB) error {
 		&model.SystemConfig{},
 		&model.AlertRule{},
 		&model.AlertRecord{},
+		&model.AlertSilence{},
+		&model.MaintenanceWindow{},
+		&model.AlertBaseline{},
 		&model.NotificationTemplate{},
 		&model.NotificationRecord{},
 		&model.Notification{},
@@ -56,5 +62,77 @@ func AutoMigrate(db *gorm.DB) error {
 		&model.ServerAgent{},
 		&model.AppInstance{},
 		&model.Application{},
+
+		// 安全管控相关表
+		&model.SSLCertificate{},
+		&model.AppGateway{},
+		&model.AppGatewayPublish{},
+		&model.AppGatewayAccessRule{},
+		&model.CertificateAuthority{},
+		&model.SecretKey{},
+		&model.SecretKeyVersion{},
+		&model.SecretKeyReference{},
+		&model.AuditLog{},
+
+		// 工作空间相关表
+		&model.UserFile{},
+		&model.FileUpload{},
+		&model.FileUploadChunk{},
+		&model.Workflow{},
+		&model.WorkflowTask{},
+		&model.WorkflowExecution{},
+		&model.WorkflowStepRun{},
+		&model.LogSegment{},
 	)
+	if err != nil {
+		return err
+	}
+	return migrateLegacyLogs(db)
+}
+
+// migrateLegacyLogs 将升级前保存在 deployment_log 与 execution_log 字段中的日志复制为一个日志分段，
+// 已有日志分段的部署或执行跳过，因此可以重复运行；原字段保留，兼容读取旧字段的客户端
+func migrateLegacyLogs(db *gorm.DB) error {
+	legacy := []struct {
+		table, column, target string
+	}{
+		{"app_deployments", "deployment_log", constants.LogTargetDeployment},
+		{"workflow_executions", "execution_log", constants.LogTargetWorkflowExecution},
+	}
+	for _, l := range legacy {
+		var rows []struct {
+			ID        uint
+			Log       string
+			CreatedAt time.Time
+		}
+		err := db.Table(l.table).Select("id, "+l.column+" AS log, created_at").
+			Where(l.column+" <> ''").
+			Where("NOT EXISTS (SELECT 1 FROM log_segments WHERE log_segments.target_type = ? AND log_segments.target_id = "+
+				l.table+".id)", l.target).
+			Find(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		segments := make([]*model.LogSegment, 0, len(rows))
+		for _, row := range rows {
+			content := row.Log
+			if !strings.HasSuffix(content, "\n") {
+				content += "\n"
+			}
+			segments = append(segments, &model.LogSegment{
+				TargetType: l.target,
+				TargetID:   row.ID,
+				Content:    content,
+				Lines:      strings.Count(content, "\n"),
+				CreatedAt:  row.CreatedAt,
+			})
+		}
+		if len(segments) > 0 {
+			if err := db.CreateInBatches(segments, 100).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
@@ -0,0 +1,219 @@
+package gateway
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/pkg/utils"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// accessRule 预解析的访问控制规则
+type accessRule struct {
+	rule   *model.AppGatewayAccessRule
+	nets   []*net.IPNet
+	window time.Duration
+}
+
+// accessPolicy 网关的访问控制策略
+// 依次执行：IP 黑名单 → IP 白名单 → 限流，规则的 Action 决定命中后的处理方式，
+// ALLOW 仅记录命中次数而不拦截，可用于规则上线前的观察
+type accessPolicy struct {
+	blacklist  []*accessRule
+	whitelist  []*accessRule
+	rateLimits []*accessRule
+}
+
+// ruleHits 规则命中计数，定期累加到数据库
+type ruleHits struct {
+	count  atomic.Int64
+	lastAt atomic.Int64
+}
+
+func compileAccessRule(rule *model.AppGatewayAccessRule) (*accessRule, error) {
+	nets, err := utils.ParseIPNets(rule.TargetIP)
+	if err != nil {
+		return nil, err
+	}
+	if rule.RuleType == constants.AccessRuleRateLimit && (rule.LimitCount <= 0 || rule.TimeWindow <= 0) {
+		return nil, fmt.Errorf("invalid rate limit %d/%ds", rule.LimitCount, rule.TimeWindow)
+	}
+	return &accessRule{
+		rule:   rule,
+		nets:   nets,
+		window: time.Duration(rule.TimeWindow) * time.Second,
+	}, nil
+}
+
+// buildPolicies 按网关分组构建访问控制策略
+func buildPolicies(rules []*model.AppGatewayAccessRule) map[uint]*accessPolicy {
+	policies := make(map[uint]*accessPolicy)
+	for _, rule := range rules {
+		compiled, err := compileAccessRule(rule)
+		if err != nil {
+			log.Printf("Skipping gateway access rule %d: %v", rule.ID, err)
+			continue
+		}
+
+		policy, ok := policies[rule.GatewayID]
+		if !ok {
+			policy = &accessPolicy{}
+			policies[rule.GatewayID] = policy
+		}
+		switch rule.RuleType {
+		case constants.AccessRuleIPBlacklist:
+			policy.blacklist = append(policy.blacklist, compiled)
+		case constants.AccessRuleIPWhitelist:
+			policy.whitelist = append(policy.whitelist, compiled)
+		case constants.AccessRuleRateLimit:
+			policy.rateLimits = append(policy.rateLimits, compiled)
+		}
+	}
+	return policies
+}
+
+// appliesTo 按路径段匹配规则的目标路径，/admin 匹配 /admin 与 /admin/users，不匹配 /administrator
+func (a *accessRule) appliesTo(path string) bool {
+	target := strings.TrimSuffix(a.rule.TargetPath, "/")
+	if target == "" || target == path {
+		return true
+	}
+	return strings.HasPrefix(path, target+"/")
+}
+
+func (a *accessRule) matchesIP(ip net.IP) bool {
+	for _, ipNet := range a.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAccess 执行访问控制，请求被拦截或重定向时返回 false
+func (g *Gateway) checkAccess(w http.ResponseWriter, r *http.Request, policy *accessPolicy) bool {
+	if policy == nil {
+		return true
+	}
+
+	ip := clientIP(r)
+	// 按规范化后的路径匹配，避免以 // 或 .. 绕过路径规则
+	path := cleanPath(r.URL.Path)
+
+	for _, rule := range policy.blacklist {
+		if rule.appliesTo(path) && rule.matchesIP(ip) {
+			if g.enforce(w, r, rule, http.StatusForbidden) {
+				return false
+			}
+		}
+	}
+
+	// 同一路径上的多条白名单规则取并集，均不匹配时由第一条规则处理
+	var denied *accessRule
+	for _, rule := range policy.whitelist {
+		if !rule.appliesTo(path) {
+			continue
+		}
+		if rule.matchesIP(ip) {
+			denied = nil
+			break
+		}
+		if denied == nil {
+			denied = rule
+		}
+	}
+	if denied != nil && g.enforce(w, r, denied, http.StatusForbidden) {
+		return false
+	}
+
+	for _, rule := range policy.rateLimits {
+		if !rule.appliesTo(path) || (len(rule.nets) > 0 && !rule.matchesIP(ip)) {
+			continue
+		}
+
+		key := strconv.FormatUint(uint64(rule.rule.ID), 10) + ":" + ip.String()
+		allowed, err := g.limiter.Allow(r.Context(), key, rule.rule.LimitCount, rule.window)
+		if err != nil {
+			// 计数存储不可用时放行，避免影响正常访问
+			log.Printf("Gateway rate limit check for rule %d failed: %v", rule.rule.ID, err)
+			continue
+		}
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(rule.rule.TimeWindow))
+			if g.enforce(w, r, rule, http.StatusTooManyRequests) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// enforce 记录规则命中并按 Action 处理请求，返回请求是否已被拦截
+func (g *Gateway) enforce(w http.ResponseWriter, r *http.Request, rule *accessRule, status int) bool {
+	g.recordHit(rule.rule.ID)
+
+	switch rule.rule.Action {
+	case constants.AccessActionAllow:
+		return false
+	case constants.AccessActionRedirect:
+		http.Redirect(w, r, rule.rule.RedirectURL, http.StatusFound)
+		return true
+	default:
+		http.Error(w, http.StatusText(status), status)
+		return true
+	}
+}
+
+func (g *Gateway) recordHit(ruleID uint) {
+	value, _ := g.hits.LoadOrStore(ruleID, &ruleHits{})
+	hits := value.(*ruleHits)
+	hits.count.Add(1)
+	hits.lastAt.Store(time.Now().UnixNano())
+}
+
+// flushHits 将累计的命中次数写入数据库
+func (g *Gateway) flushHits() {
+	g.hits.Range(func(key, value interface{}) bool {
+		hits := value.(*ruleHits)
+		delta := hits.count.Swap(0)
+		if delta == 0 {
+			return true
+		}
+
+		ruleID := key.(uint)
+		if err := g.gatewayRepo.IncrementRuleHits(ruleID, delta, time.Unix(0, hits.lastAt.Load())); err != nil {
+			log.Printf("Failed to record hits for gateway access rule %d: %v", ruleID, err)
+			hits.count.Add(delta)
+		}
+		return true
+	})
+}
+
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+func clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
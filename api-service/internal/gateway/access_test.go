@@ -0,0 +1,108 @@
+package gateway
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/pkg/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckAccess(t *testing.T) {
+	rules := []*model.AppGatewayAccessRule{
+		{ID: 1, GatewayID: 1, RuleType: constants.AccessRuleIPBlacklist, TargetPath: "/admin", TargetIP: "10.0.0.0/8",
+			Action: constants.AccessActionBlock},
+		{ID: 2, GatewayID: 1, RuleType: constants.AccessRuleIPWhitelist, TargetPath: "/internal/", TargetIP: "192.168.1.0/24",
+			Action: constants.AccessActionRedirect, RedirectURL: "https://example.com/denied"},
+		{ID: 3, GatewayID: 1, RuleType: constants.AccessRuleIPWhitelist, TargetPath: "/internal", TargetIP: "172.16.0.1",
+			Action: constants.AccessActionBlock},
+		{ID: 4, GatewayID: 1, RuleType: constants.AccessRuleIPBlacklist, TargetPath: "/audit", TargetIP: "8.8.8.8",
+			Action: constants.AccessActionAllow},
+		{ID: 5, GatewayID: 1, RuleType: constants.AccessRuleRateLimit, TargetPath: "/api", LimitCount: 2, TimeWindow: 60,
+			Action: constants.AccessActionBlock},
+		// 无效规则被跳过
+		{ID: 6, GatewayID: 1, RuleType: constants.AccessRuleIPBlacklist, TargetIP: "not-an-ip",
+			Action: constants.AccessActionBlock},
+	}
+	policy := buildPolicies(rules)[1]
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		path       string
+		wantStatus int // 0 表示放行
+	}{
+		{"blacklisted path", "10.1.2.3:1234", "/admin", http.StatusForbidden},
+		{"blacklisted sub path", "10.1.2.3:1234", "/admin/users", http.StatusForbidden},
+		{"path sharing prefix", "10.1.2.3:1234", "/administrator", 0},
+		{"double slash", "10.1.2.3:1234", "//admin/users", http.StatusForbidden},
+		{"dot segments", "10.1.2.3:1234", "/public/../admin", http.StatusForbidden},
+		{"other path", "10.1.2.3:1234", "/public", 0},
+		{"other ip", "8.8.4.4:1234", "/admin", 0},
+		{"whitelisted ip", "192.168.1.20:1234", "/internal/status", 0},
+		{"whitelists are merged", "172.16.0.1:1234", "/internal", 0},
+		{"not whitelisted redirects", "8.8.4.4:1234", "/internal/status", http.StatusFound},
+		{"whitelist path sharing prefix", "8.8.4.4:1234", "/internals", 0},
+		{"allow action only records", "8.8.8.8:1234", "/audit", 0},
+	}
+	g := &Gateway{limiter: ratelimit.NewLocal()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://app.example.com/", nil)
+			r.URL.Path = tt.path
+			r.RemoteAddr = tt.remoteAddr
+
+			allowed := g.checkAccess(w, r, policy)
+			if tt.wantStatus == 0 {
+				if !allowed {
+					t.Errorf("request was blocked with %d", w.Code)
+				}
+				return
+			}
+			if allowed || w.Code != tt.wantStatus {
+				t.Errorf("allowed = %v, status = %d, want %d", allowed, w.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	if _, ok := g.hits.Load(uint(4)); !ok {
+		t.Error("hit of ALLOW rule was not recorded")
+	}
+}
+
+func TestCheckAccessRateLimit(t *testing.T) {
+	policy := buildPolicies([]*model.AppGatewayAccessRule{
+		{ID: 1, GatewayID: 1, RuleType: constants.AccessRuleRateLimit, TargetPath: "/api", LimitCount: 2, TimeWindow: 60,
+			Action: constants.AccessActionBlock},
+	})[1]
+	g := &Gateway{limiter: ratelimit.NewLocal()}
+
+	request := func(remoteAddr, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://app.example.com"+path, nil)
+		r.RemoteAddr = remoteAddr
+		if !g.checkAccess(w, r, policy) && w.Code == http.StatusOK {
+			t.Fatalf("blocked request %s without status", path)
+		}
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := request("10.0.0.1:1234", "/api/items"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, w.Code)
+		}
+	}
+	w := request("10.0.0.1:1234", "/api")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("status = %d, Retry-After = %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	// 计数按客户端 IP 区分，不匹配路径的请求不计数
+	if w := request("10.0.0.2:1234", "/api"); w.Code != http.StatusOK {
+		t.Errorf("other client: status %d", w.Code)
+	}
+	if w := request("10.0.0.1:1234", "/apis"); w.Code != http.StatusOK {
+		t.Errorf("other path: status %d", w.Code)
+	}
+}
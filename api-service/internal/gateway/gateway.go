@@ -0,0 +1,458 @@
+package gateway
+
+import (
+	"api-service/internal/config"
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/internal/service"
+	"api-service/pkg/certutil"
+	"api-service/pkg/ratelimit"
+	"api-service/pkg/utils"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Gateway 内置反向代理网关
+// 按主机名将请求转发到已发布的应用实例端口，使用 SSLCertificate 中的证书终止 TLS，
+// 发布规则变更时通过 Redis 通知或定时轮询重新加载路由
+type Gateway struct {
+	cfg         config.GatewayConfig
+	gatewayRepo repository.GatewayRepository
+	certRepo    repository.CertificateRepository
+	rdb         *redis.Client
+	encryptor   *utils.Encryptor
+	monitor     service.MonitorService
+	transport   *http.Transport
+	limiter     ratelimit.Limiter
+	accessLog   *accessLogger
+	traffic     *trafficRecorder
+
+	table atomic.Pointer[routeTable]
+	hits  sync.Map // 规则 ID -> *ruleHits
+
+	mu        sync.Mutex
+	certCache map[uint]*cachedCertificate
+	// prevCache 上次加载的证书缓存，重新加载时只保留仍在使用的证书
+	prevCache map[uint]*cachedCertificate
+}
+
+// cachedCertificate 已解析的证书，证书更新后按 UpdatedAt 失效
+type cachedCertificate struct {
+	updatedAt   time.Time
+	certificate *tls.Certificate
+}
+
+// New 创建网关，monitor 为 nil 时不汇总流量，accessLog 为 nil 时不输出访问日志
+func New(cfg config.GatewayConfig, gatewayRepo repository.GatewayRepository, certRepo repository.CertificateRepository,
+	monitor service.MonitorService, rdb *redis.Client, encryptor *utils.Encryptor, accessLog io.Writer) *Gateway {
+	g := &Gateway{
+		cfg:         cfg,
+		gatewayRepo: gatewayRepo,
+		certRepo:    certRepo,
+		monitor:     monitor,
+		rdb:         rdb,
+		encryptor:   encryptor,
+		traffic:     newTrafficRecorder(),
+		transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           (&net.Dialer{Timeout: constants.GatewayDialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+			MaxIdleConns:          256,
+			MaxIdleConnsPerHost:   32,
+			IdleConnTimeout:       constants.GatewayIdleTimeout,
+			ExpectContinueTimeout: time.Second,
+		},
+		certCache: make(map[uint]*cachedCertificate),
+	}
+	if cfg.RateLimitStore == constants.GatewayRateLimitRedis {
+		g.limiter = ratelimit.NewRedis(rdb, constants.GatewayRateLimitKeyPrefix)
+	} else {
+		g.limiter = ratelimit.NewLocal()
+	}
+	if accessLog != nil {
+		g.accessLog = newAccessLogger(accessLog)
+	}
+	g.table.Store(newRouteTable())
+	return g
+}
+
+// Run 加载路由并启动 HTTP/HTTPS 监听，直到 ctx 结束
+func (g *Gateway) Run(ctx context.Context) error {
+	if err := g.Reload(); err != nil {
+		return err
+	}
+	g.setStatus(constants.GatewayStatusRunning)
+
+	go g.watch(ctx)
+	go g.flushLoop(ctx)
+	go g.trafficLoop(ctx)
+
+	servers := []*http.Server{{
+		Addr:              g.cfg.HTTPAddr,
+		Handler:           http.HandlerFunc(g.serveHTTP),
+		ReadHeaderTimeout: constants.GatewayReadHeaderTimeout,
+		IdleTimeout:       constants.GatewayIdleTimeout,
+		MaxHeaderBytes:    constants.DefaultMaxHeaderSize,
+	}}
+	if g.cfg.HTTPSAddr != "" {
+		servers = append(servers, &http.Server{
+			Addr:              g.cfg.HTTPSAddr,
+			Handler:           g,
+			ReadHeaderTimeout: constants.GatewayReadHeaderTimeout,
+			IdleTimeout:       constants.GatewayIdleTimeout,
+			MaxHeaderBytes:    constants.DefaultMaxHeaderSize,
+			TLSConfig: &tls.Config{
+				MinVersion:     tls.VersionTLS12,
+				GetCertificate: g.getCertificate,
+				NextProtos:     []string{"h2", "http/1.1"},
+			},
+		})
+	}
+
+	errCh := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			var err error
+			if srv.TLSConfig != nil {
+				log.Printf("Gateway listening on %s (HTTPS)", srv.Addr)
+				err = srv.ListenAndServeTLS("", "")
+			} else {
+				log.Printf("Gateway listening on %s (HTTP)", srv.Addr)
+				err = srv.ListenAndServe()
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}(srv)
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case runErr = <-errCh:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.GatewayShutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Gateway shutdown on %s failed: %v", srv.Addr, err)
+		}
+	}
+
+	g.flushHits()
+	g.flushTraffic()
+
+	if runErr != nil {
+		g.setStatus(constants.GatewayStatusError)
+		return runErr
+	}
+	g.setStatus(constants.GatewayStatusStopped)
+	return nil
+}
+
+// Reload 从数据库重新构建路由表，失败时保留原路由
+func (g *Gateway) Reload() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	publishes, err := g.gatewayRepo.ListPublishes(g.cfg.GatewayID)
+	if err != nil {
+		return err
+	}
+
+	rules, err := g.gatewayRepo.ListEnabledAccessRules(g.cfg.GatewayID)
+	if err != nil {
+		return err
+	}
+	policies := buildPolicies(rules)
+
+	g.prevCache, g.certCache = g.certCache, make(map[uint]*cachedCertificate)
+	defer func() { g.prevCache = nil }()
+
+	table := newRouteTable()
+	ownerCerts := make(map[uint][]*model.SSLCertificate)
+	for _, publish := range publishes {
+		upstream, err := service.PublishUpstream(publish)
+		if err != nil {
+			log.Printf("Skipping gateway publish %d: %v", publish.ID, err)
+			continue
+		}
+		target := &url.URL{Scheme: "http", Host: upstream}
+
+		// 发布规则按 id 排序，域名冲突时保留最早的发布规则
+		if existing := table.add(&route{
+			publish:     publish,
+			target:      target,
+			proxy:       g.newProxy(target),
+			certificate: g.resolveCertificate(publish, ownerCerts),
+			access:      policies[publish.AppGatewayID],
+		}); existing != nil {
+			log.Printf("Skipping gateway publish %d: domain %s is already served by publish %d",
+				publish.ID, publish.ServiceDomain, existing.publish.ID)
+		}
+	}
+
+	g.table.Store(table)
+	log.Printf("Gateway routes reloaded: %d routes", table.size())
+	return nil
+}
+
+// ServeHTTP 处理 HTTPS 请求
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt := g.table.Load().lookup(r.Host)
+	if rt == nil {
+		http.Error(w, "no route for host", http.StatusNotFound)
+		return
+	}
+	g.serveRoute(w, r, rt, false)
+}
+
+// serveHTTP 处理 HTTP 请求：响应 ACME HTTP-01 质询，配置了证书的域名跳转到 HTTPS
+func (g *Gateway) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, constants.ACMEHTTPChallengePath) {
+		g.serveACMEChallenge(w, r)
+		return
+	}
+
+	rt := g.table.Load().lookup(r.Host)
+	if rt == nil {
+		http.Error(w, "no route for host", http.StatusNotFound)
+		return
+	}
+	g.serveRoute(w, r, rt, rt.certificate != nil && g.cfg.HTTPSAddr != "")
+}
+
+// serveRoute 执行访问控制并转发请求，结束后记录访问日志与流量统计
+func (g *Gateway) serveRoute(w http.ResponseWriter, r *http.Request, rt *route, redirectHTTPS bool) {
+	start := time.Now()
+	rec := newResponseRecorder(w)
+	upstream := ""
+	defer func() { g.observe(rt, r, rec, upstream, start) }()
+
+	if !g.checkAccess(rec, r, rt.access) {
+		return
+	}
+	if redirectHTTPS {
+		http.Redirect(rec, r, g.httpsURL(r), http.StatusPermanentRedirect)
+		return
+	}
+	upstream = rt.target.Host
+	rt.proxy.ServeHTTP(rec, r)
+}
+
+// serveACMEChallenge 返回 ACMEService 写入 Redis 的质询响应
+func (g *Gateway) serveACMEChallenge(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, constants.ACMEHTTPChallengePath)
+	if token == "" || strings.Contains(token, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	keyAuth, err := g.rdb.Get(r.Context(), constants.ACMEHTTPChallengeKeyPrefix+token).Result()
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(keyAuth))
+}
+
+func (g *Gateway) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	rt := g.table.Load().lookup(hello.ServerName)
+	if rt == nil || rt.certificate == nil {
+		return nil, fmt.Errorf("no certificate for %q", hello.ServerName)
+	}
+	return rt.certificate, nil
+}
+
+func (g *Gateway) newProxy(target *url.URL) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			// 保留原始 Host，应用按访问域名生成链接
+			pr.Out.Host = pr.In.Host
+		},
+		Transport:     g.transport,
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("Gateway upstream %s for %s failed: %v", target.Host, r.Host, err)
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		},
+	}
+}
+
+// resolveCertificate 获取发布规则使用的证书，未指定时按域名匹配用户的有效证书
+func (g *Gateway) resolveCertificate(publish *model.AppGatewayPublish, ownerCerts map[uint][]*model.SSLCertificate) *tls.Certificate {
+	if publish.CertificateID != nil {
+		cert, err := g.certRepo.GetByID(*publish.CertificateID)
+		if err != nil || cert.OwnerID != publish.OwnerID {
+			log.Printf("Certificate %d for gateway publish %d unavailable: %v", *publish.CertificateID, publish.ID, err)
+			return nil
+		}
+		tlsCert, err := g.loadCertificate(cert)
+		if err != nil {
+			log.Printf("Failed to load certificate %d: %v", cert.ID, err)
+			return nil
+		}
+		return tlsCert
+	}
+
+	certs, ok := ownerCerts[publish.OwnerID]
+	if !ok {
+		var err error
+		certs, err = g.certRepo.ListByOwner(publish.OwnerID, constants.CertificateStatusValid)
+		if err != nil {
+			log.Printf("Failed to list certificates for owner %d: %v", publish.OwnerID, err)
+		}
+		ownerCerts[publish.OwnerID] = certs
+	}
+
+	// 证书按到期时间升序排列，取最后一个匹配项即有效期最长的证书
+	var matched *tls.Certificate
+	for _, cert := range certs {
+		tlsCert, err := g.loadCertificate(cert)
+		if err != nil {
+			continue
+		}
+		if tlsCert.Leaf != nil && certutil.CoversDomain(tlsCert.Leaf, publish.ServiceDomain) {
+			matched = tlsCert
+		}
+	}
+	return matched
+}
+
+func (g *Gateway) loadCertificate(cert *model.SSLCertificate) (*tls.Certificate, error) {
+	if cached, ok := g.certCache[cert.ID]; ok && cached.updatedAt.Equal(cert.UpdatedAt) {
+		return cached.certificate, nil
+	}
+	if cached, ok := g.prevCache[cert.ID]; ok && cached.updatedAt.Equal(cert.UpdatedAt) {
+		g.certCache[cert.ID] = cached
+		return cached.certificate, nil
+	}
+
+	keyPEM, err := g.encryptor.Decrypt(cert.PrivateKeyData)
+	if err != nil {
+		return nil, err
+	}
+	tlsCert, err := tls.X509KeyPair([]byte(cert.CertificateData+cert.CertificateChain), []byte(keyPEM))
+	if err != nil {
+		return nil, err
+	}
+
+	g.certCache[cert.ID] = &cachedCertificate{updatedAt: cert.UpdatedAt, certificate: &tlsCert}
+	return &tlsCert, nil
+}
+
+// watch 订阅重新加载通知并定时轮询
+func (g *Gateway) watch(ctx context.Context) {
+	pubsub := g.rdb.Subscribe(ctx, g.cfg.ReloadChannel)
+	defer pubsub.Close()
+
+	interval := time.Duration(g.cfg.PollInterval) * time.Second
+	if interval <= 0 {
+		interval = constants.DefaultGatewayPollSeconds * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if g.cfg.GatewayID != 0 && msg.Payload != strconv.FormatUint(uint64(g.cfg.GatewayID), 10) {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		if err := g.Reload(); err != nil {
+			log.Printf("Gateway reload failed: %v", err)
+		}
+	}
+}
+
+// flushLoop 定期写入访问控制规则的命中次数
+func (g *Gateway) flushLoop(ctx context.Context) {
+	ticker := time.NewTicker(constants.GatewayHitFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.flushHits()
+		}
+	}
+}
+
+// trafficLoop 按分钟写入流量汇总，对齐到整分钟
+func (g *Gateway) trafficLoop(ctx context.Context) {
+	interval := constants.GatewayTrafficRollupInterval
+	timer := time.NewTimer(time.Until(time.Now().Truncate(interval).Add(interval)))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			g.flushTraffic()
+			timer.Reset(time.Until(time.Now().Truncate(interval).Add(interval)))
+		}
+	}
+}
+
+// setStatus 更新本机网关的运行状态
+func (g *Gateway) setStatus(status string) {
+	if g.cfg.GatewayID == 0 {
+		return
+	}
+
+	gateway, err := g.gatewayRepo.GetByID(g.cfg.GatewayID)
+	if err != nil {
+		log.Printf("Failed to load gateway %d: %v", g.cfg.GatewayID, err)
+		return
+	}
+
+	now := time.Now()
+	gateway.Status = status
+	if status == constants.GatewayStatusRunning {
+		gateway.StartedAt = &now
+	} else {
+		gateway.StoppedAt = &now
+	}
+	if err := g.gatewayRepo.Update(gateway); err != nil {
+		log.Printf("Failed to update gateway %d status: %v", g.cfg.GatewayID, err)
+	}
+}
+
+func (g *Gateway) httpsURL(r *http.Request) string {
+	host := normalizeHost(r.Host)
+	if _, port, err := net.SplitHostPort(g.cfg.HTTPSAddr); err == nil && port != "" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	return "https://" + host + r.URL.RequestURI()
+}
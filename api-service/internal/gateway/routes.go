@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"api-service/internal/model"
+	"crypto/tls"
+	"net"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// route 一条发布规则对应的转发路由
+type route struct {
+	publish     *model.AppGatewayPublish
+	target      *url.URL
+	proxy       *httputil.ReverseProxy
+	certificate *tls.Certificate
+	access      *accessPolicy
+}
+
+// routeTable 某一时刻的完整路由快照，重新加载时整体替换
+type routeTable struct {
+	exact    map[string]*route
+	wildcard map[string]*route // key 为去掉 "*." 后的父域名
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{
+		exact:    make(map[string]*route),
+		wildcard: make(map[string]*route),
+	}
+}
+
+// add 添加路由，域名已被其他发布规则占用时不覆盖，返回占用的路由
+func (t *routeTable) add(r *route) *route {
+	domain, routes := r.publish.ServiceDomain, t.exact
+	if strings.HasPrefix(domain, "*.") {
+		domain, routes = domain[2:], t.wildcard
+	}
+	if existing, ok := routes[domain]; ok {
+		return existing
+	}
+	routes[domain] = r
+	return nil
+}
+
+// lookup 按主机名匹配路由，精确匹配优先于通配符
+func (t *routeTable) lookup(host string) *route {
+	host = normalizeHost(host)
+	if r, ok := t.exact[host]; ok {
+		return r
+	}
+	if i := strings.IndexByte(host, '.'); i > 0 {
+		if r, ok := t.wildcard[host[i+1:]]; ok {
+			return r
+		}
+	}
+	return nil
+}
+
+func (t *routeTable) size() int {
+	return len(t.exact) + len(t.wildcard)
+}
+
+// normalizeHost 去掉端口并转为小写
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"api-service/internal/model"
+	"testing"
+)
+
+func TestRouteTable(t *testing.T) {
+	newRoute := func(id uint, domain string) *route {
+		return &route{publish: &model.AppGatewayPublish{ID: id, ServiceDomain: domain}}
+	}
+
+	table := newRouteTable()
+	for _, r := range []*route{
+		newRoute(1, "app.example.com"),
+		newRoute(2, "*.example.com"),
+		newRoute(3, "other.example.org"),
+	} {
+		if existing := table.add(r); existing != nil {
+			t.Fatalf("add(%s) collided with publish %d", r.publish.ServiceDomain, existing.publish.ID)
+		}
+	}
+
+	// 同名域名不覆盖已有路由
+	for domain, want := range map[string]uint{"app.example.com": 1, "*.example.com": 2} {
+		existing := table.add(newRoute(9, domain))
+		if existing == nil || existing.publish.ID != want {
+			t.Errorf("add(%s) = %v, want existing publish %d", domain, existing, want)
+		}
+	}
+	if table.size() != 3 {
+		t.Errorf("size() = %d, want 3", table.size())
+	}
+
+	tests := []struct {
+		host string
+		want uint // 0 表示没有路由
+	}{
+		{"app.example.com", 1},
+		{"APP.example.com:8443", 1},
+		{"app.example.com.", 1},
+		{"www.example.com", 2},
+		{"a.b.example.com", 0},
+		{"example.com", 0},
+		{"other.example.org", 3},
+		{"unknown.test", 0},
+	}
+	for _, tt := range tests {
+		var got uint
+		if r := table.lookup(tt.host); r != nil {
+			got = r.publish.ID
+		}
+		if got != tt.want {
+			t.Errorf("lookup(%q) = publish %d, want %d", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package gateway
+
+import (
+	"api-service/internal/constants"
+	"api-service/pkg/histogram"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// responseRecorder 记录响应状态码与写出字节数
+// 实现 Unwrap 以便 http.ResponseController 找到底层连接，WebSocket 升级与流式响应不受影响
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// statusCode 未写出任何内容的请求(如客户端提前断开)按 200 统计，WebSocket 升级为 101
+func (r *responseRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// accessLogEntry 一条 JSON 格式的访问日志
+type accessLogEntry struct {
+	Time      time.Time `json:"time"`
+	GatewayID uint      `json:"gateway_id"`
+	PublishID uint      `json:"publish_id"`
+	Host      string    `json:"host"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Protocol  string    `json:"protocol"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	LatencyMs float64   `json:"latency_ms"`
+	Upstream  string    `json:"upstream"`
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	Referer   string    `json:"referer,omitempty"`
+}
+
+// accessLogger 按行写出访问日志，写入串行化以保证每行完整
+type accessLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newAccessLogger(w io.Writer) *accessLogger {
+	return &accessLogger{enc: json.NewEncoder(w)}
+}
+
+func (l *accessLogger) write(entry *accessLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(entry); err != nil {
+		log.Printf("Failed to write gateway access log: %v", err)
+	}
+}
+
+// trafficKey 流量汇总维度
+type trafficKey struct {
+	gatewayID uint
+	domain    string
+	path      string
+	status    int
+}
+
+type trafficStat struct {
+	requests   int64
+	bytes      int64
+	latencySum float64
+}
+
+type latencyKey struct {
+	gatewayID uint
+	domain    string
+}
+
+// trafficRecorder 按分钟汇总请求数、流量与延迟分布
+// 每个域名记录的路径数有上限，路径集合跨汇总周期保留，之后出现的新路径归入 __other__，避免指标基数随时间膨胀
+type trafficRecorder struct {
+	mu          sync.Mutex
+	windowStart time.Time
+	stats       map[trafficKey]*trafficStat
+	latency     map[latencyKey]*histogram.Histogram
+	paths       map[string]map[string]struct{}
+}
+
+func newTrafficRecorder() *trafficRecorder {
+	t := &trafficRecorder{paths: make(map[string]map[string]struct{})}
+	t.reset(time.Now())
+	return t
+}
+
+// reset 开始新的汇总周期，已记录的路径集合不清空
+func (t *trafficRecorder) reset(now time.Time) {
+	t.windowStart = now.Truncate(constants.GatewayTrafficRollupInterval)
+	t.stats = make(map[trafficKey]*trafficStat)
+	t.latency = make(map[latencyKey]*histogram.Histogram)
+}
+
+func (t *trafficRecorder) record(gatewayID uint, domain, path string, status int, bytes int64, latencyMs float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path = t.boundPath(domain, path)
+	key := trafficKey{gatewayID: gatewayID, domain: domain, path: path, status: status}
+	stat, ok := t.stats[key]
+	if !ok {
+		stat = &trafficStat{}
+		t.stats[key] = stat
+	}
+	stat.requests++
+	stat.bytes += bytes
+	stat.latencySum += latencyMs
+
+	lkey := latencyKey{gatewayID: gatewayID, domain: domain}
+	h, ok := t.latency[lkey]
+	if !ok {
+		h = histogram.New(constants.GatewayLatencyBuckets)
+		t.latency[lkey] = h
+	}
+	h.Observe(latencyMs)
+}
+
+// boundPath 截断过长路径并限制单个域名的路径数
+func (t *trafficRecorder) boundPath(domain, path string) string {
+	if len(path) > constants.GatewayMaxPathLength {
+		path = path[:constants.GatewayMaxPathLength]
+	}
+
+	seen, ok := t.paths[domain]
+	if !ok {
+		seen = make(map[string]struct{})
+		t.paths[domain] = seen
+	}
+	if _, ok := seen[path]; ok {
+		return path
+	}
+	if len(seen) >= constants.GatewayMaxPathsPerDomain {
+		return constants.GatewayOtherPath
+	}
+	seen[path] = struct{}{}
+	return path
+}
+
+// flushTraffic 将当前周期的汇总写入指标库，时间戳为周期起始时间
+func (g *Gateway) flushTraffic() {
+	if g.monitor == nil {
+		return
+	}
+
+	t := g.traffic
+	t.mu.Lock()
+	windowStart, stats, latency := t.windowStart, t.stats, t.latency
+	t.reset(time.Now())
+	t.mu.Unlock()
+
+	if len(stats) == 0 {
+		return
+	}
+
+	for key, stat := range stats {
+		tags := map[string]string{
+			"gateway_id": strconv.FormatUint(uint64(key.gatewayID), 10),
+			"domain":     key.domain,
+			"path":       key.path,
+			"status":     strconv.Itoa(key.status),
+		}
+		fields := map[string]interface{}{
+			"requests":       stat.requests,
+			"bytes":          stat.bytes,
+			"latency_ms_sum": stat.latencySum,
+		}
+		if err := g.monitor.WriteMetricsAt(constants.MeasurementGatewayRequests, tags, fields, windowStart); err != nil {
+			log.Printf("Failed to write gateway traffic for %s: %v", key.domain, err)
+		}
+	}
+
+	for key, h := range latency {
+		tags := map[string]string{
+			"gateway_id": strconv.FormatUint(uint64(key.gatewayID), 10),
+			"domain":     key.domain,
+		}
+		if err := g.monitor.WriteMetricsAt(constants.MeasurementGatewayLatency, tags, h.Fields(), windowStart); err != nil {
+			log.Printf("Failed to write gateway latency for %s: %v", key.domain, err)
+		}
+	}
+	g.monitor.Flush()
+}
+
+// observe 记录一次请求的访问日志与流量统计，被拦截或跳转的请求 upstream 为空
+func (g *Gateway) observe(rt *route, r *http.Request, rec *responseRecorder, upstream string, start time.Time) {
+	latency := time.Since(start)
+	latencyMs := float64(latency.Microseconds()) / 1000
+	status := rec.statusCode()
+	publish := rt.publish
+
+	if g.monitor != nil {
+		g.traffic.record(publish.AppGatewayID, publish.ServiceDomain, r.URL.Path, status, rec.bytes, latencyMs)
+	}
+
+	if publish.AuditLogEnabled != 1 || g.accessLog == nil {
+		return
+	}
+	ip := ""
+	if addr := clientIP(r); addr != nil {
+		ip = addr.String()
+	}
+	g.accessLog.write(&accessLogEntry{
+		Time:      start,
+		GatewayID: publish.AppGatewayID,
+		PublishID: publish.ID,
+		Host:      normalizeHost(r.Host),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Protocol:  r.Proto,
+		Status:    status,
+		Bytes:     rec.bytes,
+		LatencyMs: latencyMs,
+		Upstream:  upstream,
+		ClientIP:  ip,
+		UserAgent: r.UserAgent(),
+		Referer:   r.Referer(),
+	})
+}
@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"api-service/internal/constants"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTrafficRecorderBoundsPaths(t *testing.T) {
+	rec := newTrafficRecorder()
+	paths := func() map[string]bool {
+		seen := make(map[string]bool)
+		for key := range rec.stats {
+			seen[key.path] = true
+		}
+		return seen
+	}
+
+	for i := 0; i < constants.GatewayMaxPathsPerDomain+10; i++ {
+		rec.record(1, "app.example.com", "/p/"+strconv.Itoa(i), 200, 0, 1)
+	}
+	if got := paths(); len(got) != constants.GatewayMaxPathsPerDomain+1 || !got[constants.GatewayOtherPath] {
+		t.Fatalf("first window recorded %d paths", len(got))
+	}
+
+	// 新周期中已记录的路径保留，新路径仍归入 __other__
+	rec.reset(time.Now().Add(time.Minute))
+	rec.record(1, "app.example.com", "/p/0", 200, 0, 1)
+	rec.record(1, "app.example.com", "/new", 200, 0, 1)
+	if got := paths(); len(got) != 2 || !got["/p/0"] || !got[constants.GatewayOtherPath] {
+		t.Errorf("second window recorded paths %v", got)
+	}
+
+	// 上限按域名计算
+	rec.record(1, "other.example.com", "/new", 200, 0, 1)
+	if _, ok := rec.paths["other.example.com"]["/new"]; !ok {
+		t.Error("path of another domain was not recorded")
+	}
+
+	long := "/" + string(make([]byte, constants.GatewayMaxPathLength*2))
+	if got := rec.boundPath("other.example.com", long); len(got) != constants.GatewayMaxPathLength {
+		t.Errorf("boundPath() kept %d bytes, want %d", len(got), constants.GatewayMaxPathLength)
+	}
+}
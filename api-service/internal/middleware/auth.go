@@ -4,6 +4,7 @@ import (
 	"api-service/internal/config"
 	"api-service/pkg/auth"
 	"api-service/pkg/response"
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
@@ -41,3 +42,47 @@ func JWTAuth(cfg *config.Config) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// ScrapeAuth 校验 Prometheus 抓取使用的 Bearer 令牌，token 为空时不校验
+func ScrapeAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		expected := "Bearer " + token
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) != 1 {
+			response.Error(c, http.StatusUnauthorized, "Invalid scrape token", "")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole 要求 JWT 中的角色为 role，须在 JWTAuth 之后使用
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != role {
+			response.Error(c, http.StatusForbidden, "Permission denied", "")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// StreamTicketAuth 以 ticket 查询参数中的一次性凭证认证推送连接，浏览器的 EventSource 无法携带 Authorization 头
+func StreamTicketAuth(redeem func(ticket string) (uint, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := redeem(c.Query("ticket"))
+		if err != nil {
+			response.Error(c, http.StatusUnauthorized, "Invalid stream ticket", err.Error())
+			c.Abort()
+			return
+		}
+		c.Set("user_id", userID)
+		c.Next()
+	}
+}
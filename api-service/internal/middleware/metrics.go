@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/telemetry"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics 按路由模板记录请求耗时
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = constants.TelemetryUnmatchedRoute
+		}
+		telemetry.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -227,7 +227,7 @@ type AppDeployment struct {
 	StartTime     *time.Time        `json:"start_time"`
 	EndTime       *time.Time        `json:"end_time"`
 	ErrorMessage  string            `json:"error_message" gorm:"type:text"`
-	DeploymentLog string            `json:"deployment_log" gorm:"type:text"`
+	DeploymentLog string            `json:"deployment_log" gorm:"type:text"` // 已废弃：日志保存在 LogSegment，只保留升级前的部署日志
 	ConfigData    string            `json:"config_data" gorm:"type:json"`
 	OwnerID       uint              `json:"owner_id" gorm:"not null"`
 	Owner         User              `json:"owner" gorm:"foreignKey:OwnerID"`
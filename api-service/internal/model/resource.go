@@ -73,7 +73,7 @@ type Server struct {
 	MemoryTotal     int64          `json:"memory_total" gorm:"default:0"` // MB
 	DiskTotal       int64          `json:"disk_total" gorm:"default:0"`   // MB
 	Architecture    string         `json:"architecture"`
-	Status          string         `json:"status" gorm:"default:UNKNOWN"` // UNKNOWN, RUNNING, STOPPED, ERROR
+	Status          string         `json:"status" gorm:"default:UNKNOWN"` // UNKNOWN, RUNNING, OFFLINE, STOPPED, ERROR
 	LastHeartbeatAt *time.Time     `json:"last_heartbeat_at"`
 	ResourceGroupID *uint          `json:"resource_group_id"`
 	ResourceGroup   *ResourceGroup `json:"resource_group" gorm:"foreignKey:ResourceGroupID"`
@@ -95,6 +95,7 @@ type ServerAgent struct {
 	ID              uint           `json:"id" gorm:"primarykey"`
 	ServerID        uint           `json:"server_id" gorm:"not null"`
 	Server          Server         `json:"server" gorm:"foreignKey:ServerID"`
+	AgentID         string         `json:"agent_id" gorm:"index"` // Agent 配置中的唯一标识，用于任务通道寻址
 	ContainerID     string         `json:"container_id"`
 	AgentIP         string         `json:"agent_ip"`
 	AgentPort       int            `json:"agent_port" gorm:"default:22"`
@@ -15,10 +15,12 @@ type SSLCertificate struct {
 	ID     uint   `json:"id" gorm:"primarykey"`
 	Name   string `json:"name" gorm:"not null" binding:"required"`
 	Domain string `json:"domain" gorm:"not null" binding:"required"`
-	// CertificateType 证书类型: LETS_ENCRYPT, COMMERCIAL, SELF_SIGNED
+	// Domains 证书包含的全部域名(JSON 数组)，重新签发与续期时使用
+	Domains string `json:"domains" gorm:"type:json"`
+	// CertificateType 证书类型: LETS_ENCRYPT, COMMERCIAL, SELF_SIGNED, INTERNAL
 	CertificateType  string         `json:"certificate_type" gorm:"default:LETS_ENCRYPT"`
 	CertificateData  string         `json:"certificate_data" gorm:"type:text;not null"`
-	PrivateKeyData   string         `json:"private_key_data" gorm:"type:text;not null"`
+	PrivateKeyData   string         `json:"-" gorm:"type:text;not null"` // 加密存储
 	CertificateChain string         `json:"certificate_chain" gorm:"type:text"`
 	Issuer           string         `json:"issuer"`
 	Subject          string         `json:"subject"`
@@ -26,7 +28,11 @@ type SSLCertificate struct {
 	NotBefore        *time.Time     `json:"not_before"`
 	NotAfter         *time.Time     `json:"not_after"`
 	AutoRenew        int8           `json:"auto_renew" gorm:"default:0"`
-	Status           string         `json:"status" gorm:"default:PENDING"` // PENDING, VALID, EXPIRED, REVOKED
+	ChallengeType    string         `json:"challenge_type"` // ACME 质询方式: http-01, dns-01
+	DNSProvider      string         `json:"dns_provider"`   // dns-01 使用的服务商配置名
+	CAID             *uint          `json:"ca_id"`          // 签发该证书的内部 CA
+	LastError        string         `json:"last_error" gorm:"type:text"`
+	Status           string         `json:"status" gorm:"default:PENDING"` // PENDING, VALID, EXPIRED, REVOKED, FAILED
 	OwnerID          uint           `json:"owner_id" gorm:"not null"`
 	Owner            User           `json:"owner" gorm:"foreignKey:OwnerID"`
 	CreatedAt        time.Time      `json:"created_at"`
@@ -34,12 +40,33 @@ type SSLCertificate struct {
 	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// CertificateAuthority 内部 CA 表
+type CertificateAuthority struct {
+	ID              uint           `json:"id" gorm:"primarykey"`
+	Name            string         `json:"name" gorm:"not null" binding:"required"`
+	CommonName      string         `json:"common_name" gorm:"not null"`
+	Organization    string         `json:"organization"`
+	KeyType         string         `json:"key_type" gorm:"not null"` // RSA2048, RSA4096, ECDSA_P256, ECDSA_P384, ED25519
+	CertificateData string         `json:"certificate_data" gorm:"type:text;not null"`
+	PrivateKeyData  string         `json:"-" gorm:"type:text;not null"` // 加密存储
+	SerialNumber    string         `json:"serial_number"`
+	NotBefore       *time.Time     `json:"not_before"`
+	NotAfter        *time.Time     `json:"not_after"`
+	Description     string         `json:"description" gorm:"type:text"`
+	OwnerID         uint           `json:"owner_id" gorm:"not null"`
+	Owner           User           `json:"owner" gorm:"foreignKey:OwnerID"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
 // SecretKey 密钥管理表
 type SecretKey struct {
 	ID               uint           `json:"id" gorm:"primarykey"`
 	Name             string         `json:"name" gorm:"not null" binding:"required"`
 	KeyType          string         `json:"key_type" gorm:"not null"` // API_KEY, DATABASE, SSH, CERTIFICATE, CUSTOM
-	EncryptedValue   string         `json:"encrypted_value" gorm:"type:text;not null"`
+	EncryptedValue   string         `json:"-" gorm:"type:text;not null"`
+	CurrentVersion   int            `json:"current_version" gorm:"default:1"`
 	Description      string         `json:"description" gorm:"type:text"`
 	CustomFields     string         `json:"custom_fields" gorm:"type:json"`
 	AuthorizedUsers  string         `json:"authorized_users" gorm:"type:json"`
@@ -50,6 +77,33 @@ type SecretKey struct {
 	CreatedAt        time.Time      `json:"created_at"`
 	UpdatedAt        time.Time      `json:"updated_at"`
 	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 关联关系
+	Versions []SecretKeyVersion `json:"versions,omitempty" gorm:"foreignKey:SecretKeyID"`
+}
+
+// SecretKeyVersion 密钥版本历史表
+type SecretKeyVersion struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	SecretKeyID    uint      `json:"secret_key_id" gorm:"not null;uniqueIndex:idx_secret_key_version"`
+	Version        int       `json:"version" gorm:"not null;uniqueIndex:idx_secret_key_version"`
+	EncryptedValue string    `json:"-" gorm:"type:text;not null"`
+	ChangeNote     string    `json:"change_note"`
+	CreatedBy      uint      `json:"created_by" gorm:"not null"`
+	Creator        User      `json:"creator" gorm:"foreignKey:CreatedBy"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SecretKeyReference 密钥引用记录表，记录部署中引用的密钥及版本
+type SecretKeyReference struct {
+	ID            uint          `json:"id" gorm:"primarykey"`
+	SecretKeyID   uint          `json:"secret_key_id" gorm:"not null;index"`
+	SecretKey     SecretKey     `json:"-" gorm:"foreignKey:SecretKeyID"`
+	SecretVersion int           `json:"secret_version" gorm:"not null"`
+	DeploymentID  uint          `json:"deployment_id" gorm:"not null;index"`
+	Deployment    AppDeployment `json:"-" gorm:"foreignKey:DeploymentID"`
+	AppInstanceID *uint         `json:"app_instance_id" gorm:"index"`
+	CreatedAt     time.Time     `json:"created_at"`
 }
 
 // AppGateway 应用网关表
@@ -85,6 +139,8 @@ type AppGatewayPublish struct {
 	AppGateway         AppGateway     `json:"app_gateway" gorm:"foreignKey:AppGatewayID"`
 	ServiceDomain      string         `json:"service_domain" gorm:"not null"`
 	ServicePort        int            `json:"service_port" gorm:"default:8080"`
+	UpstreamHost       string         `json:"upstream_host"`  // 应用实例所在服务器的 IP 或主机名，为空时自动选择
+	CertificateID      *uint          `json:"certificate_id"` // 为空时按域名匹配有效证书
 	AlertRuleID        *uint          `json:"alert_rule_id"`
 	AlertRule          *AlertRule     `json:"alert_rule" gorm:"foreignKey:AlertRuleID"`
 	LimitRules         string         `json:"limit_rules" gorm:"type:text"`
@@ -99,20 +155,23 @@ type AppGatewayPublish struct {
 
 // AppGatewayAccessRule 应用网关访问控制规则表
 type AppGatewayAccessRule struct {
-	ID         uint           `json:"id" gorm:"primarykey"`
-	GatewayID  uint           `json:"gateway_id" gorm:"not null"`
-	Gateway    AppGateway     `json:"gateway" gorm:"foreignKey:GatewayID"`
-	RuleName   string         `json:"rule_name" gorm:"not null" binding:"required"`
-	RuleType   string         `json:"rule_type" gorm:"not null"` // IP_WHITELIST, IP_BLACKLIST, RATE_LIMIT
-	LimitCount int            `json:"limit_count" gorm:"not null"`
-	TimeWindow int            `json:"time_window" gorm:"not null"` // 秒
-	TargetPath string         `json:"target_path"`
-	TargetIP   string         `json:"target_ip"`
-	Action     string         `json:"action" gorm:"default:BLOCK"` // BLOCK, ALLOW, REDIRECT
-	Status     int8           `json:"status" gorm:"default:1"`     // 0-禁用，1-启用
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint           `json:"id" gorm:"primarykey"`
+	GatewayID   uint           `json:"gateway_id" gorm:"not null"`
+	Gateway     AppGateway     `json:"gateway" gorm:"foreignKey:GatewayID"`
+	RuleName    string         `json:"rule_name" gorm:"not null" binding:"required"`
+	RuleType    string         `json:"rule_type" gorm:"not null"` // IP_WHITELIST, IP_BLACKLIST, RATE_LIMIT
+	LimitCount  int            `json:"limit_count" gorm:"not null"`
+	TimeWindow  int            `json:"time_window" gorm:"not null"` // 秒
+	TargetPath  string         `json:"target_path"`                 // 路径前缀，按路径段匹配，为空时作用于全部请求
+	TargetIP    string         `json:"target_ip"`                   // IP 或 CIDR，多个以逗号分隔
+	Action      string         `json:"action" gorm:"default:BLOCK"` // BLOCK, ALLOW, REDIRECT
+	RedirectURL string         `json:"redirect_url"`
+	Status      int8           `json:"status" gorm:"default:1"` // 0-禁用，1-启用
+	HitCount    int64          `json:"hit_count" gorm:"default:0"`
+	LastHitAt   *time.Time     `json:"last_hit_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // AuditLog 审计日志表
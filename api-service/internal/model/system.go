@@ -174,6 +174,8 @@ type AlertRule struct {
 	IsEnabled            int8           `json:"is_enabled" gorm:"default:1"`
 	OwnerID              uint           `json:"owner_id" gorm:"not null"`
 	Owner                User           `json:"owner" gorm:"foreignKey:OwnerID"`
+	PendingSince         *time.Time     `json:"pending_since"`     // 条件开始满足的时间，持续 for 时长后触发
+	LastEvaluatedAt      *time.Time     `json:"last_evaluated_at"` // 多个实例以此认领每一轮评估
 	CreatedAt            time.Time      `json:"created_at"`
 	UpdatedAt            time.Time      `json:"updated_at"`
 	DeletedAt            gorm.DeletedAt `json:"-" gorm:"index"`
@@ -194,12 +196,61 @@ type AlertRecord struct {
 	AcknowledgedBy       *uint          `json:"acknowledged_by"`
 	ResolutionNote       string         `json:"resolution_note" gorm:"type:text"`
 	NotificationSent     int8           `json:"notification_sent" gorm:"default:0"`
+	ResolvedNotified     int8           `json:"resolved_notified" gorm:"default:0"` // 已发送恢复通知，保证只发送一次
 	NotificationChannels string         `json:"notification_channels" gorm:"type:json"`
+	Suppressed           int8           `json:"suppressed" gorm:"default:0"` // 触发时处于静默或维护窗口内，不发送通知
+	SuppressedBy         string         `json:"suppressed_by"`               // 如 silence:3、maintenance:2
 	CreatedAt            time.Time      `json:"created_at"`
 	UpdatedAt            time.Time      `json:"updated_at"`
 	DeletedAt            gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// AlertSilence 告警静默表，Matchers 为标签到取值的 JSON 对象，在 StartsAt 至 EndsAt 期间触发且全部标签匹配的告警被抑制
+type AlertSilence struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	Matchers  string         `json:"matchers" gorm:"type:json;not null"`
+	Comment   string         `json:"comment" gorm:"type:text"`
+	StartsAt  time.Time      `json:"starts_at" gorm:"not null"`
+	EndsAt    time.Time      `json:"ends_at" gorm:"not null;index"`
+	OwnerID   uint           `json:"owner_id" gorm:"not null"`
+	Owner     User           `json:"owner" gorm:"foreignKey:OwnerID"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// MaintenanceWindow 维护窗口表，按星期重复，窗口内服务器或资源组下服务器触发的告警被抑制
+type MaintenanceWindow struct {
+	ID              uint           `json:"id" gorm:"primarykey"`
+	Name            string         `json:"name" gorm:"not null" binding:"required"`
+	ServerID        *uint          `json:"server_id" gorm:"index"`
+	ResourceGroupID *uint          `json:"resource_group_id" gorm:"index"`
+	Weekdays        string         `json:"weekdays"`                   // 逗号分隔的星期 0-6，0 为周日，为空表示每天
+	StartTime       string         `json:"start_time" gorm:"not null"` // HH:MM
+	DurationMinutes int            `json:"duration_minutes" gorm:"not null"`
+	Timezone        string         `json:"timezone" gorm:"default:UTC"`
+	IsEnabled       int8           `json:"is_enabled" gorm:"default:1"`
+	Description     string         `json:"description" gorm:"type:text"`
+	OwnerID         uint           `json:"owner_id" gorm:"not null"`
+	Owner           User           `json:"owner" gorm:"foreignKey:OwnerID"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// AlertBaseline 异常检测规则学习到的基线，State 为 anomaly 包序列化的基线状态
+// SeriesKey 标识指标序列，规则的目标、指标或基线方法变化后基线重新学习
+type AlertBaseline struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	AlertRuleID  uint      `json:"alert_rule_id" gorm:"uniqueIndex;not null"`
+	SeriesKey    string    `json:"series_key" gorm:"not null"`
+	Method       string    `json:"method" gorm:"not null"`
+	State        string    `json:"state" gorm:"type:text"`
+	TrainedUntil time.Time `json:"trained_until"` // 已学习样本的最晚窗口结束时间
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
 // NotificationTemplate 通知模板表
 type NotificationTemplate struct {
 	ID        uint           `json:"id" gorm:"primarykey"`
@@ -215,24 +266,28 @@ type NotificationTemplate struct {
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
-// NotificationRecord 通知记录表
+// NotificationRecord 通知记录表，每个渠道的每个接收者一条，记录投递结果与重试状态
 type NotificationRecord struct {
-	ID            uint                  `json:"id" gorm:"primarykey"`
-	TemplateID    *uint                 `json:"template_id"`
-	Template      *NotificationTemplate `json:"template" gorm:"foreignKey:TemplateID"`
-	Type          string                `json:"type" gorm:"not null"` // EMAIL, SMS, WEBHOOK, PUSH
-	Recipient     string                `json:"recipient" gorm:"not null"`
-	Subject       string                `json:"subject"`
-	Content       string                `json:"content" gorm:"type:text;not null"`
-	Status        string                `json:"status" gorm:"default:PENDING"` // PENDING, SENT, FAILED
-	SentAt        *time.Time            `json:"sent_at"`
-	ErrorMsg      string                `json:"error_msg" gorm:"type:text"`
-	RetryCount    int                   `json:"retry_count" gorm:"default:0"`
-	ReferenceID   string                `json:"reference_id"`
-	ReferenceType string                `json:"reference_type"`
-	CreatedAt     time.Time             `json:"created_at"`
-	UpdatedAt     time.Time             `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt        `json:"-" gorm:"index"`
+	ID             uint                  `json:"id" gorm:"primarykey"`
+	NotificationID *uint                 `json:"notification_id" gorm:"index"`
+	Notification   *Notification         `json:"-" gorm:"foreignKey:NotificationID"`
+	TemplateID     *uint                 `json:"template_id"`
+	Template       *NotificationTemplate `json:"template" gorm:"foreignKey:TemplateID"`
+	Channel        string                `json:"channel"`              // 配置中的渠道名称
+	Type           string                `json:"type" gorm:"not null"` // EMAIL, SMS, WEBHOOK, PUSH
+	Recipient      string                `json:"recipient" gorm:"not null"`
+	Subject        string                `json:"subject"`
+	Content        string                `json:"content" gorm:"type:text;not null"`
+	Status         string                `json:"status" gorm:"default:PENDING"` // PENDING, SENT, FAILED
+	SentAt         *time.Time            `json:"sent_at"`
+	ErrorMsg       string                `json:"error_msg" gorm:"type:text"`
+	RetryCount     int                   `json:"retry_count" gorm:"default:0"` // 失败次数
+	NextRetryAt    *time.Time            `json:"next_retry_at" gorm:"index"`   // 待投递记录的下次投递时间
+	ReferenceID    string                `json:"reference_id"`
+	ReferenceType  string                `json:"reference_type"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt        `json:"-" gorm:"index"`
 }
 
 // Notification 通知消息表
@@ -261,10 +316,10 @@ type Notification struct {
 // UserNotification 用户通知记录表
 type UserNotification struct {
 	ID             uint         `json:"id" gorm:"primarykey"`
-	NotificationID uint         `json:"notification_id" gorm:"not null"`
+	NotificationID uint         `json:"notification_id" gorm:"not null;index"`
 	Notification   Notification `json:"notification" gorm:"foreignKey:NotificationID"`
-	UserID         uint         `json:"user_id" gorm:"not null"`
-	User           User         `json:"user" gorm:"foreignKey:UserID"`
+	UserID         uint         `json:"user_id" gorm:"not null;index"`
+	User           User         `json:"-" gorm:"foreignKey:UserID"`
 	IsRead         int8         `json:"is_read" gorm:"default:0"`
 	ReadAt         *time.Time   `json:"read_at"`
 	IsDeleted      int8         `json:"is_deleted" gorm:"default:0"`
@@ -273,11 +328,12 @@ type UserNotification struct {
 }
 
 // WebhookConfig Webhook配置表
+// Events 为订阅的事件列表(JSON 数组)，Headers 为附加请求头(JSON 对象)，Secret 加密存储
 type WebhookConfig struct {
 	ID         uint           `json:"id" gorm:"primarykey"`
 	Name       string         `json:"name" gorm:"not null" binding:"required"`
 	URL        string         `json:"url" gorm:"not null" binding:"required,url"`
-	Secret     string         `json:"secret"`
+	Secret     string         `json:"-"`
 	Events     string         `json:"events" gorm:"type:json;not null"`
 	Headers    string         `json:"headers" gorm:"type:json"`
 	Timeout    int            `json:"timeout" gorm:"default:30"`
@@ -290,21 +346,25 @@ type WebhookConfig struct {
 	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
-// WebhookLog Webhook执行日志表
+// WebhookLog Webhook执行日志表，每条记录为一次事件投递，失败后按指数退避重试
 type WebhookLog struct {
 	ID           uint          `json:"id" gorm:"primarykey"`
-	WebhookID    uint          `json:"webhook_id" gorm:"not null"`
-	Webhook      WebhookConfig `json:"webhook" gorm:"foreignKey:WebhookID"`
+	WebhookID    uint          `json:"webhook_id" gorm:"not null;index"`
+	Webhook      WebhookConfig `json:"-" gorm:"foreignKey:WebhookID"`
 	EventType    string        `json:"event_type" gorm:"not null"`
 	Payload      string        `json:"payload" gorm:"type:json;not null"`
-	RequestID    string        `json:"request_id" gorm:"not null"`
+	RequestID    string        `json:"request_id" gorm:"not null"` // 投递ID，即 X-Webox-Delivery 请求头
 	StatusCode   *int          `json:"status_code"`
 	ResponseBody string        `json:"response_body" gorm:"type:text"`
 	ResponseTime int           `json:"response_time" gorm:"default:0"` // 毫秒
-	RetryCount   int           `json:"retry_count" gorm:"default:0"`
+	RetryCount   int           `json:"retry_count" gorm:"default:0"`   // 失败次数
 	Success      int8          `json:"success" gorm:"default:0"`
 	ErrorMessage string        `json:"error_message" gorm:"type:text"`
+	Status       string        `json:"status" gorm:"default:PENDING"` // PENDING, SUCCEEDED, FAILED
+	NextRetryAt  *time.Time    `json:"next_retry_at" gorm:"index"`    // 待投递记录的下次投递时间
+	RedeliveryOf *uint         `json:"redelivery_of"`                 // 重新投递时为原投递记录ID
 	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
 }
 
 // RepositoryConfig 软件源配置表
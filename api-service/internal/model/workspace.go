@@ -11,26 +11,55 @@ import (
 // ========================================
 
 // UserFile 用户文件表
+// Path 为从根目录开始的完整路径(如 /docs/a.txt)，移动或重命名目录时同步更新子孙的 Path；StoragePath 为文件内容在对象存储中的键
 type UserFile struct {
 	ID            uint           `json:"id" gorm:"primarykey"`
-	UserID        uint           `json:"user_id" gorm:"not null"`
+	UserID        uint           `json:"user_id" gorm:"not null;index:idx_user_file_parent"`
 	User          User           `json:"user" gorm:"foreignKey:UserID"`
 	Name          string         `json:"name" gorm:"not null" binding:"required"`
-	Path          string         `json:"path" gorm:"not null"`
+	Path          string         `json:"path" gorm:"not null;index"`
 	Type          string         `json:"type" gorm:"not null;default:FILE"` // FILE, DIRECTORY
 	Size          int64          `json:"size" gorm:"default:0"`             // 字节
 	MimeType      string         `json:"mime_type"`
 	DownloadCount int            `json:"download_count" gorm:"default:0"`
-	ParentID      *uint          `json:"parent_id"`
-	Parent        *UserFile      `json:"parent" gorm:"foreignKey:ParentID"`
-	Children      []UserFile     `json:"children" gorm:"foreignKey:ParentID"`
-	StoragePath   string         `json:"storage_path"`
-	Checksum      string         `json:"checksum"`
+	ParentID      *uint          `json:"parent_id" gorm:"index:idx_user_file_parent"`
+	Parent        *UserFile      `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
+	Children      []UserFile     `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+	StoragePath   string         `json:"-"`
+	Checksum      string         `json:"checksum"` // SHA-256
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// FileUpload 分片上传会话，分片先保存为临时对象，全部上传后合并为文件
+// 会话在 ExpiresAt 之前可以续传，过期后与已上传的分片一起被清理
+type FileUpload struct {
+	ID          uint               `json:"-" gorm:"primarykey"`
+	UploadID    string             `json:"upload_id" gorm:"not null;uniqueIndex"`
+	UserID      uint               `json:"user_id" gorm:"not null;index"`
+	ParentID    *uint              `json:"parent_id"`
+	Name        string             `json:"name" gorm:"not null"`
+	Size        int64              `json:"size"`
+	ChunkSize   int64              `json:"chunk_size"`
+	TotalChunks int                `json:"total_chunks"`
+	Checksum    string             `json:"checksum"`                                 // 客户端提供的 SHA-256，合并后校验
+	Status      string             `json:"status" gorm:"not null;default:UPLOADING"` // UPLOADING, COMPLETING
+	ExpiresAt   time.Time          `json:"expires_at" gorm:"index"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Chunks      []*FileUploadChunk `json:"-" gorm:"foreignKey:UploadID"`
+}
+
+// FileUploadChunk 已上传的分片，同一分片重复上传时覆盖
+type FileUploadChunk struct {
+	ID        uint `gorm:"primarykey"`
+	UploadID  uint `gorm:"not null;uniqueIndex:idx_file_upload_chunk"`
+	Index     int  `gorm:"not null;uniqueIndex:idx_file_upload_chunk"`
+	Size      int64
+	CreatedAt time.Time
+}
+
 // Workflow 工作流表
 type Workflow struct {
 	ID          uint           `json:"id" gorm:"primarykey"`
@@ -57,8 +86,15 @@ type WorkflowTask struct {
 	Workflow       Workflow       `json:"workflow" gorm:"foreignKey:WorkflowID"`
 	ScheduleType   string         `json:"schedule_type" gorm:"default:MANUAL"` // MANUAL, SCHEDULE, TRIGGER
 	CronExpression string         `json:"cron_expression"`
-	Status         string         `json:"status" gorm:"default:DEFAULT"` // DEFAULT, ONLINE, OFFLINE
-	NextRunAt      *time.Time     `json:"next_run_at"`
+	Timezone       string         `json:"timezone" gorm:"default:UTC"`         // cron 表达式使用的 IANA 时区
+	CatchUpPolicy  string         `json:"catch_up_policy" gorm:"default:ONCE"` // SKIP, ONCE, ALL
+	TriggerEvent   string         `json:"trigger_event" gorm:"index"`          // 订阅的平台事件或 webhook
+	TriggerFilter  string         `json:"trigger_filter"`                      // 事件过滤表达式，为空时不过滤
+	TriggerInputs  string         `json:"trigger_inputs" gorm:"type:json"`     // 参数名到 ${{ event.* }} 模板的映射
+	TriggerToken   string         `json:"-" gorm:"index"`                      // 入站 Webhook 地址凭证的 SHA-256
+	Inputs         string         `json:"inputs" gorm:"type:json"`             // JSON格式的执行参数，手动执行时可覆盖
+	Status         string         `json:"status" gorm:"default:DEFAULT"`       // DEFAULT, ONLINE, OFFLINE
+	NextRunAt      *time.Time     `json:"next_run_at" gorm:"index"`
 	LastRunAt      *time.Time     `json:"last_run_at"`
 	RunCount       int            `json:"run_count" gorm:"default:0"`
 	SuccessCount   int            `json:"success_count" gorm:"default:0"`
@@ -76,19 +112,64 @@ type WorkflowTask struct {
 // WorkflowExecution 工作流执行历史表
 type WorkflowExecution struct {
 	ID           uint           `json:"id" gorm:"primarykey"`
-	TaskID       uint           `json:"task_id" gorm:"not null"`
+	TaskID       uint           `json:"task_id" gorm:"not null;index"`
 	Task         WorkflowTask   `json:"task" gorm:"foreignKey:TaskID"`
+	WorkflowID   uint           `json:"workflow_id" gorm:"not null;index"`
 	ExecutionID  string         `json:"execution_id" gorm:"uniqueIndex;not null"`
+	Definition   string         `json:"definition" gorm:"type:json"` // 开始执行时的工作流定义快照
+	Inputs       string         `json:"inputs" gorm:"type:json"`
 	Status       string         `json:"status" gorm:"default:PENDING"`      // PENDING, RUNNING, SUCCESS, FAILURE, STOPPED
 	TriggerType  string         `json:"trigger_type" gorm:"default:MANUAL"` // MANUAL, SCHEDULE, TRIGGER
 	TriggerBy    *uint          `json:"trigger_by"`
+	TriggerEvent string         `json:"trigger_event"`
+	EventData    string         `json:"event_data" gorm:"type:json"`    // 触发执行的事件数据
+	OwnerID      uint           `json:"owner_id" gorm:"not null;index"` // 任务所有者，步骤只能在其服务器上运行
 	Trigger      *User          `json:"trigger" gorm:"foreignKey:TriggerBy"`
 	StartTime    *time.Time     `json:"start_time"`
 	EndTime      *time.Time     `json:"end_time"`
 	Duration     int            `json:"duration" gorm:"default:0"` // 秒
 	ErrorMessage string         `json:"error_message" gorm:"type:text"`
-	ExecutionLog string         `json:"execution_log" gorm:"type:text"`
+	ExecutionLog string         `json:"execution_log" gorm:"type:text"` // 已废弃：日志保存在 LogSegment，只保留升级前的执行日志
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 关联关系
+	StepRuns []WorkflowStepRun `json:"step_runs,omitempty" gorm:"foreignKey:ExecutionID"`
+}
+
+// WorkflowStepRun 工作流步骤在一台服务器上的运行记录，跳过或无法开始的步骤记录一条 ServerID 为 0 的记录
+type WorkflowStepRun struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	ExecutionID uint       `json:"execution_id" gorm:"not null;uniqueIndex:idx_workflow_step_run"`
+	StepID      string     `json:"step_id" gorm:"not null;uniqueIndex:idx_workflow_step_run"`
+	ServerID    uint       `json:"server_id" gorm:"not null;uniqueIndex:idx_workflow_step_run"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status" gorm:"default:PENDING"` // PENDING, RUNNING, SUCCESS, FAILURE, SKIPPED, STOPPED
+	Attempt     int        `json:"attempt" gorm:"default:0"`
+	AgentTaskID string     `json:"agent_task_id" gorm:"index"`
+	Params      string     `json:"params" gorm:"type:json"` // 渲染后的任务参数
+	Outputs     string     `json:"outputs" gorm:"type:json"`
+	Message     string     `json:"message" gorm:"type:text"`
+	Output      string     `json:"output" gorm:"type:text"`
+	NextRunAt   *time.Time `json:"next_run_at" gorm:"index"`
+	Deadline    *time.Time `json:"deadline"`
+	StartedAt   *time.Time `json:"started_at"`
+	EndedAt     *time.Time `json:"ended_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// LogSegment 工作流执行与应用部署的日志分段，按 ID 顺序拼接为完整日志
+// Agent 分批推送的任务输出每批保存为一个分段，(TaskID, Seq) 唯一，多个实例收到同一批输出时只保存一次；系统日志的 TaskID 为空
+type LogSegment struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	TargetType string    `json:"target_type" gorm:"not null;index:idx_log_segment_target"` // WORKFLOW_EXECUTION, DEPLOYMENT
+	TargetID   uint      `json:"target_id" gorm:"not null;index:idx_log_segment_target"`
+	Stream     string    `json:"stream"` // 输出来源，工作流步骤为 步骤ID@服务器ID，系统日志为空
+	TaskID     *string   `json:"task_id" gorm:"uniqueIndex:idx_log_segment_task"`
+	Seq        int       `json:"seq" gorm:"uniqueIndex:idx_log_segment_task"`
+	Content    string    `json:"content" gorm:"type:text"` // 以换行结尾的一行或多行
+	Lines      int       `json:"lines"`
+	CreatedAt  time.Time `json:"created_at"`
 }
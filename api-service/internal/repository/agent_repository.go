@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AgentRepository interface {
+	GetByServerID(serverID uint) (*model.ServerAgent, error)
+	GetByAgentID(agentID string) (*model.ServerAgent, error)
+	UpdateHeartbeat(agent *model.ServerAgent, at time.Time) error
+	Count(since time.Time) (total, active int64, err error)
+}
+
+type agentRepository struct {
+	db *gorm.DB
+}
+
+func NewAgentRepository(db *gorm.DB) AgentRepository {
+	return &agentRepository{db: db}
+}
+
+// GetByServerID 获取服务器上最近一次上报心跳的 Agent
+func (r *agentRepository) GetByServerID(serverID uint) (*model.ServerAgent, error) {
+	var agent model.ServerAgent
+	err := r.db.Where("server_id = ? AND agent_id <> ''", serverID).
+		Order("last_heartbeat_at DESC").First(&agent).Error
+	if err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+func (r *agentRepository) GetByAgentID(agentID string) (*model.ServerAgent, error) {
+	var agent model.ServerAgent
+	err := r.db.Where("agent_id = ?", agentID).First(&agent).Error
+	if err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// UpdateHeartbeat 同时更新 Agent 与所在服务器的心跳时间
+func (r *agentRepository) UpdateHeartbeat(agent *model.ServerAgent, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(agent).Updates(map[string]interface{}{
+			"last_heartbeat_at": at,
+			"status":            constants.AgentStatusOnline,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.Server{}).Where("id = ?", agent.ServerID).
+			UpdateColumn("last_heartbeat_at", at).Error
+	})
+}
+
+// Count 统计 Agent 总数与 since 之后有心跳的 Agent 数
+func (r *agentRepository) Count(since time.Time) (total, active int64, err error) {
+	if err = r.db.Model(&model.ServerAgent{}).Count(&total).Error; err != nil {
+		return 0, 0, err
+	}
+	err = r.db.Model(&model.ServerAgent{}).Where("last_heartbeat_at >= ?", since).Count(&active).Error
+	return total, active, err
+}
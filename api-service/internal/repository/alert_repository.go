@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AlertRepository interface {
+	FindRule(ownerID uint, targetType, metricName string) (*model.AlertRule, error)
+	CreateRule(rule *model.AlertRule) error
+	GetRule(id uint) (*model.AlertRule, error)
+	ListRules(ownerID uint) ([]*model.AlertRule, error)
+	ListEnabledRules(ruleTypes ...string) ([]*model.AlertRule, error)
+	UpdateRule(rule *model.AlertRule) error
+	ClaimEvaluation(ruleID uint, now, since time.Time) (bool, error)
+	SetPending(ruleID uint, since *time.Time) error
+	DeleteRule(id uint) error
+	GetRecordByAlertID(alertID string) (*model.AlertRecord, error)
+	GetOpenRecord(alertIDPrefix string) (*model.AlertRecord, error)
+	CreateRecord(record *model.AlertRecord) error
+	GetRecord(id uint) (*model.AlertRecord, error)
+	ListRecords(filter *AlertRecordFilter, offset, limit int) ([]*model.AlertRecord, int64, error)
+	UpdateRecord(record *model.AlertRecord) error
+	ResolveRecords(alertIDPrefix, note string) error
+	ClaimResolvedNotice(id uint) (bool, error)
+
+	CreateSilence(silence *model.AlertSilence) error
+	GetSilence(id uint) (*model.AlertSilence, error)
+	ListSilences(ownerID uint) ([]*model.AlertSilence, error)
+	ListActiveSilences(ownerID uint, at time.Time) ([]*model.AlertSilence, error)
+	DeleteSilence(id uint) error
+
+	CreateMaintenanceWindow(window *model.MaintenanceWindow) error
+	GetMaintenanceWindow(id uint) (*model.MaintenanceWindow, error)
+	ListMaintenanceWindows(ownerID uint) ([]*model.MaintenanceWindow, error)
+	UpdateMaintenanceWindow(window *model.MaintenanceWindow) error
+	DeleteMaintenanceWindow(id uint) error
+
+	GetBaseline(ruleID uint) (*model.AlertBaseline, error)
+	SaveBaseline(baseline *model.AlertBaseline) error
+	DeleteBaseline(ruleID uint) error
+}
+
+// AlertRecordFilter 告警记录查询条件，零值字段不参与过滤
+type AlertRecordFilter struct {
+	OwnerID     uint
+	Status      string
+	AlertRuleID uint
+	Suppressed  *bool
+	Start       time.Time
+	End         time.Time
+}
+
+type alertRepository struct {
+	db *gorm.DB
+}
+
+func NewAlertRepository(db *gorm.DB) AlertRepository {
+	return &alertRepository{db: db}
+}
+
+func (r *alertRepository) FindRule(ownerID uint, targetType, metricName string) (*model.AlertRule, error) {
+	var rule model.AlertRule
+	err := r.db.Where("owner_id = ? AND target_type = ? AND metric_name = ?", ownerID, targetType, metricName).
+		First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *alertRepository) CreateRule(rule *model.AlertRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *alertRepository) GetRule(id uint) (*model.AlertRule, error) {
+	var rule model.AlertRule
+	if err := r.db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *alertRepository) ListRules(ownerID uint) ([]*model.AlertRule, error) {
+	var rules []*model.AlertRule
+	err := r.db.Where("owner_id = ?", ownerID).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func (r *alertRepository) ListEnabledRules(ruleTypes ...string) ([]*model.AlertRule, error) {
+	var rules []*model.AlertRule
+	err := r.db.Where("rule_type IN ? AND is_enabled = 1", ruleTypes).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// UpdateRule 保存规则，评估状态只由 ClaimEvaluation 与 SetPending 更新
+func (r *alertRepository) UpdateRule(rule *model.AlertRule) error {
+	return r.db.Omit("PendingSince", "LastEvaluatedAt").Save(rule).Error
+}
+
+// ClaimEvaluation 认领规则本轮的评估：上次评估早于 since 时更新为 now 并返回 true，多个实例中只有一个认领成功
+func (r *alertRepository) ClaimEvaluation(ruleID uint, now, since time.Time) (bool, error) {
+	result := r.db.Model(&model.AlertRule{}).
+		Where("id = ? AND (last_evaluated_at IS NULL OR last_evaluated_at < ?)", ruleID, since).
+		UpdateColumn("last_evaluated_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// SetPending 更新规则条件开始满足的时间，nil 表示条件不满足
+func (r *alertRepository) SetPending(ruleID uint, since *time.Time) error {
+	return r.db.Model(&model.AlertRule{}).Where("id = ?", ruleID).UpdateColumn("pending_since", since).Error
+}
+
+func (r *alertRepository) DeleteRule(id uint) error {
+	return r.db.Delete(&model.AlertRule{}, id).Error
+}
+
+func (r *alertRepository) GetRecordByAlertID(alertID string) (*model.AlertRecord, error) {
+	var record model.AlertRecord
+	err := r.db.Where("alert_id = ?", alertID).First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetOpenRecord 获取告警ID以指定前缀开头且未恢复的告警，不存在时返回 nil
+// 每次规则评估都会查询，使用 Find 避免未找到记录时的日志输出
+func (r *alertRepository) GetOpenRecord(alertIDPrefix string) (*model.AlertRecord, error) {
+	var records []*model.AlertRecord
+	err := r.db.Where("alert_id LIKE ? AND status <> ?", alertIDPrefix+"%", constants.AlertStatusResolved).
+		Order("id DESC").Limit(1).Find(&records).Error
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+func (r *alertRepository) CreateRecord(record *model.AlertRecord) error {
+	return r.db.Create(record).Error
+}
+
+// GetRecord 获取告警记录及其规则
+func (r *alertRepository) GetRecord(id uint) (*model.AlertRecord, error) {
+	var record model.AlertRecord
+	err := r.db.Preload("AlertRule").First(&record, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ListRecords 按触发时间倒序分页查询规则所有者的告警记录
+func (r *alertRepository) ListRecords(filter *AlertRecordFilter, offset, limit int) ([]*model.AlertRecord, int64, error) {
+	query := r.db.Model(&model.AlertRecord{}).
+		Joins("JOIN alert_rules ON alert_rules.id = alert_records.alert_rule_id").
+		Where("alert_rules.owner_id = ?", filter.OwnerID)
+	if filter.Status != "" {
+		query = query.Where("alert_records.status = ?", filter.Status)
+	}
+	if filter.AlertRuleID != 0 {
+		query = query.Where("alert_records.alert_rule_id = ?", filter.AlertRuleID)
+	}
+	if filter.Suppressed != nil {
+		suppressed := 0
+		if *filter.Suppressed {
+			suppressed = 1
+		}
+		query = query.Where("alert_records.suppressed = ?", suppressed)
+	}
+	if !filter.Start.IsZero() {
+		query = query.Where("alert_records.fired_at >= ?", filter.Start)
+	}
+	if !filter.End.IsZero() {
+		query = query.Where("alert_records.fired_at < ?", filter.End)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []*model.AlertRecord
+	err := query.Preload("AlertRule").Order("alert_records.fired_at DESC, alert_records.id DESC").
+		Offset(offset).Limit(limit).Find(&records).Error
+	return records, total, err
+}
+
+// UpdateRecord 保存告警记录，恢复通知标记只由 ClaimResolvedNotice 更新
+func (r *alertRepository) UpdateRecord(record *model.AlertRecord) error {
+	return r.db.Omit("AlertRule", "ResolvedNotified").Save(record).Error
+}
+
+// ResolveRecords 将告警ID以指定前缀开头的未恢复告警标记为已恢复
+func (r *alertRepository) ResolveRecords(alertIDPrefix, note string) error {
+	now := time.Now()
+	return r.db.Model(&model.AlertRecord{}).
+		Where("alert_id LIKE ? AND status <> ?", alertIDPrefix+"%", constants.AlertStatusResolved).
+		Updates(map[string]interface{}{
+			"status":          constants.AlertStatusResolved,
+			"resolved_at":     &now,
+			"resolution_note": note,
+		}).Error
+}
+
+// ClaimResolvedNotice 标记告警的恢复通知已发送，标记成功时返回 true；已被标记(如手动恢复后条件又不再满足)时返回 false
+func (r *alertRepository) ClaimResolvedNotice(id uint) (bool, error) {
+	result := r.db.Model(&model.AlertRecord{}).
+		Where("id = ? AND resolved_notified = 0", id).
+		UpdateColumn("resolved_notified", 1)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *alertRepository) CreateSilence(silence *model.AlertSilence) error {
+	return r.db.Create(silence).Error
+}
+
+func (r *alertRepository) GetSilence(id uint) (*model.AlertSilence, error) {
+	var silence model.AlertSilence
+	if err := r.db.First(&silence, id).Error; err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+func (r *alertRepository) ListSilences(ownerID uint) ([]*model.AlertSilence, error) {
+	var silences []*model.AlertSilence
+	err := r.db.Where("owner_id = ?", ownerID).Order("ends_at DESC, id DESC").Find(&silences).Error
+	return silences, err
+}
+
+// ListActiveSilences 获取 at 时刻生效的静默
+func (r *alertRepository) ListActiveSilences(ownerID uint, at time.Time) ([]*model.AlertSilence, error) {
+	var silences []*model.AlertSilence
+	err := r.db.Where("owner_id = ? AND starts_at <= ? AND ends_at > ?", ownerID, at, at).
+		Order("id").Find(&silences).Error
+	return silences, err
+}
+
+func (r *alertRepository) DeleteSilence(id uint) error {
+	return r.db.Delete(&model.AlertSilence{}, id).Error
+}
+
+func (r *alertRepository) CreateMaintenanceWindow(window *model.MaintenanceWindow) error {
+	return r.db.Create(window).Error
+}
+
+func (r *alertRepository) GetMaintenanceWindow(id uint) (*model.MaintenanceWindow, error) {
+	var window model.MaintenanceWindow
+	if err := r.db.First(&window, id).Error; err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+func (r *alertRepository) ListMaintenanceWindows(ownerID uint) ([]*model.MaintenanceWindow, error) {
+	var windows []*model.MaintenanceWindow
+	err := r.db.Where("owner_id = ?", ownerID).Order("id").Find(&windows).Error
+	return windows, err
+}
+
+func (r *alertRepository) UpdateMaintenanceWindow(window *model.MaintenanceWindow) error {
+	return r.db.Save(window).Error
+}
+
+func (r *alertRepository) DeleteMaintenanceWindow(id uint) error {
+	return r.db.Delete(&model.MaintenanceWindow{}, id).Error
+}
+
+// GetBaseline 获取规则的基线，不存在时返回 nil
+func (r *alertRepository) GetBaseline(ruleID uint) (*model.AlertBaseline, error) {
+	var baselines []*model.AlertBaseline
+	err := r.db.Where("alert_rule_id = ?", ruleID).Limit(1).Find(&baselines).Error
+	if err != nil || len(baselines) == 0 {
+		return nil, err
+	}
+	return baselines[0], nil
+}
+
+func (r *alertRepository) SaveBaseline(baseline *model.AlertBaseline) error {
+	return r.db.Save(baseline).Error
+}
+
+func (r *alertRepository) DeleteBaseline(ruleID uint) error {
+	return r.db.Where("alert_rule_id = ?", ruleID).Delete(&model.AlertBaseline{}).Error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"api-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	Create(log *model.AuditLog) error
+	ListByResource(resourceType string, resourceID uint, offset, limit int) ([]*model.AuditLog, int64, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Create(log *model.AuditLog) error {
+	return r.db.Create(log).Error
+}
+
+func (r *auditRepository) ListByResource(resourceType string, resourceID uint, offset, limit int) ([]*model.AuditLog, int64, error) {
+	var logs []*model.AuditLog
+	var total int64
+
+	query := r.db.Model(&model.AuditLog{}).Where("resource_type = ? AND resource_id = ?", resourceType, resourceID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&logs).Error
+	return logs, total, err
+}
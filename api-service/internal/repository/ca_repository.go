@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"api-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type CARepository interface {
+	Create(ca *model.CertificateAuthority) error
+	GetByID(id uint) (*model.CertificateAuthority, error)
+	Delete(id uint) error
+	ListByOwner(ownerID uint) ([]*model.CertificateAuthority, error)
+	CountIssued(caID uint) (int64, error)
+}
+
+type caRepository struct {
+	db *gorm.DB
+}
+
+func NewCARepository(db *gorm.DB) CARepository {
+	return &caRepository{db: db}
+}
+
+func (r *caRepository) Create(ca *model.CertificateAuthority) error {
+	return r.db.Create(ca).Error
+}
+
+func (r *caRepository) GetByID(id uint) (*model.CertificateAuthority, error) {
+	var ca model.CertificateAuthority
+	err := r.db.First(&ca, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ca, nil
+}
+
+func (r *caRepository) Delete(id uint) error {
+	return r.db.Delete(&model.CertificateAuthority{}, id).Error
+}
+
+func (r *caRepository) ListByOwner(ownerID uint) ([]*model.CertificateAuthority, error) {
+	var cas []*model.CertificateAuthority
+	err := r.db.Where("owner_id = ?", ownerID).Order("id").Find(&cas).Error
+	return cas, err
+}
+
+// CountIssued 统计 CA 签发且未删除的证书数量
+func (r *caRepository) CountIssued(caID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.SSLCertificate{}).Where("ca_id = ?", caID).Count(&count).Error
+	return count, err
+}
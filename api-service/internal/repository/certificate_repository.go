@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CertificateRepository interface {
+	Create(cert *model.SSLCertificate) error
+	GetByID(id uint) (*model.SSLCertificate, error)
+	Update(cert *model.SSLCertificate) error
+	Delete(id uint) error
+	ListByOwner(ownerID uint, status string) ([]*model.SSLCertificate, error)
+	ListActive() ([]*model.SSLCertificate, error)
+	ListExpiringBefore(ownerID uint, deadline time.Time) ([]*model.SSLCertificate, error)
+	ListAutoRenewBefore(certType string, deadline time.Time) ([]*model.SSLCertificate, error)
+}
+
+type certificateRepository struct {
+	db *gorm.DB
+}
+
+func NewCertificateRepository(db *gorm.DB) CertificateRepository {
+	return &certificateRepository{db: db}
+}
+
+func (r *certificateRepository) Create(cert *model.SSLCertificate) error {
+	return r.db.Create(cert).Error
+}
+
+func (r *certificateRepository) GetByID(id uint) (*model.SSLCertificate, error) {
+	var cert model.SSLCertificate
+	err := r.db.First(&cert, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+func (r *certificateRepository) Update(cert *model.SSLCertificate) error {
+	return r.db.Save(cert).Error
+}
+
+func (r *certificateRepository) Delete(id uint) error {
+	return r.db.Delete(&model.SSLCertificate{}, id).Error
+}
+
+func (r *certificateRepository) ListByOwner(ownerID uint, status string) ([]*model.SSLCertificate, error) {
+	var certs []*model.SSLCertificate
+	query := r.db.Where("owner_id = ?", ownerID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("not_after").Find(&certs).Error
+	return certs, err
+}
+
+// ListActive 查询未吊销的证书，用于每日状态刷新与过期检查
+func (r *certificateRepository) ListActive() ([]*model.SSLCertificate, error) {
+	var certs []*model.SSLCertificate
+	err := r.db.Where("status <> ? AND not_after IS NOT NULL", constants.CertificateStatusRevoked).
+		Order("not_after").Find(&certs).Error
+	return certs, err
+}
+
+func (r *certificateRepository) ListExpiringBefore(ownerID uint, deadline time.Time) ([]*model.SSLCertificate, error) {
+	var certs []*model.SSLCertificate
+	err := r.db.Where("owner_id = ? AND status <> ? AND not_after IS NOT NULL AND not_after <= ?",
+		ownerID, constants.CertificateStatusRevoked, deadline).
+		Order("not_after").Find(&certs).Error
+	return certs, err
+}
+
+func (r *certificateRepository) ListAutoRenewBefore(certType string, deadline time.Time) ([]*model.SSLCertificate, error) {
+	var certs []*model.SSLCertificate
+	err := r.db.Where("auto_renew = 1 AND certificate_type = ? AND status <> ? AND not_after IS NOT NULL AND not_after <= ?",
+		certType, constants.CertificateStatusRevoked, deadline).
+		Order("not_after").Find(&certs).Error
+	return certs, err
+}
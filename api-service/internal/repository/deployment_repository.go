@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"api-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type DeploymentRepository interface {
+	Create(deployment *model.AppDeployment) error
+	GetByID(id uint) (*model.AppDeployment, error)
+	GetByDeploymentID(deploymentID string) (*model.AppDeployment, error)
+	Update(deployment *model.AppDeployment) error
+	ListByOwner(ownerID uint, offset, limit int) ([]*model.AppDeployment, int64, error)
+	GetLatestByInstance(instanceID uint) (*model.AppDeployment, error)
+	GetTemplate(id uint) (*model.AppStoreTemplate, error)
+	CreateInstance(instance *model.AppInstance) error
+	GetInstance(id uint) (*model.AppInstance, error)
+	UpdateInstance(instance *model.AppInstance) error
+	CreateSecretReferences(refs []*model.SecretKeyReference) error
+	ListInstancesBySecret(secretID uint) ([]*model.AppInstance, error)
+}
+
+type deploymentRepository struct {
+	db *gorm.DB
+}
+
+func NewDeploymentRepository(db *gorm.DB) DeploymentRepository {
+	return &deploymentRepository{db: db}
+}
+
+func (r *deploymentRepository) Create(deployment *model.AppDeployment) error {
+	return r.db.Create(deployment).Error
+}
+
+func (r *deploymentRepository) GetByID(id uint) (*model.AppDeployment, error) {
+	var deployment model.AppDeployment
+	err := r.db.First(&deployment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+func (r *deploymentRepository) GetByDeploymentID(deploymentID string) (*model.AppDeployment, error) {
+	var deployment model.AppDeployment
+	err := r.db.Where("deployment_id = ?", deploymentID).First(&deployment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+func (r *deploymentRepository) Update(deployment *model.AppDeployment) error {
+	return r.db.Save(deployment).Error
+}
+
+func (r *deploymentRepository) ListByOwner(ownerID uint, offset, limit int) ([]*model.AppDeployment, int64, error) {
+	var deployments []*model.AppDeployment
+	var total int64
+
+	query := r.db.Model(&model.AppDeployment{}).Where("owner_id = ?", ownerID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&deployments).Error
+	return deployments, total, err
+}
+
+func (r *deploymentRepository) GetLatestByInstance(instanceID uint) (*model.AppDeployment, error) {
+	var deployment model.AppDeployment
+	err := r.db.Where("app_instance_id = ?", instanceID).Order("id DESC").First(&deployment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+func (r *deploymentRepository) GetTemplate(id uint) (*model.AppStoreTemplate, error) {
+	var template model.AppStoreTemplate
+	err := r.db.First(&template, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *deploymentRepository) CreateInstance(instance *model.AppInstance) error {
+	return r.db.Create(instance).Error
+}
+
+func (r *deploymentRepository) GetInstance(id uint) (*model.AppInstance, error) {
+	var instance model.AppInstance
+	err := r.db.First(&instance, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+func (r *deploymentRepository) UpdateInstance(instance *model.AppInstance) error {
+	return r.db.Save(instance).Error
+}
+
+func (r *deploymentRepository) CreateSecretReferences(refs []*model.SecretKeyReference) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	return r.db.Create(&refs).Error
+}
+
+// ListInstancesBySecret 查询最近一次部署仍引用该密钥的应用实例
+func (r *deploymentRepository) ListInstancesBySecret(secretID uint) ([]*model.AppInstance, error) {
+	latest := r.db.Model(&model.AppDeployment{}).
+		Select("MAX(id)").
+		Where("app_instance_id IS NOT NULL").
+		Group("app_instance_id")
+
+	instanceIDs := r.db.Model(&model.SecretKeyReference{}).
+		Select("DISTINCT app_instance_id").
+		Where("secret_key_id = ? AND app_instance_id IS NOT NULL AND deployment_id IN (?)", secretID, latest)
+
+	var instances []*model.AppInstance
+	err := r.db.Where("id IN (?)", instanceIDs).Order("id").Find(&instances).Error
+	return instances, err
+}
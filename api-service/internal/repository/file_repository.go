@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FileRepository interface {
+	Create(file *model.UserFile) error
+	GetByID(id uint) (*model.UserFile, error)
+	// List 列出目录下的文件，parentID 为 nil 时列出根目录，目录在前并按名称排序
+	List(userID uint, parentID *uint, offset, limit int) ([]*model.UserFile, int64, error)
+	// NameExists 判断目录下是否已有同名文件，excludeID 不为 0 时排除该文件
+	NameExists(userID uint, parentID *uint, name string, excludeID uint) (bool, error)
+	// Move 保存文件的名称、父目录与路径，目录同时将子孙路径中的 oldPath 前缀替换为新路径
+	Move(file *model.UserFile, oldPath string) error
+	// ListTree 返回文件本身及其全部子孙
+	ListTree(file *model.UserFile) ([]*model.UserFile, error)
+	// DeleteTree 删除文件本身及其全部子孙
+	DeleteTree(file *model.UserFile) error
+	IncrementDownloads(id uint) error
+
+	CreateUpload(upload *model.FileUpload) error
+	// GetUpload 按上传ID查询会话，包括已上传的分片
+	GetUpload(uploadID string) (*model.FileUpload, error)
+	// SaveChunk 记录已上传的分片并延长会话有效期
+	SaveChunk(chunk *model.FileUploadChunk, expiresAt time.Time) error
+	// ClaimUpload 将会话状态由 from 改为 to，状态已被其他请求修改时返回 false
+	ClaimUpload(id uint, from, to string) (bool, error)
+	DeleteUpload(id uint) error
+	ListExpiredUploads(now time.Time, limit int) ([]*model.FileUpload, error)
+}
+
+type fileRepository struct {
+	db *gorm.DB
+}
+
+func NewFileRepository(db *gorm.DB) FileRepository {
+	return &fileRepository{db: db}
+}
+
+func (r *fileRepository) Create(file *model.UserFile) error {
+	return r.db.Omit("User", "Parent", "Children").Create(file).Error
+}
+
+func (r *fileRepository) GetByID(id uint) (*model.UserFile, error) {
+	var file model.UserFile
+	if err := r.db.First(&file, id).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (r *fileRepository) List(userID uint, parentID *uint, offset, limit int) ([]*model.UserFile, int64, error) {
+	query := r.inDirectory(r.db.Model(&model.UserFile{}), userID, parentID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var files []*model.UserFile
+	err := query.Order(clause.Expr{SQL: "CASE WHEN type = ? THEN 0 ELSE 1 END", Vars: []interface{}{constants.UserFileTypeDirectory}}).
+		Order("name").Offset(offset).Limit(limit).Find(&files).Error
+	return files, total, err
+}
+
+func (r *fileRepository) NameExists(userID uint, parentID *uint, name string, excludeID uint) (bool, error) {
+	query := r.inDirectory(r.db.Model(&model.UserFile{}), userID, parentID).Where("name = ?", name)
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+func (r *fileRepository) Move(file *model.UserFile, oldPath string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.UserFile{}).Where("id = ?", file.ID).Updates(map[string]interface{}{
+			"name":      file.Name,
+			"parent_id": file.ParentID,
+			"path":      file.Path,
+		}).Error
+		if err != nil || file.Type != constants.UserFileTypeDirectory || file.Path == oldPath {
+			return err
+		}
+		// SQLite 的 substr 按字符计数
+		prefix := oldPath + "/"
+		return tx.Model(&model.UserFile{}).
+			Where("user_id = ? AND substr(path, 1, ?) = ?", file.UserID, utf8.RuneCountInString(prefix), prefix).
+			Update("path", gorm.Expr("? || substr(path, ?)", file.Path, utf8.RuneCountInString(oldPath)+1)).Error
+	})
+}
+
+func (r *fileRepository) ListTree(file *model.UserFile) ([]*model.UserFile, error) {
+	var files []*model.UserFile
+	err := r.tree(r.db, file).Find(&files).Error
+	return files, err
+}
+
+func (r *fileRepository) DeleteTree(file *model.UserFile) error {
+	return r.tree(r.db, file).Delete(&model.UserFile{}).Error
+}
+
+func (r *fileRepository) IncrementDownloads(id uint) error {
+	return r.db.Model(&model.UserFile{}).Where("id = ?", id).
+		UpdateColumn("download_count", gorm.Expr("download_count + 1")).Error
+}
+
+func (r *fileRepository) CreateUpload(upload *model.FileUpload) error {
+	return r.db.Omit("Chunks").Create(upload).Error
+}
+
+func (r *fileRepository) GetUpload(uploadID string) (*model.FileUpload, error) {
+	var upload model.FileUpload
+	err := r.db.Preload("Chunks", func(db *gorm.DB) *gorm.DB { return db.Order("`index`") }).
+		Where("upload_id = ?", uploadID).First(&upload).Error
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func (r *fileRepository) SaveChunk(chunk *model.FileUploadChunk, expiresAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "upload_id"}, {Name: "index"}},
+			DoUpdates: clause.AssignmentColumns([]string{"size", "created_at"}),
+		}).Create(chunk).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.FileUpload{}).Where("id = ?", chunk.UploadID).Update("expires_at", expiresAt).Error
+	})
+}
+
+func (r *fileRepository) ClaimUpload(id uint, from, to string) (bool, error) {
+	result := r.db.Model(&model.FileUpload{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *fileRepository) DeleteUpload(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("upload_id = ?", id).Delete(&model.FileUploadChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.FileUpload{}, id).Error
+	})
+}
+
+func (r *fileRepository) ListExpiredUploads(now time.Time, limit int) ([]*model.FileUpload, error) {
+	var uploads []*model.FileUpload
+	err := r.db.Preload("Chunks").Where("expires_at <= ?", now).Order("id").Limit(limit).Find(&uploads).Error
+	return uploads, err
+}
+
+// inDirectory 限定为用户在 parentID 目录下的文件
+func (r *fileRepository) inDirectory(query *gorm.DB, userID uint, parentID *uint) *gorm.DB {
+	query = query.Where("user_id = ?", userID)
+	if parentID == nil {
+		return query.Where("parent_id IS NULL")
+	}
+	return query.Where("parent_id = ?", *parentID)
+}
+
+// tree 限定为文件本身及路径在其下的子孙
+func (r *fileRepository) tree(query *gorm.DB, file *model.UserFile) *gorm.DB {
+	prefix := file.Path + "/"
+	return query.Where("user_id = ? AND (id = ? OR substr(path, 1, ?) = ?)",
+		file.UserID, file.ID, utf8.RuneCountInString(prefix), prefix)
+}
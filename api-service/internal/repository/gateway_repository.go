@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"api-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GatewayRepository interface {
+	Create(gateway *model.AppGateway) error
+	GetByID(id uint) (*model.AppGateway, error)
+	Update(gateway *model.AppGateway) error
+	Delete(id uint) error
+	ListByOwner(ownerID uint) ([]*model.AppGateway, error)
+	CreatePublish(publish *model.AppGatewayPublish) error
+	GetPublish(id uint) (*model.AppGatewayPublish, error)
+	UpdatePublish(publish *model.AppGatewayPublish) error
+	DeletePublish(id uint) error
+	// FindPublishByDomain 在全部网关中按域名查找发布规则，域名全局唯一
+	FindPublishByDomain(domain string) (*model.AppGatewayPublish, error)
+	ListPublishes(gatewayID uint) ([]*model.AppGatewayPublish, error)
+	GetAppInstance(id uint) (*model.AppInstance, error)
+	CreateAccessRule(rule *model.AppGatewayAccessRule) error
+	GetAccessRule(id uint) (*model.AppGatewayAccessRule, error)
+	UpdateAccessRule(rule *model.AppGatewayAccessRule) error
+	DeleteAccessRule(id uint) error
+	ListAccessRules(gatewayID uint) ([]*model.AppGatewayAccessRule, error)
+	ListEnabledAccessRules(gatewayID uint) ([]*model.AppGatewayAccessRule, error)
+	IncrementRuleHits(id uint, delta int64, lastHitAt time.Time) error
+}
+
+type gatewayRepository struct {
+	db *gorm.DB
+}
+
+func NewGatewayRepository(db *gorm.DB) GatewayRepository {
+	return &gatewayRepository{db: db}
+}
+
+func (r *gatewayRepository) Create(gateway *model.AppGateway) error {
+	return r.db.Create(gateway).Error
+}
+
+func (r *gatewayRepository) GetByID(id uint) (*model.AppGateway, error) {
+	var gateway model.AppGateway
+	err := r.db.Preload("Publishes").Preload("AccessRules").First(&gateway, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &gateway, nil
+}
+
+func (r *gatewayRepository) Update(gateway *model.AppGateway) error {
+	return r.db.Omit(clause.Associations).Save(gateway).Error
+}
+
+// Delete 删除网关及其发布规则与访问控制规则
+func (r *gatewayRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("app_gateway_id = ?", id).Delete(&model.AppGatewayPublish{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("gateway_id = ?", id).Delete(&model.AppGatewayAccessRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.AppGateway{}, id).Error
+	})
+}
+
+func (r *gatewayRepository) ListByOwner(ownerID uint) ([]*model.AppGateway, error) {
+	var gateways []*model.AppGateway
+	err := r.db.Preload("Publishes").Where("owner_id = ?", ownerID).Order("id").Find(&gateways).Error
+	return gateways, err
+}
+
+func (r *gatewayRepository) CreatePublish(publish *model.AppGatewayPublish) error {
+	auditLog := publish.AuditLogEnabled
+	if err := r.db.Omit(clause.Associations).Create(publish).Error; err != nil {
+		return err
+	}
+	// audit_log_enabled 列有默认值，零值(关闭)在创建时会被忽略，需单独写入
+	if auditLog == 0 {
+		publish.AuditLogEnabled = 0
+		return r.db.Model(publish).UpdateColumn("audit_log_enabled", 0).Error
+	}
+	return nil
+}
+
+func (r *gatewayRepository) GetPublish(id uint) (*model.AppGatewayPublish, error) {
+	var publish model.AppGatewayPublish
+	err := r.db.First(&publish, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &publish, nil
+}
+
+func (r *gatewayRepository) UpdatePublish(publish *model.AppGatewayPublish) error {
+	return r.db.Omit(clause.Associations).Save(publish).Error
+}
+
+func (r *gatewayRepository) DeletePublish(id uint) error {
+	return r.db.Delete(&model.AppGatewayPublish{}, id).Error
+}
+
+func (r *gatewayRepository) FindPublishByDomain(domain string) (*model.AppGatewayPublish, error) {
+	var publish model.AppGatewayPublish
+	err := r.db.Where("service_domain = ?", domain).First(&publish).Error
+	if err != nil {
+		return nil, err
+	}
+	return &publish, nil
+}
+
+// ListPublishes 查询网关的发布规则及转发所需的实例与服务器信息，gatewayID 为 0 时查询全部
+func (r *gatewayRepository) ListPublishes(gatewayID uint) ([]*model.AppGatewayPublish, error) {
+	var publishes []*model.AppGatewayPublish
+	query := r.db.Preload("AppInstance.Server").Preload("AppGateway")
+	if gatewayID != 0 {
+		query = query.Where("app_gateway_id = ?", gatewayID)
+	}
+	err := query.Order("id").Find(&publishes).Error
+	return publishes, err
+}
+
+func (r *gatewayRepository) GetAppInstance(id uint) (*model.AppInstance, error) {
+	var instance model.AppInstance
+	err := r.db.First(&instance, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+func (r *gatewayRepository) CreateAccessRule(rule *model.AppGatewayAccessRule) error {
+	status := rule.Status
+	if err := r.db.Omit(clause.Associations).Create(rule).Error; err != nil {
+		return err
+	}
+	// status 列有默认值，零值(禁用)在创建时会被忽略，需单独写入
+	if status == 0 {
+		rule.Status = 0
+		return r.db.Model(rule).UpdateColumn("status", 0).Error
+	}
+	return nil
+}
+
+func (r *gatewayRepository) GetAccessRule(id uint) (*model.AppGatewayAccessRule, error) {
+	var rule model.AppGatewayAccessRule
+	err := r.db.First(&rule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateAccessRule 更新规则配置，命中计数由网关单独累加，不在此覆盖
+func (r *gatewayRepository) UpdateAccessRule(rule *model.AppGatewayAccessRule) error {
+	return r.db.Omit(clause.Associations, "HitCount", "LastHitAt").Save(rule).Error
+}
+
+func (r *gatewayRepository) DeleteAccessRule(id uint) error {
+	return r.db.Delete(&model.AppGatewayAccessRule{}, id).Error
+}
+
+func (r *gatewayRepository) ListAccessRules(gatewayID uint) ([]*model.AppGatewayAccessRule, error) {
+	var rules []*model.AppGatewayAccessRule
+	err := r.db.Where("gateway_id = ?", gatewayID).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// ListEnabledAccessRules 查询启用的访问控制规则，gatewayID 为 0 时查询全部
+func (r *gatewayRepository) ListEnabledAccessRules(gatewayID uint) ([]*model.AppGatewayAccessRule, error) {
+	var rules []*model.AppGatewayAccessRule
+	query := r.db.Where("status = 1")
+	if gatewayID != 0 {
+		query = query.Where("gateway_id = ?", gatewayID)
+	}
+	err := query.Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func (r *gatewayRepository) IncrementRuleHits(id uint, delta int64, lastHitAt time.Time) error {
+	return r.db.Model(&model.AppGatewayAccessRule{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"hit_count":   gorm.Expr("hit_count + ?", delta),
+			"last_hit_at": lastHitAt,
+		}).Error
+}
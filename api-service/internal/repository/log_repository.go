@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"api-service/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LogRepository interface {
+	// Append 保存日志分段，(task_id, seq) 已存在的分段被忽略
+	Append(segments ...*model.LogSegment) error
+	// List 按 ID 顺序返回 afterID 之后的分段
+	List(targetType string, targetID, afterID uint, limit int) ([]*model.LogSegment, error)
+	// HasTaskLog 判断是否已保存 Agent 任务推送的日志
+	HasTaskLog(taskID string) (bool, error)
+}
+
+type logRepository struct {
+	db *gorm.DB
+}
+
+func NewLogRepository(db *gorm.DB) LogRepository {
+	return &logRepository{db: db}
+}
+
+func (r *logRepository) Append(segments ...*model.LogSegment) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&segments).Error
+}
+
+func (r *logRepository) List(targetType string, targetID, afterID uint, limit int) ([]*model.LogSegment, error) {
+	var segments []*model.LogSegment
+	err := r.db.Where("target_type = ? AND target_id = ? AND id > ?", targetType, targetID, afterID).
+		Order("id").Limit(limit).Find(&segments).Error
+	return segments, err
+}
+
+func (r *logRepository) HasTaskLog(taskID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.LogSegment{}).Where("task_id = ?", taskID).Limit(1).Count(&count).Error
+	return count > 0, err
+}
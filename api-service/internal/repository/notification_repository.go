@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	CreateTemplate(template *model.NotificationTemplate) error
+	GetTemplate(id uint) (*model.NotificationTemplate, error)
+	ListTemplates() ([]*model.NotificationTemplate, error)
+	ListTemplatesByName(name string) ([]*model.NotificationTemplate, error)
+	UpdateTemplate(template *model.NotificationTemplate) error
+	DeleteTemplate(id uint) error
+
+	// CreateNotification 在同一事务中创建通知、接收用户的站内通知与各渠道的投递记录，返回创建的站内通知
+	CreateNotification(notification *model.Notification, userIDs []uint,
+		records []*model.NotificationRecord) ([]*model.UserNotification, error)
+	UpdateNotificationStatus(id uint, status, errorMsg string, sentAt time.Time) error
+
+	ListDueRecords(now time.Time, limit int) ([]*model.NotificationRecord, error)
+	ClaimRecord(id uint, now, until time.Time) (bool, error)
+	UpdateRecord(record *model.NotificationRecord) error
+	CountRecords(notificationID uint) (map[string]int64, error)
+	ListRecords(filter *NotificationRecordFilter, offset, limit int) ([]*model.NotificationRecord, int64, error)
+
+	ListInbox(userID uint, filter *InboxFilter, offset, limit int) ([]*model.UserNotification, int64, error)
+	ListInboxAfter(userID, afterID uint, limit int) ([]*model.UserNotification, error)
+	CountUnread(userID uint) (int64, error)
+	MarkRead(userID uint, ids []uint, now time.Time) (int64, error)
+	MarkAllRead(userID uint, now time.Time) (int64, error)
+	DeleteInbox(userID, id uint, now time.Time) error
+}
+
+// InboxFilter 站内通知查询条件，零值字段不参与过滤
+type InboxFilter struct {
+	Unread bool
+	Kind   string
+	Level  string
+}
+
+// NotificationRecordFilter 投递记录查询条件，零值字段不参与过滤
+type NotificationRecordFilter struct {
+	Status         string
+	Channel        string
+	NotificationID uint
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) CreateTemplate(template *model.NotificationTemplate) error {
+	return r.db.Create(template).Error
+}
+
+func (r *notificationRepository) GetTemplate(id uint) (*model.NotificationTemplate, error) {
+	var template model.NotificationTemplate
+	if err := r.db.First(&template, id).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *notificationRepository) ListTemplates() ([]*model.NotificationTemplate, error) {
+	var templates []*model.NotificationTemplate
+	err := r.db.Order("name, id").Find(&templates).Error
+	return templates, err
+}
+
+// ListTemplatesByName 返回同名的各渠道类型模板
+func (r *notificationRepository) ListTemplatesByName(name string) ([]*model.NotificationTemplate, error) {
+	var templates []*model.NotificationTemplate
+	err := r.db.Where("name = ?", name).Order("id").Find(&templates).Error
+	return templates, err
+}
+
+func (r *notificationRepository) UpdateTemplate(template *model.NotificationTemplate) error {
+	return r.db.Save(template).Error
+}
+
+func (r *notificationRepository) DeleteTemplate(id uint) error {
+	return r.db.Delete(&model.NotificationTemplate{}, id).Error
+}
+
+func (r *notificationRepository) CreateNotification(notification *model.Notification, userIDs []uint,
+	records []*model.NotificationRecord) ([]*model.UserNotification, error) {
+	inbox := make([]*model.UserNotification, len(userIDs))
+	for i, userID := range userIDs {
+		inbox[i] = &model.UserNotification{UserID: userID}
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(notification).Error; err != nil {
+			return err
+		}
+
+		for _, item := range inbox {
+			item.NotificationID = notification.ID
+		}
+		if len(inbox) > 0 {
+			if err := tx.Omit("Notification", "User").CreateInBatches(&inbox, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, record := range records {
+			record.NotificationID = &notification.ID
+		}
+		if len(records) > 0 {
+			return tx.Omit("Notification", "Template").CreateInBatches(&records, 500).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inbox, nil
+}
+
+func (r *notificationRepository) UpdateNotificationStatus(id uint, status, errorMsg string, sentAt time.Time) error {
+	return r.db.Model(&model.Notification{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":    status,
+		"error_msg": errorMsg,
+		"sent_at":   sentAt,
+	}).Error
+}
+
+// ListDueRecords 返回已到投递时间的待投递记录
+func (r *notificationRepository) ListDueRecords(now time.Time, limit int) ([]*model.NotificationRecord, error) {
+	var records []*model.NotificationRecord
+	err := r.db.Preload("Notification").
+		Where("status = ? AND next_retry_at <= ?", constants.NotificationStatusPending, now).
+		Order("next_retry_at, id").Limit(limit).Find(&records).Error
+	return records, err
+}
+
+// ClaimRecord 将已到期记录的下次投递时间推迟到 until，作为投递期间的租约，
+// 记录已被其他实例领取时返回 false，避免重复投递
+func (r *notificationRepository) ClaimRecord(id uint, now, until time.Time) (bool, error) {
+	result := r.db.Model(&model.NotificationRecord{}).
+		Where("id = ? AND status = ? AND next_retry_at <= ?", id, constants.NotificationStatusPending, now).
+		Update("next_retry_at", until)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *notificationRepository) UpdateRecord(record *model.NotificationRecord) error {
+	return r.db.Omit("Notification", "Template").Save(record).Error
+}
+
+// CountRecords 按状态统计通知的投递记录数
+func (r *notificationRepository) CountRecords(notificationID uint) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db.Model(&model.NotificationRecord{}).Select("status, COUNT(*) AS count").
+		Where("notification_id = ?", notificationID).Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// ListRecords 按创建时间倒序分页查询投递记录
+func (r *notificationRepository) ListRecords(filter *NotificationRecordFilter, offset, limit int) ([]*model.NotificationRecord, int64, error) {
+	query := r.db.Model(&model.NotificationRecord{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Channel != "" {
+		query = query.Where("channel = ?", filter.Channel)
+	}
+	if filter.NotificationID != 0 {
+		query = query.Where("notification_id = ?", filter.NotificationID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []*model.NotificationRecord
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&records).Error
+	return records, total, err
+}
+
+// ListInbox 按时间倒序分页查询用户未删除的站内通知
+func (r *notificationRepository) ListInbox(userID uint, filter *InboxFilter, offset, limit int) ([]*model.UserNotification, int64, error) {
+	query := r.db.Model(&model.UserNotification{}).
+		Where("user_notifications.user_id = ? AND user_notifications.is_deleted = 0", userID)
+	if filter.Unread {
+		query = query.Where("user_notifications.is_read = 0")
+	}
+	if filter.Kind != "" || filter.Level != "" {
+		query = query.Joins("JOIN notifications ON notifications.id = user_notifications.notification_id")
+		if filter.Kind != "" {
+			query = query.Where("notifications.type = ?", filter.Kind)
+		}
+		if filter.Level != "" {
+			query = query.Where("notifications.level = ?", filter.Level)
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []*model.UserNotification
+	err := query.Preload("Notification").Order("user_notifications.id DESC").Offset(offset).Limit(limit).Find(&items).Error
+	return items, total, err
+}
+
+// ListInboxAfter 按 ID 顺序返回 afterID 之后的未删除站内通知
+func (r *notificationRepository) ListInboxAfter(userID, afterID uint, limit int) ([]*model.UserNotification, error) {
+	var items []*model.UserNotification
+	err := r.db.Preload("Notification").
+		Where("user_id = ? AND id > ? AND is_deleted = 0", userID, afterID).
+		Order("id").Limit(limit).Find(&items).Error
+	return items, err
+}
+
+func (r *notificationRepository) CountUnread(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.UserNotification{}).
+		Where("user_id = ? AND is_read = 0 AND is_deleted = 0", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkRead 将用户的指定站内通知标记为已读，返回本次标记的数量
+func (r *notificationRepository) MarkRead(userID uint, ids []uint, now time.Time) (int64, error) {
+	result := r.db.Model(&model.UserNotification{}).
+		Where("user_id = ? AND id IN ? AND is_read = 0 AND is_deleted = 0", userID, ids).
+		Updates(map[string]interface{}{"is_read": 1, "read_at": now})
+	return result.RowsAffected, result.Error
+}
+
+func (r *notificationRepository) MarkAllRead(userID uint, now time.Time) (int64, error) {
+	result := r.db.Model(&model.UserNotification{}).
+		Where("user_id = ? AND is_read = 0 AND is_deleted = 0", userID).
+		Updates(map[string]interface{}{"is_read": 1, "read_at": now})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteInbox 将用户的站内通知标记为已删除，不存在或已删除时返回 gorm.ErrRecordNotFound
+func (r *notificationRepository) DeleteInbox(userID, id uint, now time.Time) error {
+	result := r.db.Model(&model.UserNotification{}).
+		Where("id = ? AND user_id = ? AND is_deleted = 0", id, userID).
+		Updates(map[string]interface{}{"is_deleted": 1, "deleted_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"api-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SecretRepository interface {
+	Create(secret *model.SecretKey, version *model.SecretKeyVersion) error
+	GetByID(id uint) (*model.SecretKey, error)
+	GetByName(name string) ([]*model.SecretKey, error)
+	Update(secret *model.SecretKey) error
+	Delete(id uint) error
+	List(keyType string) ([]*model.SecretKey, error)
+	ListExpiringBefore(deadline time.Time) ([]*model.SecretKey, error)
+	AddVersion(secret *model.SecretKey, version *model.SecretKeyVersion) error
+	GetVersion(secretID uint, version int) (*model.SecretKeyVersion, error)
+	ListVersions(secretID uint) ([]*model.SecretKeyVersion, error)
+}
+
+type secretRepository struct {
+	db *gorm.DB
+}
+
+func NewSecretRepository(db *gorm.DB) SecretRepository {
+	return &secretRepository{db: db}
+}
+
+// Create 创建密钥及其首个版本
+func (r *secretRepository) Create(secret *model.SecretKey, version *model.SecretKeyVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(secret).Error; err != nil {
+			return err
+		}
+		version.SecretKeyID = secret.ID
+		return tx.Create(version).Error
+	})
+}
+
+func (r *secretRepository) GetByID(id uint) (*model.SecretKey, error) {
+	var secret model.SecretKey
+	err := r.db.First(&secret, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+func (r *secretRepository) GetByName(name string) ([]*model.SecretKey, error) {
+	var secrets []*model.SecretKey
+	err := r.db.Where("name = ?", name).Order("id").Find(&secrets).Error
+	return secrets, err
+}
+
+func (r *secretRepository) Update(secret *model.SecretKey) error {
+	return r.db.Save(secret).Error
+}
+
+func (r *secretRepository) Delete(id uint) error {
+	return r.db.Delete(&model.SecretKey{}, id).Error
+}
+
+func (r *secretRepository) List(keyType string) ([]*model.SecretKey, error) {
+	var secrets []*model.SecretKey
+	query := r.db.Order("name")
+	if keyType != "" {
+		query = query.Where("key_type = ?", keyType)
+	}
+	err := query.Find(&secrets).Error
+	return secrets, err
+}
+
+func (r *secretRepository) ListExpiringBefore(deadline time.Time) ([]*model.SecretKey, error) {
+	var secrets []*model.SecretKey
+	err := r.db.Where("expires_at IS NOT NULL AND expires_at <= ?", deadline).
+		Order("expires_at").Find(&secrets).Error
+	return secrets, err
+}
+
+// AddVersion 写入新版本并同步更新密钥当前值
+func (r *secretRepository) AddVersion(secret *model.SecretKey, version *model.SecretKeyVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		version.SecretKeyID = secret.ID
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return tx.Save(secret).Error
+	})
+}
+
+func (r *secretRepository) GetVersion(secretID uint, version int) (*model.SecretKeyVersion, error) {
+	var v model.SecretKeyVersion
+	err := r.db.Where("secret_key_id = ? AND version = ?", secretID, version).First(&v).Error
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *secretRepository) ListVersions(secretID uint) ([]*model.SecretKeyVersion, error) {
+	var versions []*model.SecretKeyVersion
+	err := r.db.Where("secret_key_id = ?", secretID).Order("version DESC").Find(&versions).Error
+	return versions, err
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ServerRepository interface {
+	GetByID(id uint) (*model.Server, error)
+	GetResourceGroup(id uint) (*model.ResourceGroup, error)
+	// MarkOnline 将服务器标记为运行中，状态发生变化时返回 true
+	MarkOnline(id uint) (bool, error)
+	// ListStale 返回运行中、有过心跳但 before 之后没有心跳的服务器，从未上报心跳的服务器不计入
+	ListStale(before time.Time) ([]*model.Server, error)
+	// MarkOffline 仍无心跳时将服务器标记为离线，已被其他实例标记或期间恢复心跳时返回 false
+	MarkOffline(id uint, before time.Time) (bool, error)
+}
+
+type serverRepository struct {
+	db *gorm.DB
+}
+
+func NewServerRepository(db *gorm.DB) ServerRepository {
+	return &serverRepository{db: db}
+}
+
+func (r *serverRepository) GetByID(id uint) (*model.Server, error) {
+	var server model.Server
+	err := r.db.First(&server, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+func (r *serverRepository) GetResourceGroup(id uint) (*model.ResourceGroup, error) {
+	var group model.ResourceGroup
+	err := r.db.First(&group, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *serverRepository) MarkOnline(id uint) (bool, error) {
+	result := r.db.Model(&model.Server{}).Where("id = ? AND status <> ?", id, constants.ServerStatusRunning).
+		UpdateColumn("status", constants.ServerStatusRunning)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *serverRepository) ListStale(before time.Time) ([]*model.Server, error) {
+	var servers []*model.Server
+	err := r.db.Where("status = ? AND last_heartbeat_at < ?",
+		constants.ServerStatusRunning, before).Order("id").Find(&servers).Error
+	return servers, err
+}
+
+func (r *serverRepository) MarkOffline(id uint, before time.Time) (bool, error) {
+	result := r.db.Model(&model.Server{}).
+		Where("id = ? AND status = ? AND last_heartbeat_at < ?",
+			id, constants.ServerStatusRunning, before).
+		UpdateColumn("status", constants.ServerStatusOffline)
+	return result.RowsAffected == 1, result.Error
+}
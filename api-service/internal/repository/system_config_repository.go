@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"api-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type SystemConfigRepository interface {
+	Get(key string) (*model.SystemConfig, error)
+	Save(config *model.SystemConfig) error
+}
+
+type systemConfigRepository struct {
+	db *gorm.DB
+}
+
+func NewSystemConfigRepository(db *gorm.DB) SystemConfigRepository {
+	return &systemConfigRepository{db: db}
+}
+
+func (r *systemConfigRepository) Get(key string) (*model.SystemConfig, error) {
+	var config model.SystemConfig
+	err := r.db.Where("config_key = ?", key).First(&config).Error
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (r *systemConfigRepository) Save(config *model.SystemConfig) error {
+	return r.db.Save(config).Error
+}
@@ -14,6 +14,8 @@ type UserRepository interface {
 	Update(user *model.User) error
 	Delete(id uint) error
 	List(offset, limit int) ([]*model.User, int64, error)
+	// ListActive 返回启用的用户，ids 与 groupIDs 均为 nil 时返回全部启用用户
+	ListActive(ids, groupIDs []uint) ([]*model.User, error)
 }
 
 type userRepository struct {
@@ -39,7 +41,7 @@ func (r *userRepository) GetByID(id uint) (*model.User, error) {
 
 func (r *userRepository) GetByUsername(username string) (*model.User, error) {
 	var user model.User
-	err := r.db.Where("username = ?", username).First(&user).Error
+	err := r.db.Preload("Roles").Where("username = ?", username).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -74,3 +76,17 @@ func (r *userRepository) List(offset, limit int) ([]*model.User, int64, error) {
 	err := r.db.Offset(offset).Limit(limit).Find(&users).Error
 	return users, total, err
 }
+
+func (r *userRepository) ListActive(ids, groupIDs []uint) ([]*model.User, error) {
+	query := r.db.Where("status = 1")
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+	if groupIDs != nil {
+		query = query.Where("group_id IN ?", groupIDs)
+	}
+
+	var users []*model.User
+	err := query.Order("id").Find(&users).Error
+	return users, err
+}
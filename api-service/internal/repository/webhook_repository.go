@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository interface {
+	Create(webhook *model.WebhookConfig) error
+	GetByID(id uint) (*model.WebhookConfig, error)
+	List(ownerID uint, offset, limit int) ([]*model.WebhookConfig, int64, error)
+	Update(webhook *model.WebhookConfig) error
+	Delete(id uint) error
+	// ListSubscribers 返回所有者或管理员的启用 Webhook，由调用方按订阅的事件过滤
+	ListSubscribers(ownerID uint) ([]*model.WebhookConfig, error)
+
+	CreateLogs(logs []*model.WebhookLog) error
+	GetLog(webhookID, id uint) (*model.WebhookLog, error)
+	ListDueLogs(now time.Time, limit int) ([]*model.WebhookLog, error)
+	ClaimLog(id uint, now, until time.Time) (bool, error)
+	UpdateLog(log *model.WebhookLog) error
+	ListLogs(webhookID uint, filter *WebhookLogFilter, offset, limit int) ([]*model.WebhookLog, int64, error)
+}
+
+// WebhookLogFilter 投递记录查询条件，零值字段不参与过滤
+type WebhookLogFilter struct {
+	Status    string
+	EventType string
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) Create(webhook *model.WebhookConfig) error {
+	return r.db.Omit("Owner").Create(webhook).Error
+}
+
+func (r *webhookRepository) GetByID(id uint) (*model.WebhookConfig, error) {
+	var webhook model.WebhookConfig
+	if err := r.db.First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepository) List(ownerID uint, offset, limit int) ([]*model.WebhookConfig, int64, error) {
+	query := r.db.Model(&model.WebhookConfig{}).Where("owner_id = ?", ownerID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var webhooks []*model.WebhookConfig
+	err := query.Order("id").Offset(offset).Limit(limit).Find(&webhooks).Error
+	return webhooks, total, err
+}
+
+func (r *webhookRepository) Update(webhook *model.WebhookConfig) error {
+	return r.db.Omit("Owner").Save(webhook).Error
+}
+
+func (r *webhookRepository) Delete(id uint) error {
+	return r.db.Delete(&model.WebhookConfig{}, id).Error
+}
+
+func (r *webhookRepository) ListSubscribers(ownerID uint) ([]*model.WebhookConfig, error) {
+	admins := r.db.Table("user_roles").Select("user_roles.user_id").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("roles.code = ? AND roles.deleted_at IS NULL", constants.RoleAdmin)
+
+	var webhooks []*model.WebhookConfig
+	err := r.db.Where("is_active = 1 AND (owner_id = ? OR owner_id IN (?))", ownerID, admins).
+		Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepository) CreateLogs(logs []*model.WebhookLog) error {
+	return r.db.Omit("Webhook").CreateInBatches(&logs, 500).Error
+}
+
+func (r *webhookRepository) GetLog(webhookID, id uint) (*model.WebhookLog, error) {
+	var log model.WebhookLog
+	if err := r.db.Where("webhook_id = ?", webhookID).First(&log, id).Error; err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// ListDueLogs 返回已到投递时间的待投递记录，Webhook 已删除的记录其 Webhook 为零值
+func (r *webhookRepository) ListDueLogs(now time.Time, limit int) ([]*model.WebhookLog, error) {
+	var logs []*model.WebhookLog
+	err := r.db.Preload("Webhook").
+		Where("status = ? AND next_retry_at <= ?", constants.WebhookDeliveryPending, now).
+		Order("next_retry_at, id").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// ClaimLog 将已到期记录的下次投递时间推迟到 until，作为投递期间的租约，
+// 记录已被其他实例领取时返回 false，避免重复投递
+func (r *webhookRepository) ClaimLog(id uint, now, until time.Time) (bool, error) {
+	result := r.db.Model(&model.WebhookLog{}).
+		Where("id = ? AND status = ? AND next_retry_at <= ?", id, constants.WebhookDeliveryPending, now).
+		Update("next_retry_at", until)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *webhookRepository) UpdateLog(log *model.WebhookLog) error {
+	return r.db.Omit("Webhook").Save(log).Error
+}
+
+// ListLogs 按创建时间倒序分页查询 Webhook 的投递记录
+func (r *webhookRepository) ListLogs(webhookID uint, filter *WebhookLogFilter, offset, limit int) ([]*model.WebhookLog, int64, error) {
+	query := r.db.Model(&model.WebhookLog{}).Where("webhook_id = ?", webhookID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []*model.WebhookLog
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&logs).Error
+	return logs, total, err
+}
@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WorkflowRepository interface {
+	Create(workflow *model.Workflow) error
+	GetByID(id uint) (*model.Workflow, error)
+	GetByCode(ownerID uint, code string) (*model.Workflow, error)
+	List(ownerID uint, offset, limit int) ([]*model.Workflow, int64, error)
+	Update(workflow *model.Workflow) error
+	// Delete 删除工作流及其任务，已开始的执行使用定义快照继续运行
+	Delete(id uint) error
+
+	CreateTask(task *model.WorkflowTask) error
+	GetTask(id uint) (*model.WorkflowTask, error)
+	ListTasks(ownerID uint, workflowID *uint, offset, limit int) ([]*model.WorkflowTask, int64, error)
+	UpdateTask(task *model.WorkflowTask) error
+	DeleteTask(id uint) error
+	// CountRun 记录一次任务执行
+	CountRun(taskID uint, at time.Time) error
+	// CountResult 按执行结果累加成功或失败次数
+	CountResult(taskID uint, succeeded bool) error
+	// ListDueTasks 返回已到触发时间且未下线的定时任务
+	ListDueTasks(now time.Time) ([]*model.WorkflowTask, error)
+	// ListTriggerTasks 返回所有者订阅了该事件且未下线的触发任务
+	ListTriggerTasks(ownerID uint, event string) ([]*model.WorkflowTask, error)
+	// GetTaskByTriggerToken 按入站 Webhook 凭证的 SHA-256 查找任务
+	GetTaskByTriggerToken(token string) (*model.WorkflowTask, error)
+	UpdateTriggerToken(id uint, token string) error
+	// ClaimSchedule 将下次触发时间仍为 due(读取时的值)的定时任务推进到 next，已被其他实例推进或任务已被修改时返回 false
+	ClaimSchedule(id uint, due time.Time, next *time.Time) (bool, error)
+
+	CreateExecution(execution *model.WorkflowExecution) error
+	GetExecution(id uint) (*model.WorkflowExecution, error)
+	ListExecutions(ownerID uint, taskID *uint, offset, limit int) ([]*model.WorkflowExecution, int64, error)
+	// ListActiveExecutions 返回等待或运行中的执行
+	ListActiveExecutions() ([]*model.WorkflowExecution, error)
+	// StartExecution 将等待中的执行标记为运行中，已被其他实例标记时返回 false
+	StartExecution(id uint, at time.Time) (bool, error)
+	// FinishExecution 结束仍在等待或运行中的执行，已结束时返回 false
+	FinishExecution(execution *model.WorkflowExecution) (bool, error)
+
+	// CreateStepRuns 创建步骤运行记录，已由其他实例创建的记录被忽略
+	CreateStepRuns(runs []*model.WorkflowStepRun) error
+	ListStepRuns(executionID uint) ([]*model.WorkflowStepRun, error)
+	GetStepRunByTaskID(agentTaskID string) (*model.WorkflowStepRun, error)
+	// ClaimStepRun 将等待中的步骤运行标记为运行中并记录下发的任务ID，已被其他实例领取时返回 false
+	ClaimStepRun(run *model.WorkflowStepRun, now time.Time) (bool, error)
+	// FinishStepRun 以任务ID为条件更新运行中的步骤运行，结果已被其他实例处理时返回 false
+	FinishStepRun(run *model.WorkflowStepRun, agentTaskID string) (bool, error)
+	// StopStepRuns 停止执行中尚未结束的步骤运行
+	StopStepRuns(executionID uint, at time.Time) error
+}
+
+type workflowRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkflowRepository(db *gorm.DB) WorkflowRepository {
+	return &workflowRepository{db: db}
+}
+
+func (r *workflowRepository) Create(workflow *model.Workflow) error {
+	return r.db.Omit("Owner", "Tasks").Create(workflow).Error
+}
+
+func (r *workflowRepository) GetByID(id uint) (*model.Workflow, error) {
+	var workflow model.Workflow
+	if err := r.db.First(&workflow, id).Error; err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+func (r *workflowRepository) GetByCode(ownerID uint, code string) (*model.Workflow, error) {
+	var workflow model.Workflow
+	if err := r.db.Where("owner_id = ? AND code = ?", ownerID, code).First(&workflow).Error; err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+func (r *workflowRepository) List(ownerID uint, offset, limit int) ([]*model.Workflow, int64, error) {
+	query := r.db.Model(&model.Workflow{}).Where("owner_id = ?", ownerID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var workflows []*model.Workflow
+	err := query.Order("id").Offset(offset).Limit(limit).Find(&workflows).Error
+	return workflows, total, err
+}
+
+func (r *workflowRepository) Update(workflow *model.Workflow) error {
+	return r.db.Omit("Owner", "Tasks").Save(workflow).Error
+}
+
+func (r *workflowRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("workflow_id = ?", id).Delete(&model.WorkflowTask{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Workflow{}, id).Error
+	})
+}
+
+func (r *workflowRepository) CreateTask(task *model.WorkflowTask) error {
+	return r.db.Omit("Workflow", "Owner", "Executions").Create(task).Error
+}
+
+func (r *workflowRepository) GetTask(id uint) (*model.WorkflowTask, error) {
+	var task model.WorkflowTask
+	if err := r.db.Preload("Workflow").First(&task, id).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (r *workflowRepository) ListTasks(ownerID uint, workflowID *uint, offset, limit int) ([]*model.WorkflowTask, int64, error) {
+	query := r.db.Model(&model.WorkflowTask{}).Where("owner_id = ?", ownerID)
+	if workflowID != nil {
+		query = query.Where("workflow_id = ?", *workflowID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var tasks []*model.WorkflowTask
+	err := query.Preload("Workflow").Order("id").Offset(offset).Limit(limit).Find(&tasks).Error
+	return tasks, total, err
+}
+
+func (r *workflowRepository) UpdateTask(task *model.WorkflowTask) error {
+	return r.db.Omit("Workflow", "Owner", "Executions", "RunCount", "SuccessCount", "FailureCount",
+		"LastRunAt").Save(task).Error
+}
+
+func (r *workflowRepository) DeleteTask(id uint) error {
+	return r.db.Delete(&model.WorkflowTask{}, id).Error
+}
+
+func (r *workflowRepository) CountRun(taskID uint, at time.Time) error {
+	return r.db.Model(&model.WorkflowTask{}).Where("id = ?", taskID).Updates(map[string]interface{}{
+		"run_count":   gorm.Expr("run_count + 1"),
+		"last_run_at": at,
+	}).Error
+}
+
+func (r *workflowRepository) CountResult(taskID uint, succeeded bool) error {
+	column := "failure_count"
+	if succeeded {
+		column = "success_count"
+	}
+	return r.db.Model(&model.WorkflowTask{}).Where("id = ?", taskID).
+		Update(column, gorm.Expr(column+" + 1")).Error
+}
+
+func (r *workflowRepository) ListDueTasks(now time.Time) ([]*model.WorkflowTask, error) {
+	var tasks []*model.WorkflowTask
+	err := r.db.Preload("Workflow").
+		Where("schedule_type = ? AND status <> ? AND next_run_at <= ?",
+			constants.WorkflowScheduleSchedule, constants.WorkflowTaskStatusOffline, now).
+		Order("next_run_at, id").Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *workflowRepository) ListTriggerTasks(ownerID uint, event string) ([]*model.WorkflowTask, error) {
+	var tasks []*model.WorkflowTask
+	err := r.db.Preload("Workflow").
+		Where("owner_id = ? AND schedule_type = ? AND trigger_event = ? AND status <> ?",
+			ownerID, constants.WorkflowScheduleTrigger, event, constants.WorkflowTaskStatusOffline).
+		Order("id").Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *workflowRepository) GetTaskByTriggerToken(token string) (*model.WorkflowTask, error) {
+	var task model.WorkflowTask
+	if err := r.db.Preload("Workflow").Where("trigger_token = ?", token).First(&task).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (r *workflowRepository) UpdateTriggerToken(id uint, token string) error {
+	return r.db.Model(&model.WorkflowTask{}).Where("id = ?", id).Update("trigger_token", token).Error
+}
+
+func (r *workflowRepository) ClaimSchedule(id uint, due time.Time, next *time.Time) (bool, error) {
+	result := r.db.Model(&model.WorkflowTask{}).
+		Where("id = ? AND schedule_type = ? AND next_run_at = ?", id, constants.WorkflowScheduleSchedule, due).
+		Update("next_run_at", next)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *workflowRepository) CreateExecution(execution *model.WorkflowExecution) error {
+	return r.db.Omit("Task", "Trigger", "StepRuns").Create(execution).Error
+}
+
+func (r *workflowRepository) GetExecution(id uint) (*model.WorkflowExecution, error) {
+	var execution model.WorkflowExecution
+	err := r.db.Preload("StepRuns", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&execution, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+// ListExecutions 按创建时间倒序分页查询，不返回定义快照、执行日志与事件数据
+func (r *workflowRepository) ListExecutions(ownerID uint, taskID *uint, offset, limit int) ([]*model.WorkflowExecution, int64, error) {
+	query := r.db.Model(&model.WorkflowExecution{}).Where("owner_id = ?", ownerID)
+	if taskID != nil {
+		query = query.Where("task_id = ?", *taskID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var executions []*model.WorkflowExecution
+	err := query.Omit("definition", "execution_log", "event_data").Order("id DESC").Offset(offset).Limit(limit).
+		Find(&executions).Error
+	return executions, total, err
+}
+
+func (r *workflowRepository) ListActiveExecutions() ([]*model.WorkflowExecution, error) {
+	var executions []*model.WorkflowExecution
+	err := r.db.Omit("execution_log").
+		Where("status IN ?", []string{constants.WorkflowExecutionPending, constants.WorkflowExecutionRunning}).
+		Order("id").Find(&executions).Error
+	return executions, err
+}
+
+func (r *workflowRepository) StartExecution(id uint, at time.Time) (bool, error) {
+	result := r.db.Model(&model.WorkflowExecution{}).
+		Where("id = ? AND status = ?", id, constants.WorkflowExecutionPending).
+		Updates(map[string]interface{}{
+			"status":     constants.WorkflowExecutionRunning,
+			"start_time": at,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *workflowRepository) FinishExecution(execution *model.WorkflowExecution) (bool, error) {
+	result := r.db.Model(&model.WorkflowExecution{}).
+		Where("id = ? AND status IN ?", execution.ID,
+			[]string{constants.WorkflowExecutionPending, constants.WorkflowExecutionRunning}).
+		Updates(map[string]interface{}{
+			"status":        execution.Status,
+			"end_time":      execution.EndTime,
+			"duration":      execution.Duration,
+			"error_message": execution.ErrorMessage,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *workflowRepository) CreateStepRuns(runs []*model.WorkflowStepRun) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&runs).Error
+}
+
+func (r *workflowRepository) ListStepRuns(executionID uint) ([]*model.WorkflowStepRun, error) {
+	var runs []*model.WorkflowStepRun
+	err := r.db.Where("execution_id = ?", executionID).Order("id").Find(&runs).Error
+	return runs, err
+}
+
+func (r *workflowRepository) GetStepRunByTaskID(agentTaskID string) (*model.WorkflowStepRun, error) {
+	var run model.WorkflowStepRun
+	if err := r.db.Where("agent_task_id = ?", agentTaskID).First(&run).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *workflowRepository) ClaimStepRun(run *model.WorkflowStepRun, now time.Time) (bool, error) {
+	result := r.db.Model(&model.WorkflowStepRun{}).
+		Where("id = ? AND status = ? AND attempt = ? AND next_run_at <= ?",
+			run.ID, constants.WorkflowStepPending, run.Attempt, now).
+		Updates(map[string]interface{}{
+			"status":        constants.WorkflowStepRunning,
+			"attempt":       run.Attempt + 1,
+			"agent_task_id": run.AgentTaskID,
+			"deadline":      run.Deadline,
+			"started_at":    now,
+			"next_run_at":   nil,
+		})
+	if result.RowsAffected == 1 {
+		run.Status = constants.WorkflowStepRunning
+		run.Attempt++
+		run.StartedAt = &now
+		run.NextRunAt = nil
+	}
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *workflowRepository) FinishStepRun(run *model.WorkflowStepRun, agentTaskID string) (bool, error) {
+	result := r.db.Model(&model.WorkflowStepRun{}).
+		Where("id = ? AND status = ? AND agent_task_id = ?", run.ID, constants.WorkflowStepRunning, agentTaskID).
+		Updates(map[string]interface{}{
+			"status":      run.Status,
+			"outputs":     run.Outputs,
+			"message":     run.Message,
+			"output":      run.Output,
+			"next_run_at": run.NextRunAt,
+			"deadline":    run.Deadline,
+			"ended_at":    run.EndedAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *workflowRepository) StopStepRuns(executionID uint, at time.Time) error {
+	return r.db.Model(&model.WorkflowStepRun{}).
+		Where("execution_id = ? AND status IN ?", executionID,
+			[]string{constants.WorkflowStepPending, constants.WorkflowStepRunning}).
+		Updates(map[string]interface{}{
+			"status":      constants.WorkflowStepStopped,
+			"next_run_at": nil,
+			"ended_at":    at,
+		}).Error
+}
@@ -2,9 +2,11 @@ package router
 
 import (
 	"api-service/internal/config"
+	"api-service/internal/constants"
 	"api-service/internal/controller"
 	"api-service/internal/middleware"
 	"api-service/internal/service"
+	"api-service/internal/telemetry"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -17,10 +19,35 @@ func SetupRouter(services *service.Services, cfg *config.Config) *gin.Engine {
 	r.Use(middleware.CORS())
 	r.Use(middleware.Logger())
 	r.Use(middleware.Recovery())
+	r.Use(middleware.Metrics())
 
 	// 初始化控制器
 	userController := controller.NewUserController(services.UserService)
 	appController := controller.NewApplicationController(services.ApplicationService)
+	secretController := controller.NewSecretController(services.SecretService)
+	deploymentController := controller.NewDeploymentController(services.DeploymentService)
+	certificateController := controller.NewCertificateController(services.CertificateService)
+	acmeController := controller.NewACMEController(services.ACMEService)
+	caController := controller.NewCAController(services.CAService)
+	gatewayController := controller.NewGatewayController(services.GatewayService)
+	monitorController := controller.NewMonitorController(services.MonitorService)
+	alertController := controller.NewAlertController(services.AlertService)
+	notificationController := controller.NewNotificationController(services.NotificationService)
+	inboxController := controller.NewInboxController(services.InboxService)
+	webhookController := controller.NewWebhookController(services.WebhookService)
+	workflowController := controller.NewWorkflowController(services.WorkflowService)
+	fileController := controller.NewFileController(services.FileService)
+
+	// Prometheus 运行指标
+	if cfg.Prometheus.Enabled {
+		r.GET(constants.TelemetryPath, middleware.ScrapeAuth(cfg.Prometheus.Token), gin.WrapH(telemetry.Handler()))
+	}
+
+	// ACME HTTP-01 质询，须在根路径下公开访问
+	r.GET(constants.ACMEHTTPChallengePath+":token", acmeController.HTTPChallenge)
+
+	// 工作流入站 Webhook，以地址中的凭证认证
+	r.POST(constants.WorkflowWebhookPath+":token", workflowController.TriggerWebhook)
 
 	// API路由组
 	api := r.Group("/api/v1")
@@ -30,6 +57,14 @@ func SetupRouter(services *service.Services, cfg *config.Config) *gin.Engine {
 		auth.POST("/register", userController.Register)
 		auth.POST("/login", userController.Login)
 
+		// 站内通知推送，EventSource 无法携带 Authorization 头，使用一次性凭证认证
+		api.GET("/notifications/inbox/stream", inboxController.Stream)
+
+		// 实时日志推送，使用与站内通知推送相同的一次性凭证认证
+		streamAuth := middleware.StreamTicketAuth(services.InboxService.RedeemStreamTicket)
+		api.GET("/deployments/:id/logs/stream", streamAuth, deploymentController.StreamLogs)
+		api.GET("/workflows/executions/:id/logs/stream", streamAuth, workflowController.StreamExecutionLogs)
+
 		// 需要认证的路由
 		protected := api.Group("/")
 		protected.Use(middleware.JWTAuth(cfg))
@@ -48,23 +83,160 @@ func SetupRouter(services *service.Services, cfg *config.Config) *gin.Engine {
 			applications.POST("/:id/stop", appController.StopApplication)
 			applications.POST("/:id/restart", appController.RestartApplication)
 
+			// 密钥管理相关路由
+			secrets := protected.Group("/secrets")
+			secrets.POST("/", secretController.CreateSecret)
+			secrets.GET("/", secretController.ListSecrets)
+			secrets.GET("/expiring", secretController.ListExpiringSecrets)
+			secrets.GET("/:id", secretController.GetSecret)
+			secrets.PUT("/:id", secretController.UpdateSecret)
+			secrets.DELETE("/:id", secretController.DeleteSecret)
+			secrets.POST("/:id/reveal", secretController.RevealSecret)
+			secrets.POST("/:id/rotate", secretController.RotateSecret)
+			secrets.PUT("/:id/share", secretController.ShareSecret)
+			secrets.GET("/:id/versions", secretController.ListVersions)
+			secrets.POST("/:id/versions/:version/rollback", secretController.RollbackSecret)
+			secrets.GET("/:id/usages", deploymentController.ListSecretUsages)
+			secrets.POST("/:id/redeploy", deploymentController.RedeployBySecret)
+
+			// SSL证书相关路由
+			certificates := protected.Group("/certificates")
+			certificates.POST("/", certificateController.UploadCertificate)
+			certificates.GET("/", certificateController.ListCertificates)
+			certificates.GET("/expiring", certificateController.ListExpiringCertificates)
+			certificates.POST("/acme", acmeController.RequestCertificate)
+			certificates.POST("/issue", caController.IssueCertificate)
+			certificates.POST("/:id/renew", acmeController.RenewCertificate)
+			certificates.GET("/:id", certificateController.GetCertificate)
+			certificates.PUT("/:id", certificateController.UpdateCertificate)
+			certificates.DELETE("/:id", certificateController.DeleteCertificate)
+
+			// 内部CA相关路由
+			cas := protected.Group("/certificate-authorities")
+			cas.POST("/", caController.CreateCA)
+			cas.GET("/", caController.ListCAs)
+			cas.GET("/bundle", caController.ExportBundle)
+			cas.GET("/:id", caController.GetCA)
+			cas.DELETE("/:id", caController.DeleteCA)
+			cas.GET("/:id/bundle", caController.ExportCABundle)
+			cas.POST("/:id/install", caController.InstallCA)
+
+			// 应用部署相关路由
+			deployments := protected.Group("/deployments")
+			deployments.POST("/", deploymentController.CreateDeployment)
+			deployments.GET("/", deploymentController.ListDeployments)
+			deployments.GET("/:id", deploymentController.GetDeployment)
+			deployments.GET("/:id/logs", deploymentController.ListLogs)
+
 			// 监控相关路由
 			monitoring := protected.Group("/monitoring")
-			monitoring.GET("/servers/:id/metrics", func(c *gin.Context) {
-				// TODO: 实现服务器监控数据获取
-			})
-			monitoring.GET("/applications/:id/metrics", func(c *gin.Context) {
-				// TODO: 实现应用监控数据获取
-			})
+			monitoring.GET("/servers/:id/metrics", monitorController.GetServerMetrics)
+			monitoring.GET("/applications/:id/metrics", monitorController.GetApplicationMetrics)
+
+			// 告警相关路由
+			alerts := protected.Group("/alerts")
+			alerts.GET("/rules", alertController.ListRules)
+			alerts.POST("/rules", alertController.CreateRule)
+			alerts.POST("/rules/preview", alertController.PreviewRule)
+			alerts.GET("/rules/:id", alertController.GetRule)
+			alerts.PUT("/rules/:id", alertController.UpdateRule)
+			alerts.DELETE("/rules/:id", alertController.DeleteRule)
+			alerts.GET("/records", alertController.ListRecords)
+			alerts.GET("/records/:id", alertController.GetRecord)
+			alerts.POST("/records/:id/acknowledge", alertController.AcknowledgeRecord)
+			alerts.POST("/records/:id/resolve", alertController.ResolveRecord)
+			alerts.GET("/silences", alertController.ListSilences)
+			alerts.POST("/silences", alertController.CreateSilence)
+			alerts.DELETE("/silences/:id", alertController.DeleteSilence)
+			alerts.GET("/maintenance-windows", alertController.ListMaintenanceWindows)
+			alerts.POST("/maintenance-windows", alertController.CreateMaintenanceWindow)
+			alerts.PUT("/maintenance-windows/:id", alertController.UpdateMaintenanceWindow)
+			alerts.DELETE("/maintenance-windows/:id", alertController.DeleteMaintenanceWindow)
+
+			// 通知相关路由，发送、渠道测试、模板维护与投递记录仅管理员可用
+			notifications := protected.Group("/notifications")
+			notifications.GET("/channels", notificationController.ListChannels)
+			notifications.GET("/templates", notificationController.ListTemplates)
+			notifications.GET("/templates/:id", notificationController.GetTemplate)
+			notifications.GET("/inbox", inboxController.List)
+			notifications.GET("/inbox/unread-count", inboxController.UnreadCount)
+			notifications.POST("/inbox/read", inboxController.MarkRead)
+			notifications.POST("/inbox/read-all", inboxController.MarkAllRead)
+			notifications.DELETE("/inbox/:id", inboxController.Delete)
+			notifications.POST("/inbox/stream-ticket", inboxController.StreamTicket)
+			admin := notifications.Group("/", middleware.RequireRole(constants.RoleAdmin))
+			admin.POST("/", notificationController.Send)
+			admin.POST("/channels/:name/test", notificationController.TestChannel)
+			admin.POST("/templates", notificationController.CreateTemplate)
+			admin.PUT("/templates/:id", notificationController.UpdateTemplate)
+			admin.DELETE("/templates/:id", notificationController.DeleteTemplate)
+			admin.GET("/records", notificationController.ListRecords)
+
+			// 出站 Webhook 相关路由
+			webhooks := protected.Group("/webhooks")
+			webhooks.GET("/events", webhookController.ListEvents)
+			webhooks.GET("/", webhookController.ListWebhooks)
+			webhooks.POST("/", webhookController.CreateWebhook)
+			webhooks.GET("/:id", webhookController.GetWebhook)
+			webhooks.PUT("/:id", webhookController.UpdateWebhook)
+			webhooks.DELETE("/:id", webhookController.DeleteWebhook)
+			webhooks.POST("/:id/ping", webhookController.Ping)
+			webhooks.GET("/:id/deliveries", webhookController.ListDeliveries)
+			webhooks.GET("/:id/deliveries/:delivery_id", webhookController.GetDelivery)
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", webhookController.Redeliver)
+
+			// 工作流相关路由
+			workflows := protected.Group("/workflows")
+			workflows.GET("/", workflowController.ListWorkflows)
+			workflows.POST("/", workflowController.CreateWorkflow)
+			workflows.GET("/tasks", workflowController.ListTasks)
+			workflows.POST("/tasks", workflowController.CreateTask)
+			workflows.GET("/tasks/:id", workflowController.GetTask)
+			workflows.PUT("/tasks/:id", workflowController.UpdateTask)
+			workflows.DELETE("/tasks/:id", workflowController.DeleteTask)
+			workflows.POST("/tasks/:id/run", workflowController.RunTask)
+			workflows.POST("/tasks/:id/trigger-token", workflowController.RotateTriggerToken)
+			workflows.GET("/executions", workflowController.ListExecutions)
+			workflows.GET("/executions/:id", workflowController.GetExecution)
+			workflows.GET("/executions/:id/logs", workflowController.ListExecutionLogs)
+			workflows.POST("/executions/:id/stop", workflowController.StopExecution)
+			workflows.GET("/:id", workflowController.GetWorkflow)
+			workflows.PUT("/:id", workflowController.UpdateWorkflow)
+			workflows.DELETE("/:id", workflowController.DeleteWorkflow)
+
+			// 工作空间文件相关路由
+			files := protected.Group("/files")
+			files.GET("/", fileController.ListFiles)
+			files.POST("/", fileController.UploadFile)
+			files.POST("/directories", fileController.CreateDirectory)
+			files.POST("/uploads", fileController.CreateUpload)
+			files.GET("/uploads/:upload_id", fileController.GetUpload)
+			files.PUT("/uploads/:upload_id/chunks/:index", fileController.UploadChunk)
+			files.POST("/uploads/:upload_id/complete", fileController.CompleteUpload)
+			files.DELETE("/uploads/:upload_id", fileController.AbortUpload)
+			files.GET("/:id", fileController.GetFile)
+			files.GET("/:id/download", fileController.DownloadFile)
+			files.POST("/:id/rename", fileController.RenameFile)
+			files.POST("/:id/move", fileController.MoveFile)
+			files.DELETE("/:id", fileController.DeleteFile)
 
 			// 网关相关路由
 			gateway := protected.Group("/gateway")
-			gateway.GET("/", func(c *gin.Context) {
-				// TODO: 实现网关列表获取
-			})
-			gateway.POST("/", func(c *gin.Context) {
-				// TODO: 实现网关创建
-			})
+			gateway.GET("/", gatewayController.ListGateways)
+			gateway.POST("/", gatewayController.CreateGateway)
+			gateway.GET("/:id", gatewayController.GetGateway)
+			gateway.PUT("/:id", gatewayController.UpdateGateway)
+			gateway.DELETE("/:id", gatewayController.DeleteGateway)
+			gateway.POST("/:id/publishes", gatewayController.CreatePublish)
+			gateway.PUT("/:id/publishes/:publish_id", gatewayController.UpdatePublish)
+			gateway.DELETE("/:id/publishes/:publish_id", gatewayController.DeletePublish)
+			gateway.GET("/:id/traffic", gatewayController.GetTraffic)
+			gateway.GET("/:id/proxy-config", gatewayController.ExportProxyConfig)
+			gateway.POST("/:id/proxy-config/deploy", gatewayController.DeployProxyConfig)
+			gateway.GET("/:id/access-rules", gatewayController.ListAccessRules)
+			gateway.POST("/:id/access-rules", gatewayController.CreateAccessRule)
+			gateway.PUT("/:id/access-rules/:rule_id", gatewayController.UpdateAccessRule)
+			gateway.DELETE("/:id/access-rules/:rule_id", gatewayController.DeleteAccessRule)
 		}
 	}
 
@@ -0,0 +1,551 @@
+package service
+
+import (
+	"api-service/internal/config"
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/certutil"
+	"api-service/pkg/dnsprovider"
+	"api-service/pkg/utils"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/acme"
+	"gorm.io/gorm"
+)
+
+// ACMEService 通过 ACME 协议签发并续期证书
+// HTTP-01 质询响应保存在 Redis 中，由 api-service 与应用网关共同对外提供
+type ACMEService interface {
+	RequestCertificate(ownerID uint, req *ACMERequest) (*model.SSLCertificate, error)
+	RenewCertificate(userID, id uint) (*model.SSLCertificate, error)
+	HTTPChallengeResponse(ctx context.Context, token string) (string, error)
+	RenewDue(now time.Time) error
+	Start(ctx context.Context)
+}
+
+// ACMERequest 申请证书的参数
+type ACMERequest struct {
+	Name          string
+	Domains       []string
+	ChallengeType string
+	DNSProvider   string
+	AutoRenew     bool
+}
+
+type acmeService struct {
+	certRepo   repository.CertificateRepository
+	alertRepo  repository.AlertRepository
+	configRepo repository.SystemConfigRepository
+	rdb        *redis.Client
+	encryptor  *utils.Encryptor
+	cfg        config.ACMEConfig
+
+	// 额外信任的根证书，nil 表示仅使用系统根证书
+	roots *x509.CertPool
+
+	mu     sync.Mutex
+	client *acme.Client
+
+	// 正在签发中的证书，避免重复下单
+	inflight sync.Map
+}
+
+func NewACMEService(certRepo repository.CertificateRepository, alertRepo repository.AlertRepository,
+	configRepo repository.SystemConfigRepository, rdb *redis.Client, encryptor *utils.Encryptor,
+	cfg config.ACMEConfig) (ACMEService, error) {
+	s := &acmeService{
+		certRepo:   certRepo,
+		alertRepo:  alertRepo,
+		configRepo: configRepo,
+		rdb:        rdb,
+		encryptor:  encryptor,
+		cfg:        cfg,
+	}
+
+	if cfg.CARootsFile != "" {
+		data, err := os.ReadFile(cfg.CARootsFile)
+		if err != nil {
+			return nil, fmt.Errorf("read acme ca roots: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil || roots == nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(data) {
+			return nil, errors.New("no certificates found in acme ca roots file")
+		}
+		s.roots = roots
+	}
+
+	return s, nil
+}
+
+func (s *acmeService) RequestCertificate(ownerID uint, req *ACMERequest) (*model.SSLCertificate, error) {
+	domains, err := normalizeDomains(req.Domains)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.ChallengeType {
+	case "":
+		req.ChallengeType = constants.ACMEChallengeHTTP01
+	case constants.ACMEChallengeHTTP01, constants.ACMEChallengeDNS01:
+	default:
+		return nil, invalidInputf("unsupported challenge type: %s", req.ChallengeType)
+	}
+
+	for _, domain := range domains {
+		if strings.HasPrefix(domain, "*.") && req.ChallengeType != constants.ACMEChallengeDNS01 {
+			return nil, invalidInputf("wildcard domains require dns-01 challenge")
+		}
+	}
+	if req.ChallengeType == constants.ACMEChallengeDNS01 {
+		if _, ok := s.cfg.DNSProviders[req.DNSProvider]; !ok {
+			return nil, invalidInputf("dns provider %q is not configured", req.DNSProvider)
+		}
+	}
+
+	name := req.Name
+	if name == "" {
+		name = domains[0]
+	}
+
+	cert := &model.SSLCertificate{
+		Name:            name,
+		Domain:          domains[0],
+		Domains:         encodeDomains(domains),
+		CertificateType: constants.CertificateTypeLetsEncrypt,
+		ChallengeType:   req.ChallengeType,
+		DNSProvider:     req.DNSProvider,
+		Status:          constants.CertificateStatusPending,
+		OwnerID:         ownerID,
+	}
+	if req.AutoRenew {
+		cert.AutoRenew = 1
+	}
+	if err := s.certRepo.Create(cert); err != nil {
+		return nil, err
+	}
+
+	s.issueAsync(cert, domains)
+	return cert, nil
+}
+
+// RenewCertificate 立即续期指定证书
+func (s *acmeService) RenewCertificate(userID, id uint) (*model.SSLCertificate, error) {
+	cert, err := s.certRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if cert.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	if cert.CertificateType != constants.CertificateTypeLetsEncrypt || cert.ChallengeType == "" {
+		return nil, invalidInputf("certificate was not issued via ACME")
+	}
+
+	domains, err := certificateDomains(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	s.issueAsync(cert, domains)
+	return cert, nil
+}
+
+func (s *acmeService) HTTPChallengeResponse(ctx context.Context, token string) (string, error) {
+	keyAuth, err := s.rdb.Get(ctx, constants.ACMEHTTPChallengeKeyPrefix+token).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return keyAuth, err
+}
+
+// RenewDue 续期即将到期且开启自动续期的 ACME 证书
+func (s *acmeService) RenewDue(now time.Time) error {
+	deadline := now.AddDate(0, 0, s.cfg.RenewBeforeDays)
+	certs, err := s.certRepo.ListAutoRenewBefore(constants.CertificateTypeLetsEncrypt, deadline)
+	if err != nil {
+		return err
+	}
+
+	for _, cert := range certs {
+		if cert.ChallengeType == "" {
+			continue
+		}
+		domains, err := certificateDomains(cert)
+		if err != nil {
+			log.Printf("Skip renewing certificate %d: %v", cert.ID, err)
+			continue
+		}
+		// 续期逐个进行，避免触发 CA 的速率限制
+		s.issue(cert, domains)
+	}
+	return nil
+}
+
+// Start 启动自动续期检查
+func (s *acmeService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(constants.ACMERenewCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.RenewDue(time.Now()); err != nil {
+				log.Printf("ACME renewal check failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// issueAsync 后台签发，使用副本避免与调用方共享同一对象
+func (s *acmeService) issueAsync(cert *model.SSLCertificate, domains []string) {
+	copied := *cert
+	go s.issue(&copied, domains)
+}
+
+// issue 下单签发证书并保存结果，失败时记录错误信息
+func (s *acmeService) issue(cert *model.SSLCertificate, domains []string) {
+	if _, loaded := s.inflight.LoadOrStore(cert.ID, struct{}{}); loaded {
+		return
+	}
+	defer s.inflight.Delete(cert.ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.ACMEOrderTimeout)
+	defer cancel()
+
+	input, err := s.obtain(ctx, cert, domains)
+	if err == nil {
+		err = populateCertificate(cert, input, s.encryptor, s.roots)
+	}
+
+	if err != nil {
+		log.Printf("ACME issuance for certificate %d (%s) failed: %v", cert.ID, cert.Domain, err)
+		cert.LastError = err.Error()
+		if cert.NotAfter == nil {
+			cert.Status = constants.CertificateStatusFailed
+		}
+	} else {
+		// 新证书已生效，之前的过期告警不再有效
+		if err := s.alertRepo.ResolveRecords(certificateAlertPrefix(cert), "certificate renewed"); err != nil {
+			log.Printf("Failed to resolve alerts for certificate %d: %v", cert.ID, err)
+		}
+	}
+
+	if err := s.certRepo.Update(cert); err != nil {
+		log.Printf("Failed to save certificate %d: %v", cert.ID, err)
+	}
+}
+
+// obtain 完成 ACME 下单、质询与签发流程，返回 PEM 格式的证书与私钥
+func (s *acmeService) obtain(ctx context.Context, cert *model.SSLCertificate, domains []string) (*CertificateInput, error) {
+	client, err := s.acmeClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return nil, fmt.Errorf("create order: %w", err)
+	}
+
+	// 订单查询响应不一定带 Location，保留下单时返回的订单地址
+	orderURL := order.URI
+	for _, authzURL := range order.AuthzURLs {
+		if err := s.authorize(ctx, client, cert, authzURL); err != nil {
+			return nil, err
+		}
+	}
+
+	order, err = client.WaitOrder(ctx, orderURL)
+	if err != nil {
+		return nil, fmt.Errorf("wait order: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: strings.TrimPrefix(domains[0], "*.")},
+		DNSNames: domains,
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+
+	ders, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		// 部分 CA(如 Pebble)异步签发且 finalize 响应不带订单地址，改为按原订单地址等待签发
+		done, waitErr := client.WaitOrder(ctx, orderURL)
+		if waitErr != nil || done.CertURL == "" {
+			return nil, fmt.Errorf("finalize order: %w", err)
+		}
+		if ders, err = client.FetchCert(ctx, done.CertURL, true); err != nil {
+			return nil, fmt.Errorf("fetch certificate: %w", err)
+		}
+	}
+
+	var certPEM []byte
+	for _, der := range ders {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateInput{
+		CertificateType: constants.CertificateTypeLetsEncrypt,
+		Certificate:     string(certPEM),
+		PrivateKey:      string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+		AutoRenew:       cert.AutoRenew == 1,
+	}, nil
+}
+
+// authorize 完成单个域名授权的质询
+func (s *acmeService) authorize(ctx context.Context, client *acme.Client, cert *model.SSLCertificate, authzURL string) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("get authorization: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var chal *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == cert.ChallengeType {
+			chal = c
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("%s challenge not offered for %s", cert.ChallengeType, authz.Identifier.Value)
+	}
+
+	switch cert.ChallengeType {
+	case constants.ACMEChallengeHTTP01:
+		keyAuth, err := client.HTTP01ChallengeResponse(chal.Token)
+		if err != nil {
+			return err
+		}
+		key := constants.ACMEHTTPChallengeKeyPrefix + chal.Token
+		if err := s.rdb.Set(ctx, key, keyAuth, constants.ACMEChallengeTTL).Err(); err != nil {
+			return fmt.Errorf("store http-01 challenge: %w", err)
+		}
+		defer s.rdb.Del(context.Background(), key)
+
+	case constants.ACMEChallengeDNS01:
+		provider, err := s.dnsProvider(cert.DNSProvider)
+		if err != nil {
+			return err
+		}
+		value, err := client.DNS01ChallengeRecord(chal.Token)
+		if err != nil {
+			return err
+		}
+		// 通配符域名与其主域名使用同一个质询记录
+		fqdn := "_acme-challenge." + authz.Identifier.Value + "."
+		if err := provider.Present(ctx, fqdn, value); err != nil {
+			return fmt.Errorf("present dns record: %w", err)
+		}
+		defer func() {
+			if err := provider.CleanUp(context.Background(), fqdn, value); err != nil {
+				log.Printf("Failed to clean up dns record %s: %v", fqdn, err)
+			}
+		}()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(s.cfg.DNSPropagationSeconds) * time.Second):
+		}
+	}
+
+	if _, err := client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("accept challenge: %w", err)
+	}
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("authorize %s: %w", authz.Identifier.Value, err)
+	}
+	return nil
+}
+
+func (s *acmeService) dnsProvider(name string) (dnsprovider.Provider, error) {
+	options, ok := s.cfg.DNSProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("dns provider %q is not configured", name)
+	}
+	return dnsprovider.New(options["type"], options)
+}
+
+// acmeClient 返回已注册账户的 ACME 客户端，账户私钥加密保存在系统配置中
+func (s *acmeService) acmeClient(ctx context.Context) (*acme.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	key, err := s.accountKey()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &acme.Client{
+		Key:          key,
+		DirectoryURL: s.cfg.DirectoryURL,
+		UserAgent:    "webox",
+	}
+	if s.roots != nil {
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: s.roots, MinVersion: tls.VersionTLS12},
+			},
+		}
+	}
+
+	account := &acme.Account{}
+	if s.cfg.Email != "" {
+		account.Contact = []string{"mailto:" + s.cfg.Email}
+	}
+	if s.cfg.EABKeyID != "" {
+		hmacKey, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s.cfg.EABHMACKey, "="))
+		if err != nil {
+			return nil, fmt.Errorf("invalid eab hmac key: %w", err)
+		}
+		account.ExternalAccountBinding = &acme.ExternalAccountBinding{
+			KID: s.cfg.EABKeyID,
+			Key: hmacKey,
+		}
+	}
+
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("register acme account: %w", err)
+	}
+
+	s.client = client
+	return client, nil
+}
+
+func (s *acmeService) accountKey() (crypto.Signer, error) {
+	stored, err := s.configRepo.Get(constants.SystemConfigACMEAccountKey)
+	if err == nil {
+		keyPEM, err := s.encryptor.Decrypt(stored.ConfigValue)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt acme account key: %w", err)
+		}
+		return certutil.ParsePrivateKey([]byte(keyPEM))
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encryptor.Encrypt(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.configRepo.Save(&model.SystemConfig{
+		ConfigKey:   constants.SystemConfigACMEAccountKey,
+		ConfigValue: encrypted,
+		ConfigType:  "TEXT",
+		Category:    "SECURITY",
+		Description: "ACME account private key",
+		IsReadonly:  1,
+		IsEncrypted: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func normalizeDomains(domains []string) ([]string, error) {
+	seen := make(map[string]bool, len(domains))
+	result := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if domain == "" || seen[domain] {
+			continue
+		}
+		if strings.ContainsAny(domain, " /:") {
+			return nil, invalidInputf("invalid domain: %s", domain)
+		}
+		seen[domain] = true
+		result = append(result, domain)
+	}
+	if len(result) == 0 {
+		return nil, invalidInputf("at least one domain is required")
+	}
+	return result, nil
+}
+
+// encodeDomains 将域名列表编码为证书记录中保存的 JSON 数组
+func encodeDomains(domains []string) string {
+	data, _ := json.Marshal(domains)
+	return string(data)
+}
+
+// certificateDomains 返回重新签发与续期时使用的域名列表
+// 优先取证书记录保存的域名列表，升级前的记录取现有证书的 SAN
+func certificateDomains(cert *model.SSLCertificate) ([]string, error) {
+	if cert.Domains != "" {
+		var domains []string
+		if err := json.Unmarshal([]byte(cert.Domains), &domains); err != nil {
+			return nil, fmt.Errorf("invalid certificate domains: %w", err)
+		}
+		return normalizeDomains(domains)
+	}
+	if cert.CertificateData == "" {
+		return normalizeDomains([]string{cert.Domain})
+	}
+	certs, err := certutil.ParseCertificates([]byte(cert.CertificateData))
+	if err != nil {
+		return nil, err
+	}
+	if len(certs[0].DNSNames) == 0 {
+		return normalizeDomains([]string{cert.Domain})
+	}
+	return normalizeDomains(certs[0].DNSNames)
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/acme"
+)
+
+// newACMEStub 启动一个只接受账户查询、拒绝所有订单的 ACME 服务，记录每个订单请求的域名
+func newACMEStub(t *testing.T) (*httptest.Server, func() [][]string) {
+	var (
+		mu     sync.Mutex
+		orders [][]string
+	)
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/directory", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   server.URL + "/nonce",
+			"newAccount": server.URL + "/account",
+			"newOrder":   server.URL + "/order",
+		})
+	})
+	mux.HandleFunc("/nonce", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "nonce")
+	})
+	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "nonce")
+		w.Header().Set("Location", server.URL+"/account/1")
+		json.NewEncoder(w).Encode(map[string]string{"status": "valid"})
+	})
+	mux.HandleFunc("/order", func(w http.ResponseWriter, r *http.Request) {
+		var jws struct {
+			Payload string `json:"payload"`
+		}
+		var order struct {
+			Identifiers []acme.AuthzID `json:"identifiers"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+			t.Errorf("decode jws: %v", err)
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+		if err == nil {
+			err = json.Unmarshal(payload, &order)
+		}
+		if err != nil {
+			t.Errorf("decode order: %v", err)
+		}
+
+		domains := make([]string, 0, len(order.Identifiers))
+		for _, id := range order.Identifiers {
+			domains = append(domains, id.Value)
+		}
+		mu.Lock()
+		orders = append(orders, domains)
+		mu.Unlock()
+
+		w.Header().Set("Replay-Nonce", "nonce")
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"type":"urn:ietf:params:acme:error:rejectedIdentifier","detail":"rejected"}`)
+	})
+
+	return server, func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]string(nil), orders...)
+	}
+}
+
+func TestACMERetryKeepsAllDomains(t *testing.T) {
+	server, orders := newACMEStub(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &acmeService{client: &acme.Client{Key: key, DirectoryURL: server.URL + "/directory"}}
+
+	requested := []string{"example.com", "www.example.com", "api.example.com"}
+	cert := &model.SSLCertificate{
+		Domain:          requested[0],
+		Domains:         encodeDomains(requested),
+		CertificateType: constants.CertificateTypeLetsEncrypt,
+		ChallengeType:   constants.ACMEChallengeHTTP01,
+	}
+
+	// 首次签发失败后证书没有证书内容，重试与续期仍应使用全部域名
+	for attempt := 0; attempt < 2; attempt++ {
+		domains, err := certificateDomains(cert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.obtain(context.Background(), cert, domains)
+		if err == nil || !strings.Contains(err.Error(), "create order") {
+			t.Fatalf("attempt %d: expected order error, got %v", attempt, err)
+		}
+	}
+
+	got := orders()
+	if len(got) != 2 {
+		t.Fatalf("got %d orders, want 2", len(got))
+	}
+	for i, domains := range got {
+		if !reflect.DeepEqual(domains, requested) {
+			t.Errorf("order %d domains = %v, want %v", i, domains, requested)
+		}
+	}
+}
+
+func TestCertificateDomains(t *testing.T) {
+	tests := []struct {
+		name    string
+		cert    *model.SSLCertificate
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "stored domains",
+			cert: &model.SSLCertificate{Domain: "a.example.com", Domains: `["a.example.com","*.b.example.com"]`},
+			want: []string{"a.example.com", "*.b.example.com"},
+		},
+		{
+			name: "legacy record without domains",
+			cert: &model.SSLCertificate{Domain: "A.example.com."},
+			want: []string{"a.example.com"},
+		},
+		{
+			name:    "invalid stored domains",
+			cert:    &model.SSLCertificate{Domain: "a.example.com", Domains: `a.example.com`},
+			wantErr: true,
+		},
+		{
+			name:    "empty stored domains",
+			cert:    &model.SSLCertificate{Domain: "a.example.com", Domains: `[]`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := certificateDomains(tt.cert)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("certificateDomains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
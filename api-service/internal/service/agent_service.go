@@ -0,0 +1,414 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/internal/telemetry"
+	"api-service/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// AgentService 负责向 Agent 下发任务并接收执行结果
+// 任务与结果均通过 Redis 频道传输，消息体使用每个 Agent 独立的任务密钥加密，密钥由主任务密钥按 Agent ID 派生
+type AgentService interface {
+	DispatchTask(serverID uint, task *AgentTask) error
+	OnTaskResult(taskType string, handler TaskResultHandler)
+	OnTaskLog(taskType string, handler TaskLogHandler)
+	DecodeMessage(agentID, payload string, v interface{}) error
+	Heartbeat(heartbeat *AgentHeartbeat) (*model.ServerAgent, error)
+	CountAgents() (registered, connected int64, err error)
+	Start(ctx context.Context)
+}
+
+// AgentTask 下发给 Agent 的任务，与 Agent 端 task.Task 结构一致
+type AgentTask struct {
+	ID       string                 `json:"id"`
+	Type     string                 `json:"type"`
+	Params   map[string]interface{} `json:"params"`
+	Timeout  int                    `json:"timeout"`
+	Priority int                    `json:"priority"`
+}
+
+// AgentTaskResult Agent 返回的任务执行结果，与 Agent 端 task.TaskResult 结构一致
+type AgentTaskResult struct {
+	TaskID   string                 `json:"task_id"`
+	Type     string                 `json:"type"`
+	Status   string                 `json:"status"`
+	Message  string                 `json:"message"`
+	Data     map[string]interface{} `json:"data"`
+	Duration int64                  `json:"duration"`
+}
+
+// AgentTaskLog Agent 执行任务时分批推送的输出，与 Agent 端 task.TaskLog 结构一致
+type AgentTaskLog struct {
+	TaskID string    `json:"task_id"`
+	Type   string    `json:"type"`
+	Seq    int       `json:"seq"`
+	Lines  []string  `json:"lines"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// AgentHeartbeat Agent 经 gRPC 上报的心跳，与 Agent 端 communication.Heartbeat 结构一致
+type AgentHeartbeat struct {
+	AgentID string    `json:"agent_id"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// AgentMetrics Agent 批量上报的指标，上报同时视为一次心跳
+type AgentMetrics struct {
+	AgentHeartbeat
+	Points []*AgentMetricPoint `json:"points"`
+}
+
+// AgentMetricPoint 与 Agent 端 monitor.Point 结构一致
+type AgentMetricPoint struct {
+	Measurement string             `json:"measurement"`
+	Tags        map[string]string  `json:"tags"`
+	Fields      map[string]float64 `json:"fields"`
+	Time        time.Time          `json:"time"`
+}
+
+// TaskResultHandler 任务结果处理函数，agent 为已通过密钥验证的发送方
+type TaskResultHandler func(agent *model.ServerAgent, result *AgentTaskResult)
+
+// TaskLogHandler 任务实时日志处理函数，agent 为已通过密钥验证的发送方
+type TaskLogHandler func(agent *model.ServerAgent, taskLog *AgentTaskLog)
+
+// agentMessage 任务通道上的消息信封
+type agentMessage struct {
+	Type    string `json:"type"`
+	AgentID string `json:"agent_id,omitempty"`
+	Payload string `json:"payload"`
+}
+
+type agentService struct {
+	agentRepo      repository.AgentRepository
+	serverRepo     repository.ServerRepository
+	webhooks       WebhookService
+	rdb            *redis.Client
+	taskKey        string
+	resultsChannel string
+
+	mu          sync.RWMutex
+	handlers    map[string][]TaskResultHandler
+	logHandlers map[string][]TaskLogHandler
+	ciphers     map[string]*utils.Encryptor
+}
+
+// NewAgentService taskKey 为主任务密钥，只保存在 api-service，Agent 配置由它派生的密钥(见 AgentTaskKey)
+func NewAgentService(agentRepo repository.AgentRepository, serverRepo repository.ServerRepository, webhooks WebhookService,
+	rdb *redis.Client, taskKey string, resultsChannel string) AgentService {
+	return &agentService{
+		agentRepo:      agentRepo,
+		serverRepo:     serverRepo,
+		webhooks:       webhooks,
+		rdb:            rdb,
+		taskKey:        taskKey,
+		resultsChannel: resultsChannel,
+		handlers:       make(map[string][]TaskResultHandler),
+		logHandlers:    make(map[string][]TaskLogHandler),
+		ciphers:        make(map[string]*utils.Encryptor),
+	}
+}
+
+// AgentTaskKey 由主任务密钥派生指定 Agent 的任务密钥，用作 Agent 配置中的 security.task_key
+func AgentTaskKey(taskKey, agentID string) string {
+	return utils.DeriveKey(taskKey, constants.AgentTaskKeyLabel+agentID)
+}
+
+// DispatchTask 加密任务并发布到服务器对应 Agent 的任务频道
+func (s *agentService) DispatchTask(serverID uint, task *AgentTask) error {
+	err := s.dispatchTask(serverID, task)
+	telemetry.CountTaskDispatch(task.Type, err)
+	return err
+}
+
+func (s *agentService) dispatchTask(serverID uint, task *AgentTask) error {
+	agent, err := s.agentRepo.GetByServerID(serverID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("no agent registered for server %d", serverID)
+		}
+		return err
+	}
+
+	if task.ID == "" {
+		task.ID = uuid.NewString()
+	}
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	cipher, err := s.cipher(agent.AgentID)
+	if err != nil {
+		return err
+	}
+	payload, err := cipher.Encrypt(string(data))
+	if err != nil {
+		return err
+	}
+
+	message, err := json.Marshal(&agentMessage{
+		Type:    constants.AgentMessageTask,
+		Payload: payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	// 订阅数为 0 说明 Agent 未连接，任务不会被执行
+	channel := constants.AgentTaskChannelPrefix + agent.AgentID
+	receivers, err := s.rdb.Publish(context.Background(), channel, message).Result()
+	if err != nil {
+		return err
+	}
+	if receivers == 0 {
+		return fmt.Errorf("agent %s of server %d is not connected", agent.AgentID, serverID)
+	}
+	return nil
+}
+
+// OnTaskResult 注册指定任务类型的结果处理函数
+func (s *agentService) OnTaskResult(taskType string, handler TaskResultHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[taskType] = append(s.handlers[taskType], handler)
+}
+
+// OnTaskLog 注册指定任务类型的实时日志处理函数
+func (s *agentService) OnTaskLog(taskType string, handler TaskLogHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logHandlers[taskType] = append(s.logHandlers[taskType], handler)
+}
+
+// DecodeMessage 使用 agentID 的任务密钥解密载荷，解密成功即证明发送方是该 Agent
+func (s *agentService) DecodeMessage(agentID, payload string, v interface{}) error {
+	if agentID == "" {
+		return errors.New("agent id is required")
+	}
+	cipher, err := s.cipher(agentID)
+	if err != nil {
+		return err
+	}
+	data, err := cipher.Decrypt(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), v)
+}
+
+// cipher 返回 Agent 任务密钥的加密器，按 Agent ID 缓存
+func (s *agentService) cipher(agentID string) (*utils.Encryptor, error) {
+	s.mu.RLock()
+	cipher, ok := s.ciphers[agentID]
+	s.mu.RUnlock()
+	if ok {
+		return cipher, nil
+	}
+
+	cipher, err := utils.NewEncryptor(AgentTaskKey(s.taskKey, agentID))
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.ciphers[agentID] = cipher
+	s.mu.Unlock()
+	return cipher, nil
+}
+
+// Heartbeat 校验发送时间并更新 Agent 与服务器的心跳时间，服务器由其他状态恢复为运行中时发送 server.online 事件
+func (s *agentService) Heartbeat(heartbeat *AgentHeartbeat) (*model.ServerAgent, error) {
+	now := time.Now()
+	if skew := now.Sub(heartbeat.SentAt); skew > constants.AgentMessageMaxSkew || skew < -constants.AgentMessageMaxSkew {
+		return nil, fmt.Errorf("message time %s is outside the allowed skew", heartbeat.SentAt.Format(time.RFC3339))
+	}
+
+	agent, err := s.agentRepo.GetByAgentID(heartbeat.AgentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := s.agentRepo.UpdateHeartbeat(agent, now); err != nil {
+		return nil, err
+	}
+
+	online, err := s.serverRepo.MarkOnline(agent.ServerID)
+	if err != nil {
+		log.Printf("Failed to update status of server %d: %v", agent.ServerID, err)
+	} else if online {
+		s.emitPresence(agent.ServerID, constants.WebhookEventServerOnline, agent.AgentID)
+	}
+	return agent, nil
+}
+
+// CountAgents 统计已注册的 Agent 与在线窗口内有心跳的 Agent
+func (s *agentService) CountAgents() (registered, connected int64, err error) {
+	return s.agentRepo.Count(time.Now().Add(-constants.AgentConnectedWindow))
+}
+
+// Start 订阅结果频道并定期检查离线的服务器，直到 ctx 结束
+func (s *agentService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(constants.ServerPresenceInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.checkPresence()
+			}
+		}
+	}()
+
+	go func() {
+		pubsub := s.rdb.Subscribe(ctx, s.resultsChannel)
+		defer pubsub.Close()
+
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				s.handleMessage(msg.Payload)
+			}
+		}
+	}()
+}
+
+// checkPresence 将超过在线窗口没有心跳的运行中服务器标记为离线并发送 server.offline 事件
+func (s *agentService) checkPresence() {
+	before := time.Now().Add(-constants.AgentConnectedWindow)
+	servers, err := s.serverRepo.ListStale(before)
+	if err != nil {
+		log.Printf("Failed to list stale servers: %v", err)
+		return
+	}
+	for _, server := range servers {
+		offline, err := s.serverRepo.MarkOffline(server.ID, before)
+		if err != nil {
+			log.Printf("Failed to update status of server %d: %v", server.ID, err)
+			continue
+		}
+		if offline {
+			server.Status = constants.ServerStatusOffline
+			s.webhooks.Emit(server.OwnerID, constants.WebhookEventServerOffline, serverEventData(server, ""))
+		}
+	}
+}
+
+func (s *agentService) emitPresence(serverID uint, event, agentID string) {
+	server, err := s.serverRepo.GetByID(serverID)
+	if err != nil {
+		log.Printf("Failed to load server %d: %v", serverID, err)
+		return
+	}
+	s.webhooks.Emit(server.OwnerID, event, serverEventData(server, agentID))
+}
+
+// serverEventData server.online 与 server.offline 事件的数据
+func serverEventData(server *model.Server, agentID string) map[string]interface{} {
+	data := map[string]interface{}{
+		"server_id":         server.ID,
+		"name":              server.Name,
+		"hostname":          server.Hostname,
+		"ip_address":        server.IPAddress,
+		"status":            server.Status,
+		"last_heartbeat_at": server.LastHeartbeatAt,
+	}
+	if agentID != "" {
+		data["agent_id"] = agentID
+	}
+	return data
+}
+
+func (s *agentService) handleMessage(raw string) {
+	var message agentMessage
+	if err := json.Unmarshal([]byte(raw), &message); err != nil {
+		log.Printf("Invalid agent message: %v", err)
+		return
+	}
+	if message.Type != constants.AgentMessageTaskResult && message.Type != constants.AgentMessageTaskLog {
+		return
+	}
+
+	// 信封中的 Agent ID 只用于选择密钥，载荷能以该 Agent 的密钥解密才视为来自该 Agent
+	if message.Type == constants.AgentMessageTaskLog {
+		var taskLog AgentTaskLog
+		if err := s.DecodeMessage(message.AgentID, message.Payload, &taskLog); err != nil {
+			log.Printf("Rejected %s claiming to be from agent %q: %v", message.Type, message.AgentID, err)
+			return
+		}
+		if agent := s.messageAgent(message.AgentID); agent != nil {
+			s.handleTaskLog(agent, &taskLog)
+		}
+		return
+	}
+
+	var result AgentTaskResult
+	if err := s.DecodeMessage(message.AgentID, message.Payload, &result); err != nil {
+		log.Printf("Rejected %s claiming to be from agent %q: %v", message.Type, message.AgentID, err)
+		return
+	}
+	agent := s.messageAgent(message.AgentID)
+	if agent == nil {
+		return
+	}
+
+	s.mu.RLock()
+	handlers := s.handlers[result.Type]
+	s.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(agent, &result)
+	}
+}
+
+// messageAgent 获取发送消息的 Agent，未注册时返回 nil
+func (s *agentService) messageAgent(agentID string) *model.ServerAgent {
+	agent, err := s.agentRepo.GetByAgentID(agentID)
+	if err != nil {
+		log.Printf("Ignored message from unregistered agent %s: %v", agentID, err)
+		return nil
+	}
+	return agent
+}
+
+func (s *agentService) handleTaskLog(agent *model.ServerAgent, taskLog *AgentTaskLog) {
+	if len(taskLog.Lines) == 0 {
+		return
+	}
+	if len(taskLog.Lines) > constants.MaxAgentTaskLogLines {
+		taskLog.Lines = taskLog.Lines[:constants.MaxAgentTaskLogLines]
+	}
+
+	s.mu.RLock()
+	handlers := s.logHandlers[taskLog.Type]
+	s.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(agent, taskLog)
+	}
+}
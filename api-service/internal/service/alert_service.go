@@ -0,0 +1,1110 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/alertexpr"
+	"api-service/pkg/anomaly"
+	"api-service/pkg/metricstore"
+	"api-service/pkg/schedule"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AlertService 管理阈值与异常检测告警规则，并定期按指标存储评估已启用的规则
+// 条件满足并持续 for 指定的时长后生成告警记录，告警期间不重复生成，条件不再满足时自动恢复
+// 异常检测规则的基线随评估持续学习并持久化，重启后继续使用
+// 静默或维护窗口内触发的告警照常记录，但标记为已抑制
+type AlertService interface {
+	CreateRule(ownerID uint, input *AlertRuleInput) (*model.AlertRule, error)
+	GetRule(userID, id uint) (*model.AlertRule, error)
+	ListRules(userID uint) ([]*model.AlertRule, error)
+	UpdateRule(userID, id uint, input *AlertRuleInput) (*model.AlertRule, error)
+	DeleteRule(userID, id uint) error
+	PreviewRule(ownerID uint, input *AlertRuleInput, days int) (*AnomalyPreview, error)
+
+	ListRecords(userID uint, query *AlertRecordQuery, page, pageSize int) ([]*model.AlertRecord, int64, error)
+	GetRecord(userID, id uint) (*model.AlertRecord, error)
+	AcknowledgeRecord(userID, id uint, note string) (*model.AlertRecord, error)
+	ResolveRecord(userID, id uint, note string) (*model.AlertRecord, error)
+
+	CreateSilence(ownerID uint, input *SilenceInput) (*model.AlertSilence, error)
+	ListSilences(userID uint) ([]*model.AlertSilence, error)
+	DeleteSilence(userID, id uint) error
+
+	CreateMaintenanceWindow(ownerID uint, input *MaintenanceWindowInput) (*model.MaintenanceWindow, error)
+	ListMaintenanceWindows(userID uint) ([]*model.MaintenanceWindow, error)
+	UpdateMaintenanceWindow(userID, id uint, input *MaintenanceWindowInput) (*model.MaintenanceWindow, error)
+	DeleteMaintenanceWindow(userID, id uint) error
+
+	Suppression(rule *model.AlertRule, at time.Time) (string, error)
+	Notify(rule *model.AlertRule, record *model.AlertRecord) error
+	Start(ctx context.Context)
+}
+
+// AlertRuleInput 规则参数，ConditionExpression 语法见 alertexpr 包
+// RuleType 为 THRESHOLD 或 ANOMALY，为空时新建规则使用 THRESHOLD，更新规则保持原类型
+type AlertRuleInput struct {
+	Name                 string
+	RuleType             string
+	TargetType           string
+	TargetID             uint
+	ConditionExpression  string
+	NotificationChannels []string
+	Enabled              bool
+}
+
+// AnomalyPreview 异常检测规则在 [From, To) 内的回测结果
+type AnomalyPreview struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Episodes []anomaly.Episode `json:"episodes"`
+}
+
+// AlertRecordQuery 告警记录查询条件
+type AlertRecordQuery struct {
+	Status      string
+	AlertRuleID uint
+	Suppressed  *bool
+	Start       time.Time
+	End         time.Time
+}
+
+// SilenceInput 静默参数，Matchers 的键为 constants.AlertSilenceLabels 中的标签，StartsAt 为零值时立即生效
+type SilenceInput struct {
+	Matchers map[string]string
+	Comment  string
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+// MaintenanceWindowInput 维护窗口参数，ServerID 与 ResourceGroupID 二选一
+type MaintenanceWindowInput struct {
+	Name            string
+	ServerID        *uint
+	ResourceGroupID *uint
+	Weekdays        string
+	StartTime       string
+	DurationMinutes int
+	Timezone        string
+	Enabled         bool
+	Description     string
+}
+
+// alertTarget 规则评估的指标序列
+type alertTarget struct {
+	measurement string
+	tag         string
+	field       string
+}
+
+type alertService struct {
+	alertRepo     repository.AlertRepository
+	serverRepo    repository.ServerRepository
+	appRepo       repository.ApplicationRepository
+	store         metricstore.Store
+	notifications NotificationService
+	webhooks      WebhookService
+}
+
+func NewAlertService(alertRepo repository.AlertRepository, serverRepo repository.ServerRepository,
+	appRepo repository.ApplicationRepository, store metricstore.Store, notifications NotificationService,
+	webhooks WebhookService) AlertService {
+	return &alertService{
+		alertRepo:     alertRepo,
+		serverRepo:    serverRepo,
+		appRepo:       appRepo,
+		store:         store,
+		notifications: notifications,
+		webhooks:      webhooks,
+	}
+}
+
+func (s *alertService) CreateRule(ownerID uint, input *AlertRuleInput) (*model.AlertRule, error) {
+	rule := &model.AlertRule{
+		RuleType: constants.AlertRuleTypeThreshold,
+		OwnerID:  ownerID,
+	}
+	if err := s.applyInput(rule, input); err != nil {
+		return nil, err
+	}
+	if err := s.alertRepo.CreateRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *alertService) GetRule(userID, id uint) (*model.AlertRule, error) {
+	return s.getOwned(userID, id)
+}
+
+func (s *alertService) ListRules(userID uint) ([]*model.AlertRule, error) {
+	return s.alertRepo.ListRules(userID)
+}
+
+// UpdateRule 更新规则，停用时恢复该规则未恢复的告警
+func (s *alertService) UpdateRule(userID, id uint, input *AlertRuleInput) (*model.AlertRule, error) {
+	rule, err := s.getOwnedMetricRule(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyInput(rule, input); err != nil {
+		return nil, err
+	}
+	if err := s.alertRepo.UpdateRule(rule); err != nil {
+		return nil, err
+	}
+
+	s.clearPending(rule)
+	if rule.IsEnabled == 0 {
+		s.resolveAlerts(rule, "alert rule disabled")
+	}
+	return rule, nil
+}
+
+func (s *alertService) DeleteRule(userID, id uint) error {
+	rule, err := s.getOwnedMetricRule(userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.alertRepo.DeleteRule(rule.ID); err != nil {
+		return err
+	}
+
+	s.resolveAlerts(rule, "alert rule deleted")
+	if err := s.alertRepo.DeleteBaseline(rule.ID); err != nil {
+		log.Printf("Failed to delete baseline for alert rule %d: %v", rule.ID, err)
+	}
+	return nil
+}
+
+// PreviewRule 用最近 days 天的指标回测异常检测规则，基线先以回测开始前的历史数据学习，不影响已保存的基线
+func (s *alertService) PreviewRule(ownerID uint, input *AlertRuleInput, days int) (*AnomalyPreview, error) {
+	if days == 0 {
+		days = constants.DefaultAnomalyPreviewDays
+	}
+	if days < 0 || days > constants.MaxAnomalyPreviewDays {
+		return nil, invalidInputf("days must be between 1 and %d", constants.MaxAnomalyPreviewDays)
+	}
+	if input.RuleType != "" && input.RuleType != constants.AlertRuleTypeAnomaly {
+		return nil, invalidInputf("only anomaly rules can be previewed")
+	}
+
+	// 预览不保存规则，名称可省略
+	rule := &model.AlertRule{RuleType: constants.AlertRuleTypeAnomaly, OwnerID: ownerID}
+	preview := *input
+	if preview.Name == "" {
+		preview.Name = "preview"
+	}
+	if err := s.applyInput(rule, &preview); err != nil {
+		return nil, err
+	}
+	expr, target, err := parseAlertRule(rule)
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := anomaly.New(expr.Func)
+	if err != nil {
+		return nil, err
+	}
+
+	to := time.Now().Truncate(constants.AlertEvaluationInterval)
+	from := to.AddDate(0, 0, -days)
+	trainStep := baseline.Step()
+	trainStart := from.Add(-expr.Window).Truncate(trainStep).Add(-constants.AnomalyTrainingSteps * trainStep)
+
+	training, err := s.queryPoints(rule, target, trainStart, to.Truncate(trainStep), trainStep)
+	if err != nil {
+		return nil, err
+	}
+	values, err := s.queryPoints(rule, target, from.Add(-expr.Window), to, evaluationStep(expr))
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := anomaly.Backtest(baseline, training, values, from, to, constants.AlertEvaluationInterval,
+		expr.Window, expr.For, func(score float64) bool { return expr.Holds(math.Abs(score)) })
+	if episodes == nil {
+		episodes = []anomaly.Episode{}
+	}
+	return &AnomalyPreview{From: from, To: to, Episodes: episodes}, nil
+}
+
+func (s *alertService) ListRecords(userID uint, query *AlertRecordQuery, page, pageSize int) ([]*model.AlertRecord, int64, error) {
+	offset := (page - 1) * pageSize
+	return s.alertRepo.ListRecords(&repository.AlertRecordFilter{
+		OwnerID:     userID,
+		Status:      query.Status,
+		AlertRuleID: query.AlertRuleID,
+		Suppressed:  query.Suppressed,
+		Start:       query.Start,
+		End:         query.End,
+	}, offset, pageSize)
+}
+
+func (s *alertService) GetRecord(userID, id uint) (*model.AlertRecord, error) {
+	record, err := s.alertRepo.GetRecord(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if record.AlertRule.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return record, nil
+}
+
+// AcknowledgeRecord 确认告警中的记录，确认后仍在条件不再满足时自动恢复
+func (s *alertService) AcknowledgeRecord(userID, id uint, note string) (*model.AlertRecord, error) {
+	record, err := s.GetRecord(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != constants.AlertStatusFiring {
+		return nil, invalidInputf("only firing alerts can be acknowledged, alert is %s", record.Status)
+	}
+
+	now := time.Now()
+	record.Status = constants.AlertStatusAcknowledged
+	record.AcknowledgedAt = &now
+	record.AcknowledgedBy = &userID
+	if note != "" {
+		record.ResolutionNote = note
+	}
+	if err := s.alertRepo.UpdateRecord(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ResolveRecord 手动恢复告警，规则条件仍满足时将在持续 for 时长后重新触发
+func (s *alertService) ResolveRecord(userID, id uint, note string) (*model.AlertRecord, error) {
+	record, err := s.GetRecord(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status == constants.AlertStatusResolved {
+		return nil, invalidInputf("alert is already resolved")
+	}
+
+	now := time.Now()
+	record.Status = constants.AlertStatusResolved
+	record.ResolvedAt = &now
+	if note != "" {
+		record.ResolutionNote = note
+	}
+	if err := s.alertRepo.UpdateRecord(record); err != nil {
+		return nil, err
+	}
+	resolution := record.ResolutionNote
+	if resolution == "" {
+		resolution = "resolved manually"
+	}
+	s.notifyResolved(&record.AlertRule, record, resolution)
+	return record, nil
+}
+
+func (s *alertService) CreateSilence(ownerID uint, input *SilenceInput) (*model.AlertSilence, error) {
+	if len(input.Matchers) == 0 {
+		return nil, invalidInputf("at least one matcher is required")
+	}
+	for label := range input.Matchers {
+		if !isSilenceLabel(label) {
+			return nil, invalidInputf("unknown label %q, expected one of %s", label,
+				strings.Join(constants.AlertSilenceLabels, ", "))
+		}
+	}
+
+	now := time.Now()
+	startsAt := input.StartsAt
+	if startsAt.IsZero() {
+		startsAt = now
+	}
+	if !input.EndsAt.After(startsAt) || !input.EndsAt.After(now) {
+		return nil, invalidInputf("ends_at must be after starts_at and in the future")
+	}
+
+	matchers, err := json.Marshal(input.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	silence := &model.AlertSilence{
+		Matchers: string(matchers),
+		Comment:  input.Comment,
+		StartsAt: startsAt,
+		EndsAt:   input.EndsAt,
+		OwnerID:  ownerID,
+	}
+	if err := s.alertRepo.CreateSilence(silence); err != nil {
+		return nil, err
+	}
+	return silence, nil
+}
+
+func (s *alertService) ListSilences(userID uint) ([]*model.AlertSilence, error) {
+	return s.alertRepo.ListSilences(userID)
+}
+
+func (s *alertService) DeleteSilence(userID, id uint) error {
+	silence, err := s.alertRepo.GetSilence(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if silence.OwnerID != userID {
+		return ErrPermissionDenied
+	}
+	return s.alertRepo.DeleteSilence(id)
+}
+
+func (s *alertService) CreateMaintenanceWindow(ownerID uint, input *MaintenanceWindowInput) (*model.MaintenanceWindow, error) {
+	window := &model.MaintenanceWindow{OwnerID: ownerID}
+	if err := s.applyMaintenanceInput(window, input); err != nil {
+		return nil, err
+	}
+	if err := s.alertRepo.CreateMaintenanceWindow(window); err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+func (s *alertService) ListMaintenanceWindows(userID uint) ([]*model.MaintenanceWindow, error) {
+	return s.alertRepo.ListMaintenanceWindows(userID)
+}
+
+func (s *alertService) UpdateMaintenanceWindow(userID, id uint, input *MaintenanceWindowInput) (*model.MaintenanceWindow, error) {
+	window, err := s.getOwnedMaintenanceWindow(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyMaintenanceInput(window, input); err != nil {
+		return nil, err
+	}
+	if err := s.alertRepo.UpdateMaintenanceWindow(window); err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+func (s *alertService) DeleteMaintenanceWindow(userID, id uint) error {
+	window, err := s.getOwnedMaintenanceWindow(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.alertRepo.DeleteMaintenanceWindow(window.ID)
+}
+
+// Suppression 返回规则在 at 时刻触发的告警被抑制的原因，如 silence:3 或 maintenance:2，未被抑制时返回空
+func (s *alertService) Suppression(rule *model.AlertRule, at time.Time) (string, error) {
+	silences, err := s.alertRepo.ListActiveSilences(rule.OwnerID, at)
+	if err != nil {
+		return "", err
+	}
+	labels := alertLabels(rule)
+	for _, silence := range silences {
+		var matchers map[string]string
+		if err := json.Unmarshal([]byte(silence.Matchers), &matchers); err != nil {
+			log.Printf("Invalid matchers in alert silence %d: %v", silence.ID, err)
+			continue
+		}
+		if matchesLabels(matchers, labels) {
+			return fmt.Sprintf("silence:%d", silence.ID), nil
+		}
+	}
+
+	server, err := s.alertServer(rule)
+	if err != nil || server == nil {
+		return "", err
+	}
+	windows, err := s.alertRepo.ListMaintenanceWindows(rule.OwnerID)
+	if err != nil {
+		return "", err
+	}
+	for _, window := range windows {
+		if window.IsEnabled == 0 || !coversServer(window, server) {
+			continue
+		}
+		weekly, err := schedule.NewWeekly(window.Weekdays, window.StartTime,
+			time.Duration(window.DurationMinutes)*time.Minute, window.Timezone)
+		if err != nil {
+			log.Printf("Invalid maintenance window %d: %v", window.ID, err)
+			continue
+		}
+		if weekly.Active(at) {
+			return fmt.Sprintf("maintenance:%d", window.ID), nil
+		}
+	}
+	return "", nil
+}
+
+// alertServer 获取规则目标所在的服务器，目标不是服务器或应用时返回 nil
+func (s *alertService) alertServer(rule *model.AlertRule) (*model.Server, error) {
+	if rule.TargetID == nil {
+		return nil, nil
+	}
+	switch rule.TargetType {
+	case constants.AlertTargetServer:
+		server, err := s.serverRepo.GetByID(*rule.TargetID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return server, err
+	case constants.AlertTargetApplication:
+		app, err := s.appRepo.GetByID(*rule.TargetID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &app.Server, nil
+	}
+	return nil, nil
+}
+
+// applyMaintenanceInput 校验参数及服务器或资源组归属并写入维护窗口
+func (s *alertService) applyMaintenanceInput(window *model.MaintenanceWindow, input *MaintenanceWindowInput) error {
+	if input.Name == "" {
+		return invalidInputf("name is required")
+	}
+	if (input.ServerID == nil) == (input.ResourceGroupID == nil) {
+		return invalidInputf("exactly one of server_id and resource_group_id is required")
+	}
+
+	if input.ServerID != nil {
+		server, err := s.serverRepo.GetByID(*input.ServerID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return invalidInputf("server %d not found", *input.ServerID)
+			}
+			return err
+		}
+		if server.OwnerID != window.OwnerID {
+			return ErrPermissionDenied
+		}
+	} else {
+		group, err := s.serverRepo.GetResourceGroup(*input.ResourceGroupID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return invalidInputf("resource group %d not found", *input.ResourceGroupID)
+			}
+			return err
+		}
+		if group.OwnerID != window.OwnerID {
+			return ErrPermissionDenied
+		}
+	}
+
+	timezone := input.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	weekly, err := schedule.NewWeekly(input.Weekdays, input.StartTime,
+		time.Duration(input.DurationMinutes)*time.Minute, timezone)
+	if err != nil {
+		return err
+	}
+
+	days := make([]string, len(weekly.Weekdays))
+	for i, d := range weekly.Weekdays {
+		days[i] = strconv.Itoa(int(d))
+	}
+
+	window.Name = input.Name
+	window.ServerID = input.ServerID
+	window.ResourceGroupID = input.ResourceGroupID
+	window.Weekdays = strings.Join(days, ",")
+	window.StartTime = fmt.Sprintf("%02d:%02d", weekly.Hour, weekly.Minute)
+	window.DurationMinutes = input.DurationMinutes
+	window.Timezone = timezone
+	window.Description = input.Description
+	window.IsEnabled = 0
+	if input.Enabled {
+		window.IsEnabled = 1
+	}
+	return nil
+}
+
+func (s *alertService) getOwnedMaintenanceWindow(userID, id uint) (*model.MaintenanceWindow, error) {
+	window, err := s.alertRepo.GetMaintenanceWindow(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if window.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return window, nil
+}
+
+// Start 按评估间隔评估已启用的阈值与异常检测规则，多个实例同时运行时每条规则每轮只由一个实例评估
+func (s *alertService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(constants.AlertEvaluationInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.evaluate(now)
+			}
+		}
+	}()
+}
+
+func (s *alertService) evaluate(now time.Time) {
+	rules, err := s.alertRepo.ListEnabledRules(constants.AlertRuleTypeThreshold, constants.AlertRuleTypeAnomaly)
+	if err != nil {
+		log.Printf("Failed to list alert rules: %v", err)
+		return
+	}
+
+	// 上次评估距今不足半个间隔的规则已由其他实例评估
+	since := now.Add(-constants.AlertEvaluationInterval / 2)
+	for _, rule := range rules {
+		claimed, err := s.alertRepo.ClaimEvaluation(rule.ID, now, since)
+		if err != nil {
+			log.Printf("Failed to claim evaluation of alert rule %d: %v", rule.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if err := s.evaluateRule(rule, now); err != nil {
+			log.Printf("Failed to evaluate alert rule %d: %v", rule.ID, err)
+		}
+	}
+}
+
+func (s *alertService) evaluateRule(rule *model.AlertRule, now time.Time) error {
+	expr, target, err := parseAlertRule(rule)
+	if err != nil {
+		return err
+	}
+
+	value, ok, err := s.queryValue(rule, expr, target, now)
+	if err != nil {
+		return err
+	}
+
+	var holds bool
+	var detail string
+	if expr.Anomaly() {
+		if holds, detail, err = s.checkAnomaly(rule, expr, target, value, ok, now); err != nil {
+			return err
+		}
+	} else {
+		holds = expr.Holds(value)
+		detail = fmt.Sprintf("%s(%s) = %s", expr.Func, expr.Metric, strconv.FormatFloat(value, 'f', 2, 64))
+	}
+	// 窗口内没有数据(如 Agent 失联)时保持原状态，既不触发也不恢复
+	if !ok {
+		return nil
+	}
+
+	prefix := alertRulePrefix(rule)
+	open, err := s.alertRepo.GetOpenRecord(prefix)
+	if err != nil {
+		return err
+	}
+
+	if !holds {
+		s.clearPending(rule)
+		if open != nil {
+			if err := s.alertRepo.ResolveRecords(prefix, "condition cleared"); err != nil {
+				return err
+			}
+			s.notifyResolved(rule, open, "condition cleared")
+		}
+		return nil
+	}
+	if open != nil {
+		return nil
+	}
+
+	if rule.PendingSince == nil {
+		if err := s.alertRepo.SetPending(rule.ID, &now); err != nil {
+			return err
+		}
+		rule.PendingSince = &now
+	}
+	if now.Sub(*rule.PendingSince) < expr.For {
+		return nil
+	}
+	s.clearPending(rule)
+
+	suppressedBy, err := s.Suppression(rule, now)
+	if err != nil {
+		return err
+	}
+
+	targetName := strings.ToLower(rule.TargetType)
+	record := &model.AlertRecord{
+		AlertRuleID: rule.ID,
+		AlertID:     prefix + strconv.FormatInt(now.Unix(), 10),
+		Title:       fmt.Sprintf("%s on %s %d", rule.Name, targetName, *rule.TargetID),
+		Description: fmt.Sprintf("Condition %q is met for %s %d: %s.", expr.String(), targetName,
+			*rule.TargetID, detail),
+		Status:               constants.AlertStatusFiring,
+		FiredAt:              now,
+		NotificationChannels: rule.NotificationChannels,
+		SuppressedBy:         suppressedBy,
+	}
+	if suppressedBy != "" {
+		record.Suppressed = 1
+	}
+	if err := s.alertRepo.CreateRecord(record); err != nil {
+		return err
+	}
+	if err := s.Notify(rule, record); err != nil {
+		log.Printf("Failed to send notification for alert %s: %v", record.AlertID, err)
+	}
+	return nil
+}
+
+// Notify 通过规则的通知渠道与站内通知告知规则所有者告警已触发，静默或维护窗口内的告警不通知
+func (s *alertService) Notify(rule *model.AlertRule, record *model.AlertRecord) error {
+	if record.Suppressed == 1 {
+		return nil
+	}
+	s.webhooks.Emit(rule.OwnerID, constants.WebhookEventAlertFiring, alertEventData(rule, record))
+
+	_, err := s.notifications.Send(&NotificationRequest{
+		Kind:       constants.NotificationKindAlert,
+		Level:      constants.NotificationLevelWarning,
+		TargetType: constants.NotificationTargetUser,
+		TargetIDs:  []uint{rule.OwnerID},
+		Channels:   alertChannels(record),
+		Template:   constants.NotificationTemplateAlertFiring,
+		Variables: map[string]interface{}{
+			"alert_id":    record.AlertID,
+			"title":       record.Title,
+			"description": record.Description,
+			"rule":        rule.Name,
+			"fired_at":    record.FiredAt,
+		},
+		ReferenceType: constants.NotificationRefAlert,
+		ReferenceID:   record.AlertID,
+	})
+	if err != nil {
+		return err
+	}
+	record.NotificationSent = 1
+	return s.alertRepo.UpdateRecord(record)
+}
+
+// notifyResolved 已发送过触发通知的告警恢复时发送恢复通知，未被静默的告警发送 alert.resolved 事件
+// 每条告警只通知一次，手动恢复与条件不再满足时的自动恢复不会重复通知
+func (s *alertService) notifyResolved(rule *model.AlertRule, record *model.AlertRecord, note string) {
+	claimed, err := s.alertRepo.ClaimResolvedNotice(record.ID)
+	if err != nil {
+		log.Printf("Failed to mark resolved notification for alert %s: %v", record.AlertID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	if record.Suppressed != 1 {
+		data := alertEventData(rule, record)
+		data["note"] = note
+		s.webhooks.Emit(rule.OwnerID, constants.WebhookEventAlertResolved, data)
+	}
+	if record.NotificationSent != 1 {
+		return
+	}
+	_, err = s.notifications.Send(&NotificationRequest{
+		Kind:       constants.NotificationKindAlert,
+		Level:      constants.NotificationLevelInfo,
+		TargetType: constants.NotificationTargetUser,
+		TargetIDs:  []uint{rule.OwnerID},
+		Channels:   alertChannels(record),
+		Template:   constants.NotificationTemplateAlertResolved,
+		Variables: map[string]interface{}{
+			"alert_id": record.AlertID,
+			"title":    record.Title,
+			"rule":     rule.Name,
+			"note":     note,
+			"fired_at": record.FiredAt,
+		},
+		ReferenceType: constants.NotificationRefAlert,
+		ReferenceID:   record.AlertID,
+	})
+	if err != nil {
+		log.Printf("Failed to send resolved notification for alert %s: %v", record.AlertID, err)
+	}
+}
+
+// alertEventData alert.firing 与 alert.resolved 事件的数据
+func alertEventData(rule *model.AlertRule, record *model.AlertRecord) map[string]interface{} {
+	return map[string]interface{}{
+		"alert_id":    record.AlertID,
+		"rule_id":     rule.ID,
+		"rule":        rule.Name,
+		"target_type": rule.TargetType,
+		"target_id":   rule.TargetID,
+		"metric_name": rule.MetricName,
+		"title":       record.Title,
+		"description": record.Description,
+		"status":      record.Status,
+		"fired_at":    record.FiredAt,
+		"resolved_at": record.ResolvedAt,
+	}
+}
+
+// checkAnomaly 更新规则的基线并判断窗口平均值的偏离程度是否满足条件，基线样本不足时不满足
+func (s *alertService) checkAnomaly(rule *model.AlertRule, expr *alertexpr.Expr, target *alertTarget,
+	value float64, ok bool, now time.Time) (bool, string, error) {
+	baseline, err := s.trainBaseline(rule, expr, target, now)
+	if err != nil || !ok {
+		return false, "", err
+	}
+	center, scale, ready := baseline.Expect(now)
+	if !ready {
+		return false, "", nil
+	}
+
+	score := (value - center) / scale
+	detail := fmt.Sprintf("avg(%s) = %s deviates %s from the %s baseline %s", expr.Metric,
+		strconv.FormatFloat(value, 'f', 2, 64), strconv.FormatFloat(score, 'f', 2, 64), expr.Func,
+		strconv.FormatFloat(center, 'f', 2, 64))
+	return expr.Holds(math.Abs(score)), detail, nil
+}
+
+// trainBaseline 加载规则的基线并学习截至 now 减去窗口的新样本，评估窗口内的数据不参与学习
+// 没有基线或指标序列、基线方法变化时重新创建，并回溯学习最多 AnomalyTrainingSteps 步的历史数据
+func (s *alertService) trainBaseline(rule *model.AlertRule, expr *alertexpr.Expr, target *alertTarget,
+	now time.Time) (anomaly.Baseline, error) {
+	record, err := s.alertRepo.GetBaseline(rule.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s:%d:%s", target.measurement, *rule.TargetID, target.field)
+	var baseline anomaly.Baseline
+	if record != nil && record.SeriesKey == key && record.Method == expr.Func {
+		if baseline, err = anomaly.Unmarshal(record.Method, []byte(record.State)); err != nil {
+			log.Printf("Discarding baseline of alert rule %d: %v", rule.ID, err)
+		}
+	}
+	if record == nil {
+		record = &model.AlertBaseline{AlertRuleID: rule.ID}
+	}
+	if baseline == nil {
+		if baseline, err = anomaly.New(expr.Func); err != nil {
+			return nil, err
+		}
+		record.SeriesKey, record.Method, record.TrainedUntil = key, expr.Func, time.Time{}
+	}
+
+	step := baseline.Step()
+	end := now.Add(-expr.Window).Truncate(step)
+	start := end.Add(-constants.AnomalyTrainingSteps * step)
+	if record.TrainedUntil.After(start) {
+		start = record.TrainedUntil
+	}
+	if !end.After(start) {
+		return baseline, nil
+	}
+
+	points, err := s.queryPoints(rule, target, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range points {
+		baseline.Observe(p.Time, p.Value)
+	}
+
+	state, err := json.Marshal(baseline)
+	if err != nil {
+		return nil, err
+	}
+	record.State = string(state)
+	record.TrainedUntil = end
+	return baseline, s.alertRepo.SaveBaseline(record)
+}
+
+// queryValue 查询规则窗口内按评估步长聚合的平均值，并按规则函数汇总，无数据时返回 false
+func (s *alertService) queryValue(rule *model.AlertRule, expr *alertexpr.Expr, target *alertTarget,
+	now time.Time) (float64, bool, error) {
+	points, err := s.queryPoints(rule, target, now.Add(-expr.Window), now, evaluationStep(expr))
+	if err != nil {
+		return 0, false, err
+	}
+
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	value, ok := expr.Reduce(values)
+	return value, ok, nil
+}
+
+// queryPoints 查询规则指标在 [start, end) 内按 step 聚合的平均值，跳过无数据的窗口
+func (s *alertService) queryPoints(rule *model.AlertRule, target *alertTarget, start, end time.Time,
+	step time.Duration) ([]anomaly.Point, error) {
+	samples, err := s.store.Aggregate(context.Background(), &metricstore.Query{
+		Measurement: target.measurement,
+		Tags:        map[string]string{target.tag: fmt.Sprint(*rule.TargetID)},
+		Fields:      []string{target.field},
+		Start:       start,
+		End:         end,
+	}, step)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]anomaly.Point, 0, len(samples))
+	for _, sample := range samples {
+		if sample.Value != nil {
+			points = append(points, anomaly.Point{Time: sample.Time, Value: *sample.Value})
+		}
+	}
+	return points, nil
+}
+
+// evaluationStep 窗口内的聚合步长，窗口小于评估步长时使用窗口长度
+func evaluationStep(expr *alertexpr.Expr) time.Duration {
+	if expr.Window < constants.AlertEvaluationStep {
+		return expr.Window
+	}
+	return constants.AlertEvaluationStep
+}
+
+// applyInput 校验参数及目标归属并写入规则
+func (s *alertService) applyInput(rule *model.AlertRule, input *AlertRuleInput) error {
+	if input.Name == "" {
+		return invalidInputf("name is required")
+	}
+	if input.TargetID == 0 {
+		return invalidInputf("target_id is required")
+	}
+
+	ruleType := input.RuleType
+	if ruleType == "" {
+		ruleType = rule.RuleType
+	}
+	if ruleType != constants.AlertRuleTypeThreshold && ruleType != constants.AlertRuleTypeAnomaly {
+		return invalidInputf("unsupported rule type: %s", ruleType)
+	}
+
+	switch input.TargetType {
+	case constants.AlertTargetServer:
+		server, err := s.serverRepo.GetByID(input.TargetID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return invalidInputf("server %d not found", input.TargetID)
+			}
+			return err
+		}
+		if server.OwnerID != rule.OwnerID {
+			return ErrPermissionDenied
+		}
+	case constants.AlertTargetApplication:
+		app, err := s.appRepo.GetByID(input.TargetID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return invalidInputf("application %d not found", input.TargetID)
+			}
+			return err
+		}
+		if app.Server.OwnerID != rule.OwnerID {
+			return ErrPermissionDenied
+		}
+	default:
+		return invalidInputf("unsupported target type: %s", input.TargetType)
+	}
+
+	channels := input.NotificationChannels
+	if channels == nil {
+		channels = []string{}
+	}
+	if err := s.notifications.ValidateChannels(channels); err != nil {
+		return err
+	}
+	channelsJSON, err := json.Marshal(channels)
+	if err != nil {
+		return err
+	}
+
+	targetID := input.TargetID
+	rule.Name = input.Name
+	rule.RuleType = ruleType
+	rule.TargetType = input.TargetType
+	rule.TargetID = &targetID
+	rule.ConditionExpression = input.ConditionExpression
+	rule.NotificationChannels = string(channelsJSON)
+	rule.IsEnabled = 0
+	if input.Enabled {
+		rule.IsEnabled = 1
+	}
+
+	_, target, err := parseAlertRule(rule)
+	if err != nil {
+		return err
+	}
+	rule.MetricName = target.field
+	return nil
+}
+
+func (s *alertService) getOwned(userID, id uint) (*model.AlertRule, error) {
+	rule, err := s.alertRepo.GetRule(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if rule.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return rule, nil
+}
+
+// getOwnedMetricRule 获取阈值或异常检测规则，系统生成的规则(如证书过期)不可修改
+func (s *alertService) getOwnedMetricRule(userID, id uint) (*model.AlertRule, error) {
+	rule, err := s.getOwned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if rule.RuleType != constants.AlertRuleTypeThreshold && rule.RuleType != constants.AlertRuleTypeAnomaly {
+		return nil, invalidInputf("%s rules cannot be modified", strings.ToLower(rule.RuleType))
+	}
+	return rule, nil
+}
+
+func (s *alertService) clearPending(rule *model.AlertRule) {
+	if rule.PendingSince == nil {
+		return
+	}
+	if err := s.alertRepo.SetPending(rule.ID, nil); err != nil {
+		log.Printf("Failed to clear pending state of alert rule %d: %v", rule.ID, err)
+		return
+	}
+	rule.PendingSince = nil
+}
+
+func (s *alertService) resolveAlerts(rule *model.AlertRule, note string) {
+	if err := s.alertRepo.ResolveRecords(alertRulePrefix(rule), note); err != nil {
+		log.Printf("Failed to resolve alerts for rule %d: %v", rule.ID, err)
+	}
+}
+
+// parseAlertRule 解析规则表达式并确定指标序列，表达式中的指标名以点或下划线分隔
+func parseAlertRule(rule *model.AlertRule) (*alertexpr.Expr, *alertTarget, error) {
+	expr, err := alertexpr.Parse(rule.ConditionExpression)
+	if err != nil {
+		return nil, nil, invalidInput(err)
+	}
+	if expr.Window > constants.MaxAlertWindow {
+		return nil, nil, invalidInputf("window must not exceed %s", constants.MaxAlertWindow)
+	}
+	if expr.For > constants.MaxAlertFor {
+		return nil, nil, invalidInputf("for must not exceed %s", constants.MaxAlertFor)
+	}
+	if anomalyRule := rule.RuleType == constants.AlertRuleTypeAnomaly; anomalyRule != expr.Anomaly() {
+		if anomalyRule {
+			return nil, nil, invalidInputf("anomaly rules must use %s or %s", alertexpr.FuncEWMA, alertexpr.FuncSeasonal)
+		}
+		return nil, nil, invalidInputf("%s is only available for anomaly rules", expr.Func)
+	}
+
+	target := &alertTarget{field: strings.ReplaceAll(expr.Metric, ".", "_")}
+	switch rule.TargetType {
+	case constants.AlertTargetServer:
+		target.measurement, target.tag = constants.MeasurementServerMetrics, constants.MetricTagServerID
+	case constants.AlertTargetApplication:
+		target.measurement, target.tag = constants.MeasurementAppMetrics, constants.MetricTagAppID
+	default:
+		return nil, nil, invalidInputf("unsupported target type: %s", rule.TargetType)
+	}
+	if rule.TargetID == nil {
+		return nil, nil, invalidInputf("rule has no target")
+	}
+
+	for _, field := range constants.AlertMetricFields[rule.TargetType] {
+		if field == target.field {
+			return expr, target, nil
+		}
+	}
+	return nil, nil, invalidInputf("unknown metric %q for %s", expr.Metric, strings.ToLower(rule.TargetType))
+}
+
+// alertRulePrefix 规则生成的告警ID前缀，每次触发追加触发时间
+func alertRulePrefix(rule *model.AlertRule) string {
+	return fmt.Sprintf("rule-%d-", rule.ID)
+}
+
+// alertChannels 告警触发时记录的通知渠道
+func alertChannels(record *model.AlertRecord) []string {
+	var channels []string
+	if record.NotificationChannels != "" {
+		if err := json.Unmarshal([]byte(record.NotificationChannels), &channels); err != nil {
+			log.Printf("Invalid notification channels of alert %s: %v", record.AlertID, err)
+		}
+	}
+	return channels
+}
+
+// alertLabels 规则触发的告警可被静默匹配的标签
+func alertLabels(rule *model.AlertRule) map[string]string {
+	labels := map[string]string{
+		constants.AlertLabelRuleID:     strconv.FormatUint(uint64(rule.ID), 10),
+		constants.AlertLabelRuleName:   rule.Name,
+		constants.AlertLabelRuleType:   rule.RuleType,
+		constants.AlertLabelTargetType: rule.TargetType,
+		constants.AlertLabelMetricName: rule.MetricName,
+	}
+	if rule.TargetID != nil {
+		labels[constants.AlertLabelTargetID] = strconv.FormatUint(uint64(*rule.TargetID), 10)
+	}
+	return labels
+}
+
+// matchesLabels 全部匹配项与标签取值相等时返回 true
+func matchesLabels(matchers, labels map[string]string) bool {
+	if len(matchers) == 0 {
+		return false
+	}
+	for label, value := range matchers {
+		if labels[label] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func isSilenceLabel(label string) bool {
+	for _, l := range constants.AlertSilenceLabels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// coversServer 维护窗口是否作用于该服务器，资源组窗口作用于组内全部服务器
+func coversServer(window *model.MaintenanceWindow, server *model.Server) bool {
+	if window.ServerID != nil {
+		return *window.ServerID == server.ID
+	}
+	return window.ResourceGroupID != nil && server.ResourceGroupID != nil &&
+		*window.ResourceGroupID == *server.ResourceGroupID
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/certutil"
+	"api-service/pkg/utils"
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CAService 管理内部 CA，签发内部证书并向 Agent 下发 CA 证书安装任务
+type CAService interface {
+	CreateCA(ownerID uint, input *CAInput) (*model.CertificateAuthority, error)
+	GetCA(userID, id uint) (*model.CertificateAuthority, error)
+	ListCAs(userID uint) ([]*model.CertificateAuthority, error)
+	DeleteCA(userID, id uint) error
+	IssueCertificate(ownerID uint, input *IssueCertificateInput) (*model.SSLCertificate, error)
+	ExportBundle(userID uint, ids []uint) ([]byte, error)
+	InstallCA(userID, id uint, serverIDs []uint) ([]string, error)
+}
+
+// CAInput 创建 CA 的参数
+type CAInput struct {
+	Name         string
+	CommonName   string
+	Organization string
+	KeyType      string
+	ValidityDays int
+	Description  string
+}
+
+// IssueCertificateInput 签发证书的参数，CAID 为空时生成自签名证书
+type IssueCertificateInput struct {
+	Name         string
+	CAID         *uint
+	CommonName   string
+	DNSNames     []string
+	IPAddresses  []string
+	KeyType      string
+	ValidityDays int
+	Usages       []string
+}
+
+// caNamePattern 限制 CA 名称，名称会作为 Agent 端信任库中的文件名
+var caNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+type caService struct {
+	caRepo       repository.CARepository
+	certRepo     repository.CertificateRepository
+	serverRepo   repository.ServerRepository
+	agentService AgentService
+	encryptor    *utils.Encryptor
+}
+
+func NewCAService(caRepo repository.CARepository, certRepo repository.CertificateRepository,
+	serverRepo repository.ServerRepository, agentService AgentService, encryptor *utils.Encryptor) CAService {
+	s := &caService{
+		caRepo:       caRepo,
+		certRepo:     certRepo,
+		serverRepo:   serverRepo,
+		agentService: agentService,
+		encryptor:    encryptor,
+	}
+	agentService.OnTaskResult(constants.AgentTaskInstallCA, s.handleInstallResult)
+	return s
+}
+
+func (s *caService) CreateCA(ownerID uint, input *CAInput) (*model.CertificateAuthority, error) {
+	if !caNamePattern.MatchString(input.Name) {
+		return nil, invalidInputf("invalid certificate authority name: %s", input.Name)
+	}
+
+	commonName := input.CommonName
+	if commonName == "" {
+		commonName = input.Name
+	}
+	keyType := input.KeyType
+	if keyType == "" {
+		keyType = constants.DefaultCAKeyType
+	}
+	validityDays := input.ValidityDays
+	if validityDays <= 0 {
+		validityDays = constants.DefaultCAValidityDays
+	}
+
+	now := time.Now()
+	cert, key, err := certutil.Issue(&certutil.Request{
+		CommonName:   commonName,
+		Organization: input.Organization,
+		KeyType:      keyType,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(0, 0, validityDays),
+		IsCA:         true,
+	}, nil, nil)
+	if err != nil {
+		return nil, invalidInput(err)
+	}
+
+	keyPEM, err := certutil.EncodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	encryptedKey, err := s.encryptor.Encrypt(string(keyPEM))
+	if err != nil {
+		return nil, err
+	}
+
+	info := certutil.Inspect(cert)
+	ca := &model.CertificateAuthority{
+		Name:            input.Name,
+		CommonName:      commonName,
+		Organization:    input.Organization,
+		KeyType:         keyType,
+		CertificateData: string(certutil.EncodeCertificates([]*x509.Certificate{cert})),
+		PrivateKeyData:  encryptedKey,
+		SerialNumber:    info.SerialNumber,
+		NotBefore:       &info.NotBefore,
+		NotAfter:        &info.NotAfter,
+		Description:     input.Description,
+		OwnerID:         ownerID,
+	}
+	if err := s.caRepo.Create(ca); err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
+
+func (s *caService) GetCA(userID, id uint) (*model.CertificateAuthority, error) {
+	return s.getOwned(userID, id)
+}
+
+func (s *caService) ListCAs(userID uint) ([]*model.CertificateAuthority, error) {
+	return s.caRepo.ListByOwner(userID)
+}
+
+// DeleteCA 删除 CA，仍有签发证书时拒绝删除
+func (s *caService) DeleteCA(userID, id uint) error {
+	ca, err := s.getOwned(userID, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := s.caRepo.CountIssued(ca.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return invalidInputf("certificate authority still has %d issued certificates", count)
+	}
+
+	return s.caRepo.Delete(ca.ID)
+}
+
+// IssueCertificate 生成密钥并签发证书，结果作为 SSL 证书保存
+func (s *caService) IssueCertificate(ownerID uint, input *IssueCertificateInput) (*model.SSLCertificate, error) {
+	commonName := input.CommonName
+	if commonName == "" && len(input.DNSNames) > 0 {
+		commonName = input.DNSNames[0]
+	}
+	if commonName == "" && len(input.IPAddresses) > 0 {
+		commonName = input.IPAddresses[0]
+	}
+	if commonName == "" {
+		return nil, invalidInputf("common_name, dns_names or ip_addresses is required")
+	}
+
+	keyType := input.KeyType
+	if keyType == "" {
+		keyType = constants.DefaultCAKeyType
+	}
+	validityDays := input.ValidityDays
+	if validityDays <= 0 {
+		validityDays = constants.DefaultLeafValidityDays
+	}
+	if validityDays > constants.MaxLeafValidityDays {
+		return nil, invalidInputf("validity_days must not exceed %d", constants.MaxLeafValidityDays)
+	}
+
+	var (
+		parent    *x509.Certificate
+		parentKey crypto.Signer
+		roots     *x509.CertPool
+		chain     string
+	)
+	certType := constants.CertificateTypeSelfSigned
+	if input.CAID != nil {
+		ca, err := s.getOwned(ownerID, *input.CAID)
+		if err != nil {
+			return nil, err
+		}
+		parent, parentKey, err = s.loadCA(ca)
+		if err != nil {
+			return nil, err
+		}
+		roots = x509.NewCertPool()
+		roots.AddCert(parent)
+		chain = ca.CertificateData
+		certType = constants.CertificateTypeInternal
+	}
+
+	now := time.Now()
+	leaf, key, err := certutil.Issue(&certutil.Request{
+		CommonName:  commonName,
+		DNSNames:    input.DNSNames,
+		IPAddresses: input.IPAddresses,
+		KeyType:     keyType,
+		Usages:      input.Usages,
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.AddDate(0, 0, validityDays),
+	}, parent, parentKey)
+	if err != nil {
+		return nil, invalidInput(err)
+	}
+
+	keyPEM, err := certutil.EncodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &model.SSLCertificate{
+		Name:    input.Name,
+		CAID:    input.CAID,
+		OwnerID: ownerID,
+	}
+	if err := populateCertificate(cert, &CertificateInput{
+		CertificateType: certType,
+		Certificate:     string(certutil.EncodeCertificates([]*x509.Certificate{leaf})),
+		PrivateKey:      string(keyPEM),
+		Chain:           chain,
+	}, s.encryptor, roots); err != nil {
+		return nil, err
+	}
+
+	if err := s.certRepo.Create(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// ExportBundle 导出 CA 证书 PEM 包，ids 为空时导出用户的全部 CA
+func (s *caService) ExportBundle(userID uint, ids []uint) ([]byte, error) {
+	var cas []*model.CertificateAuthority
+	if len(ids) == 0 {
+		owned, err := s.caRepo.ListByOwner(userID)
+		if err != nil {
+			return nil, err
+		}
+		cas = owned
+	} else {
+		for _, id := range ids {
+			ca, err := s.getOwned(userID, id)
+			if err != nil {
+				return nil, err
+			}
+			cas = append(cas, ca)
+		}
+	}
+	if len(cas) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var bundle bytes.Buffer
+	for _, ca := range cas {
+		fmt.Fprintf(&bundle, "# %s\n", ca.Name)
+		bundle.WriteString(ca.CertificateData)
+	}
+	return bundle.Bytes(), nil
+}
+
+// InstallCA 向服务器的 Agent 下发任务，将 CA 证书安装到系统信任库
+// 任一服务器不存在或不属于当前用户时拒绝整个请求，不下发任何任务
+func (s *caService) InstallCA(userID, id uint, serverIDs []uint) ([]string, error) {
+	ca, err := s.getOwned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(serverIDs) == 0 {
+		return nil, invalidInputf("server_ids is required")
+	}
+	for _, serverID := range serverIDs {
+		if err := s.checkServer(userID, serverID); err != nil {
+			return nil, err
+		}
+	}
+
+	taskIDs := make([]string, 0, len(serverIDs))
+	for _, serverID := range serverIDs {
+		task := &AgentTask{
+			Type: constants.AgentTaskInstallCA,
+			Params: map[string]interface{}{
+				"name":        ca.Name,
+				"certificate": ca.CertificateData,
+			},
+		}
+		if err := s.agentService.DispatchTask(serverID, task); err != nil {
+			return taskIDs, fmt.Errorf("failed to dispatch install task to server %d: %w", serverID, err)
+		}
+		taskIDs = append(taskIDs, task.ID)
+	}
+	return taskIDs, nil
+}
+
+// checkServer 确认服务器存在且属于 ownerID
+func (s *caService) checkServer(ownerID, serverID uint) error {
+	server, err := s.serverRepo.GetByID(serverID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalidInputf("server %d not found", serverID)
+		}
+		return err
+	}
+	if server.OwnerID != ownerID {
+		return fmt.Errorf("server %d: %w", serverID, ErrPermissionDenied)
+	}
+	return nil
+}
+
+func (s *caService) handleInstallResult(agent *model.ServerAgent, result *AgentTaskResult) {
+	if result.Status != constants.AppStatusSuccess {
+		log.Printf("Agent %s failed to install CA certificate (task %s): %s", agent.AgentID, result.TaskID, result.Message)
+		return
+	}
+	log.Printf("Agent %s installed CA certificate (task %s)", agent.AgentID, result.TaskID)
+}
+
+func (s *caService) getOwned(userID, id uint) (*model.CertificateAuthority, error) {
+	ca, err := s.caRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if ca.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return ca, nil
+}
+
+// loadCA 解析 CA 证书并解密私钥
+func (s *caService) loadCA(ca *model.CertificateAuthority) (*x509.Certificate, crypto.Signer, error) {
+	certs, err := certutil.ParseCertificates([]byte(ca.CertificateData))
+	if err != nil {
+		return nil, nil, err
+	}
+	if time.Now().After(certs[0].NotAfter) {
+		return nil, nil, invalidInputf("certificate authority %s has expired", ca.Name)
+	}
+
+	keyPEM, err := s.encryptor.Decrypt(ca.PrivateKeyData)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := certutil.ParsePrivateKey([]byte(keyPEM))
+	if err != nil {
+		return nil, nil, err
+	}
+	return certs[0], key, nil
+}
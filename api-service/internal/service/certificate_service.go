@@ -0,0 +1,403 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/certutil"
+	"api-service/pkg/utils"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CertificateService interface {
+	UploadCertificate(ownerID uint, input *CertificateInput) (*model.SSLCertificate, error)
+	UpdateCertificate(userID, id uint, input *CertificateInput) (*model.SSLCertificate, error)
+	GetCertificate(userID, id uint) (*model.SSLCertificate, error)
+	ListCertificates(userID uint, status string) ([]*model.SSLCertificate, error)
+	ListExpiringCertificates(userID uint, days int) ([]*model.SSLCertificate, error)
+	DeleteCertificate(userID, id uint) error
+	CheckExpiry(now time.Time) error
+	Start(ctx context.Context)
+}
+
+// CertificateInput 上传证书的参数，均为 PEM 格式
+// Certificate 中叶子证书之后的证书视为证书链的一部分
+type CertificateInput struct {
+	Name            string
+	CertificateType string
+	Certificate     string
+	PrivateKey      string
+	Chain           string
+	AutoRenew       bool
+}
+
+type certificateService struct {
+	certRepo     repository.CertificateRepository
+	alertRepo    repository.AlertRepository
+	alertService AlertService
+	webhooks     WebhookService
+	encryptor    *utils.Encryptor
+	warningDays  int
+}
+
+var validCertificateTypes = map[string]bool{
+	constants.CertificateTypeLetsEncrypt: true,
+	constants.CertificateTypeCommercial:  true,
+	constants.CertificateTypeSelfSigned:  true,
+	constants.CertificateTypeInternal:    true,
+}
+
+func NewCertificateService(certRepo repository.CertificateRepository, alertRepo repository.AlertRepository,
+	alertService AlertService, webhooks WebhookService, encryptor *utils.Encryptor, warningDays int) CertificateService {
+	return &certificateService{
+		certRepo:     certRepo,
+		alertRepo:    alertRepo,
+		alertService: alertService,
+		webhooks:     webhooks,
+		encryptor:    encryptor,
+		warningDays:  warningDays,
+	}
+}
+
+func (s *certificateService) UploadCertificate(ownerID uint, input *CertificateInput) (*model.SSLCertificate, error) {
+	cert := &model.SSLCertificate{
+		Name:    input.Name,
+		OwnerID: ownerID,
+	}
+	if err := populateCertificate(cert, input, s.encryptor, nil); err != nil {
+		return nil, err
+	}
+
+	if err := s.certRepo.Create(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// UpdateCertificate 替换证书内容，常用于续期后重新上传
+func (s *certificateService) UpdateCertificate(userID, id uint, input *CertificateInput) (*model.SSLCertificate, error) {
+	cert, err := s.getOwned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != "" {
+		cert.Name = input.Name
+	}
+	if err := populateCertificate(cert, input, s.encryptor, nil); err != nil {
+		return nil, err
+	}
+
+	if err := s.certRepo.Update(cert); err != nil {
+		return nil, err
+	}
+
+	// 证书已更换，之前的过期告警不再有效
+	s.resolveAlerts(cert, "certificate replaced")
+	return cert, nil
+}
+
+func (s *certificateService) GetCertificate(userID, id uint) (*model.SSLCertificate, error) {
+	return s.getOwned(userID, id)
+}
+
+func (s *certificateService) ListCertificates(userID uint, status string) ([]*model.SSLCertificate, error) {
+	return s.certRepo.ListByOwner(userID, status)
+}
+
+func (s *certificateService) ListExpiringCertificates(userID uint, days int) ([]*model.SSLCertificate, error) {
+	if days <= 0 {
+		days = s.warningDays
+	}
+	return s.certRepo.ListExpiringBefore(userID, time.Now().AddDate(0, 0, days))
+}
+
+func (s *certificateService) DeleteCertificate(userID, id uint) error {
+	cert, err := s.getOwned(userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.certRepo.Delete(cert.ID); err != nil {
+		return err
+	}
+
+	s.resolveAlerts(cert, "certificate deleted")
+	return nil
+}
+
+// CheckExpiry 刷新证书状态，并为预警期内的证书生成告警
+func (s *certificateService) CheckExpiry(now time.Time) error {
+	certs, err := s.certRepo.ListActive()
+	if err != nil {
+		return err
+	}
+
+	deadline := now.AddDate(0, 0, s.warningDays)
+	for _, cert := range certs {
+		status := certificateStatus(*cert.NotBefore, *cert.NotAfter, now)
+		if status != cert.Status {
+			cert.Status = status
+			if err := s.certRepo.Update(cert); err != nil {
+				log.Printf("Failed to update certificate %d status: %v", cert.ID, err)
+			}
+		}
+
+		if cert.NotAfter.After(deadline) {
+			continue
+		}
+		if err := s.raiseExpiryAlert(cert, now); err != nil {
+			log.Printf("Failed to raise expiry alert for certificate %d: %v", cert.ID, err)
+		}
+	}
+	return nil
+}
+
+// Start 启动每日证书过期检查
+func (s *certificateService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(constants.CertificateCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.CheckExpiry(time.Now()); err != nil {
+				log.Printf("Certificate expiry check failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// populateCertificate 解析并校验证书、私钥与证书链，填充证书元数据
+// roots 为空时按系统根证书校验证书链
+func populateCertificate(cert *model.SSLCertificate, input *CertificateInput, encryptor *utils.Encryptor, roots *x509.CertPool) error {
+	certs, err := certutil.ParseCertificates([]byte(input.Certificate))
+	if err != nil {
+		return invalidInput(err)
+	}
+	leaf := certs[0]
+
+	chain := certs[1:]
+	if input.Chain != "" {
+		extra, err := certutil.ParseCertificates([]byte(input.Chain))
+		if err != nil {
+			return invalidInputf("invalid certificate chain: %w", err)
+		}
+		chain = append(chain, extra...)
+	}
+
+	key, err := certutil.ParsePrivateKey([]byte(input.PrivateKey))
+	if err != nil {
+		return invalidInput(err)
+	}
+	if err := certutil.KeyMatches(leaf, key); err != nil {
+		return invalidInput(err)
+	}
+
+	// 已过期的证书仍允许录入，按有效期内的时间点校验证书链
+	verifyAt := time.Now()
+	if verifyAt.After(leaf.NotAfter) {
+		verifyAt = leaf.NotAfter
+	}
+	if roots != nil {
+		roots = roots.Clone()
+	}
+	if err := certutil.VerifyChain(leaf, chain, roots, verifyAt); err != nil {
+		return invalidInput(err)
+	}
+
+	certType := input.CertificateType
+	if certType == "" {
+		certType = defaultCertificateType(leaf)
+	}
+	if !validCertificateTypes[certType] {
+		return invalidInputf("invalid certificate type: %s", certType)
+	}
+
+	encryptedKey, err := encryptor.Encrypt(input.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	info := certutil.Inspect(leaf)
+	if cert.Name == "" {
+		cert.Name = certutil.PrimaryDomain(leaf)
+	}
+	cert.Domain = certutil.PrimaryDomain(leaf)
+	if len(leaf.DNSNames) > 0 {
+		cert.Domains = encodeDomains(leaf.DNSNames)
+	} else {
+		cert.Domains = encodeDomains([]string{cert.Domain})
+	}
+	cert.CertificateType = certType
+	cert.CertificateData = string(certutil.EncodeCertificates([]*x509.Certificate{leaf}))
+	cert.CertificateChain = string(certutil.EncodeCertificates(chain))
+	cert.PrivateKeyData = encryptedKey
+	cert.Issuer = info.Issuer
+	cert.Subject = info.Subject
+	cert.SerialNumber = info.SerialNumber
+	cert.NotBefore = &info.NotBefore
+	cert.NotAfter = &info.NotAfter
+	cert.Status = certificateStatus(info.NotBefore, info.NotAfter, time.Now())
+	cert.LastError = ""
+	if input.AutoRenew {
+		cert.AutoRenew = 1
+	} else {
+		cert.AutoRenew = 0
+	}
+
+	if cert.Domain == "" {
+		return invalidInputf("certificate has no domain name")
+	}
+	return nil
+}
+
+func (s *certificateService) getOwned(userID, id uint) (*model.SSLCertificate, error) {
+	cert, err := s.certRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if cert.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return cert, nil
+}
+
+// raiseExpiryAlert 为证书生成过期告警并发送 certificate.expiring 事件
+// 同一证书序列号在预警期内与过期后各告警一次，过期告警取代预警期的告警
+func (s *certificateService) raiseExpiryAlert(cert *model.SSLCertificate, now time.Time) error {
+	warningID := certificateAlertPrefix(cert) + cert.SerialNumber
+	expired := !cert.NotAfter.After(now)
+	alertID := warningID
+	if expired {
+		alertID += certificateExpiredSuffix
+	}
+	if _, err := s.alertRepo.GetRecordByAlertID(alertID); err == nil {
+		return nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	rule, err := s.expiryRule(cert.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	days := int(math.Ceil(cert.NotAfter.Sub(now).Hours() / 24))
+	title := fmt.Sprintf("SSL certificate for %s expires in %d days", cert.Domain, days)
+	if expired {
+		title = fmt.Sprintf("SSL certificate for %s has expired", cert.Domain)
+		// 过期告警尚未创建，按前缀只会恢复预警期的告警
+		if err := s.alertRepo.ResolveRecords(warningID, "certificate expired"); err != nil {
+			return err
+		}
+	}
+
+	suppressedBy, err := s.alertService.Suppression(rule, now)
+	if err != nil {
+		return err
+	}
+
+	record := &model.AlertRecord{
+		AlertRuleID: rule.ID,
+		AlertID:     alertID,
+		Title:       title,
+		Description: fmt.Sprintf("Certificate %q (serial %s, issuer %s) is valid until %s.",
+			cert.Name, cert.SerialNumber, cert.Issuer, cert.NotAfter.Format(time.RFC3339)),
+		Status:               constants.AlertStatusFiring,
+		FiredAt:              now,
+		NotificationChannels: rule.NotificationChannels,
+		SuppressedBy:         suppressedBy,
+	}
+	if suppressedBy != "" {
+		record.Suppressed = 1
+	}
+	if err := s.alertRepo.CreateRecord(record); err != nil {
+		return err
+	}
+
+	s.webhooks.Emit(cert.OwnerID, constants.WebhookEventCertificateExpiring, map[string]interface{}{
+		"certificate_id": cert.ID,
+		"name":           cert.Name,
+		"domain":         cert.Domain,
+		"serial_number":  cert.SerialNumber,
+		"issuer":         cert.Issuer,
+		"not_after":      cert.NotAfter,
+		"days_left":      days,
+		"alert_id":       alertID,
+	})
+	return s.alertService.Notify(rule, record)
+}
+
+// expiryRule 获取用户的证书过期系统告警规则，不存在时自动创建
+func (s *certificateService) expiryRule(ownerID uint) (*model.AlertRule, error) {
+	rule, err := s.alertRepo.FindRule(ownerID, constants.AlertTargetCertificate, constants.AlertMetricCertificateExpiry)
+	if err == nil {
+		return rule, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	rule = &model.AlertRule{
+		Name:                 "SSL certificate expiry",
+		RuleType:             constants.AlertRuleTypeCustom,
+		TargetType:           constants.AlertTargetCertificate,
+		MetricName:           constants.AlertMetricCertificateExpiry,
+		ConditionExpression:  fmt.Sprintf("days_to_expiry <= %d", s.warningDays),
+		NotificationChannels: "[]",
+		IsEnabled:            1,
+		OwnerID:              ownerID,
+	}
+	if err := s.alertRepo.CreateRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *certificateService) resolveAlerts(cert *model.SSLCertificate, note string) {
+	if err := s.alertRepo.ResolveRecords(certificateAlertPrefix(cert), note); err != nil {
+		log.Printf("Failed to resolve alerts for certificate %d: %v", cert.ID, err)
+	}
+}
+
+// certificateExpiredSuffix 过期告警的告警ID后缀，与预警期的告警区分
+const certificateExpiredSuffix = ":expired"
+
+func certificateAlertPrefix(cert *model.SSLCertificate) string {
+	return fmt.Sprintf("ssl-expiry-%d-", cert.ID)
+}
+
+func certificateStatus(notBefore, notAfter, now time.Time) string {
+	switch {
+	case now.After(notAfter):
+		return constants.CertificateStatusExpired
+	case now.Before(notBefore):
+		return constants.CertificateStatusPending
+	default:
+		return constants.CertificateStatusValid
+	}
+}
+
+func defaultCertificateType(leaf *x509.Certificate) string {
+	if certutil.IsSelfSigned(leaf) {
+		return constants.CertificateTypeSelfSigned
+	}
+	return constants.CertificateTypeCommercial
+}
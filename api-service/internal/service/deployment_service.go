@@ -0,0 +1,512 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type DeploymentService interface {
+	CreateDeployment(ownerID uint, input *DeploymentInput, meta *AuditMeta) (*model.AppDeployment, error)
+	GetDeployment(userID, id uint) (*model.AppDeployment, error)
+	ListDeployments(userID uint, page, pageSize int) ([]*model.AppDeployment, int64, error)
+	ListSecretUsages(userID, secretID uint) ([]*model.AppInstance, error)
+	RedeployBySecret(userID, secretID uint, instanceIDs []uint, meta *AuditMeta) ([]*model.AppDeployment, error)
+	// ListLogs 返回部署 afterID 之后的日志分段
+	ListLogs(userID, id, afterID uint, limit int) (*LogPage, error)
+}
+
+// DeploymentInput 部署参数
+// Inputs 为模板输入变量，ConfigData 为附加配置，其中 env 对象会合并到容器环境变量；
+// 两者的字符串值都可以通过 ${secret:名称} 引用密钥库中的密钥
+type DeploymentInput struct {
+	ServerID      uint
+	TemplateID    *uint
+	AppInstanceID *uint
+	ProjectName   string
+	Inputs        map[string]string
+	ConfigData    map[string]interface{}
+}
+
+// deploymentConfig 保存在 AppDeployment.ConfigData 中的部署配置，密钥保持引用形式
+type deploymentConfig struct {
+	ProjectName string                 `json:"project_name"`
+	Inputs      map[string]string      `json:"inputs"`
+	Config      map[string]interface{} `json:"config"`
+}
+
+var (
+	secretRefPattern   = regexp.MustCompile(`\$\{secret:([A-Za-z0-9_.\-]+)\}`)
+	projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+	envNamePattern     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+type deploymentService struct {
+	deploymentRepo repository.DeploymentRepository
+	serverRepo     repository.ServerRepository
+	secretService  SecretService
+	agentService   AgentService
+	notifications  NotificationService
+	webhooks       WebhookService
+	logs           LogService
+	channels       []string
+}
+
+// NewDeploymentService channels 为部署完成时通知部署者使用的通知渠道
+func NewDeploymentService(deploymentRepo repository.DeploymentRepository, serverRepo repository.ServerRepository,
+	secretService SecretService, agentService AgentService, notifications NotificationService, webhooks WebhookService, logs LogService,
+	channels []string) DeploymentService {
+	s := &deploymentService{
+		deploymentRepo: deploymentRepo,
+		serverRepo:     serverRepo,
+		secretService:  secretService,
+		agentService:   agentService,
+		notifications:  notifications,
+		webhooks:       webhooks,
+		logs:           logs,
+		channels:       channels,
+	}
+	agentService.OnTaskResult(constants.AgentTaskDeployApp, s.handleDeployResult)
+	agentService.OnTaskLog(constants.AgentTaskDeployApp, s.handleDeployLog)
+	return s
+}
+
+func (s *deploymentService) CreateDeployment(ownerID uint, input *DeploymentInput, meta *AuditMeta) (*model.AppDeployment, error) {
+	if !projectNamePattern.MatchString(input.ProjectName) {
+		return nil, invalidInputf("invalid project name: %s", input.ProjectName)
+	}
+	for name := range input.Inputs {
+		if !envNamePattern.MatchString(name) {
+			return nil, invalidInputf("invalid input name: %s", name)
+		}
+	}
+
+	instance, template, err := s.prepareInstance(ownerID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &deploymentConfig{
+		ProjectName: input.ProjectName,
+		Inputs:      input.Inputs,
+		Config:      input.ConfigData,
+	}
+	configData, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// 解析引用的密钥，明文只进入下发的任务，不写入部署记录
+	secrets, err := s.secretService.ResolveSecrets(ownerID, collectSecretRefs(config), meta)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := buildDeploymentEnv(config, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	templateID := template.ID
+	deployment := &model.AppDeployment{
+		DeploymentID:  uuid.NewString(),
+		TemplateID:    &templateID,
+		AppInstanceID: &instance.ID,
+		ServerID:      input.ServerID,
+		Status:        constants.DeploymentStatusPending,
+		ConfigData:    string(configData),
+		OwnerID:       ownerID,
+	}
+	if err := s.deploymentRepo.Create(deployment); err != nil {
+		return nil, err
+	}
+
+	refs := make([]*model.SecretKeyReference, 0, len(secrets))
+	secretFiles := make(map[string]interface{}, len(secrets))
+	for name, secret := range secrets {
+		refs = append(refs, &model.SecretKeyReference{
+			SecretKeyID:   secret.ID,
+			SecretVersion: secret.Version,
+			DeploymentID:  deployment.ID,
+			AppInstanceID: &instance.ID,
+		})
+		secretFiles[name] = secret.Value
+	}
+	if err := s.deploymentRepo.CreateSecretReferences(refs); err != nil {
+		return nil, err
+	}
+
+	task := &AgentTask{
+		ID:   deployment.DeploymentID,
+		Type: constants.AgentTaskDeployApp,
+		Params: map[string]interface{}{
+			"project": input.ProjectName,
+			"compose": template.ComposeTemplate,
+			"env":     env,
+			"secrets": secretFiles,
+		},
+	}
+
+	now := time.Now()
+	if err := s.agentService.DispatchTask(input.ServerID, task); err != nil {
+		deployment.Status = constants.DeploymentStatusFailed
+		deployment.ErrorMessage = err.Error()
+		deployment.EndTime = &now
+		if updateErr := s.deploymentRepo.Update(deployment); updateErr != nil {
+			log.Printf("Failed to update deployment %s: %v", deployment.DeploymentID, updateErr)
+		}
+		return deployment, err
+	}
+
+	deployment.Status = constants.DeploymentStatusRunning
+	deployment.StartTime = &now
+	if err := s.deploymentRepo.Update(deployment); err != nil {
+		return nil, err
+	}
+
+	instance.Status = constants.AppInstanceStatusDeployment
+	if err := s.deploymentRepo.UpdateInstance(instance); err != nil {
+		log.Printf("Failed to update app instance %d: %v", instance.ID, err)
+	}
+
+	return deployment, nil
+}
+
+func (s *deploymentService) GetDeployment(userID, id uint) (*model.AppDeployment, error) {
+	deployment, err := s.deploymentRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if deployment.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return deployment, nil
+}
+
+func (s *deploymentService) ListDeployments(userID uint, page, pageSize int) ([]*model.AppDeployment, int64, error) {
+	offset := (page - 1) * pageSize
+	return s.deploymentRepo.ListByOwner(userID, offset, pageSize)
+}
+
+func (s *deploymentService) ListLogs(userID, id, afterID uint, limit int) (*LogPage, error) {
+	deployment, err := s.GetDeployment(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := s.logs.List(constants.LogTargetDeployment, deployment.ID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	finished := deployment.Status != constants.DeploymentStatusPending && deployment.Status != constants.DeploymentStatusRunning
+	return newLogPage(segments, afterID, deployment.Status, finished), nil
+}
+
+// ListSecretUsages 列出当前部署引用了该密钥的应用实例
+func (s *deploymentService) ListSecretUsages(userID, secretID uint) ([]*model.AppInstance, error) {
+	if _, err := s.secretService.GetSecret(userID, secretID); err != nil {
+		return nil, err
+	}
+	return s.deploymentRepo.ListInstancesBySecret(secretID)
+}
+
+// RedeployBySecret 使用各实例最近一次的部署配置重新部署，用于密钥轮换后一键生效
+// instanceIDs 为空时重新部署全部引用该密钥且属于当前用户的实例
+func (s *deploymentService) RedeployBySecret(userID, secretID uint, instanceIDs []uint, meta *AuditMeta) ([]*model.AppDeployment, error) {
+	instances, err := s.ListSecretUsages(userID, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	var deployments []*model.AppDeployment
+	for _, instance := range instances {
+		if len(instanceIDs) > 0 && !containsID(instanceIDs, instance.ID) {
+			continue
+		}
+		if instance.OwnerID != userID {
+			continue
+		}
+
+		deployment, err := s.redeployInstance(userID, instance, meta)
+		if err != nil {
+			return deployments, fmt.Errorf("failed to redeploy app instance %d: %v", instance.ID, err)
+		}
+		deployments = append(deployments, deployment)
+	}
+
+	return deployments, nil
+}
+
+func (s *deploymentService) redeployInstance(userID uint, instance *model.AppInstance, meta *AuditMeta) (*model.AppDeployment, error) {
+	latest, err := s.deploymentRepo.GetLatestByInstance(instance.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var config deploymentConfig
+	if err := json.Unmarshal([]byte(latest.ConfigData), &config); err != nil {
+		return nil, fmt.Errorf("invalid deployment config: %v", err)
+	}
+
+	instanceID := instance.ID
+	return s.CreateDeployment(userID, &DeploymentInput{
+		ServerID:      instance.ServerID,
+		AppInstanceID: &instanceID,
+		ProjectName:   config.ProjectName,
+		Inputs:        config.Inputs,
+		ConfigData:    config.Config,
+	}, meta)
+}
+
+// prepareInstance 确认目标服务器属于部署者，获取或创建部署对应的应用实例
+func (s *deploymentService) prepareInstance(ownerID uint, input *DeploymentInput) (*model.AppInstance, *model.AppStoreTemplate, error) {
+	if err := s.checkServer(ownerID, input.ServerID); err != nil {
+		return nil, nil, err
+	}
+	if input.AppInstanceID != nil {
+		instance, err := s.deploymentRepo.GetInstance(*input.AppInstanceID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil, ErrNotFound
+			}
+			return nil, nil, err
+		}
+		if instance.OwnerID != ownerID {
+			return nil, nil, ErrPermissionDenied
+		}
+		if instance.ServerID != input.ServerID {
+			return nil, nil, invalidInputf("app instance belongs to another server")
+		}
+
+		template, err := s.deploymentRepo.GetTemplate(instance.TemplateID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return instance, template, nil
+	}
+
+	if input.TemplateID == nil {
+		return nil, nil, invalidInputf("template_id or app_instance_id is required")
+	}
+
+	template, err := s.deploymentRepo.GetTemplate(*input.TemplateID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	instance := &model.AppInstance{
+		Name:       input.ProjectName,
+		TemplateID: template.ID,
+		ServerID:   input.ServerID,
+		Status:     constants.AppInstanceStatusDeployment,
+		OwnerID:    ownerID,
+	}
+	if err := s.deploymentRepo.CreateInstance(instance); err != nil {
+		return nil, nil, err
+	}
+	return instance, template, nil
+}
+
+// checkServer 确认服务器存在且属于 ownerID
+func (s *deploymentService) checkServer(ownerID, serverID uint) error {
+	server, err := s.serverRepo.GetByID(serverID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalidInputf("server %d not found", serverID)
+		}
+		return err
+	}
+	if server.OwnerID != ownerID {
+		return fmt.Errorf("server %d: %w", serverID, ErrPermissionDenied)
+	}
+	return nil
+}
+
+func (s *deploymentService) handleDeployResult(agent *model.ServerAgent, result *AgentTaskResult) {
+	deployment, err := s.deploymentRepo.GetByDeploymentID(result.TaskID)
+	if err != nil {
+		log.Printf("Deployment %s reported by agent %s not found: %v", result.TaskID, agent.AgentID, err)
+		return
+	}
+	if deployment.ServerID != agent.ServerID {
+		log.Printf("Ignored result of deployment %s from agent %s of server %d", result.TaskID, agent.AgentID, agent.ServerID)
+		return
+	}
+
+	now := time.Now()
+	deployment.EndTime = &now
+	if output, ok := result.Data["output"].(string); ok {
+		if err := s.logs.AppendTaskOutput(constants.LogTargetDeployment, deployment.ID, "", result.TaskID, output); err != nil {
+			log.Printf("Failed to save output of deployment %s: %v", deployment.DeploymentID, err)
+		}
+	}
+
+	succeeded := result.Status == constants.AppStatusSuccess
+	if succeeded {
+		deployment.Status = constants.DeploymentStatusSuccess
+		deployment.Progress = 100
+	} else {
+		deployment.Status = constants.DeploymentStatusFailed
+		deployment.ErrorMessage = result.Message
+	}
+
+	if err := s.deploymentRepo.Update(deployment); err != nil {
+		log.Printf("Failed to update deployment %s: %v", deployment.DeploymentID, err)
+		return
+	}
+	s.notifyResult(deployment)
+
+	if deployment.AppInstanceID == nil || !succeeded {
+		return
+	}
+
+	instance, err := s.deploymentRepo.GetInstance(*deployment.AppInstanceID)
+	if err != nil {
+		log.Printf("Failed to load app instance %d: %v", *deployment.AppInstanceID, err)
+		return
+	}
+	instance.Status = constants.AppInstanceStatusRunning
+	instance.StartedAt = &now
+	if err := s.deploymentRepo.UpdateInstance(instance); err != nil {
+		log.Printf("Failed to update app instance %d: %v", instance.ID, err)
+	}
+}
+
+// handleDeployLog 保存部署任务推送的实时输出
+func (s *deploymentService) handleDeployLog(agent *model.ServerAgent, taskLog *AgentTaskLog) {
+	deployment, err := s.deploymentRepo.GetByDeploymentID(taskLog.TaskID)
+	if err != nil {
+		log.Printf("Deployment %s reported by agent %s not found: %v", taskLog.TaskID, agent.AgentID, err)
+		return
+	}
+	if deployment.ServerID != agent.ServerID {
+		log.Printf("Ignored log of deployment %s from agent %s of server %d", taskLog.TaskID, agent.AgentID, agent.ServerID)
+		return
+	}
+	if err := s.logs.AppendTaskLog(constants.LogTargetDeployment, deployment.ID, "", taskLog); err != nil {
+		log.Printf("Failed to save log of deployment %s: %v", deployment.DeploymentID, err)
+	}
+}
+
+// notifyResult 通知部署者部署结果，并发送 app.deployed 或 app.failed 事件
+func (s *deploymentService) notifyResult(deployment *model.AppDeployment) {
+	var config deploymentConfig
+	if err := json.Unmarshal([]byte(deployment.ConfigData), &config); err != nil {
+		log.Printf("Invalid config of deployment %s: %v", deployment.DeploymentID, err)
+	}
+
+	template, level := constants.NotificationTemplateDeploymentSucceeded, constants.NotificationLevelInfo
+	event := constants.WebhookEventAppDeployed
+	if deployment.Status == constants.DeploymentStatusFailed {
+		template, level = constants.NotificationTemplateDeploymentFailed, constants.NotificationLevelError
+		event = constants.WebhookEventAppFailed
+	}
+	s.webhooks.Emit(deployment.OwnerID, event, map[string]interface{}{
+		"deployment_id":   deployment.DeploymentID,
+		"project":         config.ProjectName,
+		"server_id":       deployment.ServerID,
+		"app_instance_id": deployment.AppInstanceID,
+		"status":          deployment.Status,
+		"error_message":   deployment.ErrorMessage,
+		"start_time":      deployment.StartTime,
+		"end_time":        deployment.EndTime,
+	})
+	_, err := s.notifications.Send(&NotificationRequest{
+		Kind:       constants.NotificationKindSystem,
+		Level:      level,
+		TargetType: constants.NotificationTargetUser,
+		TargetIDs:  []uint{deployment.OwnerID},
+		Channels:   s.channels,
+		Template:   template,
+		Variables: map[string]interface{}{
+			"deployment_id": deployment.DeploymentID,
+			"project":       config.ProjectName,
+			"server_id":     deployment.ServerID,
+			"message":       deployment.ErrorMessage,
+		},
+		ReferenceType: constants.NotificationRefDeployment,
+		ReferenceID:   deployment.DeploymentID,
+	})
+	if err != nil {
+		log.Printf("Failed to send notification for deployment %s: %v", deployment.DeploymentID, err)
+	}
+}
+
+// collectSecretRefs 收集部署配置中引用的密钥名称
+func collectSecretRefs(config *deploymentConfig) []string {
+	seen := make(map[string]bool)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch value := v.(type) {
+		case string:
+			for _, match := range secretRefPattern.FindAllStringSubmatch(value, -1) {
+				seen[match[1]] = true
+			}
+		case map[string]interface{}:
+			for _, item := range value {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range value {
+				walk(item)
+			}
+		}
+	}
+
+	for _, value := range config.Inputs {
+		walk(value)
+	}
+	walk(config.Config)
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildDeploymentEnv 合并模板输入与 config.env，并替换其中的密钥引用
+func buildDeploymentEnv(config *deploymentConfig, secrets map[string]*ResolvedSecret) (map[string]interface{}, error) {
+	substitute := func(value string) string {
+		return secretRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+			name := secretRefPattern.FindStringSubmatch(ref)[1]
+			return secrets[name].Value
+		})
+	}
+
+	env := make(map[string]interface{}, len(config.Inputs))
+	for name, value := range config.Inputs {
+		env[name] = substitute(value)
+	}
+
+	if extra, ok := config.Config["env"].(map[string]interface{}); ok {
+		for name, value := range extra {
+			if !envNamePattern.MatchString(name) {
+				return nil, invalidInputf("invalid env name: %s", name)
+			}
+			str, ok := value.(string)
+			if !ok {
+				str = fmt.Sprint(value)
+			}
+			env[name] = substitute(str)
+		}
+	}
+
+	return env, nil
+}
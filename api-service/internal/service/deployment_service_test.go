@@ -0,0 +1,194 @@
+package service
+
+import (
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestCollectSecretRefs(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *deploymentConfig
+		want   []string
+	}{
+		{
+			name:   "empty",
+			config: &deploymentConfig{},
+			want:   []string{},
+		},
+		{
+			name: "inputs and nested config",
+			config: &deploymentConfig{
+				Inputs: map[string]string{
+					"DB_URL":  "postgres://app:${secret:db.password}@db/app",
+					"API_KEY": "${secret:api-key}",
+					"PLAIN":   "$HOME ${not_a_secret}",
+				},
+				Config: map[string]interface{}{
+					"env": map[string]interface{}{
+						"TOKEN": "${secret:token_v2}${secret:api-key}",
+						"PORT":  8080,
+					},
+					"volumes": []interface{}{"${secret:tls.key}:/etc/tls.key", map[string]interface{}{"x": "${secret:nested}"}},
+				},
+			},
+			want: []string{"api-key", "db.password", "nested", "tls.key", "token_v2"},
+		},
+		{
+			name: "malformed references",
+			config: &deploymentConfig{
+				Inputs: map[string]string{
+					"A": "${secret:}",
+					"B": "${secret:has space}",
+					"C": "${secret:unterminated",
+					"D": "$secret:bare",
+				},
+			},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collectSecretRefs(tt.config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectSecretRefs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDeploymentEnv(t *testing.T) {
+	secrets := map[string]*ResolvedSecret{
+		"db.password": {Name: "db.password", Value: `p'a"s$s`},
+		"multiline":   {Name: "multiline", Value: "line1\nline2"},
+		// 密钥取值中的引用不再展开
+		"recursive": {Name: "recursive", Value: "${secret:db.password}"},
+	}
+
+	tests := []struct {
+		name    string
+		config  *deploymentConfig
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "inputs and config env",
+			config: &deploymentConfig{
+				Inputs: map[string]string{
+					"DB_URL": "postgres://app:${secret:db.password}@db/app",
+					"PLAIN":  "$HOME",
+				},
+				Config: map[string]interface{}{
+					"env": map[string]interface{}{
+						"CERT":  "${secret:multiline}",
+						"PORT":  8080,
+						"DEBUG": true,
+						"RAW":   "${secret:recursive}",
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"DB_URL": `postgres://app:p'a"s$s@db/app`,
+				"PLAIN":  "$HOME",
+				"CERT":   "line1\nline2",
+				"PORT":   "8080",
+				"DEBUG":  "true",
+				"RAW":    "${secret:db.password}",
+			},
+		},
+		{
+			name: "config env overrides inputs",
+			config: &deploymentConfig{
+				Inputs: map[string]string{"PORT": "80"},
+				Config: map[string]interface{}{"env": map[string]interface{}{"PORT": "8080"}},
+			},
+			want: map[string]interface{}{"PORT": "8080"},
+		},
+		{
+			name: "invalid env name",
+			config: &deploymentConfig{
+				Config: map[string]interface{}{"env": map[string]interface{}{"BAD-NAME": "x"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "env name with newline",
+			config: &deploymentConfig{
+				Config: map[string]interface{}{"env": map[string]interface{}{"A\nB": "x"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildDeploymentEnv(tt.config, secrets)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildDeploymentEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateDeploymentServerOwnership(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.Server{}, &model.AppStoreTemplate{}, &model.AppInstance{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range []interface{}{
+		&model.Server{ID: 1, Name: "mine", Hostname: "a", IPAddress: "10.0.0.1", OSType: "linux", OwnerID: 1},
+		&model.Server{ID: 2, Name: "theirs", Hostname: "b", IPAddress: "10.0.0.2", OSType: "linux", OwnerID: 2},
+		&model.AppStoreTemplate{ID: 1, Name: "app", Code: "app", Version: "1", ComposeTemplate: "services: {}"},
+		// 指向他人服务器的实例，不能借此绕过服务器检查
+		&model.AppInstance{ID: 1, Name: "app", TemplateID: 1, ServerID: 2, OwnerID: 1},
+	} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 服务器检查先于密钥解析与任务下发，其余依赖不会被调用
+	s := &deploymentService{
+		deploymentRepo: repository.NewDeploymentRepository(db),
+		serverRepo:     repository.NewServerRepository(db),
+	}
+	templateID, instanceID := uint(1), uint(1)
+	for name, tt := range map[string]struct {
+		input *DeploymentInput
+		want  error
+	}{
+		"other owner's server": {&DeploymentInput{ServerID: 2, TemplateID: &templateID, ProjectName: "app"}, ErrPermissionDenied},
+		"existing instance":    {&DeploymentInput{ServerID: 2, AppInstanceID: &instanceID, ProjectName: "app"}, ErrPermissionDenied},
+		"missing server":       {&DeploymentInput{ServerID: 9, TemplateID: &templateID, ProjectName: "app"}, ErrInvalidInput},
+	} {
+		if _, err := s.CreateDeployment(1, tt.input, nil); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error %v is not %v", name, err, tt.want)
+		}
+	}
+
+	var instances int64
+	db.Model(&model.AppInstance{}).Count(&instances)
+	if instances != 1 {
+		t.Errorf("app instances = %d, want 1", instances)
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound         = errors.New("resource not found")
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrInvalidInput 请求参数或业务规则校验失败，由调用方修正后重试
+	ErrInvalidInput = errors.New("invalid input")
+)
+
+// inputError 校验失败的错误，消息与被包装的错误一致，errors.Is 可匹配 ErrInvalidInput
+type inputError struct {
+	err error
+}
+
+func (e *inputError) Error() string { return e.err.Error() }
+
+func (e *inputError) Unwrap() error { return e.err }
+
+func (e *inputError) Is(target error) bool { return target == ErrInvalidInput }
+
+// invalidInput 将错误标记为校验失败，err 为 nil 时返回 nil
+func invalidInput(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &inputError{err: err}
+}
+
+// invalidInputf 按格式构造校验失败的错误
+func invalidInputf(format string, args ...interface{}) error {
+	return &inputError{err: fmt.Errorf(format, args...)}
+}
+
+// AuditMeta 审计日志中记录的请求上下文
+type AuditMeta struct {
+	Username      string
+	IPAddress     string
+	UserAgent     string
+	RequestMethod string
+	RequestURL    string
+}
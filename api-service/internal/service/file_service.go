@@ -0,0 +1,560 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/blobstore"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FileService 用户工作空间文件
+// 目录树保存在数据库中，文件内容保存在 blobstore；大文件可分片上传，分片先保存为临时对象，全部上传后合并
+type FileService interface {
+	// List 列出目录下的文件，parentID 为 nil 时列出根目录
+	List(userID uint, parentID *uint, page, pageSize int) ([]*model.UserFile, int64, error)
+	Get(userID, id uint) (*model.UserFile, error)
+	CreateDirectory(userID uint, parentID *uint, name string) (*model.UserFile, error)
+	// Upload 上传文件，size 为 r 的字节数，checksum 不为空时校验内容的 SHA-256
+	Upload(userID uint, parentID *uint, name string, r io.Reader, size int64, checksum string) (*model.UserFile, error)
+	Rename(userID, id uint, name string) (*model.UserFile, error)
+	// Move 移动到 parentID 目录，nil 为根目录
+	Move(userID, id uint, parentID *uint) (*model.UserFile, error)
+	// Delete 删除文件，目录连同其中的全部文件删除
+	Delete(userID, id uint) error
+	// Open 打开文件内容用于下载
+	Open(userID, id uint) (*model.UserFile, io.ReadSeekCloser, error)
+	// CountDownload 累计下载次数，失败只记录日志
+	CountDownload(file *model.UserFile)
+	MaxFileSize() int64
+
+	CreateUpload(userID uint, input *UploadInput) (*UploadSession, error)
+	GetUpload(userID uint, uploadID string) (*UploadSession, error)
+	// UploadChunk 上传第 index 个分片(从 0 开始)，重复上传时覆盖
+	UploadChunk(userID uint, uploadID string, index int, r io.Reader, size int64) error
+	// CompleteUpload 合并全部分片为文件，失败时会话保留，可以重新上传分片后再次合并
+	CompleteUpload(userID uint, uploadID string) (*model.UserFile, error)
+	AbortUpload(userID uint, uploadID string) error
+	Start(ctx context.Context)
+}
+
+// UploadInput 分片上传参数，ChunkSize 为 0 时使用默认分片大小
+type UploadInput struct {
+	ParentID  *uint
+	Name      string
+	Size      int64
+	ChunkSize int64
+	Checksum  string
+}
+
+// UploadSession 分片上传会话及已上传的分片序号
+type UploadSession struct {
+	*model.FileUpload
+	ReceivedChunks []int `json:"received_chunks"`
+}
+
+var checksumPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type fileService struct {
+	fileRepo    repository.FileRepository
+	blobs       blobstore.Store
+	maxFileSize int64
+}
+
+// NewFileService maxFileSize 为单个文件的大小上限(字节)
+func NewFileService(fileRepo repository.FileRepository, blobs blobstore.Store, maxFileSize int64) FileService {
+	return &fileService{
+		fileRepo:    fileRepo,
+		blobs:       blobs,
+		maxFileSize: maxFileSize,
+	}
+}
+
+func (s *fileService) List(userID uint, parentID *uint, page, pageSize int) ([]*model.UserFile, int64, error) {
+	if _, err := s.directoryPath(userID, parentID); err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	return s.fileRepo.List(userID, parentID, offset, pageSize)
+}
+
+func (s *fileService) Get(userID, id uint) (*model.UserFile, error) {
+	file, err := s.fileRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return file, nil
+}
+
+func (s *fileService) CreateDirectory(userID uint, parentID *uint, name string) (*model.UserFile, error) {
+	dirPath, err := s.checkTarget(userID, parentID, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	dir := &model.UserFile{
+		UserID:   userID,
+		Name:     name,
+		Path:     dirPath + "/" + name,
+		Type:     constants.UserFileTypeDirectory,
+		ParentID: parentID,
+	}
+	if err := s.fileRepo.Create(dir); err != nil {
+		return nil, err
+	}
+	return dir, nil
+}
+
+func (s *fileService) Upload(userID uint, parentID *uint, name string, r io.Reader, size int64, checksum string) (*model.UserFile, error) {
+	if err := s.checkSize(size, checksum); err != nil {
+		return nil, err
+	}
+	dirPath, err := s.checkTarget(userID, parentID, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s.store(userID, parentID, dirPath, name, r, size, checksum)
+}
+
+// store 写入文件内容并创建文件记录，写入时计算 SHA-256 并识别 MIME 类型
+func (s *fileService) store(userID uint, parentID *uint, dirPath, name string, r io.Reader, size int64, checksum string) (*model.UserFile, error) {
+	key := fmt.Sprintf("files/%d/%s", userID, uuid.NewString())
+	hash := sha256.New()
+	head := &headBuffer{limit: constants.MimeSniffLength}
+	if err := s.blobs.Put(context.Background(), key, io.TeeReader(r, io.MultiWriter(hash, head)), size); err != nil {
+		return nil, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if checksum != "" && checksum != sum {
+		s.deleteBlob(key)
+		return nil, invalidInputf("checksum mismatch: expected %s, got %s", checksum, sum)
+	}
+
+	file := &model.UserFile{
+		UserID:      userID,
+		Name:        name,
+		Path:        dirPath + "/" + name,
+		Type:        constants.UserFileTypeFile,
+		Size:        size,
+		MimeType:    detectMimeType(name, head.data),
+		ParentID:    parentID,
+		StoragePath: key,
+		Checksum:    sum,
+	}
+	if err := s.fileRepo.Create(file); err != nil {
+		s.deleteBlob(key)
+		return nil, err
+	}
+	return file, nil
+}
+
+func (s *fileService) Rename(userID, id uint, name string) (*model.UserFile, error) {
+	file, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.move(file, file.ParentID, name)
+}
+
+func (s *fileService) Move(userID, id uint, parentID *uint) (*model.UserFile, error) {
+	file, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.move(file, parentID, file.Name)
+}
+
+func (s *fileService) move(file *model.UserFile, parentID *uint, name string) (*model.UserFile, error) {
+	dirPath, err := s.checkTarget(file.UserID, parentID, name, file.ID)
+	if err != nil {
+		return nil, err
+	}
+	if file.Type == constants.UserFileTypeDirectory && (dirPath == file.Path || strings.HasPrefix(dirPath, file.Path+"/")) {
+		return nil, invalidInputf("cannot move a directory into itself")
+	}
+
+	oldPath := file.Path
+	file.Name = name
+	file.ParentID = parentID
+	file.Path = dirPath + "/" + name
+	if err := s.fileRepo.Move(file, oldPath); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (s *fileService) Delete(userID, id uint) error {
+	file, err := s.Get(userID, id)
+	if err != nil {
+		return err
+	}
+	files, err := s.fileRepo.ListTree(file)
+	if err != nil {
+		return err
+	}
+	if err := s.fileRepo.DeleteTree(file); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.StoragePath != "" {
+			s.deleteBlob(f.StoragePath)
+		}
+	}
+	return nil
+}
+
+func (s *fileService) Open(userID, id uint) (*model.UserFile, io.ReadSeekCloser, error) {
+	file, err := s.Get(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if file.Type != constants.UserFileTypeFile {
+		return nil, nil, invalidInputf("cannot download a directory")
+	}
+	content, err := s.blobs.Open(context.Background(), file.StoragePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, content, nil
+}
+
+func (s *fileService) CountDownload(file *model.UserFile) {
+	if err := s.fileRepo.IncrementDownloads(file.ID); err != nil {
+		log.Printf("Failed to count download of file %d: %v", file.ID, err)
+	}
+}
+
+func (s *fileService) MaxFileSize() int64 {
+	return s.maxFileSize
+}
+
+func (s *fileService) CreateUpload(userID uint, input *UploadInput) (*UploadSession, error) {
+	if err := s.checkSize(input.Size, input.Checksum); err != nil {
+		return nil, err
+	}
+	chunkSize := input.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = constants.DefaultUploadChunkSize
+	}
+	if chunkSize < constants.MinUploadChunkSize || chunkSize > constants.MaxUploadChunkSize {
+		return nil, invalidInputf("chunk_size must be between %d and %d bytes", constants.MinUploadChunkSize, constants.MaxUploadChunkSize)
+	}
+	if _, err := s.checkTarget(userID, input.ParentID, input.Name, 0); err != nil {
+		return nil, err
+	}
+
+	upload := &model.FileUpload{
+		UploadID:    uuid.NewString(),
+		UserID:      userID,
+		ParentID:    input.ParentID,
+		Name:        input.Name,
+		Size:        input.Size,
+		ChunkSize:   chunkSize,
+		TotalChunks: int((input.Size + chunkSize - 1) / chunkSize),
+		Checksum:    input.Checksum,
+		Status:      constants.FileUploadUploading,
+		ExpiresAt:   time.Now().Add(constants.UploadSessionTTL),
+	}
+	if err := s.fileRepo.CreateUpload(upload); err != nil {
+		return nil, err
+	}
+	return &UploadSession{FileUpload: upload, ReceivedChunks: []int{}}, nil
+}
+
+func (s *fileService) GetUpload(userID uint, uploadID string) (*UploadSession, error) {
+	upload, err := s.getUpload(userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	session := &UploadSession{FileUpload: upload, ReceivedChunks: make([]int, 0, len(upload.Chunks))}
+	for _, chunk := range upload.Chunks {
+		session.ReceivedChunks = append(session.ReceivedChunks, chunk.Index)
+	}
+	return session, nil
+}
+
+func (s *fileService) UploadChunk(userID uint, uploadID string, index int, r io.Reader, size int64) error {
+	upload, err := s.getUpload(userID, uploadID)
+	if err != nil {
+		return err
+	}
+	if upload.Status != constants.FileUploadUploading {
+		return invalidInputf("upload is being completed")
+	}
+	if index < 0 || index >= upload.TotalChunks {
+		return invalidInputf("chunk index must be between 0 and %d", upload.TotalChunks-1)
+	}
+	if expected := chunkLength(upload, index); size != expected {
+		return invalidInputf("chunk %d must be %d bytes, got %d", index, expected, size)
+	}
+
+	if err := s.blobs.Put(context.Background(), uploadChunkKey(upload, index), r, size); err != nil {
+		return err
+	}
+	return s.fileRepo.SaveChunk(&model.FileUploadChunk{UploadID: upload.ID, Index: index, Size: size},
+		time.Now().Add(constants.UploadSessionTTL))
+}
+
+func (s *fileService) CompleteUpload(userID uint, uploadID string) (*model.UserFile, error) {
+	upload, err := s.getUpload(userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if len(upload.Chunks) != upload.TotalChunks {
+		return nil, invalidInputf("%d of %d chunks uploaded", len(upload.Chunks), upload.TotalChunks)
+	}
+	claimed, err := s.fileRepo.ClaimUpload(upload.ID, constants.FileUploadUploading, constants.FileUploadCompleting)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, invalidInputf("upload is being completed")
+	}
+
+	file, err := s.compose(upload)
+	if err != nil {
+		if _, claimErr := s.fileRepo.ClaimUpload(upload.ID, constants.FileUploadCompleting, constants.FileUploadUploading); claimErr != nil {
+			log.Printf("Failed to release upload %s: %v", upload.UploadID, claimErr)
+		}
+		return nil, err
+	}
+	s.removeUpload(upload)
+	return file, nil
+}
+
+// compose 按序号依次读取分片写入文件
+func (s *fileService) compose(upload *model.FileUpload) (*model.UserFile, error) {
+	dirPath, err := s.checkTarget(upload.UserID, upload.ParentID, upload.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+	chunks := &chunkReader{blobs: s.blobs, upload: upload}
+	defer chunks.Close()
+	return s.store(upload.UserID, upload.ParentID, dirPath, upload.Name, chunks, upload.Size, upload.Checksum)
+}
+
+func (s *fileService) AbortUpload(userID uint, uploadID string) error {
+	upload, err := s.getUpload(userID, uploadID)
+	if err != nil {
+		return err
+	}
+	s.removeUpload(upload)
+	return nil
+}
+
+// Start 定期清理过期的上传会话
+func (s *fileService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(constants.UploadCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			s.cleanupUploads()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *fileService) cleanupUploads() {
+	uploads, err := s.fileRepo.ListExpiredUploads(time.Now(), constants.UploadCleanupBatch)
+	if err != nil {
+		log.Printf("Failed to list expired uploads: %v", err)
+		return
+	}
+	for _, upload := range uploads {
+		s.removeUpload(upload)
+	}
+}
+
+// removeUpload 删除会话与已上传的分片
+func (s *fileService) removeUpload(upload *model.FileUpload) {
+	if err := s.fileRepo.DeleteUpload(upload.ID); err != nil {
+		log.Printf("Failed to delete upload %s: %v", upload.UploadID, err)
+		return
+	}
+	for _, chunk := range upload.Chunks {
+		s.deleteBlob(uploadChunkKey(upload, chunk.Index))
+	}
+}
+
+func (s *fileService) getUpload(userID uint, uploadID string) (*model.FileUpload, error) {
+	upload, err := s.fileRepo.GetUpload(uploadID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if upload.UserID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return upload, nil
+}
+
+// directoryPath 返回目录的路径，parentID 为 nil 时为根目录(空字符串)
+func (s *fileService) directoryPath(userID uint, parentID *uint) (string, error) {
+	if parentID == nil {
+		return "", nil
+	}
+	dir, err := s.Get(userID, *parentID)
+	if err != nil {
+		return "", err
+	}
+	if dir.Type != constants.UserFileTypeDirectory {
+		return "", invalidInputf("parent is not a directory")
+	}
+	return dir.Path, nil
+}
+
+// checkTarget 校验文件名与目标目录，目录下不能有同名文件，返回目录的路径
+func (s *fileService) checkTarget(userID uint, parentID *uint, name string, excludeID uint) (string, error) {
+	if err := checkFileName(name); err != nil {
+		return "", err
+	}
+	dirPath, err := s.directoryPath(userID, parentID)
+	if err != nil {
+		return "", err
+	}
+	exists, err := s.fileRepo.NameExists(userID, parentID, name, excludeID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", invalidInputf("%s already exists", name)
+	}
+	return dirPath, nil
+}
+
+func (s *fileService) checkSize(size int64, checksum string) error {
+	if size < 0 {
+		return invalidInputf("size must not be negative")
+	}
+	if size > s.maxFileSize {
+		return invalidInputf("file exceeds the maximum size of %d bytes", s.maxFileSize)
+	}
+	if checksum != "" && !checksumPattern.MatchString(checksum) {
+		return invalidInputf("checksum must be a lowercase hex SHA-256")
+	}
+	return nil
+}
+
+func (s *fileService) deleteBlob(key string) {
+	if err := s.blobs.Delete(context.Background(), key); err != nil {
+		log.Printf("Failed to delete blob %s: %v", key, err)
+	}
+}
+
+// chunkReader 依次打开并读取上传会话的各个分片
+type chunkReader struct {
+	blobs   blobstore.Store
+	upload  *model.FileUpload
+	index   int
+	current io.ReadSeekCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for r.index < r.upload.TotalChunks {
+		if r.current == nil {
+			chunk, err := r.blobs.Open(context.Background(), uploadChunkKey(r.upload, r.index))
+			if err != nil {
+				return 0, fmt.Errorf("failed to open chunk %d: %v", r.index, err)
+			}
+			r.current = chunk
+		}
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			r.index++
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+	return 0, io.EOF
+}
+
+func (r *chunkReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
+}
+
+// headBuffer 保留写入内容的前 limit 个字节
+type headBuffer struct {
+	limit int
+	data  []byte
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - len(b.data); remaining > 0 {
+		b.data = append(b.data, p[:min(remaining, len(p))]...)
+	}
+	return len(p), nil
+}
+
+func uploadChunkKey(upload *model.FileUpload, index int) string {
+	return "uploads/" + upload.UploadID + "/" + strconv.Itoa(index)
+}
+
+// chunkLength 分片的字节数，最后一个分片为剩余部分
+func chunkLength(upload *model.FileUpload, index int) int64 {
+	return min(upload.ChunkSize, upload.Size-int64(index)*upload.ChunkSize)
+}
+
+// checkFileName 文件名不能包含路径分隔符与控制字符
+func checkFileName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return invalidInputf("invalid file name")
+	}
+	if len(name) > constants.MaxFileNameLength {
+		return invalidInputf("file name must not exceed %d bytes", constants.MaxFileNameLength)
+	}
+	for _, r := range name {
+		if r == '/' || r == '\\' || unicode.IsControl(r) {
+			return invalidInputf("file name must not contain %q", r)
+		}
+	}
+	return nil
+}
+
+// detectMimeType 按文件内容识别 MIME 类型，内容只能识别为通用文本或二进制时使用扩展名对应的类型
+func detectMimeType(name string, head []byte) string {
+	sniffed := http.DetectContentType(head)
+	if sniffed != "application/octet-stream" && !strings.HasPrefix(sniffed, "text/plain") {
+		return sniffed
+	}
+	if byExt := mime.TypeByExtension(strings.ToLower(path.Ext(name))); byExt != "" {
+		return byExt
+	}
+	return sniffed
+}
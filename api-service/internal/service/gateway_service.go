@@ -0,0 +1,795 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/certutil"
+	"api-service/pkg/proxyconf"
+	"api-service/pkg/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// GatewayService 管理应用网关及其发布规则，变更后通知网关进程重新加载路由
+type GatewayService interface {
+	CreateGateway(ownerID uint, input *GatewayInput) (*model.AppGateway, error)
+	GetGateway(userID, id uint) (*model.AppGateway, error)
+	ListGateways(userID uint) ([]*model.AppGateway, error)
+	UpdateGateway(userID, id uint, input *GatewayInput) (*model.AppGateway, error)
+	DeleteGateway(userID, id uint) error
+	CreatePublish(userID, gatewayID uint, input *PublishInput) (*model.AppGatewayPublish, error)
+	UpdatePublish(userID, gatewayID, publishID uint, input *PublishInput) (*model.AppGatewayPublish, error)
+	DeletePublish(userID, gatewayID, publishID uint) error
+	ListAccessRules(userID, gatewayID uint) ([]*model.AppGatewayAccessRule, error)
+	CreateAccessRule(userID, gatewayID uint, input *AccessRuleInput) (*model.AppGatewayAccessRule, error)
+	UpdateAccessRule(userID, gatewayID, ruleID uint, input *AccessRuleInput) (*model.AppGatewayAccessRule, error)
+	DeleteAccessRule(userID, gatewayID, ruleID uint) error
+	GetTraffic(userID, gatewayID uint, domain string, start, end time.Time, top int) ([]*GatewayTraffic, error)
+	ExportConfig(userID, gatewayID uint, format string) (*ProxyConfigExport, error)
+	DeployConfig(userID, gatewayID uint, input *ProxyDeployInput) (*ProxyConfigDeployment, error)
+}
+
+type GatewayInput struct {
+	Name        string
+	ServerID    uint
+	Description string
+}
+
+// PublishInput 发布参数，将域名的访问转发到应用实例的服务端口
+// AuditLogEnabled 控制网关是否为该域名输出访问日志，流量统计不受影响
+type PublishInput struct {
+	AppInstanceID   uint
+	ServiceDomain   string
+	ServicePort     int
+	UpstreamHost    string
+	CertificateID   *uint
+	AuditLogEnabled bool
+}
+
+// AccessRuleInput 访问控制规则参数
+// TargetIP 为逗号分隔的 IP/CIDR，TargetPath 为路径前缀，均为空时作用于全部请求
+type AccessRuleInput struct {
+	RuleName    string
+	RuleType    string
+	LimitCount  int
+	TimeWindow  int
+	TargetPath  string
+	TargetIP    string
+	Action      string
+	RedirectURL string
+	Enabled     bool
+}
+
+// ProxyConfigExport 为 nginx 或 Traefik 生成的网关配置
+type ProxyConfigExport struct {
+	Format   string   `json:"format"`
+	Filename string   `json:"filename"`
+	Content  string   `json:"content"`
+	Warnings []string `json:"warnings"`
+}
+
+// ProxyDeployInput 下发代理配置的参数，目录为空时使用对应格式的默认目录
+type ProxyDeployInput struct {
+	Format    string
+	ConfigDir string
+	CertDir   string
+}
+
+// ProxyConfigDeployment 已下发到网关所在服务器的配置任务
+type ProxyConfigDeployment struct {
+	TaskID     string   `json:"task_id"`
+	ServerID   uint     `json:"server_id"`
+	ConfigPath string   `json:"config_path"`
+	Warnings   []string `json:"warnings"`
+}
+
+var serviceDomainPattern = regexp.MustCompile(`^(\*\.)?[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+type gatewayService struct {
+	gatewayRepo   repository.GatewayRepository
+	certRepo      repository.CertificateRepository
+	serverRepo    repository.ServerRepository
+	monitor       MonitorService
+	agentService  AgentService
+	rdb           *redis.Client
+	encryptor     *utils.Encryptor
+	reloadChannel string
+}
+
+func NewGatewayService(gatewayRepo repository.GatewayRepository, certRepo repository.CertificateRepository,
+	serverRepo repository.ServerRepository, monitor MonitorService, agentService AgentService, rdb *redis.Client, encryptor *utils.Encryptor,
+	reloadChannel string) GatewayService {
+	s := &gatewayService{
+		gatewayRepo:   gatewayRepo,
+		certRepo:      certRepo,
+		serverRepo:    serverRepo,
+		monitor:       monitor,
+		agentService:  agentService,
+		rdb:           rdb,
+		encryptor:     encryptor,
+		reloadChannel: reloadChannel,
+	}
+	agentService.OnTaskResult(constants.AgentTaskProxyConfig, s.handleProxyConfigResult)
+	return s
+}
+
+func (s *gatewayService) CreateGateway(ownerID uint, input *GatewayInput) (*model.AppGateway, error) {
+	if input.ServerID != 0 {
+		if err := s.checkServer(ownerID, input.ServerID); err != nil {
+			return nil, err
+		}
+	}
+	gateway := &model.AppGateway{
+		Name:        input.Name,
+		ServerID:    input.ServerID,
+		Description: input.Description,
+		Status:      constants.GatewayStatusDefault,
+		OwnerID:     ownerID,
+	}
+	if err := s.gatewayRepo.Create(gateway); err != nil {
+		return nil, err
+	}
+	return gateway, nil
+}
+
+func (s *gatewayService) GetGateway(userID, id uint) (*model.AppGateway, error) {
+	return s.getOwned(userID, id)
+}
+
+func (s *gatewayService) ListGateways(userID uint) ([]*model.AppGateway, error) {
+	return s.gatewayRepo.ListByOwner(userID)
+}
+
+func (s *gatewayService) UpdateGateway(userID, id uint, input *GatewayInput) (*model.AppGateway, error) {
+	gateway, err := s.getOwned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != "" {
+		gateway.Name = input.Name
+	}
+	gateway.Description = input.Description
+	// 网关所在服务器变化会影响本机实例的转发地址
+	moved := input.ServerID != 0 && input.ServerID != gateway.ServerID
+	if moved {
+		if err := s.checkServer(userID, input.ServerID); err != nil {
+			return nil, err
+		}
+		gateway.ServerID = input.ServerID
+	}
+
+	if err := s.gatewayRepo.Update(gateway); err != nil {
+		return nil, err
+	}
+	if moved {
+		s.notifyReload(gateway.ID)
+	}
+	return gateway, nil
+}
+
+func (s *gatewayService) DeleteGateway(userID, id uint) error {
+	gateway, err := s.getOwned(userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.gatewayRepo.Delete(gateway.ID); err != nil {
+		return err
+	}
+	s.notifyReload(gateway.ID)
+	return nil
+}
+
+func (s *gatewayService) CreatePublish(userID, gatewayID uint, input *PublishInput) (*model.AppGatewayPublish, error) {
+	gateway, err := s.getOwned(userID, gatewayID)
+	if err != nil {
+		return nil, err
+	}
+
+	publish := &model.AppGatewayPublish{
+		AppGatewayID: gateway.ID,
+		OwnerID:      userID,
+	}
+	if err := s.applyPublishInput(userID, publish, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.gatewayRepo.CreatePublish(publish); err != nil {
+		return nil, err
+	}
+	s.notifyReload(gateway.ID)
+	return publish, nil
+}
+
+func (s *gatewayService) UpdatePublish(userID, gatewayID, publishID uint, input *PublishInput) (*model.AppGatewayPublish, error) {
+	publish, err := s.getPublish(userID, gatewayID, publishID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyPublishInput(userID, publish, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.gatewayRepo.UpdatePublish(publish); err != nil {
+		return nil, err
+	}
+	s.notifyReload(gatewayID)
+	return publish, nil
+}
+
+func (s *gatewayService) DeletePublish(userID, gatewayID, publishID uint) error {
+	publish, err := s.getPublish(userID, gatewayID, publishID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.gatewayRepo.DeletePublish(publish.ID); err != nil {
+		return err
+	}
+	s.notifyReload(gatewayID)
+	return nil
+}
+
+func (s *gatewayService) ListAccessRules(userID, gatewayID uint) ([]*model.AppGatewayAccessRule, error) {
+	if _, err := s.getOwned(userID, gatewayID); err != nil {
+		return nil, err
+	}
+	return s.gatewayRepo.ListAccessRules(gatewayID)
+}
+
+func (s *gatewayService) CreateAccessRule(userID, gatewayID uint, input *AccessRuleInput) (*model.AppGatewayAccessRule, error) {
+	gateway, err := s.getOwned(userID, gatewayID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &model.AppGatewayAccessRule{GatewayID: gateway.ID}
+	if err := applyAccessRuleInput(rule, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.gatewayRepo.CreateAccessRule(rule); err != nil {
+		return nil, err
+	}
+	s.notifyReload(gateway.ID)
+	return rule, nil
+}
+
+func (s *gatewayService) UpdateAccessRule(userID, gatewayID, ruleID uint, input *AccessRuleInput) (*model.AppGatewayAccessRule, error) {
+	rule, err := s.getAccessRule(userID, gatewayID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyAccessRuleInput(rule, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.gatewayRepo.UpdateAccessRule(rule); err != nil {
+		return nil, err
+	}
+	s.notifyReload(gatewayID)
+	return rule, nil
+}
+
+func (s *gatewayService) DeleteAccessRule(userID, gatewayID, ruleID uint) error {
+	rule, err := s.getAccessRule(userID, gatewayID, ruleID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.gatewayRepo.DeleteAccessRule(rule.ID); err != nil {
+		return err
+	}
+	s.notifyReload(gatewayID)
+	return nil
+}
+
+// GetTraffic 查询网关发布域名的流量统计，domain 为空时返回全部发布域名
+func (s *gatewayService) GetTraffic(userID, gatewayID uint, domain string, start, end time.Time, top int) ([]*GatewayTraffic, error) {
+	gateway, err := s.getOwned(userID, gatewayID)
+	if err != nil {
+		return nil, err
+	}
+	if !start.Before(end) {
+		return nil, invalidInputf("start must be before end")
+	}
+
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	var domains []string
+	for _, publish := range gateway.Publishes {
+		if domain == "" || publish.ServiceDomain == domain {
+			domains = append(domains, publish.ServiceDomain)
+		}
+	}
+	if domain != "" && len(domains) == 0 {
+		return nil, ErrNotFound
+	}
+
+	result := make([]*GatewayTraffic, 0, len(domains))
+	for _, d := range domains {
+		traffic, err := s.monitor.GetGatewayTraffic(gateway.ID, d, start, end, top)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query traffic for %s: %w", d, err)
+		}
+		result = append(result, traffic)
+	}
+	return result, nil
+}
+
+// ExportConfig 将网关的发布规则与启用的访问控制规则导出为 nginx 或 Traefik 配置
+// 证书按默认证书目录引用，需自行放置证书文件
+func (s *gatewayService) ExportConfig(userID, gatewayID uint, format string) (*ProxyConfigExport, error) {
+	gateway, err := s.getOwned(userID, gatewayID)
+	if err != nil {
+		return nil, err
+	}
+	_, certDir, err := proxyExportDirs(format)
+	if err != nil {
+		return nil, err
+	}
+
+	result, _, err := s.buildProxyConfig(gateway, format, certDir)
+	if err != nil {
+		return nil, err
+	}
+	return &ProxyConfigExport{
+		Format:   format,
+		Filename: proxyConfigFilename(gateway.ID, format),
+		Content:  string(result.Content),
+		Warnings: result.Warnings,
+	}, nil
+}
+
+// DeployConfig 生成代理配置并连同证书下发到网关所在服务器，由 Agent 校验配置后重新加载代理
+func (s *gatewayService) DeployConfig(userID, gatewayID uint, input *ProxyDeployInput) (*ProxyConfigDeployment, error) {
+	gateway, err := s.getOwned(userID, gatewayID)
+	if err != nil {
+		return nil, err
+	}
+	if gateway.ServerID == 0 {
+		return nil, invalidInputf("gateway is not bound to a server")
+	}
+	// 服务器可能在网关创建后转移给其他用户，下发前重新确认
+	if err := s.checkServer(userID, gateway.ServerID); err != nil {
+		return nil, err
+	}
+
+	configDir, certDir, err := proxyExportDirs(input.Format)
+	if err != nil {
+		return nil, err
+	}
+	if input.ConfigDir != "" {
+		configDir = input.ConfigDir
+	}
+	if input.CertDir != "" {
+		certDir = input.CertDir
+	}
+	for _, dir := range []string{configDir, certDir} {
+		if !filepath.IsAbs(dir) || filepath.Clean(dir) != dir {
+			return nil, invalidInputf("invalid directory: %s", dir)
+		}
+	}
+
+	result, certs, err := s.buildProxyConfig(gateway, input.Format, certDir)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates := make([]map[string]interface{}, 0, len(certs))
+	for _, cert := range certs {
+		keyPEM, err := s.encryptor.Decrypt(cert.PrivateKeyData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key of certificate %d: %w", cert.ID, err)
+		}
+		certificates = append(certificates, map[string]interface{}{
+			"name":        proxyCertName(cert.ID),
+			"certificate": cert.CertificateData + cert.CertificateChain,
+			"private_key": keyPEM,
+		})
+	}
+
+	filename := proxyConfigFilename(gateway.ID, input.Format)
+	task := &AgentTask{
+		Type: constants.AgentTaskProxyConfig,
+		Params: map[string]interface{}{
+			"format":       input.Format,
+			"config_dir":   configDir,
+			"filename":     filename,
+			"content":      string(result.Content),
+			"cert_dir":     certDir,
+			"certificates": certificates,
+		},
+	}
+	if err := s.agentService.DispatchTask(gateway.ServerID, task); err != nil {
+		return nil, fmt.Errorf("failed to dispatch proxy config task to server %d: %w", gateway.ServerID, err)
+	}
+
+	return &ProxyConfigDeployment{
+		TaskID:     task.ID,
+		ServerID:   gateway.ServerID,
+		ConfigPath: filepath.Join(configDir, filename),
+		Warnings:   result.Warnings,
+	}, nil
+}
+
+// checkServer 确认服务器存在且属于 ownerID
+func (s *gatewayService) checkServer(ownerID, serverID uint) error {
+	server, err := s.serverRepo.GetByID(serverID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalidInputf("server %d not found", serverID)
+		}
+		return err
+	}
+	if server.OwnerID != ownerID {
+		return fmt.Errorf("server %d: %w", serverID, ErrPermissionDenied)
+	}
+	return nil
+}
+
+func (s *gatewayService) handleProxyConfigResult(agent *model.ServerAgent, result *AgentTaskResult) {
+	if result.Status != constants.AppStatusSuccess {
+		log.Printf("Agent %s failed to apply proxy config (task %s): %s", agent.AgentID, result.TaskID, result.Message)
+		return
+	}
+	log.Printf("Agent %s applied proxy config (task %s)", agent.AgentID, result.TaskID)
+}
+
+// buildProxyConfig 生成代理配置，返回配置引用的证书
+func (s *gatewayService) buildProxyConfig(gateway *model.AppGateway, format, certDir string) (*proxyconf.Result, []*model.SSLCertificate, error) {
+	publishes, err := s.gatewayRepo.ListPublishes(gateway.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	rules, err := s.gatewayRepo.ListEnabledAccessRules(gateway.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proxyRules := make([]proxyconf.Rule, 0, len(rules))
+	for _, rule := range rules {
+		nets, err := utils.ParseIPNets(rule.TargetIP)
+		if err != nil {
+			return nil, nil, fmt.Errorf("access rule %d: %w", rule.ID, err)
+		}
+		ips := make([]string, 0, len(nets))
+		for _, ipNet := range nets {
+			ips = append(ips, ipNet.String())
+		}
+		proxyRules = append(proxyRules, proxyconf.Rule{
+			Name:        fmt.Sprintf("r%d", rule.ID),
+			Type:        rule.RuleType,
+			Path:        rule.TargetPath,
+			IPs:         ips,
+			LimitCount:  rule.LimitCount,
+			Window:      rule.TimeWindow,
+			Action:      rule.Action,
+			RedirectURL: rule.RedirectURL,
+		})
+	}
+
+	var sites []proxyconf.Site
+	var certs []*model.SSLCertificate
+	seenCerts := make(map[uint]bool)
+	var ownerCerts []*model.SSLCertificate
+	for _, publish := range publishes {
+		upstream, err := PublishUpstream(publish)
+		if err != nil {
+			return nil, nil, fmt.Errorf("publish %s: %w", publish.ServiceDomain, err)
+		}
+		site := proxyconf.Site{
+			Name:     fmt.Sprintf("g%d_p%d", gateway.ID, publish.ID),
+			Domain:   publish.ServiceDomain,
+			Upstream: upstream,
+			Rules:    proxyRules,
+		}
+
+		if ownerCerts == nil {
+			if ownerCerts, err = s.certRepo.ListByOwner(gateway.OwnerID, constants.CertificateStatusValid); err != nil {
+				return nil, nil, err
+			}
+		}
+		cert, err := s.publishCertificate(publish, ownerCerts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cert != nil {
+			site.TLS = &proxyconf.TLS{
+				CertFile: filepath.Join(certDir, proxyCertName(cert.ID)+".crt"),
+				KeyFile:  filepath.Join(certDir, proxyCertName(cert.ID)+".key"),
+			}
+			if !seenCerts[cert.ID] {
+				seenCerts[cert.ID] = true
+				certs = append(certs, cert)
+			}
+		}
+		sites = append(sites, site)
+	}
+
+	result, err := proxyconf.Generate(format, sites)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, certs, nil
+}
+
+// publishCertificate 与网关一致：优先使用发布规则指定的证书，否则取覆盖域名且有效期最长的证书
+func (s *gatewayService) publishCertificate(publish *model.AppGatewayPublish, ownerCerts []*model.SSLCertificate) (*model.SSLCertificate, error) {
+	if publish.CertificateID != nil {
+		cert, err := s.certRepo.GetByID(*publish.CertificateID)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %d for %s: %w", *publish.CertificateID, publish.ServiceDomain, err)
+		}
+		if cert.OwnerID != publish.OwnerID {
+			return nil, ErrPermissionDenied
+		}
+		return cert, nil
+	}
+
+	var matched *model.SSLCertificate
+	for _, cert := range ownerCerts {
+		parsed, err := certutil.ParseCertificates([]byte(cert.CertificateData))
+		if err != nil {
+			continue
+		}
+		if certutil.CoversDomain(parsed[0], publish.ServiceDomain) {
+			matched = cert
+		}
+	}
+	return matched, nil
+}
+
+// proxyExportDirs 返回格式对应的默认配置目录与证书目录
+func proxyExportDirs(format string) (string, string, error) {
+	switch format {
+	case proxyconf.FormatNginx:
+		return constants.ProxyExportNginxConfigDir, constants.ProxyExportNginxCertDir, nil
+	case proxyconf.FormatTraefik:
+		return constants.ProxyExportTraefikConfigDir, constants.ProxyExportTraefikCertDir, nil
+	default:
+		return "", "", invalidInputf("unsupported proxy config format: %s", format)
+	}
+}
+
+func proxyConfigFilename(gatewayID uint, format string) string {
+	ext := ".conf"
+	if format == proxyconf.FormatTraefik {
+		ext = ".yml"
+	}
+	return constants.ProxyExportFilePrefix + strconv.FormatUint(uint64(gatewayID), 10) + ext
+}
+
+func proxyCertName(certID uint) string {
+	return constants.ProxyExportCertPrefix + strconv.FormatUint(uint64(certID), 10)
+}
+
+// applyPublishInput 校验发布参数并写入发布规则
+func (s *gatewayService) applyPublishInput(userID uint, publish *model.AppGatewayPublish, input *PublishInput) error {
+	domain := strings.ToLower(strings.TrimSpace(input.ServiceDomain))
+	if !serviceDomainPattern.MatchString(domain) {
+		return invalidInputf("invalid service domain: %s", input.ServiceDomain)
+	}
+	if input.ServicePort <= 0 || input.ServicePort > 65535 {
+		return invalidInputf("invalid service port: %d", input.ServicePort)
+	}
+
+	// 所有网关进程可能加载同一张路由表，域名在全部网关间唯一，避免他人发布同名域名接管流量
+	existing, err := s.gatewayRepo.FindPublishByDomain(domain)
+	if err == nil && existing.ID != publish.ID {
+		return invalidInputf("service domain %s is already published", domain)
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	instance, err := s.gatewayRepo.GetAppInstance(input.AppInstanceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if instance.OwnerID != userID {
+		return ErrPermissionDenied
+	}
+
+	// 转发地址只能是应用实例所在服务器的地址，防止网关被用作访问任意内网地址的代理
+	upstreamHost := strings.TrimSpace(input.UpstreamHost)
+	if upstreamHost != "" {
+		server, err := s.serverRepo.GetByID(instance.ServerID)
+		if err != nil {
+			return err
+		}
+		if !isServerAddress(server, upstreamHost) {
+			return invalidInputf("upstream_host must be an address of server %d: %s", server.ID, upstreamHost)
+		}
+	}
+
+	if input.CertificateID != nil {
+		cert, err := s.certRepo.GetByID(*input.CertificateID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if cert.OwnerID != userID {
+			return ErrPermissionDenied
+		}
+	}
+
+	publish.AppInstanceID = instance.ID
+	publish.ServiceDomain = domain
+	publish.ServicePort = input.ServicePort
+	publish.UpstreamHost = upstreamHost
+	publish.CertificateID = input.CertificateID
+	publish.AuditLogEnabled = 0
+	if input.AuditLogEnabled {
+		publish.AuditLogEnabled = 1
+	}
+	return nil
+}
+
+// applyAccessRuleInput 校验访问控制规则参数并写入规则
+func applyAccessRuleInput(rule *model.AppGatewayAccessRule, input *AccessRuleInput) error {
+	if strings.TrimSpace(input.RuleName) == "" {
+		return invalidInputf("rule_name is required")
+	}
+
+	switch input.RuleType {
+	case constants.AccessRuleIPWhitelist, constants.AccessRuleIPBlacklist:
+		if strings.TrimSpace(input.TargetIP) == "" {
+			return invalidInputf("target_ip is required for %s rules", input.RuleType)
+		}
+	case constants.AccessRuleRateLimit:
+		if input.LimitCount <= 0 || input.TimeWindow <= 0 {
+			return invalidInputf("limit_count and time_window must be positive for RATE_LIMIT rules")
+		}
+	default:
+		return invalidInputf("invalid rule type: %s", input.RuleType)
+	}
+
+	if _, err := utils.ParseIPNets(input.TargetIP); err != nil {
+		return invalidInput(err)
+	}
+	if input.TargetPath != "" && !strings.HasPrefix(input.TargetPath, "/") {
+		return invalidInputf("target_path must start with /: %s", input.TargetPath)
+	}
+
+	action := input.Action
+	if action == "" {
+		action = constants.AccessActionBlock
+	}
+	switch action {
+	case constants.AccessActionBlock, constants.AccessActionAllow:
+	case constants.AccessActionRedirect:
+		target, err := url.Parse(input.RedirectURL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return invalidInputf("invalid redirect_url: %s", input.RedirectURL)
+		}
+	default:
+		return invalidInputf("invalid action: %s", action)
+	}
+
+	rule.RuleName = input.RuleName
+	rule.RuleType = input.RuleType
+	rule.LimitCount = input.LimitCount
+	rule.TimeWindow = input.TimeWindow
+	rule.TargetPath = input.TargetPath
+	rule.TargetIP = input.TargetIP
+	rule.Action = action
+	rule.RedirectURL = input.RedirectURL
+	if input.Enabled {
+		rule.Status = 1
+	} else {
+		rule.Status = 0
+	}
+	return nil
+}
+
+func (s *gatewayService) getAccessRule(userID, gatewayID, ruleID uint) (*model.AppGatewayAccessRule, error) {
+	if _, err := s.getOwned(userID, gatewayID); err != nil {
+		return nil, err
+	}
+
+	rule, err := s.gatewayRepo.GetAccessRule(ruleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if rule.GatewayID != gatewayID {
+		return nil, ErrNotFound
+	}
+	return rule, nil
+}
+
+func (s *gatewayService) getOwned(userID, id uint) (*model.AppGateway, error) {
+	gateway, err := s.gatewayRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if gateway.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return gateway, nil
+}
+
+func (s *gatewayService) getPublish(userID, gatewayID, publishID uint) (*model.AppGatewayPublish, error) {
+	if _, err := s.getOwned(userID, gatewayID); err != nil {
+		return nil, err
+	}
+
+	publish, err := s.gatewayRepo.GetPublish(publishID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if publish.AppGatewayID != gatewayID {
+		return nil, ErrNotFound
+	}
+	return publish, nil
+}
+
+// PublishUpstream 计算发布规则的转发地址(host:port)，需预加载 AppInstance.Server 与 AppGateway
+// 与网关同一服务器上的实例走本机回环地址，其余优先使用内网地址
+func PublishUpstream(publish *model.AppGatewayPublish) (string, error) {
+	host := publish.UpstreamHost
+	if host != "" && !isServerAddress(&publish.AppInstance.Server, host) {
+		return "", fmt.Errorf("upstream host %s is not an address of server %d", host, publish.AppInstance.ServerID)
+	}
+	if host == "" {
+		server := publish.AppInstance.Server
+		switch {
+		case publish.AppInstance.ServerID == publish.AppGateway.ServerID:
+			host = "127.0.0.1"
+		case server.InternalIP != "":
+			host = server.InternalIP
+		default:
+			host = server.IPAddress
+		}
+	}
+	if host == "" {
+		return "", invalidInputf("no upstream address for app instance %d", publish.AppInstanceID)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(publish.ServicePort)), nil
+}
+
+// isServerAddress 判断 host 是否为服务器的公网 IP、内网 IP 或主机名
+func isServerAddress(server *model.Server, host string) bool {
+	for _, address := range []string{server.IPAddress, server.InternalIP, server.Hostname} {
+		if address != "" && strings.EqualFold(address, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyReload 通知网关进程重新加载路由，失败时由网关轮询兜底
+func (s *gatewayService) notifyReload(gatewayID uint) {
+	err := s.rdb.Publish(context.Background(), s.reloadChannel, strconv.FormatUint(uint64(gatewayID), 10)).Err()
+	if err != nil {
+		log.Printf("Failed to notify gateway %d reload: %v", gatewayID, err)
+	}
+}
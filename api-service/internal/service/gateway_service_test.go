@@ -0,0 +1,41 @@
+package service
+
+import (
+	"api-service/internal/model"
+	"testing"
+)
+
+func TestPublishUpstream(t *testing.T) {
+	server := model.Server{ID: 2, Hostname: "app-host", IPAddress: "203.0.113.10", InternalIP: "10.0.0.2"}
+	newPublish := func(upstreamHost string, gatewayServerID uint) *model.AppGatewayPublish {
+		return &model.AppGatewayPublish{
+			ServicePort:  8080,
+			UpstreamHost: upstreamHost,
+			AppInstance:  model.AppInstance{ServerID: server.ID, Server: server},
+			AppGateway:   model.AppGateway{ServerID: gatewayServerID},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		publish *model.AppGatewayPublish
+		want    string
+		wantErr bool
+	}{
+		{"same server", newPublish("", 2), "127.0.0.1:8080", false},
+		{"internal address", newPublish("", 1), "10.0.0.2:8080", false},
+		{"server public address", newPublish("203.0.113.10", 1), "203.0.113.10:8080", false},
+		{"server hostname", newPublish("APP-HOST", 1), "APP-HOST:8080", false},
+		{"loopback", newPublish("127.0.0.1", 1), "", true},
+		{"metadata address", newPublish("169.254.169.254", 1), "", true},
+		{"other host", newPublish("internal.example.com", 1), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PublishUpstream(tt.publish)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("PublishUpstream() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
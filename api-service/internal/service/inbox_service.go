@@ -0,0 +1,360 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// InboxService 站内通知收件箱与实时推送
+// 新通知与未读数变化发布到 Redis 频道，每个实例订阅后推送给本实例上建立了推送连接的用户
+type InboxService interface {
+	List(userID uint, query *InboxQuery, page, pageSize int) ([]*model.UserNotification, int64, error)
+	UnreadCount(userID uint) (int64, error)
+	MarkRead(userID uint, ids []uint) (int64, error)
+	MarkAllRead(userID uint) (int64, error)
+	Delete(userID, id uint) error
+
+	// Publish 推送新创建的站内通知
+	Publish(notification *model.Notification, inbox []*model.UserNotification)
+	// IssueStreamTicket 签发建立推送连接使用的一次性凭证，浏览器的 EventSource 无法携带 Authorization 头
+	IssueStreamTicket(userID uint) (string, error)
+	RedeemStreamTicket(ticket string) (uint, error)
+	// Subscribe 订阅用户的推送事件，afterID 不为 0 时先补发该ID之后的站内通知
+	Subscribe(userID, afterID uint) (*InboxSubscription, error)
+	Start(ctx context.Context)
+}
+
+// InboxQuery 站内通知查询条件
+type InboxQuery struct {
+	Unread bool
+	Kind   string
+	Level  string
+}
+
+// InboxEvent 推送事件，notification 事件的 ID 为站内通知ID
+type InboxEvent struct {
+	Type        string                  `json:"type"`
+	ID          uint                    `json:"-"`
+	Item        *model.UserNotification `json:"item,omitempty"`
+	UnreadCount *int64                  `json:"unread_count,omitempty"`
+}
+
+// InboxSubscription 推送订阅，接收过慢时 Events 被关闭，客户端应重新连接
+type InboxSubscription struct {
+	Events <-chan *InboxEvent
+	Close  func()
+}
+
+// inboxMessage 实例间转发的推送消息
+type inboxMessage struct {
+	Type         string              `json:"type"`
+	Notification *model.Notification `json:"notification,omitempty"`
+	Recipients   map[uint]uint       `json:"recipients,omitempty"` // 用户ID -> 站内通知ID
+	UserID       uint                `json:"user_id,omitempty"`
+	UnreadCount  int64               `json:"unread_count,omitempty"`
+}
+
+type inboxSubscriber struct {
+	events chan *InboxEvent
+	closed bool
+}
+
+type inboxService struct {
+	notificationRepo repository.NotificationRepository
+	rdb              *redis.Client
+	channel          string
+
+	mu          sync.Mutex
+	subscribers map[uint]map[*inboxSubscriber]struct{}
+}
+
+func NewInboxService(notificationRepo repository.NotificationRepository, rdb *redis.Client, channel string) InboxService {
+	return &inboxService{
+		notificationRepo: notificationRepo,
+		rdb:              rdb,
+		channel:          channel,
+		subscribers:      make(map[uint]map[*inboxSubscriber]struct{}),
+	}
+}
+
+func (s *inboxService) List(userID uint, query *InboxQuery, page, pageSize int) ([]*model.UserNotification, int64, error) {
+	offset := (page - 1) * pageSize
+	return s.notificationRepo.ListInbox(userID, &repository.InboxFilter{
+		Unread: query.Unread,
+		Kind:   query.Kind,
+		Level:  query.Level,
+	}, offset, pageSize)
+}
+
+func (s *inboxService) UnreadCount(userID uint) (int64, error) {
+	return s.notificationRepo.CountUnread(userID)
+}
+
+func (s *inboxService) MarkRead(userID uint, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, invalidInputf("ids is required")
+	}
+	n, err := s.notificationRepo.MarkRead(userID, ids, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		s.publishUnreadCount(userID)
+	}
+	return n, nil
+}
+
+func (s *inboxService) MarkAllRead(userID uint) (int64, error) {
+	n, err := s.notificationRepo.MarkAllRead(userID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		s.publishUnreadCount(userID)
+	}
+	return n, nil
+}
+
+func (s *inboxService) Delete(userID, id uint) error {
+	if err := s.notificationRepo.DeleteInbox(userID, id, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	s.publishUnreadCount(userID)
+	return nil
+}
+
+func (s *inboxService) Publish(notification *model.Notification, inbox []*model.UserNotification) {
+	if len(inbox) == 0 {
+		return
+	}
+	recipients := make(map[uint]uint, len(inbox))
+	for _, item := range inbox {
+		recipients[item.UserID] = item.ID
+	}
+	s.publish(&inboxMessage{
+		Type:         constants.InboxEventNotification,
+		Notification: notification,
+		Recipients:   recipients,
+	})
+}
+
+func (s *inboxService) IssueStreamTicket(userID uint) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	ticket := hex.EncodeToString(buf)
+	err := s.rdb.Set(context.Background(), constants.NotificationStreamTicketKey+ticket,
+		strconv.FormatUint(uint64(userID), 10), constants.NotificationStreamTicketTTL).Err()
+	if err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// RedeemStreamTicket 校验并作废凭证，返回签发凭证的用户
+func (s *inboxService) RedeemStreamTicket(ticket string) (uint, error) {
+	value, err := s.rdb.GetDel(context.Background(), constants.NotificationStreamTicketKey+ticket).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, invalidInputf("invalid or expired stream ticket")
+	}
+	if err != nil {
+		return 0, err
+	}
+	userID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(userID), nil
+}
+
+func (s *inboxService) Subscribe(userID, afterID uint) (*InboxSubscription, error) {
+	sub := &inboxSubscriber{events: make(chan *InboxEvent, constants.NotificationStreamBuffer)}
+
+	// 先注册订阅再补发，补发期间到达的新通知可能重复，客户端按ID去重
+	s.mu.Lock()
+	if s.subscribers[userID] == nil {
+		s.subscribers[userID] = make(map[*inboxSubscriber]struct{})
+	}
+	s.subscribers[userID][sub] = struct{}{}
+	s.mu.Unlock()
+
+	var missed []*model.UserNotification
+	if afterID != 0 {
+		var err error
+		missed, err = s.notificationRepo.ListInboxAfter(userID, afterID, constants.NotificationStreamReplayLimit)
+		if err != nil {
+			s.unsubscribe(userID, sub)
+			return nil, err
+		}
+	}
+	unread, err := s.notificationRepo.CountUnread(userID)
+	if err != nil {
+		s.unsubscribe(userID, sub)
+		return nil, err
+	}
+
+	events := make(chan *InboxEvent, len(missed)+1)
+	for _, item := range missed {
+		events <- &InboxEvent{Type: constants.InboxEventNotification, ID: item.ID, Item: item}
+	}
+	events <- &InboxEvent{Type: constants.InboxEventUnreadCount, UnreadCount: &unread}
+	close(events)
+
+	// 合并补发事件与实时事件
+	out := make(chan *InboxEvent)
+	done := make(chan struct{})
+	go func() {
+		defer close(out)
+		for _, ch := range []chan *InboxEvent{events, sub.events} {
+			for event := range ch {
+				select {
+				case out <- event:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return &InboxSubscription{
+		Events: out,
+		Close: func() {
+			once.Do(func() {
+				close(done)
+				s.unsubscribe(userID, sub)
+			})
+		},
+	}, nil
+}
+
+// Start 订阅推送频道，直到 ctx 结束
+func (s *inboxService) Start(ctx context.Context) {
+	go func() {
+		pubsub := s.rdb.Subscribe(ctx, s.channel)
+		defer pubsub.Close()
+
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				var message inboxMessage
+				if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+					log.Printf("Invalid inbox message: %v", err)
+					continue
+				}
+				s.dispatch(&message)
+			}
+		}
+	}()
+}
+
+func (s *inboxService) publishUnreadCount(userID uint) {
+	count, err := s.notificationRepo.CountUnread(userID)
+	if err != nil {
+		log.Printf("Failed to count unread notifications of user %d: %v", userID, err)
+		return
+	}
+	s.publish(&inboxMessage{Type: constants.InboxEventUnreadCount, UserID: userID, UnreadCount: count})
+}
+
+// publish 发布到 Redis 频道，发布失败时只推送给本实例的订阅者
+func (s *inboxService) publish(message *inboxMessage) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Failed to encode inbox message: %v", err)
+		return
+	}
+	if err := s.rdb.Publish(context.Background(), s.channel, payload).Err(); err != nil {
+		log.Printf("Failed to publish inbox message: %v", err)
+		s.dispatch(message)
+	}
+}
+
+// dispatch 将消息推送给本实例上的订阅者
+func (s *inboxService) dispatch(message *inboxMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch message.Type {
+	case constants.InboxEventNotification:
+		if message.Notification == nil {
+			return
+		}
+		for userID, subs := range s.subscribers {
+			id, ok := message.Recipients[userID]
+			if !ok {
+				continue
+			}
+			item := &model.UserNotification{
+				ID:             id,
+				NotificationID: message.Notification.ID,
+				Notification:   *message.Notification,
+				UserID:         userID,
+				CreatedAt:      message.Notification.CreatedAt,
+			}
+			s.send(userID, subs, &InboxEvent{Type: constants.InboxEventNotification, ID: id, Item: item})
+		}
+	case constants.InboxEventUnreadCount:
+		if subs := s.subscribers[message.UserID]; subs != nil {
+			count := message.UnreadCount
+			s.send(message.UserID, subs, &InboxEvent{Type: constants.InboxEventUnreadCount, UnreadCount: &count})
+		}
+	}
+}
+
+// send 非阻塞推送，订阅者缓冲区已满时关闭其事件通道，需持有 s.mu
+func (s *inboxService) send(userID uint, subs map[*inboxSubscriber]struct{}, event *InboxEvent) {
+	for sub := range subs {
+		select {
+		case sub.events <- event:
+		default:
+			log.Printf("Inbox subscriber of user %d is too slow, closing stream", userID)
+			sub.closed = true
+			close(sub.events)
+			delete(subs, sub)
+		}
+	}
+	if len(subs) == 0 {
+		delete(s.subscribers, userID)
+	}
+}
+
+func (s *inboxService) unsubscribe(userID uint, sub *inboxSubscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.events)
+	if subs := s.subscribers[userID]; subs != nil {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(s.subscribers, userID)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"strings"
+)
+
+// LogService 工作流执行与应用部署的分段日志
+// Agent 推送的每批任务输出保存为一个分段，系统日志每行一个分段；分段ID单调递增，既是分页游标也是实时日志的续传位置
+type LogService interface {
+	// AppendLine 追加一行系统日志
+	AppendLine(targetType string, targetID uint, line string) error
+	// AppendTaskLog 保存 Agent 推送的一批任务输出，多个实例收到同一批时只保存一次
+	AppendTaskLog(targetType string, targetID uint, stream string, taskLog *AgentTaskLog) error
+	// AppendTaskOutput 任务没有推送实时日志时(如旧版 Agent)，以任务结果中的完整输出作为日志
+	AppendTaskOutput(targetType string, targetID uint, stream, taskID, output string) error
+	List(targetType string, targetID, afterID uint, limit int) ([]*model.LogSegment, error)
+}
+
+// LogPage 一页日志分段，NextAfter 为下一页的 after 参数；Finished 表示目标已结束，读完后日志不会再增加
+type LogPage struct {
+	Segments  []*model.LogSegment `json:"segments"`
+	NextAfter uint                `json:"next_after"`
+	Status    string              `json:"status"`
+	Finished  bool                `json:"finished"`
+}
+
+type logService struct {
+	logRepo repository.LogRepository
+}
+
+func NewLogService(logRepo repository.LogRepository) LogService {
+	return &logService{logRepo: logRepo}
+}
+
+func (s *logService) AppendLine(targetType string, targetID uint, line string) error {
+	return s.logRepo.Append(&model.LogSegment{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Content:    strings.TrimSuffix(line, "\n") + "\n",
+		Lines:      1,
+	})
+}
+
+func (s *logService) AppendTaskLog(targetType string, targetID uint, stream string, taskLog *AgentTaskLog) error {
+	// Seq 0 保留给任务结果中的完整输出
+	if taskLog.Seq < 1 {
+		return nil
+	}
+	taskID := taskLog.TaskID
+	return s.logRepo.Append(&model.LogSegment{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Stream:     stream,
+		TaskID:     &taskID,
+		Seq:        taskLog.Seq,
+		Content:    strings.Join(taskLog.Lines, "\n") + "\n",
+		Lines:      len(taskLog.Lines),
+	})
+}
+
+func (s *logService) AppendTaskOutput(targetType string, targetID uint, stream, taskID, output string) error {
+	if output == "" {
+		return nil
+	}
+	streamed, err := s.logRepo.HasTaskLog(taskID)
+	if err != nil || streamed {
+		return err
+	}
+	output = strings.TrimSuffix(output, "\n") + "\n"
+	return s.logRepo.Append(&model.LogSegment{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Stream:     stream,
+		TaskID:     &taskID,
+		Content:    output,
+		Lines:      strings.Count(output, "\n"),
+	})
+}
+
+func (s *logService) List(targetType string, targetID, afterID uint, limit int) ([]*model.LogSegment, error) {
+	if limit <= 0 {
+		limit = constants.DefaultLogPageSize
+	}
+	if limit > constants.MaxLogPageSize {
+		limit = constants.MaxLogPageSize
+	}
+	return s.logRepo.List(targetType, targetID, afterID, limit)
+}
+
+// newLogPage 组装日志分页，没有新分段时 NextAfter 保持为 afterID
+func newLogPage(segments []*model.LogSegment, afterID uint, status string, finished bool) *LogPage {
+	page := &LogPage{Segments: segments, NextAfter: afterID, Status: status, Finished: finished}
+	if len(segments) > 0 {
+		page.NextAfter = segments[len(segments)-1].ID
+	}
+	return page
+}
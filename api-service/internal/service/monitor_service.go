@@ -1,76 +1,377 @@
 package service
 
 import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/histogram"
+	"api-service/pkg/metricstore"
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
-	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
-	"github.com/influxdata/influxdb-client-go/v2/api"
+	"gorm.io/gorm"
 )
 
 type MonitorService interface {
 	WriteMetrics(measurement string, tags map[string]string, fields map[string]interface{}) error
-	QueryMetrics(query string) ([]map[string]interface{}, error)
-	GetServerMetrics(serverID string, timeRange string) ([]map[string]interface{}, error)
-	GetApplicationMetrics(appID string, timeRange string) ([]map[string]interface{}, error)
+	WriteMetricsAt(measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time) error
+	Flush()
+	GetServerMetrics(userID, serverID uint, query *MetricsQuery) (*TimeSeries, error)
+	GetApplicationMetrics(userID, appID uint, query *MetricsQuery) (*TimeSeries, error)
+	GetGatewayTraffic(gatewayID uint, domain string, start, end time.Time, top int) (*GatewayTraffic, error)
+	IngestAgentMetrics(agent *model.ServerAgent, points []*AgentMetricPoint) (int, error)
 }
 
-type monitorService struct {
-	influxClient influxdb2.Client
-	writeAPI     api.WriteAPI
-	queryAPI     api.QueryAPI
+// MetricsQuery 监控指标查询参数，Groups 为空时查询全部分组，Window 为 0 时按时间范围自动选择聚合窗口
+type MetricsQuery struct {
+	Start  time.Time
+	End    time.Time
+	Groups []string
+	Window time.Duration
+}
+
+// TimeSeries 图表使用的时序数据，各序列的时间点按聚合窗口对齐，无数据的窗口值为 null
+type TimeSeries struct {
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	WindowSeconds int64     `json:"window_seconds"`
+	Series        []*Series `json:"series"`
+}
+
+// Series 单个指标字段的时序
+type Series struct {
+	Field  string   `json:"field"`
+	Points []*Point `json:"points"`
+}
+
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value *float64  `json:"value"`
+}
+
+// GatewayTraffic 网关单个域名在时间范围内的流量统计
+type GatewayTraffic struct {
+	Domain        string           `json:"domain"`
+	Start         time.Time        `json:"start"`
+	End           time.Time        `json:"end"`
+	TotalRequests int64            `json:"total_requests"`
+	TotalBytes    int64            `json:"total_bytes"`
+	StatusCodes   map[string]int64 `json:"status_codes"`
+	TopPaths      []*PathTraffic   `json:"top_paths"`
+	Latency       LatencySummary   `json:"latency"`
+}
+
+// PathTraffic 单个路径的请求统计
+type PathTraffic struct {
+	Path         string  `json:"path"`
+	Requests     int64   `json:"requests"`
+	Bytes        int64   `json:"bytes"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// LatencySummary 由延迟直方图估算的分位数(毫秒)，无数据时为 nil
+type LatencySummary struct {
+	P50 *float64 `json:"p50"`
+	P95 *float64 `json:"p95"`
+	P99 *float64 `json:"p99"`
 }
 
-func NewMonitorService(influxClient influxdb2.Client) MonitorService {
-	writeAPI := influxClient.WriteAPI("websoft9", "metrics")
-	queryAPI := influxClient.QueryAPI("websoft9")
+type monitorService struct {
+	store      metricstore.Store
+	serverRepo repository.ServerRepository
+	appRepo    repository.ApplicationRepository
+}
 
+func NewMonitorService(store metricstore.Store, serverRepo repository.ServerRepository,
+	appRepo repository.ApplicationRepository) MonitorService {
 	return &monitorService{
-		influxClient: influxClient,
-		writeAPI:     writeAPI,
-		queryAPI:     queryAPI,
+		store:      store,
+		serverRepo: serverRepo,
+		appRepo:    appRepo,
 	}
 }
 
 func (s *monitorService) WriteMetrics(measurement string, tags map[string]string, fields map[string]interface{}) error {
-	p := influxdb2.NewPoint(measurement, tags, fields, time.Now())
-	s.writeAPI.WritePoint(p)
-	return nil
+	return s.store.Write(measurement, tags, fields, time.Now())
+}
+
+// WriteMetricsAt 按指定时间写入指标，用于按时间窗口汇总后的数据
+func (s *monitorService) WriteMetricsAt(measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time) error {
+	return s.store.Write(measurement, tags, fields, ts)
+}
+
+// Flush 立即写出缓冲区中的指标
+func (s *monitorService) Flush() {
+	s.store.Flush()
+}
+
+// IngestAgentMetrics 写入 Agent 上报的服务器与容器指标，返回写入的数据点数
+// server_id、agent_id 以 Agent 的注册信息为准；容器指标按 Compose 项目名或容器名关联到服务器上的同名应用
+func (s *monitorService) IngestAgentMetrics(agent *model.ServerAgent, points []*AgentMetricPoint) (int, error) {
+	if len(points) > constants.AgentMaxMetricPoints {
+		return 0, fmt.Errorf("too many points in one batch: %d > %d", len(points), constants.AgentMaxMetricPoints)
+	}
+
+	apps, err := s.appRepo.GetByServerID(agent.ServerID)
+	if err != nil {
+		return 0, err
+	}
+	appIDs := make(map[string]uint, len(apps))
+	for _, app := range apps {
+		appIDs[app.Name] = app.ID
+	}
+
+	now := time.Now()
+	accepted := 0
+	for _, p := range points {
+		if p == nil || len(p.Fields) == 0 {
+			continue
+		}
+		if p.Measurement != constants.MeasurementServerMetrics && p.Measurement != constants.MeasurementAppMetrics {
+			continue
+		}
+		ts := p.Time
+		if ts.IsZero() {
+			ts = now
+		} else if ts.After(now.Add(constants.AgentMessageMaxSkew)) {
+			continue
+		}
+
+		tags := make(map[string]string, len(p.Tags)+3)
+		for k, v := range p.Tags {
+			tags[k] = v
+		}
+		delete(tags, constants.MetricTagAppID)
+		tags[constants.MetricTagServerID] = fmt.Sprint(agent.ServerID)
+		tags[constants.MetricTagAgentID] = agent.AgentID
+		if p.Measurement == constants.MeasurementAppMetrics {
+			if id, ok := appIDs[tags[constants.MetricTagComposeProject]]; ok {
+				tags[constants.MetricTagAppID] = fmt.Sprint(id)
+			} else if id, ok := appIDs[tags[constants.MetricTagContainerName]]; ok {
+				tags[constants.MetricTagAppID] = fmt.Sprint(id)
+			}
+		}
+
+		fields := make(map[string]interface{}, len(p.Fields))
+		for k, v := range p.Fields {
+			fields[k] = v
+		}
+		if err := s.store.Write(p.Measurement, tags, fields, ts); err != nil {
+			return accepted, err
+		}
+		accepted++
+	}
+	return accepted, nil
+}
+
+// GetServerMetrics 查询服务器的监控指标
+func (s *monitorService) GetServerMetrics(userID, serverID uint, query *MetricsQuery) (*TimeSeries, error) {
+	server, err := s.serverRepo.GetByID(serverID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if server.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return s.queryTimeSeries(constants.MeasurementServerMetrics, constants.MetricTagServerID, serverID, query)
+}
+
+// GetApplicationMetrics 查询应用的监控指标，应用归属于其所在服务器的所有者
+func (s *monitorService) GetApplicationMetrics(userID, appID uint, query *MetricsQuery) (*TimeSeries, error) {
+	app, err := s.appRepo.GetByID(appID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if app.Server.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return s.queryTimeSeries(constants.MeasurementAppMetrics, constants.MetricTagAppID, appID, query)
 }
 
-func (s *monitorService) QueryMetrics(query string) ([]map[string]interface{}, error) {
-	result, err := s.queryAPI.Query(context.Background(), query)
+// queryTimeSeries 按窗口聚合指标，tag 为区分服务器或应用的标签名
+func (s *monitorService) queryTimeSeries(measurement, tag string, id uint, query *MetricsQuery) (*TimeSeries, error) {
+	fields, window, err := validateMetricsQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err := s.store.Aggregate(context.Background(), &metricstore.Query{
+		Measurement: measurement,
+		Tags:        map[string]string{tag: fmt.Sprint(id)},
+		Fields:      fields,
+		Start:       query.Start,
+		End:         query.End,
+	}, window)
 	if err != nil {
 		return nil, err
 	}
 
-	var data []map[string]interface{}
-	for result.Next() {
-		record := result.Record()
-		data = append(data, map[string]interface{}{
-			"time":  record.Time(),
-			"value": record.Value(),
-			"field": record.Field(),
-		})
+	ts := &TimeSeries{
+		Start:         query.Start,
+		End:           query.End,
+		WindowSeconds: int64(window / time.Second),
+		Series:        make([]*Series, 0, len(fields)),
+	}
+	byField := make(map[string]*Series, len(fields))
+	for _, field := range fields {
+		series := &Series{Field: field, Points: []*Point{}}
+		byField[field] = series
+		ts.Series = append(ts.Series, series)
 	}
 
-	return data, nil
+	for _, sample := range samples {
+		series, ok := byField[sample.Field]
+		if !ok {
+			continue
+		}
+		series.Points = append(series.Points, &Point{Time: sample.Time, Value: sample.Value})
+	}
+	return ts, nil
 }
 
-func (s *monitorService) GetServerMetrics(serverID, timeRange string) ([]map[string]interface{}, error) {
-	query := `from(bucket: "metrics")
-		|> range(start: ` + timeRange + `)
-		|> filter(fn: (r) => r["_measurement"] == "server_metrics")
-		|> filter(fn: (r) => r["server_id"] == "` + serverID + `")`
+// validateMetricsQuery 校验时间范围与指标分组，返回查询字段与聚合窗口
+func validateMetricsQuery(query *MetricsQuery) ([]string, time.Duration, error) {
+	span := query.End.Sub(query.Start)
+	if span <= 0 {
+		return nil, 0, invalidInputf("start must be before end")
+	}
+	if span > constants.MaxMetricsRange {
+		return nil, 0, invalidInputf("time range must not exceed %s", constants.MaxMetricsRange)
+	}
+
+	groups := query.Groups
+	if len(groups) == 0 {
+		groups = constants.MetricGroups
+	}
+	var fields []string
+	seen := make(map[string]bool)
+	for _, group := range groups {
+		groupFields, ok := constants.MetricGroupFields[group]
+		if !ok {
+			return nil, 0, invalidInputf("unknown metric group: %s", group)
+		}
+		if seen[group] {
+			continue
+		}
+		seen[group] = true
+		fields = append(fields, groupFields...)
+	}
+
+	window := query.Window
+	if window == 0 {
+		window = metricsWindow(span)
+	} else if window%time.Second != 0 {
+		return nil, 0, invalidInputf("window must be whole seconds")
+	} else if window < constants.MinMetricsWindow {
+		return nil, 0, invalidInputf("window must be at least %s", constants.MinMetricsWindow)
+	} else if span/window > constants.MaxMetricsPoints {
+		return nil, 0, invalidInputf("window %s yields more than %d points for this range", window, constants.MaxMetricsPoints)
+	}
+	return fields, window, nil
+}
 
-	return s.QueryMetrics(query)
+// metricsWindow 选择使数据点不超过 MaxMetricsPoints 的最小窗口
+func metricsWindow(span time.Duration) time.Duration {
+	for _, window := range constants.MetricsWindows {
+		if span/window <= constants.MaxMetricsPoints {
+			return window
+		}
+	}
+	return constants.MetricsWindows[len(constants.MetricsWindows)-1]
 }
 
-func (s *monitorService) GetApplicationMetrics(appID, timeRange string) ([]map[string]interface{}, error) {
-	query := `from(bucket: "metrics")
-		|> range(start: ` + timeRange + `)
-		|> filter(fn: (r) => r["_measurement"] == "app_metrics")
-		|> filter(fn: (r) => r["app_id"] == "` + appID + `")`
+// GetGatewayTraffic 汇总网关域名的请求数、状态码分布、热门路径与延迟分位数，只统计该网关记录的样本
+func (s *monitorService) GetGatewayTraffic(gatewayID uint, domain string, start, end time.Time, top int) (*GatewayTraffic, error) {
+	traffic := &GatewayTraffic{
+		Domain:      domain,
+		Start:       start,
+		End:         end,
+		StatusCodes: make(map[string]int64),
+	}
 
-	return s.QueryMetrics(query)
+	// 域名可能先后发布在不同网关上，按 gateway_id 过滤以免返回其他网关的样本
+	tags := map[string]string{
+		"gateway_id": strconv.FormatUint(uint64(gatewayID), 10),
+		"domain":     domain,
+	}
+	q := &metricstore.Query{
+		Measurement: constants.MeasurementGatewayRequests,
+		Tags:        tags,
+		Start:       start,
+		End:         end,
+	}
+	rows, err := s.store.Sum(context.Background(), q, []string{"path", "status"})
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]*PathTraffic)
+	latencySums := make(map[string]float64)
+	for _, row := range rows {
+		path, status := row.Tags["path"], row.Tags["status"]
+
+		p, ok := paths[path]
+		if !ok {
+			p = &PathTraffic{Path: path}
+			paths[path] = p
+		}
+
+		switch row.Field {
+		case "requests":
+			n := int64(row.Value)
+			p.Requests += n
+			traffic.TotalRequests += n
+			traffic.StatusCodes[status] += n
+		case "bytes":
+			n := int64(row.Value)
+			p.Bytes += n
+			traffic.TotalBytes += n
+		case "latency_ms_sum":
+			latencySums[path] += row.Value
+		}
+	}
+
+	traffic.TopPaths = make([]*PathTraffic, 0, len(paths))
+	for path, p := range paths {
+		if p.Requests > 0 {
+			p.AvgLatencyMs = latencySums[path] / float64(p.Requests)
+		}
+		traffic.TopPaths = append(traffic.TopPaths, p)
+	}
+	sort.Slice(traffic.TopPaths, func(i, j int) bool {
+		if traffic.TopPaths[i].Requests != traffic.TopPaths[j].Requests {
+			return traffic.TopPaths[i].Requests > traffic.TopPaths[j].Requests
+		}
+		return traffic.TopPaths[i].Path < traffic.TopPaths[j].Path
+	})
+	if top > 0 && len(traffic.TopPaths) > top {
+		traffic.TopPaths = traffic.TopPaths[:top]
+	}
+
+	q.Measurement = constants.MeasurementGatewayLatency
+	rows, err = s.store.Sum(context.Background(), q, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	h := histogram.New(constants.GatewayLatencyBuckets)
+	for _, row := range rows {
+		h.Add(row.Field, int64(row.Value))
+	}
+
+	if h.Total() > 0 {
+		p50, p95, p99 := h.Quantile(0.5), h.Quantile(0.95), h.Quantile(0.99)
+		traffic.Latency = LatencySummary{P50: &p50, P95: &p95, P99: &p99}
+	}
+	return traffic, nil
 }
@@ -0,0 +1,691 @@
+package service
+
+import (
+	"api-service/internal/config"
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/notifier"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NotificationService 渲染通知模板并通过站内通知与配置的渠道发送
+// 每个渠道的每个接收者生成一条投递记录，由后台任务投递，失败后按指数退避重试，重启后继续
+type NotificationService interface {
+	Send(req *NotificationRequest) (*model.Notification, error)
+	Channels() []*NotificationChannel
+	ValidateChannels(names []string) error
+	TestChannel(name, recipient string) error
+
+	CreateTemplate(input *NotificationTemplateInput) (*model.NotificationTemplate, error)
+	GetTemplate(id uint) (*model.NotificationTemplate, error)
+	ListTemplates() ([]*model.NotificationTemplate, error)
+	UpdateTemplate(id uint, input *NotificationTemplateInput) (*model.NotificationTemplate, error)
+	DeleteTemplate(id uint) error
+
+	ListRecords(query *NotificationRecordQuery, page, pageSize int) ([]*model.NotificationRecord, int64, error)
+	Start(ctx context.Context)
+}
+
+// NotificationRequest 通知参数
+// Template 为模板名称，设置时由模板与 Variables 渲染标题和内容，否则直接使用 Title 与 Content
+// 接收者为 TargetType 为 USER、GROUP 时 TargetIDs 指定的用户或用户组中的启用用户，ALL 为全部启用用户
+type NotificationRequest struct {
+	Kind          string // SYSTEM, USER, ALERT，默认 SYSTEM
+	Level         string // 默认 INFO
+	SenderID      *uint
+	TargetType    string
+	TargetIDs     []uint
+	Channels      []string
+	Template      string
+	Variables     map[string]interface{}
+	Title         string
+	Content       string
+	ReferenceType string
+	ReferenceID   string
+}
+
+// NotificationChannel 已配置的渠道
+type NotificationChannel struct {
+	Name   string `json:"name"`
+	Driver string `json:"driver"`
+	Type   string `json:"type"`
+}
+
+// NotificationTemplateInput 模板参数，Variables 为变量名到类型(string、number、bool、time)的映射
+type NotificationTemplateInput struct {
+	Name      string
+	Type      string
+	Subject   string
+	Content   string
+	Variables map[string]string
+	Enabled   bool
+}
+
+// NotificationRecordQuery 投递记录查询条件
+type NotificationRecordQuery struct {
+	Status         string
+	Channel        string
+	NotificationID uint
+}
+
+type notificationChannel struct {
+	driver string
+	sender notifier.Sender
+}
+
+type notificationService struct {
+	notificationRepo repository.NotificationRepository
+	userRepo         repository.UserRepository
+	inbox            InboxService
+	channels         map[string]*notificationChannel
+	consoleURL       string
+	maxAttempts      int
+	wake             chan struct{}
+}
+
+func NewNotificationService(notificationRepo repository.NotificationRepository, userRepo repository.UserRepository,
+	inbox InboxService, cfg config.NotificationConfig) (NotificationService, error) {
+	s := &notificationService{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		inbox:            inbox,
+		channels:         make(map[string]*notificationChannel, len(cfg.Channels)),
+		consoleURL:       strings.TrimRight(cfg.ConsoleURL, "/"),
+		maxAttempts:      cfg.MaxAttempts,
+		wake:             make(chan struct{}, 1),
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = constants.DefaultNotificationMaxAttempts
+	}
+	if s.consoleURL != "" {
+		if u, err := url.Parse(s.consoleURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid notification console_url: %s", cfg.ConsoleURL)
+		}
+	}
+
+	for name, options := range cfg.Channels {
+		sender, err := notifier.New(options["driver"], options)
+		if err != nil {
+			return nil, fmt.Errorf("notification channel %s: %w", name, err)
+		}
+		s.channels[name] = &notificationChannel{driver: options["driver"], sender: sender}
+	}
+	return s, nil
+}
+
+// Send 创建通知、站内通知与各渠道的投递记录，投递由后台任务异步完成
+func (s *notificationService) Send(req *NotificationRequest) (*model.Notification, error) {
+	if err := s.ValidateChannels(req.Channels); err != nil {
+		return nil, err
+	}
+	kind, level := req.Kind, req.Level
+	if kind == "" {
+		kind = constants.NotificationKindSystem
+	}
+	if level == "" {
+		level = constants.NotificationLevelInfo
+	}
+
+	users, err := s.resolveRecipients(req.TargetType, req.TargetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var variants []*model.NotificationTemplate
+	if req.Template != "" {
+		if variants, err = s.notificationRepo.ListTemplatesByName(req.Template); err != nil {
+			return nil, err
+		}
+		if len(variants) == 0 {
+			return nil, invalidInputf("notification template %q not found", req.Template)
+		}
+	}
+
+	// 站内通知使用 PUSH 类型的模板
+	base := pickTemplate(variants, constants.NotificationTypePush)
+	title, content, err := renderNotification(base, req)
+	if err != nil {
+		return nil, err
+	}
+
+	targetIDs, err := json.Marshal(nonNil(req.TargetIDs))
+	if err != nil {
+		return nil, err
+	}
+	channels, err := json.Marshal(nonNil(req.Channels))
+	if err != nil {
+		return nil, err
+	}
+	vars := req.Variables
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+	variables, err := json.Marshal(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	notification := &model.Notification{
+		Title:      title,
+		Content:    content,
+		Type:       kind,
+		Level:      level,
+		SenderID:   req.SenderID,
+		TargetType: req.TargetType,
+		TargetIDs:  string(targetIDs),
+		Channels:   string(channels),
+		Variables:  string(variables),
+		Status:     constants.NotificationStatusPending,
+	}
+	if base != nil {
+		notification.TemplateID = &base.ID
+	}
+
+	now := time.Now()
+	var records []*model.NotificationRecord
+	for _, name := range req.Channels {
+		channel := s.channels[name]
+		template := pickTemplate(variants, channel.sender.Type())
+		subject, body, err := renderNotification(template, req)
+		if err != nil {
+			return nil, fmt.Errorf("channel %s: %w", name, err)
+		}
+
+		for _, recipient := range channelRecipients(channel.sender.Type(), name, users) {
+			record := &model.NotificationRecord{
+				Channel:       name,
+				Type:          channel.sender.Type(),
+				Recipient:     recipient,
+				Subject:       subject,
+				Content:       body,
+				Status:        constants.NotificationStatusPending,
+				NextRetryAt:   &now,
+				ReferenceType: req.ReferenceType,
+				ReferenceID:   req.ReferenceID,
+			}
+			if template != nil {
+				record.TemplateID = &template.ID
+			}
+			if recipient == "" {
+				// 用户未填写邮箱或手机号，记录为失败便于排查
+				record.Status = constants.NotificationStatusFailed
+				record.ErrorMsg = fmt.Sprintf("recipient has no %s address", channel.sender.Type())
+				record.NextRetryAt = nil
+			}
+			records = append(records, record)
+		}
+	}
+
+	userIDs := make([]uint, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+	inbox, err := s.notificationRepo.CreateNotification(notification, userIDs, records)
+	if err != nil {
+		return nil, err
+	}
+	s.inbox.Publish(notification, inbox)
+
+	s.finishNotification(notification.ID)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return notification, nil
+}
+
+func (s *notificationService) Channels() []*NotificationChannel {
+	channels := make([]*NotificationChannel, 0, len(s.channels))
+	for name, channel := range s.channels {
+		channels = append(channels, &NotificationChannel{
+			Name:   name,
+			Driver: channel.driver,
+			Type:   channel.sender.Type(),
+		})
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Name < channels[j].Name })
+	return channels
+}
+
+// ValidateChannels 检查渠道名称均已配置
+func (s *notificationService) ValidateChannels(names []string) error {
+	for _, name := range names {
+		if s.channels[name] == nil {
+			return invalidInputf("notification channel %q is not configured", name)
+		}
+	}
+	return nil
+}
+
+// TestChannel 立即通过渠道发送一条测试通知，不记录投递记录
+func (s *notificationService) TestChannel(name, recipient string) error {
+	channel := s.channels[name]
+	if channel == nil {
+		return ErrNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.NotificationSendTimeout)
+	defer cancel()
+	return channel.sender.Send(ctx, recipient, &notifier.Message{
+		Subject: "Webox test notification",
+		Content: fmt.Sprintf("This is a test notification sent through channel %s.", name),
+		Level:   constants.NotificationLevelInfo,
+		Link:    s.consoleURL,
+	})
+}
+
+func (s *notificationService) CreateTemplate(input *NotificationTemplateInput) (*model.NotificationTemplate, error) {
+	template := &model.NotificationTemplate{}
+	if err := s.applyTemplateInput(template, input); err != nil {
+		return nil, err
+	}
+	if err := s.notificationRepo.CreateTemplate(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (s *notificationService) GetTemplate(id uint) (*model.NotificationTemplate, error) {
+	template, err := s.notificationRepo.GetTemplate(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return template, nil
+}
+
+func (s *notificationService) ListTemplates() ([]*model.NotificationTemplate, error) {
+	return s.notificationRepo.ListTemplates()
+}
+
+// UpdateTemplate 更新模板，内置模板只能修改主题、内容与启用状态
+func (s *notificationService) UpdateTemplate(id uint, input *NotificationTemplateInput) (*model.NotificationTemplate, error) {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	if template.IsSystem == 1 {
+		vars, err := notifier.ParseVariables(template.Variables)
+		if err != nil {
+			return nil, err
+		}
+		fixed := *input
+		fixed.Name, fixed.Type, fixed.Variables = template.Name, template.Type, vars
+		input = &fixed
+	}
+	if err := s.applyTemplateInput(template, input); err != nil {
+		return nil, err
+	}
+	if err := s.notificationRepo.UpdateTemplate(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (s *notificationService) DeleteTemplate(id uint) error {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return err
+	}
+	if template.IsSystem == 1 {
+		return invalidInputf("system templates cannot be deleted")
+	}
+	return s.notificationRepo.DeleteTemplate(id)
+}
+
+func (s *notificationService) ListRecords(query *NotificationRecordQuery, page, pageSize int) ([]*model.NotificationRecord, int64, error) {
+	offset := (page - 1) * pageSize
+	return s.notificationRepo.ListRecords(&repository.NotificationRecordFilter{
+		Status:         query.Status,
+		Channel:        query.Channel,
+		NotificationID: query.NotificationID,
+	}, offset, pageSize)
+}
+
+// Start 创建缺失的内置模板，并启动投递任务
+func (s *notificationService) Start(ctx context.Context) {
+	if err := s.ensureSystemTemplates(); err != nil {
+		log.Printf("Failed to create system notification templates: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(constants.NotificationPollInterval)
+		defer ticker.Stop()
+
+		for {
+			s.deliverDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// deliverDue 投递已到期的记录，每条记录先领取租约，多个实例不会重复投递
+func (s *notificationService) deliverDue(ctx context.Context) {
+	for {
+		now := time.Now()
+		records, err := s.notificationRepo.ListDueRecords(now, constants.NotificationBatchSize)
+		if err != nil {
+			log.Printf("Failed to list pending notifications: %v", err)
+			return
+		}
+
+		for _, record := range records {
+			if ctx.Err() != nil {
+				return
+			}
+			claimed, err := s.notificationRepo.ClaimRecord(record.ID, now, now.Add(2*constants.NotificationSendTimeout))
+			if err != nil {
+				log.Printf("Failed to claim notification record %d: %v", record.ID, err)
+				continue
+			}
+			if claimed {
+				s.deliver(ctx, record)
+			}
+		}
+		if len(records) < constants.NotificationBatchSize {
+			return
+		}
+	}
+}
+
+func (s *notificationService) deliver(ctx context.Context, record *model.NotificationRecord) {
+	level := constants.NotificationLevelInfo
+	if record.Notification != nil {
+		level = record.Notification.Level
+	}
+
+	var err error
+	if channel := s.channels[record.Channel]; channel == nil {
+		err = fmt.Errorf("notification channel %q is not configured", record.Channel)
+	} else {
+		sendCtx, cancel := context.WithTimeout(ctx, constants.NotificationSendTimeout)
+		err = channel.sender.Send(sendCtx, record.Recipient, &notifier.Message{
+			Subject: record.Subject,
+			Content: record.Content,
+			Level:   level,
+			Link:    s.consoleLink(record.ReferenceType, record.ReferenceID),
+		})
+		cancel()
+	}
+
+	now := time.Now()
+	if err == nil {
+		record.Status = constants.NotificationStatusSent
+		record.SentAt = &now
+		record.ErrorMsg = ""
+		record.NextRetryAt = nil
+	} else {
+		record.RetryCount++
+		record.ErrorMsg = err.Error()
+		if record.RetryCount >= s.maxAttempts {
+			record.Status = constants.NotificationStatusFailed
+			record.NextRetryAt = nil
+		} else {
+			next := now.Add(retryBackoff(record.RetryCount, constants.NotificationRetryBase, constants.NotificationRetryMax))
+			record.NextRetryAt = &next
+		}
+		log.Printf("Failed to send notification record %d through %s (attempt %d): %v",
+			record.ID, record.Channel, record.RetryCount, err)
+	}
+
+	if err := s.notificationRepo.UpdateRecord(record); err != nil {
+		log.Printf("Failed to update notification record %d: %v", record.ID, err)
+		return
+	}
+	if record.NotificationID != nil && record.Status != constants.NotificationStatusPending {
+		s.finishNotification(*record.NotificationID)
+	}
+}
+
+// finishNotification 全部投递结束后更新通知状态，有投递失败时为 FAILED
+func (s *notificationService) finishNotification(id uint) {
+	counts, err := s.notificationRepo.CountRecords(id)
+	if err != nil {
+		log.Printf("Failed to count notification records of %d: %v", id, err)
+		return
+	}
+	if counts[constants.NotificationStatusPending] > 0 {
+		return
+	}
+
+	status := constants.NotificationStatusSent
+	var errorMsg string
+	if failed := counts[constants.NotificationStatusFailed]; failed > 0 {
+		status = constants.NotificationStatusFailed
+		errorMsg = fmt.Sprintf("%d of %d deliveries failed", failed, failed+counts[constants.NotificationStatusSent])
+	}
+	if err := s.notificationRepo.UpdateNotificationStatus(id, status, errorMsg, time.Now()); err != nil {
+		log.Printf("Failed to update notification %d: %v", id, err)
+	}
+}
+
+// consoleLink 返回控制台中通知关联对象的页面地址，未配置 console_url 时为空
+func (s *notificationService) consoleLink(referenceType, referenceID string) string {
+	if s.consoleURL == "" {
+		return ""
+	}
+	path, ok := notificationLinkPaths[referenceType]
+	if !ok || referenceID == "" {
+		return s.consoleURL
+	}
+	return s.consoleURL + fmt.Sprintf(path, url.PathEscape(referenceID))
+}
+
+// resolveRecipients 返回通知的接收用户
+func (s *notificationService) resolveRecipients(targetType string, ids []uint) ([]*model.User, error) {
+	switch targetType {
+	case constants.NotificationTargetUser:
+		if len(ids) == 0 {
+			return nil, invalidInputf("target_ids is required")
+		}
+		return s.userRepo.ListActive(ids, nil)
+	case constants.NotificationTargetGroup:
+		if len(ids) == 0 {
+			return nil, invalidInputf("target_ids is required")
+		}
+		return s.userRepo.ListActive(nil, ids)
+	case constants.NotificationTargetAll:
+		return s.userRepo.ListActive(nil, nil)
+	}
+	return nil, invalidInputf("unsupported target type: %s", targetType)
+}
+
+// applyTemplateInput 校验模板语法与变量声明后写入模板，同名模板的每种类型只能有一个
+func (s *notificationService) applyTemplateInput(template *model.NotificationTemplate, input *NotificationTemplateInput) error {
+	if input.Name == "" {
+		return invalidInputf("name is required")
+	}
+	switch input.Type {
+	case constants.NotificationTypeEmail, constants.NotificationTypeSMS,
+		constants.NotificationTypeWebhook, constants.NotificationTypePush:
+	default:
+		return invalidInputf("unsupported template type: %s", input.Type)
+	}
+	if input.Content == "" {
+		return invalidInputf("content is required")
+	}
+
+	variants, err := s.notificationRepo.ListTemplatesByName(input.Name)
+	if err != nil {
+		return err
+	}
+	for _, v := range variants {
+		if v.ID != template.ID && v.Type == input.Type {
+			return invalidInputf("template %s already has a %s variant", input.Name, input.Type)
+		}
+	}
+
+	vars := input.Variables
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	rendered := &notifier.Template{Subject: input.Subject, Content: input.Content, Variables: vars}
+	if err := rendered.Validate(); err != nil {
+		return invalidInput(err)
+	}
+	varsJSON, err := json.Marshal(vars)
+	if err != nil {
+		return err
+	}
+
+	template.Name = input.Name
+	template.Type = input.Type
+	template.Subject = input.Subject
+	template.Content = input.Content
+	template.Variables = string(varsJSON)
+	template.Status = 0
+	if input.Enabled {
+		template.Status = 1
+	}
+	return nil
+}
+
+// ensureSystemTemplates 创建缺失的内置模板，已存在的模板保留用户修改
+func (s *notificationService) ensureSystemTemplates() error {
+	for _, builtin := range systemNotificationTemplates {
+		variants, err := s.notificationRepo.ListTemplatesByName(builtin.Name)
+		if err != nil {
+			return err
+		}
+		if len(variants) > 0 {
+			continue
+		}
+		template := builtin
+		template.IsSystem = 1
+		template.Status = 1
+		if err := s.notificationRepo.CreateTemplate(&template); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notificationLinkPaths 各关联对象在控制台中的页面路径
+var notificationLinkPaths = map[string]string{
+	constants.NotificationRefAlert:      "/alerts/%s",
+	constants.NotificationRefDeployment: "/deployments/%s",
+}
+
+// systemNotificationTemplates 告警与部署使用的内置模板
+var systemNotificationTemplates = []model.NotificationTemplate{
+	{
+		Name:      constants.NotificationTemplateAlertFiring,
+		Type:      constants.NotificationTypePush,
+		Subject:   "[FIRING] {{.title}}",
+		Content:   "{{.description}}\n\nRule: {{.rule}}\nAlert: {{.alert_id}}\nFired at: {{.fired_at.Format \"2006-01-02 15:04:05 MST\"}}",
+		Variables: `{"alert_id":"string","description":"string","fired_at":"time","rule":"string","title":"string"}`,
+	},
+	{
+		Name:      constants.NotificationTemplateAlertResolved,
+		Type:      constants.NotificationTypePush,
+		Subject:   "[RESOLVED] {{.title}}",
+		Content:   "Alert {{.alert_id}} of rule {{.rule}} is resolved: {{.note}}\n\nFired at: {{.fired_at.Format \"2006-01-02 15:04:05 MST\"}}",
+		Variables: `{"alert_id":"string","fired_at":"time","note":"string","rule":"string","title":"string"}`,
+	},
+	{
+		Name:      constants.NotificationTemplateDeploymentSucceeded,
+		Type:      constants.NotificationTypePush,
+		Subject:   "Deployment {{.project}} succeeded",
+		Content:   "Deployment {{.deployment_id}} of {{.project}} on server {{.server_id}} finished successfully.",
+		Variables: `{"deployment_id":"string","message":"string","project":"string","server_id":"number"}`,
+	},
+	{
+		Name:      constants.NotificationTemplateDeploymentFailed,
+		Type:      constants.NotificationTypePush,
+		Subject:   "Deployment {{.project}} failed",
+		Content:   "Deployment {{.deployment_id}} of {{.project}} on server {{.server_id}} failed: {{.message}}",
+		Variables: `{"deployment_id":"string","message":"string","project":"string","server_id":"number"}`,
+	},
+}
+
+// pickTemplate 选择与渠道类型一致的启用模板，没有时依次使用 PUSH 类型与第一个启用的模板
+func pickTemplate(variants []*model.NotificationTemplate, typ string) *model.NotificationTemplate {
+	var fallback *model.NotificationTemplate
+	for _, t := range variants {
+		if t.Status != 1 {
+			continue
+		}
+		if t.Type == typ {
+			return t
+		}
+		if fallback == nil || (t.Type == constants.NotificationTypePush && fallback.Type != constants.NotificationTypePush) {
+			fallback = t
+		}
+	}
+	return fallback
+}
+
+// renderNotification 使用模板渲染主题与内容，没有模板时使用请求中的标题与内容
+func renderNotification(template *model.NotificationTemplate, req *NotificationRequest) (string, string, error) {
+	if template == nil {
+		if req.Template != "" {
+			return "", "", invalidInputf("notification template %q is disabled", req.Template)
+		}
+		if req.Title == "" || req.Content == "" {
+			return "", "", invalidInputf("title and content are required without a template")
+		}
+		return req.Title, req.Content, nil
+	}
+
+	vars, err := notifier.ParseVariables(template.Variables)
+	if err != nil {
+		return "", "", err
+	}
+	t := &notifier.Template{Subject: template.Subject, Content: template.Content, Variables: vars}
+	title, content, err := t.Render(req.Variables)
+	return title, content, invalidInput(err)
+}
+
+// channelRecipients 返回渠道的接收地址，WEBHOOK 类型只投递一次，接收者记为渠道名称
+// 未填写邮箱或手机号的用户返回空字符串
+func channelRecipients(typ, channel string, users []*model.User) []string {
+	if typ == notifier.TypeWebhook {
+		return []string{channel}
+	}
+	recipients := make([]string, len(users))
+	for i, user := range users {
+		if typ == notifier.TypeEmail {
+			recipients[i] = user.Email
+		} else {
+			recipients[i] = user.Phone
+		}
+	}
+	return recipients
+}
+
+// retryBackoff 第 n 次失败后的重试间隔，从 base 开始翻倍，不超过 max
+func retryBackoff(n int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
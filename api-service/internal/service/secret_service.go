@@ -0,0 +1,542 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SecretService interface {
+	CreateSecret(ownerID uint, input *SecretInput, meta *AuditMeta) (*model.SecretKey, error)
+	GetSecret(userID, id uint) (*model.SecretKey, error)
+	ListSecrets(userID uint, keyType string) ([]*model.SecretKey, error)
+	UpdateSecret(userID, id uint, input *SecretUpdateInput, meta *AuditMeta) (*model.SecretKey, error)
+	DeleteSecret(userID, id uint, meta *AuditMeta) error
+	RevealSecret(userID, id uint, meta *AuditMeta) (string, error)
+	RotateSecret(userID, id uint, value, note string, meta *AuditMeta) (*model.SecretKey, error)
+	ListVersions(userID, id uint) ([]*model.SecretKeyVersion, error)
+	RollbackSecret(userID, id uint, version int, meta *AuditMeta) (*model.SecretKey, error)
+	ShareSecret(userID, id uint, users, groups []uint, meta *AuditMeta) (*model.SecretKey, error)
+	ListExpiringSecrets(userID uint, days int) ([]*model.SecretKey, error)
+	ResolveSecrets(userID uint, names []string, meta *AuditMeta) (map[string]*ResolvedSecret, error)
+}
+
+// ResolvedSecret 按名称解析出的密钥明文，仅在部署下发时使用，不落库
+type ResolvedSecret struct {
+	ID      uint
+	Name    string
+	Version int
+	Value   string
+}
+
+// SecretInput 创建密钥的参数
+type SecretInput struct {
+	Name         string
+	KeyType      string
+	Value        string
+	Description  string
+	CustomFields map[string]interface{}
+	ExpiresAt    *time.Time
+}
+
+// SecretUpdateInput 更新密钥元数据的参数，只修改请求中给出的字段
+// ClearExpiresAt 为 true 时移除过期时间
+type SecretUpdateInput struct {
+	Name           string
+	KeyType        string
+	Description    *string
+	CustomFields   map[string]interface{}
+	ExpiresAt      *time.Time
+	ClearExpiresAt bool
+}
+
+type secretService struct {
+	secretRepo  repository.SecretRepository
+	userRepo    repository.UserRepository
+	auditRepo   repository.AuditRepository
+	encryptor   *utils.Encryptor
+	warningDays int
+}
+
+var validSecretTypes = map[string]bool{
+	constants.SecretTypeAPIKey:      true,
+	constants.SecretTypeDatabase:    true,
+	constants.SecretTypeSSH:         true,
+	constants.SecretTypeCertificate: true,
+	constants.SecretTypeCustom:      true,
+}
+
+func NewSecretService(secretRepo repository.SecretRepository, userRepo repository.UserRepository,
+	auditRepo repository.AuditRepository, encryptor *utils.Encryptor, warningDays int) SecretService {
+	return &secretService{
+		secretRepo:  secretRepo,
+		userRepo:    userRepo,
+		auditRepo:   auditRepo,
+		encryptor:   encryptor,
+		warningDays: warningDays,
+	}
+}
+
+func (s *secretService) CreateSecret(ownerID uint, input *SecretInput, meta *AuditMeta) (*model.SecretKey, error) {
+	if !validSecretTypes[input.KeyType] {
+		return nil, invalidInputf("invalid key type: %s", input.KeyType)
+	}
+	if input.Value == "" {
+		return nil, invalidInputf("secret value is required")
+	}
+
+	// 同一用户下密钥名称唯一，部署时按名称引用
+	if err := s.checkNameAvailable(ownerID, input.Name, 0); err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.encryptor.Encrypt(input.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	customFields := "{}"
+	if input.CustomFields != nil {
+		if customFields, err = encodeJSON(input.CustomFields); err != nil {
+			return nil, err
+		}
+	}
+
+	secret := &model.SecretKey{
+		Name:             input.Name,
+		KeyType:          input.KeyType,
+		EncryptedValue:   encrypted,
+		CurrentVersion:   1,
+		Description:      input.Description,
+		CustomFields:     customFields,
+		AuthorizedUsers:  "[]",
+		AuthorizedGroups: "[]",
+		ExpiresAt:        input.ExpiresAt,
+		OwnerID:          ownerID,
+	}
+	version := &model.SecretKeyVersion{
+		Version:        1,
+		EncryptedValue: encrypted,
+		ChangeNote:     "initial version",
+		CreatedBy:      ownerID,
+	}
+
+	if err := s.secretRepo.Create(secret, version); err != nil {
+		return nil, err
+	}
+
+	s.audit(ownerID, secret, constants.AuditActionCreate, "secret created", meta)
+	return secret, nil
+}
+
+func (s *secretService) GetSecret(userID, id uint) (*model.SecretKey, error) {
+	secret, err := s.getSecret(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkReadAccess(userID, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func (s *secretService) ListSecrets(userID uint, keyType string) ([]*model.SecretKey, error) {
+	secrets, err := s.secretRepo.List(keyType)
+	if err != nil {
+		return nil, err
+	}
+	return s.filterReadable(userID, secrets)
+}
+
+func (s *secretService) UpdateSecret(userID, id uint, input *SecretUpdateInput, meta *AuditMeta) (*model.SecretKey, error) {
+	secret, err := s.getOwnedSecret(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.KeyType != "" {
+		if !validSecretTypes[input.KeyType] {
+			return nil, invalidInputf("invalid key type: %s", input.KeyType)
+		}
+		secret.KeyType = input.KeyType
+	}
+	if input.Name != "" && input.Name != secret.Name {
+		if err := s.checkNameAvailable(secret.OwnerID, input.Name, secret.ID); err != nil {
+			return nil, err
+		}
+		secret.Name = input.Name
+	}
+	if input.Description != nil {
+		secret.Description = *input.Description
+	}
+	if input.ClearExpiresAt {
+		secret.ExpiresAt = nil
+	} else if input.ExpiresAt != nil {
+		secret.ExpiresAt = input.ExpiresAt
+	}
+	if input.CustomFields != nil {
+		customFields, encodeErr := encodeJSON(input.CustomFields)
+		if encodeErr != nil {
+			return nil, encodeErr
+		}
+		secret.CustomFields = customFields
+	}
+
+	if err := s.secretRepo.Update(secret); err != nil {
+		return nil, err
+	}
+
+	s.audit(userID, secret, constants.AuditActionUpdate, "secret metadata updated", meta)
+	return secret, nil
+}
+
+func (s *secretService) DeleteSecret(userID, id uint, meta *AuditMeta) error {
+	secret, err := s.getOwnedSecret(userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.secretRepo.Delete(secret.ID); err != nil {
+		return err
+	}
+
+	s.audit(userID, secret, constants.AuditActionDelete, "secret deleted", meta)
+	return nil
+}
+
+// RevealSecret 解密并返回密钥明文，每次调用都会写入审计日志
+func (s *secretService) RevealSecret(userID, id uint, meta *AuditMeta) (string, error) {
+	secret, err := s.GetSecret(userID, id)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := s.encryptor.Decrypt(secret.EncryptedValue)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %v", err)
+	}
+
+	s.audit(userID, secret, constants.AuditActionReveal,
+		fmt.Sprintf("secret value revealed (version %d)", secret.CurrentVersion), meta)
+	return value, nil
+}
+
+// RotateSecret 写入新的密钥值并生成新版本
+func (s *secretService) RotateSecret(userID, id uint, value, note string, meta *AuditMeta) (*model.SecretKey, error) {
+	if value == "" {
+		return nil, invalidInputf("secret value is required")
+	}
+
+	secret, err := s.getOwnedSecret(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.encryptor.Encrypt(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if note == "" {
+		note = "rotated"
+	}
+	if err := s.addVersion(userID, secret, encrypted, note); err != nil {
+		return nil, err
+	}
+
+	s.audit(userID, secret, constants.AuditActionRotate,
+		fmt.Sprintf("secret rotated to version %d", secret.CurrentVersion), meta)
+	return secret, nil
+}
+
+func (s *secretService) ListVersions(userID, id uint) ([]*model.SecretKeyVersion, error) {
+	secret, err := s.GetSecret(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.secretRepo.ListVersions(secret.ID)
+}
+
+// RollbackSecret 将历史版本的值复制为新版本，历史记录保持不变
+func (s *secretService) RollbackSecret(userID, id uint, version int, meta *AuditMeta) (*model.SecretKey, error) {
+	secret, err := s.getOwnedSecret(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.secretRepo.GetVersion(secret.ID, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := s.addVersion(userID, secret, target.EncryptedValue,
+		fmt.Sprintf("rollback to version %d", version)); err != nil {
+		return nil, err
+	}
+
+	s.audit(userID, secret, constants.AuditActionRollback,
+		fmt.Sprintf("secret rolled back to version %d as version %d", version, secret.CurrentVersion), meta)
+	return secret, nil
+}
+
+// ShareSecret 设置可访问密钥的用户和用户组，覆盖原有授权
+func (s *secretService) ShareSecret(userID, id uint, users, groups []uint, meta *AuditMeta) (*model.SecretKey, error) {
+	secret, err := s.getOwnedSecret(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizedUsers, err := encodeJSON(uniqueIDs(users))
+	if err != nil {
+		return nil, err
+	}
+	authorizedGroups, err := encodeJSON(uniqueIDs(groups))
+	if err != nil {
+		return nil, err
+	}
+
+	secret.AuthorizedUsers = authorizedUsers
+	secret.AuthorizedGroups = authorizedGroups
+	if err := s.secretRepo.Update(secret); err != nil {
+		return nil, err
+	}
+
+	s.audit(userID, secret, constants.AuditActionShare,
+		fmt.Sprintf("shared with users %s and groups %s", authorizedUsers, authorizedGroups), meta)
+	return secret, nil
+}
+
+// ListExpiringSecrets 返回指定天数内过期(含已过期)且当前用户可访问的密钥
+func (s *secretService) ListExpiringSecrets(userID uint, days int) ([]*model.SecretKey, error) {
+	if days <= 0 {
+		days = s.warningDays
+	}
+
+	secrets, err := s.secretRepo.ListExpiringBefore(time.Now().AddDate(0, 0, days))
+	if err != nil {
+		return nil, err
+	}
+	return s.filterReadable(userID, secrets)
+}
+
+// ResolveSecrets 按名称解析当前用户可访问的密钥，同名时优先使用用户自己的密钥
+func (s *secretService) ResolveSecrets(userID uint, names []string, meta *AuditMeta) (map[string]*ResolvedSecret, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make(map[string]*ResolvedSecret, len(names))
+	for _, name := range names {
+		if _, ok := resolved[name]; ok {
+			continue
+		}
+
+		candidates, err := s.secretRepo.GetByName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		var secret *model.SecretKey
+		for _, candidate := range candidates {
+			if candidate.OwnerID == userID {
+				secret = candidate
+				break
+			}
+			if secret == nil && (containsID(decodeIDs(candidate.AuthorizedUsers), userID) ||
+				containsID(decodeIDs(candidate.AuthorizedGroups), user.GroupID)) {
+				secret = candidate
+			}
+		}
+		if secret == nil {
+			return nil, invalidInputf("secret %q not found or not accessible", name)
+		}
+		if secret.ExpiresAt != nil && secret.ExpiresAt.Before(time.Now()) {
+			return nil, invalidInputf("secret %q has expired", name)
+		}
+
+		value, err := s.encryptor.Decrypt(secret.EncryptedValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret %q: %v", name, err)
+		}
+
+		resolved[name] = &ResolvedSecret{
+			ID:      secret.ID,
+			Name:    secret.Name,
+			Version: secret.CurrentVersion,
+			Value:   value,
+		}
+		s.audit(userID, secret, constants.AuditActionInject,
+			fmt.Sprintf("secret version %d injected into deployment", secret.CurrentVersion), meta)
+	}
+
+	return resolved, nil
+}
+
+func (s *secretService) addVersion(userID uint, secret *model.SecretKey, encrypted, note string) error {
+	secret.CurrentVersion++
+	secret.EncryptedValue = encrypted
+
+	version := &model.SecretKeyVersion{
+		Version:        secret.CurrentVersion,
+		EncryptedValue: encrypted,
+		ChangeNote:     note,
+		CreatedBy:      userID,
+	}
+	return s.secretRepo.AddVersion(secret, version)
+}
+
+func (s *secretService) checkNameAvailable(ownerID uint, name string, excludeID uint) error {
+	existing, err := s.secretRepo.GetByName(name)
+	if err != nil {
+		return err
+	}
+	for _, secret := range existing {
+		if secret.OwnerID == ownerID && secret.ID != excludeID {
+			return invalidInputf("secret name already exists")
+		}
+	}
+	return nil
+}
+
+func (s *secretService) getSecret(id uint) (*model.SecretKey, error) {
+	secret, err := s.secretRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return secret, nil
+}
+
+// getOwnedSecret 获取密钥并校验当前用户为所有者，修改类操作仅所有者可执行
+func (s *secretService) getOwnedSecret(userID, id uint) (*model.SecretKey, error) {
+	secret, err := s.getSecret(id)
+	if err != nil {
+		return nil, err
+	}
+	if secret.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return secret, nil
+}
+
+func (s *secretService) checkReadAccess(userID uint, secret *model.SecretKey) error {
+	if secret.OwnerID == userID {
+		return nil
+	}
+
+	if containsID(decodeIDs(secret.AuthorizedUsers), userID) {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrPermissionDenied
+	}
+	if containsID(decodeIDs(secret.AuthorizedGroups), user.GroupID) {
+		return nil
+	}
+
+	return ErrPermissionDenied
+}
+
+func (s *secretService) filterReadable(userID uint, secrets []*model.SecretKey) ([]*model.SecretKey, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	readable := make([]*model.SecretKey, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret.OwnerID == userID ||
+			containsID(decodeIDs(secret.AuthorizedUsers), userID) ||
+			containsID(decodeIDs(secret.AuthorizedGroups), user.GroupID) {
+			readable = append(readable, secret)
+		}
+	}
+	return readable, nil
+}
+
+func (s *secretService) audit(userID uint, secret *model.SecretKey, action, description string, meta *AuditMeta) {
+	resourceID := secret.ID
+	entry := &model.AuditLog{
+		UserID:        &userID,
+		Action:        action,
+		Module:        constants.AuditModuleSecret,
+		ResourceType:  "secret_key",
+		ResourceID:    &resourceID,
+		ResourceName:  secret.Name,
+		Description:   description,
+		RequestParams: "{}",
+		Success:       1,
+	}
+	if meta != nil {
+		entry.Username = meta.Username
+		entry.IPAddress = meta.IPAddress
+		entry.UserAgent = meta.UserAgent
+		entry.RequestMethod = meta.RequestMethod
+		entry.RequestURL = meta.RequestURL
+	}
+
+	if err := s.auditRepo.Create(entry); err != nil {
+		log.Printf("Failed to write audit log for secret %d: %v", secret.ID, err)
+	}
+}
+
+func encodeJSON(v interface{}) (string, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func decodeIDs(data string) []uint {
+	var ids []uint
+	if data == "" {
+		return ids
+	}
+	if err := json.Unmarshal([]byte(data), &ids); err != nil {
+		return nil
+	}
+	return ids
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/utils"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestSecretService(t *testing.T) (SecretService, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	// 内存数据库按连接隔离，只使用一个连接
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&model.UserGroup{}, &model.User{}, &model.SecretKey{}, &model.SecretKeyVersion{},
+		&model.AuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []*model.User{
+		{ID: 1, GroupID: 1, Username: "owner", Email: "owner@example.com", PasswordHash: "x"},
+		{ID: 2, GroupID: 2, Username: "shared", Email: "shared@example.com", PasswordHash: "x"},
+		{ID: 3, GroupID: 3, Username: "group", Email: "group@example.com", PasswordHash: "x"},
+		{ID: 4, GroupID: 4, Username: "other", Email: "other@example.com", PasswordHash: "x"},
+	} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	encryptor, err := utils.NewEncryptor("test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSecretService(repository.NewSecretRepository(db), repository.NewUserRepository(db),
+		repository.NewAuditRepository(db), encryptor, 30), db
+}
+
+func TestSecretLifecycle(t *testing.T) {
+	s, db := newTestSecretService(t)
+
+	secret, err := s.CreateSecret(1, &SecretInput{Name: "db.password", KeyType: constants.SecretTypeDatabase, Value: "v1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.EncryptedValue == "v1" {
+		t.Error("secret value stored in plaintext")
+	}
+
+	if _, err := s.RotateSecret(1, secret.ID, "v2", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := s.RevealSecret(1, secret.ID, nil); value != "v2" {
+		t.Errorf("revealed %q after rotation, want v2", value)
+	}
+
+	rolled, err := s.RollbackSecret(1, secret.ID, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rolled.CurrentVersion != 3 {
+		t.Errorf("version after rollback = %d, want 3", rolled.CurrentVersion)
+	}
+	if value, _ := s.RevealSecret(1, secret.ID, nil); value != "v1" {
+		t.Errorf("revealed %q after rollback, want v1", value)
+	}
+	versions, err := s.ListVersions(1, secret.ID)
+	if err != nil || len(versions) != 3 {
+		t.Fatalf("ListVersions() = %d versions, %v", len(versions), err)
+	}
+	if _, err := s.RollbackSecret(1, secret.ID, 9, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("rollback to missing version: %v", err)
+	}
+
+	var reveals int64
+	db.Model(&model.AuditLog{}).Where("action = ? AND resource_id = ?", constants.AuditActionReveal, secret.ID).Count(&reveals)
+	if reveals != 2 {
+		t.Errorf("reveal audit entries = %d, want 2", reveals)
+	}
+}
+
+func TestSecretValidation(t *testing.T) {
+	s, _ := newTestSecretService(t)
+
+	if _, err := s.CreateSecret(1, &SecretInput{Name: "a", KeyType: constants.SecretTypeCustom, Value: "x"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for name, input := range map[string]*SecretInput{
+		"invalid type":   {Name: "b", KeyType: "PLAIN", Value: "x"},
+		"empty value":    {Name: "b", KeyType: constants.SecretTypeCustom},
+		"duplicate name": {Name: "a", KeyType: constants.SecretTypeCustom, Value: "y"},
+	} {
+		if _, err := s.CreateSecret(1, input, nil); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: error %v is not ErrInvalidInput", name, err)
+		}
+	}
+	// 名称只在同一所有者内唯一
+	if _, err := s.CreateSecret(4, &SecretInput{Name: "a", KeyType: constants.SecretTypeCustom, Value: "z"}, nil); err != nil {
+		t.Errorf("same name for another owner: %v", err)
+	}
+}
+
+func TestUpdateSecret(t *testing.T) {
+	s, _ := newTestSecretService(t)
+
+	expiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	secret, err := s.CreateSecret(1, &SecretInput{Name: "token", KeyType: constants.SecretTypeAPIKey, Value: "t",
+		Description: "ci token", ExpiresAt: &expiresAt}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 未给出的字段保持不变
+	updated, err := s.UpdateSecret(1, secret.ID, &SecretUpdateInput{Name: "ci-token"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "ci-token" || updated.Description != "ci token" || updated.ExpiresAt == nil ||
+		!updated.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("after renaming: name %q, description %q, expires_at %v", updated.Name, updated.Description, updated.ExpiresAt)
+	}
+
+	empty := ""
+	updated, err = s.UpdateSecret(1, secret.ID, &SecretUpdateInput{Description: &empty, ClearExpiresAt: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Description != "" || updated.ExpiresAt != nil {
+		t.Errorf("after clearing: description %q, expires_at %v", updated.Description, updated.ExpiresAt)
+	}
+	if got, _ := s.GetSecret(1, secret.ID); got.Name != "ci-token" || got.ExpiresAt != nil {
+		t.Errorf("stored secret: name %q, expires_at %v", got.Name, got.ExpiresAt)
+	}
+}
+
+func TestSecretAccess(t *testing.T) {
+	s, _ := newTestSecretService(t)
+
+	secret, err := s.CreateSecret(1, &SecretInput{Name: "token", KeyType: constants.SecretTypeAPIKey, Value: "t"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []uint{2, 3, 4} {
+		if _, err := s.RevealSecret(userID, secret.ID, nil); !errors.Is(err, ErrPermissionDenied) {
+			t.Errorf("user %d revealed unshared secret: %v", userID, err)
+		}
+	}
+	if _, err := s.RevealSecret(1, 999, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reveal of missing secret: %v", err)
+	}
+
+	if _, err := s.ShareSecret(1, secret.ID, []uint{2}, []uint{3}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []uint{2, 3} {
+		if value, err := s.RevealSecret(userID, secret.ID, nil); err != nil || value != "t" {
+			t.Errorf("user %d: reveal of shared secret = %q, %v", userID, value, err)
+		}
+	}
+	if _, err := s.RevealSecret(4, secret.ID, nil); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("user 4 revealed secret: %v", err)
+	}
+
+	// 修改类操作仅所有者可执行
+	if _, err := s.RotateSecret(2, secret.ID, "x", "", nil); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("shared user rotated secret: %v", err)
+	}
+	if err := s.DeleteSecret(3, secret.ID, nil); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("group member deleted secret: %v", err)
+	}
+
+	listed, err := s.ListSecrets(4, "")
+	if err != nil || len(listed) != 0 {
+		t.Errorf("ListSecrets for unrelated user = %d secrets, %v", len(listed), err)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	s, _ := newTestSecretService(t)
+
+	past := time.Now().Add(-time.Hour)
+	mustCreate := func(ownerID uint, input *SecretInput) *model.SecretKey {
+		secret, err := s.CreateSecret(ownerID, input, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return secret
+	}
+	own := mustCreate(1, &SecretInput{Name: "db", KeyType: constants.SecretTypeDatabase, Value: "mine"})
+	shared := mustCreate(4, &SecretInput{Name: "db", KeyType: constants.SecretTypeDatabase, Value: "theirs"})
+	if _, err := s.ShareSecret(4, shared.ID, []uint{1}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(1, &SecretInput{Name: "old", KeyType: constants.SecretTypeCustom, Value: "x", ExpiresAt: &past})
+	mustCreate(4, &SecretInput{Name: "private", KeyType: constants.SecretTypeCustom, Value: "x"})
+
+	resolved, err := s.ResolveSecrets(1, []string{"db", "db"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同名时优先使用自己的密钥
+	if got := resolved["db"]; got == nil || got.ID != own.ID || got.Value != "mine" {
+		t.Errorf("resolved db = %+v", got)
+	}
+
+	for _, name := range []string{"old", "private", "missing"} {
+		if _, err := s.ResolveSecrets(1, []string{name}, nil); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("resolve %s: error %v is not ErrInvalidInput", name, err)
+		}
+	}
+}
@@ -4,34 +4,129 @@ import (
 	"api-service/internal/config"
 	"api-service/internal/repository"
 	"api-service/pkg/auth"
+	"api-service/pkg/blobstore"
+	"api-service/pkg/metricstore"
+	"api-service/pkg/utils"
+	"context"
+	"errors"
 
-	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
 type Services struct {
-	UserService        UserService
-	ApplicationService ApplicationService
-	MonitorService     MonitorService
+	UserService         UserService
+	ApplicationService  ApplicationService
+	MonitorService      MonitorService
+	SecretService       SecretService
+	AgentService        AgentService
+	DeploymentService   DeploymentService
+	CertificateService  CertificateService
+	ACMEService         ACMEService
+	CAService           CAService
+	GatewayService      GatewayService
+	AlertService        AlertService
+	NotificationService NotificationService
+	InboxService        InboxService
+	WebhookService      WebhookService
+	WorkflowService     WorkflowService
+	FileService         FileService
 }
 
-func NewServices(db *gorm.DB, rdb *redis.Client, influxClient influxdb2.Client, cfg *config.Config) *Services {
+func NewServices(db *gorm.DB, rdb *redis.Client, metricsStore metricstore.Store, blobStore blobstore.Store,
+	cfg *config.Config) (*Services, error) {
 	// 初始化JWT认证
 	jwtAuth := auth.NewJWTAuth(cfg.JWT.Secret, cfg.JWT.ExpireTime)
 
+	// 初始化敏感数据加密器
+	encryptor, err := utils.NewEncryptor(cfg.Security.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Agent 任务密钥由主任务密钥按 Agent ID 派生
+	if cfg.Agent.TaskKey == "" {
+		return nil, errors.New("agent task key is empty")
+	}
+
 	// 初始化Repository
 	userRepo := repository.NewUserRepository(db)
 	appRepo := repository.NewApplicationRepository(db)
+	secretRepo := repository.NewSecretRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	agentRepo := repository.NewAgentRepository(db)
+	deploymentRepo := repository.NewDeploymentRepository(db)
+	certRepo := repository.NewCertificateRepository(db)
+	alertRepo := repository.NewAlertRepository(db)
+	systemConfigRepo := repository.NewSystemConfigRepository(db)
+	caRepo := repository.NewCARepository(db)
+	gatewayRepo := repository.NewGatewayRepository(db)
+	serverRepo := repository.NewServerRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	workflowRepo := repository.NewWorkflowRepository(db)
+	logRepo := repository.NewLogRepository(db)
+	fileRepo := repository.NewFileRepository(db)
 
 	// 初始化Service
 	userService := NewUserService(userRepo, jwtAuth)
 	appService := NewApplicationService(appRepo)
-	monitorService := NewMonitorService(influxClient)
+	monitorService := NewMonitorService(metricsStore, serverRepo, appRepo)
+	secretService := NewSecretService(secretRepo, userRepo, auditRepo, encryptor, cfg.Security.SecretExpiryWarningDays)
+	webhookService := NewWebhookService(webhookRepo, encryptor)
+	logService := NewLogService(logRepo)
+	agentService := NewAgentService(agentRepo, serverRepo, webhookService, rdb, cfg.Agent.TaskKey, cfg.Agent.ResultsChannel)
+	inboxService := NewInboxService(notificationRepo, rdb, cfg.Notification.PushChannel)
+	notificationService, err := NewNotificationService(notificationRepo, userRepo, inboxService, cfg.Notification)
+	if err != nil {
+		return nil, err
+	}
+	if err := notificationService.ValidateChannels(cfg.Notification.DeploymentChannels); err != nil {
+		return nil, err
+	}
+	deploymentService := NewDeploymentService(deploymentRepo, serverRepo, secretService, agentService, notificationService,
+		webhookService, logService, cfg.Notification.DeploymentChannels)
+	alertService := NewAlertService(alertRepo, serverRepo, appRepo, metricsStore, notificationService, webhookService)
+	certificateService := NewCertificateService(certRepo, alertRepo, alertService, webhookService, encryptor, cfg.Certificate.ExpiryWarningDays)
+	acmeService, err := NewACMEService(certRepo, alertRepo, systemConfigRepo, rdb, encryptor, cfg.ACME)
+	if err != nil {
+		return nil, err
+	}
+	caService := NewCAService(caRepo, certRepo, serverRepo, agentService, encryptor)
+	gatewayService := NewGatewayService(gatewayRepo, certRepo, serverRepo, monitorService, agentService, rdb, encryptor,
+		cfg.Gateway.ReloadChannel)
+	workflowService := NewWorkflowService(workflowRepo, serverRepo, agentService, webhookService, logService)
+	fileService := NewFileService(fileRepo, blobStore, cfg.Storage.MaxFileSize)
 
 	return &Services{
-		UserService:        userService,
-		ApplicationService: appService,
-		MonitorService:     monitorService,
-	}
+		UserService:         userService,
+		ApplicationService:  appService,
+		MonitorService:      monitorService,
+		SecretService:       secretService,
+		AgentService:        agentService,
+		DeploymentService:   deploymentService,
+		CertificateService:  certificateService,
+		ACMEService:         acmeService,
+		CAService:           caService,
+		GatewayService:      gatewayService,
+		AlertService:        alertService,
+		NotificationService: notificationService,
+		InboxService:        inboxService,
+		WebhookService:      webhookService,
+		WorkflowService:     workflowService,
+		FileService:         fileService,
+	}, nil
+}
+
+// Start 启动后台任务，ctx 结束时停止
+func (s *Services) Start(ctx context.Context) {
+	s.InboxService.Start(ctx)
+	s.NotificationService.Start(ctx)
+	s.WebhookService.Start(ctx)
+	s.AgentService.Start(ctx)
+	s.CertificateService.Start(ctx)
+	s.ACMEService.Start(ctx)
+	s.AlertService.Start(ctx)
+	s.WorkflowService.Start(ctx)
+	s.FileService.Start(ctx)
 }
@@ -0,0 +1,592 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/utils"
+	"api-service/pkg/webhooksig"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookService 管理出站 Webhook 并投递事件
+// 每个订阅事件的 Webhook 生成一条投递记录，由后台任务以 HMAC-SHA256 签名后投递，失败后按指数退避重试，重启后继续
+type WebhookService interface {
+	Events() []*WebhookEvent
+
+	// CreateWebhook 创建 Webhook 并返回签名密钥，未指定密钥时随机生成，密钥只在创建时返回
+	CreateWebhook(ownerID uint, input *WebhookInput) (*model.WebhookConfig, string, error)
+	GetWebhook(userID, id uint) (*model.WebhookConfig, error)
+	ListWebhooks(userID uint, page, pageSize int) ([]*model.WebhookConfig, int64, error)
+	UpdateWebhook(userID, id uint, input *WebhookInput) (*model.WebhookConfig, error)
+	DeleteWebhook(userID, id uint) error
+	// Ping 向 Webhook 投递一次 ping 事件，用于验证地址与签名
+	Ping(userID, id uint) (*model.WebhookLog, error)
+
+	// Emit 向所有者与管理员订阅了该事件的 Webhook 投递事件，失败只记录日志
+	Emit(ownerID uint, event string, data interface{})
+	// OnEvent 注册事件处理函数，Emit 时在投递前同步调用
+	OnEvent(handler EventHandler)
+
+	ListDeliveries(userID, webhookID uint, query *WebhookDeliveryQuery, page, pageSize int) ([]*model.WebhookLog, int64, error)
+	GetDelivery(userID, webhookID, id uint) (*model.WebhookLog, error)
+	// Redeliver 以新的投递ID重新投递原事件，事件ID与内容不变
+	Redeliver(userID, webhookID, id uint) (*model.WebhookLog, error)
+	Start(ctx context.Context)
+}
+
+// EventHandler 平台事件处理函数，ownerID 为事件相关资源的所有者
+type EventHandler func(ownerID uint, event string, data interface{})
+
+// WebhookEvent 可订阅的事件
+type WebhookEvent struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// WebhookInput Webhook 参数
+// Events 为订阅的事件，"*" 订阅全部事件；Secret 为空时创建随机生成，更新时保留原密钥
+type WebhookInput struct {
+	Name       string
+	URL        string
+	Secret     string
+	Events     []string
+	Headers    map[string]string
+	Timeout    int
+	RetryCount *int
+	Active     bool
+}
+
+// WebhookDeliveryQuery 投递记录查询条件
+type WebhookDeliveryQuery struct {
+	Status string
+	Event  string
+}
+
+// webhookPayload 投递的请求体，同一事件投递到各 Webhook 时 ID 相同，接收方可据此去重
+type webhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// webhookReservedHeaders 不能通过附加请求头覆盖的请求头
+var webhookReservedHeaders = []string{"Content-Type", "User-Agent", webhooksig.SignatureHeader,
+	webhooksig.TimestampHeader, constants.WebhookHeaderEvent, constants.WebhookHeaderDelivery}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	encryptor   *utils.Encryptor
+	client      *http.Client
+	wake        chan struct{}
+
+	mu       sync.RWMutex
+	handlers []EventHandler
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepository, encryptor *utils.Encryptor) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		encryptor:   encryptor,
+		client: &http.Client{
+			Transport: webhookTransport(),
+			// 不跟随重定向，避免签名请求被转发到其他地址
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+func (s *webhookService) Events() []*WebhookEvent {
+	events := make([]*WebhookEvent, 0, len(constants.WebhookEvents))
+	for name, description := range constants.WebhookEvents {
+		events = append(events, &WebhookEvent{Name: name, Description: description})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Name < events[j].Name })
+	return events
+}
+
+func (s *webhookService) CreateWebhook(ownerID uint, input *WebhookInput) (*model.WebhookConfig, string, error) {
+	secret := input.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, "", err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	webhook := &model.WebhookConfig{OwnerID: ownerID}
+	withSecret := *input
+	withSecret.Secret = secret
+	if err := s.applyInput(webhook, &withSecret); err != nil {
+		return nil, "", err
+	}
+	if err := s.webhookRepo.Create(webhook); err != nil {
+		return nil, "", err
+	}
+	return webhook, secret, nil
+}
+
+func (s *webhookService) GetWebhook(userID, id uint) (*model.WebhookConfig, error) {
+	webhook, err := s.webhookRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if webhook.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return webhook, nil
+}
+
+func (s *webhookService) ListWebhooks(userID uint, page, pageSize int) ([]*model.WebhookConfig, int64, error) {
+	offset := (page - 1) * pageSize
+	return s.webhookRepo.List(userID, offset, pageSize)
+}
+
+func (s *webhookService) UpdateWebhook(userID, id uint, input *WebhookInput) (*model.WebhookConfig, error) {
+	webhook, err := s.GetWebhook(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyInput(webhook, input); err != nil {
+		return nil, err
+	}
+	if err := s.webhookRepo.Update(webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook 删除 Webhook，尚未完成的投递在下次投递时记为失败
+func (s *webhookService) DeleteWebhook(userID, id uint) error {
+	if _, err := s.GetWebhook(userID, id); err != nil {
+		return err
+	}
+	return s.webhookRepo.Delete(id)
+}
+
+func (s *webhookService) Ping(userID, id uint) (*model.WebhookLog, error) {
+	webhook, err := s.GetWebhook(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := newWebhookPayload(constants.WebhookEventPing, map[string]interface{}{
+		"webhook_id": webhook.ID,
+		"name":       webhook.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logs, err := s.enqueue([]*model.WebhookConfig{webhook}, constants.WebhookEventPing, payload, nil)
+	if err != nil {
+		return nil, err
+	}
+	return logs[0], nil
+}
+
+func (s *webhookService) Emit(ownerID uint, event string, data interface{}) {
+	s.mu.RLock()
+	handlers := s.handlers
+	s.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(ownerID, event, data)
+	}
+
+	webhooks, err := s.webhookRepo.ListSubscribers(ownerID)
+	if err != nil {
+		log.Printf("Failed to list webhooks for event %s: %v", event, err)
+		return
+	}
+
+	var targets []*model.WebhookConfig
+	for _, webhook := range webhooks {
+		if subscribes(webhook, event) {
+			targets = append(targets, webhook)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	payload, err := newWebhookPayload(event, data)
+	if err != nil {
+		log.Printf("Failed to encode webhook event %s: %v", event, err)
+		return
+	}
+	if _, err := s.enqueue(targets, event, payload, nil); err != nil {
+		log.Printf("Failed to create webhook deliveries for event %s: %v", event, err)
+	}
+}
+
+func (s *webhookService) OnEvent(handler EventHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+func (s *webhookService) ListDeliveries(userID, webhookID uint, query *WebhookDeliveryQuery, page, pageSize int) ([]*model.WebhookLog, int64, error) {
+	if _, err := s.GetWebhook(userID, webhookID); err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	return s.webhookRepo.ListLogs(webhookID, &repository.WebhookLogFilter{
+		Status:    query.Status,
+		EventType: query.Event,
+	}, offset, pageSize)
+}
+
+func (s *webhookService) GetDelivery(userID, webhookID, id uint) (*model.WebhookLog, error) {
+	if _, err := s.GetWebhook(userID, webhookID); err != nil {
+		return nil, err
+	}
+	delivery, err := s.webhookRepo.GetLog(webhookID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (s *webhookService) Redeliver(userID, webhookID, id uint) (*model.WebhookLog, error) {
+	webhook, err := s.GetWebhook(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := s.webhookRepo.GetLog(webhookID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if delivery.Status == constants.WebhookDeliveryPending {
+		return nil, invalidInputf("delivery is still pending")
+	}
+
+	logs, err := s.enqueue([]*model.WebhookConfig{webhook}, delivery.EventType, delivery.Payload, &delivery.ID)
+	if err != nil {
+		return nil, err
+	}
+	return logs[0], nil
+}
+
+// Start 启动投递任务
+func (s *webhookService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(constants.WebhookPollInterval)
+		defer ticker.Stop()
+
+		for {
+			s.deliverDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// enqueue 为每个 Webhook 创建一条待投递记录并唤醒投递任务
+func (s *webhookService) enqueue(webhooks []*model.WebhookConfig, event, payload string, redeliveryOf *uint) ([]*model.WebhookLog, error) {
+	now := time.Now()
+	logs := make([]*model.WebhookLog, len(webhooks))
+	for i, webhook := range webhooks {
+		logs[i] = &model.WebhookLog{
+			WebhookID:    webhook.ID,
+			EventType:    event,
+			Payload:      payload,
+			RequestID:    uuid.NewString(),
+			Status:       constants.WebhookDeliveryPending,
+			NextRetryAt:  &now,
+			RedeliveryOf: redeliveryOf,
+		}
+	}
+	if err := s.webhookRepo.CreateLogs(logs); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return logs, nil
+}
+
+// deliverDue 投递已到期的记录，每条记录先领取租约，多个实例不会重复投递
+func (s *webhookService) deliverDue(ctx context.Context) {
+	for {
+		now := time.Now()
+		logs, err := s.webhookRepo.ListDueLogs(now, constants.WebhookBatchSize)
+		if err != nil {
+			log.Printf("Failed to list pending webhook deliveries: %v", err)
+			return
+		}
+
+		for _, delivery := range logs {
+			if ctx.Err() != nil {
+				return
+			}
+			lease := time.Duration(constants.MaxWebhookTimeout) * 2 * time.Second
+			claimed, err := s.webhookRepo.ClaimLog(delivery.ID, now, now.Add(lease))
+			if err != nil {
+				log.Printf("Failed to claim webhook delivery %d: %v", delivery.ID, err)
+				continue
+			}
+			if claimed {
+				s.deliver(ctx, delivery)
+			}
+		}
+		if len(logs) < constants.WebhookBatchSize {
+			return
+		}
+	}
+}
+
+func (s *webhookService) deliver(ctx context.Context, delivery *model.WebhookLog) {
+	webhook := &delivery.Webhook
+	retryable := true
+
+	var err error
+	switch {
+	case webhook.ID == 0:
+		err, retryable = errors.New("webhook has been deleted"), false
+	case webhook.IsActive != 1:
+		err, retryable = errors.New("webhook is disabled"), false
+	default:
+		err = s.send(ctx, webhook, delivery)
+	}
+
+	now := time.Now()
+	if err == nil {
+		delivery.Status = constants.WebhookDeliverySucceeded
+		delivery.Success = 1
+		delivery.ErrorMessage = ""
+		delivery.NextRetryAt = nil
+	} else {
+		delivery.RetryCount++
+		delivery.ErrorMessage = err.Error()
+		if !retryable || delivery.RetryCount > webhook.RetryCount {
+			delivery.Status = constants.WebhookDeliveryFailed
+			delivery.NextRetryAt = nil
+		} else {
+			next := now.Add(retryBackoff(delivery.RetryCount, constants.WebhookRetryBase, constants.WebhookRetryMax))
+			delivery.NextRetryAt = &next
+		}
+		log.Printf("Failed to deliver webhook event %s to webhook %d (attempt %d): %v",
+			delivery.EventType, delivery.WebhookID, delivery.RetryCount, err)
+	}
+
+	if err := s.webhookRepo.UpdateLog(delivery); err != nil {
+		log.Printf("Failed to update webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send 签名并发送请求，记录响应状态、响应体与耗时，非 2xx 响应视为失败
+func (s *webhookService) send(ctx context.Context, webhook *model.WebhookConfig, delivery *model.WebhookLog) error {
+	secret, err := s.encryptor.Decrypt(webhook.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+	var headers map[string]string
+	if webhook.Headers != "" {
+		if err := json.Unmarshal([]byte(webhook.Headers), &headers); err != nil {
+			return fmt.Errorf("invalid webhook headers: %w", err)
+		}
+	}
+
+	timeout := time.Duration(webhook.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = constants.DefaultWebhookTimeout * time.Second
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(sendCtx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Webox-Webhook/1.0")
+	req.Header.Set(constants.WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(constants.WebhookHeaderDelivery, delivery.RequestID)
+	req.Header.Set(webhooksig.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhooksig.SignatureHeader, webhooksig.Sign(secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	delivery.ResponseTime = int(time.Since(start).Milliseconds())
+	if err != nil {
+		delivery.StatusCode = nil
+		delivery.ResponseBody = ""
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, constants.WebhookMaxResponseBody))
+	statusCode := resp.StatusCode
+	delivery.StatusCode = &statusCode
+	delivery.ResponseBody = string(respBody)
+	if statusCode < 200 || statusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", statusCode)
+	}
+	return nil
+}
+
+// webhookTransport 只连接公网地址的 Transport
+// 在 DNS 解析后的连接阶段检查地址，防止经 Webhook 访问内网服务并通过投递记录中的响应体读取内容；不使用环境变量中的代理
+func webhookTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   constants.WebhookDialTimeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !utils.IsPublicIP(ip) {
+				return fmt.Errorf("webhook address %s is not a public address", host)
+			}
+			return nil
+		},
+	}).DialContext
+	return transport
+}
+
+// applyInput 校验参数后写入 Webhook，密钥加密存储
+func (s *webhookService) applyInput(webhook *model.WebhookConfig, input *WebhookInput) error {
+	if input.Name == "" {
+		return invalidInputf("name is required")
+	}
+	u, err := url.Parse(input.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return invalidInputf("invalid webhook url: %s", input.URL)
+	}
+	// 域名在每次投递时解析并检查，这里只拒绝明显的内网地址
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if ip := net.ParseIP(host); (ip != nil && !utils.IsPublicIP(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return invalidInputf("webhook url must point to a public address: %s", input.URL)
+	}
+	if len(input.Events) == 0 {
+		return invalidInputf("events is required")
+	}
+	for _, event := range input.Events {
+		if _, ok := constants.WebhookEvents[event]; !ok && event != constants.WebhookEventAll {
+			return invalidInputf("unsupported webhook event: %s", event)
+		}
+	}
+	for name := range input.Headers {
+		for _, reserved := range webhookReservedHeaders {
+			if strings.EqualFold(name, reserved) {
+				return invalidInputf("header %s cannot be overridden", name)
+			}
+		}
+	}
+
+	timeout := input.Timeout
+	if timeout == 0 {
+		timeout = constants.DefaultWebhookTimeout
+	}
+	if timeout < 1 || timeout > constants.MaxWebhookTimeout {
+		return invalidInputf("timeout must be between 1 and %d seconds", constants.MaxWebhookTimeout)
+	}
+	retryCount := constants.DefaultWebhookRetryCount
+	if input.RetryCount != nil {
+		retryCount = *input.RetryCount
+	}
+	if retryCount < 0 || retryCount > constants.MaxWebhookRetryCount {
+		return invalidInputf("retry_count must be between 0 and %d", constants.MaxWebhookRetryCount)
+	}
+
+	events, err := json.Marshal(input.Events)
+	if err != nil {
+		return err
+	}
+	extra := input.Headers
+	if extra == nil {
+		extra = map[string]string{}
+	}
+	headers, err := json.Marshal(extra)
+	if err != nil {
+		return err
+	}
+	if input.Secret != "" {
+		secret, err := s.encryptor.Encrypt(input.Secret)
+		if err != nil {
+			return err
+		}
+		webhook.Secret = secret
+	}
+
+	webhook.Name = input.Name
+	webhook.URL = input.URL
+	webhook.Events = string(events)
+	webhook.Headers = string(headers)
+	webhook.Timeout = timeout
+	webhook.RetryCount = retryCount
+	webhook.IsActive = 0
+	if input.Active {
+		webhook.IsActive = 1
+	}
+	return nil
+}
+
+// subscribes 判断 Webhook 是否订阅了事件
+func subscribes(webhook *model.WebhookConfig, event string) bool {
+	var events []string
+	if err := json.Unmarshal([]byte(webhook.Events), &events); err != nil {
+		log.Printf("Invalid events of webhook %d: %v", webhook.ID, err)
+		return false
+	}
+	for _, e := range events {
+		if e == event || e == constants.WebhookEventAll {
+			return true
+		}
+	}
+	return false
+}
+
+func newWebhookPayload(event string, data interface{}) (string, error) {
+	payload, err := json.Marshal(&webhookPayload{
+		ID:        uuid.NewString(),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	return string(payload), err
+}
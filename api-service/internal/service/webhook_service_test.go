@@ -0,0 +1,45 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookTransportRejectsPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: webhookTransport()}
+	for _, url := range []string{server.URL, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)} {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			t.Errorf("request to %s succeeded", url)
+		} else if !strings.Contains(err.Error(), "not a public address") {
+			t.Errorf("request to %s: unexpected error %v", url, err)
+		}
+	}
+}
+
+func TestWebhookApplyInputRejectsPrivateURL(t *testing.T) {
+	s := &webhookService{}
+	for _, url := range []string{
+		"http://127.0.0.1:6379/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]:8080/",
+		"http://localhost:8080/api/v1/users",
+		"http://LOCALHOST./",
+		"http://10.0.0.5/hook",
+		"ftp://example.com/",
+		"http:///path",
+	} {
+		err := s.applyInput(nil, &WebhookInput{Name: "w", URL: url, Events: []string{"*"}})
+		if err == nil {
+			t.Errorf("applyInput accepted %s", url)
+		}
+	}
+}
@@ -0,0 +1,1228 @@
+package service
+
+import (
+	"api-service/internal/constants"
+	"api-service/internal/model"
+	"api-service/internal/repository"
+	"api-service/pkg/schedule"
+	"api-service/pkg/workflow"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WorkflowService 管理工作流、工作流任务与执行，并按 DAG 在 Agent 上运行步骤
+// 执行状态与每个步骤在每台服务器上的运行记录保存在数据库中，后台任务轮询推进，api-service 重启后继续运行；
+// 多个实例同时推进时以条件更新领取步骤与结果，同一步骤不会重复下发
+type WorkflowService interface {
+	CreateWorkflow(ownerID uint, input *WorkflowInput) (*model.Workflow, error)
+	GetWorkflow(userID, id uint) (*model.Workflow, error)
+	ListWorkflows(userID uint, page, pageSize int) ([]*model.Workflow, int64, error)
+	UpdateWorkflow(userID, id uint, input *WorkflowInput) (*model.Workflow, error)
+	DeleteWorkflow(userID, id uint) error
+
+	CreateTask(ownerID uint, input *WorkflowTaskInput) (*model.WorkflowTask, error)
+	GetTask(userID, id uint) (*model.WorkflowTask, error)
+	ListTasks(userID uint, workflowID *uint, page, pageSize int) ([]*model.WorkflowTask, int64, error)
+	UpdateTask(userID, id uint, input *WorkflowTaskInput) (*model.WorkflowTask, error)
+	DeleteTask(userID, id uint) error
+	// RunTask 手动执行任务，inputs 覆盖任务中保存的同名参数
+	RunTask(userID, id uint, inputs map[string]interface{}) (*model.WorkflowExecution, error)
+	// RotateTriggerToken 为订阅入站 Webhook 的任务生成新的地址凭证，旧凭证失效，凭证只在生成时返回
+	RotateTriggerToken(userID, id uint) (string, error)
+	// TriggerWebhook 以入站 Webhook 的请求体为事件数据触发任务，未通过过滤条件时返回 nil
+	TriggerWebhook(token string, data map[string]interface{}) (*model.WorkflowExecution, error)
+
+	ListExecutions(userID uint, taskID *uint, page, pageSize int) ([]*model.WorkflowExecution, int64, error)
+	GetExecution(userID, id uint) (*model.WorkflowExecution, error)
+	// StopExecution 停止执行，尚未开始的步骤不再下发，已下发的 Agent 任务不会被中断，其结果被忽略
+	StopExecution(userID, id uint) (*model.WorkflowExecution, error)
+	// ListExecutionLogs 返回执行 afterID 之后的日志分段，包括系统日志与各步骤在各服务器上的任务输出
+	ListExecutionLogs(userID, id, afterID uint, limit int) (*LogPage, error)
+	Start(ctx context.Context)
+}
+
+// WorkflowInput 工作流参数，Definition 为 workflow 包定义的 JSON 对象，Status 为空时为 DRAFT
+type WorkflowInput struct {
+	Name        string
+	Code        string
+	Description string
+	Definition  []byte
+	Status      string
+}
+
+// WorkflowTaskInput 工作流任务参数，ScheduleType 为空时为 MANUAL，Status 为空时为 DEFAULT
+// TriggerFilter 与 TriggerInputs 中的表达式只能引用 event.*，TriggerInputs 渲染后覆盖 Inputs 中的同名参数
+type WorkflowTaskInput struct {
+	Name           string
+	WorkflowID     uint
+	ScheduleType   string
+	CronExpression string
+	Timezone       string
+	CatchUpPolicy  string
+	TriggerEvent   string
+	TriggerFilter  string
+	TriggerInputs  map[string]string
+	Inputs         map[string]interface{}
+	Status         string
+}
+
+// workflowEvent 触发执行的事件
+type workflowEvent struct {
+	Name string
+	Data map[string]interface{}
+}
+
+var workflowCodePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+type workflowService struct {
+	workflowRepo repository.WorkflowRepository
+	serverRepo   repository.ServerRepository
+	agentService AgentService
+	logs         LogService
+	wake         chan struct{}
+}
+
+func NewWorkflowService(workflowRepo repository.WorkflowRepository, serverRepo repository.ServerRepository,
+	agentService AgentService, webhooks WebhookService, logs LogService) WorkflowService {
+	s := &workflowService{
+		workflowRepo: workflowRepo,
+		serverRepo:   serverRepo,
+		agentService: agentService,
+		logs:         logs,
+		wake:         make(chan struct{}, 1),
+	}
+	for _, taskType := range constants.WorkflowStepTypes {
+		agentService.OnTaskResult(taskType, s.handleStepResult)
+		agentService.OnTaskLog(taskType, s.handleStepLog)
+	}
+	webhooks.OnEvent(s.handleEvent)
+	return s
+}
+
+func (s *workflowService) CreateWorkflow(ownerID uint, input *WorkflowInput) (*model.Workflow, error) {
+	wf := &model.Workflow{OwnerID: ownerID}
+	if err := s.applyWorkflowInput(wf, input); err != nil {
+		return nil, err
+	}
+	if err := s.workflowRepo.Create(wf); err != nil {
+		return nil, err
+	}
+	return wf, nil
+}
+
+func (s *workflowService) GetWorkflow(userID, id uint) (*model.Workflow, error) {
+	wf, err := s.workflowRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if wf.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return wf, nil
+}
+
+func (s *workflowService) ListWorkflows(userID uint, page, pageSize int) ([]*model.Workflow, int64, error) {
+	offset := (page - 1) * pageSize
+	return s.workflowRepo.List(userID, offset, pageSize)
+}
+
+// UpdateWorkflow 更新工作流，已开始的执行继续使用开始时的定义
+func (s *workflowService) UpdateWorkflow(userID, id uint, input *WorkflowInput) (*model.Workflow, error) {
+	wf, err := s.GetWorkflow(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyWorkflowInput(wf, input); err != nil {
+		return nil, err
+	}
+	if err := s.workflowRepo.Update(wf); err != nil {
+		return nil, err
+	}
+	return wf, nil
+}
+
+// DeleteWorkflow 删除工作流及其任务
+func (s *workflowService) DeleteWorkflow(userID, id uint) error {
+	if _, err := s.GetWorkflow(userID, id); err != nil {
+		return err
+	}
+	return s.workflowRepo.Delete(id)
+}
+
+func (s *workflowService) applyWorkflowInput(wf *model.Workflow, input *WorkflowInput) error {
+	if !workflowCodePattern.MatchString(input.Code) {
+		return invalidInputf("invalid workflow code: %s", input.Code)
+	}
+	existing, err := s.workflowRepo.GetByCode(wf.OwnerID, input.Code)
+	if err == nil && existing.ID != wf.ID {
+		return invalidInputf("workflow code %s already exists", input.Code)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	status := input.Status
+	switch status {
+	case "":
+		status = constants.WorkflowStatusDraft
+	case constants.WorkflowStatusDraft, constants.WorkflowStatusActive,
+		constants.WorkflowStatusInactive, constants.WorkflowStatusArchived:
+	default:
+		return invalidInputf("invalid workflow status: %s", status)
+	}
+
+	def, err := workflow.Parse(input.Definition)
+	if err != nil {
+		return invalidInput(err)
+	}
+	for _, step := range def.Steps {
+		if !slices.Contains(constants.WorkflowStepTypes, step.Type) {
+			return invalidInputf("step %s: unsupported type %s", step.ID, step.Type)
+		}
+		// 固定的服务器在保存时检查，模板渲染出的服务器在步骤开始时检查
+		for _, raw := range step.Servers {
+			if id, ok := raw.(float64); ok {
+				if err := s.checkServer(wf.OwnerID, uint(id)); err != nil {
+					return fmt.Errorf("step %s: %w", step.ID, err)
+				}
+			}
+		}
+	}
+
+	var definition bytes.Buffer
+	if err := json.Compact(&definition, input.Definition); err != nil {
+		return invalidInput(err)
+	}
+
+	wf.Name = input.Name
+	wf.Code = input.Code
+	wf.Description = input.Description
+	wf.Definition = definition.String()
+	wf.Status = status
+	return nil
+}
+
+// checkServer 确认服务器存在且属于 ownerID
+func (s *workflowService) checkServer(ownerID, serverID uint) error {
+	server, err := s.serverRepo.GetByID(serverID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalidInputf("server %d not found", serverID)
+		}
+		return err
+	}
+	if server.OwnerID != ownerID {
+		return fmt.Errorf("server %d: %w", serverID, ErrPermissionDenied)
+	}
+	return nil
+}
+
+func (s *workflowService) CreateTask(ownerID uint, input *WorkflowTaskInput) (*model.WorkflowTask, error) {
+	task := &model.WorkflowTask{OwnerID: ownerID}
+	if err := s.applyTaskInput(task, input); err != nil {
+		return nil, err
+	}
+	if err := s.workflowRepo.CreateTask(task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *workflowService) GetTask(userID, id uint) (*model.WorkflowTask, error) {
+	task, err := s.workflowRepo.GetTask(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if task.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return task, nil
+}
+
+func (s *workflowService) ListTasks(userID uint, workflowID *uint, page, pageSize int) ([]*model.WorkflowTask, int64, error) {
+	offset := (page - 1) * pageSize
+	return s.workflowRepo.ListTasks(userID, workflowID, offset, pageSize)
+}
+
+func (s *workflowService) UpdateTask(userID, id uint, input *WorkflowTaskInput) (*model.WorkflowTask, error) {
+	task, err := s.GetTask(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyTaskInput(task, input); err != nil {
+		return nil, err
+	}
+	if err := s.workflowRepo.UpdateTask(task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *workflowService) DeleteTask(userID, id uint) error {
+	if _, err := s.GetTask(userID, id); err != nil {
+		return err
+	}
+	return s.workflowRepo.DeleteTask(id)
+}
+
+func (s *workflowService) applyTaskInput(task *model.WorkflowTask, input *WorkflowTaskInput) error {
+	wf, err := s.GetWorkflow(task.OwnerID, input.WorkflowID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return invalidInputf("workflow %d not found", input.WorkflowID)
+		}
+		return err
+	}
+
+	status := input.Status
+	switch status {
+	case "":
+		status = constants.WorkflowTaskStatusDefault
+	case constants.WorkflowTaskStatusDefault, constants.WorkflowTaskStatusOnline, constants.WorkflowTaskStatusOffline:
+	default:
+		return invalidInputf("invalid task status: %s", status)
+	}
+
+	scheduleType := input.ScheduleType
+	if scheduleType == "" {
+		scheduleType = constants.WorkflowScheduleManual
+	}
+	cronExpression, timezone, catchUp := "", "UTC", constants.WorkflowCatchUpOnce
+	var nextRunAt *time.Time
+	triggerEvent, triggerFilter := "", ""
+	switch scheduleType {
+	case constants.WorkflowScheduleManual:
+	case constants.WorkflowScheduleTrigger:
+		triggerEvent = input.TriggerEvent
+		if _, ok := constants.WebhookEvents[triggerEvent]; !ok && triggerEvent != constants.WorkflowEventWebhook {
+			return invalidInputf("unsupported trigger event: %q", triggerEvent)
+		}
+		if input.TriggerFilter != "" {
+			expr, err := workflow.ParseExpr(input.TriggerFilter)
+			if err != nil {
+				return invalidInputf("invalid trigger filter: %v", err)
+			}
+			if err := checkEventPaths(expr.Paths()); err != nil {
+				return invalidInputf("invalid trigger filter: %v", err)
+			}
+			triggerFilter = input.TriggerFilter
+		}
+	case constants.WorkflowScheduleSchedule:
+		if input.CronExpression == "" {
+			return invalidInputf("cron expression is required for scheduled task")
+		}
+		if input.Timezone != "" {
+			timezone = input.Timezone
+		}
+		cron, err := schedule.ParseCron(input.CronExpression, timezone)
+		if err != nil {
+			return invalidInput(err)
+		}
+		switch input.CatchUpPolicy {
+		case "":
+		case constants.WorkflowCatchUpSkip, constants.WorkflowCatchUpOnce, constants.WorkflowCatchUpAll:
+			catchUp = input.CatchUpPolicy
+		default:
+			return invalidInputf("invalid catch up policy: %s", input.CatchUpPolicy)
+		}
+		cronExpression = input.CronExpression
+		if status != constants.WorkflowTaskStatusOffline {
+			next := cron.Next(time.Now())
+			if next.IsZero() {
+				return invalidInputf("cron expression %q never fires", cronExpression)
+			}
+			next = next.UTC()
+			nextRunAt = &next
+		}
+	default:
+		return invalidInputf("unsupported schedule type: %s", scheduleType)
+	}
+
+	def, err := workflow.Parse([]byte(wf.Definition))
+	if err != nil {
+		return err
+	}
+	if err := def.CheckInputs(input.Inputs); err != nil {
+		return invalidInput(err)
+	}
+	inputs := ""
+	if len(input.Inputs) > 0 {
+		data, err := json.Marshal(input.Inputs)
+		if err != nil {
+			return err
+		}
+		inputs = string(data)
+	}
+	triggerInputs := ""
+	if scheduleType == constants.WorkflowScheduleTrigger && len(input.TriggerInputs) > 0 {
+		for name, tmpl := range input.TriggerInputs {
+			if _, ok := def.Inputs[name]; !ok {
+				return invalidInputf("undeclared input %q", name)
+			}
+			paths, err := workflow.TemplatePaths(tmpl)
+			if err != nil {
+				return invalidInputf("trigger input %q: %v", name, err)
+			}
+			if err := checkEventPaths(paths); err != nil {
+				return invalidInputf("trigger input %q: %v", name, err)
+			}
+		}
+		data, err := json.Marshal(input.TriggerInputs)
+		if err != nil {
+			return err
+		}
+		triggerInputs = string(data)
+	}
+	// 不再订阅入站 Webhook 时原地址失效
+	if triggerEvent != constants.WorkflowEventWebhook {
+		task.TriggerToken = ""
+	}
+
+	task.Name = input.Name
+	task.WorkflowID = wf.ID
+	task.Workflow = *wf
+	task.ScheduleType = scheduleType
+	task.CronExpression = cronExpression
+	task.Timezone = timezone
+	task.CatchUpPolicy = catchUp
+	task.NextRunAt = nextRunAt
+	task.TriggerEvent = triggerEvent
+	task.TriggerFilter = triggerFilter
+	task.TriggerInputs = triggerInputs
+	task.Inputs = inputs
+	task.Status = status
+	return nil
+}
+
+func (s *workflowService) RunTask(userID, id uint, inputs map[string]interface{}) (*model.WorkflowExecution, error) {
+	task, err := s.GetTask(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(task, constants.WorkflowTriggerManual, &userID, inputs, nil)
+}
+
+func (s *workflowService) RotateTriggerToken(userID, id uint) (string, error) {
+	task, err := s.GetTask(userID, id)
+	if err != nil {
+		return "", err
+	}
+	if task.ScheduleType != constants.WorkflowScheduleTrigger || task.TriggerEvent != constants.WorkflowEventWebhook {
+		return "", invalidInputf("workflow task is not triggered by inbound webhook")
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := s.workflowRepo.UpdateTriggerToken(task.ID, hashTriggerToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *workflowService) TriggerWebhook(token string, data map[string]interface{}) (*model.WorkflowExecution, error) {
+	task, err := s.workflowRepo.GetTaskByTriggerToken(hashTriggerToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if task.ScheduleType != constants.WorkflowScheduleTrigger || task.TriggerEvent != constants.WorkflowEventWebhook {
+		return nil, ErrNotFound
+	}
+	return s.trigger(task, constants.WorkflowEventWebhook, data)
+}
+
+// handleEvent 触发所有者订阅了该事件的任务，事件数据转换为 JSON 对象后作为 event 上下文
+func (s *workflowService) handleEvent(ownerID uint, event string, data interface{}) {
+	tasks, err := s.workflowRepo.ListTriggerTasks(ownerID, event)
+	if err != nil {
+		log.Printf("Failed to list workflow tasks triggered by %s: %v", event, err)
+		return
+	}
+	if len(tasks) == 0 {
+		return
+	}
+
+	payload := make(map[string]interface{})
+	raw, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(raw, &payload)
+	}
+	if err != nil {
+		log.Printf("Failed to decode event %s for workflow tasks: %v", event, err)
+		return
+	}
+	for _, task := range tasks {
+		if _, err := s.trigger(task, event, payload); err != nil {
+			log.Printf("Failed to trigger workflow task %d by %s: %v", task.ID, event, err)
+		}
+	}
+}
+
+// trigger 事件通过任务的过滤条件时，以事件数据渲染 trigger_inputs 并创建执行，未通过时返回 nil
+func (s *workflowService) trigger(task *model.WorkflowTask, event string, data map[string]interface{}) (*model.WorkflowExecution, error) {
+	ctx := map[string]interface{}{"event": data}
+	if task.TriggerFilter != "" {
+		expr, err := workflow.ParseExpr(task.TriggerFilter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trigger filter: %v", err)
+		}
+		if !expr.Match(ctx) {
+			return nil, nil
+		}
+	}
+
+	inputs := make(map[string]interface{})
+	if task.TriggerInputs != "" {
+		var templates map[string]string
+		if err := json.Unmarshal([]byte(task.TriggerInputs), &templates); err != nil {
+			return nil, fmt.Errorf("invalid trigger inputs: %v", err)
+		}
+		for name, tmpl := range templates {
+			value, err := workflow.Render(tmpl, ctx)
+			if err != nil {
+				return nil, fmt.Errorf("trigger input %q: %v", name, err)
+			}
+			inputs[name] = value
+		}
+	}
+	return s.run(task, constants.WorkflowTriggerEvent, nil, inputs, &workflowEvent{Name: event, Data: data})
+}
+
+// run 按任务中保存的参数与 inputs 创建一次执行，由后台任务开始运行；event 为触发执行的事件，可为 nil
+func (s *workflowService) run(task *model.WorkflowTask, triggerType string, triggerBy *uint, inputs map[string]interface{},
+	event *workflowEvent) (*model.WorkflowExecution, error) {
+	if task.Status == constants.WorkflowTaskStatusOffline {
+		return nil, invalidInputf("workflow task is offline")
+	}
+	wf := &task.Workflow
+	if wf.Status == constants.WorkflowStatusInactive || wf.Status == constants.WorkflowStatusArchived {
+		return nil, invalidInputf("workflow is %s", wf.Status)
+	}
+	def, err := workflow.Parse([]byte(wf.Definition))
+	if err != nil {
+		return nil, err
+	}
+
+	given := make(map[string]interface{})
+	if task.Inputs != "" {
+		if err := json.Unmarshal([]byte(task.Inputs), &given); err != nil {
+			return nil, fmt.Errorf("invalid task inputs: %v", err)
+		}
+	}
+	for name, value := range inputs {
+		given[name] = value
+	}
+	resolved, err := def.ResolveInputs(given)
+	if err != nil {
+		return nil, invalidInput(err)
+	}
+	data, err := json.Marshal(resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	execution := &model.WorkflowExecution{
+		TaskID:      task.ID,
+		WorkflowID:  wf.ID,
+		ExecutionID: uuid.NewString(),
+		Definition:  wf.Definition,
+		Inputs:      string(data),
+		Status:      constants.WorkflowExecutionPending,
+		TriggerType: triggerType,
+		TriggerBy:   triggerBy,
+		OwnerID:     task.OwnerID,
+	}
+	created := triggerType
+	if event != nil {
+		eventData, err := json.Marshal(event.Data)
+		if err != nil {
+			return nil, err
+		}
+		execution.TriggerEvent = event.Name
+		execution.EventData = string(eventData)
+		created += " " + event.Name
+	}
+	if err := s.workflowRepo.CreateExecution(execution); err != nil {
+		return nil, err
+	}
+	s.appendLog(execution, "Execution created (%s)", created)
+	if err := s.workflowRepo.CountRun(task.ID, execution.CreatedAt); err != nil {
+		log.Printf("Failed to count run of workflow task %d: %v", task.ID, err)
+	}
+
+	s.notify()
+	return execution, nil
+}
+
+func (s *workflowService) ListExecutions(userID uint, taskID *uint, page, pageSize int) ([]*model.WorkflowExecution, int64, error) {
+	offset := (page - 1) * pageSize
+	return s.workflowRepo.ListExecutions(userID, taskID, offset, pageSize)
+}
+
+func (s *workflowService) GetExecution(userID, id uint) (*model.WorkflowExecution, error) {
+	execution, err := s.workflowRepo.GetExecution(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if execution.OwnerID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return execution, nil
+}
+
+func (s *workflowService) ListExecutionLogs(userID, id, afterID uint, limit int) (*LogPage, error) {
+	execution, err := s.GetExecution(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := s.logs.List(constants.LogTargetWorkflowExecution, execution.ID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	finished := execution.Status != constants.WorkflowExecutionPending && execution.Status != constants.WorkflowExecutionRunning
+	return newLogPage(segments, afterID, execution.Status, finished), nil
+}
+
+func (s *workflowService) StopExecution(userID, id uint) (*model.WorkflowExecution, error) {
+	execution, err := s.GetExecution(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	stopped, err := s.finish(execution, constants.WorkflowExecutionStopped, fmt.Sprintf("stopped by user %d", userID))
+	if err != nil {
+		return nil, err
+	}
+	if !stopped {
+		return nil, invalidInputf("execution has already finished")
+	}
+	if err := s.workflowRepo.StopStepRuns(execution.ID, *execution.EndTime); err != nil {
+		return nil, err
+	}
+	return s.workflowRepo.GetExecution(id)
+}
+
+// Start 启动执行引擎
+func (s *workflowService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(constants.WorkflowPollInterval)
+		defer ticker.Stop()
+
+		for {
+			s.fireDue(time.Now().UTC())
+			s.advanceAll(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// fireDue 触发已到期的定时任务
+// 各实例以读取到的 next_run_at 为条件推进 next_run_at，只有推进成功的实例创建执行；
+// 错过的触发时间按任务的 catch_up_policy 跳过、补执行一次或逐次补执行
+func (s *workflowService) fireDue(now time.Time) {
+	tasks, err := s.workflowRepo.ListDueTasks(now)
+	if err != nil {
+		log.Printf("Failed to list due workflow tasks: %v", err)
+		return
+	}
+	for _, task := range tasks {
+		s.fireTask(task, now)
+	}
+}
+
+func (s *workflowService) fireTask(task *model.WorkflowTask, now time.Time) {
+	due := task.NextRunAt.UTC()
+	cron, err := schedule.ParseCron(task.CronExpression, task.Timezone)
+	if err != nil {
+		// 表达式无法解析时停止调度，修改任务后重新计算
+		log.Printf("Disable schedule of workflow task %d: %v", task.ID, err)
+		if _, err := s.workflowRepo.ClaimSchedule(task.ID, due, nil); err != nil {
+			log.Printf("Failed to update workflow task %d: %v", task.ID, err)
+		}
+		return
+	}
+
+	// 补执行的触发时间，ALL 策略下最多保留最近 MaxWorkflowCatchUpRuns 次
+	fires := []time.Time{due}
+	late := now.Sub(due) > constants.WorkflowScheduleGrace
+	if late {
+		switch task.CatchUpPolicy {
+		case constants.WorkflowCatchUpSkip:
+			fires = nil
+		case constants.WorkflowCatchUpAll:
+			for t := cron.Next(due); !t.IsZero() && !t.After(now); t = cron.Next(t) {
+				fires = append(fires, t.UTC())
+			}
+			if len(fires) > constants.MaxWorkflowCatchUpRuns {
+				fires = fires[len(fires)-constants.MaxWorkflowCatchUpRuns:]
+			}
+		}
+	}
+
+	var next *time.Time
+	if t := cron.Next(now); !t.IsZero() {
+		t = t.UTC()
+		next = &t
+	}
+	claimed, err := s.workflowRepo.ClaimSchedule(task.ID, due, next)
+	if err != nil {
+		log.Printf("Failed to claim schedule of workflow task %d: %v", task.ID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	if len(fires) == 0 {
+		log.Printf("Skip missed run of workflow task %d scheduled at %s", task.ID, due.Format(time.RFC3339))
+		return
+	}
+	for _, at := range fires {
+		execution, err := s.run(task, constants.WorkflowTriggerSchedule, nil, nil, nil)
+		if err != nil {
+			log.Printf("Failed to run scheduled workflow task %d: %v", task.ID, err)
+			return
+		}
+		if late {
+			s.appendLog(execution, "Catch up missed run scheduled at %s", at.Format(time.RFC3339))
+		}
+	}
+}
+
+func (s *workflowService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *workflowService) advanceAll(ctx context.Context) {
+	executions, err := s.workflowRepo.ListActiveExecutions()
+	if err != nil {
+		log.Printf("Failed to list active workflow executions: %v", err)
+		return
+	}
+	for _, execution := range executions {
+		if ctx.Err() != nil {
+			return
+		}
+		s.advance(execution)
+	}
+}
+
+// advance 推进一次执行：处理超时、下发到期的步骤运行、开始依赖已结束的步骤，全部步骤结束后结束执行
+func (s *workflowService) advance(execution *model.WorkflowExecution) {
+	def, err := workflow.Parse([]byte(execution.Definition))
+	if err != nil {
+		s.finishLogged(execution, constants.WorkflowExecutionFailure, err.Error())
+		return
+	}
+
+	now := time.Now()
+	if execution.Status == constants.WorkflowExecutionPending {
+		started, err := s.workflowRepo.StartExecution(execution.ID, now)
+		if err != nil {
+			log.Printf("Failed to start workflow execution %s: %v", execution.ExecutionID, err)
+			return
+		}
+		if !started {
+			return
+		}
+		execution.Status = constants.WorkflowExecutionRunning
+		execution.StartTime = &now
+		s.appendLog(execution, "Execution started")
+	}
+
+	var inputs map[string]interface{}
+	if err := json.Unmarshal([]byte(execution.Inputs), &inputs); err != nil {
+		s.finishLogged(execution, constants.WorkflowExecutionFailure, fmt.Sprintf("invalid inputs: %v", err))
+		return
+	}
+
+	// 步骤结束或新步骤开始后继续推进，直到没有变化，每一轮至少结束或开始一个步骤
+	for i := 0; i <= 2*len(def.Steps); i++ {
+		runs, err := s.workflowRepo.ListStepRuns(execution.ID)
+		if err != nil {
+			log.Printf("Failed to list step runs of workflow execution %s: %v", execution.ExecutionID, err)
+			return
+		}
+
+		finished := s.advanceRuns(execution, def, runs, time.Now())
+		if finished {
+			continue
+		}
+
+		planned, done := s.planSteps(execution, def, inputs, runs, time.Now())
+		if done {
+			status, message := executionResult(def, runs)
+			s.finishLogged(execution, status, message)
+			return
+		}
+		if len(planned) == 0 {
+			return
+		}
+		if err := s.workflowRepo.CreateStepRuns(planned); err != nil {
+			log.Printf("Failed to create step runs of workflow execution %s: %v", execution.ExecutionID, err)
+			return
+		}
+		for _, run := range planned {
+			if run.Status != constants.WorkflowStepPending {
+				s.appendLog(execution, "Step %s %s: %s", run.StepID, stepRunVerb(run.Status), run.Message)
+			}
+		}
+	}
+}
+
+// advanceRuns 处理超时与到期的步骤运行，有步骤运行结束时返回 true
+func (s *workflowService) advanceRuns(execution *model.WorkflowExecution, def *workflow.Definition,
+	runs []*model.WorkflowStepRun, now time.Time) bool {
+	finished := false
+	for _, run := range runs {
+		step := def.Step(run.StepID)
+		if step == nil {
+			continue
+		}
+		switch {
+		case run.Status == constants.WorkflowStepRunning && run.Deadline != nil && now.After(*run.Deadline):
+			result := &workflow.Result{Status: "timeout", Message: "no result received before the step timeout"}
+			finished = s.completeRun(execution, step, run, run.AgentTaskID, result) || finished
+		case run.Status == constants.WorkflowStepPending && run.NextRunAt != nil && !run.NextRunAt.After(now):
+			finished = s.dispatchRun(execution, step, run, now) || finished
+		}
+	}
+	return finished
+}
+
+// dispatchRun 领取并下发步骤运行，下发失败按任务失败处理，步骤运行因此结束时返回 true
+func (s *workflowService) dispatchRun(execution *model.WorkflowExecution, step *workflow.Step,
+	run *model.WorkflowStepRun, now time.Time) bool {
+	timeout := step.Timeout
+	if timeout == 0 {
+		timeout = constants.DefaultWorkflowStepTimeout
+	}
+	deadline := now.Add(time.Duration(timeout)*time.Second + constants.WorkflowResultGrace)
+	run.AgentTaskID = uuid.NewString()
+	run.Deadline = &deadline
+
+	claimed, err := s.workflowRepo.ClaimStepRun(run, now)
+	if err != nil {
+		log.Printf("Failed to claim workflow step run %d: %v", run.ID, err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	var params map[string]interface{}
+	if err := json.Unmarshal([]byte(run.Params), &params); err != nil {
+		return s.completeRun(execution, step, run, run.AgentTaskID,
+			&workflow.Result{Status: constants.AppStatusFailed, Message: fmt.Sprintf("invalid params: %v", err)})
+	}
+
+	s.appendLog(execution, "Step %s on server %d: dispatched task %s (attempt %d)",
+		run.StepID, run.ServerID, run.AgentTaskID, run.Attempt)
+	task := &AgentTask{
+		ID:      run.AgentTaskID,
+		Type:    step.Type,
+		Params:  params,
+		Timeout: timeout,
+	}
+	if err := s.agentService.DispatchTask(run.ServerID, task); err != nil {
+		return s.completeRun(execution, step, run, run.AgentTaskID,
+			&workflow.Result{Status: constants.AppStatusFailed, Message: fmt.Sprintf("failed to dispatch task: %v", err)})
+	}
+	return false
+}
+
+// completeRun 记录任务结果，失败时在重试次数内安排重试，步骤运行因此结束时返回 true
+func (s *workflowService) completeRun(execution *model.WorkflowExecution, step *workflow.Step,
+	run *model.WorkflowStepRun, agentTaskID string, result *workflow.Result) bool {
+	now := time.Now()
+	run.Message = result.Message
+	run.Output = ""
+	if output, ok := result.Data["output"].(string); ok {
+		// 只保留输出的末尾，错误信息通常在最后
+		if len(output) > constants.WorkflowMaxStepOutput {
+			output = output[len(output)-constants.WorkflowMaxStepOutput:]
+		}
+		run.Output = output
+	}
+
+	var retryIn time.Duration
+	switch {
+	case result.Status == constants.AppStatusSuccess:
+		outputs, err := json.Marshal(step.ExtractOutputs(result))
+		if err != nil {
+			log.Printf("Failed to encode outputs of workflow step run %d: %v", run.ID, err)
+		}
+		run.Status = constants.WorkflowStepSuccess
+		run.Outputs = string(outputs)
+		run.NextRunAt = nil
+		run.EndedAt = &now
+	case run.Attempt <= step.Retries:
+		retryIn = time.Duration(step.RetryDelay) * time.Second
+		next := now.Add(retryIn)
+		run.Status = constants.WorkflowStepPending
+		run.NextRunAt = &next
+		run.Deadline = nil
+	default:
+		run.Status = constants.WorkflowStepFailure
+		run.NextRunAt = nil
+		run.EndedAt = &now
+	}
+
+	updated, err := s.workflowRepo.FinishStepRun(run, agentTaskID)
+	if err != nil {
+		log.Printf("Failed to update workflow step run %d: %v", run.ID, err)
+		return false
+	}
+	if !updated {
+		return false
+	}
+
+	if run.Status == constants.WorkflowStepPending {
+		s.appendLog(execution, "Step %s on server %d: %s (attempt %d), retrying in %s: %s",
+			run.StepID, run.ServerID, result.Status, run.Attempt, retryIn, result.Message)
+		return false
+	}
+	s.appendLog(execution, "Step %s on server %d: %s (attempt %d): %s",
+		run.StepID, run.ServerID, stepRunVerb(run.Status), run.Attempt, result.Message)
+	return true
+}
+
+// handleStepResult 处理 Agent 返回的步骤任务结果，不属于工作流的任务被忽略
+func (s *workflowService) handleStepResult(agent *model.ServerAgent, result *AgentTaskResult) {
+	run, err := s.workflowRepo.GetStepRunByTaskID(result.TaskID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to load workflow step run for task %s: %v", result.TaskID, err)
+		}
+		return
+	}
+	if run.ServerID != agent.ServerID {
+		log.Printf("Ignored result of task %s from agent %s of server %d", result.TaskID, agent.AgentID, agent.ServerID)
+		return
+	}
+	if run.Status != constants.WorkflowStepRunning {
+		return
+	}
+	if output, ok := result.Data["output"].(string); ok {
+		if err := s.logs.AppendTaskOutput(constants.LogTargetWorkflowExecution, run.ExecutionID, stepLogStream(run),
+			result.TaskID, output); err != nil {
+			log.Printf("Failed to save output of workflow step run %d: %v", run.ID, err)
+		}
+	}
+
+	execution, err := s.workflowRepo.GetExecution(run.ExecutionID)
+	if err != nil {
+		log.Printf("Failed to load workflow execution %d: %v", run.ExecutionID, err)
+		return
+	}
+	def, err := workflow.Parse([]byte(execution.Definition))
+	if err != nil {
+		log.Printf("Invalid definition of workflow execution %s: %v", execution.ExecutionID, err)
+		return
+	}
+	step := def.Step(run.StepID)
+	if step == nil {
+		return
+	}
+
+	s.completeRun(execution, step, run, result.TaskID, &workflow.Result{
+		Status:  result.Status,
+		Message: result.Message,
+		Data:    result.Data,
+	})
+	s.notify()
+}
+
+// handleStepLog 保存步骤任务推送的实时输出
+func (s *workflowService) handleStepLog(agent *model.ServerAgent, taskLog *AgentTaskLog) {
+	run, err := s.workflowRepo.GetStepRunByTaskID(taskLog.TaskID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to load workflow step run for task %s: %v", taskLog.TaskID, err)
+		}
+		return
+	}
+	if run.ServerID != agent.ServerID {
+		log.Printf("Ignored log of task %s from agent %s of server %d", taskLog.TaskID, agent.AgentID, agent.ServerID)
+		return
+	}
+	if err := s.logs.AppendTaskLog(constants.LogTargetWorkflowExecution, run.ExecutionID, stepLogStream(run), taskLog); err != nil {
+		log.Printf("Failed to save log of workflow step run %d: %v", run.ID, err)
+	}
+}
+
+// planSteps 为依赖已全部结束的步骤创建运行记录：需要执行的步骤在每台目标服务器上创建一条等待记录，
+// 跳过或无法开始的步骤创建一条已结束的记录；全部步骤结束时 done 为 true
+func (s *workflowService) planSteps(execution *model.WorkflowExecution, def *workflow.Definition,
+	inputs map[string]interface{}, runs []*model.WorkflowStepRun, now time.Time) (planned []*model.WorkflowStepRun, done bool) {
+	byStep := groupStepRuns(runs)
+	statuses := make(map[string]string)
+	steps := make(map[string]interface{})
+	for _, step := range def.Order() {
+		if status := stepStatus(byStep[step.ID]); status != "" {
+			statuses[step.ID] = status
+			steps[step.ID] = map[string]interface{}{
+				"status":  status,
+				"outputs": stepOutputs(byStep[step.ID]),
+			}
+		}
+	}
+	ctx := map[string]interface{}{
+		"inputs": inputs,
+		"steps":  steps,
+		"execution": map[string]interface{}{
+			"id":           execution.ExecutionID,
+			"task_id":      execution.TaskID,
+			"workflow_id":  execution.WorkflowID,
+			"trigger_type": execution.TriggerType,
+		},
+	}
+
+	done = true
+	for _, step := range def.Order() {
+		if statuses[step.ID] != "" {
+			continue
+		}
+		done = false
+		if len(byStep[step.ID]) > 0 {
+			continue
+		}
+
+		ready := true
+		for _, dep := range step.DependsOn {
+			if statuses[dep] == "" {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			planned = append(planned, s.planStep(execution, step, statuses, ctx, now)...)
+		}
+	}
+	return planned, done
+}
+
+func (s *workflowService) planStep(execution *model.WorkflowExecution, step *workflow.Step,
+	statuses map[string]string, ctx map[string]interface{}, now time.Time) []*model.WorkflowStepRun {
+	ended := func(status, message string) []*model.WorkflowStepRun {
+		return []*model.WorkflowStepRun{{
+			ExecutionID: execution.ID,
+			StepID:      step.ID,
+			Type:        step.Type,
+			Status:      status,
+			Message:     message,
+			EndedAt:     &now,
+		}}
+	}
+
+	if !step.Ready(statuses, ctx) {
+		if step.If != "" {
+			return ended(constants.WorkflowStepSkipped, fmt.Sprintf("condition %s is false", step.If))
+		}
+		return ended(constants.WorkflowStepSkipped, "not all dependencies succeeded")
+	}
+
+	servers, err := step.ResolveServers(ctx)
+	if err != nil {
+		return ended(constants.WorkflowStepFailure, err.Error())
+	}
+	for _, serverID := range servers {
+		if err := s.checkServer(execution.OwnerID, serverID); err != nil {
+			return ended(constants.WorkflowStepFailure, err.Error())
+		}
+	}
+	params, err := step.RenderParams(ctx)
+	if err != nil {
+		return ended(constants.WorkflowStepFailure, err.Error())
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ended(constants.WorkflowStepFailure, err.Error())
+	}
+
+	runs := make([]*model.WorkflowStepRun, len(servers))
+	for i, serverID := range servers {
+		runs[i] = &model.WorkflowStepRun{
+			ExecutionID: execution.ID,
+			StepID:      step.ID,
+			ServerID:    serverID,
+			Type:        step.Type,
+			Status:      constants.WorkflowStepPending,
+			Params:      string(data),
+			NextRunAt:   &now,
+		}
+	}
+	return runs
+}
+
+// finish 结束执行并累计任务的成功或失败次数，执行已结束时返回 false
+func (s *workflowService) finish(execution *model.WorkflowExecution, status, message string) (bool, error) {
+	now := time.Now()
+	start := execution.CreatedAt
+	if execution.StartTime != nil {
+		start = *execution.StartTime
+	}
+	execution.Status = status
+	execution.EndTime = &now
+	execution.Duration = int(now.Sub(start).Seconds())
+	execution.ErrorMessage = message
+
+	finished, err := s.workflowRepo.FinishExecution(execution)
+	if err != nil || !finished {
+		return finished, err
+	}
+
+	s.appendLog(execution, "Execution finished with status %s in %ds", status, execution.Duration)
+	if status != constants.WorkflowExecutionStopped {
+		if err := s.workflowRepo.CountResult(execution.TaskID, status == constants.WorkflowExecutionSuccess); err != nil {
+			log.Printf("Failed to count result of workflow task %d: %v", execution.TaskID, err)
+		}
+	}
+	return true, nil
+}
+
+func (s *workflowService) finishLogged(execution *model.WorkflowExecution, status, message string) {
+	if _, err := s.finish(execution, status, message); err != nil {
+		log.Printf("Failed to finish workflow execution %s: %v", execution.ExecutionID, err)
+	}
+}
+
+func (s *workflowService) appendLog(execution *model.WorkflowExecution, format string, args ...interface{}) {
+	line := workflowLogLine(time.Now(), format, args...)
+	if err := s.logs.AppendLine(constants.LogTargetWorkflowExecution, execution.ID, line); err != nil {
+		log.Printf("Failed to append log of workflow execution %s: %v", execution.ExecutionID, err)
+	}
+}
+
+// checkEventPaths 触发条件与参数模板只能引用事件数据
+func checkEventPaths(paths []string) error {
+	for _, path := range paths {
+		if path != "event" && !strings.HasPrefix(path, "event.") {
+			return invalidInputf("%s is not an event field, only event.* can be referenced", path)
+		}
+	}
+	return nil
+}
+
+// hashTriggerToken 入站 Webhook 凭证只保存 SHA-256
+func hashTriggerToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// stepLogStream 步骤任务输出的来源标识
+func stepLogStream(run *model.WorkflowStepRun) string {
+	return fmt.Sprintf("%s@%d", run.StepID, run.ServerID)
+}
+
+func workflowLogLine(at time.Time, format string, args ...interface{}) string {
+	return at.UTC().Format(time.RFC3339) + " " + fmt.Sprintf(format, args...) + "\n"
+}
+
+func stepRunVerb(status string) string {
+	switch status {
+	case constants.WorkflowStepSuccess:
+		return "succeeded"
+	case constants.WorkflowStepFailure:
+		return "failed"
+	case constants.WorkflowStepSkipped:
+		return "skipped"
+	}
+	return status
+}
+
+func groupStepRuns(runs []*model.WorkflowStepRun) map[string][]*model.WorkflowStepRun {
+	byStep := make(map[string][]*model.WorkflowStepRun)
+	for _, run := range runs {
+		byStep[run.StepID] = append(byStep[run.StepID], run)
+	}
+	return byStep
+}
+
+// stepStatus 汇总步骤在各服务器上的运行状态，尚未结束时返回空字符串；
+// 任一服务器失败则步骤失败，全部跳过则步骤跳过
+func stepStatus(runs []*model.WorkflowStepRun) string {
+	if len(runs) == 0 {
+		return ""
+	}
+	status := workflow.StatusSkipped
+	for _, run := range runs {
+		switch run.Status {
+		case constants.WorkflowStepSuccess:
+			if status == workflow.StatusSkipped {
+				status = workflow.StatusSuccess
+			}
+		case constants.WorkflowStepFailure, constants.WorkflowStepStopped:
+			status = workflow.StatusFailure
+		case constants.WorkflowStepSkipped:
+		default:
+			return ""
+		}
+	}
+	return status
+}
+
+// stepOutputs 返回步骤的输出，多台服务器时取第一台成功服务器的输出
+func stepOutputs(runs []*model.WorkflowStepRun) map[string]string {
+	outputs := make(map[string]string)
+	for _, run := range runs {
+		if run.Status == constants.WorkflowStepSuccess && run.Outputs != "" {
+			if err := json.Unmarshal([]byte(run.Outputs), &outputs); err == nil {
+				break
+			}
+		}
+	}
+	return outputs
+}
+
+// executionResult 全部步骤结束后的执行状态，任一步骤失败则执行失败
+func executionResult(def *workflow.Definition, runs []*model.WorkflowStepRun) (string, string) {
+	byStep := groupStepRuns(runs)
+	for _, step := range def.Order() {
+		if stepStatus(byStep[step.ID]) != workflow.StatusFailure {
+			continue
+		}
+		for _, run := range byStep[step.ID] {
+			if run.Status == constants.WorkflowStepFailure {
+				return constants.WorkflowExecutionFailure, fmt.Sprintf("step %s failed: %s", step.ID, run.Message)
+			}
+		}
+		return constants.WorkflowExecutionFailure, fmt.Sprintf("step %s failed", step.ID)
+	}
+	return constants.WorkflowExecutionSuccess, ""
+}
@@ -0,0 +1,50 @@
+package telemetry
+
+import (
+	"api-service/internal/constants"
+	"log"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AgentCounter 统计已注册的 Agent 与在线 Agent 数量
+type AgentCounter interface {
+	CountAgents() (registered, connected int64, err error)
+}
+
+// agentCollector 在每次抓取时查询 Agent 数量
+type agentCollector struct {
+	counter    AgentCounter
+	registered *prometheus.Desc
+	connected  *prometheus.Desc
+}
+
+// RegisterAgentCollector 注册 Agent 数量指标
+func RegisterAgentCollector(counter AgentCounter) {
+	registry.MustRegister(&agentCollector{
+		counter: counter,
+		registered: prometheus.NewDesc(
+			prometheus.BuildFQName(constants.TelemetryNamespace, "agent", "registered"),
+			"Agents registered to servers.", nil, nil),
+		connected: prometheus.NewDesc(
+			prometheus.BuildFQName(constants.TelemetryNamespace, "agent", "connected"),
+			"Agents with a heartbeat within the connection window.", nil, nil),
+	})
+}
+
+func (c *agentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.registered
+	ch <- c.connected
+}
+
+func (c *agentCollector) Collect(ch chan<- prometheus.Metric) {
+	registered, connected, err := c.counter.CountAgents()
+	if err != nil {
+		log.Printf("Failed to count agents: %v", err)
+		ch <- prometheus.NewInvalidMetric(c.registered, err)
+		ch <- prometheus.NewInvalidMetric(c.connected, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.registered, prometheus.GaugeValue, float64(registered))
+	ch <- prometheus.MustNewConstMetric(c.connected, prometheus.GaugeValue, float64(connected))
+}
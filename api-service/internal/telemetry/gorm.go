@@ -0,0 +1,56 @@
+package telemetry
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const dbStartKey = "telemetry:start"
+
+// InstrumentDB 注册 GORM 回调，按操作类型与表名记录查询耗时
+func InstrumentDB(db *gorm.DB) error {
+	cb := db.Callback()
+	steps := []error{
+		cb.Create().Before("gorm:create").Register("telemetry:before_create", beforeQuery),
+		cb.Create().After("gorm:create").Register("telemetry:after_create", afterQuery("create")),
+		cb.Query().Before("gorm:query").Register("telemetry:before_query", beforeQuery),
+		cb.Query().After("gorm:query").Register("telemetry:after_query", afterQuery("query")),
+		cb.Update().Before("gorm:update").Register("telemetry:before_update", beforeQuery),
+		cb.Update().After("gorm:update").Register("telemetry:after_update", afterQuery("update")),
+		cb.Delete().Before("gorm:delete").Register("telemetry:before_delete", beforeQuery),
+		cb.Delete().After("gorm:delete").Register("telemetry:after_delete", afterQuery("delete")),
+		cb.Row().Before("gorm:row").Register("telemetry:before_row", beforeQuery),
+		cb.Row().After("gorm:row").Register("telemetry:after_row", afterQuery("row")),
+		cb.Raw().Before("gorm:raw").Register("telemetry:before_raw", beforeQuery),
+		cb.Raw().After("gorm:raw").Register("telemetry:after_raw", afterQuery("raw")),
+	}
+	for _, err := range steps {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func beforeQuery(db *gorm.DB) {
+	db.InstanceSet(dbStartKey, time.Now())
+}
+
+func afterQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(dbStartKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		ObserveDBQuery(operation, table, time.Since(start))
+	}
+}
@@ -0,0 +1,75 @@
+// Package telemetry 提供 api-service 自身运行指标的 Prometheus 暴露
+package telemetry
+
+import (
+	"api-service/internal/constants"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registry 独立的指标注册表，不包含第三方库注册到默认注册表的指标
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: constants.TelemetryNamespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: constants.TelemetryNamespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Database query latency by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "table"})
+
+	taskDispatches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.TelemetryNamespace,
+		Subsystem: "agent",
+		Name:      "task_dispatches_total",
+		Help:      "Tasks dispatched to agents by task type and result.",
+	}, []string{"type", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		dbQueryDuration,
+		taskDispatches,
+	)
+}
+
+// Handler 返回 /metrics 处理器，按 Accept 头协商 Prometheus 文本格式或 OpenMetrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// ObserveHTTPRequest 记录 HTTP 请求耗时，route 为路由模板以限制标签基数
+func ObserveHTTPRequest(method, route string, status int, d time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(d.Seconds())
+}
+
+// ObserveDBQuery 记录数据库查询耗时
+func ObserveDBQuery(operation, table string, d time.Duration) {
+	dbQueryDuration.WithLabelValues(operation, table).Observe(d.Seconds())
+}
+
+// CountTaskDispatch 记录一次任务下发
+func CountTaskDispatch(taskType string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	taskDispatches.WithLabelValues(taskType, result).Inc()
+}
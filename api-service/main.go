@@ -1,10 +1,13 @@
 package main
 
 import (
+	"api-service/internal/agentrpc"
 	"api-service/internal/config"
 	"api-service/internal/database"
 	"api-service/internal/router"
 	"api-service/internal/service"
+	"api-service/internal/telemetry"
+	"context"
 	"log"
 )
 
@@ -21,20 +24,56 @@ func main() {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
+	// 记录数据库查询耗时
+	if err := telemetry.InstrumentDB(db); err != nil {
+		log.Fatal("Failed to instrument database:", err)
+	}
+
+	// 自动迁移数据库表结构
+	if err := database.AutoMigrate(db); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
 	// 初始化Redis
 	rdb, err := database.InitRedis(cfg)
 	if err != nil {
 		log.Fatal("Failed to initialize Redis:", err)
 	}
 
-	// 初始化InfluxDB
-	influxClient, err := database.InitInfluxDB(cfg)
+	// 初始化指标存储(InfluxDB 或内置 SQLite)
+	metricsStore, err := database.InitMetricsStore(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize metrics store:", err)
+	}
+	defer metricsStore.Close()
+
+	// 初始化用户文件存储(本地目录或兼容 S3 的对象存储)
+	blobStore, err := database.InitBlobStore(cfg)
 	if err != nil {
-		log.Fatal("Failed to initialize InfluxDB:", err)
+		log.Fatal("Failed to initialize blob store:", err)
 	}
 
 	// 初始化服务
-	services := service.NewServices(db, rdb, influxClient, cfg)
+	services, err := service.NewServices(db, rdb, metricsStore, blobStore, cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize services:", err)
+	}
+
+	// 在 /metrics 中暴露 Agent 数量
+	telemetry.RegisterAgentCollector(services.AgentService)
+
+	// 启动后台任务
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	services.Start(ctx)
+
+	// 启动 Agent gRPC 服务，接收心跳与指标上报
+	go func() {
+		rpcServer := agentrpc.NewServer(services.AgentService, services.MonitorService)
+		if err := rpcServer.Serve(ctx, ":"+cfg.GRPC.Port, cfg.GRPC.TLSCert, cfg.GRPC.TLSKey); err != nil {
+			log.Fatal("Failed to start agent gRPC server:", err)
+		}
+	}()
 
 	// 初始化路由
 	r := router.SetupRouter(services, cfg)
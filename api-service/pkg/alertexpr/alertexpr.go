@@ -0,0 +1,249 @@
+// Package alertexpr 解析告警规则的条件表达式
+//
+// 语法: <函数>(<指标>, <窗口>) <比较符> <阈值> [for <持续时长>]
+// 例如 avg(cpu.usage, 5m) > 90 for 10m，表示 5 分钟平均 CPU 使用率持续 10 分钟高于 90 时告警。
+// 函数为 avg、min、max、last，比较符为 >、>=、<、<=、==、!=，时长使用 Go 时长格式(如 30s、5m、1h)。
+//
+// 异常检测函数 ewma、seasonal 比较窗口平均值偏离学习基线的程度，阈值为灵敏度(偏离的标准差倍数)，
+// 只能使用 > 或 >=，例如 ewma(cpu.usage, 5m) > 3 for 10m。
+package alertexpr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 聚合函数
+const (
+	FuncAvg  = "avg"
+	FuncMin  = "min"
+	FuncMax  = "max"
+	FuncLast = "last"
+
+	// 异常检测函数，名称与 anomaly 包的基线方法一致
+	FuncEWMA     = "ewma"
+	FuncSeasonal = "seasonal"
+)
+
+var funcs = map[string]bool{FuncAvg: true, FuncMin: true, FuncMax: true, FuncLast: true,
+	FuncEWMA: true, FuncSeasonal: true}
+
+var ops = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+// Expr 解析后的条件表达式
+type Expr struct {
+	Func      string
+	Metric    string // 原样保留，如 cpu.usage
+	Window    time.Duration
+	Op        string
+	Threshold float64
+	For       time.Duration // 条件须持续满足的时长，0 表示满足即告警
+}
+
+// Parse 解析条件表达式
+func Parse(s string) (*Expr, error) {
+	p := &parser{tokens: tokenize(s)}
+	e, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %v", s, err)
+	}
+	return e, nil
+}
+
+// Holds 判断聚合值是否满足条件
+func (e *Expr) Holds(v float64) bool {
+	return ops[e.Op](v, e.Threshold)
+}
+
+// Anomaly 判断是否为异常检测表达式，此时 Holds 的参数为偏离程度的绝对值
+func (e *Expr) Anomaly() bool {
+	return e.Func == FuncEWMA || e.Func == FuncSeasonal
+}
+
+// Reduce 按函数汇总按时间排序的值，异常检测函数取平均值，values 为空时返回 false
+func (e *Expr) Reduce(values []float64) (float64, bool) {
+	if len(values) == 0 {
+		return 0, false
+	}
+	switch e.Func {
+	case FuncMin:
+		v := math.Inf(1)
+		for _, x := range values {
+			v = math.Min(v, x)
+		}
+		return v, true
+	case FuncMax:
+		v := math.Inf(-1)
+		for _, x := range values {
+			v = math.Max(v, x)
+		}
+		return v, true
+	case FuncLast:
+		return values[len(values)-1], true
+	default:
+		var sum float64
+		for _, x := range values {
+			sum += x
+		}
+		return sum / float64(len(values)), true
+	}
+}
+
+func (e *Expr) String() string {
+	s := fmt.Sprintf("%s(%s, %s) %s %s", e.Func, e.Metric, formatDuration(e.Window), e.Op,
+		strconv.FormatFloat(e.Threshold, 'f', -1, 64))
+	if e.For > 0 {
+		s += " for " + formatDuration(e.For)
+	}
+	return s
+}
+
+// formatDuration 省略 time.Duration.String 末尾的零值单位，如 5m0s 输出为 5m
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// tokenize 切分为标识符/数字/时长、括号、逗号与比较符
+func tokenize(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, string(c))
+			i++
+		case strings.ContainsRune("<>=!", c):
+			j := i + 1
+			if j < len(s) && s[j] == '=' {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		default:
+			j := i
+			for j < len(s) && !unicode.IsSpace(rune(s[j])) && !strings.ContainsRune("(),<>=!", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *parser) expect(want string) error {
+	if got := p.next(); got != want {
+		return fmt.Errorf("expected %q, got %q", want, got)
+	}
+	return nil
+}
+
+func (p *parser) parse() (*Expr, error) {
+	e := &Expr{Func: strings.ToLower(p.next())}
+	if !funcs[e.Func] {
+		return nil, fmt.Errorf("unknown function %q", e.Func)
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	e.Metric = p.next()
+	if !isMetricName(e.Metric) {
+		return nil, fmt.Errorf("invalid metric name %q", e.Metric)
+	}
+	if err := p.expect(","); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if e.Window, err = parseDuration(p.next()); err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	e.Op = p.next()
+	if ops[e.Op] == nil {
+		return nil, fmt.Errorf("unknown operator %q", e.Op)
+	}
+
+	raw := p.next()
+	e.Threshold, err = strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(e.Threshold) || math.IsInf(e.Threshold, 0) {
+		return nil, fmt.Errorf("invalid threshold %q", raw)
+	}
+	if e.Anomaly() && (e.Threshold <= 0 || (e.Op != ">" && e.Op != ">=")) {
+		return nil, fmt.Errorf("%s requires > or >= with a positive sensitivity", e.Func)
+	}
+
+	switch t := p.next(); strings.ToLower(t) {
+	case "":
+		return e, nil
+	case "for":
+		if e.For, err = parseDuration(p.next()); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unexpected %q", t)
+	}
+	if t := p.next(); t != "" {
+		return nil, fmt.Errorf("unexpected %q", t)
+	}
+	return e, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// isMetricName 指标名由字母、数字、下划线与点组成，以字母开头
+func isMetricName(s string) bool {
+	if s == "" || !unicode.IsLetter(rune(s[0])) {
+		return false
+	}
+	for _, c := range s {
+		if c > unicode.MaxASCII || !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
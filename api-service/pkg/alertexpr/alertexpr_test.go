@@ -0,0 +1,88 @@
+package alertexpr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	e, err := Parse("avg(cpu.usage, 5m) > 90 for 10m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Expr{Func: FuncAvg, Metric: "cpu.usage", Window: 5 * time.Minute, Op: ">", Threshold: 90, For: 10 * time.Minute}
+	if *e != want {
+		t.Errorf("Parse() = %+v, want %+v", *e, want)
+	}
+	if got := e.String(); got != "avg(cpu.usage, 5m) > 90 for 10m" {
+		t.Errorf("String() = %q", got)
+	}
+
+	e, err = Parse("MAX(memory_usage,30s)<=12.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Func != FuncMax || e.Metric != "memory_usage" || e.Window != 30*time.Second || e.Op != "<=" || e.Threshold != 12.5 || e.For != 0 {
+		t.Errorf("Parse() = %+v", *e)
+	}
+
+	e, err = Parse("seasonal(network.rx_bps, 15m) >= 2.5 for 1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Anomaly() || e.Func != FuncSeasonal || e.Threshold != 2.5 || e.For != time.Hour {
+		t.Errorf("Parse() = %+v", *e)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"median(cpu.usage, 5m) > 90",
+		"avg(cpu.usage 5m) > 90",
+		"avg(cpu.usage, 5) > 90",
+		"avg(cpu.usage, -5m) > 90",
+		"avg(cpu.usage, 5m) => 90",
+		"avg(cpu.usage, 5m) > ninety",
+		"avg(cpu.usage, 5m) > NaN",
+		"avg(cpu.usage, 5m) > 90 for",
+		"avg(cpu.usage, 5m) > 90 during 10m",
+		"avg(cpu.usage, 5m) > 90 for 10m and",
+		"avg(1cpu, 5m) > 90",
+		`avg(cpu"), 5m) > 90`,
+		"ewma(cpu.usage, 5m) < 3",
+		"ewma(cpu.usage, 5m) > 0",
+		"seasonal(cpu.usage, 5m) > -2",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestReduceAndHolds(t *testing.T) {
+	values := []float64{3, 9, 6}
+	tests := []struct {
+		expr string
+		want float64
+		hold bool
+	}{
+		{"avg(x, 1m) > 5", 6, true},
+		{"min(x, 1m) < 3", 3, false},
+		{"max(x, 1m) >= 9", 9, true},
+		{"last(x, 1m) != 6", 6, false},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, ok := e.Reduce(values)
+		if !ok || v != tt.want || e.Holds(v) != tt.hold {
+			t.Errorf("%s: Reduce() = %v, %v; Holds() = %v", tt.expr, v, ok, e.Holds(v))
+		}
+	}
+	if _, ok := (&Expr{Func: FuncAvg}).Reduce(nil); ok {
+		t.Error("Reduce(nil) ok = true")
+	}
+}
@@ -0,0 +1,168 @@
+// Package anomaly 为异常检测告警规则学习指标序列的基线
+//
+// 基线按固定步长接收历史样本，给出某一时刻的期望值与离散程度，
+// 偏离程度为 (值 - 期望值) / 离散程度。支持两种方法：
+//   - ewma: 指数加权移动平均与方差，适合没有明显周期的指标
+//   - seasonal: 按一周中的小时(UTC)分桶，取最近若干周同一时段的中位数与 MAD，适合有日/周周期的指标
+package anomaly
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// 基线方法
+const (
+	MethodEWMA     = "ewma"
+	MethodSeasonal = "seasonal"
+)
+
+const (
+	// EWMAAlpha 每个样本的权重，约等于最近 50 分钟的记忆
+	EWMAAlpha = 0.02
+	// EWMAMinSamples 开始评分前至少需要的样本数
+	EWMAMinSamples = 60
+	// EWMAClip 学习时将样本截断到期望值上下若干倍离散程度内，异常值只能缓慢改变基线
+	EWMAClip = 3
+
+	// SeasonalMaxSamples 每个时段保留的最近周数
+	SeasonalMaxSamples = 8
+	// SeasonalMinSamples 时段至少有多少周的样本才开始评分
+	SeasonalMinSamples = 3
+
+	// MinRelativeScale 离散程度的下限(相对期望值)，避免平稳序列的微小波动被判为异常
+	MinRelativeScale = 0.05
+	minAbsoluteScale = 1e-3
+
+	// madScale 使正态分布下 MAD 与标准差一致
+	madScale = 1.4826
+)
+
+// Baseline 指标序列的基线
+type Baseline interface {
+	// Step 训练样本的步长
+	Step() time.Duration
+	// Observe 学习一个样本，t 为样本所在步长窗口的结束时间，须按时间顺序调用
+	Observe(t time.Time, v float64)
+	// Expect 返回 t 时刻的期望值与离散程度，样本不足时返回 false
+	Expect(t time.Time) (center, scale float64, ok bool)
+}
+
+// New 创建空的基线
+func New(method string) (Baseline, error) {
+	switch method {
+	case MethodEWMA:
+		return &EWMA{}, nil
+	case MethodSeasonal:
+		return &Seasonal{Buckets: make(map[int][]float64)}, nil
+	}
+	return nil, fmt.Errorf("unknown anomaly method %q", method)
+}
+
+// Unmarshal 从 JSON 恢复基线，data 由 json.Marshal(Baseline) 生成
+func Unmarshal(method string, data []byte) (Baseline, error) {
+	b, err := New(method)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("decode %s baseline: %w", method, err)
+	}
+	if s, ok := b.(*Seasonal); ok && s.Buckets == nil {
+		s.Buckets = make(map[int][]float64)
+	}
+	return b, nil
+}
+
+// Score 返回 v 在 t 时刻相对基线的偏离程度，正值表示高于期望
+func Score(b Baseline, t time.Time, v float64) (float64, bool) {
+	center, scale, ok := b.Expect(t)
+	if !ok {
+		return 0, false
+	}
+	return (v - center) / scale, true
+}
+
+// floorScale 为离散程度设置下限
+func floorScale(scale, center float64) float64 {
+	return math.Max(scale, math.Max(math.Abs(center)*MinRelativeScale, minAbsoluteScale))
+}
+
+// EWMA 指数加权移动平均基线，步长 1 分钟
+type EWMA struct {
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	Count    int     `json:"count"`
+}
+
+func (e *EWMA) Step() time.Duration { return time.Minute }
+
+func (e *EWMA) Observe(t time.Time, v float64) {
+	if center, scale, ok := e.Expect(t); ok {
+		v = math.Max(center-EWMAClip*scale, math.Min(v, center+EWMAClip*scale))
+	}
+	if e.Count == 0 {
+		e.Mean = v
+	} else {
+		d := v - e.Mean
+		e.Mean += EWMAAlpha * d
+		e.Variance = (1 - EWMAAlpha) * (e.Variance + EWMAAlpha*d*d)
+	}
+	e.Count++
+}
+
+func (e *EWMA) Expect(time.Time) (float64, float64, bool) {
+	if e.Count < EWMAMinSamples {
+		return 0, 0, false
+	}
+	return e.Mean, floorScale(math.Sqrt(e.Variance), e.Mean), true
+}
+
+// Seasonal 按一周中的小时分桶的中位数/MAD 基线，步长 1 小时
+type Seasonal struct {
+	// 键为 weekday*24+hour(UTC)，值为该时段最近的小时平均值，按时间顺序
+	Buckets map[int][]float64 `json:"buckets"`
+}
+
+func (s *Seasonal) Step() time.Duration { return time.Hour }
+
+func (s *Seasonal) Observe(t time.Time, v float64) {
+	// 样本覆盖 [t-1h, t)，归入窗口开始所在的时段
+	k := hourOfWeek(t.Add(-time.Hour))
+	values := append(s.Buckets[k], v)
+	if len(values) > SeasonalMaxSamples {
+		values = values[len(values)-SeasonalMaxSamples:]
+	}
+	s.Buckets[k] = values
+}
+
+func (s *Seasonal) Expect(t time.Time) (float64, float64, bool) {
+	values := s.Buckets[hourOfWeek(t)]
+	if len(values) < SeasonalMinSamples {
+		return 0, 0, false
+	}
+	center := median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - center)
+	}
+	return center, floorScale(madScale*median(deviations), center), true
+}
+
+func hourOfWeek(t time.Time) int {
+	t = t.UTC()
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
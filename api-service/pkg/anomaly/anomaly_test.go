@@ -0,0 +1,93 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC) // 周日
+
+func TestEWMA(t *testing.T) {
+	b, _ := New(MethodEWMA)
+	if _, ok := Score(b, epoch, 50); ok {
+		t.Fatal("Score() ok before warmup")
+	}
+	for i := 0; i < 120; i++ {
+		b.Observe(epoch.Add(time.Duration(i)*time.Minute), 50+float64(i%5-2))
+	}
+
+	normal, ok := Score(b, epoch, 51)
+	if !ok || math.Abs(normal) > 1 {
+		t.Errorf("Score(51) = %v, %v", normal, ok)
+	}
+	if high, _ := Score(b, epoch, 80); high < 5 {
+		t.Errorf("Score(80) = %v, want > 5", high)
+	}
+	if low, _ := Score(b, epoch, 20); low > -5 {
+		t.Errorf("Score(20) = %v, want < -5", low)
+	}
+}
+
+func TestSeasonal(t *testing.T) {
+	b, _ := New(MethodSeasonal)
+	// 每天 9 点至 18 点负载较高，学习三周
+	for h := 1; h <= 21*24; h++ {
+		end := epoch.Add(time.Duration(h) * time.Hour)
+		v := 10.0
+		if hour := end.Add(-time.Hour).Hour(); hour >= 9 && hour < 18 {
+			v = 70
+		}
+		b.Observe(end, v+float64(h%3))
+	}
+
+	noon := epoch.Add(21*24*time.Hour + 12*time.Hour + 30*time.Minute)
+	if score, ok := Score(b, noon, 71); !ok || math.Abs(score) > 1 {
+		t.Errorf("Score(noon, 71) = %v, %v", score, ok)
+	}
+	night := epoch.Add(21*24*time.Hour + 2*time.Hour)
+	if score, _ := Score(b, night, 71); score < 5 {
+		t.Errorf("Score(night, 71) = %v, want > 5", score)
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := Unmarshal(MethodSeasonal, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c1, s1, _ := b.Expect(noon)
+	c2, s2, ok := restored.Expect(noon)
+	if !ok || c1 != c2 || s1 != s2 {
+		t.Errorf("restored Expect() = %v, %v, %v; want %v, %v", c2, s2, ok, c1, s1)
+	}
+
+	if _, err := New("holt"); err == nil {
+		t.Error("New(holt) succeeded")
+	}
+}
+
+func TestBacktest(t *testing.T) {
+	var points []Point
+	for i := 1; i <= 300; i++ {
+		v := 50 + float64(i%5-2)
+		if i > 200 && i <= 230 {
+			v = 95
+		}
+		points = append(points, Point{Time: epoch.Add(time.Duration(i) * time.Minute), Value: v})
+	}
+
+	b, _ := New(MethodEWMA)
+	episodes := Backtest(b, points, points, epoch.Add(90*time.Minute), epoch.Add(300*time.Minute),
+		time.Minute, 5*time.Minute, 10*time.Minute, func(score float64) bool { return math.Abs(score) > 3 })
+	if len(episodes) != 1 {
+		t.Fatalf("Backtest() = %+v, want 1 episode", episodes)
+	}
+	e := episodes[0]
+	if e.Start.Before(epoch.Add(210*time.Minute)) || e.End == nil || !e.End.After(e.Start) || e.Peak < 3 {
+		t.Errorf("episode = %+v", e)
+	}
+}
@@ -0,0 +1,79 @@
+package anomaly
+
+import (
+	"math"
+	"time"
+)
+
+// Point 按时间排序的样本，Time 为样本所在窗口的结束时间
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Episode 回测期间的一次告警
+type Episode struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end"`        // 回测结束时仍在告警为 nil
+	Peak  float64    `json:"peak_score"` // 告警期间绝对值最大的偏离程度
+}
+
+// Backtest 在 [from, to) 内每隔 step 模拟一次规则评估，返回告警区间
+//
+// training 为基线步长的样本，评估时刻 t 只学习结束时间不晚于 t-window 的样本，与在线评估一致；
+// values 为评估用的样本，窗口 (t-window, t] 内的平均值与基线比较，holds 判断偏离程度是否满足条件，
+// 持续满足 forDur 后告警，不满足或窗口内无数据时恢复。
+func Backtest(b Baseline, training, values []Point, from, to time.Time, step, window, forDur time.Duration,
+	holds func(score float64) bool) []Episode {
+	var (
+		episodes []Episode
+		firing   bool
+		pending  bool
+		since    time.Time
+		sum      float64
+		next     int // 下一个待学习的训练样本
+		lo, hi   int // 窗口内的评估样本为 values[lo:hi]
+	)
+
+	for t := from; t.Before(to); t = t.Add(step) {
+		for ; next < len(training) && !training[next].Time.After(t.Add(-window)); next++ {
+			b.Observe(training[next].Time, training[next].Value)
+		}
+		for ; hi < len(values) && !values[hi].Time.After(t); hi++ {
+			sum += values[hi].Value
+		}
+		for ; lo < hi && !values[lo].Time.After(t.Add(-window)); lo++ {
+			sum -= values[lo].Value
+		}
+
+		var score float64
+		ok := false
+		if hi > lo {
+			score, ok = Score(b, t, sum/float64(hi-lo))
+		}
+		if !ok || !holds(score) {
+			pending = false
+			if firing {
+				end := t
+				episodes[len(episodes)-1].End = &end
+				firing = false
+			}
+			continue
+		}
+
+		if firing {
+			if last := &episodes[len(episodes)-1]; math.Abs(score) > math.Abs(last.Peak) {
+				last.Peak = score
+			}
+			continue
+		}
+		if !pending {
+			pending, since = true, t
+		}
+		if t.Sub(since) >= forDur {
+			episodes = append(episodes, Episode{Start: t, Peak: score})
+			firing, pending = true, false
+		}
+	}
+	return episodes
+}
@@ -0,0 +1,39 @@
+// Package blobstore 提供按键存取的二进制对象存储
+//
+// Store 屏蔽具体后端：Local 将对象保存为本地目录下的文件，适合单实例部署；
+// S3 使用兼容 S3 的对象存储(AWS S3、MinIO 等)，多个 api-service 实例共享同一存储。
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("blob not found")
+
+// Store 对象存储，键由 / 分隔的路径段组成
+type Store interface {
+	// Put 写入对象，size 为 r 的字节数，已存在的对象被覆盖；写入失败时不会留下不完整的对象
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Open 打开对象，返回的 Reader 支持 Seek，可用于按范围读取
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// checkKey 键不能为空，路径段不能为空、. 或 ..
+func checkKey(key string) error {
+	if key == "" {
+		return errors.New("empty blob key")
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, "\\\x00") {
+			return fmt.Errorf("invalid blob key: %s", key)
+		}
+	}
+	return nil
+}
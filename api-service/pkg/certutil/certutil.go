@@ -0,0 +1,212 @@
+package certutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoCertificate   = errors.New("no certificate found in PEM data")
+	ErrNoPrivateKey    = errors.New("no private key found in PEM data")
+	ErrKeyMismatch     = errors.New("private key does not match certificate")
+	ErrIncompleteChain = errors.New("certificate chain is incomplete")
+)
+
+// Info 证书元数据
+type Info struct {
+	Subject      string
+	Issuer       string
+	SerialNumber string
+	DNSNames     []string
+	NotBefore    time.Time
+	NotAfter     time.Time
+	IsCA         bool
+	SelfSigned   bool
+}
+
+// ParseCertificates 解析 PEM 中的全部证书，按出现顺序返回
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, ErrNoCertificate
+	}
+	return certs, nil
+}
+
+// ParsePrivateKey 解析 PEM 私钥，支持 PKCS#1、PKCS#8 与 SEC 1 格式
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, ErrNoPrivateKey
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+
+		if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, errors.New("unsupported private key type")
+			}
+			return signer, nil
+		}
+		if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unsupported private key format: %s", block.Type)
+	}
+}
+
+// KeyMatches 校验私钥与证书公钥是否匹配
+func KeyMatches(cert *x509.Certificate, key crypto.Signer) error {
+	type equaler interface {
+		Equal(crypto.PublicKey) bool
+	}
+
+	switch cert.PublicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+	default:
+		return fmt.Errorf("unsupported public key type %T", cert.PublicKey)
+	}
+
+	pub, ok := key.Public().(equaler)
+	if !ok || !pub.Equal(cert.PublicKey) {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
+// VerifyChain 校验叶子证书能否经中间证书链到可信根
+// roots 为空时使用系统根证书，链中自签名证书同样视为根
+func VerifyChain(leaf *x509.Certificate, chain []*x509.Certificate, roots *x509.CertPool, at time.Time) error {
+	intermediates := x509.NewCertPool()
+	trusted := roots
+	if trusted == nil {
+		system, err := x509.SystemCertPool()
+		if err != nil || system == nil {
+			system = x509.NewCertPool()
+		}
+		trusted = system
+	}
+
+	for _, cert := range chain {
+		if IsSelfSigned(cert) {
+			trusted.AddCert(cert)
+		} else {
+			intermediates.AddCert(cert)
+		}
+	}
+	if IsSelfSigned(leaf) {
+		trusted.AddCert(leaf)
+	}
+
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         trusted,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		var unknown x509.UnknownAuthorityError
+		if errors.As(err, &unknown) {
+			return fmt.Errorf("%w: %v", ErrIncompleteChain, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// IsSelfSigned 判断证书是否为自签名
+// 直接校验签名而非 CheckSignatureFrom，后者要求签发者为 CA，会漏判自签名的终端证书
+func IsSelfSigned(cert *x509.Certificate) bool {
+	if cert.Subject.String() != cert.Issuer.String() {
+		return false
+	}
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// Inspect 提取证书元数据
+func Inspect(cert *x509.Certificate) *Info {
+	return &Info{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: FormatSerial(cert),
+		DNSNames:     cert.DNSNames,
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		IsCA:         cert.IsCA,
+		SelfSigned:   IsSelfSigned(cert),
+	}
+}
+
+// PrimaryDomain 返回证书的主域名，优先取 SAN 中的第一个 DNS 名称
+func PrimaryDomain(cert *x509.Certificate) string {
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return cert.Subject.CommonName
+}
+
+// FormatSerial 以冒号分隔的十六进制格式输出序列号
+func FormatSerial(cert *x509.Certificate) string {
+	raw := hex.EncodeToString(cert.SerialNumber.Bytes())
+	if len(raw)%2 == 1 {
+		raw = "0" + raw
+	}
+	parts := make([]string, 0, len(raw)/2)
+	for i := 0; i < len(raw); i += 2 {
+		parts = append(parts, strings.ToUpper(raw[i:i+2]))
+	}
+	return strings.Join(parts, ":")
+}
+
+// CoversDomain 判断证书是否覆盖域名，通配符域名要求证书包含相同的通配符 SAN
+func CoversDomain(cert *x509.Certificate, domain string) bool {
+	if strings.HasPrefix(domain, "*.") {
+		for _, name := range cert.DNSNames {
+			if strings.EqualFold(name, domain) {
+				return true
+			}
+		}
+		return false
+	}
+	return cert.VerifyHostname(domain) == nil
+}
+
+// EncodeCertificates 将证书编码为 PEM
+func EncodeCertificates(certs []*x509.Certificate) []byte {
+	var buf []byte
+	for _, cert := range certs {
+		buf = append(buf, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return buf
+}
@@ -0,0 +1,175 @@
+package certutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func newCert(t *testing.T, cn string, isCA bool, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if !isCA {
+		template.DNSNames = []string{cn}
+	}
+
+	signer := parentKey
+	if parent == nil {
+		parent = template
+		signer = key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestVerifyChain(t *testing.T) {
+	root, rootKey := newCert(t, "Test Root", true, nil, nil)
+	inter, interKey := newCert(t, "Test Intermediate", true, root, rootKey)
+	leaf, _ := newCert(t, "example.com", false, inter, interKey)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	tests := []struct {
+		name    string
+		chain   []*x509.Certificate
+		wantErr error
+	}{
+		{"complete chain", []*x509.Certificate{inter}, nil},
+		{"chain with root", []*x509.Certificate{inter, root}, nil},
+		{"missing intermediate", nil, ErrIncompleteChain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyChain(leaf, tt.chain, roots.Clone(), time.Now())
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("VerifyChain() error = %v; expected nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyChain() error = %v; expected %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyMatches(t *testing.T) {
+	root, rootKey := newCert(t, "Test Root", true, nil, nil)
+	leaf, leafKey := newCert(t, "example.com", false, root, rootKey)
+
+	if err := KeyMatches(leaf, leafKey); err != nil {
+		t.Errorf("KeyMatches() with matching key error = %v", err)
+	}
+	if err := KeyMatches(leaf, rootKey); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("KeyMatches() with other key error = %v; expected %v", err, ErrKeyMismatch)
+	}
+}
+
+func TestParsePEM(t *testing.T) {
+	root, rootKey := newCert(t, "Test Root", true, nil, nil)
+	leaf, leafKey := newCert(t, "example.com", false, root, rootKey)
+
+	certs, err := ParseCertificates(EncodeCertificates([]*x509.Certificate{leaf, root}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 2 || PrimaryDomain(certs[0]) != "example.com" {
+		t.Errorf("ParseCertificates() returned unexpected certificates")
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := KeyMatches(leaf, key); err != nil {
+		t.Errorf("parsed key does not match: %v", err)
+	}
+
+	if _, err := ParseCertificates([]byte("not a pem")); !errors.Is(err, ErrNoCertificate) {
+		t.Errorf("ParseCertificates() error = %v; expected %v", err, ErrNoCertificate)
+	}
+}
+
+func TestIssue(t *testing.T) {
+	now := time.Now()
+	ca, caKey, err := Issue(&Request{
+		CommonName: "Webox Internal CA",
+		KeyType:    KeyTypeECDSAP256,
+		NotBefore:  now.Add(-time.Hour),
+		NotAfter:   now.AddDate(1, 0, 0),
+		IsCA:       true,
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("Issue() CA error = %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	for _, keyType := range []string{KeyTypeRSA2048, KeyTypeECDSAP384, KeyTypeEd25519} {
+		t.Run(keyType, func(t *testing.T) {
+			leaf, key, err := Issue(&Request{
+				CommonName:  "app.internal",
+				DNSNames:    []string{"app.internal"},
+				IPAddresses: []string{"10.0.0.8"},
+				KeyType:     keyType,
+				Usages:      []string{UsageServerAuth, UsageClientAuth},
+				NotBefore:   now.Add(-time.Hour),
+				NotAfter:    now.AddDate(2, 0, 0),
+			}, ca, caKey)
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+			if err := KeyMatches(leaf, key); err != nil {
+				t.Errorf("KeyMatches() error = %v", err)
+			}
+			if err := VerifyChain(leaf, nil, roots.Clone(), now); err != nil {
+				t.Errorf("VerifyChain() error = %v", err)
+			}
+			if !leaf.NotAfter.Equal(ca.NotAfter) {
+				t.Errorf("NotAfter = %v; expected capped to CA %v", leaf.NotAfter, ca.NotAfter)
+			}
+			if len(leaf.IPAddresses) != 1 || len(leaf.ExtKeyUsage) != 2 {
+				t.Errorf("unexpected SANs or usages: %v %v", leaf.IPAddresses, leaf.ExtKeyUsage)
+			}
+		})
+	}
+
+	if _, _, err := Issue(&Request{CommonName: "x", KeyType: "DSA", NotBefore: now, NotAfter: now.Add(time.Hour)}, nil, nil); err == nil {
+		t.Error("Issue() with unsupported key type: expected error")
+	}
+}
@@ -0,0 +1,188 @@
+package certutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// 支持的密钥类型
+const (
+	KeyTypeRSA2048   = "RSA2048"
+	KeyTypeRSA4096   = "RSA4096"
+	KeyTypeECDSAP256 = "ECDSA_P256"
+	KeyTypeECDSAP384 = "ECDSA_P384"
+	KeyTypeEd25519   = "ED25519"
+)
+
+// 支持的扩展密钥用途
+const (
+	UsageServerAuth      = "server_auth"
+	UsageClientAuth      = "client_auth"
+	UsageCodeSigning     = "code_signing"
+	UsageEmailProtection = "email_protection"
+)
+
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	UsageServerAuth:      x509.ExtKeyUsageServerAuth,
+	UsageClientAuth:      x509.ExtKeyUsageClientAuth,
+	UsageCodeSigning:     x509.ExtKeyUsageCodeSigning,
+	UsageEmailProtection: x509.ExtKeyUsageEmailProtection,
+}
+
+// Request 签发证书的参数
+type Request struct {
+	CommonName   string
+	Organization string
+	DNSNames     []string
+	IPAddresses  []string
+	EmailAddrs   []string
+	KeyType      string
+	Usages       []string
+	NotBefore    time.Time
+	NotAfter     time.Time
+	IsCA         bool
+	// MaxPathLen 仅对 CA 有效，0 表示只能签发终端证书
+	MaxPathLen int
+}
+
+// GenerateKey 按密钥类型生成私钥
+func GenerateKey(keyType string) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case KeyTypeRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case KeyTypeECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyTypeEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+	}
+}
+
+// EncodePrivateKey 将私钥编码为 PKCS#8 PEM
+func EncodePrivateKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// Issue 生成密钥并签发证书，parent 为空时生成自签名证书
+func Issue(req *Request, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer, error) {
+	key, err := GenerateKey(req.KeyType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := buildTemplate(req, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signer := parentKey
+	if parent == nil {
+		parent = template
+		signer = key
+	} else if template.NotAfter.After(parent.NotAfter) {
+		// 证书有效期不能超过签发 CA
+		template.NotAfter = parent.NotAfter
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func buildTemplate(req *Request, key crypto.Signer) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	if !req.NotAfter.After(req.NotBefore) {
+		return nil, fmt.Errorf("invalid validity period")
+	}
+
+	subject := pkix.Name{CommonName: req.CommonName}
+	if req.Organization != "" {
+		subject.Organization = []string{req.Organization}
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             req.NotBefore,
+		NotAfter:              req.NotAfter,
+		DNSNames:              req.DNSNames,
+		EmailAddresses:        req.EmailAddrs,
+		BasicConstraintsValid: true,
+		SubjectKeyId:          subjectKeyID(key.Public()),
+	}
+
+	for _, raw := range req.IPAddresses {
+		ip := net.ParseIP(raw)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip address: %s", raw)
+		}
+		template.IPAddresses = append(template.IPAddresses, ip)
+	}
+
+	if req.IsCA {
+		template.IsCA = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+		template.MaxPathLen = req.MaxPathLen
+		template.MaxPathLenZero = req.MaxPathLen == 0
+		return template, nil
+	}
+
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	usages := req.Usages
+	if len(usages) == 0 {
+		usages = []string{UsageServerAuth}
+	}
+	for _, usage := range usages {
+		eku, ok := extKeyUsages[usage]
+		if !ok {
+			return nil, fmt.Errorf("unsupported usage: %s", usage)
+		}
+		template.ExtKeyUsage = append(template.ExtKeyUsage, eku)
+	}
+
+	return template, nil
+}
+
+func subjectKeyID(pub crypto.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil
+	}
+	sum := sha1.Sum(der) // #nosec G401 - RFC 5280 推荐的 SubjectKeyId 计算方式
+	return sum[:]
+}
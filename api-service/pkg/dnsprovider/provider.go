@@ -0,0 +1,55 @@
+package dnsprovider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Provider 为 ACME DNS-01 质询创建和清理 TXT 记录
+type Provider interface {
+	// Present 创建 TXT 记录，fqdn 形如 _acme-challenge.example.com.
+	Present(ctx context.Context, fqdn, value string) error
+	// CleanUp 删除 Present 创建的 TXT 记录
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// Factory 根据配置项创建 Provider
+type Factory func(options map[string]string) (Provider, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register 注册 DNS 服务商实现，通常在实现包的 init 中调用
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// New 按类型创建 Provider
+func New(providerType string, options map[string]string) (Provider, error) {
+	mu.RLock()
+	factory, ok := factories[providerType]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown dns provider type: %s", providerType)
+	}
+	return factory(options)
+}
+
+// Types 返回已注册的服务商类型
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	types := make([]string, 0, len(factories))
+	for name := range factories {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
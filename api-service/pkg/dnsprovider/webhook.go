@@ -0,0 +1,80 @@
+package dnsprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+func init() {
+	Register("webhook", newWebhook)
+}
+
+// webhook 将 TXT 记录的创建与删除转发到外部 HTTP 服务，便于对接任意 DNS 服务商
+//
+// 配置项:
+//   - url: 接收请求的地址，必填
+//   - token: 可选，以 Bearer Token 方式携带
+//
+// 请求体为 {"action": "present"|"cleanup", "fqdn": "...", "value": "..."}，返回 2xx 视为成功
+type webhook struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newWebhook(options map[string]string) (Provider, error) {
+	if options["url"] == "" {
+		return nil, errors.New("webhook dns provider requires url")
+	}
+	return &webhook{
+		url:    options["url"],
+		token:  options["token"],
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (w *webhook) Present(ctx context.Context, fqdn, value string) error {
+	return w.call(ctx, "present", fqdn, value)
+}
+
+func (w *webhook) CleanUp(ctx context.Context, fqdn, value string) error {
+	return w.call(ctx, "cleanup", fqdn, value)
+}
+
+func (w *webhook) call(ctx context.Context, action, fqdn, value string) error {
+	body, err := json.Marshal(map[string]string{
+		"action": action,
+		"fqdn":   fqdn,
+		"value":  value,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("dns webhook %s failed: %s %s", action, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
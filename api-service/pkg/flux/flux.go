@@ -0,0 +1,95 @@
+// Package flux 构造参数化的 InfluxDB Flux 查询
+// 参数以 params 记录的形式置于查询开头，查询体通过 params.<name> 引用，
+// 与 InfluxDB Cloud 的参数化查询写法一致，同时适用于不支持 params 的开源版；
+// 参数值按类型转换为字面量，字符串经过转义，不会拼接进查询体
+package flux
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Query 在查询体前加入 params 记录
+func Query(body string, params map[string]interface{}) (string, error) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		if !IsIdentifier(name) {
+			return "", fmt.Errorf("invalid flux parameter name: %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]string, 0, len(names))
+	for _, name := range names {
+		lit, err := Literal(params[name])
+		if err != nil {
+			return "", fmt.Errorf("flux parameter %s: %w", name, err)
+		}
+		fields = append(fields, name+": "+lit)
+	}
+	return "params = {" + strings.Join(fields, ", ") + "}\n" + body, nil
+}
+
+// IsIdentifier 判断 name 是否为合法的 Flux 标识符，可直接用于 r.<name> 形式的列引用
+func IsIdentifier(name string) bool {
+	return identPattern.MatchString(name)
+}
+
+// Literal 将 Go 值转换为 Flux 字面量
+func Literal(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return String(val), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case int:
+		return strconv.Itoa(val), nil
+	case int64:
+		return strconv.FormatInt(val, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(val), 10), nil
+	case uint64:
+		return strconv.FormatUint(val, 10), nil
+	case float64:
+		s := strconv.FormatFloat(val, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s, nil
+	case time.Time:
+		return val.UTC().Format(time.RFC3339Nano), nil
+	case time.Duration:
+		return Duration(val), nil
+	case []string:
+		items := make([]string, len(val))
+		for i, s := range val {
+			items[i] = String(s)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// String 转义为 Flux 字符串字面量，包括字符串插值符 ${
+func String(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, `${`, `\${`)
+	return `"` + s + `"`
+}
+
+// Duration 转换为 Flux 时长字面量，整秒时以秒为单位
+func Duration(d time.Duration) string {
+	if d%time.Second == 0 {
+		return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+	}
+	return strconv.FormatInt(int64(d), 10) + "ns"
+}
@@ -0,0 +1,59 @@
+package flux
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	got, err := Query(`from(bucket: "metrics") |> range(start: params.start)`, map[string]interface{}{
+		"start":  time.Date(2024, 5, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+		"every":  5 * time.Minute,
+		"id":     "1",
+		"fields": []string{"cpu_usage", "memory_usage"},
+	})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	want := `params = {every: 300s, fields: ["cpu_usage", "memory_usage"], id: "1", start: 2024-05-01T00:00:00Z}
+from(bucket: "metrics") |> range(start: params.start)`
+	if got != want {
+		t.Errorf("Query() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestQueryRejectsBadInput(t *testing.T) {
+	if _, err := Query("", map[string]interface{}{"a b": "x"}); err == nil {
+		t.Error("invalid parameter name accepted")
+	}
+	if _, err := Query("", map[string]interface{}{"x": struct{}{}}); err == nil {
+		t.Error("unsupported parameter type accepted")
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"web", `"web"`},
+		{`1") |> drop(columns: ["_value"]) //`, `"1\") |> drop(columns: [\"_value\"]) //"`},
+		{`a\`, `"a\\"`},
+		{"${params.x}", `"\${params.x}"`},
+	}
+	for _, tt := range tests {
+		if got := String(tt.in); got != tt.want {
+			t.Errorf("String(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	if got := Duration(90 * time.Second); got != "90s" {
+		t.Errorf("Duration(90s) = %s", got)
+	}
+	if got := Duration(1500 * time.Millisecond); got != "1500000000ns" {
+		t.Errorf("Duration(1.5s) = %s", got)
+	}
+}
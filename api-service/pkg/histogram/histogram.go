@@ -0,0 +1,101 @@
+// Package histogram 提供固定分桶直方图，用于按时间汇总延迟分布并估算分位数
+package histogram
+
+import (
+	"math"
+	"strconv"
+)
+
+// InfBucket 超出最大边界的分桶字段名
+const InfBucket = "le_inf"
+
+// Histogram 固定分桶直方图，Counts[i] 为落在 (Bounds[i-1], Bounds[i]] 内的观测数，
+// 最后一个计数为超出最大边界的观测数
+type Histogram struct {
+	Bounds []float64
+	Counts []int64
+}
+
+// New 按升序边界创建直方图
+func New(bounds []float64) *Histogram {
+	return &Histogram{
+		Bounds: bounds,
+		Counts: make([]int64, len(bounds)+1),
+	}
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(v float64) {
+	for i, bound := range h.Bounds {
+		if v <= bound {
+			h.Counts[i]++
+			return
+		}
+	}
+	h.Counts[len(h.Bounds)]++
+}
+
+// Total 观测总数
+func (h *Histogram) Total() int64 {
+	var total int64
+	for _, c := range h.Counts {
+		total += c
+	}
+	return total
+}
+
+// Fields 将分桶计数转换为指标字段，字段名为 le_<边界>
+func (h *Histogram) Fields() map[string]interface{} {
+	fields := make(map[string]interface{}, len(h.Counts))
+	for i, c := range h.Counts {
+		fields[FieldName(h, i)] = c
+	}
+	return fields
+}
+
+// Add 按字段名累加分桶计数，未知字段忽略
+func (h *Histogram) Add(field string, count int64) {
+	for i := range h.Counts {
+		if FieldName(h, i) == field {
+			h.Counts[i] += count
+			return
+		}
+	}
+}
+
+// FieldName 第 i 个分桶的字段名
+func FieldName(h *Histogram, i int) string {
+	if i >= len(h.Bounds) {
+		return InfBucket
+	}
+	return "le_" + strconv.FormatFloat(h.Bounds[i], 'f', -1, 64)
+}
+
+// Quantile 估算分位数，在所在分桶内线性插值；落入溢出分桶时返回最大边界
+func (h *Histogram) Quantile(q float64) float64 {
+	total := h.Total()
+	if total == 0 {
+		return math.NaN()
+	}
+
+	rank := q * float64(total)
+	var cumulative int64
+	for i, c := range h.Counts {
+		if c == 0 {
+			continue
+		}
+		if float64(cumulative+c) >= rank {
+			if i >= len(h.Bounds) {
+				return h.Bounds[len(h.Bounds)-1]
+			}
+			lower := 0.0
+			if i > 0 {
+				lower = h.Bounds[i-1]
+			}
+			upper := h.Bounds[i]
+			return lower + (upper-lower)*(rank-float64(cumulative))/float64(c)
+		}
+		cumulative += c
+	}
+	return h.Bounds[len(h.Bounds)-1]
+}
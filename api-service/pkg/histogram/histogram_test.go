@@ -0,0 +1,65 @@
+package histogram
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuantile(t *testing.T) {
+	h := New([]float64{10, 100, 1000})
+	for i := 0; i < 50; i++ {
+		h.Observe(5)
+	}
+	for i := 0; i < 45; i++ {
+		h.Observe(50)
+	}
+	for i := 0; i < 4; i++ {
+		h.Observe(500)
+	}
+	h.Observe(5000)
+
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.5, 10},
+		{0.95, 100},
+		{0.99, 1000},
+		{1, 1000},
+	}
+	for _, tt := range tests {
+		if got := h.Quantile(tt.q); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Quantile(%v) = %v; expected %v", tt.q, got, tt.want)
+		}
+	}
+
+	if got := h.Quantile(0.25); math.Abs(got-5) > 1e-9 {
+		t.Errorf("Quantile(0.25) = %v; expected 5", got)
+	}
+	if !math.IsNaN(New([]float64{1}).Quantile(0.5)) {
+		t.Error("Quantile() of empty histogram: expected NaN")
+	}
+}
+
+func TestFieldsRoundTrip(t *testing.T) {
+	h := New([]float64{2.5, 10})
+	h.Observe(1)
+	h.Observe(7)
+	h.Observe(70)
+
+	merged := New([]float64{2.5, 10})
+	for field, value := range h.Fields() {
+		merged.Add(field, value.(int64))
+		merged.Add(field, value.(int64))
+	}
+
+	want := []int64{2, 2, 2}
+	for i, c := range merged.Counts {
+		if c != want[i] {
+			t.Errorf("Counts[%d] = %d; expected %d", i, c, want[i])
+		}
+	}
+	if _, ok := h.Fields()["le_2.5"]; !ok {
+		t.Error("Fields() missing le_2.5")
+	}
+}
@@ -0,0 +1,169 @@
+package metricstore
+
+import (
+	"api-service/pkg/flux"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+)
+
+// Influx 基于 InfluxDB 2.x 的指标存储，写入由客户端批量异步提交
+type Influx struct {
+	client   influxdb2.Client
+	writeAPI api.WriteAPI
+	queryAPI api.QueryAPI
+	bucket   string
+}
+
+func NewInflux(client influxdb2.Client, org, bucket string) *Influx {
+	s := &Influx{
+		client:   client,
+		writeAPI: client.WriteAPI(org, bucket),
+		queryAPI: client.QueryAPI(org),
+		bucket:   bucket,
+	}
+	// 异步写入的错误只能从 Errors() 读取，须在首次写入前开始读取，客户端关闭时通道关闭
+	errs := s.writeAPI.Errors()
+	go func() {
+		for err := range errs {
+			log.Printf("Failed to write metrics to InfluxDB: %v", err)
+		}
+	}()
+	return s
+}
+
+func (s *Influx) Write(measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time) error {
+	s.writeAPI.WritePoint(influxdb2.NewPoint(measurement, tags, fields, ts))
+	return nil
+}
+
+func (s *Influx) Flush() {
+	s.writeAPI.Flush()
+}
+
+func (s *Influx) Close() error {
+	s.writeAPI.Flush()
+	s.client.Close()
+	return nil
+}
+
+// Aggregate 同一字段的多个序列先合并再按窗口求平均
+func (s *Influx) Aggregate(ctx context.Context, q *Query, window time.Duration) ([]Sample, error) {
+	filter, params, err := s.filter(q)
+	if err != nil {
+		return nil, err
+	}
+	params["every"] = window
+
+	query, err := flux.Query(filter+`
+		|> group(columns: ["_field"])
+		|> aggregateWindow(every: params.every, fn: mean, createEmpty: true)
+		|> keep(columns: ["_time", "_field", "_value"])`, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.queryAPI.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []Sample
+	for result.Next() {
+		record := result.Record()
+		sample := Sample{Field: record.Field(), Time: record.Time()}
+		if record.Value() != nil {
+			v, err := toFloat64(record.Value())
+			if err != nil {
+				return nil, err
+			}
+			sample.Value = &v
+		}
+		samples = append(samples, sample)
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+func (s *Influx) Sum(ctx context.Context, q *Query, groupBy []string) ([]Row, error) {
+	filter, params, err := s.filter(q)
+	if err != nil {
+		return nil, err
+	}
+	params["columns"] = append(append([]string{}, groupBy...), "_field")
+
+	query, err := flux.Query(filter+`
+		|> group(columns: params.columns)
+		|> sum()`, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.queryAPI.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	for result.Next() {
+		record := result.Record()
+		row := Row{Tags: make(map[string]string, len(groupBy)), Field: record.Field()}
+		for _, tag := range groupBy {
+			row.Tags[tag], _ = record.ValueByKey(tag).(string)
+		}
+		if row.Value, err = toFloat64(record.Value()); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	sortRows(rows, groupBy)
+	return rows, nil
+}
+
+// filter 生成 from/range/filter 查询片段，标签名作为列引用须为合法标识符，标签值通过参数传入
+func (s *Influx) filter(q *Query) (string, map[string]interface{}, error) {
+	params := map[string]interface{}{
+		"bucket":      s.bucket,
+		"start":       q.Start,
+		"stop":        q.End,
+		"measurement": q.Measurement,
+	}
+
+	tags := make([]string, 0, len(q.Tags))
+	for tag := range q.Tags {
+		if !flux.IsIdentifier(tag) {
+			return "", nil, fmt.Errorf("invalid tag name: %q", tag)
+		}
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	conds := []string{"r._measurement == params.measurement"}
+	for i, tag := range tags {
+		name := fmt.Sprintf("tag%d", i)
+		params[name] = q.Tags[tag]
+		conds = append(conds, "r."+tag+" == params."+name)
+	}
+
+	var b strings.Builder
+	b.WriteString(`from(bucket: params.bucket)
+		|> range(start: params.start, stop: params.stop)
+		|> filter(fn: (r) => ` + strings.Join(conds, " and ") + `)`)
+	if len(q.Fields) > 0 {
+		params["fields"] = q.Fields
+		b.WriteString(`
+		|> filter(fn: (r) => contains(value: r._field, set: params.fields))`)
+	}
+	return b.String(), params, nil
+}
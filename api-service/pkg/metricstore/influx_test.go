@@ -0,0 +1,62 @@
+package metricstore
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestInfluxLogsWriteErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":"not found","message":"bucket \"bucket\" not found"}`))
+	}))
+	defer server.Close()
+
+	var out syncBuffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	client := influxdb2.NewClientWithOptions(server.URL, "token", influxdb2.DefaultOptions().SetMaxRetries(0))
+	s := NewInflux(client, "org", "bucket")
+	defer s.Close()
+
+	if err := s.Write("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 1.0}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	s.Flush()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "Failed to write metrics to InfluxDB") {
+		if time.Now().After(deadline) {
+			t.Fatalf("write error was not logged, log output: %q", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
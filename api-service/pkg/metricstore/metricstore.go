@@ -0,0 +1,103 @@
+// Package metricstore 提供时序指标的存储与聚合查询
+//
+// Store 屏蔽具体后端：InfluxDB 适合大规模部署，SQLite 内置于 api-service，
+// 按分辨率逐级降采样并按保留时长清理，适合不希望额外运行 InfluxDB 的小规模安装。
+package metricstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Store 指标存储
+type Store interface {
+	// Write 写入一个数据点，写入可能被缓冲，Flush 或 Close 后落盘
+	Write(measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time) error
+	// Flush 立即写出缓冲区中的数据点
+	Flush()
+	// Aggregate 按 window 对齐的时间窗口计算各字段的平均值，无数据的窗口也会返回(值为 nil)
+	Aggregate(ctx context.Context, q *Query, window time.Duration) ([]Sample, error)
+	// Sum 按 groupBy 标签与字段分组求和
+	Sum(ctx context.Context, q *Query, groupBy []string) ([]Row, error)
+	// Close 写出缓冲区并释放资源
+	Close() error
+}
+
+// Query 查询条件，Tags 为等值过滤，Fields 为空时查询全部字段，时间范围为 [Start, End)
+type Query struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      []string
+	Start       time.Time
+	End         time.Time
+}
+
+// Sample 聚合窗口的值，Time 为窗口结束时间(不超过查询的 End)
+type Sample struct {
+	Field string
+	Time  time.Time
+	Value *float64
+}
+
+// Row 分组求和结果，Tags 只包含 groupBy 中的标签
+type Row struct {
+	Tags  map[string]string
+	Field string
+	Value float64
+}
+
+// toFloat64 将字段值转换为浮点数，不支持非数值字段
+func toFloat64(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint:
+		return float64(n), nil
+	case uint32:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case bool:
+		if n {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("unsupported field type %T", v)
+}
+
+// windowStops 返回 [start, end) 内按 Unix 纪元对齐的各窗口结束时间，与 InfluxDB aggregateWindow 一致
+func windowStops(start, end time.Time, window time.Duration) []time.Time {
+	var stops []time.Time
+	first := time.Unix(0, start.UnixNano()-start.UnixNano()%int64(window))
+	for t := first; t.Before(end); t = t.Add(window) {
+		stop := t.Add(window)
+		if stop.After(end) {
+			stop = end
+		}
+		stops = append(stops, stop)
+	}
+	return stops
+}
+
+// sortRows 按字段与标签排序，保证结果稳定
+func sortRows(rows []Row, groupBy []string) {
+	sort.Slice(rows, func(i, j int) bool {
+		for _, tag := range groupBy {
+			if rows[i].Tags[tag] != rows[j].Tags[tag] {
+				return rows[i].Tags[tag] < rows[j].Tags[tag]
+			}
+		}
+		return rows[i].Field < rows[j].Field
+	})
+}
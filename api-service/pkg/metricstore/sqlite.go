@@ -0,0 +1,519 @@
+package metricstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	sqliteFlushInterval    = 5 * time.Second
+	sqliteMaintainInterval = time.Minute
+	// 降采样等待迟到的数据点，网关按分钟汇总的流量会在窗口结束后写入
+	sqliteRollupDelay = 2 * time.Minute
+	sqliteBatchSize   = 500
+)
+
+// Tier 存储分辨率与保留时长，Resolution 为 0 表示原始数据
+type Tier struct {
+	Resolution time.Duration
+	Retention  time.Duration
+}
+
+// metricSeries 由 measurement、标签与字段确定的序列
+type metricSeries struct {
+	ID          uint   `gorm:"primaryKey"`
+	Measurement string `gorm:"not null;uniqueIndex:idx_metric_series"`
+	Tags        string `gorm:"not null;uniqueIndex:idx_metric_series"` // 按键排序的 JSON
+	Field       string `gorm:"not null;uniqueIndex:idx_metric_series"`
+}
+
+func (metricSeries) TableName() string { return "metric_series" }
+
+// metricSample 数据点，降采样后的数据点保存窗口内的和与个数，平均值与求和都可以逐级合并
+type metricSample struct {
+	SeriesID   uint  `gorm:"primaryKey;autoIncrement:false"`
+	Resolution int64 `gorm:"primaryKey;autoIncrement:false;index:idx_metric_samples_time,priority:1"` // 秒，0 为原始数据
+	Timestamp  int64 `gorm:"primaryKey;autoIncrement:false;index:idx_metric_samples_time,priority:2"`
+	Sum        float64
+	Count      int64
+}
+
+func (metricSample) TableName() string { return "metric_samples" }
+
+// metricRollup 记录各分辨率已完成降采样的位置
+type metricRollup struct {
+	Resolution  int64 `gorm:"primaryKey;autoIncrement:false"`
+	RolledUntil int64
+}
+
+func (metricRollup) TableName() string { return "metric_rollups" }
+
+type pendingSample struct {
+	key       seriesKey
+	timestamp int64
+	value     float64
+}
+
+type seriesKey struct {
+	measurement string
+	tags        string
+	field       string
+}
+
+type seriesInfo struct {
+	tags  map[string]string
+	field string
+}
+
+// SQLite 内置指标存储，写入先缓冲再定期批量落盘，后台按 tiers 降采样并清理过期数据
+// 查询时对早于某一分辨率降采样位置的时间段使用该分辨率的数据
+type SQLite struct {
+	db    *gorm.DB
+	tiers []Tier
+	now   func() time.Time
+
+	mu      sync.Mutex
+	pending []pendingSample
+	series  map[seriesKey]uint
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSQLite 创建内置存储并启动后台任务，tiers[0] 须为原始数据，之后的分辨率须为前一级的整数倍
+func NewSQLite(db *gorm.DB, tiers []Tier) (*SQLite, error) {
+	if err := validateTiers(tiers); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&metricSeries{}, &metricSample{}, &metricRollup{}); err != nil {
+		return nil, err
+	}
+
+	s := &SQLite{
+		db:     db,
+		tiers:  tiers,
+		now:    time.Now,
+		series: make(map[seriesKey]uint),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.loop()
+	return s, nil
+}
+
+func validateTiers(tiers []Tier) error {
+	if len(tiers) == 0 || tiers[0].Resolution != 0 {
+		return errors.New("first tier must hold raw samples")
+	}
+	for i, tier := range tiers {
+		if tier.Retention <= 0 {
+			return fmt.Errorf("tier %d: retention must be positive", i)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := tiers[i-1]
+		if tier.Resolution < time.Second || tier.Resolution%time.Second != 0 {
+			return fmt.Errorf("tier %d: resolution must be whole seconds", i)
+		}
+		if prev.Resolution > 0 && tier.Resolution%prev.Resolution != 0 {
+			return fmt.Errorf("tier %d: resolution must be a multiple of %s", i, prev.Resolution)
+		}
+		// 上一级数据须保留到本级降采样完成之后
+		if prev.Retention <= tier.Resolution+sqliteRollupDelay {
+			return fmt.Errorf("tier %d: retention %s is too short to roll up into %s", i-1, prev.Retention, tier.Resolution)
+		}
+	}
+	return nil
+}
+
+func (s *SQLite) loop() {
+	defer close(s.done)
+
+	flush := time.NewTicker(sqliteFlushInterval)
+	defer flush.Stop()
+	maintain := time.NewTicker(sqliteMaintainInterval)
+	defer maintain.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-flush.C:
+			s.Flush()
+		case <-maintain.C:
+			s.Flush()
+			if err := s.maintain(); err != nil {
+				log.Printf("Metrics store maintenance failed: %v", err)
+			}
+		}
+	}
+}
+
+func (s *SQLite) Write(measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time) error {
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	if tags == nil {
+		tagsJSON = []byte("{}")
+	}
+
+	samples := make([]pendingSample, 0, len(fields))
+	for field, raw := range fields {
+		value, err := toFloat64(raw)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field, err)
+		}
+		samples = append(samples, pendingSample{
+			key:       seriesKey{measurement: measurement, tags: string(tagsJSON), field: field},
+			timestamp: ts.Unix(),
+			value:     value,
+		})
+	}
+
+	s.mu.Lock()
+	s.pending = append(s.pending, samples...)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *SQLite) Flush() {
+	if err := s.flush(); err != nil {
+		log.Printf("Failed to flush metrics: %v", err)
+	}
+}
+
+func (s *SQLite) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	// 同一秒内同一序列的多次写入合并为一个数据点
+	merged := make(map[[2]int64]*metricSample)
+	var order [][2]int64
+	for _, p := range s.pending {
+		id, err := s.seriesID(p.key)
+		if err != nil {
+			return err
+		}
+		k := [2]int64{int64(id), p.timestamp}
+		sample, ok := merged[k]
+		if !ok {
+			sample = &metricSample{SeriesID: id, Timestamp: p.timestamp}
+			merged[k] = sample
+			order = append(order, k)
+		}
+		sample.Sum += p.value
+		sample.Count++
+	}
+
+	samples := make([]*metricSample, 0, len(order))
+	for _, k := range order {
+		samples = append(samples, merged[k])
+	}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "series_id"}, {Name: "resolution"}, {Name: "timestamp"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"sum":   gorm.Expr("metric_samples.sum + excluded.sum"),
+			"count": gorm.Expr("metric_samples.count + excluded.count"),
+		}),
+	}).CreateInBatches(samples, sqliteBatchSize).Error
+	if err != nil {
+		return err
+	}
+
+	s.pending = s.pending[:0]
+	return nil
+}
+
+// seriesID 获取或创建序列，调用方须持有 s.mu
+func (s *SQLite) seriesID(key seriesKey) (uint, error) {
+	if id, ok := s.series[key]; ok {
+		return id, nil
+	}
+	series := metricSeries{Measurement: key.measurement, Tags: key.tags, Field: key.field}
+	err := s.db.Where(&series).FirstOrCreate(&series).Error
+	if err != nil {
+		return 0, err
+	}
+	s.series[key] = series.ID
+	return series.ID, nil
+}
+
+func (s *SQLite) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+	return s.flush()
+}
+
+// maintain 逐级降采样并清理过期数据
+func (s *SQLite) maintain() error {
+	now := s.now()
+
+	for i := 1; i < len(s.tiers); i++ {
+		res := int64(s.tiers[i].Resolution / time.Second)
+		src := int64(s.tiers[i-1].Resolution / time.Second)
+		cutoff := now.Add(-sqliteRollupDelay).Unix() / res * res
+
+		var rollup metricRollup
+		if err := s.db.Where("resolution = ?", res).Limit(1).Find(&rollup).Error; err != nil {
+			return err
+		}
+		if rollup.RolledUntil >= cutoff {
+			continue
+		}
+
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec(`INSERT INTO metric_samples (series_id, resolution, timestamp, sum, count)
+				SELECT series_id, ?, timestamp / ? * ?, SUM(sum), SUM(count) FROM metric_samples
+				WHERE resolution = ? AND timestamp >= ? AND timestamp < ?
+				GROUP BY series_id, timestamp / ?
+				ON CONFLICT (series_id, resolution, timestamp) DO UPDATE SET
+				sum = metric_samples.sum + excluded.sum, count = metric_samples.count + excluded.count`,
+				res, res, res, src, rollup.RolledUntil, cutoff, res).Error
+			if err != nil {
+				return err
+			}
+			return tx.Save(&metricRollup{Resolution: res, RolledUntil: cutoff}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("roll up into %s: %w", s.tiers[i].Resolution, err)
+		}
+	}
+
+	for _, tier := range s.tiers {
+		res := int64(tier.Resolution / time.Second)
+		err := s.db.Where("resolution = ? AND timestamp < ?", res, now.Add(-tier.Retention).Unix()).
+			Delete(&metricSample{}).Error
+		if err != nil {
+			return fmt.Errorf("apply %s retention: %w", tier.Retention, err)
+		}
+	}
+	return nil
+}
+
+type tierRange struct {
+	resolution int64
+	start, end int64
+}
+
+// ranges 选择覆盖查询起点的最细分辨率，之后未降采样的时间段依次使用更细的分辨率
+func (s *SQLite) ranges(start, end time.Time) ([]tierRange, error) {
+	now := s.now()
+	k := len(s.tiers) - 1
+	for i, tier := range s.tiers {
+		if !start.Before(now.Add(-tier.Retention)) {
+			k = i
+			break
+		}
+	}
+
+	var rollups []metricRollup
+	if err := s.db.Find(&rollups).Error; err != nil {
+		return nil, err
+	}
+	rolled := make(map[int64]int64, len(rollups))
+	for _, r := range rollups {
+		rolled[r.Resolution] = r.RolledUntil
+	}
+
+	var result []tierRange
+	cursor := start.Unix()
+	for i := k; i >= 0; i-- {
+		res := int64(s.tiers[i].Resolution / time.Second)
+		upper := end.Unix()
+		if i > 0 && rolled[res] < upper {
+			upper = rolled[res]
+		}
+		if upper > cursor {
+			result = append(result, tierRange{resolution: res, start: cursor, end: upper})
+			cursor = upper
+		}
+	}
+	return result, nil
+}
+
+// matchSeries 返回 measurement 下满足标签与字段条件的序列
+func (s *SQLite) matchSeries(q *Query) (map[uint]seriesInfo, error) {
+	var all []metricSeries
+	db := s.db.Where("measurement = ?", q.Measurement)
+	if len(q.Fields) > 0 {
+		db = db.Where("field IN ?", q.Fields)
+	}
+	if err := db.Find(&all).Error; err != nil {
+		return nil, err
+	}
+
+	matched := make(map[uint]seriesInfo)
+	for _, series := range all {
+		var tags map[string]string
+		if err := json.Unmarshal([]byte(series.Tags), &tags); err != nil {
+			return nil, err
+		}
+		ok := true
+		for k, v := range q.Tags {
+			if tags[k] != v {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched[series.ID] = seriesInfo{tags: tags, field: series.Field}
+		}
+	}
+	return matched, nil
+}
+
+type bucketRow struct {
+	SeriesID uint
+	Bucket   int64
+	Sum      float64
+	Count    int64
+}
+
+// query 在各分辨率的时间段内按 window 秒分桶汇总，window 为 0 时不分桶
+func (s *SQLite) query(ctx context.Context, q *Query, window int64) (map[uint]seriesInfo, []bucketRow, error) {
+	s.Flush()
+
+	series, err := s.matchSeries(q)
+	if err != nil || len(series) == 0 {
+		return series, nil, err
+	}
+	ranges, err := s.ranges(q.Start, q.End)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bucket := "0"
+	if window > 0 {
+		bucket = fmt.Sprintf("timestamp / %d * %d", window, window)
+	}
+
+	// 只读取匹配序列的数据点，按主键 (series_id, resolution, timestamp) 查找，序列较多时分批查询
+	ids := make([]uint, 0, len(series))
+	for id := range series {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var rows []bucketRow
+	for _, r := range ranges {
+		for i := 0; i < len(ids); i += sqliteBatchSize {
+			batch := ids[i:min(i+sqliteBatchSize, len(ids))]
+			var part []bucketRow
+			err := s.db.WithContext(ctx).Raw(`SELECT series_id, `+bucket+` AS bucket, SUM(sum) AS sum, SUM(count) AS count
+				FROM metric_samples
+				WHERE series_id IN ? AND resolution = ? AND timestamp >= ? AND timestamp < ?
+				GROUP BY series_id, bucket`, batch, r.resolution, r.start, r.end).Scan(&part).Error
+			if err != nil {
+				return nil, nil, err
+			}
+			rows = append(rows, part...)
+		}
+	}
+	return series, rows, nil
+}
+
+// Aggregate 同一字段的多个序列合并计算平均值，window 须为整秒
+func (s *SQLite) Aggregate(ctx context.Context, q *Query, window time.Duration) ([]Sample, error) {
+	if window < time.Second || window%time.Second != 0 {
+		return nil, fmt.Errorf("window must be whole seconds: %s", window)
+	}
+	series, rows, err := s.query(ctx, q, int64(window/time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	type acc struct {
+		sum   float64
+		count int64
+	}
+	buckets := make(map[string]map[int64]*acc)
+	for _, row := range rows {
+		info, ok := series[row.SeriesID]
+		if !ok {
+			continue
+		}
+		if buckets[info.field] == nil {
+			buckets[info.field] = make(map[int64]*acc)
+		}
+		a := buckets[info.field][row.Bucket]
+		if a == nil {
+			a = &acc{}
+			buckets[info.field][row.Bucket] = a
+		}
+		a.sum += row.Sum
+		a.count += row.Count
+	}
+
+	fields := q.Fields
+	if len(fields) == 0 {
+		for field := range buckets {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+	}
+
+	stops := windowStops(q.Start, q.End, window)
+	samples := make([]Sample, 0, len(fields)*len(stops))
+	for _, field := range fields {
+		for _, stop := range stops {
+			sample := Sample{Field: field, Time: stop}
+			// 最后一个窗口可能被 End 截断，窗口起点按对齐位置计算
+			start := (stop.Unix() - 1) / int64(window/time.Second) * int64(window/time.Second)
+			if a := buckets[field][start]; a != nil && a.count > 0 {
+				v := a.sum / float64(a.count)
+				sample.Value = &v
+			}
+			samples = append(samples, sample)
+		}
+	}
+	return samples, nil
+}
+
+func (s *SQLite) Sum(ctx context.Context, q *Query, groupBy []string) ([]Row, error) {
+	series, rows, err := s.query(ctx, q, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	var result []Row
+	for _, row := range rows {
+		info, ok := series[row.SeriesID]
+		if !ok {
+			continue
+		}
+		tags := make(map[string]string, len(groupBy))
+		for _, tag := range groupBy {
+			tags[tag] = info.tags[tag]
+		}
+		keyJSON, _ := json.Marshal([]interface{}{tags, info.field})
+		key := string(keyJSON)
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			result = append(result, Row{Tags: tags, Field: info.field})
+		}
+		result[i].Value += row.Sum
+	}
+	sortRows(result, groupBy)
+	return result, nil
+}
@@ -0,0 +1,211 @@
+package metricstore
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestSQLite(t *testing.T, now time.Time) *SQLite {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "metrics.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSQLite(db, []Tier{
+		{Resolution: 0, Retention: time.Hour},
+		{Resolution: 5 * time.Minute, Retention: 24 * time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return now }
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func values(samples []Sample) []interface{} {
+	out := make([]interface{}, len(samples))
+	for i, s := range samples {
+		if s.Value == nil {
+			out[i] = nil
+		} else {
+			out[i] = *s.Value
+		}
+	}
+	return out
+}
+
+func TestSQLiteAggregate(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	s := newTestSQLite(t, t0.Add(10*time.Minute))
+
+	for i, v := range []float64{10, 20, 30, 40} {
+		tags := map[string]string{"server_id": "1"}
+		if err := s.Write("server_metrics", tags, map[string]interface{}{"cpu_usage": v}, t0.Add(time.Duration(i)*30*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Write("server_metrics", map[string]string{"server_id": "2"}, map[string]interface{}{"cpu_usage": 99.0}, t0)
+
+	samples, err := s.Aggregate(context.Background(), &Query{
+		Measurement: "server_metrics",
+		Tags:        map[string]string{"server_id": "1"},
+		Fields:      []string{"cpu_usage"},
+		Start:       t0,
+		End:         t0.Add(150 * time.Second),
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := values(samples)
+	want := []interface{}{15.0, 35.0, nil}
+	if len(got) != len(want) {
+		t.Fatalf("Aggregate() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Aggregate()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if !samples[2].Time.Equal(t0.Add(150 * time.Second)) {
+		t.Errorf("last window time = %s, want truncated to end", samples[2].Time)
+	}
+}
+
+func TestSQLiteSum(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	s := newTestSQLite(t, t0.Add(10*time.Minute))
+
+	write := func(path, status string, requests int64, ts time.Time) {
+		tags := map[string]string{"domain": "a.com", "path": path, "status": status}
+		if err := s.Write("gateway_requests", tags, map[string]interface{}{"requests": requests}, ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("/", "200", 5, t0)
+	write("/", "404", 1, t0)
+	write("/", "200", 7, t0.Add(time.Minute))
+	write("/api", "200", 3, t0)
+
+	rows, err := s.Sum(context.Background(), &Query{
+		Measurement: "gateway_requests",
+		Tags:        map[string]string{"domain": "a.com"},
+		Start:       t0,
+		End:         t0.Add(5 * time.Minute),
+	}, []string{"path"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Tags["path"] != "/" || rows[0].Value != 13 || rows[1].Tags["path"] != "/api" || rows[1].Value != 3 {
+		t.Errorf("Sum() = %+v", rows)
+	}
+}
+
+// 匹配的序列超过一批时分批查询，结果与单批一致
+func TestSQLiteSumManySeries(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	s := newTestSQLite(t, t0.Add(10*time.Minute))
+
+	servers := sqliteBatchSize*2 + 10
+	for i := 0; i < servers; i++ {
+		tags := map[string]string{"server_id": strconv.Itoa(i), "zone": "a"}
+		if err := s.Write("server_metrics", tags, map[string]interface{}{"requests": int64(1)}, t0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Write("server_metrics", map[string]string{"server_id": "x", "zone": "b"}, map[string]interface{}{"requests": int64(1000)}, t0)
+	s.Write("other_metrics", map[string]string{"zone": "a"}, map[string]interface{}{"requests": int64(1000)}, t0)
+
+	rows, err := s.Sum(context.Background(), &Query{
+		Measurement: "server_metrics",
+		Tags:        map[string]string{"zone": "a"},
+		Start:       t0,
+		End:         t0.Add(5 * time.Minute),
+	}, []string{"zone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Value != float64(servers) {
+		t.Errorf("Sum() = %+v, want %d", rows, servers)
+	}
+}
+
+func TestSQLiteRollupAndRetention(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	s := newTestSQLite(t, t0)
+
+	for i := 0; i < 10; i++ {
+		s.Write("app_metrics", map[string]string{"app_id": "7"}, map[string]interface{}{"memory_usage": float64(i)}, t0.Add(time.Duration(i)*time.Minute))
+	}
+	s.Flush()
+
+	// 两小时后原始数据已超过保留时长，查询使用 5 分钟分辨率的数据
+	s.now = func() time.Time { return t0.Add(2 * time.Hour) }
+	if err := s.maintain(); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw int64
+	s.db.Model(&metricSample{}).Where("resolution = 0").Count(&raw)
+	if raw != 0 {
+		t.Errorf("raw samples after retention = %d, want 0", raw)
+	}
+
+	samples, err := s.Aggregate(context.Background(), &Query{
+		Measurement: "app_metrics",
+		Tags:        map[string]string{"app_id": "7"},
+		Fields:      []string{"memory_usage"},
+		Start:       t0,
+		End:         t0.Add(10 * time.Minute),
+	}, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := values(samples)
+	if len(got) != 2 || got[0] != 2.0 || got[1] != 7.0 {
+		t.Errorf("Aggregate() after rollup = %v, want [2 7]", got)
+	}
+
+	// 再次执行不会重复累加
+	if err := s.maintain(); err != nil {
+		t.Fatal(err)
+	}
+	samples, _ = s.Aggregate(context.Background(), &Query{
+		Measurement: "app_metrics",
+		Fields:      []string{"memory_usage"},
+		Start:       t0,
+		End:         t0.Add(10 * time.Minute),
+	}, 10*time.Minute)
+	if got := values(samples); len(got) != 1 || got[0] != 4.5 {
+		t.Errorf("Aggregate() after second maintenance = %v, want [4.5]", got)
+	}
+}
+
+func TestValidateTiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		tiers []Tier
+		ok    bool
+	}{
+		{"valid", []Tier{{0, time.Hour}, {5 * time.Minute, 24 * time.Hour}, {time.Hour, 30 * 24 * time.Hour}}, true},
+		{"no raw tier", []Tier{{5 * time.Minute, time.Hour}}, false},
+		{"not a multiple", []Tier{{0, time.Hour}, {5 * time.Minute, 24 * time.Hour}, {7 * time.Minute, 48 * time.Hour}}, false},
+		{"raw retention too short", []Tier{{0, 5 * time.Minute}, {5 * time.Minute, time.Hour}}, false},
+	}
+	for _, tt := range tests {
+		if err := validateTiers(tt.tiers); (err == nil) != tt.ok {
+			t.Errorf("%s: validateTiers() error = %v", tt.name, err)
+		}
+	}
+}
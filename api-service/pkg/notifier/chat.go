@@ -0,0 +1,84 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 群机器人消息卡片使用的级别颜色，ERROR 为红色，WARNING 为黄色，其余为绿色
+const (
+	colorError   = "#E01E5A"
+	colorWarning = "#ECB22E"
+	colorInfo    = "#2EB67D"
+)
+
+// linkText 消息卡片中链接按钮的文字
+const linkText = "View in console"
+
+func levelColor(level string) string {
+	switch strings.ToUpper(level) {
+	case "ERROR":
+		return colorError
+	case "WARNING":
+		return colorWarning
+	}
+	return colorInfo
+}
+
+// levelLabel 消息标题前的级别标记，如 [WARNING]
+func levelLabel(level string) string {
+	if level == "" {
+		level = "INFO"
+	}
+	return "[" + strings.ToUpper(level) + "]"
+}
+
+// chatResult 钉钉、企业微信与飞书群机器人的响应，HTTP 200 时以错误码表示失败
+type chatResult struct {
+	ErrCode    *int   `json:"errcode"`
+	ErrMsg     string `json:"errmsg"`
+	Code       *int   `json:"code"`
+	Msg        string `json:"msg"`
+	StatusCode *int   `json:"StatusCode"`
+}
+
+func (r *chatResult) err() error {
+	for _, code := range []*int{r.ErrCode, r.Code, r.StatusCode} {
+		if code != nil && *code != 0 {
+			msg := r.ErrMsg
+			if msg == "" {
+				msg = r.Msg
+			}
+			return fmt.Errorf("chat bot returned error %d: %s", *code, msg)
+		}
+	}
+	return nil
+}
+
+// postChat 发送 JSON 消息并检查响应中的错误码
+func postChat(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var result chatResult
+	if err := doDecode(client, req, &result); err != nil {
+		return err
+	}
+	return result.err()
+}
+
+// signHMAC 计算 HMAC-SHA256 并以 base64 编码
+func signHMAC(key, data string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
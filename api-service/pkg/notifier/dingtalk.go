@@ -0,0 +1,82 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("dingtalk", newDingTalk)
+}
+
+// dingTalk 通过钉钉群机器人发送 markdown 消息
+//
+// 配置项:
+//   - url: 机器人 Webhook 地址(含 access_token)，必填
+//   - secret: 可选，机器人启用加签时的密钥，请求时附带 timestamp 与 sign 参数
+type dingTalk struct {
+	url    *url.URL
+	secret string
+	client *http.Client
+}
+
+func newDingTalk(options map[string]string) (Sender, error) {
+	if options["url"] == "" {
+		return nil, errors.New("dingtalk notification driver requires url")
+	}
+	u, err := url.Parse(options["url"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid dingtalk url: %w", err)
+	}
+	return &dingTalk{
+		url:    u,
+		secret: options["secret"],
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (d *dingTalk) Type() string { return TypeWebhook }
+
+func (d *dingTalk) Send(ctx context.Context, _ string, msg *Message) error {
+	title := levelLabel(msg.Level) + " " + msg.Subject
+	var b strings.Builder
+	fmt.Fprintf(&b, "### <font color=\"%s\">%s</font>\n\n", levelColor(msg.Level), title)
+	// 钉钉 markdown 中单个换行不换行
+	b.WriteString(strings.ReplaceAll(msg.Content, "\n", "\n\n"))
+	if msg.Link != "" {
+		fmt.Fprintf(&b, "\n\n[%s](%s)", linkText, msg.Link)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": title,
+			"text":  b.String(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return postChat(ctx, d.client, d.signedURL(time.Now()), body)
+}
+
+// signedURL 启用加签时在地址中附带毫秒时间戳与签名，签名为以 secret 为密钥对 "timestamp\nsecret" 的 HMAC-SHA256
+func (d *dingTalk) signedURL(now time.Time) string {
+	if d.secret == "" {
+		return d.url.String()
+	}
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	u := *d.url
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", signHMAC(d.secret, timestamp+"\n"+d.secret))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
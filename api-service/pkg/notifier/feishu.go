@@ -0,0 +1,98 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("feishu", newFeishu)
+}
+
+// feishu 通过飞书群机器人发送消息卡片，卡片标题颜色表示级别
+//
+// 配置项:
+//   - url: 机器人 Webhook 地址，必填
+//   - secret: 可选，机器人启用签名校验时的密钥，请求体中附带 timestamp 与 sign
+type feishu struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func newFeishu(options map[string]string) (Sender, error) {
+	if options["url"] == "" {
+		return nil, errors.New("feishu notification driver requires url")
+	}
+	return &feishu{
+		url:    options["url"],
+		secret: options["secret"],
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (f *feishu) Type() string { return TypeWebhook }
+
+func (f *feishu) Send(ctx context.Context, _ string, msg *Message) error {
+	elements := []interface{}{
+		map[string]interface{}{
+			"tag":  "div",
+			"text": map[string]string{"tag": "lark_md", "content": msg.Content},
+		},
+	}
+	if msg.Link != "" {
+		elements = append(elements, map[string]interface{}{
+			"tag": "action",
+			"actions": []interface{}{map[string]interface{}{
+				"tag":  "button",
+				"text": map[string]string{"tag": "plain_text", "content": linkText},
+				"type": "primary",
+				"url":  msg.Link,
+			}},
+		})
+	}
+
+	payload := map[string]interface{}{
+		"msg_type": "interactive",
+		"card": map[string]interface{}{
+			"config": map[string]interface{}{"wide_screen_mode": true},
+			"header": map[string]interface{}{
+				"title":    map[string]string{"tag": "plain_text", "content": levelLabel(msg.Level) + " " + msg.Subject},
+				"template": feishuTemplate(msg.Level),
+			},
+			"elements": elements,
+		},
+	}
+	if f.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		payload["timestamp"] = timestamp
+		payload["sign"] = feishuSign(f.secret, timestamp)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return postChat(ctx, f.client, f.url, body)
+}
+
+// feishuSign 签名为以 "timestamp\nsecret" 为密钥对空字符串的 HMAC-SHA256
+func feishuSign(secret, timestamp string) string {
+	return signHMAC(timestamp+"\n"+secret, "")
+}
+
+// feishuTemplate 卡片标题颜色
+func feishuTemplate(level string) string {
+	switch strings.ToUpper(level) {
+	case "ERROR":
+		return "red"
+	case "WARNING":
+		return "orange"
+	}
+	return "green"
+}
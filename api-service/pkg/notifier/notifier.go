@@ -0,0 +1,75 @@
+// Package notifier 提供通知渠道驱动与通知模板渲染
+//
+// 驱动按名称注册，api-service 根据配置中每个渠道的 driver 创建 Sender。
+// 渠道类型决定接收地址：EMAIL 发送到用户邮箱，SMS 发送到用户手机号，WEBHOOK 每条通知发送一次到渠道配置的地址。
+// slack、dingtalk、wecom、feishu 驱动将通知转换为各平台群机器人的消息卡片，渠道类型同为 WEBHOOK。
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// 渠道类型，与 NotificationRecord.Type 一致
+const (
+	TypeEmail   = "EMAIL"
+	TypeSMS     = "SMS"
+	TypeWebhook = "WEBHOOK"
+)
+
+// Message 渲染后的通知
+type Message struct {
+	Subject string
+	Content string
+	Level   string // INFO, WARNING, ERROR
+	Link    string // 可选，控制台中相关页面的地址，消息卡片中显示为按钮
+}
+
+// Sender 通知渠道
+type Sender interface {
+	// Type 渠道类型
+	Type() string
+	// Send 发送通知，to 为邮箱或手机号，WEBHOOK 类型的渠道忽略 to
+	Send(ctx context.Context, to string, msg *Message) error
+}
+
+// Factory 根据配置项创建 Sender
+type Factory func(options map[string]string) (Sender, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register 注册渠道驱动，通常在实现文件的 init 中调用
+func Register(driver string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[driver] = factory
+}
+
+// New 按驱动名称创建 Sender
+func New(driver string, options map[string]string) (Sender, error) {
+	mu.RLock()
+	factory, ok := factories[driver]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown notification driver: %s", driver)
+	}
+	return factory(options)
+}
+
+// Drivers 返回已注册的驱动名称
+func Drivers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	drivers := make([]string, 0, len(factories))
+	for name := range factories {
+		drivers = append(drivers, name)
+	}
+	sort.Strings(drivers)
+	return drivers
+}
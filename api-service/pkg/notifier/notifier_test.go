@@ -0,0 +1,214 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateRender(t *testing.T) {
+	vars, err := ParseVariables(`{"server": "string", "value": "number", "fired_at": "time"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &Template{
+		Subject:   "CPU high on {{.server}}",
+		Content:   `{{printf "%.1f" .value}}% at {{.fired_at.Format "15:04"}}`,
+		Variables: vars,
+	}
+	if err := tmpl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	subject, content, err := tmpl.Render(map[string]interface{}{
+		"server":   "web-1",
+		"value":    95,
+		"fired_at": "2026-01-02T10:30:00Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "CPU high on web-1" || content != "95.0% at 10:30" {
+		t.Errorf("Render() = %q, %q", subject, content)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"server": "web-1", "value": 95},
+		{"server": "web-1", "value": "95", "fired_at": time.Now()},
+		{"server": "web-1", "value": 95, "fired_at": time.Now(), "extra": 1},
+	} {
+		if _, _, err := tmpl.Render(bad); err == nil {
+			t.Errorf("Render(%v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	if _, err := ParseVariables(`{"x": "uuid"}`); err == nil {
+		t.Error("ParseVariables accepted unknown type")
+	}
+	for _, tmpl := range []*Template{
+		{Subject: "{{.missing}}", Variables: map[string]string{}},
+		{Content: "{{.x", Variables: map[string]string{"x": VarString}},
+	} {
+		if err := tmpl.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error", tmpl)
+		}
+	}
+}
+
+func TestHTTPDrivers(t *testing.T) {
+	var got []map[string]string
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		params := map[string]string{}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			json.Unmarshal(body, &params)
+		} else {
+			form, _ := url.ParseQuery(string(body))
+			for k := range form {
+				params[k] = form.Get(k)
+			}
+		}
+		got = append(got, params)
+	}))
+	defer srv.Close()
+
+	hook, err := New("webhook", map[string]string{"url": srv.URL, "token": "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{Subject: "s", Content: "c", Level: "ERROR"}
+	if err := hook.Send(context.Background(), "", msg); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer t" || got[0]["subject"] != "s" || got[0]["level"] != "ERROR" {
+		t.Errorf("webhook request = %v, auth %q", got[0], auth)
+	}
+
+	sms, err := New("sms", map[string]string{"url": srv.URL, "format": "form", "phone_param": "mobile", "sender": "Webox"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sms.Send(context.Background(), "13800000000", msg); err != nil {
+		t.Fatal(err)
+	}
+	if p := got[1]; p["mobile"] != "13800000000" || p["message"] != "c" || p["sender"] != "Webox" {
+		t.Errorf("sms request = %v", p)
+	}
+
+	if _, err := New("sms", map[string]string{}); err == nil {
+		t.Error("New(sms) without url succeeded")
+	}
+	if _, err := New("pigeon", nil); err == nil {
+		t.Error("New(pigeon) succeeded")
+	}
+}
+
+func TestChatDrivers(t *testing.T) {
+	type request struct {
+		query url.Values
+		body  map[string]interface{}
+	}
+	var got request
+	reply := `{"errcode": 0, "errmsg": "ok"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = request{query: r.URL.Query()}
+		json.NewDecoder(r.Body).Decode(&got.body)
+		io.WriteString(w, reply)
+	}))
+	defer srv.Close()
+
+	send := func(driver string, options map[string]string, msg *Message) error {
+		t.Helper()
+		options["url"] = srv.URL + "/hook?access_token=abc"
+		sender, err := New(driver, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sender.Type() != TypeWebhook {
+			t.Errorf("%s type = %s", driver, sender.Type())
+		}
+		return sender.Send(context.Background(), "", msg)
+	}
+	// field 按路径读取 JSON 字段，数字为数组下标
+	field := func(path ...interface{}) interface{} {
+		var v interface{} = got.body
+		for _, p := range path {
+			switch k := p.(type) {
+			case string:
+				m, _ := v.(map[string]interface{})
+				v = m[k]
+			case int:
+				a, _ := v.([]interface{})
+				if k >= len(a) {
+					return nil
+				}
+				v = a[k]
+			}
+		}
+		return v
+	}
+	msg := &Message{Subject: "CPU high", Content: "avg > 90 <now>", Level: "ERROR", Link: "https://webox.example.com/alerts/1"}
+
+	if err := send("slack", map[string]string{}, msg); err != nil {
+		t.Fatal(err)
+	}
+	if field("attachments", 0, "color") != colorError ||
+		field("attachments", 0, "blocks", 1, "text", "text") != "avg &gt; 90 &lt;now&gt;" ||
+		field("attachments", 0, "blocks", 2, "elements", 0, "url") != msg.Link {
+		t.Errorf("slack payload = %v", got.body)
+	}
+
+	if err := send("dingtalk", map[string]string{"secret": "SEC"}, msg); err != nil {
+		t.Fatal(err)
+	}
+	ts := got.query.Get("timestamp")
+	if got.query.Get("access_token") != "abc" || got.query.Get("sign") != signHMAC("SEC", ts+"\nSEC") {
+		t.Errorf("dingtalk query = %v", got.query)
+	}
+	if text, _ := field("markdown", "text").(string); !strings.Contains(text, colorError) || !strings.Contains(text, "("+msg.Link+")") {
+		t.Errorf("dingtalk text = %q", text)
+	}
+
+	if err := send("wecom", map[string]string{}, msg); err != nil {
+		t.Fatal(err)
+	}
+	if field("msgtype") != "template_card" || field("template_card", "card_action", "url") != msg.Link ||
+		field("template_card", "source", "desc_color") != float64(2) {
+		t.Errorf("wecom payload = %v", got.body)
+	}
+	if err := send("wecom", map[string]string{}, &Message{Subject: "s", Content: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if field("msgtype") != "markdown" {
+		t.Errorf("wecom payload without link = %v", got.body)
+	}
+
+	reply = `{"code": 0, "msg": "success"}`
+	if err := send("feishu", map[string]string{"secret": "SEC"}, msg); err != nil {
+		t.Fatal(err)
+	}
+	ts, _ = field("timestamp").(string)
+	if field("sign") != feishuSign("SEC", ts) || field("card", "header", "template") != "red" ||
+		field("card", "elements", 1, "actions", 0, "url") != msg.Link {
+		t.Errorf("feishu payload = %v", got.body)
+	}
+
+	reply = `{"code": 19021, "msg": "sign match fail or timestamp is not within one hour from current time"}`
+	if err := send("feishu", map[string]string{"secret": "SEC"}, msg); err == nil {
+		t.Error("feishu error response treated as success")
+	}
+	reply = `{"errcode": 310000, "errmsg": "keywords not in content"}`
+	if err := send("dingtalk", map[string]string{}, msg); err == nil {
+		t.Error("dingtalk error response treated as success")
+	}
+}
@@ -0,0 +1,86 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("slack", newSlack)
+}
+
+// slack 通过 Incoming Webhook 发送 Block Kit 消息，附件的颜色条表示级别
+//
+// 配置项:
+//   - url: Incoming Webhook 地址，必填
+type slack struct {
+	url    string
+	client *http.Client
+}
+
+func newSlack(options map[string]string) (Sender, error) {
+	if options["url"] == "" {
+		return nil, errors.New("slack notification driver requires url")
+	}
+	return &slack{
+		url:    options["url"],
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (s *slack) Type() string { return TypeWebhook }
+
+func (s *slack) Send(ctx context.Context, _ string, msg *Message) error {
+	title := levelLabel(msg.Level) + " " + msg.Subject
+	blocks := []interface{}{
+		map[string]interface{}{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": truncate(title, 150)},
+		},
+		map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": truncate(slackEscape(msg.Content), 3000)},
+		},
+	}
+	if msg.Link != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "actions",
+			"elements": []interface{}{map[string]interface{}{
+				"type": "button",
+				"text": map[string]interface{}{"type": "plain_text", "text": linkText},
+				"url":  msg.Link,
+			}},
+		})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		// text 用于推送通知与不支持 Block Kit 的客户端
+		"text": title,
+		"attachments": []interface{}{map[string]interface{}{
+			"color":  levelColor(msg.Level),
+			"blocks": blocks,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, s.url, "", body)
+}
+
+// slackEscape 转义 mrkdwn 中的控制字符
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// truncate 将字符串截断为不超过 n 个字符
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,99 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("sms", newSMS)
+}
+
+// sms 通过 HTTP 接口对接短信网关，只发送通知内容
+//
+// 配置项:
+//   - url: 网关地址，必填
+//   - token: 可选，以 Bearer Token 方式携带
+//   - format: json(默认) 或 form
+//   - phone_param / message_param: 手机号与内容的参数名，默认 phone 与 message
+//   - sender: 可选，以 sender_param(默认 sender) 参数携带的签名或发送号码
+type sms struct {
+	url          string
+	token        string
+	form         bool
+	phoneParam   string
+	messageParam string
+	senderParam  string
+	sender       string
+	client       *http.Client
+}
+
+func newSMS(options map[string]string) (Sender, error) {
+	if options["url"] == "" {
+		return nil, errors.New("sms notification driver requires url")
+	}
+	s := &sms{
+		url:          options["url"],
+		token:        options["token"],
+		phoneParam:   optionOr(options, "phone_param", "phone"),
+		messageParam: optionOr(options, "message_param", "message"),
+		senderParam:  optionOr(options, "sender_param", "sender"),
+		sender:       options["sender"],
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+	switch format := optionOr(options, "format", "json"); format {
+	case "json":
+	case "form":
+		s.form = true
+	default:
+		return nil, fmt.Errorf("unsupported sms format: %s", format)
+	}
+	return s, nil
+}
+
+func (s *sms) Type() string { return TypeSMS }
+
+func (s *sms) Send(ctx context.Context, to string, msg *Message) error {
+	if to == "" {
+		return errors.New("phone number is required")
+	}
+	params := map[string]string{s.phoneParam: to, s.messageParam: msg.Content}
+	if s.sender != "" {
+		params[s.senderParam] = s.sender
+	}
+
+	if !s.form {
+		body, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		return postJSON(ctx, s.client, s.url, s.token, body)
+	}
+
+	values := url.Values{}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	return do(s.client, req)
+}
+
+func optionOr(options map[string]string, key, def string) string {
+	if v := options[key]; v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,143 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("smtp", newSMTP)
+}
+
+// smtpSender 通过 SMTP 发送纯文本邮件
+//
+// 配置项:
+//   - host / port: 服务器地址，port 默认 587
+//   - from: 发件人，如 "Webox <noreply@example.com>"，必填
+//   - username / password: 可选，使用 PLAIN 认证(仅在加密连接或本机地址上可用)
+//   - tls: starttls(默认，要求服务器支持 STARTTLS)、tls(465 端口的隐式 TLS)或 none(本机中继)
+type smtpSender struct {
+	host     string
+	addr     string
+	from     *mail.Address
+	username string
+	password string
+	mode     string
+}
+
+func newSMTP(options map[string]string) (Sender, error) {
+	if options["host"] == "" {
+		return nil, errors.New("smtp notification driver requires host")
+	}
+	from, err := mail.ParseAddress(options["from"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp from address: %w", err)
+	}
+	mode := optionOr(options, "tls", "starttls")
+	if mode != "starttls" && mode != "tls" && mode != "none" {
+		return nil, fmt.Errorf("unsupported smtp tls mode: %s", mode)
+	}
+	return &smtpSender{
+		host:     options["host"],
+		addr:     net.JoinHostPort(options["host"], optionOr(options, "port", "587")),
+		from:     from,
+		username: options["username"],
+		password: options["password"],
+		mode:     mode,
+	}, nil
+}
+
+func (s *smtpSender) Type() string { return TypeEmail }
+
+func (s *smtpSender) Send(ctx context.Context, to string, msg *Message) error {
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid email address %q: %w", to, err)
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.mode == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", s.addr)
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(rcpt.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.compose(rcpt, msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial 建立连接，ctx 的截止时间同时作为整个会话的超时
+func (s *smtpSender) dial(ctx context.Context) (*smtp.Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if s.mode == "tls" {
+		conn = tls.Client(conn, &tls.Config{ServerName: s.host})
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func (s *smtpSender) compose(to *mail.Address, msg *Message) []byte {
+	var buf bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+	header("From", s.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	buf.WriteString("\r\n")
+	// 正文统一使用 CRLF 换行
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Content, "\r\n", "\n"), "\n", "\r\n"))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
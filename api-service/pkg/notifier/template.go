@@ -0,0 +1,158 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 模板变量类型
+const (
+	VarString = "string"
+	VarNumber = "number"
+	VarBool   = "bool"
+	VarTime   = "time" // time.Time，或 RFC3339 格式的字符串
+)
+
+var varTypes = map[string]bool{VarString: true, VarNumber: true, VarBool: true, VarTime: true}
+
+// Template 通知模板，Subject 与 Content 使用 text/template 语法，以 {{.name}} 引用变量
+// Variables 声明全部变量及其类型，渲染时须全部提供且类型匹配
+type Template struct {
+	Subject   string
+	Content   string
+	Variables map[string]string
+}
+
+// ParseVariables 解析形如 {"server": "string", "value": "number"} 的变量声明，空字符串表示没有变量
+func ParseVariables(raw string) (map[string]string, error) {
+	vars := make(map[string]string)
+	if strings.TrimSpace(raw) == "" {
+		return vars, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &vars); err != nil {
+		return nil, fmt.Errorf("invalid template variables: %w", err)
+	}
+	for name, typ := range vars {
+		if !varTypes[typ] {
+			return nil, fmt.Errorf("variable %q has unknown type %q", name, typ)
+		}
+	}
+	return vars, nil
+}
+
+// Validate 检查模板语法，并以各类型的零值试渲染，引用未声明的变量时报错
+func (t *Template) Validate() error {
+	zero := make(map[string]interface{}, len(t.Variables))
+	for name, typ := range t.Variables {
+		switch typ {
+		case VarNumber:
+			zero[name] = float64(0)
+		case VarBool:
+			zero[name] = false
+		case VarTime:
+			zero[name] = time.Time{}
+		default:
+			zero[name] = ""
+		}
+	}
+	_, _, err := t.Render(zero)
+	return err
+}
+
+// Render 校验变量后渲染主题与内容
+func (t *Template) Render(vars map[string]interface{}) (string, string, error) {
+	data, err := t.convert(vars)
+	if err != nil {
+		return "", "", err
+	}
+	subject, err := execute("subject", t.Subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	content, err := execute("content", t.Content, data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, content, nil
+}
+
+// convert 按声明的类型转换变量，JSON 解码得到的数值与时间字符串也可接受
+func (t *Template) convert(vars map[string]interface{}) (map[string]interface{}, error) {
+	for name := range vars {
+		if _, ok := t.Variables[name]; !ok {
+			return nil, fmt.Errorf("undeclared variable %q", name)
+		}
+	}
+
+	names := make([]string, 0, len(t.Variables))
+	for name := range t.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	data := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		v, ok := vars[name]
+		if !ok {
+			return nil, fmt.Errorf("missing variable %q", name)
+		}
+		converted, ok := convertValue(t.Variables[name], v)
+		if !ok {
+			return nil, fmt.Errorf("variable %q must be a %s, got %T", name, t.Variables[name], v)
+		}
+		data[name] = converted
+	}
+	return data, nil
+}
+
+func convertValue(typ string, v interface{}) (interface{}, bool) {
+	switch typ {
+	case VarString:
+		s, ok := v.(string)
+		return s, ok
+	case VarNumber:
+		switch n := v.(type) {
+		case float64:
+			return n, true
+		case float32:
+			return float64(n), true
+		case int:
+			return float64(n), true
+		case int64:
+			return float64(n), true
+		case uint:
+			return float64(n), true
+		case json.Number:
+			f, err := n.Float64()
+			return f, err == nil
+		}
+	case VarBool:
+		b, ok := v.(bool)
+		return b, ok
+	case VarTime:
+		switch tv := v.(type) {
+		case time.Time:
+			return tv, true
+		case string:
+			parsed, err := time.Parse(time.RFC3339, tv)
+			return parsed, err == nil
+		}
+	}
+	return nil, false
+}
+
+func execute(name, text string, data map[string]interface{}) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %s: %w", name, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render %s: %w", name, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,94 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+func init() {
+	Register("webhook", newWebhook)
+}
+
+// webhook 以 JSON 将通知 POST 到任意 HTTP 地址
+//
+// 配置项:
+//   - url: 接收请求的地址，必填
+//   - token: 可选，以 Bearer Token 方式携带
+//
+// 请求体为 {"subject": "...", "content": "...", "level": "...", "link": "...", "sent_at": "..."}，返回 2xx 视为成功
+type webhook struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newWebhook(options map[string]string) (Sender, error) {
+	if options["url"] == "" {
+		return nil, errors.New("webhook notification driver requires url")
+	}
+	return &webhook{
+		url:    options["url"],
+		token:  options["token"],
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (w *webhook) Type() string { return TypeWebhook }
+
+func (w *webhook) Send(ctx context.Context, _ string, msg *Message) error {
+	body, err := json.Marshal(map[string]string{
+		"subject": msg.Subject,
+		"content": msg.Content,
+		"level":   msg.Level,
+		"link":    msg.Link,
+		"sent_at": time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, w.client, w.url, w.token, body)
+}
+
+// postJSON 发送 JSON 请求，非 2xx 响应返回包含响应片段的错误
+func postJSON(ctx context.Context, client *http.Client, url, token string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return do(client, req)
+}
+
+func do(client *http.Client, req *http.Request) error {
+	return doDecode(client, req, nil)
+}
+
+// doDecode 发送请求，2xx 响应且 out 不为空时将响应体解码到 out
+func doDecode(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s %s", req.URL.Host, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", req.URL.Host, err)
+	}
+	return nil
+}
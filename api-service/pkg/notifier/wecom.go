@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("wecom", newWeCom)
+}
+
+// weCom 通过企业微信群机器人发送消息
+// 有链接时发送文本通知模板卡片，点击卡片跳转到控制台；否则发送 markdown 消息
+//
+// 配置项:
+//   - url: 机器人 Webhook 地址(含 key)，必填
+type weCom struct {
+	url    string
+	client *http.Client
+}
+
+func newWeCom(options map[string]string) (Sender, error) {
+	if options["url"] == "" {
+		return nil, errors.New("wecom notification driver requires url")
+	}
+	return &weCom{
+		url:    options["url"],
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (w *weCom) Type() string { return TypeWebhook }
+
+func (w *weCom) Send(ctx context.Context, _ string, msg *Message) error {
+	var payload map[string]interface{}
+	if msg.Link != "" {
+		payload = map[string]interface{}{
+			"msgtype": "template_card",
+			"template_card": map[string]interface{}{
+				"card_type": "text_notice",
+				"source": map[string]interface{}{
+					"desc":       levelLabel(msg.Level),
+					"desc_color": weComColor(msg.Level),
+				},
+				"main_title":     map[string]string{"title": msg.Subject},
+				"sub_title_text": msg.Content,
+				"jump_list": []interface{}{map[string]interface{}{
+					"type":  1,
+					"title": linkText,
+					"url":   msg.Link,
+				}},
+				"card_action": map[string]interface{}{"type": 1, "url": msg.Link},
+			},
+		}
+	} else {
+		// markdown 只支持 info(绿)、comment(灰)、warning(橙红) 三种颜色
+		color := "info"
+		if level := strings.ToUpper(msg.Level); level == "ERROR" || level == "WARNING" {
+			color = "warning"
+		}
+		payload = map[string]interface{}{
+			"msgtype": "markdown",
+			"markdown": map[string]string{
+				"content": fmt.Sprintf("### <font color=\"%s\">%s %s</font>\n%s",
+					color, levelLabel(msg.Level), msg.Subject, msg.Content),
+			},
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return postChat(ctx, w.client, w.url, body)
+}
+
+// weComColor 模板卡片来源文字颜色：1 黑色，2 红色，3 绿色
+func weComColor(level string) int {
+	switch strings.ToUpper(level) {
+	case "ERROR":
+		return 2
+	case "WARNING":
+		return 1
+	}
+	return 3
+}
@@ -0,0 +1,176 @@
+package proxyconf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// warnings 按出现顺序记录去重后的告警
+type warnings struct {
+	seen map[string]bool
+	list []string
+}
+
+func (w *warnings) add(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if w.seen == nil {
+		w.seen = make(map[string]bool)
+	}
+	if !w.seen[msg] {
+		w.seen[msg] = true
+		w.list = append(w.list, msg)
+	}
+}
+
+// Nginx 生成 nginx 配置，文件应在 http 块内引入(如 conf.d 目录)
+// 黑名单与白名单转换为 deny/allow，限流转换为 limit_req，REDIRECT 通过 error_page 跳转
+func Nginx(sites []Site) (*Result, error) {
+	var buf bytes.Buffer
+	var warn warnings
+
+	buf.WriteString("# Generated by Webox. Manual changes will be overwritten.\n")
+
+	for _, site := range sites {
+		buf.WriteString("\n")
+		fmt.Fprintf(&buf, "# %s -> %s\n", site.Domain, site.Upstream)
+
+		// limit_req_zone 与 geo 只能位于 http 块
+		for _, rule := range site.Rules {
+			if rule.Type != RuleRateLimit || rule.Action == ActionAllow {
+				continue
+			}
+			zone := zoneName(site, rule)
+			key := "$binary_remote_addr"
+			if len(rule.IPs) > 0 {
+				key = "$" + zone + "_key"
+				fmt.Fprintf(&buf, "geo %s {\n    default \"\";\n", key)
+				for _, ip := range rule.IPs {
+					fmt.Fprintf(&buf, "    %s $binary_remote_addr;\n", ip)
+				}
+				buf.WriteString("}\n")
+			}
+			rate, exact := nginxRate(rule.LimitCount, rule.Window)
+			if !exact {
+				warn.add("rule %s: %d requests per %ds approximated as %s", rule.Name, rule.LimitCount, rule.Window, rate)
+			}
+			fmt.Fprintf(&buf, "limit_req_zone %s zone=%s:10m rate=%s;\n", key, zone, rate)
+		}
+
+		if site.TLS != nil {
+			fmt.Fprintf(&buf, "server {\n    listen 80;\n    server_name %s;\n\n", site.Domain)
+			buf.WriteString("    location / {\n        return 308 https://$host$request_uri;\n    }\n}\n")
+		}
+
+		buf.WriteString("server {\n")
+		if site.TLS != nil {
+			buf.WriteString("    listen 443 ssl;\n")
+		} else {
+			buf.WriteString("    listen 80;\n")
+		}
+		fmt.Fprintf(&buf, "    server_name %s;\n", site.Domain)
+		if site.TLS != nil {
+			fmt.Fprintf(&buf, "    ssl_certificate %s;\n    ssl_certificate_key %s;\n", site.TLS.CertFile, site.TLS.KeyFile)
+		}
+
+		for _, path := range locationPaths(site.Rules) {
+			buf.WriteString("\n")
+			writeNginxLocation(&buf, &warn, site, path)
+		}
+		buf.WriteString("}\n")
+	}
+
+	return &Result{Content: buf.Bytes(), Warnings: warn.list}, nil
+}
+
+func writeNginxLocation(buf *bytes.Buffer, warn *warnings, site Site, path string) {
+	if path == "/" {
+		buf.WriteString("    location / {\n")
+	} else {
+		fmt.Fprintf(buf, "    location ^~ %s {\n", path)
+	}
+
+	var deny, allow []string
+	var forbidden, limited *Rule
+	rules := rulesFor(site.Rules, path)
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Action == ActionAllow {
+			warn.add("rule %s: ALLOW rules only count hits and are not exported", rule.Name)
+			continue
+		}
+		switch rule.Type {
+		case RuleIPBlacklist:
+			deny = append(deny, rule.IPs...)
+			forbidden = pickAction(warn, forbidden, rule)
+		case RuleIPWhitelist:
+			allow = append(allow, rule.IPs...)
+			forbidden = pickAction(warn, forbidden, rule)
+		case RuleRateLimit:
+			// nodelay 时首个请求之外还可立即通过 burst 个请求
+			fmt.Fprintf(buf, "        limit_req zone=%s burst=%d nodelay;\n", zoneName(site, *rule), max(rule.LimitCount-1, 0))
+			limited = pickAction(warn, limited, rule)
+		}
+	}
+
+	// 与网关一致：先匹配黑名单，再要求命中白名单
+	for _, ip := range deny {
+		fmt.Fprintf(buf, "        deny %s;\n", ip)
+	}
+	if len(allow) > 0 {
+		for _, ip := range allow {
+			fmt.Fprintf(buf, "        allow %s;\n", ip)
+		}
+		buf.WriteString("        deny all;\n")
+	}
+	if limited != nil {
+		buf.WriteString("        limit_req_status 429;\n")
+	}
+	if forbidden != nil && forbidden.Action == ActionRedirect {
+		fmt.Fprintf(buf, "        error_page 403 =302 %s;\n", forbidden.RedirectURL)
+	}
+	if limited != nil && limited.Action == ActionRedirect {
+		fmt.Fprintf(buf, "        error_page 429 =302 %s;\n", limited.RedirectURL)
+	}
+
+	fmt.Fprintf(buf, "        proxy_pass http://%s;\n", site.Upstream)
+	buf.WriteString("        proxy_http_version 1.1;\n")
+	buf.WriteString("        proxy_set_header Host $host;\n")
+	buf.WriteString("        proxy_set_header X-Real-IP $remote_addr;\n")
+	buf.WriteString("        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;\n")
+	buf.WriteString("        proxy_set_header X-Forwarded-Proto $scheme;\n")
+	buf.WriteString("        proxy_set_header Upgrade $http_upgrade;\n")
+	buf.WriteString("        proxy_set_header Connection $http_connection;\n")
+	buf.WriteString("    }\n")
+}
+
+// pickAction 同一 location 中同类响应只能有一种处理方式，以先出现的规则为准
+func pickAction(warn *warnings, current, rule *Rule) *Rule {
+	if current == nil {
+		return rule
+	}
+	if current.Action != rule.Action || current.RedirectURL != rule.RedirectURL {
+		warn.add("rule %s: action conflicts with rule %s on the same path, using %s", rule.Name, current.Name, current.Action)
+	}
+	return current
+}
+
+// nginxRate 将 count/window 转换为 nginx 支持的 r/s 或 r/m，无法整除时向上取整
+func nginxRate(count, window int) (string, bool) {
+	if window > 0 && count%window == 0 {
+		return fmt.Sprintf("%dr/s", count/window), true
+	}
+	perMinute := count * 60
+	if window > 0 && perMinute%window == 0 {
+		return fmt.Sprintf("%dr/m", perMinute/window), true
+	}
+	rate := 1
+	if window > 0 {
+		rate = (perMinute + window - 1) / window
+	}
+	return fmt.Sprintf("%dr/m", rate), false
+}
+
+func zoneName(site Site, rule Rule) string {
+	return strings.ToLower("webox_" + site.Name + "_" + rule.Name)
+}
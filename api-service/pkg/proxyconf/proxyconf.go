@@ -0,0 +1,153 @@
+// Package proxyconf 将网关发布规则转换为 nginx 或 Traefik 配置，
+// 供需要继续使用现有反向代理的主机替代内置网关
+package proxyconf
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// 支持的配置格式
+const (
+	FormatNginx   = "nginx"
+	FormatTraefik = "traefik"
+)
+
+// 访问控制规则类型与处理方式，取值与网关规则一致
+const (
+	RuleIPWhitelist = "IP_WHITELIST"
+	RuleIPBlacklist = "IP_BLACKLIST"
+	RuleRateLimit   = "RATE_LIMIT"
+
+	ActionBlock    = "BLOCK"
+	ActionRedirect = "REDIRECT"
+	ActionAllow    = "ALLOW"
+)
+
+// Site 一个发布域名
+type Site struct {
+	// Name 站点标识，用于生成 zone、router 等名称，只能包含字母、数字和下划线
+	Name     string
+	Domain   string
+	Upstream string // host:port
+	TLS      *TLS
+	Rules    []Rule
+}
+
+// TLS 证书文件在目标主机上的路径
+type TLS struct {
+	CertFile string
+	KeyFile  string
+}
+
+// Rule 访问控制规则，Path 为路径前缀，为空时作用于全部请求
+type Rule struct {
+	Name        string
+	Type        string
+	Path        string
+	IPs         []string
+	LimitCount  int
+	Window      int // 秒
+	Action      string
+	RedirectURL string
+}
+
+// Result 生成结果，Warnings 记录目标代理无法等价表达而被跳过或近似处理的规则
+type Result struct {
+	Content  []byte
+	Warnings []string
+}
+
+// Generate 按格式生成配置
+func Generate(format string, sites []Site) (*Result, error) {
+	for i := range sites {
+		if err := validateSite(&sites[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	switch format {
+	case FormatNginx:
+		return Nginx(sites)
+	case FormatTraefik:
+		return Traefik(sites)
+	default:
+		return nil, fmt.Errorf("unsupported proxy config format: %s", format)
+	}
+}
+
+// validateSite 校验会写入配置文件的字段，防止注入额外指令
+func validateSite(site *Site) error {
+	if !isIdentifier(site.Name) {
+		return fmt.Errorf("invalid site name: %q", site.Name)
+	}
+	if site.Domain == "" || strings.ContainsAny(site.Domain, " \t\r\n;{}\"'`$\\") {
+		return fmt.Errorf("invalid domain: %q", site.Domain)
+	}
+	host, port, err := net.SplitHostPort(site.Upstream)
+	if err != nil || host == "" || port == "" || strings.ContainsAny(site.Upstream, " \t\r\n;{}\"'`$\\/") {
+		return fmt.Errorf("invalid upstream for %s: %q", site.Domain, site.Upstream)
+	}
+	if site.TLS != nil {
+		for _, path := range []string{site.TLS.CertFile, site.TLS.KeyFile} {
+			if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, " \t\r\n;{}\"'`$\\") {
+				return fmt.Errorf("invalid certificate path for %s: %q", site.Domain, path)
+			}
+		}
+	}
+
+	for _, rule := range site.Rules {
+		if !isIdentifier(rule.Name) {
+			return fmt.Errorf("invalid rule name: %q", rule.Name)
+		}
+		if rule.Path != "" && (!strings.HasPrefix(rule.Path, "/") || strings.ContainsAny(rule.Path, " \t\r\n;{}\"'`$\\")) {
+			return fmt.Errorf("invalid path for rule %s: %q", rule.Name, rule.Path)
+		}
+		for _, ip := range rule.IPs {
+			if _, _, err := net.ParseCIDR(ip); err != nil {
+				return fmt.Errorf("invalid CIDR for rule %s: %q", rule.Name, ip)
+			}
+		}
+		if rule.Action == ActionRedirect && (rule.RedirectURL == "" || strings.ContainsAny(rule.RedirectURL, " \t\r\n;{}\"'`$\\")) {
+			return fmt.Errorf("invalid redirect URL for rule %s: %q", rule.Name, rule.RedirectURL)
+		}
+	}
+	return nil
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// rulesFor 返回作用于 path 前缀的规则，与网关按前缀匹配的语义一致
+func rulesFor(rules []Rule, path string) []Rule {
+	var matched []Rule
+	for _, rule := range rules {
+		if rule.Path == "" || strings.HasPrefix(path, rule.Path) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+// locationPaths 返回需要单独生成的路径前缀，"/" 始终在首位
+func locationPaths(rules []Rule) []string {
+	paths := []string{"/"}
+	seen := map[string]bool{"/": true}
+	for _, rule := range rules {
+		if rule.Path != "" && !seen[rule.Path] {
+			seen[rule.Path] = true
+			paths = append(paths, rule.Path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,124 @@
+package proxyconf
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func testSites() []Site {
+	return []Site{{
+		Name:     "g1_p1",
+		Domain:   "app.example.com",
+		Upstream: "10.0.0.5:8080",
+		TLS:      &TLS{CertFile: "/etc/nginx/webox/certs/cert-1.crt", KeyFile: "/etc/nginx/webox/certs/cert-1.key"},
+		Rules: []Rule{
+			{Name: "r1", Type: RuleIPBlacklist, Path: "/legacy", IPs: []string{"192.0.2.0/24"}, Action: ActionRedirect, RedirectURL: "https://example.com/denied"},
+			{Name: "r2", Type: RuleIPWhitelist, Path: "/admin", IPs: []string{"10.0.0.0/8"}, Action: ActionBlock},
+			{Name: "r3", Type: RuleRateLimit, Path: "/api", LimitCount: 100, Window: 60, Action: ActionBlock},
+			{Name: "r4", Type: RuleRateLimit, LimitCount: 1, Window: 7, Action: ActionAllow},
+		},
+	}}
+}
+
+func TestNginx(t *testing.T) {
+	result, err := Generate(FormatNginx, testSites())
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	conf := string(result.Content)
+
+	for _, want := range []string{
+		"limit_req_zone $binary_remote_addr zone=webox_g1_p1_r3:10m rate=100r/m;",
+		"return 308 https://$host$request_uri;",
+		"ssl_certificate /etc/nginx/webox/certs/cert-1.crt;",
+		"location ^~ /legacy {\n        deny 192.0.2.0/24;\n        error_page 403 =302 https://example.com/denied;",
+		"location ^~ /admin {\n        allow 10.0.0.0/8;\n        deny all;\n        proxy_pass",
+		"location ^~ /api {\n        limit_req zone=webox_g1_p1_r3 burst=99 nodelay;\n        limit_req_status 429;",
+		"proxy_pass http://10.0.0.5:8080;",
+	} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("config missing %q\n%s", want, conf)
+		}
+	}
+	if strings.Count(conf, "{") != strings.Count(conf, "}") {
+		t.Errorf("unbalanced braces\n%s", conf)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "r4") {
+		t.Errorf("Warnings = %v, want one warning for r4", result.Warnings)
+	}
+}
+
+func TestTraefik(t *testing.T) {
+	result, err := Generate(FormatTraefik, testSites())
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	var cfg traefikConfig
+	if err := yaml.Unmarshal(result.Content, &cfg); err != nil {
+		t.Fatalf("output is not valid YAML: %v\n%s", err, result.Content)
+	}
+
+	admin := cfg.HTTP.Routers["webox-g1-p1-path-admin"]
+	if admin == nil || admin.Rule != "Host(`app.example.com`) && PathPrefix(`/admin`)" {
+		t.Fatalf("admin router = %+v", admin)
+	}
+	if len(admin.Middlewares) != 1 || cfg.HTTP.Middlewares[admin.Middlewares[0]].IPAllowList == nil {
+		t.Errorf("admin router middlewares = %v, want ip allow list", admin.Middlewares)
+	}
+	if cfg.HTTP.Routers["webox-g1-p1-http"] == nil || cfg.TLS == nil || len(cfg.TLS.Certificates) != 1 {
+		t.Errorf("missing HTTPS redirect router or TLS certificate\n%s", result.Content)
+	}
+	if rl := cfg.HTTP.Middlewares["webox-g1-p1-r3"]; rl == nil || rl.RateLimit.Average != 100 || rl.RateLimit.Period != "60s" {
+		t.Errorf("rate limit middleware = %+v", rl)
+	}
+	// 黑名单、跳转与 ALLOW 规则无法在 Traefik 中表达
+	if len(result.Warnings) != 3 {
+		t.Errorf("Warnings = %v, want 3", result.Warnings)
+	}
+}
+
+func TestGenerateRejectsInjection(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Site)
+	}{
+		{"domain", func(s *Site) { s.Domain = "a.com; include /etc/passwd" }},
+		{"upstream", func(s *Site) { s.Upstream = "10.0.0.5:80;" }},
+		{"path", func(s *Site) { s.Rules[1].Path = "/admin { return 200; }" }},
+		{"redirect", func(s *Site) { s.Rules[0].RedirectURL = "https://x/$host" }},
+		{"cidr", func(s *Site) { s.Rules[0].IPs = []string{"all"} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sites := testSites()
+			tt.mutate(&sites[0])
+			if _, err := Generate(FormatNginx, sites); err == nil {
+				t.Error("Generate() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestNginxRate(t *testing.T) {
+	tests := []struct {
+		count, window int
+		want          string
+		exact         bool
+	}{
+		{10, 1, "10r/s", true},
+		{100, 60, "100r/m", true},
+		{30, 10, "3r/s", true},
+		{1, 7, "9r/m", false},
+		{1, 3600, "1r/m", false},
+	}
+	for _, tt := range tests {
+		got, exact := nginxRate(tt.count, tt.window)
+		if got != tt.want || exact != tt.exact {
+			t.Errorf("nginxRate(%d, %d) = %s, %v, want %s, %v", tt.count, tt.window, got, exact, tt.want, tt.exact)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package proxyconf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Traefik 默认入口点名称
+const (
+	TraefikEntryPointHTTP  = "web"
+	TraefikEntryPointHTTPS = "websecure"
+)
+
+// Traefik v3 文件提供者的动态配置结构
+type traefikConfig struct {
+	HTTP traefikHTTP `yaml:"http"`
+	TLS  *traefikTLS `yaml:"tls,omitempty"`
+}
+
+type traefikHTTP struct {
+	Routers     map[string]*traefikRouter     `yaml:"routers"`
+	Services    map[string]*traefikService    `yaml:"services"`
+	Middlewares map[string]*traefikMiddleware `yaml:"middlewares,omitempty"`
+}
+
+type traefikRouter struct {
+	Rule        string            `yaml:"rule"`
+	EntryPoints []string          `yaml:"entryPoints"`
+	Service     string            `yaml:"service"`
+	Middlewares []string          `yaml:"middlewares,omitempty"`
+	TLS         *traefikRouterTLS `yaml:"tls,omitempty"`
+}
+
+type traefikRouterTLS struct{}
+
+type traefikService struct {
+	LoadBalancer traefikLoadBalancer `yaml:"loadBalancer"`
+}
+
+type traefikLoadBalancer struct {
+	Servers        []traefikServer `yaml:"servers"`
+	PassHostHeader bool            `yaml:"passHostHeader"`
+}
+
+type traefikServer struct {
+	URL string `yaml:"url"`
+}
+
+type traefikMiddleware struct {
+	IPAllowList    *traefikIPAllowList    `yaml:"ipAllowList,omitempty"`
+	RateLimit      *traefikRateLimit      `yaml:"rateLimit,omitempty"`
+	RedirectScheme *traefikRedirectScheme `yaml:"redirectScheme,omitempty"`
+}
+
+type traefikIPAllowList struct {
+	SourceRange []string `yaml:"sourceRange"`
+}
+
+type traefikRateLimit struct {
+	Average int    `yaml:"average"`
+	Period  string `yaml:"period"`
+	Burst   int    `yaml:"burst"`
+}
+
+type traefikRedirectScheme struct {
+	Scheme    string `yaml:"scheme"`
+	Permanent bool   `yaml:"permanent"`
+}
+
+type traefikTLS struct {
+	Certificates []traefikCertificate `yaml:"certificates"`
+}
+
+type traefikCertificate struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+// Traefik 生成 Traefik v3 文件提供者动态配置
+// 白名单转换为 ipAllowList，限流转换为 rateLimit；Traefik 没有黑名单与跳转中间件，这类规则会被跳过并给出告警
+func Traefik(sites []Site) (*Result, error) {
+	var warn warnings
+	cfg := traefikConfig{
+		HTTP: traefikHTTP{
+			Routers:     make(map[string]*traefikRouter),
+			Services:    make(map[string]*traefikService),
+			Middlewares: make(map[string]*traefikMiddleware),
+		},
+	}
+
+	for _, site := range sites {
+		name := "webox-" + strings.ReplaceAll(strings.ToLower(site.Name), "_", "-")
+		hostRule := traefikHostRule(site.Domain)
+
+		cfg.HTTP.Services[name] = &traefikService{
+			LoadBalancer: traefikLoadBalancer{
+				Servers:        []traefikServer{{URL: "http://" + site.Upstream}},
+				PassHostHeader: true,
+			},
+		}
+
+		entryPoint := TraefikEntryPointHTTP
+		var routerTLS *traefikRouterTLS
+		if site.TLS != nil {
+			entryPoint = TraefikEntryPointHTTPS
+			routerTLS = &traefikRouterTLS{}
+			if cfg.TLS == nil {
+				cfg.TLS = &traefikTLS{}
+			}
+			cfg.TLS.Certificates = append(cfg.TLS.Certificates, traefikCertificate{
+				CertFile: site.TLS.CertFile,
+				KeyFile:  site.TLS.KeyFile,
+			})
+
+			redirect := name + "-https"
+			cfg.HTTP.Middlewares[redirect] = &traefikMiddleware{
+				RedirectScheme: &traefikRedirectScheme{Scheme: "https", Permanent: true},
+			}
+			cfg.HTTP.Routers[name+"-http"] = &traefikRouter{
+				Rule:        hostRule,
+				EntryPoints: []string{TraefikEntryPointHTTP},
+				Service:     name,
+				Middlewares: []string{redirect},
+			}
+		}
+
+		// 规则较长的路由优先级更高，路径前缀路由自然优先于 "/"
+		for _, path := range locationPaths(site.Rules) {
+			routerName := name
+			rule := hostRule
+			if path != "/" {
+				routerName = name + "-" + traefikPathSuffix(path)
+				for i := 2; cfg.HTTP.Routers[routerName] != nil; i++ {
+					routerName = fmt.Sprintf("%s-%s-%d", name, traefikPathSuffix(path), i)
+				}
+				rule = hostRule + " && PathPrefix(`" + path + "`)"
+			}
+
+			router := &traefikRouter{
+				Rule:        rule,
+				EntryPoints: []string{entryPoint},
+				Service:     name,
+				TLS:         routerTLS,
+			}
+			router.Middlewares = traefikMiddlewares(&cfg, &warn, name, routerName, rulesFor(site.Rules, path))
+			cfg.HTTP.Routers[routerName] = router
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by Webox. Manual changes will be overwritten.\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&cfg); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return &Result{Content: buf.Bytes(), Warnings: warn.list}, nil
+}
+
+// traefikMiddlewares 为路由生成访问控制中间件，同一路由的白名单取并集
+func traefikMiddlewares(cfg *traefikConfig, warn *warnings, siteName, routerName string, rules []Rule) []string {
+	var names []string
+	var allow []string
+
+	for _, rule := range rules {
+		if rule.Action == ActionAllow {
+			warn.add("rule %s: ALLOW rules only count hits and are not exported", rule.Name)
+			continue
+		}
+		if rule.Action == ActionRedirect {
+			warn.add("rule %s: Traefik has no redirect on deny, requests are rejected instead", rule.Name)
+		}
+
+		switch rule.Type {
+		case RuleIPBlacklist:
+			warn.add("rule %s: Traefik does not support IP blacklists, rule skipped", rule.Name)
+		case RuleIPWhitelist:
+			allow = append(allow, rule.IPs...)
+		case RuleRateLimit:
+			if len(rule.IPs) > 0 {
+				warn.add("rule %s: Traefik cannot limit rate for selected IPs only, rule skipped", rule.Name)
+				continue
+			}
+			name := siteName + "-" + strings.ReplaceAll(strings.ToLower(rule.Name), "_", "-")
+			cfg.HTTP.Middlewares[name] = &traefikMiddleware{
+				RateLimit: &traefikRateLimit{
+					Average: rule.LimitCount,
+					Period:  fmt.Sprintf("%ds", rule.Window),
+					Burst:   rule.LimitCount,
+				},
+			}
+			names = append(names, name)
+		}
+	}
+
+	if len(allow) > 0 {
+		name := routerName + "-allow"
+		cfg.HTTP.Middlewares[name] = &traefikMiddleware{
+			IPAllowList: &traefikIPAllowList{SourceRange: allow},
+		}
+		names = append([]string{name}, names...)
+	}
+	return names
+}
+
+// traefikHostRule 通配符域名转换为 HostRegexp，只匹配一级子域名
+func traefikHostRule(domain string) string {
+	if strings.HasPrefix(domain, "*.") {
+		return "HostRegexp(`^[^.]+" + regexp.QuoteMeta(domain[1:]) + "$`)"
+	}
+	return "Host(`" + domain + "`)"
+}
+
+var nonNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// traefikPathSuffix 由路径前缀生成路由名称后缀
+func traefikPathSuffix(path string) string {
+	suffix := strings.Trim(nonNameChars.ReplaceAllString(strings.ToLower(path), "-"), "-")
+	if suffix == "" {
+		suffix = "root"
+	}
+	return "path-" + suffix
+}
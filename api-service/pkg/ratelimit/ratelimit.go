@@ -0,0 +1,91 @@
+// Package ratelimit 提供滑动窗口限流，支持进程内与基于 Redis 的共享计数
+//
+// 采用滑动窗口计数算法：按窗口长度划分固定窗口，当前请求数估算为
+// 上一窗口计数 × 上一窗口在滑动窗口内的剩余比例 + 当前窗口计数。
+// 被拒绝的请求同样计入窗口，持续超限的客户端不会因窗口滑动而周期性放行。
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter 限流器
+type Limiter interface {
+	// Allow 记录一次请求，返回 key 在 window 内的请求数是否未超过 limit
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
+}
+
+// estimate 按滑动窗口估算请求数
+func estimate(prev, cur int64, elapsed, window time.Duration) float64 {
+	weight := 1 - float64(elapsed)/float64(window)
+	if weight < 0 {
+		weight = 0
+	}
+	return float64(prev)*weight + float64(cur)
+}
+
+type localWindow struct {
+	window time.Duration
+	index  int64
+	prev   int64
+	cur    int64
+}
+
+// Local 进程内限流器，适用于单个网关实例
+type Local struct {
+	mu        sync.Mutex
+	windows   map[string]*localWindow
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewLocal() *Local {
+	return &Local{
+		windows: make(map[string]*localWindow),
+		now:     time.Now,
+	}
+}
+
+func (l *Local) Allow(_ context.Context, key string, limit int, window time.Duration) (bool, error) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	index := now.UnixNano() / int64(window)
+	w, ok := l.windows[key]
+	if !ok || w.window != window {
+		w = &localWindow{window: window, index: index}
+		l.windows[key] = w
+	}
+
+	switch {
+	case index == w.index+1:
+		w.prev, w.cur = w.cur, 0
+	case index > w.index+1:
+		w.prev, w.cur = 0, 0
+	}
+	w.index = index
+	w.cur++
+
+	elapsed := time.Duration(now.UnixNano() - index*int64(window))
+	return estimate(w.prev, w.cur, elapsed, window) <= float64(limit), nil
+}
+
+// sweep 定期清理两个窗口内无请求的计数
+func (l *Local) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for key, w := range l.windows {
+		if now.UnixNano()/int64(w.window) > w.index+1 {
+			delete(l.windows, key)
+		}
+	}
+}
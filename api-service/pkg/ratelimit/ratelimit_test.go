@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLocalAllow(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	now := start
+	l := NewLocal()
+	l.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	window := 10 * time.Second
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   bool
+	}{
+		{"first request", 0, true},
+		{"second request", time.Second, true},
+		{"third request", 2 * time.Second, true},
+		{"over limit", 3 * time.Second, false},
+		// 下一窗口开始，上一窗口的 4 次请求按剩余比例计入
+		{"next window still limited", 11 * time.Second, false},
+		{"previous window mostly expired", 19 * time.Second, true},
+		{"idle for two windows", 45 * time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = start.Add(tt.offset)
+			got, err := l.Allow(ctx, "client", 3, window)
+			if err != nil {
+				t.Fatalf("Allow() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Allow() = %v; expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalKeysAreIndependent(t *testing.T) {
+	l := NewLocal()
+	ctx := context.Background()
+
+	if ok, _ := l.Allow(ctx, "a", 1, time.Minute); !ok {
+		t.Fatal("Allow(a) = false; expected true")
+	}
+	if ok, _ := l.Allow(ctx, "a", 1, time.Minute); ok {
+		t.Error("second Allow(a) = true; expected false")
+	}
+	if ok, _ := l.Allow(ctx, "b", 1, time.Minute); !ok {
+		t.Error("Allow(b) = false; expected true")
+	}
+}
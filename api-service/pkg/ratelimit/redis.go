@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript 原子地累加当前窗口计数并读取上一窗口计数
+var slidingWindowScript = redis.NewScript(`
+local cur = redis.call('INCR', KEYS[1])
+if cur == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+return {cur, prev}
+`)
+
+// Redis 基于 Redis 的限流器，多个网关实例共享计数
+type Redis struct {
+	rdb    *redis.Client
+	prefix string
+	now    func() time.Time
+}
+
+func NewRedis(rdb *redis.Client, prefix string) *Redis {
+	return &Redis{
+		rdb:    rdb,
+		prefix: prefix,
+		now:    time.Now,
+	}
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	now := r.now()
+	index := now.UnixNano() / int64(window)
+	base := r.prefix + key + ":" + strconv.FormatInt(int64(window/time.Millisecond), 10) + ":"
+
+	keys := []string{
+		base + strconv.FormatInt(index, 10),
+		base + strconv.FormatInt(index-1, 10),
+	}
+	// 计数需保留到下一个窗口结束，供下一窗口作为上一窗口计数读取
+	ttl := strconv.FormatInt(int64(2*window/time.Millisecond), 10)
+
+	values, err := slidingWindowScript.Run(ctx, r.rdb, keys, ttl).Int64Slice()
+	if err != nil {
+		return true, err
+	}
+
+	elapsed := time.Duration(now.UnixNano() - index*int64(window))
+	return estimate(values[1], values[0], elapsed, window) <= float64(limit), nil
+}
@@ -0,0 +1,190 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron 五段式 cron 表达式: 分 时 日 月 周，按 Location 时区计算触发时间
+//
+// 每段支持 *、数字、a-b 范围、/n 步长与逗号分隔的列表，月与周可使用英文缩写(JAN、MON)，周的 0 与 7 均为周日；
+// 日与周同时受限时满足其一即触发。也可使用 @yearly、@monthly、@weekly、@daily、@hourly。
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+	Location                      *time.Location
+}
+
+// cronSearchYears 查找下次触发时间的范围，超出时视为不会再触发(如 2 月 30 日)
+const cronSearchYears = 5
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// ParseCron 解析 cron 表达式，timezone 为 IANA 时区，空字符串为 UTC
+func ParseCron(expr, timezone string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q, expected 5 fields", expr)
+	}
+
+	var masks [5]uint64
+	for i, part := range parts {
+		mask, err := cronFields[i].parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %v", expr, err)
+		}
+		masks[i] = mask
+	}
+	// 周日可写作 0 或 7
+	if masks[4]&(1<<7) != 0 {
+		masks[4] = masks[4]&^(1<<7) | 1
+	}
+
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", timezone)
+	}
+
+	return &Cron{
+		minute:   masks[0],
+		hour:     masks[1],
+		dom:      masks[2],
+		month:    masks[3],
+		dow:      masks[4],
+		domAny:   parts[2] == "*" || parts[2] == "?",
+		dowAny:   parts[4] == "*" || parts[4] == "?",
+		Location: loc,
+	}, nil
+}
+
+func (f cronField) parse(s string) (uint64, error) {
+	var mask uint64
+	for _, item := range strings.Split(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepPart, f.name)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(b); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q in %s", rangePart, f.name)
+			}
+		default:
+			v, err := f.value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// 5/15 表示从 5 开始每 15 个单位
+			if !hasStep {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q, expected %d-%d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next 返回 after 之后的下一次触发时间，不会再触发时返回零值
+func (c *Cron) Next(after time.Time) time.Time {
+	t := after.In(c.Location).Truncate(time.Second)
+	t = t.Add(time.Duration(60-t.Second()) * time.Second)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.Location)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.Location)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			// 按绝对时间前进，夏令时切换时不会停留在同一小时
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
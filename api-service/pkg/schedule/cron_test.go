@@ -0,0 +1,58 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	tests := []struct {
+		expr, tz string
+		after    time.Time
+		want     time.Time
+	}{
+		{"*/15 * * * *", "", time.Date(2024, 6, 1, 10, 7, 30, 0, time.UTC), time.Date(2024, 6, 1, 10, 15, 0, 0, time.UTC)},
+		{"0 * * * *", "", time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", "Asia/Shanghai", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 2, 2, 30, 0, 0, shanghai)},
+		{"0 9 * * mon-fri", "", time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)}, // 周六之后是周一
+		{"0 0 1,15 * *", "", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", "", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 7, 0, 0, 0, 0, time.UTC)}, // 日与周满足其一
+		{"0 0 29 2 *", "", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"5/20 8 * JAN *", "", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 8, 5, 0, 0, time.UTC)},
+		{"0 0 * * 7", "", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)},
+		{"@daily", "", time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// 夏令时开始当天 02:30 不存在，顺延到次日
+		{"30 2 * * *", "America/New_York", time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), time.Date(2024, 3, 11, 2, 30, 0, 0, newYork)},
+		{"0 3 * * *", "America/New_York", time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), time.Date(2024, 3, 10, 3, 0, 0, 0, newYork)},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr, tt.tz)
+		if err != nil {
+			t.Errorf("ParseCron(%q) = %v", tt.expr, err)
+			continue
+		}
+		if got := c.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("%q Next(%s) = %s, want %s", tt.expr, tt.after, got, tt.want)
+		}
+	}
+
+	c, _ := ParseCron("0 0 30 2 *", "")
+	if got := c.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Next of Feb 30 = %s, want zero", got)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "5-1 * * * *", "*/0 * * * *", "* * * foo *", "@every 5m"} {
+		if _, err := ParseCron(expr, ""); err == nil {
+			t.Errorf("ParseCron(%q) succeeded", expr)
+		}
+	}
+	if _, err := ParseCron("* * * * *", "Mars/Olympus"); err == nil {
+		t.Error("ParseCron with invalid timezone succeeded")
+	}
+}
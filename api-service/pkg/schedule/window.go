@@ -0,0 +1,102 @@
+// Package schedule 提供按星期重复的时间窗口与 cron 表达式，用于维护窗口、定时任务等周期性时段
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Weekly 每周重复的时间窗口，在 Weekdays 指定日期的 Start 时刻开始，持续 Duration
+// Weekdays 为空表示每天，窗口可跨越午夜
+type Weekly struct {
+	Weekdays []time.Weekday
+	Hour     int
+	Minute   int
+	Duration time.Duration
+	Location *time.Location
+}
+
+// MaxDuration 单个窗口的最长持续时间
+const MaxDuration = 7 * 24 * time.Hour
+
+// NewWeekly 由逗号分隔的星期(0-6，0 为周日)、HH:MM 开始时刻、持续时长与 IANA 时区创建窗口
+func NewWeekly(weekdays, start string, duration time.Duration, timezone string) (*Weekly, error) {
+	w := &Weekly{Duration: duration}
+
+	days, err := ParseWeekdays(weekdays)
+	if err != nil {
+		return nil, err
+	}
+	w.Weekdays = days
+
+	t, err := time.Parse("15:04", start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time %q, expected HH:MM", start)
+	}
+	w.Hour, w.Minute = t.Hour(), t.Minute()
+
+	if duration <= 0 || duration > MaxDuration {
+		return nil, fmt.Errorf("duration must be between 1m and %s", MaxDuration)
+	}
+
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if w.Location, err = time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", timezone)
+	}
+	return w, nil
+}
+
+// ParseWeekdays 解析逗号分隔的星期，去重并排序，空字符串表示每天
+func ParseWeekdays(s string) ([]time.Weekday, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	seen := make(map[time.Weekday]bool)
+	var days []time.Weekday
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 || n > 6 {
+			return nil, fmt.Errorf("invalid weekday %q, expected 0-6", part)
+		}
+		if d := time.Weekday(n); !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+	return days, nil
+}
+
+// Active 判断 t 是否处于某次窗口内
+func (w *Weekly) Active(t time.Time) bool {
+	local := t.In(w.Location)
+	// 从当天开始向前检查，覆盖跨越午夜或持续多天的窗口
+	for back := 0; back <= int(w.Duration/(24*time.Hour))+1; back++ {
+		day := local.AddDate(0, 0, -back)
+		if !w.runsOn(day.Weekday()) {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), w.Hour, w.Minute, 0, 0, w.Location)
+		if !t.Before(start) && t.Before(start.Add(w.Duration)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Weekly) runsOn(d time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, wd := range w.Weekdays {
+		if wd == d {
+			return true
+		}
+	}
+	return false
+}
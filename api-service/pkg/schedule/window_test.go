@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeeklyActive(t *testing.T) {
+	// 每周六 23:00 (Asia/Shanghai) 开始，持续 3 小时
+	w, err := NewWeekly("6", "23:00", 3*time.Hour, "Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := w.Location
+
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2024, 6, 1, 22, 59, 0, 0, loc), false}, // 周六开始前
+		{time.Date(2024, 6, 1, 23, 0, 0, 0, loc), true},
+		{time.Date(2024, 6, 2, 1, 30, 0, 0, loc), true}, // 跨越午夜到周日
+		{time.Date(2024, 6, 2, 2, 0, 0, 0, loc), false},
+		{time.Date(2024, 6, 2, 23, 30, 0, 0, loc), false}, // 周日不开始
+		{time.Date(2024, 6, 1, 15, 30, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := w.Active(tt.at); got != tt.want {
+			t.Errorf("Active(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestWeeklyEveryDayMultiDay(t *testing.T) {
+	w, err := NewWeekly("", "12:00", 30*time.Minute, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.Active(time.Date(2024, 6, 5, 12, 10, 0, 0, time.UTC)) {
+		t.Error("daily window not active at 12:10")
+	}
+
+	// 周五 18:00 开始持续到周一 08:00
+	w, err = NewWeekly("5", "18:00", 62*time.Hour, "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.Active(time.Date(2024, 6, 9, 12, 0, 0, 0, time.UTC)) { // 周日
+		t.Error("weekend window not active on Sunday")
+	}
+	if w.Active(time.Date(2024, 6, 10, 8, 0, 0, 0, time.UTC)) { // 周一 08:00 结束
+		t.Error("weekend window still active on Monday 08:00")
+	}
+}
+
+func TestNewWeeklyErrors(t *testing.T) {
+	tests := []struct {
+		weekdays, start, tz string
+		duration            time.Duration
+	}{
+		{"7", "01:00", "UTC", time.Hour},
+		{"1,x", "01:00", "UTC", time.Hour},
+		{"1", "25:00", "UTC", time.Hour},
+		{"1", "01:00", "Mars/Olympus", time.Hour},
+		{"1", "01:00", "UTC", 0},
+		{"1", "01:00", "UTC", 8 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if _, err := NewWeekly(tt.weekdays, tt.start, tt.duration, tt.tz); err == nil {
+			t.Errorf("NewWeekly(%q, %q, %s, %q) succeeded, want error", tt.weekdays, tt.start, tt.duration, tt.tz)
+		}
+	}
+}
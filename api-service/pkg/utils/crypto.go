@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// Encryptor 使用 AES-256-GCM 加解密敏感数据
+// 密文格式为 base64(nonce || ciphertext)
+type Encryptor struct {
+	aead cipher.AEAD
+}
+
+// NewEncryptor 根据密钥字符串创建加密器，密钥经 SHA256 派生为 32 字节
+func NewEncryptor(secret string) (*Encryptor, error) {
+	if secret == "" {
+		return nil, errors.New("encryption key is empty")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Encryptor{aead: aead}, nil
+}
+
+// DeriveKey 由主密钥派生用途为 label 的子密钥(HMAC-SHA256，十六进制)，持有子密钥无法推算主密钥或其他子密钥
+func DeriveKey(master, label string) string {
+	mac := hmac.New(sha256.New, []byte(master))
+	mac.Write([]byte(label))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Encrypt 加密字符串
+func (e *Encryptor) Encrypt(plaintext string) (string, error) {
+	data, err := e.Seal([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Decrypt 解密由 Encrypt 生成的字符串
+func (e *Encryptor) Decrypt(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := e.Open(data)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Seal 加密字节数据，返回 nonce || ciphertext
+func (e *Encryptor) Seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open 解密 Seal 生成的字节数据
+func (e *Encryptor) Open(data []byte) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, errors.New("ciphertext too short")
+	}
+	return e.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+}
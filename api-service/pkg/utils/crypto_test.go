@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestEncryptorRoundTrip(t *testing.T) {
+	enc, err := NewEncryptor("test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, plaintext := range []string{"", "secret", "多字节 ✓", strings.Repeat("x", 64*1024)} {
+		ciphertext, err := enc.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plaintext != "" && strings.Contains(ciphertext, plaintext) {
+			t.Errorf("ciphertext contains plaintext")
+		}
+		got, err := enc.Decrypt(ciphertext)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if got != plaintext {
+			t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+		}
+	}
+
+	// 每次加密使用随机 nonce，相同明文的密文不同
+	a, _ := enc.Encrypt("same")
+	b, _ := enc.Encrypt("same")
+	if a == b {
+		t.Error("encrypting the same plaintext twice produced identical ciphertexts")
+	}
+}
+
+func TestEncryptorWrongKey(t *testing.T) {
+	enc, _ := NewEncryptor("key-a")
+	other, _ := NewEncryptor("key-b")
+
+	ciphertext, err := enc.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := other.Decrypt(ciphertext); err == nil {
+		t.Errorf("Decrypt with wrong key succeeded: %q", got)
+	}
+}
+
+func TestEncryptorTampered(t *testing.T) {
+	enc, _ := NewEncryptor("test-key")
+	ciphertext, err := enc.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(ciphertext)
+
+	for i := range data {
+		tampered := append([]byte(nil), data...)
+		tampered[i] ^= 0x01
+		if _, err := enc.Decrypt(base64.StdEncoding.EncodeToString(tampered)); err == nil {
+			t.Errorf("Decrypt accepted ciphertext with byte %d flipped", i)
+		}
+	}
+
+	for name, input := range map[string]string{
+		"truncated":  base64.StdEncoding.EncodeToString(data[:len(data)-1]),
+		"too short":  base64.StdEncoding.EncodeToString(data[:4]),
+		"empty":      "",
+		"not base64": "!!!",
+	} {
+		if _, err := enc.Decrypt(input); err == nil {
+			t.Errorf("Decrypt accepted %s ciphertext", name)
+		}
+	}
+}
+
+func TestNewEncryptorEmptyKey(t *testing.T) {
+	if _, err := NewEncryptor(""); err == nil {
+		t.Error("NewEncryptor accepted an empty key")
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	a := DeriveKey("master", "label-a")
+	if len(a) != 64 {
+		t.Errorf("derived key length = %d, want 64", len(a))
+	}
+	if a != DeriveKey("master", "label-a") {
+		t.Error("DeriveKey is not deterministic")
+	}
+	if a == DeriveKey("master", "label-b") || a == DeriveKey("other", "label-a") {
+		t.Error("different labels or master keys derived the same key")
+	}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// reservedNets 除 net.IP 方法可识别的回环、私有、链路本地地址外，其他不属于公网的地址段
+// 包括运营商级 NAT(阿里云元数据地址 100.100.100.200 位于其中)、保留与测试地址段
+var reservedNets = mustParseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+	"64:ff9b:1::/48",
+	"2001:db8::/32",
+)
+
+// IsPublicIP 判断是否为公网地址，回环、私有、链路本地(含云主机元数据地址 169.254.169.254)、组播与保留地址返回 false
+func IsPublicIP(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	if ip == nil || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, ipNet := range reservedNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// ParseIPNets 解析逗号分隔的 IP 或 CIDR 列表，单个 IP 视为主机地址
+func ParseIPNets(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if strings.Contains(item, "/") {
+			_, ipNet, err := net.ParseCIDR(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR: %s", item)
+			}
+			nets = append(nets, ipNet)
+			continue
+		}
+
+		ip := net.ParseIP(item)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address: %s", item)
+		}
+		bits := 128
+		if v4 := ip.To4(); v4 != nil {
+			ip, bits = v4, 32
+		}
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nets, nil
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"1.1.1.1", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"127.1.2.3", false},
+		{"::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"fd00:ec2::254", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.1.2.3", false},
+		{"64:ff9b::a00:1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"198.18.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestParseIPNets(t *testing.T) {
+	tests := []struct {
+		list     string
+		contains []string
+		excludes []string
+		wantLen  int
+		wantErr  bool
+	}{
+		{list: "", wantLen: 0},
+		{list: " , ", wantLen: 0},
+		{list: "10.0.0.1", contains: []string{"10.0.0.1", "::ffff:10.0.0.1"}, excludes: []string{"10.0.0.2"}, wantLen: 1},
+		{list: "10.0.0.0/8, 192.168.1.0/24", contains: []string{"10.255.0.1", "192.168.1.9"},
+			excludes: []string{"192.168.2.1", "11.0.0.1"}, wantLen: 2},
+		{list: "2001:db8::1,2001:db8:1::/48", contains: []string{"2001:db8::1", "2001:db8:1::5"},
+			excludes: []string{"2001:db8::2"}, wantLen: 2},
+		{list: "10.0.0.1,not-an-ip", wantErr: true},
+		{list: "10.0.0.0/33", wantErr: true},
+		{list: "10.0.0.0/", wantErr: true},
+	}
+	for _, tt := range tests {
+		nets, err := ParseIPNets(tt.list)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseIPNets(%q) expected error", tt.list)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseIPNets(%q): %v", tt.list, err)
+			continue
+		}
+		if len(nets) != tt.wantLen {
+			t.Errorf("ParseIPNets(%q) returned %d networks, want %d", tt.list, len(nets), tt.wantLen)
+		}
+		contains := func(ip string) bool {
+			for _, n := range nets {
+				if n.Contains(net.ParseIP(ip)) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, ip := range tt.contains {
+			if !contains(ip) {
+				t.Errorf("ParseIPNets(%q) does not contain %s", tt.list, ip)
+			}
+		}
+		for _, ip := range tt.excludes {
+			if contains(ip) {
+				t.Errorf("ParseIPNets(%q) contains %s", tt.list, ip)
+			}
+		}
+	}
+}
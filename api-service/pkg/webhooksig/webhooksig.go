@@ -0,0 +1,57 @@
+// Package webhooksig 计算与校验出站 Webhook 的签名
+//
+// 每次投递携带 X-Webox-Timestamp(Unix 秒)与 X-Webox-Signature 头，签名为
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body))。
+// 接收方应使用原始请求体校验签名，并拒绝时间戳偏差过大的请求以防重放。
+package webhooksig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webox-Signature"
+	TimestampHeader = "X-Webox-Timestamp"
+
+	prefix = "sha256="
+)
+
+var (
+	ErrInvalidSignature = errors.New("webhook signature mismatch")
+	ErrInvalidTimestamp = errors.New("webhook timestamp is invalid or outside the tolerance")
+)
+
+// Sign 返回 X-Webox-Signature 头的值
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return prefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验签名与时间戳，tolerance 为允许的时间偏差，为 0 时不校验时间
+func Verify(secret, signature, timestamp string, body []byte, tolerance time.Duration, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	if tolerance > 0 {
+		if d := now.Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+			return ErrInvalidTimestamp
+		}
+	}
+	if !strings.HasPrefix(signature, prefix) {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
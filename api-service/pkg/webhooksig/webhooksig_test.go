@@ -0,0 +1,48 @@
+package webhooksig
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"app.deployed"}`)
+	sig := Sign("s3cret", now.Unix(), body)
+
+	// 参考值: printf '1700000000.{"event":"app.deployed"}' | openssl dgst -sha256 -hmac s3cret
+	if want := "sha256=3b869042b58c7ace9b5dbad69af5c4fc072f4f3e75ddff5dcb8d87e696ffa967"; sig != want {
+		t.Fatalf("Sign() = %q, want %q", sig, want)
+	}
+
+	ts := strconv.FormatInt(now.Unix(), 10)
+	if err := Verify("s3cret", sig, ts, body, 5*time.Minute, now.Add(time.Minute)); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		want      error
+	}{
+		{"wrong secret", "other", sig, ts, body, ErrInvalidSignature},
+		{"tampered body", "s3cret", sig, ts, []byte(`{"event":"app.failed"}`), ErrInvalidSignature},
+		{"replayed timestamp", "s3cret", sig, strconv.FormatInt(now.Unix()+1, 10), body, ErrInvalidSignature},
+		{"missing prefix", "s3cret", sig[len(prefix):], ts, body, ErrInvalidSignature},
+		{"bad timestamp", "s3cret", sig, "yesterday", body, ErrInvalidTimestamp},
+	}
+	for _, c := range cases {
+		if err := Verify(c.secret, c.signature, c.timestamp, c.body, 5*time.Minute, now); !errors.Is(err, c.want) {
+			t.Errorf("%s: Verify() = %v, want %v", c.name, err, c.want)
+		}
+	}
+
+	if err := Verify("s3cret", sig, ts, body, 5*time.Minute, now.Add(10*time.Minute)); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Errorf("Verify() outside tolerance = %v", err)
+	}
+}
@@ -0,0 +1,498 @@
+// Package workflow 解析与校验工作流定义，并提供执行期间的条件求值、参数渲染与输出提取
+//
+// 定义为 JSON 对象，steps 中的步骤按 depends_on 组成有向无环图:
+//
+//	{
+//	  "inputs": {"app": {"type": "string", "required": true}},
+//	  "steps": [
+//	    {"id": "check", "type": "system_command", "servers": [1],
+//	     "params": {"command": "docker inspect -f '{{.State.Status}}' ${{ inputs.app }}"},
+//	     "outputs": {"state": {"from": "data.output"}}},
+//	    {"id": "restart", "type": "system_command", "servers": [1], "depends_on": ["check"],
+//	     "if": "steps.check.outputs.state != 'running'", "retries": 2, "timeout": 120,
+//	     "params": {"command": "docker restart ${{ inputs.app }}"}}
+//	  ]
+//	}
+//
+// 依赖全部结束后步骤才会开始：没有 if 时要求依赖全部成功，否则跳过；有 if 时按条件决定执行或跳过。
+// 字符串参数与 servers 中的 ${{ 表达式 }} 在步骤开始时渲染，可引用 inputs、steps.<id>.status、
+// steps.<id>.outputs.<name> 以及执行方提供的其他上下文，只能引用依赖链上的步骤。
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 步骤状态
+const (
+	StatusSuccess = "SUCCESS"
+	StatusFailure = "FAILURE"
+	StatusSkipped = "SKIPPED"
+)
+
+// 输入类型
+const (
+	InputString = "string"
+	InputNumber = "number"
+	InputBool   = "bool"
+)
+
+// 步骤限制
+const (
+	MaxSteps      = 100
+	MaxRetries    = 10
+	MaxRetryDelay = 3600  // 秒
+	MaxTimeout    = 86400 // 秒
+)
+
+var stepIDPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]{0,63}$`)
+
+// Definition 工作流定义
+type Definition struct {
+	Inputs map[string]*Input `json:"inputs,omitempty"`
+	Steps  []*Step           `json:"steps"`
+
+	order []*Step
+	index map[string]*Step
+}
+
+// Input 输入参数声明
+type Input struct {
+	Type        string      `json:"type"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// Step 步骤，在 servers 指定的每台服务器上下发一个 type 类型的 Agent 任务
+// servers 的元素为服务器ID或渲染结果为服务器ID的字符串
+type Step struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name,omitempty"`
+	Type       string                 `json:"type"`
+	Servers    []interface{}          `json:"servers"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	DependsOn  []string               `json:"depends_on,omitempty"`
+	If         string                 `json:"if,omitempty"`
+	Retries    int                    `json:"retries,omitempty"`
+	RetryDelay int                    `json:"retry_delay,omitempty"` // 秒
+	Timeout    int                    `json:"timeout,omitempty"`     // 秒，0 使用执行方的默认值
+	Outputs    map[string]*Output     `json:"outputs,omitempty"`
+
+	cond *Expr
+}
+
+// Output 从任务结果中提取输出，From 为 status、message 或 data.<键>；
+// Pattern 非空时取正则的第一个分组，没有分组时取整个匹配
+type Output struct {
+	From    string `json:"from"`
+	Pattern string `json:"pattern,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Result Agent 任务结果
+type Result struct {
+	Status  string
+	Message string
+	Data    map[string]interface{}
+}
+
+// Parse 解析并校验定义
+func Parse(data []byte) (*Definition, error) {
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("invalid workflow definition: %v", err)
+	}
+	if err := def.validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// Order 按拓扑顺序返回步骤，同层步骤保持定义中的顺序
+func (d *Definition) Order() []*Step { return d.order }
+
+// Step 按ID查找步骤
+func (d *Definition) Step(id string) *Step { return d.index[id] }
+
+// CheckInputs 校验参数已声明且类型正确，不检查必填参数
+func (d *Definition) CheckInputs(given map[string]interface{}) error {
+	for name, value := range given {
+		input, ok := d.Inputs[name]
+		if !ok {
+			return fmt.Errorf("undeclared input %q", name)
+		}
+		if value == nil {
+			continue
+		}
+		if _, err := convertInput(input.Type, value); err != nil {
+			return fmt.Errorf("input %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// ResolveInputs 校验执行参数并填充默认值，未声明的参数被拒绝
+func (d *Definition) ResolveInputs(given map[string]interface{}) (map[string]interface{}, error) {
+	if err := d.CheckInputs(given); err != nil {
+		return nil, err
+	}
+	resolved := make(map[string]interface{}, len(d.Inputs))
+	for name, input := range d.Inputs {
+		value, ok := given[name]
+		if !ok || value == nil {
+			if input.Required && input.Default == nil {
+				return nil, fmt.Errorf("input %q is required", name)
+			}
+			value = input.Default
+		}
+		if value == nil {
+			continue
+		}
+		converted, err := convertInput(input.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("input %q: %v", name, err)
+		}
+		resolved[name] = converted
+	}
+	return resolved, nil
+}
+
+// Ready 判断步骤是否应当执行，deps 为依赖步骤的状态
+func (s *Step) Ready(deps map[string]string, ctx map[string]interface{}) bool {
+	if s.cond != nil {
+		return s.cond.Match(ctx)
+	}
+	for _, id := range s.DependsOn {
+		if deps[id] != StatusSuccess {
+			return false
+		}
+	}
+	return true
+}
+
+// ResolveServers 渲染并返回目标服务器ID，去除重复项
+func (s *Step) ResolveServers(ctx map[string]interface{}) ([]uint, error) {
+	seen := make(map[uint]bool, len(s.Servers))
+	var ids []uint
+	for _, raw := range s.Servers {
+		var id uint64
+		switch v := raw.(type) {
+		case float64:
+			id = uint64(v)
+		case string:
+			rendered, err := Render(v, ctx)
+			if err != nil {
+				return nil, err
+			}
+			if id, err = strconv.ParseUint(strings.TrimSpace(Format(rendered)), 10, 32); err != nil {
+				return nil, fmt.Errorf("step %s: server %q is not a server ID", s.ID, Format(rendered))
+			}
+		}
+		if id == 0 {
+			return nil, fmt.Errorf("step %s: invalid server ID", s.ID)
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, nil
+}
+
+// RenderParams 渲染参数中的 ${{ 表达式 }}
+func (s *Step) RenderParams(ctx map[string]interface{}) (map[string]interface{}, error) {
+	rendered, err := renderValue(s.Params, ctx)
+	if err != nil {
+		return nil, err
+	}
+	params, _ := rendered.(map[string]interface{})
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return params, nil
+}
+
+// ExtractOutputs 从任务结果中提取步骤输出
+func (s *Step) ExtractOutputs(result *Result) map[string]string {
+	outputs := make(map[string]string, len(s.Outputs))
+	for name, out := range s.Outputs {
+		var value string
+		switch {
+		case out.From == "status":
+			value = result.Status
+		case out.From == "message":
+			value = result.Message
+		default:
+			value = Format(Lookup(result.Data, strings.TrimPrefix(out.From, "data.")))
+		}
+		if out.re != nil {
+			m := out.re.FindStringSubmatch(value)
+			switch {
+			case m == nil:
+				value = ""
+			case len(m) > 1:
+				value = m[1]
+			default:
+				value = m[0]
+			}
+		}
+		outputs[name] = strings.TrimSpace(value)
+	}
+	return outputs
+}
+
+func (d *Definition) validate() error {
+	if len(d.Steps) == 0 {
+		return errors.New("workflow has no steps")
+	}
+	if len(d.Steps) > MaxSteps {
+		return fmt.Errorf("workflow has more than %d steps", MaxSteps)
+	}
+	for name, input := range d.Inputs {
+		if !stepIDPattern.MatchString(name) {
+			return fmt.Errorf("invalid input name %q", name)
+		}
+		if input == nil {
+			return fmt.Errorf("input %q has no declaration", name)
+		}
+		switch input.Type {
+		case "":
+			input.Type = InputString
+		case InputString, InputNumber, InputBool:
+		default:
+			return fmt.Errorf("input %q has unsupported type %q", name, input.Type)
+		}
+		if input.Default != nil {
+			if _, err := convertInput(input.Type, input.Default); err != nil {
+				return fmt.Errorf("default of input %q: %v", name, err)
+			}
+		}
+	}
+
+	d.index = make(map[string]*Step, len(d.Steps))
+	for _, step := range d.Steps {
+		if step == nil {
+			return errors.New("step must be an object")
+		}
+		if !stepIDPattern.MatchString(step.ID) {
+			return fmt.Errorf("invalid step id %q", step.ID)
+		}
+		if d.index[step.ID] != nil {
+			return fmt.Errorf("duplicate step id %q", step.ID)
+		}
+		d.index[step.ID] = step
+	}
+
+	for _, step := range d.Steps {
+		if err := d.validateStep(step); err != nil {
+			return fmt.Errorf("step %s: %v", step.ID, err)
+		}
+	}
+	if err := d.sort(); err != nil {
+		return err
+	}
+
+	// 表达式只能引用依赖链上的步骤
+	for _, step := range d.Steps {
+		ancestors := d.ancestors(step)
+		for _, path := range step.references() {
+			parts := strings.SplitN(path, ".", 3)
+			if parts[0] != "steps" {
+				continue
+			}
+			if len(parts) < 2 || !ancestors[parts[1]] {
+				return fmt.Errorf("step %s references %s which is not one of its dependencies", step.ID, path)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Definition) validateStep(step *Step) error {
+	if step.Type == "" {
+		return errors.New("type is required")
+	}
+	if len(step.Servers) == 0 {
+		return errors.New("servers is required")
+	}
+	for _, raw := range step.Servers {
+		switch v := raw.(type) {
+		case float64:
+			if v < 1 || v != float64(uint32(v)) {
+				return fmt.Errorf("invalid server ID %v", v)
+			}
+		case string:
+			if _, err := parseTemplate(v); err != nil {
+				return err
+			}
+			if !strings.Contains(v, "${{") {
+				if _, err := strconv.ParseUint(v, 10, 32); err != nil {
+					return fmt.Errorf("invalid server %q", v)
+				}
+			}
+		default:
+			return fmt.Errorf("invalid server %v", raw)
+		}
+	}
+
+	for _, dep := range step.DependsOn {
+		if dep == step.ID {
+			return errors.New("step depends on itself")
+		}
+		if d.index[dep] == nil {
+			return fmt.Errorf("unknown dependency %q", dep)
+		}
+	}
+	if step.If != "" {
+		cond, err := ParseExpr(step.If)
+		if err != nil {
+			return err
+		}
+		step.cond = cond
+	}
+	if err := validateTemplates(step.Params); err != nil {
+		return err
+	}
+
+	if step.Retries < 0 || step.Retries > MaxRetries {
+		return fmt.Errorf("retries must be between 0 and %d", MaxRetries)
+	}
+	if step.RetryDelay < 0 || step.RetryDelay > MaxRetryDelay {
+		return fmt.Errorf("retry_delay must be between 0 and %d seconds", MaxRetryDelay)
+	}
+	if step.Timeout < 0 || step.Timeout > MaxTimeout {
+		return fmt.Errorf("timeout must be between 0 and %d seconds", MaxTimeout)
+	}
+
+	for name, out := range step.Outputs {
+		if !stepIDPattern.MatchString(name) {
+			return fmt.Errorf("invalid output name %q", name)
+		}
+		if out == nil || (out.From != "status" && out.From != "message" &&
+			(!strings.HasPrefix(out.From, "data.") || len(out.From) == len("data."))) {
+			return fmt.Errorf("output %s: from must be status, message or data.<key>", name)
+		}
+		if out.Pattern != "" {
+			re, err := regexp.Compile(out.Pattern)
+			if err != nil {
+				return fmt.Errorf("output %s: %v", name, err)
+			}
+			out.re = re
+		}
+	}
+	return nil
+}
+
+// sort 按拓扑顺序排列步骤，存在环时返回错误
+func (d *Definition) sort() error {
+	indegree := make(map[string]int, len(d.Steps))
+	children := make(map[string][]string, len(d.Steps))
+	for _, step := range d.Steps {
+		indegree[step.ID] += 0
+		for _, dep := range step.DependsOn {
+			indegree[step.ID]++
+			children[dep] = append(children[dep], step.ID)
+		}
+	}
+
+	d.order = make([]*Step, 0, len(d.Steps))
+	done := make(map[string]bool, len(d.Steps))
+	for len(d.order) < len(d.Steps) {
+		progressed := false
+		for _, step := range d.Steps {
+			if done[step.ID] || indegree[step.ID] > 0 {
+				continue
+			}
+			done[step.ID] = true
+			progressed = true
+			d.order = append(d.order, step)
+			for _, child := range children[step.ID] {
+				indegree[child]--
+			}
+		}
+		if !progressed {
+			var cyclic []string
+			for _, step := range d.Steps {
+				if !done[step.ID] {
+					cyclic = append(cyclic, step.ID)
+				}
+			}
+			return fmt.Errorf("workflow has a dependency cycle among steps %s", strings.Join(cyclic, ", "))
+		}
+	}
+	return nil
+}
+
+// ancestors 返回步骤直接或间接依赖的步骤
+func (d *Definition) ancestors(step *Step) map[string]bool {
+	seen := make(map[string]bool)
+	var visit func(s *Step)
+	visit = func(s *Step) {
+		for _, dep := range s.DependsOn {
+			if !seen[dep] {
+				seen[dep] = true
+				visit(d.index[dep])
+			}
+		}
+	}
+	visit(step)
+	return seen
+}
+
+// references 返回步骤条件、参数与服务器中引用的上下文路径
+func (s *Step) references() []string {
+	var paths []string
+	if s.cond != nil {
+		paths = append(paths, s.cond.Paths()...)
+	}
+	collect := func(v string) {
+		t, _ := parseTemplate(v)
+		for _, part := range t {
+			if part.expr != nil {
+				paths = append(paths, part.expr.Paths()...)
+			}
+		}
+	}
+	walkStrings(s.Params, collect)
+	for _, raw := range s.Servers {
+		if v, ok := raw.(string); ok {
+			collect(v)
+		}
+	}
+	return paths
+}
+
+func convertInput(typ string, value interface{}) (interface{}, error) {
+	switch typ {
+	case InputString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return Format(value), nil
+	case InputNumber:
+		if f, ok := number(value); ok {
+			return f, nil
+		}
+		return nil, fmt.Errorf("%v is not a number", value)
+	case InputBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a bool", v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("%v is not a bool", value)
+	}
+	return nil, fmt.Errorf("unsupported input type %q", typ)
+}
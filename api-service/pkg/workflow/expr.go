@@ -0,0 +1,478 @@
+package workflow
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr 解析后的条件表达式
+//
+// 语法与 GitHub Actions 的表达式类似:
+//
+//	steps.check.status == "SUCCESS" && steps.check.outputs.code != "0"
+//	!(inputs.force) || contains(inputs.app, "web")
+//
+// 操作数为字符串('..' 或 ".." )、数字、true、false、null 与以点分隔的上下文路径(如 inputs.app)，
+// 比较符为 ==、!=、<、<=、>、>=，逻辑运算为 &&、||、!，函数为 contains、startsWith、endsWith。
+// 不存在的路径取值为 null；数字与数字字符串比较时按数值比较。
+type Expr struct {
+	src  string
+	root node
+}
+
+// ParseExpr 解析表达式
+func ParseExpr(s string) (*Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", s, err)
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", s, err)
+	}
+	return &Expr{src: s, root: root}, nil
+}
+
+func (e *Expr) String() string { return e.src }
+
+// Eval 在上下文中求值
+func (e *Expr) Eval(ctx map[string]interface{}) interface{} {
+	return e.root.eval(ctx)
+}
+
+// Match 求值并转换为布尔值，null、false、0 与空字符串为假
+func (e *Expr) Match(ctx map[string]interface{}) bool {
+	return truthy(e.Eval(ctx))
+}
+
+// Paths 返回表达式引用的上下文路径
+func (e *Expr) Paths() []string {
+	var paths []string
+	e.root.walk(func(n node) {
+		if p, ok := n.(pathNode); ok {
+			paths = append(paths, strings.Join(p, "."))
+		}
+	})
+	return paths
+}
+
+// Lookup 按以点分隔的路径在上下文中取值
+func Lookup(ctx map[string]interface{}, path string) interface{} {
+	return pathNode(strings.Split(path, ".")).eval(ctx)
+}
+
+type node interface {
+	eval(ctx map[string]interface{}) interface{}
+	walk(fn func(node))
+}
+
+type literalNode struct{ value interface{} }
+
+func (n literalNode) eval(map[string]interface{}) interface{} { return n.value }
+func (n literalNode) walk(fn func(node))                      { fn(n) }
+
+type pathNode []string
+
+func (n pathNode) eval(ctx map[string]interface{}) interface{} {
+	var cur interface{} = ctx
+	for _, key := range n {
+		switch v := cur.(type) {
+		case map[string]interface{}:
+			cur = v[key]
+		case map[string]string:
+			s, ok := v[key]
+			if !ok {
+				return nil
+			}
+			cur = s
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			cur = v[i]
+		default:
+			return nil
+		}
+	}
+	return cur
+}
+
+func (n pathNode) walk(fn func(node)) { fn(n) }
+
+type notNode struct{ x node }
+
+func (n notNode) eval(ctx map[string]interface{}) interface{} { return !truthy(n.x.eval(ctx)) }
+func (n notNode) walk(fn func(node))                          { fn(n); n.x.walk(fn) }
+
+type binaryNode struct {
+	op   string
+	l, r node
+}
+
+func (n binaryNode) eval(ctx map[string]interface{}) interface{} {
+	switch n.op {
+	case "&&":
+		return truthy(n.l.eval(ctx)) && truthy(n.r.eval(ctx))
+	case "||":
+		return truthy(n.l.eval(ctx)) || truthy(n.r.eval(ctx))
+	}
+	return compare(n.op, n.l.eval(ctx), n.r.eval(ctx))
+}
+
+func (n binaryNode) walk(fn func(node)) { fn(n); n.l.walk(fn); n.r.walk(fn) }
+
+type callNode struct {
+	name string
+	args []node
+}
+
+var exprFuncs = map[string]func(s, sub string) bool{
+	"contains":   strings.Contains,
+	"startswith": strings.HasPrefix,
+	"endswith":   strings.HasSuffix,
+}
+
+func (n callNode) eval(ctx map[string]interface{}) interface{} {
+	fn := exprFuncs[n.name]
+	if n.name == "contains" {
+		// contains 的第一个参数为数组时判断元素是否存在
+		if list, ok := n.args[0].eval(ctx).([]interface{}); ok {
+			item := n.args[1].eval(ctx)
+			for _, v := range list {
+				if compare("==", v, item) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return fn(Format(n.args[0].eval(ctx)), Format(n.args[1].eval(ctx)))
+}
+
+func (n callNode) walk(fn func(node)) {
+	fn(n)
+	for _, arg := range n.args {
+		arg.walk(fn)
+	}
+}
+
+// Format 将取值格式化为字符串，null 为空字符串
+func Format(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	return fmt.Sprint(v)
+}
+
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case string:
+		return x != ""
+	case float64:
+		return x != 0
+	}
+	return true
+}
+
+// number 将数字及数字字符串转换为 float64
+func number(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case int:
+		return float64(x), true
+	case uint:
+		return float64(x), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil && !math.IsNaN(f)
+	}
+	return 0, false
+}
+
+func compare(op string, a, b interface{}) bool {
+	if a == nil || b == nil {
+		switch op {
+		case "==":
+			return a == nil && b == nil
+		case "!=":
+			return !(a == nil && b == nil)
+		}
+		return false
+	}
+
+	// 任一侧为数字时按数值比较
+	_, aNum := a.(float64)
+	_, bNum := b.(float64)
+	if aNum || bNum {
+		x, ok1 := number(a)
+		y, ok2 := number(b)
+		if ok1 && ok2 {
+			switch op {
+			case "==":
+				return x == y
+			case "!=":
+				return x != y
+			case "<":
+				return x < y
+			case "<=":
+				return x <= y
+			case ">":
+				return x > y
+			case ">=":
+				return x >= y
+			}
+		}
+	}
+
+	x, y := Format(a), Format(b)
+	switch op {
+	case "==":
+		return x == y
+	case "!=":
+		return x != y
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	}
+	return false
+}
+
+type token struct {
+	kind byte // 'i' 标识符或路径, 's' 字符串, 'n' 数字, 'o' 运算符或标点
+	text string
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(rs) && rs[j] != r; j++ {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+				}
+				b.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, token{'s', b.String()})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i + 1
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{'n', string(rs[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '-' || rs[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{'i', string(rs[i:j])})
+			i = j
+		default:
+			if i+1 < len(rs) {
+				two := string(rs[i : i+2])
+				switch two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tokens = append(tokens, token{'o', two})
+					i += 2
+					continue
+				}
+			}
+			switch r {
+			case '<', '>', '!', '(', ')', ',':
+				tokens = append(tokens, token{'o', string(r)})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character %q", r)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *exprParser) accept(op string) bool {
+	if t, ok := p.peek(); ok && t.kind == 'o' && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: "||", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *exprParser) parseAnd() (node, error) {
+	l, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		r, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: "&&", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *exprParser) parseCompare() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			r, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return binaryNode{op: op, l: l, r: r}, nil
+		}
+	}
+	return l, nil
+}
+
+func (p *exprParser) parseUnary() (node, error) {
+	if p.accept("!") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+
+	switch t.kind {
+	case 's':
+		return literalNode{t.text}, nil
+	case 'n':
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return literalNode{f}, nil
+	case 'i':
+		switch t.text {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		case "null":
+			return literalNode{nil}, nil
+		}
+		if p.accept("(") {
+			return p.parseCall(t.text)
+		}
+		parts := strings.Split(t.text, ".")
+		for _, part := range parts {
+			if part == "" {
+				return nil, fmt.Errorf("invalid path %q", t.text)
+			}
+		}
+		return pathNode(parts), nil
+	}
+
+	if t.text == "(" {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return x, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+func (p *exprParser) parseCall(name string) (node, error) {
+	if _, ok := exprFuncs[strings.ToLower(name)]; !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	var args []node
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(")") {
+			break
+		}
+		if !p.accept(",") {
+			return nil, fmt.Errorf("expected , or ) in call to %s", name)
+		}
+	}
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%s expects 2 arguments", name)
+	}
+	return callNode{name: strings.ToLower(name), args: args}, nil
+}
@@ -0,0 +1,132 @@
+package workflow
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	templateOpen  = "${{"
+	templateClose = "}}"
+)
+
+type templatePart struct {
+	text string
+	expr *Expr
+}
+
+// parseTemplate 将字符串拆分为文本与 ${{ 表达式 }} 片段
+func parseTemplate(s string) ([]templatePart, error) {
+	var parts []templatePart
+	for {
+		start := strings.Index(s, templateOpen)
+		if start < 0 {
+			if s != "" {
+				parts = append(parts, templatePart{text: s})
+			}
+			return parts, nil
+		}
+		end := strings.Index(s[start:], templateClose)
+		if end < 0 {
+			return nil, errors.New("unterminated ${{ in " + s)
+		}
+		if start > 0 {
+			parts = append(parts, templatePart{text: s[:start]})
+		}
+		expr, err := ParseExpr(strings.TrimSpace(s[start+len(templateOpen) : start+end]))
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, templatePart{expr: expr})
+		s = s[start+end+len(templateClose):]
+	}
+}
+
+// Render 渲染字符串中的 ${{ 表达式 }}，整个字符串只有一个表达式时保留取值的类型
+func Render(s string, ctx map[string]interface{}) (interface{}, error) {
+	parts, err := parseTemplate(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 1 && parts[0].expr != nil {
+		return parts[0].expr.Eval(ctx), nil
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		if part.expr != nil {
+			b.WriteString(Format(part.expr.Eval(ctx)))
+		} else {
+			b.WriteString(part.text)
+		}
+	}
+	return b.String(), nil
+}
+
+// TemplatePaths 解析字符串中的 ${{ 表达式 }}，返回引用的上下文路径
+func TemplatePaths(s string) ([]string, error) {
+	parts, err := parseTemplate(s)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, part := range parts {
+		if part.expr != nil {
+			paths = append(paths, part.expr.Paths()...)
+		}
+	}
+	return paths, nil
+}
+
+// renderValue 渲染 JSON 值中的全部字符串
+func renderValue(v interface{}, ctx map[string]interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		return Render(x, ctx)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			rendered, err := renderValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = rendered
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			rendered, err := renderValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func validateTemplates(v interface{}) error {
+	var err error
+	walkStrings(v, func(s string) {
+		if err == nil {
+			_, err = parseTemplate(s)
+		}
+	})
+	return err
+}
+
+func walkStrings(v interface{}, fn func(string)) {
+	switch x := v.(type) {
+	case string:
+		fn(x)
+	case map[string]interface{}:
+		for _, item := range x {
+			walkStrings(item, fn)
+		}
+	case []interface{}:
+		for _, item := range x {
+			walkStrings(item, fn)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package workflow
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpr(t *testing.T) {
+	ctx := map[string]interface{}{
+		"inputs": map[string]interface{}{"app": "web-1", "replicas": 3.0, "force": false},
+		"steps": map[string]interface{}{
+			"check": map[string]interface{}{
+				"status":  StatusSuccess,
+				"outputs": map[string]string{"code": "0", "state": "exited"},
+			},
+		},
+		"event": map[string]interface{}{"labels": []interface{}{"prod", "db"}},
+	}
+
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{`steps.check.status == "SUCCESS"`, true},
+		{`steps.check.status == 'FAILURE' || steps.check.outputs.state != "running"`, true},
+		{`steps.check.outputs.code == 0`, true},
+		{`inputs.replicas >= 3 && inputs.replicas < 10`, true},
+		{`inputs.replicas > "10"`, false},
+		{`!inputs.force`, true},
+		{`!(inputs.force || true)`, false},
+		{`contains(inputs.app, "web") && startsWith(inputs.app, 'web') && endsWith(inputs.app, "-1")`, true},
+		{`contains(event.labels, "db")`, true},
+		{`contains(event.labels, "dev")`, false},
+		{`inputs.missing == null`, true},
+		{`inputs.missing`, false},
+		{`event.labels.0 == "prod"`, true},
+	}
+	for _, c := range cases {
+		e, err := ParseExpr(c.expr)
+		if err != nil {
+			t.Errorf("ParseExpr(%q) = %v", c.expr, err)
+			continue
+		}
+		if got := e.Match(ctx); got != c.want {
+			t.Errorf("%s = %v, want %v", c.expr, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{``, `a ==`, `(a == 1`, `foo(a, b)`, `contains(a)`, `a = 1`, `"open`, `a b`} {
+		if _, err := ParseExpr(bad); err == nil {
+			t.Errorf("ParseExpr(%q) succeeded", bad)
+		}
+	}
+
+	e, _ := ParseExpr(`steps.a.status == "SUCCESS" && contains(inputs.x, steps.b.outputs.y)`)
+	if got, want := e.Paths(), []string{"steps.a.status", "inputs.x", "steps.b.outputs.y"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths() = %v, want %v", got, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	ctx := map[string]interface{}{
+		"inputs": map[string]interface{}{"app": "web", "port": 8080.0},
+	}
+	got, err := Render("docker restart ${{ inputs.app }} # ${{inputs.port}}${{ inputs.none }}", ctx)
+	if err != nil || got != "docker restart web # 8080" {
+		t.Errorf("Render() = %v, %v", got, err)
+	}
+	// 只有一个表达式时保留类型
+	if got, _ := Render("${{ inputs.port }}", ctx); got != 8080.0 {
+		t.Errorf("Render() = %#v", got)
+	}
+	if _, err := Render("${{ inputs.app", ctx); err == nil {
+		t.Error("Render() with unterminated expression succeeded")
+	}
+
+	paths, err := TemplatePaths("${{ event.app }}-${{ contains(event.labels.env, 'prod') }}")
+	if err != nil || !reflect.DeepEqual(paths, []string{"event.app", "event.labels.env"}) {
+		t.Errorf("TemplatePaths() = %v, %v", paths, err)
+	}
+}
+
+const sample = `{
+  "inputs": {
+    "app": {"type": "string", "required": true},
+    "server": {"type": "number", "default": 2},
+    "force": {"type": "bool"}
+  },
+  "steps": [
+    {"id": "restart", "type": "system_command", "servers": [1], "depends_on": ["check"],
+     "if": "steps.check.outputs.state != 'running' || inputs.force",
+     "params": {"command": "docker restart ${{ inputs.app }}"}, "retries": 2, "timeout": 60},
+    {"id": "check", "type": "system_command", "servers": [1, "${{ inputs.server }}", 1],
+     "params": {"command": "docker inspect ${{ inputs.app }}", "args": ["${{ inputs.server }}"]},
+     "outputs": {"state": {"from": "data.output", "pattern": "state=(\\w+)"}, "status": {"from": "status"}}},
+    {"id": "notify", "type": "system_command", "servers": [1], "depends_on": ["restart"],
+     "params": {"command": "echo ${{ steps.check.outputs.state }}"}}
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	def, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, step := range def.Order() {
+		order = append(order, step.ID)
+	}
+	if want := []string{"check", "restart", "notify"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Order() = %v, want %v", order, want)
+	}
+
+	inputs, err := def.ResolveInputs(map[string]interface{}{"app": "web", "force": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"app": "web", "server": 2.0, "force": true}; !reflect.DeepEqual(inputs, want) {
+		t.Errorf("ResolveInputs() = %v, want %v", inputs, want)
+	}
+	if _, err := def.ResolveInputs(map[string]interface{}{}); err == nil {
+		t.Error("ResolveInputs() without required input succeeded")
+	}
+	if _, err := def.ResolveInputs(map[string]interface{}{"app": "web", "other": 1}); err == nil {
+		t.Error("ResolveInputs() with undeclared input succeeded")
+	}
+	if _, err := def.ResolveInputs(map[string]interface{}{"app": "web", "server": "two"}); err == nil {
+		t.Error("ResolveInputs() with invalid number succeeded")
+	}
+
+	ctx := map[string]interface{}{"inputs": inputs, "steps": map[string]interface{}{}}
+	check := def.Step("check")
+	servers, err := check.ResolveServers(ctx)
+	if err != nil || !reflect.DeepEqual(servers, []uint{1, 2}) {
+		t.Errorf("ResolveServers() = %v, %v", servers, err)
+	}
+	params, err := check.RenderParams(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"command": "docker inspect web", "args": []interface{}{2.0}}; !reflect.DeepEqual(params, want) {
+		t.Errorf("RenderParams() = %v, want %v", params, want)
+	}
+
+	outputs := check.ExtractOutputs(&Result{Status: "success", Data: map[string]interface{}{"output": "name=web state=exited\n"}})
+	if want := map[string]string{"state": "exited", "status": "success"}; !reflect.DeepEqual(outputs, want) {
+		t.Errorf("ExtractOutputs() = %v, want %v", outputs, want)
+	}
+
+	// 有 if 时按条件决定，没有 if 时要求依赖全部成功
+	ctx["steps"] = map[string]interface{}{"check": map[string]interface{}{"status": StatusSuccess, "outputs": outputs}}
+	inputs["force"] = false
+	if !def.Step("restart").Ready(map[string]string{"check": StatusSuccess}, ctx) {
+		t.Error("restart should run when the container is not running")
+	}
+	ctx["steps"] = map[string]interface{}{"check": map[string]interface{}{"status": StatusSuccess,
+		"outputs": map[string]string{"state": "running"}}}
+	if def.Step("restart").Ready(map[string]string{"check": StatusSuccess}, ctx) {
+		t.Error("restart should be skipped when the container is running")
+	}
+	if def.Step("notify").Ready(map[string]string{"restart": StatusSkipped}, ctx) {
+		t.Error("notify should be skipped when restart is skipped")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	step := func(extra string) string {
+		return `{"steps": [{"id": "a", "type": "system_command", "servers": [1]},
+			{"id": "b", "type": "system_command", "servers": [1]` + extra + `}]}`
+	}
+	cases := map[string]string{
+		`{"steps": []}`: "no steps",
+		`{"steps": [{"id": "a b", "type": "x", "servers": [1]}]}`:                                                    "invalid step id",
+		`{"steps": [{"id": "a", "type": "x", "servers": [1]}, {"id": "a", "type": "x", "servers": [1]}]}`:            "duplicate step id",
+		`{"steps": [{"id": "a", "servers": [1]}]}`:                                                                   "type is required",
+		`{"steps": [{"id": "a", "type": "x"}]}`:                                                                      "servers is required",
+		`{"steps": [{"id": "a", "type": "x", "servers": [0]}]}`:                                                      "invalid server ID",
+		`{"steps": [{"id": "a", "type": "x", "servers": ["web"]}]}`:                                                  "invalid server",
+		`{"inputs": {"n": {"type": "number", "default": "x"}}, "steps": [{"id": "a", "type": "x", "servers": [1]}]}`: "default of input",
+		`{"inputs": {"n": {"type": "list"}}, "steps": [{"id": "a", "type": "x", "servers": [1]}]}`:                   "unsupported type",
+		step(`, "depends_on": ["c"]`):                                                                                "unknown dependency",
+		step(`, "depends_on": ["b"]`):                                                                                "depends on itself",
+		step(`, "if": "steps.a.status =="`):                                                                          "invalid expression",
+		step(`, "if": "steps.a.status == 'SUCCESS'"`):                                                                "not one of its dependencies",
+		step(`, "params": {"command": "${{ steps.a.outputs.x"}`):                                                     "unterminated",
+		step(`, "retries": 11`):                                                                                      "retries must be between",
+		step(`, "timeout": -1`):                                                                                      "timeout must be between",
+		step(`, "outputs": {"x": {"from": "stdout"}}`):                                                               "from must be",
+		step(`, "outputs": {"x": {"from": "data.output", "pattern": "("}}`):                                          "missing closing",
+		`{"steps": [{"id": "a", "type": "x", "servers": [1], "depends_on": ["b"]},
+			{"id": "b", "type": "x", "servers": [1], "depends_on": ["a"]}]}`: "dependency cycle",
+	}
+	for def, want := range cases {
+		_, err := Parse([]byte(def))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%s) = %v, want error containing %q", def, err, want)
+		}
+	}
+}
@@ -136,7 +136,7 @@ CREATE TABLE `app_deployments` (
     `start_time` DATETIME NULL COMMENT '开始时间',
     `end_time` DATETIME NULL COMMENT '结束时间',
     `error_message` TEXT NULL COMMENT '错误信息',
-    `deployment_log` TEXT NULL COMMENT '部署日志',
+    `deployment_log` TEXT NULL COMMENT '部署日志(已废弃，日志保存在 log_segments)',
     `config_data` JSON NULL COMMENT '部署配置',
     `owner_id` BIGINT UNSIGNED NOT NULL COMMENT '所有者ID',
     `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
@@ -226,7 +226,7 @@ CREATE TABLE `workflow_executions` (
     `end_time` DATETIME NULL COMMENT '结束时间',
     `duration` INT NOT NULL DEFAULT 0 COMMENT '执行时长(秒)',
     `error_message` TEXT NULL COMMENT '错误信息',
-    `execution_log` TEXT NULL COMMENT '执行日志',
+    `execution_log` TEXT NULL COMMENT '执行日志(已废弃，日志保存在 log_segments)',
     `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
     PRIMARY KEY (`id`),
@@ -236,6 +236,22 @@ CREATE TABLE `workflow_executions` (
     CONSTRAINT `fk_workflow_executions_task` FOREIGN KEY (`task_id`) REFERENCES `workflow_tasks` (`id`) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='工作流执行历史表';
 
+-- 日志分段表
+CREATE TABLE `log_segments` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `target_type` VARCHAR(32) NOT NULL COMMENT '日志对象类型: WORKFLOW_EXECUTION, DEPLOYMENT',
+    `target_id` BIGINT UNSIGNED NOT NULL COMMENT '工作流执行或应用部署ID',
+    `stream` VARCHAR(255) NULL COMMENT '输出来源，工作流步骤为 步骤ID@服务器ID',
+    `task_id` VARCHAR(64) NULL COMMENT 'Agent 任务ID，系统日志为空',
+    `seq` INT NULL COMMENT '任务输出批次序号',
+    `content` MEDIUMTEXT NULL COMMENT '日志内容',
+    `lines` INT NULL COMMENT '行数',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_log_segment_target` (`target_type`, `target_id`),
+    UNIQUE KEY `idx_log_segment_task` (`task_id`, `seq`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='工作流执行与应用部署的日志分段表';
+
 -- ========================================
 -- 3.3 资源管理 (Resource Management)
 -- ========================================
@@ -110,7 +110,7 @@ CREATE TABLE app_deployments (
     start_time DATETIME,
     end_time DATETIME,
     error_message TEXT,
-    deployment_log TEXT,
+    deployment_log TEXT, -- 已废弃，日志保存在 log_segments
     config_data TEXT, -- JSON format
     owner_id INTEGER NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
@@ -181,11 +181,24 @@ CREATE TABLE workflow_executions (
     end_time DATETIME,
     duration INTEGER DEFAULT 0,
     error_message TEXT,
-    execution_log TEXT,
+    execution_log TEXT, -- 已废弃，日志保存在 log_segments
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 工作流执行与应用部署的日志分段表
+CREATE TABLE log_segments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    target_type VARCHAR(32) NOT NULL, -- WORKFLOW_EXECUTION, DEPLOYMENT
+    target_id INTEGER NOT NULL,
+    stream VARCHAR(255),
+    task_id VARCHAR(64),
+    seq INTEGER,
+    content TEXT,
+    lines INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
 -- ========================================
 -- 3.3 资源管理 (Resource Management)
 -- ========================================
@@ -568,6 +581,8 @@ CREATE INDEX idx_app_store_reviews_user ON app_store_reviews(user_id);
 CREATE INDEX idx_workflows_owner ON workflows(owner_id);
 CREATE INDEX idx_workflow_tasks_workflow ON workflow_tasks(workflow_id);
 CREATE INDEX idx_workflow_executions_task ON workflow_executions(task_id);
+CREATE INDEX idx_log_segment_target ON log_segments(target_type, target_id);
+CREATE UNIQUE INDEX idx_log_segment_task ON log_segments(task_id, seq);
 
 -- 资源管理相关索引
 CREATE INDEX idx_servers_owner ON servers(owner_id);
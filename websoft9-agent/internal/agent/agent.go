@@ -48,6 +48,24 @@ func New(cfg *config.Config) (*Agent, error) {
 		return nil, err
 	}
 
+	// 任务经通信管理器下发到执行器，执行结果再经通信管理器回传
+	commMgr.SetTaskHandler(func(t *task.Task) {
+		if err := taskExec.Submit(t); err != nil {
+			logrus.Error(err)
+		}
+	})
+	taskExec.SetResultHandler(func(result *task.TaskResult) {
+		if err := commMgr.SendTaskResult(result); err != nil {
+			logrus.WithError(err).Errorf("发送任务结果失败: %s", result.TaskID)
+		}
+	})
+	taskExec.SetLogHandler(func(log *task.TaskLog) {
+		if err := commMgr.SendTaskLog(log); err != nil {
+			logrus.WithError(err).Warnf("发送任务日志失败: %s", log.TaskID)
+		}
+	})
+	mon.SetMetricsHandler(commMgr.SendMetrics)
+
 	return &Agent{
 		config:       cfg,
 		monitor:      mon,
@@ -126,6 +144,7 @@ func (a *Agent) startHeartbeat() {
 
 // sendHeartbeat 发送心跳
 func (a *Agent) sendHeartbeat() {
-	logrus.Debug("发送心跳")
-	// TODO: 实现心跳发送逻辑
+	if err := a.comm.SendHeartbeat(); err != nil {
+		logrus.WithError(err).Warn("发送心跳失败")
+	}
 }
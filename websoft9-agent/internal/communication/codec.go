@@ -0,0 +1,28 @@
+package communication
+
+import (
+	"encoding/json"
+
+	"websoft9-agent/internal/constants"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// codec 以 JSON 编码 gRPC 消息，与 api-service 的 agentrpc 编码一致，无需 protobuf 生成代码
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return constants.AgentRPCCodec
+}
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
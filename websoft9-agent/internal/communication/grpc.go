@@ -2,28 +2,61 @@ package communication
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"os"
+	"time"
 
 	"websoft9-agent/internal/config"
+	"websoft9-agent/internal/constants"
+	"websoft9-agent/internal/monitor"
+	"websoft9-agent/pkg/security"
 
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 // GRPCClient gRPC 客户端
+// 服务定义见 api-service 的 agentrpc 包，消息以 JSON 编码，载荷使用任务密钥加密
 type GRPCClient struct {
 	config *config.Config
 	conn   *grpc.ClientConn
+	cipher *security.TaskCipher
+}
+
+// Envelope 请求信封，与 api-service 的 agentrpc.Envelope 结构一致
+type Envelope struct {
+	AgentID string `json:"agent_id"`
+	Payload string `json:"payload"`
+}
+
+// Reply 服务端响应
+type Reply struct {
+	ServerID uint `json:"server_id"`
+	Accepted int  `json:"accepted"`
+}
 
-	// TODO: 添加具体的 gRPC 服务客户端
-	// agentServiceClient pb.AgentServiceClient
+// Heartbeat 心跳载荷，发送时间用于服务端限制重放
+type Heartbeat struct {
+	AgentID string    `json:"agent_id"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// MetricsBatch 指标上报载荷
+type MetricsBatch struct {
+	Heartbeat
+	Points []*monitor.Point `json:"points"`
 }
 
 // NewGRPCClient 创建 gRPC 客户端
-func NewGRPCClient(cfg *config.Config) (*GRPCClient, error) {
+func NewGRPCClient(cfg *config.Config, cipher *security.TaskCipher) (*GRPCClient, error) {
 	return &GRPCClient{
 		config: cfg,
+		cipher: cipher,
 	}, nil
 }
 
@@ -34,10 +67,11 @@ func (c *GRPCClient) Start(ctx context.Context) error {
 	logrus.Infof("连接到服务端: %s", serverAddr)
 
 	// 创建连接选项
-	var opts []grpc.DialOption
-	// TODO: 配置 TLS 证书
-	// 目前统一使用不安全连接，后续根据配置决定是否启用 TLS
-	opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds, err := c.transportCredentials()
+	if err != nil {
+		return err
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
 
 	// 建立连接 - 使用 grpc.NewClient 替代废弃的 grpc.DialContext
 	conn, err := grpc.NewClient(serverAddr, opts...)
@@ -47,13 +81,35 @@ func (c *GRPCClient) Start(ctx context.Context) error {
 
 	c.conn = conn
 
-	// TODO: 初始化服务客户端
-	// c.agentServiceClient = pb.NewAgentServiceClient(conn)
-
 	logrus.Info("gRPC 客户端启动成功")
 	return nil
 }
 
+// transportCredentials 按 server.tls 配置返回连接凭据，未启用 TLS 时使用明文连接
+func (c *GRPCClient) transportCredentials() (credentials.TransportCredentials, error) {
+	if !c.config.Server.TLS {
+		logrus.Warn("gRPC 连接未启用 TLS，生产环境请配置 server.tls")
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: c.config.Server.Host,
+		MinVersion: tls.VersionTLS12,
+	}
+	if c.config.Server.CAFile != "" {
+		data, err := os.ReadFile(c.config.Server.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取服务端根证书失败: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("服务端根证书文件中没有证书: %s", c.config.Server.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
 // Stop 停止 gRPC 客户端
 func (c *GRPCClient) Stop() {
 	if c.conn != nil {
@@ -65,34 +121,55 @@ func (c *GRPCClient) Stop() {
 
 // SendHeartbeat 发送心跳
 func (c *GRPCClient) SendHeartbeat() error {
-	if c.conn == nil {
-		return fmt.Errorf("gRPC 连接未建立")
+	_, err := c.invoke(constants.AgentRPCHeartbeat, c.heartbeat())
+	if err != nil {
+		return err
 	}
-
-	// TODO: 实现心跳发送
-	// 需要实现以下功能：
-	// 1. 创建带超时的上下文
-	// 2. 构建心跳请求
-	// 3. 调用 gRPC 心跳接口
 	logrus.Debug("发送心跳到服务端")
+	return nil
+}
 
+// SendMetrics 批量发送监控指标，服务端同时记录心跳
+func (c *GRPCClient) SendMetrics(points []*monitor.Point) error {
+	reply, err := c.invoke(constants.AgentRPCReportMetrics, &MetricsBatch{
+		Heartbeat: *c.heartbeat(),
+		Points:    points,
+	})
+	if err != nil {
+		return err
+	}
+	logrus.Debugf("发送监控指标到服务端: %d/%d 个数据点被接收", reply.Accepted, len(points))
 	return nil
 }
 
-// SendMetrics 发送监控指标
-func (c *GRPCClient) SendMetrics(metrics interface{}) error {
+func (c *GRPCClient) heartbeat() *Heartbeat {
+	return &Heartbeat{AgentID: c.config.Agent.ID, SentAt: time.Now()}
+}
+
+// invoke 加密载荷并调用服务端方法
+func (c *GRPCClient) invoke(method string, payload interface{}) (*Reply, error) {
 	if c.conn == nil {
-		return fmt.Errorf("gRPC 连接未建立")
+		return nil, fmt.Errorf("gRPC 连接未建立")
 	}
 
-	// TODO: 实现指标发送
-	// 需要实现以下功能：
-	// 1. 创建带超时的上下文
-	// 2. 转换指标数据格式
-	// 3. 调用 gRPC 指标发送接口
-	logrus.Debug("发送监控指标到服务端")
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := c.cipher.Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRPCTimeout)
+	defer cancel()
+
+	var reply Reply
+	req := &Envelope{AgentID: c.config.Agent.ID, Payload: encrypted}
+	if err := c.conn.Invoke(ctx, method, req, &reply, grpc.CallContentSubtype(constants.AgentRPCCodec)); err != nil {
+		return nil, fmt.Errorf("调用 %s 失败: %v", method, err)
+	}
+	return &reply, nil
 }
 
 // SendTaskResult 发送任务结果
@@ -0,0 +1,79 @@
+package communication
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"websoft9-agent/internal/config"
+	"websoft9-agent/internal/constants"
+	"websoft9-agent/internal/monitor"
+	"websoft9-agent/pkg/lineprotocol"
+)
+
+// InfluxWriter 以行协议将指标直接写入 InfluxDB 2.x，用于不经服务端转发的部署
+type InfluxWriter struct {
+	writeURL string
+	token    string
+	client   *http.Client
+}
+
+// NewInfluxWriter 创建 InfluxDB 写入器
+func NewInfluxWriter(cfg config.InfluxDBConfig) (*InfluxWriter, error) {
+	base, err := url.Parse(strings.TrimRight(cfg.URL, "/"))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("无效的 InfluxDB 地址: %s", cfg.URL)
+	}
+
+	query := url.Values{}
+	query.Set("org", cfg.Org)
+	query.Set("bucket", cfg.Bucket)
+	query.Set("precision", "s")
+	base.Path += "/api/v2/write"
+	base.RawQuery = query.Encode()
+
+	return &InfluxWriter{
+		writeURL: base.String(),
+		token:    cfg.Token,
+		client:   &http.Client{Timeout: constants.DefaultInfluxDBTimeout},
+	}, nil
+}
+
+// Write 写入一批数据点
+func (w *InfluxWriter) Write(points []*monitor.Point) error {
+	var body bytes.Buffer
+	for _, p := range points {
+		if line := lineprotocol.Encode(p.Measurement, p.Tags, p.Fields, p.Time); line != "" {
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+	}
+	if body.Len() == 0 {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, w.writeURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("写入 InfluxDB 失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("写入 InfluxDB 失败: HTTP %d %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -2,10 +2,15 @@ package communication
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 
 	"websoft9-agent/internal/config"
+	"websoft9-agent/internal/constants"
+	"websoft9-agent/internal/monitor"
+	"websoft9-agent/internal/task"
+	"websoft9-agent/pkg/security"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
@@ -16,8 +21,13 @@ type Manager struct {
 	config *config.Config
 
 	// 通信组件
-	grpcClient  *GRPCClient
-	redisClient *redis.Client
+	grpcClient   *GRPCClient
+	redisClient  *redis.Client
+	cipher       *security.TaskCipher
+	influxWriter *InfluxWriter // 仅在指标直接写入 InfluxDB 时创建
+
+	// 任务回调
+	taskHandler func(*task.Task)
 
 	// 控制
 	ctx    context.Context
@@ -25,8 +35,21 @@ type Manager struct {
 	wg     sync.WaitGroup
 }
 
+// message 任务通道上的消息信封，Payload 为加密后的任务或结果
+type message struct {
+	Type    string `json:"type"`
+	AgentID string `json:"agent_id,omitempty"`
+	Payload string `json:"payload"`
+}
+
 // NewManager 创建通信管理器
 func NewManager(cfg *config.Config) (*Manager, error) {
+	// 创建任务通道加解密器
+	taskCipher, err := security.NewTaskCipher(cfg.Security.TaskKey)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建 Redis 客户端
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
@@ -40,18 +63,33 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 	}
 
 	// 创建 gRPC 客户端
-	grpcClient, err := NewGRPCClient(cfg)
+	grpcClient, err := NewGRPCClient(cfg, taskCipher)
 	if err != nil {
 		return nil, err
 	}
 
+	var influxWriter *InfluxWriter
+	if cfg.Metrics.Output == constants.MetricsOutputInfluxDB {
+		influxWriter, err = NewInfluxWriter(cfg.Metrics.InfluxDB)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Manager{
-		config:      cfg,
-		grpcClient:  grpcClient,
-		redisClient: redisClient,
+		config:       cfg,
+		grpcClient:   grpcClient,
+		redisClient:  redisClient,
+		cipher:       taskCipher,
+		influxWriter: influxWriter,
 	}, nil
 }
 
+// SetTaskHandler 设置任务处理回调，需在 Start 之前调用
+func (m *Manager) SetTaskHandler(handler func(*task.Task)) {
+	m.taskHandler = handler
+}
+
 // Start 启动通信管理器
 func (m *Manager) Start(ctx context.Context) error {
 	m.ctx, m.cancel = context.WithCancel(ctx)
@@ -97,7 +135,7 @@ func (m *Manager) listenMessages() {
 	logrus.Info("开始监听消息队列...")
 
 	// 订阅 Agent 相关的消息频道
-	pubsub := m.redisClient.Subscribe(m.ctx, fmt.Sprintf("agent:%s", m.config.Agent.ID))
+	pubsub := m.redisClient.Subscribe(m.ctx, constants.TaskChannelPrefix+m.config.Agent.ID)
 	defer pubsub.Close()
 
 	ch := pubsub.Channel()
@@ -106,7 +144,10 @@ func (m *Manager) listenMessages() {
 		select {
 		case <-m.ctx.Done():
 			return
-		case msg := <-ch:
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
 			m.handleMessage(msg)
 		}
 	}
@@ -114,14 +155,35 @@ func (m *Manager) listenMessages() {
 
 // handleMessage 处理消息
 func (m *Manager) handleMessage(msg *redis.Message) {
-	logrus.Debugf("收到消息: %s", msg.Payload)
-
-	// TODO: 解析消息并分发到相应的处理器
-	// 消息类型可能包括:
-	// - 任务指令
-	// - 工作流任务
-	// - 配置更新
-	// - 控制指令
+	var envelope message
+	if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+		logrus.WithError(err).Warn("无法解析的消息")
+		return
+	}
+
+	switch envelope.Type {
+	case constants.MessageTypeTask:
+		// 任务内容可能包含密钥，解密后不记录原文
+		data, err := m.cipher.Decrypt(envelope.Payload)
+		if err != nil {
+			logrus.WithError(err).Warn("Security audit: failed to decrypt task message")
+			return
+		}
+
+		var t task.Task
+		if err := json.Unmarshal(data, &t); err != nil {
+			logrus.WithError(err).Warn("无法解析的任务")
+			return
+		}
+
+		logrus.Debugf("收到任务: %s (类型: %s)", t.ID, t.Type)
+		if m.taskHandler != nil {
+			m.taskHandler(&t)
+		}
+	default:
+		// 任务频道只下发任务消息，其他类型忽略
+		logrus.Debugf("忽略消息类型: %s", envelope.Type)
+	}
 }
 
 // SendHeartbeat 发送心跳
@@ -129,14 +191,45 @@ func (m *Manager) SendHeartbeat() error {
 	return m.grpcClient.SendHeartbeat()
 }
 
-// SendMetrics 发送监控指标
-func (m *Manager) SendMetrics(metrics interface{}) error {
-	return m.grpcClient.SendMetrics(metrics)
+// SendMetrics 按 metrics.output 经 gRPC 上报或直接写入 InfluxDB
+func (m *Manager) SendMetrics(points []*monitor.Point) error {
+	if m.influxWriter != nil {
+		return m.influxWriter.Write(points)
+	}
+	return m.grpcClient.SendMetrics(points)
+}
+
+// SendTaskResult 加密任务结果并发布到结果频道
+func (m *Manager) SendTaskResult(result *task.TaskResult) error {
+	return m.publishResult(constants.MessageTypeTaskResult, result)
 }
 
-// SendTaskResult 发送任务结果
-func (m *Manager) SendTaskResult(result interface{}) error {
-	return m.grpcClient.SendTaskResult(result)
+// SendTaskLog 加密任务实时日志并发布到结果频道，与任务结果使用同一频道以保证先于结果到达
+func (m *Manager) SendTaskLog(log *task.TaskLog) error {
+	return m.publishResult(constants.MessageTypeTaskLog, log)
+}
+
+func (m *Manager) publishResult(messageType string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	payload, err := m.cipher.Encrypt(data)
+	if err != nil {
+		return err
+	}
+
+	envelope, err := json.Marshal(&message{
+		Type:    messageType,
+		AgentID: m.config.Agent.ID,
+		Payload: payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	return m.redisClient.Publish(context.Background(), constants.TaskResultsChannel, envelope).Err()
 }
 
 // SendEvent 发送事件消息
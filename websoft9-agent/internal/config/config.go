@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"websoft9-agent/internal/constants"
 	"websoft9-agent/pkg/security"
@@ -12,17 +13,21 @@ import (
 
 // Config Agent 配置结构
 type Config struct {
-	Server ServerConfig `yaml:"server"`
-	Redis  RedisConfig  `yaml:"redis"`
-	Log    LogConfig    `yaml:"log"`
-	Agent  AgentConfig  `yaml:"agent"`
+	Server     ServerConfig     `yaml:"server"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Log        LogConfig        `yaml:"log"`
+	Agent      AgentConfig      `yaml:"agent"`
+	Security   SecurityConfig   `yaml:"security"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Prometheus PrometheusConfig `yaml:"prometheus"`
 }
 
 // ServerConfig 服务端配置
 type ServerConfig struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
-	TLS  bool   `yaml:"tls"`
+	Host   string `yaml:"host"`
+	Port   int    `yaml:"port"`
+	TLS    bool   `yaml:"tls"`
+	CAFile string `yaml:"ca_file"` // 校验服务端证书的根证书(PEM)，为空时使用系统根证书
 }
 
 // RedisConfig Redis 配置
@@ -45,6 +50,32 @@ type AgentConfig struct {
 	HeartbeatInterval int    `yaml:"heartbeat_interval"`
 	MonitorInterval   int    `yaml:"monitor_interval"`
 	WorkDir           string `yaml:"work_dir"`
+	SecretsDir        string `yaml:"secrets_dir"` // 部署密钥临时目录，必须位于 tmpfs
+	ServerID          uint   `yaml:"server_id"`   // 所在服务器 ID，直接写入 InfluxDB 时作为 server_id 标签
+}
+
+// SecurityConfig 安全配置
+type SecurityConfig struct {
+	TaskKey string `yaml:"task_key"` // 本 Agent 的任务密钥，由 api-service 的主任务密钥按 Agent ID 派生
+}
+
+// MetricsConfig 指标上报配置，Output 为 grpc(经服务端写入指标存储)或 influxdb(以行协议直接写入)
+type MetricsConfig struct {
+	Output   string         `yaml:"output"`
+	InfluxDB InfluxDBConfig `yaml:"influxdb"`
+}
+
+// InfluxDBConfig 直接写入的 InfluxDB 配置
+type InfluxDBConfig struct {
+	URL    string `yaml:"url"`
+	Token  string `yaml:"token"`
+	Org    string `yaml:"org"`
+	Bucket string `yaml:"bucket"`
+}
+
+// PrometheusConfig 本地 Prometheus 抓取端点配置，Listen 为空时不启动
+type PrometheusConfig struct {
+	Listen string `yaml:"listen"` // 如 127.0.0.1:9101
 }
 
 // Load 加载配置文件
@@ -112,6 +143,12 @@ func setDefaults(config *Config) {
 	if config.Agent.WorkDir == "" {
 		config.Agent.WorkDir = "/var/lib/websoft9/agent"
 	}
+	if config.Agent.SecretsDir == "" {
+		config.Agent.SecretsDir = "/dev/shm/websoft9"
+	}
+	if config.Metrics.Output == "" {
+		config.Metrics.Output = constants.MetricsOutputGRPC
+	}
 }
 
 // validateConfig 验证配置内容
@@ -143,6 +180,33 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("监控间隔必须大于 0")
 	}
 
+	// 验证安全配置
+	if config.Security.TaskKey == "" {
+		return fmt.Errorf("任务密钥不能为空")
+	}
+
+	// 验证指标上报配置
+	switch config.Metrics.Output {
+	case constants.MetricsOutputGRPC:
+	case constants.MetricsOutputInfluxDB:
+		influx := config.Metrics.InfluxDB
+		if influx.URL == "" || influx.Org == "" || influx.Bucket == "" {
+			return fmt.Errorf("直接写入 InfluxDB 时 url、org、bucket 不能为空")
+		}
+		if config.Agent.ServerID == 0 {
+			return fmt.Errorf("直接写入 InfluxDB 时必须配置 agent.server_id")
+		}
+	default:
+		return fmt.Errorf("无效的指标上报方式: %s", config.Metrics.Output)
+	}
+
+	// 验证 Prometheus 抓取端点
+	if listen := config.Prometheus.Listen; listen != "" {
+		if _, port, err := net.SplitHostPort(listen); err != nil || port == "" {
+			return fmt.Errorf("无效的 Prometheus 监听地址: %s", listen)
+		}
+	}
+
 	// 验证日志级别
 	validLogLevels := map[string]bool{
 		"trace": true,
@@ -13,6 +13,77 @@ const (
 	StatusStopped   = "stopped"
 )
 
+// 任务通道相关常量，需与 api-service 保持一致
+const (
+	TaskChannelPrefix     = "agent:"
+	TaskResultsChannel    = "agent:results"
+	MessageTypeTask       = "task"
+	MessageTypeTaskResult = "task_result"
+	MessageTypeTaskLog    = "task_log"
+
+	// 任务输出按行分批推送，单行超过上限时切分
+	TaskLogFlushInterval = 500 * time.Millisecond
+	TaskLogBatchLines    = 200
+	TaskLogMaxLineBytes  = 4096
+)
+
+// gRPC 通道常量，需与 api-service 保持一致；消息以 JSON 编码，载荷使用任务密钥加密
+const (
+	AgentRPCService        = "webox.agent.v1.AgentService"
+	AgentRPCCodec          = "json"
+	AgentRPCHeartbeat      = "/" + AgentRPCService + "/Heartbeat"
+	AgentRPCReportMetrics  = "/" + AgentRPCService + "/ReportMetrics"
+	DefaultRPCTimeout      = 10 * time.Second
+	DefaultInfluxDBTimeout = 10 * time.Second
+)
+
+// 本地 Prometheus 抓取端点
+const (
+	PrometheusNamespace       = "webox"
+	PrometheusPath            = "/metrics"
+	PrometheusReadTimeout     = 10 * time.Second
+	PrometheusShutdownTimeout = 5 * time.Second
+)
+
+// 指标上报相关常量，measurement、字段与标签名需与 api-service 保持一致
+const (
+	MetricsOutputGRPC     = "grpc"
+	MetricsOutputInfluxDB = "influxdb"
+
+	// 上报失败时缓存的数据点上限，超出后丢弃最早的数据点
+	MaxPendingMetricPoints = 5000
+
+	MeasurementServerMetrics = "server_metrics"
+	MeasurementAppMetrics    = "app_metrics"
+
+	MetricFieldCPUUsage    = "cpu_usage"      // 百分比
+	MetricFieldMemUsage    = "memory_usage"   // 百分比
+	MetricFieldMemUsed     = "memory_used"    // 字节
+	MetricFieldDiskUsage   = "disk_usage"     // 百分比，根分区
+	MetricFieldNetRxRate   = "network_rx_bps" // 字节/秒
+	MetricFieldNetTxRate   = "network_tx_bps" // 字节/秒
+	MetricFieldContainerUp = "running"        // 1 为运行中
+
+	MetricTagServerID       = "server_id"
+	MetricTagAgentID        = "agent_id"
+	MetricTagContainerID    = "container_id"
+	MetricTagContainerName  = "container_name"
+	MetricTagComposeProject = "compose_project"
+
+	ComposeProjectLabel = "com.docker.compose.project"
+)
+
+// 任务类型常量
+const (
+	TaskTypeDeployApp     = "deploy_app"
+	TaskTypeManageApp     = "manage_app"
+	TaskTypeSystemCommand = "system_command"
+	TaskTypeFileTransfer  = "file_transfer"
+	TaskTypeServiceManage = "service_manage"
+	TaskTypeInstallCA     = "install_ca"
+	TaskTypeProxyConfig   = "proxy_config"
+)
+
 // 网络相关常量
 const (
 	DefaultHost = "localhost"
@@ -1,9 +1,17 @@
 package monitor
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
 	"os/exec"
+	"strconv"
+	"strings"
+	"time"
 
 	"websoft9-agent/internal/config"
+	"websoft9-agent/internal/constants"
 
 	"github.com/sirupsen/logrus"
 )
@@ -11,6 +19,14 @@ import (
 // ContainerMonitor 容器监控器
 type ContainerMonitor struct {
 	config *config.Config
+
+	// 上一次采集的容器网络累计值，用于计算速率
+	prevNetwork map[string]containerCounters
+}
+
+type containerCounters struct {
+	network ContainerNetwork
+	at      time.Time
 }
 
 // ContainerMetrics 容器指标
@@ -18,6 +34,7 @@ type ContainerMetrics struct {
 	ID      string           `json:"id"`
 	Name    string           `json:"name"`
 	Image   string           `json:"image"`
+	Project string           `json:"project"` // Compose 项目名
 	State   string           `json:"state"`
 	Status  string           `json:"status"`
 	CPU     ContainerCPU     `json:"cpu"`
@@ -46,37 +63,175 @@ type ContainerNetwork struct {
 // NewContainerMonitor 创建容器监控器
 func NewContainerMonitor(cfg *config.Config) (*ContainerMonitor, error) {
 	return &ContainerMonitor{
-		config: cfg,
+		config:      cfg,
+		prevNetwork: make(map[string]containerCounters),
 	}, nil
 }
 
-// Collect 采集容器指标
-func (c *ContainerMonitor) Collect() error {
-	// 使用 docker ps 命令获取容器列表
-	cmd := exec.Command("docker", "ps", "-a", "--format", "table {{.ID}}\t{{.Names}}\t{{.Image}}\t{{.Status}}")
+// Collect 采集容器指标并转换为数据点，Docker 未安装或未运行时返回空
+func (c *ContainerMonitor) Collect() ([]*Point, error) {
+	// #nosec G204 - 固定命令与参数
+	cmd := exec.Command("docker", "ps", "-a", "--format",
+		`{{.ID}}\t{{.Names}}\t{{.Image}}\t{{.State}}\t{{.Status}}\t{{.Label "`+constants.ComposeProjectLabel+`"}}`)
 	output, err := cmd.Output()
 	if err != nil {
 		logrus.Debugf("Docker 命令执行失败，可能 Docker 未安装或未运行: %v", err)
-		return nil // 不返回错误，因为 Docker 可能未安装
+		return nil, nil // 不返回错误，因为 Docker 可能未安装
+	}
+
+	containers := parseContainerList(output)
+	if len(containers) == 0 {
+		return nil, nil
 	}
 
-	logrus.Debugf("Docker 容器列表:\n%s", string(output))
+	// docker stats 只返回运行中的容器
+	// #nosec G204 - 固定命令与参数
+	cmd = exec.Command("docker", "stats", "--no-stream", "--format",
+		`{{.ID}}\t{{.CPUPerc}}\t{{.MemUsage}}\t{{.MemPerc}}\t{{.NetIO}}`)
+	output, err = cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("获取容器资源使用失败: %v", err)
+	}
+	parseContainerStats(output, containers)
+
+	return c.points(containers, time.Now()), nil
+}
+
+// points 转换为 app_metrics 数据点，由服务端按 Compose 项目名或容器名关联到应用
+func (c *ContainerMonitor) points(containers map[string]*ContainerMetrics, now time.Time) []*Point {
+	points := make([]*Point, 0, len(containers))
+	seen := make(map[string]bool, len(containers))
+
+	for id, m := range containers {
+		tags := map[string]string{
+			constants.MetricTagContainerID:   id,
+			constants.MetricTagContainerName: m.Name,
+		}
+		if m.Project != "" {
+			tags[constants.MetricTagComposeProject] = m.Project
+		}
+
+		fields := map[string]float64{constants.MetricFieldContainerUp: 0}
+		if m.State == constants.StatusRunning {
+			fields[constants.MetricFieldContainerUp] = 1
+			fields[constants.MetricFieldCPUUsage] = m.CPU.Usage
+			fields[constants.MetricFieldMemUsage] = m.Memory.Percent
+			fields[constants.MetricFieldMemUsed] = float64(m.Memory.Usage)
 
-	// TODO: 解析输出并收集详细指标
-	// TODO: 发送指标到 InfluxDB
+			if prev, ok := c.prevNetwork[id]; ok {
+				elapsed := now.Sub(prev.at)
+				if rate, ok := counterRate(prev.network.RxBytes, m.Network.RxBytes, elapsed); ok {
+					fields[constants.MetricFieldNetRxRate] = rate
+				}
+				if rate, ok := counterRate(prev.network.TxBytes, m.Network.TxBytes, elapsed); ok {
+					fields[constants.MetricFieldNetTxRate] = rate
+				}
+			}
+			c.prevNetwork[id] = containerCounters{network: m.Network, at: now}
+			seen[id] = true
+		}
+
+		points = append(points, &Point{
+			Measurement: constants.MeasurementAppMetrics,
+			Tags:        tags,
+			Fields:      fields,
+			Time:        now,
+		})
+	}
 
-	return nil
+	for id := range c.prevNetwork {
+		if !seen[id] {
+			delete(c.prevNetwork, id)
+		}
+	}
+	return points
+}
+
+// parseContainerList 解析 docker ps 输出，按容器 ID 索引
+func parseContainerList(output []byte) map[string]*ContainerMetrics {
+	containers := make(map[string]*ContainerMetrics)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 6 || cols[0] == "" {
+			continue
+		}
+		containers[cols[0]] = &ContainerMetrics{
+			ID:      cols[0],
+			Name:    cols[1],
+			Image:   cols[2],
+			State:   cols[3],
+			Status:  cols[4],
+			Project: cols[5],
+		}
+	}
+	return containers
 }
 
-// TODO: collectContainerMetrics 采集单个容器指标 - 当需要详细容器指标时启用
-// func (c *ContainerMonitor) collectContainerMetrics(containerID string) (*ContainerMetrics, error) {
-//     // 实现基于命令行的容器指标采集
-//     // 这里可以使用 docker stats, docker inspect 等命令
-//
-//     metrics := &ContainerMetrics{
-//         ID:    containerID,
-//         State: "unknown",
-//     }
-//
-//     return metrics, nil
-// }
+// parseContainerStats 解析 docker stats 输出并填入对应容器，无法解析的列保持为 0
+func parseContainerStats(output []byte, containers map[string]*ContainerMetrics) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 5 {
+			continue
+		}
+		m, ok := containers[cols[0]]
+		if !ok {
+			continue
+		}
+
+		m.CPU.Usage, _ = parsePercent(cols[1])
+		m.Memory.Usage, m.Memory.Limit, _ = parseSizePair(cols[2])
+		m.Memory.Percent, _ = parsePercent(cols[3])
+		m.Network.RxBytes, m.Network.TxBytes, _ = parseSizePair(cols[4])
+	}
+}
+
+// parsePercent 解析 "12.34%" 格式
+func parsePercent(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+}
+
+// parseSizePair 解析 "1.5MiB / 1.944GiB" 格式
+func parseSizePair(s string) (uint64, uint64, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("无效的格式: %q", s)
+	}
+	a, err := parseSize(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := parseSize(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return a, b, nil
+}
+
+// sizeUnits docker 输出的容量单位，内存使用二进制单位，网络与磁盘 IO 使用十进制单位
+var sizeUnits = []struct {
+	suffix string
+	factor float64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40}, {"PiB", 1 << 50},
+	{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12}, {"PB", 1e15},
+	{"B", 1},
+}
+
+// parseSize 解析 "1.2kB"、"512MiB"、"0B" 格式
+func parseSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	for _, unit := range sizeUnits {
+		if !strings.HasSuffix(s, unit.suffix) {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("无效的容量: %q", s)
+		}
+		return uint64(math.Round(v * unit.factor)), nil
+	}
+	return 0, fmt.Errorf("无效的容量: %q", s)
+}
@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint64
+	}{
+		{"0B", 0},
+		{"648B", 648},
+		{"1.2kB", 1200},
+		{"10.5MiB", 11010048},
+		{" 1.944GiB", 2087354106},
+		{"3MB", 3000000},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := parseSize("--"); err == nil {
+		t.Error("parseSize(--) error = nil")
+	}
+}
+
+func TestContainerPoints(t *testing.T) {
+	containers := parseContainerList([]byte("abc\tshop-web-1\tnginx\trunning\tUp 2 hours\tshop\ndef\tdb\tmysql\texited\tExited (0)\t\n"))
+	parseContainerStats([]byte("abc\t12.5%\t10MiB / 1GiB\t0.98%\t1kB / 2kB\n"), containers)
+
+	c := &ContainerMonitor{prevNetwork: map[string]containerCounters{
+		"abc": {network: ContainerNetwork{RxBytes: 0, TxBytes: 1000}, at: time.Unix(0, 0)},
+		"old": {},
+	}}
+	points := c.points(containers, time.Unix(10, 0))
+	if len(points) != 2 {
+		t.Fatalf("points = %d, want 2", len(points))
+	}
+
+	for _, p := range points {
+		switch p.Tags["container_id"] {
+		case "abc":
+			if p.Tags["compose_project"] != "shop" || p.Fields["running"] != 1 || p.Fields["cpu_usage"] != 12.5 ||
+				p.Fields["network_rx_bps"] != 100 || p.Fields["network_tx_bps"] != 100 {
+				t.Errorf("running container point = %+v", p)
+			}
+		case "def":
+			if _, ok := p.Tags["compose_project"]; ok || p.Fields["running"] != 0 || len(p.Fields) != 1 {
+				t.Errorf("exited container point = %+v", p)
+			}
+		}
+	}
+	if _, ok := c.prevNetwork["old"]; ok {
+		t.Error("counters of removed container were kept")
+	}
+}
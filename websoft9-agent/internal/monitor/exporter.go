@@ -0,0 +1,156 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"websoft9-agent/internal/constants"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+// exportedMeasurement 数据点 measurement 对应的指标前缀与标签
+type exportedMeasurement struct {
+	subsystem string
+	labels    []string
+}
+
+var exportedMeasurements = map[string]exportedMeasurement{
+	constants.MeasurementServerMetrics: {
+		subsystem: "host",
+		labels:    []string{constants.MetricTagAgentID, constants.MetricTagServerID},
+	},
+	constants.MeasurementAppMetrics: {
+		subsystem: "container",
+		labels: []string{constants.MetricTagAgentID, constants.MetricTagServerID,
+			constants.MetricTagContainerID, constants.MetricTagContainerName, constants.MetricTagComposeProject},
+	},
+}
+
+// exportedFields 数据点字段对应的指标名与说明，未列出的字段不导出
+var exportedFields = map[string]struct {
+	name string
+	help string
+}{
+	constants.MetricFieldCPUUsage:    {"cpu_usage_percent", "CPU usage in percent."},
+	constants.MetricFieldMemUsage:    {"memory_usage_percent", "Memory usage in percent."},
+	constants.MetricFieldMemUsed:     {"memory_used_bytes", "Memory in use in bytes."},
+	constants.MetricFieldDiskUsage:   {"root_disk_usage_percent", "Root filesystem usage in percent."},
+	constants.MetricFieldNetRxRate:   {"network_receive_bytes_per_second", "Network receive rate over the last collection interval."},
+	constants.MetricFieldNetTxRate:   {"network_transmit_bytes_per_second", "Network transmit rate over the last collection interval."},
+	constants.MetricFieldContainerUp: {"running", "Whether the container is running (1) or not (0)."},
+}
+
+// Exporter 以 Prometheus/OpenMetrics 格式暴露最近一轮采集的主机与容器指标
+type Exporter struct {
+	mu          sync.RWMutex
+	points      []*Point
+	collectedAt time.Time
+
+	descs       map[string]map[string]*prometheus.Desc // measurement -> field -> desc
+	lastCollect *prometheus.Desc
+}
+
+// NewExporter 创建指标导出器
+func NewExporter() *Exporter {
+	e := &Exporter{
+		descs: make(map[string]map[string]*prometheus.Desc),
+		lastCollect: prometheus.NewDesc(
+			prometheus.BuildFQName(constants.PrometheusNamespace, "agent", "last_collect_timestamp_seconds"),
+			"Unix time of the last metrics collection.", nil, nil),
+	}
+	for measurement, m := range exportedMeasurements {
+		e.descs[measurement] = make(map[string]*prometheus.Desc)
+		for field, f := range exportedFields {
+			e.descs[measurement][field] = prometheus.NewDesc(
+				prometheus.BuildFQName(constants.PrometheusNamespace, m.subsystem, f.name), f.help, m.labels, nil)
+		}
+	}
+	return e
+}
+
+// Update 替换为最新一轮采集的数据点
+func (e *Exporter) Update(points []*Point) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.points = points
+	e.collectedAt = time.Now()
+}
+
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.lastCollect
+	for _, fields := range e.descs {
+		for _, desc := range fields {
+			ch <- desc
+		}
+	}
+}
+
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.collectedAt.IsZero() {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(e.lastCollect, prometheus.GaugeValue, float64(e.collectedAt.Unix()))
+
+	for _, p := range e.points {
+		fields, ok := e.descs[p.Measurement]
+		if !ok {
+			continue
+		}
+		labels := exportedMeasurements[p.Measurement].labels
+		values := make([]string, len(labels))
+		for i, label := range labels {
+			values[i] = p.Tags[label]
+		}
+		for field, v := range p.Fields {
+			if desc, ok := fields[field]; ok {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, values...)
+			}
+		}
+	}
+}
+
+// Serve 在 addr 上提供抓取端点，直到 ctx 结束
+func (e *Exporter) Serve(ctx context.Context, addr string) error {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		e,
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle(constants.PrometheusPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: constants.PrometheusReadTimeout,
+		ReadTimeout:       constants.PrometheusReadTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.PrometheusShutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	logrus.WithFields(logrus.Fields{
+		"listen": addr,
+		"action": "prometheus_listen",
+	}).Info("Security audit: exposing metrics endpoint")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestExporterCollect(t *testing.T) {
+	e := NewExporter()
+	if n := testutil.CollectAndCount(e); n != 0 {
+		t.Fatalf("metrics before first collection = %d, want 0", n)
+	}
+
+	now := time.Now()
+	e.Update([]*Point{
+		{
+			Measurement: "server_metrics",
+			Tags:        map[string]string{"agent_id": "a1", "server_id": "3"},
+			Fields:      map[string]float64{"cpu_usage": 12.5, "unknown_field": 1},
+			Time:        now,
+		},
+		{
+			Measurement: "app_metrics",
+			Tags:        map[string]string{"agent_id": "a1", "container_id": "abc", "container_name": "wp"},
+			Fields:      map[string]float64{"running": 1},
+			Time:        now,
+		},
+	})
+
+	want := `
+# HELP webox_container_running Whether the container is running (1) or not (0).
+# TYPE webox_container_running gauge
+webox_container_running{agent_id="a1",compose_project="",container_id="abc",container_name="wp",server_id=""} 1
+# HELP webox_host_cpu_usage_percent CPU usage in percent.
+# TYPE webox_host_cpu_usage_percent gauge
+webox_host_cpu_usage_percent{agent_id="a1",server_id="3"} 12.5
+`
+	err := testutil.CollectAndCompare(e, strings.NewReader(want),
+		"webox_host_cpu_usage_percent", "webox_container_running")
+	if err != nil {
+		t.Error(err)
+	}
+}
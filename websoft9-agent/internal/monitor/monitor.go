@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -20,6 +21,13 @@ type Monitor struct {
 	containerMonitor *ContainerMonitor
 	healthChecker    *HealthChecker
 
+	// 指标上报，上报失败的数据点留待下次重试
+	metricsHandler func([]*Point) error
+	pending        []*Point
+
+	// 本地 Prometheus 抓取端点，未配置 prometheus.listen 时为 nil
+	exporter *Exporter
+
 	// 控制
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -43,12 +51,21 @@ func New(cfg *config.Config) (*Monitor, error) {
 		return nil, err
 	}
 
-	return &Monitor{
+	m := &Monitor{
 		config:           cfg,
 		systemMonitor:    sysMon,
 		containerMonitor: containerMon,
 		healthChecker:    healthChecker,
-	}, nil
+	}
+	if cfg.Prometheus.Listen != "" {
+		m.exporter = NewExporter()
+	}
+	return m, nil
+}
+
+// SetMetricsHandler 设置指标上报回调，需在 Start 之前调用
+func (m *Monitor) SetMetricsHandler(handler func([]*Point) error) {
+	m.metricsHandler = handler
 }
 
 // Start 启动监控
@@ -57,19 +74,23 @@ func (m *Monitor) Start(ctx context.Context) error {
 
 	logrus.Info("启动监控组件...")
 
-	// 启动系统监控
+	// 启动系统与容器指标采集
 	m.wg.Add(1)
 	go func() {
 		defer m.wg.Done()
-		m.runSystemMonitor()
+		m.runMetricsCollector()
 	}()
 
-	// 启动容器监控
-	m.wg.Add(1)
-	go func() {
-		defer m.wg.Done()
-		m.runContainerMonitor()
-	}()
+	// 启动本地 Prometheus 抓取端点
+	if m.exporter != nil {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			if err := m.exporter.Serve(m.ctx, m.config.Prometheus.Listen); err != nil {
+				logrus.Errorf("Prometheus 抓取端点启动失败: %v", err)
+			}
+		}()
+	}
 
 	// 启动健康检查
 	m.wg.Add(1)
@@ -89,8 +110,8 @@ func (m *Monitor) Stop() {
 	m.wg.Wait()
 }
 
-// runSystemMonitor 运行系统监控
-func (m *Monitor) runSystemMonitor() {
+// runMetricsCollector 按监控间隔采集系统与容器指标，同一轮的数据点合并为一批上报
+func (m *Monitor) runMetricsCollector() {
 	ticker := time.NewTicker(time.Duration(m.config.Agent.MonitorInterval) * time.Second)
 	defer ticker.Stop()
 
@@ -99,28 +120,70 @@ func (m *Monitor) runSystemMonitor() {
 		case <-m.ctx.Done():
 			return
 		case <-ticker.C:
-			if err := m.systemMonitor.Collect(); err != nil {
-				logrus.Errorf("系统监控采集失败: %v", err)
-			}
+			m.collect()
 		}
 	}
 }
 
-// runContainerMonitor 运行容器监控
-func (m *Monitor) runContainerMonitor() {
-	ticker := time.NewTicker(time.Duration(m.config.Agent.MonitorInterval) * time.Second)
-	defer ticker.Stop()
+// collect 执行一轮采集并上报
+func (m *Monitor) collect() {
+	var points []*Point
 
-	for {
-		select {
-		case <-m.ctx.Done():
-			return
-		case <-ticker.C:
-			if err := m.containerMonitor.Collect(); err != nil {
-				logrus.Errorf("容器监控采集失败: %v", err)
-			}
-		}
+	sysPoints, err := m.systemMonitor.Collect()
+	if err != nil {
+		logrus.Errorf("系统监控采集失败: %v", err)
+	}
+	points = append(points, sysPoints...)
+
+	containerPoints, err := m.containerMonitor.Collect()
+	if err != nil {
+		logrus.Errorf("容器监控采集失败: %v", err)
+	}
+	points = append(points, containerPoints...)
+
+	for _, p := range points {
+		m.tag(p)
 	}
+	if m.exporter != nil {
+		m.exporter.Update(points)
+	}
+	m.enqueue(points)
+
+	if err := m.flush(); err != nil {
+		logrus.WithError(err).Warnf("指标上报失败，%d 个数据点待重试", len(m.pending))
+	}
+}
+
+// tag 为数据点添加 Agent 标识，server_id 仅在配置时添加，经 gRPC 上报时由服务端设置
+func (m *Monitor) tag(p *Point) {
+	if p.Tags == nil {
+		p.Tags = make(map[string]string)
+	}
+	p.Tags[constants.MetricTagAgentID] = m.config.Agent.ID
+	if m.config.Agent.ServerID != 0 {
+		p.Tags[constants.MetricTagServerID] = fmt.Sprint(m.config.Agent.ServerID)
+	}
+}
+
+// enqueue 加入待上报队列，超出上限时丢弃最早的数据点
+func (m *Monitor) enqueue(points []*Point) {
+	m.pending = append(m.pending, points...)
+	if over := len(m.pending) - constants.MaxPendingMetricPoints; over > 0 {
+		logrus.Warnf("待上报指标超出上限，丢弃最早的 %d 个数据点", over)
+		m.pending = append([]*Point(nil), m.pending[over:]...)
+	}
+}
+
+// flush 上报待上报队列，成功后清空
+func (m *Monitor) flush() error {
+	if m.metricsHandler == nil || len(m.pending) == 0 {
+		return nil
+	}
+	if err := m.metricsHandler(m.pending); err != nil {
+		return err
+	}
+	m.pending = nil
+	return nil
 }
 
 // runHealthChecker 运行健康检查
@@ -0,0 +1,19 @@
+package monitor
+
+import "time"
+
+// Point 上报的指标数据点，与 api-service 的 AgentMetricPoint 结构一致
+type Point struct {
+	Measurement string             `json:"measurement"`
+	Tags        map[string]string  `json:"tags"`
+	Fields      map[string]float64 `json:"fields"`
+	Time        time.Time          `json:"time"`
+}
+
+// counterRate 由两次采集的累计值计算每秒速率，计数器回绕或重置时返回 false
+func counterRate(prev, cur uint64, elapsed time.Duration) (float64, bool) {
+	if cur < prev || elapsed <= 0 {
+		return 0, false
+	}
+	return float64(cur-prev) / elapsed.Seconds(), true
+}
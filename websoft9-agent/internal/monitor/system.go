@@ -1,7 +1,10 @@
 package monitor
 
 import (
+	"time"
+
 	"websoft9-agent/internal/config"
+	"websoft9-agent/internal/constants"
 
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
@@ -13,6 +16,10 @@ import (
 // SystemMonitor 系统监控器
 type SystemMonitor struct {
 	config *config.Config
+
+	// 上一次采集的网络累计值，用于计算速率
+	prevNetwork *NetworkMetrics
+	prevAt      time.Time
 }
 
 // SystemMetrics 系统指标
@@ -63,18 +70,46 @@ func NewSystemMonitor(cfg *config.Config) (*SystemMonitor, error) {
 	}, nil
 }
 
-// Collect 采集系统指标
-func (s *SystemMonitor) Collect() error {
+// Collect 采集系统指标并转换为数据点，首次采集没有网络速率
+func (s *SystemMonitor) Collect() ([]*Point, error) {
 	metrics, err := s.collectMetrics()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	logrus.Debugf("系统指标: CPU使用率=%.2f%%, 内存使用率=%.2f%%",
 		metrics.CPU.Usage, metrics.Memory.Usage)
 
-	// TODO: 发送指标到 InfluxDB
-	return nil
+	now := time.Now()
+	fields := map[string]float64{
+		constants.MetricFieldCPUUsage: metrics.CPU.Usage,
+		constants.MetricFieldMemUsage: metrics.Memory.Usage,
+		constants.MetricFieldMemUsed:  float64(metrics.Memory.Used),
+	}
+	for _, d := range metrics.Disk {
+		if d.Mountpoint == "/" {
+			fields[constants.MetricFieldDiskUsage] = d.Usage
+			break
+		}
+	}
+	if s.prevNetwork != nil {
+		elapsed := now.Sub(s.prevAt)
+		if rate, ok := counterRate(s.prevNetwork.BytesRecv, metrics.Network.BytesRecv, elapsed); ok {
+			fields[constants.MetricFieldNetRxRate] = rate
+		}
+		if rate, ok := counterRate(s.prevNetwork.BytesSent, metrics.Network.BytesSent, elapsed); ok {
+			fields[constants.MetricFieldNetTxRate] = rate
+		}
+	}
+	s.prevNetwork = &metrics.Network
+	s.prevAt = now
+
+	return []*Point{{
+		Measurement: constants.MeasurementServerMetrics,
+		Tags:        map[string]string{},
+		Fields:      fields,
+		Time:        now,
+	}}, nil
 }
 
 // collectMetrics 采集所有系统指标
@@ -2,6 +2,8 @@ package task
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"sync"
 	"time"
 
@@ -18,6 +20,15 @@ type Executor struct {
 	// 任务处理器
 	handlers map[string]TaskHandler
 
+	// 待执行任务队列
+	queue chan *Task
+
+	// 结果回调
+	resultHandler func(*TaskResult)
+
+	// 实时日志回调
+	logHandler func(*TaskLog)
+
 	// 控制
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -36,11 +47,14 @@ type Task struct {
 	Params   map[string]interface{} `json:"params"`
 	Timeout  int                    `json:"timeout"`
 	Priority int                    `json:"priority"`
+
+	output io.Writer
 }
 
 // TaskResult 任务执行结果
 type TaskResult struct {
 	TaskID   string                 `json:"task_id"`
+	Type     string                 `json:"type"`
 	Status   string                 `json:"status"` // success, failed, timeout
 	Message  string                 `json:"message"`
 	Data     map[string]interface{} `json:"data"`
@@ -52,6 +66,7 @@ func NewExecutor(cfg *config.Config) (*Executor, error) {
 	executor := &Executor{
 		config:   cfg,
 		handlers: make(map[string]TaskHandler),
+		queue:    make(chan *Task, constants.DefaultBatchSize),
 	}
 
 	// 注册任务处理器
@@ -60,18 +75,30 @@ func NewExecutor(cfg *config.Config) (*Executor, error) {
 	return executor, nil
 }
 
+// SetResultHandler 设置任务结果回调，需在 Start 之前调用
+func (e *Executor) SetResultHandler(handler func(*TaskResult)) {
+	e.resultHandler = handler
+}
+
+// SetLogHandler 设置任务实时日志回调，需在 Start 之前调用
+func (e *Executor) SetLogHandler(handler func(*TaskLog)) {
+	e.logHandler = handler
+}
+
 // Start 启动任务执行器
 func (e *Executor) Start(ctx context.Context) error {
 	e.ctx, e.cancel = context.WithCancel(ctx)
 
 	logrus.Info("启动任务执行器...")
 
-	// 启动任务监听
-	e.wg.Add(1)
-	go func() {
-		defer e.wg.Done()
-		e.listenTasks()
-	}()
+	// 启动任务工作协程
+	for i := 0; i < constants.DefaultWorkerCount; i++ {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			e.listenTasks()
+		}()
+	}
 
 	return nil
 }
@@ -84,65 +111,86 @@ func (e *Executor) Stop() {
 	e.wg.Wait()
 }
 
+// Submit 提交任务到执行队列
+func (e *Executor) Submit(task *Task) error {
+	select {
+	case e.queue <- task:
+		return nil
+	default:
+		return fmt.Errorf("任务队列已满，丢弃任务: %s", task.ID)
+	}
+}
+
 // registerHandlers 注册任务处理器
 func (e *Executor) registerHandlers() {
-	e.handlers["deploy_app"] = &AppDeployHandler{}
-	e.handlers["manage_app"] = &AppManageHandler{}
-	e.handlers["system_command"] = &SystemCommandHandler{}
-	e.handlers["file_transfer"] = &FileTransferHandler{}
-	e.handlers["service_manage"] = &ServiceManageHandler{}
+	e.handlers[constants.TaskTypeDeployApp] = NewAppDeployHandler(e.config)
+	e.handlers[constants.TaskTypeManageApp] = &AppManageHandler{}
+	e.handlers[constants.TaskTypeSystemCommand] = NewSystemCommandHandler()
+	e.handlers[constants.TaskTypeFileTransfer] = &FileTransferHandler{}
+	e.handlers[constants.TaskTypeServiceManage] = NewServiceManageHandler()
+	e.handlers[constants.TaskTypeInstallCA] = NewInstallCAHandler()
+	e.handlers[constants.TaskTypeProxyConfig] = NewProxyConfigHandler()
 }
 
-// listenTasks 监听任务
+// listenTasks 监听任务队列
 func (e *Executor) listenTasks() {
-	logrus.Info("开始监听任务...")
-
-	ticker := time.NewTicker(constants.DefaultRetryInterval)
-	defer ticker.Stop()
-
 	for {
 		select {
 		case <-e.ctx.Done():
 			return
-		case <-ticker.C:
-			// TODO: 从 Redis 队列获取任务
-			// task := getTaskFromQueue()
-			// if task != nil {
-			//     e.executeTask(task)
-			// }
+		case task := <-e.queue:
+			e.executeTask(task)
 		}
 	}
 }
 
-// TODO: executeTask 执行任务 - 当实现任务队列时需要此函数
-// func (e *Executor) executeTask(task *Task) {
-//     logrus.Infof("执行任务: %s (类型: %s)", task.ID, task.Type)
-//
-//     handler, exists := e.handlers[task.Type]
-//     if !exists {
-//         logrus.Errorf("未知的任务类型: %s", task.Type)
-//         return
-//     }
-//
-//     // 创建任务上下文
-//     taskCtx := e.ctx
-//     if task.Timeout > 0 {
-//         var cancel context.CancelFunc
-//         taskCtx, cancel = context.WithTimeout(e.ctx, time.Duration(task.Timeout)*time.Second)
-//         defer cancel()
-//     }
-//
-//     // 执行任务
-//     result, err := handler.Execute(taskCtx, task)
-//     if err != nil {
-//         logrus.Errorf("任务执行失败: %v", err)
-//         result = &TaskResult{
-//             TaskID:  task.ID,
-//             Status:  constants.StatusFailed,
-//             Message: err.Error(),
-//         }
-//     }
-//
-//     // TODO: 发送结果到服务端
-//     logrus.Infof("任务 %s 执行完成: %s", task.ID, result.Status)
-// }
+// executeTask 执行任务并回传结果
+func (e *Executor) executeTask(task *Task) {
+	logrus.Infof("执行任务: %s (类型: %s)", task.ID, task.Type)
+
+	var stream *logStream
+	if e.logHandler != nil {
+		stream = newLogStream(task, e.logHandler)
+		task.output = stream
+	}
+
+	start := time.Now()
+	result, err := e.runTask(task)
+	if stream != nil {
+		stream.Close()
+	}
+	if err != nil {
+		logrus.Errorf("任务执行失败: %v", err)
+		result = &TaskResult{
+			TaskID:   task.ID,
+			Status:   constants.StatusFailed,
+			Message:  err.Error(),
+			Duration: time.Since(start).Milliseconds(),
+		}
+	}
+	result.TaskID = task.ID
+	result.Type = task.Type
+
+	logrus.Infof("任务 %s 执行完成: %s", task.ID, result.Status)
+
+	if e.resultHandler != nil {
+		e.resultHandler(result)
+	}
+}
+
+func (e *Executor) runTask(task *Task) (*TaskResult, error) {
+	handler, exists := e.handlers[task.Type]
+	if !exists {
+		return nil, fmt.Errorf("未知的任务类型: %s", task.Type)
+	}
+
+	// 创建任务上下文
+	taskCtx := e.ctx
+	if task.Timeout > 0 {
+		var cancel context.CancelFunc
+		taskCtx, cancel = context.WithTimeout(e.ctx, time.Duration(task.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	return handler.Execute(taskCtx, task)
+}
@@ -1,36 +1,218 @@
 package task
 
 import (
+	"bytes"
 	"context"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
+	"websoft9-agent/internal/config"
 	"websoft9-agent/internal/constants"
 	"websoft9-agent/pkg/security"
 
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 )
 
 // AppDeployHandler 应用部署处理器
-type AppDeployHandler struct{}
+// 通过 docker compose 部署应用，密钥仅写入 tmpfs 目录
+type AppDeployHandler struct {
+	workDir    string
+	secretsDir string
+}
+
+// NewAppDeployHandler 创建应用部署处理器
+func NewAppDeployHandler(cfg *config.Config) *AppDeployHandler {
+	return &AppDeployHandler{
+		workDir:    cfg.Agent.WorkDir,
+		secretsDir: cfg.Agent.SecretsDir,
+	}
+}
+
+var (
+	projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	envKeyPattern      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	secretNamePattern  = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+)
 
 func (h *AppDeployHandler) Execute(ctx context.Context, task *Task) (*TaskResult, error) {
 	start := time.Now()
 
 	logrus.Infof("执行应用部署任务: %s", task.ID)
 
-	// TODO: 实现应用部署逻辑
+	failed := func(message string) (*TaskResult, error) {
+		return &TaskResult{
+			TaskID:   task.ID,
+			Status:   constants.StatusFailed,
+			Message:  message,
+			Duration: time.Since(start).Milliseconds(),
+		}, nil
+	}
+
 	// 1. 解析部署参数
-	// 2. 拉取镜像
-	// 3. 创建容器
-	// 4. 启动应用
+	project, _ := task.Params["project"].(string)
+	if !projectNamePattern.MatchString(project) {
+		return failed(fmt.Sprintf("无效的项目名称: %s", project))
+	}
+	compose, _ := task.Params["compose"].(string)
+	if compose == "" {
+		return failed("缺少 compose 参数")
+	}
+	env, err := stringMapParam(task.Params, "env")
+	if err != nil {
+		return failed(err.Error())
+	}
+	secrets, err := stringMapParam(task.Params, "secrets")
+	if err != nil {
+		return failed(err.Error())
+	}
 
-	return &TaskResult{
+	// 2. 写入 compose 文件，模板中不包含密钥
+	projectDir := filepath.Join(h.workDir, "apps", project)
+	if err := os.MkdirAll(projectDir, 0750); err != nil {
+		return failed(fmt.Sprintf("创建项目目录失败: %v", err))
+	}
+	composeFile := filepath.Join(projectDir, "docker-compose.yml")
+	if err := os.WriteFile(composeFile, []byte(compose), 0640); err != nil {
+		return failed(fmt.Sprintf("写入 compose 文件失败: %v", err))
+	}
+
+	// 3. 环境变量与密钥文件仅写入 tmpfs
+	secretDir, err := h.writeSecrets(project, env, secrets)
+	if err != nil {
+		return failed(err.Error())
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"task_id": task.ID,
+		"project": project,
+		"secrets": len(secrets),
+		"action":  "app_deploy",
+	}).Info("Security audit: app deployment with injected secrets")
+
+	// 4. 拉取镜像并启动应用
+	// #nosec G204 - project is validated by projectNamePattern, paths are generated by the agent
+	cmd := exec.CommandContext(ctx, "docker", "compose",
+		"-p", project,
+		"-f", composeFile,
+		"--env-file", filepath.Join(secretDir, ".env"),
+		"up", "-d", "--remove-orphans")
+	cmd.Dir = projectDir
+	cmd.Env = append(os.Environ(), "WEBOX_SECRETS_DIR="+secretDir)
+	output, err := runCommand(task, cmd)
+
+	result := &TaskResult{
 		TaskID:   task.ID,
-		Status:   constants.StatusSuccess,
-		Message:  "应用部署成功",
 		Duration: time.Since(start).Milliseconds(),
-	}, nil
+		Data: map[string]interface{}{
+			"output": string(output),
+		},
+	}
+
+	if err != nil {
+		result.Status = constants.StatusFailed
+		result.Message = err.Error()
+		logrus.WithFields(logrus.Fields{
+			"task_id": task.ID,
+			"project": project,
+			"error":   err.Error(),
+		}).Error("App deployment failed")
+	} else {
+		result.Status = constants.StatusSuccess
+		result.Message = "应用部署成功"
+	}
+
+	return result, nil
+}
+
+// writeSecrets 在 tmpfs 中写入 .env 与密钥文件，返回项目密钥目录
+// 密钥文件以密钥名命名，compose 模板可通过 ${WEBOX_SECRETS_DIR}/<name> 引用
+func (h *AppDeployHandler) writeSecrets(project string, env, secrets map[string]string) (string, error) {
+	if err := os.MkdirAll(h.secretsDir, 0700); err != nil {
+		return "", fmt.Errorf("创建密钥目录失败: %v", err)
+	}
+	if err := ensureTmpfs(h.secretsDir); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(h.secretsDir, project)
+	// 清理上一次部署遗留的密钥
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("清理密钥目录失败: %v", err)
+	}
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return "", fmt.Errorf("创建密钥目录失败: %v", err)
+	}
+
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		if !envKeyPattern.MatchString(key) {
+			return "", fmt.Errorf("无效的环境变量名: %s", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf strings.Builder
+	for _, key := range keys {
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(quoteEnvValue(env[key]))
+		buf.WriteByte('\n')
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(buf.String()), 0600); err != nil {
+		return "", fmt.Errorf("写入环境变量文件失败: %v", err)
+	}
+
+	for name, value := range secrets {
+		if !secretNamePattern.MatchString(name) {
+			return "", fmt.Errorf("无效的密钥名称: %s", name)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0600); err != nil {
+			return "", fmt.Errorf("写入密钥文件失败: %v", err)
+		}
+	}
+
+	return dir, nil
+}
+
+// quoteEnvValue 按 compose env 文件语法转义取值，单引号内不做变量插值
+func quoteEnvValue(value string) string {
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// stringMapParam 读取字符串映射类型的任务参数
+func stringMapParam(params map[string]interface{}, name string) (map[string]string, error) {
+	values := make(map[string]string)
+	raw, ok := params[name]
+	if !ok || raw == nil {
+		return values, nil
+	}
+
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("参数 %s 格式错误", name)
+	}
+	for key, value := range m {
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
 }
 
 // AppManageHandler 应用管理处理器
@@ -102,7 +284,7 @@ func (h *SystemCommandHandler) Execute(ctx context.Context, task *Task) (*TaskRe
 
 	// #nosec G204 - Command is validated by security.CommandValidator before execution
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
-	output, err := cmd.CombinedOutput()
+	output, err := runCommand(task, cmd)
 
 	result := &TaskResult{
 		TaskID:   task.ID,
@@ -221,7 +403,7 @@ func (h *ServiceManageHandler) Execute(ctx context.Context, task *Task) (*TaskRe
 	// 使用参数化命令执行，避免命令注入
 	// #nosec G204 - Action and serviceName are validated by security.CommandValidator
 	cmd := exec.CommandContext(ctx, "systemctl", action, serviceName)
-	output, err := cmd.CombinedOutput()
+	output, err := runCommand(task, cmd)
 
 	result := &TaskResult{
 		TaskID:   task.ID,
@@ -247,3 +429,362 @@ func (h *ServiceManageHandler) Execute(ctx context.Context, task *Task) (*TaskRe
 
 	return result, nil
 }
+
+// trustStore 系统 CA 信任库
+type trustStore struct {
+	dir     string
+	command []string
+}
+
+// trustStores 按顺序探测，Debian/Ubuntu/Alpine 优先，其次 RHEL/CentOS/Fedora
+var trustStores = []trustStore{
+	{dir: "/usr/local/share/ca-certificates", command: []string{"update-ca-certificates"}},
+	{dir: "/etc/pki/ca-trust/source/anchors", command: []string{"update-ca-trust", "extract"}},
+}
+
+// InstallCAHandler 将内部 CA 证书安装到主机系统信任库
+type InstallCAHandler struct {
+	stores []trustStore
+}
+
+// NewInstallCAHandler 创建 CA 证书安装处理器
+func NewInstallCAHandler() *InstallCAHandler {
+	return &InstallCAHandler{
+		stores: trustStores,
+	}
+}
+
+func (h *InstallCAHandler) Execute(ctx context.Context, task *Task) (*TaskResult, error) {
+	start := time.Now()
+
+	logrus.Infof("执行 CA 证书安装任务: %s", task.ID)
+
+	failed := func(message string) (*TaskResult, error) {
+		return &TaskResult{
+			TaskID:   task.ID,
+			Status:   constants.StatusFailed,
+			Message:  message,
+			Duration: time.Since(start).Milliseconds(),
+		}, nil
+	}
+
+	name, _ := task.Params["name"].(string)
+	if !secretNamePattern.MatchString(name) {
+		return failed(fmt.Sprintf("无效的 CA 名称: %s", name))
+	}
+	certificate, _ := task.Params["certificate"].(string)
+	if err := validateCACertificate([]byte(certificate)); err != nil {
+		return failed(err.Error())
+	}
+
+	store, err := h.detectStore()
+	if err != nil {
+		return failed(err.Error())
+	}
+
+	path := filepath.Join(store.dir, "webox-"+name+".crt")
+	previous, readErr := os.ReadFile(path) // #nosec G304 - 路径由固定目录与校验过的名称组成
+	if readErr == nil && bytes.Equal(previous, []byte(certificate)) {
+		return &TaskResult{
+			TaskID:   task.ID,
+			Status:   constants.StatusSuccess,
+			Message:  "CA 证书已安装",
+			Duration: time.Since(start).Milliseconds(),
+			Data:     map[string]interface{}{"path": path},
+		}, nil
+	}
+
+	if err := os.MkdirAll(store.dir, 0755); err != nil {
+		return failed(fmt.Sprintf("创建信任库目录失败: %v", err))
+	}
+	// #nosec G306 - CA 证书为公开内容，信任库要求可读
+	if err := os.WriteFile(path, []byte(certificate), 0644); err != nil {
+		return failed(fmt.Sprintf("写入 CA 证书失败: %v", err))
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"task_id":   task.ID,
+		"ca_name":   name,
+		"path":      path,
+		"operation": "install_ca",
+	}).Info("Security audit: CA certificate installed into system trust store")
+
+	// #nosec G204 - 命令来自固定的信任库列表
+	cmd := exec.CommandContext(ctx, store.command[0], store.command[1:]...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		// 刷新失败时恢复原有文件，避免信任库处于不一致状态
+		if readErr == nil {
+			_ = os.WriteFile(path, previous, 0644) // #nosec G306
+		} else {
+			_ = os.Remove(path)
+		}
+		return &TaskResult{
+			TaskID:   task.ID,
+			Status:   constants.StatusFailed,
+			Message:  fmt.Sprintf("更新系统信任库失败: %v", err),
+			Duration: time.Since(start).Milliseconds(),
+			Data:     map[string]interface{}{"output": string(output)},
+		}, nil
+	}
+
+	return &TaskResult{
+		TaskID:   task.ID,
+		Status:   constants.StatusSuccess,
+		Message:  "CA 证书安装成功",
+		Duration: time.Since(start).Milliseconds(),
+		Data: map[string]interface{}{
+			"path":   path,
+			"output": string(output),
+		},
+	}, nil
+}
+
+// detectStore 选择主机上可用的信任库
+func (h *InstallCAHandler) detectStore() (*trustStore, error) {
+	for i := range h.stores {
+		if _, err := exec.LookPath(h.stores[i].command[0]); err == nil {
+			return &h.stores[i], nil
+		}
+	}
+	return nil, fmt.Errorf("未找到支持的系统信任库工具")
+}
+
+// validateCACertificate 校验内容为单个 PEM 编码的 CA 证书
+func validateCACertificate(data []byte) error {
+	block, rest := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return fmt.Errorf("无效的 CA 证书")
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return fmt.Errorf("CA 证书只能包含一个证书")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("解析 CA 证书失败: %v", err)
+	}
+	if !cert.IsCA {
+		return fmt.Errorf("证书不是 CA 证书")
+	}
+	return nil
+}
+
+// proxyTarget 外部反向代理，配置与证书只能写入 root 目录下
+type proxyTarget struct {
+	root     string
+	validate []string
+	reload   []string
+}
+
+// proxyTargets 支持的代理类型，Traefik 文件提供者自动监听配置变更，无需重新加载
+var proxyTargets = map[string]proxyTarget{
+	"nginx": {
+		root:     "/etc/nginx",
+		validate: []string{"nginx", "-t"},
+		reload:   []string{"nginx", "-s", "reload"},
+	},
+	"traefik": {
+		root: "/etc/traefik",
+	},
+}
+
+// ProxyConfigHandler 写入网关导出的 nginx/Traefik 配置及证书并重新加载代理
+// 校验或重新加载失败时恢复全部文件
+type ProxyConfigHandler struct {
+	targets map[string]proxyTarget
+}
+
+// NewProxyConfigHandler 创建代理配置处理器
+func NewProxyConfigHandler() *ProxyConfigHandler {
+	return &ProxyConfigHandler{
+		targets: proxyTargets,
+	}
+}
+
+// fileBackup 写入前的文件状态，用于回滚
+type fileBackup struct {
+	path    string
+	content []byte
+	mode    os.FileMode
+	existed bool
+}
+
+func (h *ProxyConfigHandler) Execute(ctx context.Context, task *Task) (*TaskResult, error) {
+	start := time.Now()
+
+	logrus.Infof("执行代理配置任务: %s", task.ID)
+
+	failed := func(message string, output []byte) (*TaskResult, error) {
+		result := &TaskResult{
+			TaskID:   task.ID,
+			Status:   constants.StatusFailed,
+			Message:  message,
+			Duration: time.Since(start).Milliseconds(),
+		}
+		if output != nil {
+			result.Data = map[string]interface{}{"output": string(output)}
+		}
+		return result, nil
+	}
+
+	// 1. 解析并校验参数
+	format, _ := task.Params["format"].(string)
+	target, ok := h.targets[format]
+	if !ok {
+		return failed(fmt.Sprintf("不支持的代理类型: %s", format), nil)
+	}
+	configDir, _ := task.Params["config_dir"].(string)
+	certDir, _ := task.Params["cert_dir"].(string)
+	for _, dir := range []string{configDir, certDir} {
+		if !withinDir(target.root, dir) {
+			return failed(fmt.Sprintf("目录必须位于 %s 下: %s", target.root, dir), nil)
+		}
+	}
+	filename, _ := task.Params["filename"].(string)
+	if !secretNamePattern.MatchString(filename) {
+		return failed(fmt.Sprintf("无效的配置文件名: %s", filename), nil)
+	}
+	content, _ := task.Params["content"].(string)
+	if content == "" {
+		return failed("配置内容为空", nil)
+	}
+	if format == "traefik" {
+		var parsed map[string]interface{}
+		if err := yaml.Unmarshal([]byte(content), &parsed); err != nil {
+			return failed(fmt.Sprintf("Traefik 配置格式错误: %v", err), nil)
+		}
+	}
+
+	files := map[string][]byte{filepath.Join(configDir, filename): []byte(content)}
+	keyFiles := make(map[string]bool)
+	certs, _ := task.Params["certificates"].([]interface{})
+	for _, item := range certs {
+		cert, _ := item.(map[string]interface{})
+		name, _ := cert["name"].(string)
+		if !secretNamePattern.MatchString(name) {
+			return failed(fmt.Sprintf("无效的证书名称: %s", name), nil)
+		}
+		certPEM, _ := cert["certificate"].(string)
+		keyPEM, _ := cert["private_key"].(string)
+		if block, _ := pem.Decode([]byte(certPEM)); block == nil || block.Type != "CERTIFICATE" {
+			return failed(fmt.Sprintf("证书 %s 格式错误", name), nil)
+		}
+		if block, _ := pem.Decode([]byte(keyPEM)); block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return failed(fmt.Sprintf("证书 %s 的私钥格式错误", name), nil)
+		}
+		files[filepath.Join(certDir, name+".crt")] = []byte(certPEM)
+		keyPath := filepath.Join(certDir, name+".key")
+		files[keyPath] = []byte(keyPEM)
+		keyFiles[keyPath] = true
+	}
+
+	for _, command := range [][]string{target.validate, target.reload} {
+		if len(command) > 0 {
+			if _, err := exec.LookPath(command[0]); err != nil {
+				return failed(fmt.Sprintf("未找到 %s: %v", command[0], err), nil)
+			}
+		}
+	}
+
+	// 2. 写入文件，证书先于配置写入，保证校验时引用的证书已存在
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return filepath.Dir(paths[i]) == certDir && filepath.Dir(paths[j]) != certDir
+	})
+
+	var backups []fileBackup
+	rollback := func() {
+		for i := len(backups) - 1; i >= 0; i-- {
+			b := backups[i]
+			if b.existed {
+				_ = os.WriteFile(b.path, b.content, b.mode) // #nosec G306 - 恢复原有权限
+			} else {
+				_ = os.Remove(b.path)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		backup, err := backupFile(path)
+		if err != nil {
+			rollback()
+			return failed(fmt.Sprintf("读取原文件失败: %v", err), nil)
+		}
+		backups = append(backups, *backup)
+
+		var mode os.FileMode = 0644
+		if keyFiles[path] {
+			mode = 0600
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			rollback()
+			return failed(fmt.Sprintf("创建目录失败: %v", err), nil)
+		}
+		if err := os.WriteFile(path, files[path], mode); err != nil {
+			rollback()
+			return failed(fmt.Sprintf("写入文件失败: %v", err), nil)
+		}
+	}
+
+	// 3. 校验并重新加载
+	var output []byte
+	for _, command := range [][]string{target.validate, target.reload} {
+		if len(command) == 0 {
+			continue
+		}
+		// #nosec G204 - 命令来自固定的代理类型列表
+		out, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput()
+		output = append(output, out...)
+		if err != nil {
+			rollback()
+			return failed(fmt.Sprintf("执行 %s 失败，已恢复原配置: %v", strings.Join(command, " "), err), output)
+		}
+	}
+
+	configPath := filepath.Join(configDir, filename)
+	logrus.WithFields(logrus.Fields{
+		"task_id":      task.ID,
+		"format":       format,
+		"config_path":  configPath,
+		"certificates": len(certs),
+		"operation":    "proxy_config",
+	}).Info("Security audit: reverse proxy config and certificates written")
+
+	return &TaskResult{
+		TaskID:   task.ID,
+		Status:   constants.StatusSuccess,
+		Message:  "代理配置已生效",
+		Duration: time.Since(start).Milliseconds(),
+		Data: map[string]interface{}{
+			"config_path": configPath,
+			"output":      string(output),
+		},
+	}, nil
+}
+
+// withinDir 判断 dir 为 root 或其子目录，要求为规范化的绝对路径
+func withinDir(root, dir string) bool {
+	if !filepath.IsAbs(dir) || filepath.Clean(dir) != dir {
+		return false
+	}
+	return dir == root || strings.HasPrefix(dir, root+string(filepath.Separator))
+}
+
+// backupFile 记录文件当前内容，文件不存在时回滚为删除
+func backupFile(path string) (*fileBackup, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return &fileBackup{path: path}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path) // #nosec G304 - 路径已限制在代理配置目录内
+	if err != nil {
+		return nil, err
+	}
+	return &fileBackup{path: path, content: content, mode: info.Mode().Perm(), existed: true}, nil
+}
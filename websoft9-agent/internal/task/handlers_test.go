@@ -0,0 +1,133 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQuoteEnvValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"plain", "secret", `'secret'`},
+		{"empty", "", `''`},
+		{"dollar stays literal in single quotes", "pa$$word${HOME}", `'pa$$word${HOME}'`},
+		{"double quote and backslash", `a"b\c`, `'a"b\c'`},
+		{"single quote", "it's", `"it's"`},
+		{"single quote with dollar", "it's $HOME", `"it's \$HOME"`},
+		{"single quote with double quote and backslash", `'"\`, `"'\"\\"`},
+		{"newline", "line1\nline2", `"line1\nline2"`},
+		{"crlf", "a\r\nb", `"a\r\nb"`},
+		{"pem", "-----BEGIN KEY-----\nMII$x\n-----END KEY-----\n", `"-----BEGIN KEY-----\nMII\$x\n-----END KEY-----\n"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quoteEnvValue(tt.value); got != tt.want {
+				t.Errorf("quoteEnvValue(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteSecrets(t *testing.T) {
+	root, err := os.MkdirTemp("/dev/shm", "webox-test-")
+	if err != nil {
+		t.Skipf("tmpfs not available: %v", err)
+	}
+	defer os.RemoveAll(root)
+	if err := ensureTmpfs(root); err != nil {
+		t.Skip(err)
+	}
+	h := &AppDeployHandler{secretsDir: filepath.Join(root, "secrets")}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		secrets map[string]string
+		wantEnv string
+		wantErr bool
+	}{
+		{
+			name:    "sorted and quoted",
+			env:     map[string]string{"B": "it's", "A": "x$y", "C": "1\n2"},
+			secrets: map[string]string{"db.password": "p@ss\n"},
+			wantEnv: "A='x$y'\nB=\"it's\"\nC=\"1\\n2\"\n",
+		},
+		{
+			name:    "empty",
+			wantEnv: "",
+		},
+		{
+			name:    "invalid env name",
+			env:     map[string]string{"A=B": "x"},
+			wantErr: true,
+		},
+		{
+			name:    "env name with newline",
+			env:     map[string]string{"A\nB": "x"},
+			wantErr: true,
+		},
+		{
+			name:    "secret name with path",
+			secrets: map[string]string{"../escape": "x"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 先写入一个遗留文件，确认重新部署时被清理
+			stale := filepath.Join(h.secretsDir, "app", "stale")
+			if err := os.MkdirAll(filepath.Dir(stale), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(stale, []byte("old"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			dir, err := h.writeSecrets("app", tt.env, tt.secrets)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				if _, err := os.Stat(filepath.Join(root, "escape")); err == nil {
+					t.Error("secret written outside of the secrets directory")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := os.Stat(stale); !os.IsNotExist(err) {
+				t.Errorf("stale secret was not removed: %v", err)
+			}
+			data, err := os.ReadFile(filepath.Join(dir, ".env"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.wantEnv {
+				t.Errorf(".env = %q, want %q", data, tt.wantEnv)
+			}
+			for name, value := range tt.secrets {
+				path := filepath.Join(dir, name)
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != value {
+					t.Errorf("secret %s = %q, want %q", name, data, value)
+				}
+				info, _ := os.Stat(path)
+				if perm := info.Mode().Perm(); perm != 0600 {
+					t.Errorf("secret %s mode = %o", name, perm)
+				}
+			}
+			if info, _ := os.Stat(dir); info.Mode().Perm() != 0700 {
+				t.Errorf("secrets dir mode = %o", info.Mode().Perm())
+			}
+		})
+	}
+}
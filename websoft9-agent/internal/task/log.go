@@ -0,0 +1,156 @@
+package task
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"websoft9-agent/internal/constants"
+)
+
+// TaskLog 任务输出的一批日志行，Seq 从 1 开始按批次递增，api-service 据此去重
+type TaskLog struct {
+	TaskID string    `json:"task_id"`
+	Type   string    `json:"type"`
+	Seq    int       `json:"seq"`
+	Lines  []string  `json:"lines"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// logStream 按行切分任务输出，积累到 TaskLogBatchLines 行或每隔 TaskLogFlushInterval 回调一次，每批不超过 TaskLogBatchLines 行
+type logStream struct {
+	taskID   string
+	taskType string
+	handler  func(*TaskLog)
+
+	mu      sync.Mutex
+	partial []byte
+	lines   []string
+	seq     int
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newLogStream(task *Task, handler func(*TaskLog)) *logStream {
+	s := &logStream{
+		taskID:   task.ID,
+		taskType: task.Type,
+		handler:  handler,
+		done:     make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(constants.TaskLogFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				s.flush(false)
+			}
+		}
+	}()
+	return s
+}
+
+// Write 实现 io.Writer，可被多个协程同时写入(如命令的 stdout 与 stderr)
+func (s *logStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	s.partial = append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			break
+		}
+		s.addLine(s.partial[:i])
+		s.partial = s.partial[i+1:]
+	}
+	// 没有换行的超长输出不再等待换行
+	for len(s.partial) >= constants.TaskLogMaxLineBytes {
+		s.addLine(s.partial[:constants.TaskLogMaxLineBytes])
+		s.partial = s.partial[constants.TaskLogMaxLineBytes:]
+	}
+	full := len(s.lines) >= constants.TaskLogBatchLines
+	s.mu.Unlock()
+
+	if full {
+		s.flush(false)
+	}
+	return len(p), nil
+}
+
+// addLine 超过上限的行切分为多行
+func (s *logStream) addLine(line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	for len(line) > constants.TaskLogMaxLineBytes {
+		s.lines = append(s.lines, string(line[:constants.TaskLogMaxLineBytes]))
+		line = line[constants.TaskLogMaxLineBytes:]
+	}
+	s.lines = append(s.lines, string(line))
+}
+
+// flush 回调积累的日志行，final 为 true 时同时输出不以换行结尾的最后一行
+func (s *logStream) flush(final bool) {
+	s.mu.Lock()
+	if final && len(s.partial) > 0 {
+		s.addLine(s.partial)
+		s.partial = nil
+	}
+	// 持锁回调，保证批次按 Seq 顺序发送
+	for len(s.lines) > 0 {
+		n := min(len(s.lines), constants.TaskLogBatchLines)
+		s.seq++
+		s.handler(&TaskLog{
+			TaskID: s.taskID,
+			Type:   s.taskType,
+			Seq:    s.seq,
+			Lines:  s.lines[:n:n],
+			SentAt: time.Now(),
+		})
+		s.lines = s.lines[n:]
+	}
+	s.lines = nil
+	s.mu.Unlock()
+}
+
+// Close 停止定时回调并发送剩余的日志行，任务结果须在 Close 之后发送
+func (s *logStream) Close() {
+	close(s.done)
+	s.wg.Wait()
+	s.flush(true)
+}
+
+// Output 返回任务输出的实时日志写入端，未设置日志回调时丢弃
+func (t *Task) Output() io.Writer {
+	if t.output == nil {
+		return io.Discard
+	}
+	return t.output
+}
+
+// runCommand 执行命令，输出同时写入任务实时日志，返回合并的 stdout 与 stderr
+func runCommand(task *Task, cmd *exec.Cmd) ([]byte, error) {
+	var buf bytes.Buffer
+	w := &lockedWriter{w: io.MultiWriter(&buf, task.Output())}
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err := cmd.Run()
+	return buf.Bytes(), err
+}
+
+// lockedWriter 串行化 stdout 与 stderr 的写入
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
@@ -0,0 +1,47 @@
+package task
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"websoft9-agent/internal/constants"
+)
+
+func TestLogStream(t *testing.T) {
+	var batches []*TaskLog
+	stream := newLogStream(&Task{ID: "t1", Type: constants.TaskTypeSystemCommand}, func(log *TaskLog) {
+		batches = append(batches, log)
+	})
+
+	stream.Write([]byte("first\r\nsec"))
+	stream.Write([]byte("ond\nno newline"))
+	stream.Write([]byte(strings.Repeat("x", constants.TaskLogMaxLineBytes+1) + "\n"))
+	stream.Close()
+
+	var lines []string
+	for i, batch := range batches {
+		if batch.Seq != i+1 || batch.TaskID != "t1" || batch.Type != constants.TaskTypeSystemCommand {
+			t.Errorf("batch %d = %+v", i, batch)
+		}
+		lines = append(lines, batch.Lines...)
+	}
+	// 超长行按上限切分
+	want := []string{"first", "second", "no newline" + strings.Repeat("x", constants.TaskLogMaxLineBytes-10), "xxxxxxxxxxx"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("got %d lines, first %q", len(lines), lines[0])
+	}
+}
+
+func TestLogStreamBatches(t *testing.T) {
+	var batches []*TaskLog
+	stream := newLogStream(&Task{ID: "t2"}, func(log *TaskLog) {
+		batches = append(batches, log)
+	})
+	stream.Write([]byte(strings.Repeat("line\n", constants.TaskLogBatchLines+1)))
+	stream.Close()
+
+	if len(batches) != 2 || len(batches[0].Lines) != constants.TaskLogBatchLines || len(batches[1].Lines) != 1 {
+		t.Errorf("got %d batches", len(batches))
+	}
+}
@@ -0,0 +1,23 @@
+//go:build linux
+
+package task
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// tmpfsMagic tmpfs 文件系统类型标识 (TMPFS_MAGIC)
+const tmpfsMagic = 0x01021994
+
+// ensureTmpfs 确认目录位于 tmpfs，避免密钥落盘
+func ensureTmpfs(dir string) error {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return fmt.Errorf("无法获取目录文件系统信息: %v", err)
+	}
+	if int64(stat.Type) != tmpfsMagic {
+		return fmt.Errorf("密钥目录 %s 不在 tmpfs 上", dir)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package task
+
+import "fmt"
+
+// ensureTmpfs 非 Linux 平台无法确认 tmpfs，拒绝写入密钥
+func ensureTmpfs(dir string) error {
+	return fmt.Errorf("当前平台不支持 tmpfs 密钥目录: %s", dir)
+}
@@ -0,0 +1,66 @@
+// Package lineprotocol 将数据点编码为 InfluxDB 行协议
+// https://docs.influxdata.com/influxdb/v2/reference/syntax/line-protocol/
+package lineprotocol
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+)
+
+// Encode 编码一行数据，时间精度为秒；标签与字段按名称排序，空标签值与非有限数值字段会被忽略
+// 所有字段都被忽略时返回空字符串
+func Encode(measurement string, tags map[string]string, fields map[string]float64, ts time.Time) string {
+	var b strings.Builder
+	b.WriteString(measurementEscaper.Replace(measurement))
+
+	for _, k := range sortedKeys(tags) {
+		if tags[k] == "" {
+			continue
+		}
+		b.WriteByte(',')
+		b.WriteString(tagEscaper.Replace(k))
+		b.WriteByte('=')
+		b.WriteString(tagEscaper.Replace(tags[k]))
+	}
+
+	n := 0
+	for _, k := range sortedKeys(fields) {
+		v := fields[k]
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		if n == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(tagEscaper.Replace(k))
+		b.WriteByte('=')
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+		n++
+	}
+	if n == 0 {
+		return ""
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(ts.Unix(), 10))
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,49 @@
+package lineprotocol
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	ts := time.Unix(1714550400, 0)
+
+	tests := []struct {
+		name   string
+		tags   map[string]string
+		fields map[string]float64
+		want   string
+	}{
+		{
+			name:   "sorted",
+			tags:   map[string]string{"server_id": "1", "agent_id": "a1"},
+			fields: map[string]float64{"memory_usage": 42.5, "cpu_usage": 3},
+			want:   "server_metrics,agent_id=a1,server_id=1 cpu_usage=3,memory_usage=42.5 1714550400",
+		},
+		{
+			name:   "escaped",
+			tags:   map[string]string{"container_name": "web app,1=x", "empty": ""},
+			fields: map[string]float64{"cpu usage": 1},
+			want:   `server_metrics,container_name=web\ app\,1\=x cpu\ usage=1 1714550400`,
+		},
+		{
+			name:   "skip non finite",
+			fields: map[string]float64{"a": math.NaN(), "b": math.Inf(1), "c": 0},
+			want:   "server_metrics c=0 1714550400",
+		},
+		{
+			name:   "no fields",
+			fields: map[string]float64{"a": math.NaN()},
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Encode("server_metrics", tt.tags, tt.fields, ts); got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// TaskCipher 任务通道加解密器，与 api-service 使用相同的 AES-256-GCM 格式
+// 密文格式为 base64(nonce || ciphertext)
+type TaskCipher struct {
+	aead cipher.AEAD
+}
+
+// NewTaskCipher 根据共享任务密钥创建加解密器，密钥经 SHA256 派生为 32 字节
+func NewTaskCipher(secret string) (*TaskCipher, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("任务密钥不能为空")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TaskCipher{aead: aead}, nil
+}
+
+// Encrypt 加密数据并返回 base64 字符串
+func (c *TaskCipher) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	data := c.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Decrypt 解密 base64 字符串
+func (c *TaskCipher) Decrypt(ciphertext string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("密文长度不足")
+	}
+	return c.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+}